@@ -16,10 +16,12 @@ import (
 
 	_ "github.com/interline-io/transitland-lib/ext/filters"
 	_ "github.com/interline-io/transitland-lib/ext/plus"
+	_ "github.com/interline-io/transitland-lib/netex"
 	_ "github.com/interline-io/transitland-lib/tlcsv"
 	_ "github.com/interline-io/transitland-lib/tldb"
 	_ "github.com/interline-io/transitland-lib/tldb/postgres"
 	_ "github.com/interline-io/transitland-lib/tldb/sqlite"
+	_ "github.com/interline-io/transitland-lib/tlparquet"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -47,6 +49,7 @@ func init() {
 		tlcli.CobraHelper(&cmds.CopyCommand{}, pc, "copy"),
 		tlcli.CobraHelper(&cmds.ExtractCommand{}, pc, "extract"),
 		tlcli.CobraHelper(&cmds.FetchCommand{}, pc, "fetch"),
+		tlcli.CobraHelper(&cmds.FetchDaemonCommand{}, pc, "fetch-daemon"),
 		tlcli.CobraHelper(&cmds.ImportCommand{}, pc, "import"),
 		tlcli.CobraHelper(&cmds.ChecksumCommand{}, pc, "checksum"),
 		tlcli.CobraHelper(&cmds.MergeCommand{}, pc, "merge"),
@@ -54,6 +57,7 @@ func init() {
 
 		tlcli.CobraHelper(&cmds.ValidatorCommand{}, pc, "validate"),
 		tlcli.CobraHelper(&cmds.RTConvertCommand{}, pc, "rt-convert"),
+		tlcli.CobraHelper(&cmds.RTRecordCommand{}, pc, "rt-record"),
 		tlcli.CobraHelper(&diff.Command{}, pc, "diff"),
 		tlcli.CobraHelper(&tlxy.PolylinesCommand{}, pc, "polylines-create"),
 		tlcli.CobraHelper(&cmds.ServerCommand{}, pc, "server"),
@@ -66,6 +70,7 @@ func init() {
 		tlcli.CobraHelper(&cmds.DeleteCommand{}, pc, "delete"),
 		tlcli.CobraHelper(&cmds.UnimportCommand{}, pc, "unimport"),
 		tlcli.CobraHelper(&cmds.FeedStateManagerCommand{}, pc, "feed-state"),
+		tlcli.CobraHelper(&cmds.FeedActivateCommand{}, pc, "feed-activate"),
 
 		cmds.NewDmfrCommand(pc),
 		dmfrFormatCommand,
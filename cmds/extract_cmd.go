@@ -15,6 +15,7 @@ import (
 	_ "github.com/interline-io/transitland-lib/ext/plus"
 	"github.com/interline-io/transitland-lib/extract"
 	"github.com/interline-io/transitland-lib/tlcli"
+	"github.com/interline-io/transitland-lib/tt"
 	"github.com/spf13/pflag"
 )
 
@@ -45,6 +46,10 @@ type ExtractCommand struct {
 	excludeRoutes       []string
 	excludeRouteTypes   []string
 	bbox                string
+	startDate           string
+	endDate             string
+	startTime           string
+	endTime             string
 	writeExtraColumns   bool
 	readerPath          string
 	writerPath          string
@@ -129,6 +134,10 @@ func (cmd *ExtractCommand) AddFlags(fl *pflag.FlagSet) {
 	fl.BoolVar(&cmd.excludeUnusedRoutes, "exclude-unused-routes", false, "Exclude routes that have no trips in the source data")
 
 	fl.StringVar(&cmd.bbox, "bbox", "", "Extract bbox as (min lon, min lat, max lon, max lat), e.g. -122.276,37.794,-122.259,37.834")
+	fl.StringVar(&cmd.startDate, "start-date", "", "Extract service active on or after this date (YYYY-MM-DD); requires --end-date")
+	fl.StringVar(&cmd.endDate, "end-date", "", "Extract service active on or before this date (YYYY-MM-DD); requires --start-date")
+	fl.StringVar(&cmd.startTime, "start-time", "", "Extract trips departing at or after this time of day (HH:MM:SS); times are compared modulo 24 hours and frequencies are clipped to the window")
+	fl.StringVar(&cmd.endTime, "end-time", "", "Extract trips departing before this time of day (HH:MM:SS); use a time after 24:00:00 to span midnight")
 
 	fl.StringArrayVar(&cmd.extractSet, "set", nil, "Set values on output; format is filename,id,key,value")
 	fl.StringArrayVar(&cmd.PrefixFilesInclude, "prefix-files-include", nil, "Prefix files to use for entity matching")
@@ -244,6 +253,47 @@ func (cmd *ExtractCommand) Run(ctx context.Context) error {
 	em := extract.NewMarker()
 	// Includes
 	em.SetBbox(cmd.bbox)
+	if cmd.startDate != "" || cmd.endDate != "" {
+		startDate, err := tt.ParseDate(cmd.startDate)
+		if err != nil {
+			return fmt.Errorf("invalid start date: %s", cmd.startDate)
+		}
+		endDate, err := tt.ParseDate(cmd.endDate)
+		if err != nil {
+			return fmt.Errorf("invalid end date: %s", cmd.endDate)
+		}
+		if err := em.SetDateRange(startDate.Val, endDate.Val); err != nil {
+			return err
+		}
+		// Clip calendars and calendar_dates to the date range
+		drf, err := filters.NewDateRangeFilter(startDate.Val, endDate.Val)
+		if err != nil {
+			return err
+		}
+		cmd.Options.AddExtension(drf)
+	}
+	if cmd.startTime != "" || cmd.endTime != "" {
+		var startTime, endTime tt.Seconds
+		if cmd.startTime != "" {
+			if startTime, err = tt.NewSecondsFromString(cmd.startTime); err != nil {
+				return fmt.Errorf("invalid start time: %s", cmd.startTime)
+			}
+		}
+		if cmd.endTime != "" {
+			if endTime, err = tt.NewSecondsFromString(cmd.endTime); err != nil {
+				return fmt.Errorf("invalid end time: %s", cmd.endTime)
+			}
+		}
+		if err := em.SetTimeWindow(startTime, endTime); err != nil {
+			return err
+		}
+		// Clip frequencies to the time window
+		twf, err := filters.NewTimeWindowFilter(startTime, endTime)
+		if err != nil {
+			return err
+		}
+		cmd.Options.AddExtension(twf)
+	}
 	for _, eid := range cmd.extractTrips {
 		em.AddInclude("trips.txt", eid)
 	}
@@ -0,0 +1,135 @@
+package cmds
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/interline-io/log"
+	"github.com/interline-io/transitland-lib/internal/feedstate"
+	"github.com/interline-io/transitland-lib/tldb"
+	"github.com/interline-io/transitland-lib/tt"
+	"github.com/spf13/pflag"
+)
+
+// FeedActivateCommand chooses the active feed version of each feed using an activation policy
+type FeedActivateCommand struct {
+	DBURL          string
+	FeedIDs        []string
+	Policy         feedstate.ActivationPolicy
+	DryRun         bool
+	Decisions      []feedstate.ActivationDecision
+	Adapter        tldb.Adapter // allow for mocks
+	date           string
+	maxServiceDrop float64
+	maxErrorRate   float64
+	reportPath     string
+}
+
+func (cmd *FeedActivateCommand) HelpDesc() (string, string) {
+	return "Activate feed versions according to an activation policy", `For each feed, considers the successfully imported feed versions from newest to oldest and activates the first one that passes every enabled rule. Reaching the currently active version keeps it; older versions never replace the active one.
+
+Rules:
+
+- ` + "`--require-started`" + `: the version's service must have started, keeping the previous version active until the new one's start date
+- ` + "`--require-coverage`" + `: the version's service window must cover the evaluation date
+- ` + "`--max-service-drop`" + `: reject versions whose weekly scheduled service drops by more than this fraction compared to the active version
+- ` + "`--max-error-rate`" + `: reject versions whose latest validation report has more errors per row than this fraction, or that have no validation report
+
+Use --dry-run to report the changes without making them, and --report to write the decisions as JSON.`
+}
+
+func (cmd *FeedActivateCommand) HelpArgs() string {
+	return "[flags] [feeds...]"
+}
+
+func (cmd *FeedActivateCommand) AddFlags(fl *pflag.FlagSet) {
+	fl.StringVar(&cmd.DBURL, "dburl", "", "Database URL (default: $TL_DATABASE_URL)")
+	fl.StringVar(&cmd.date, "date", "", "Evaluate the rules for this date, as YYYY-MM-DD (default: today)")
+	fl.BoolVar(&cmd.Policy.RequireStarted, "require-started", false, "Only activate versions whose service has started")
+	fl.BoolVar(&cmd.Policy.RequireCoverage, "require-coverage", false, "Only activate versions whose service window covers the evaluation date")
+	fl.Float64Var(&cmd.maxServiceDrop, "max-service-drop", -1, "Reject versions whose weekly service drops by more than this fraction vs the active version, e.g. 0.3; negative disables")
+	fl.Float64Var(&cmd.maxErrorRate, "max-error-rate", -1, "Reject versions with more validation errors per row than this fraction; negative disables")
+	fl.BoolVar(&cmd.DryRun, "dry-run", false, "Show what would be done without making changes")
+	fl.StringVar(&cmd.reportPath, "report", "", "Write a JSON report of the activation decisions to this file")
+}
+
+// Parse command line flags
+func (cmd *FeedActivateCommand) Parse(args []string) error {
+	if cmd.DBURL == "" {
+		cmd.DBURL = os.Getenv("TL_DATABASE_URL")
+	}
+	cmd.FeedIDs = append(cmd.FeedIDs, args...)
+	if cmd.date != "" {
+		d, err := time.Parse("2006-01-02", cmd.date)
+		if err != nil {
+			return fmt.Errorf("invalid --date: %w", err)
+		}
+		cmd.Policy.Date = d
+	}
+	if cmd.maxServiceDrop >= 0 {
+		cmd.Policy.MaxServiceDrop = tt.NewFloat(cmd.maxServiceDrop)
+	}
+	if cmd.maxErrorRate >= 0 {
+		cmd.Policy.MaxErrorRate = tt.NewFloat(cmd.maxErrorRate)
+	}
+	return nil
+}
+
+// Run the feed activation command
+func (cmd *FeedActivateCommand) Run(ctx context.Context) error {
+	if cmd.Adapter == nil {
+		writer, err := tldb.OpenWriter(cmd.DBURL, true)
+		if err != nil {
+			return fmt.Errorf("failed to open database: %w", err)
+		}
+		cmd.Adapter = writer.Adapter
+		defer writer.Close()
+	}
+	err := cmd.Adapter.Tx(func(atx tldb.Adapter) error {
+		var err error
+		cmd.Decisions, err = runActivationPolicy(ctx, atx, cmd.Policy, cmd.FeedIDs, cmd.DryRun)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if cmd.reportPath != "" {
+		data, err := json.MarshalIndent(cmd.Decisions, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(cmd.reportPath, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runActivationPolicy evaluates the activation policy for the feeds with the given
+// onestop ids, or all feeds, and applies the changes unless dryRun is set.
+func runActivationPolicy(ctx context.Context, atx tldb.Adapter, policy feedstate.ActivationPolicy, feedOnestopIDs []string, dryRun bool) ([]feedstate.ActivationDecision, error) {
+	m := feedstate.NewManager(atx)
+	feedIDs, err := m.GetFeedIDsForOnestopIDs(ctx, feedOnestopIDs)
+	if err != nil {
+		return nil, err
+	}
+	decisions, err := m.PlanActivation(ctx, policy, feedIDs)
+	if err != nil {
+		return nil, err
+	}
+	changed := 0
+	for _, d := range decisions {
+		if d.Changed() {
+			changed++
+		}
+	}
+	log.For(ctx).Info().Int("feeds", len(decisions)).Int("changes", changed).Msg("activation policy evaluated")
+	if dryRun {
+		log.For(ctx).Info().Msg("dry-run; no changes will be made")
+		return decisions, nil
+	}
+	return decisions, m.ApplyActivation(ctx, decisions)
+}
@@ -0,0 +1,362 @@
+package cmds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/interline-io/log"
+	"github.com/interline-io/transitland-lib/dmfr"
+	"github.com/interline-io/transitland-lib/feedmanager"
+	"github.com/interline-io/transitland-lib/fetch"
+	"github.com/interline-io/transitland-lib/internal/clock"
+	"github.com/interline-io/transitland-lib/internal/gbfs"
+	"github.com/interline-io/transitland-lib/stats"
+	"github.com/interline-io/transitland-lib/tldb"
+	"github.com/interline-io/transitland-lib/tt"
+	sq "github.com/irees/squirrel"
+	"github.com/spf13/pflag"
+)
+
+// rtURLTypes are the feed URLs fetched for a gtfs-rt feed.
+var rtURLTypes = []string{"realtime_alerts", "realtime_trip_updates", "realtime_vehicle_positions"}
+
+// FetchDaemonCommand repeatedly fetches the static, RT and GBFS feeds that are due
+// according to their fetch policy. The next due time and consecutive failure count
+// are kept in feed_states. Each due feed is claimed by advancing its next_fetch_at
+// before it is fetched, so several daemons can share a database without fetching
+// the same feed twice.
+type FetchDaemonCommand struct {
+	Options        fetch.StaticFetchOptions
+	SecretsFile    string
+	SecretEnv      []string
+	Workers        int
+	PollInterval   time.Duration
+	Specs          []string
+	StaticInterval time.Duration
+	RTInterval     time.Duration
+	GbfsInterval   time.Duration
+	Backoff        time.Duration
+	MaxBackoff     time.Duration
+	ClaimTimeout   time.Duration
+	Once           bool
+	DBURL          string
+	Adapter        tldb.Adapter // allow for mocks
+	Clock          clock.Clock  // allow for mocks
+}
+
+func (cmd *FetchDaemonCommand) HelpDesc() (string, string) {
+	return "Continuously fetch feeds according to their fetch policies", `Polls the database for feeds that are due and fetches them with bounded concurrency. Static (gtfs) feeds create feed versions as with the ` + "`fetch`" + ` command; gtfs-rt and gbfs feeds are fetched and recorded as feed fetches.
+
+The fetch interval and failure backoff default to the command flags, and can be set per feed with DMFR feed tags:
+
+- ` + "`fetch_interval`" + `: time between successful fetches, e.g. "6h"
+- ` + "`fetch_backoff`" + `: delay before the first retry after a failed fetch; doubles with each further failure
+- ` + "`fetch_max_backoff`" + `: maximum retry delay
+- ` + "`fetch_window`" + `: time of day in which fetches are allowed, e.g. "01:00-05:00"; may wrap midnight
+- ` + "`fetch_window_timezone`" + `: timezone for ` + "`fetch_window`" + `, default UTC
+
+The next due time is stored in feed_states.next_fetch_at; clear it to fetch a feed on the next poll. Due feeds are claimed by advancing next_fetch_at before they are fetched, so several daemons may share a database.`
+}
+
+func (cmd *FetchDaemonCommand) HelpArgs() string {
+	return "[flags]"
+}
+
+func (cmd *FetchDaemonCommand) AddFlags(fl *pflag.FlagSet) {
+	fl.IntVar(&cmd.Workers, "workers", 1, "Worker threads")
+	fl.DurationVar(&cmd.PollInterval, "poll-interval", 1*time.Minute, "Time between checks for due feeds")
+	fl.StringSliceVar(&cmd.Specs, "spec", []string{"gtfs", "gtfs-rt", "gbfs"}, "Feed specs to fetch")
+	fl.DurationVar(&cmd.StaticInterval, "static-interval", 24*time.Hour, "Default fetch interval for gtfs feeds")
+	fl.DurationVar(&cmd.RTInterval, "rt-interval", 1*time.Minute, "Default fetch interval for gtfs-rt feeds")
+	fl.DurationVar(&cmd.GbfsInterval, "gbfs-interval", 5*time.Minute, "Default fetch interval for gbfs feeds")
+	fl.DurationVar(&cmd.Backoff, "backoff", 5*time.Minute, "Default delay before retrying a failed fetch; doubles with each consecutive failure")
+	fl.DurationVar(&cmd.MaxBackoff, "max-backoff", 6*time.Hour, "Default maximum retry delay")
+	fl.DurationVar(&cmd.ClaimTimeout, "claim-timeout", 1*time.Hour, "Time a claimed feed is held before another poll may fetch it again, e.g. after a crash")
+	fl.BoolVar(&cmd.Once, "once", false, "Fetch due feeds once and exit")
+	fl.StringArrayVar(&cmd.SecretEnv, "secret-env", nil, "Specify secret from environment variable as feed_id:ENV_VAR or file.json:ENV_VAR")
+	fl.StringVar(&cmd.DBURL, "dburl", "", "Database URL (default: $TL_DATABASE_URL)")
+	fl.StringVar(&cmd.SecretsFile, "secrets", "", "Path to DMFR Secrets file")
+	// StaticFetchOptions
+	fl.BoolVar(&cmd.Options.AllowFTPFetch, "allow-ftp-fetch", false, "Allow fetching from FTP urls")
+	fl.BoolVar(&cmd.Options.AllowLocalFetch, "allow-local-fetch", false, "Allow fetching from filesystem directories/zip files")
+	fl.BoolVar(&cmd.Options.AllowS3Fetch, "allow-s3-fetch", false, "Allow fetching from S3 urls")
+	fl.BoolVar(&cmd.Options.AllowHTTPFetchUnfiltered, "allow-http-fetch-unfiltered", false, "Disable SSRF protection for http(s) fetches; allow private/loopback/metadata IPs (use only for local CLI runs)")
+	fl.BoolVar(&cmd.Options.SaveValidationReport, "validation-report", false, "Save validation report")
+	fl.BoolVar(&cmd.Options.StrictValidation, "strict", false, "Reject feeds with validation errors")
+	fl.BoolVar(&cmd.Options.IgnoreConditional, "ignore-conditional", false, "Always download the full feed; do not send If-None-Match/If-Modified-Since from the last successful fetch")
+	fl.BoolVar(&cmd.Options.AllowPartial, "allow-partial", false, "Allow partial feeds missing normally-required files (agency, routes, trips, stop_times, calendar)")
+	fl.StringVar(&cmd.Options.Storage, "storage", ".", "Storage destination; can be s3://... az://... or path to a directory")
+	fl.StringVar(&cmd.Options.ValidationReportStorage, "validation-report-storage", "", "Storage path for saving validation report JSON")
+}
+
+func (cmd *FetchDaemonCommand) Parse(args []string) error {
+	if cmd.DBURL == "" {
+		cmd.DBURL = os.Getenv("TL_DATABASE_URL")
+	}
+	if len(args) > 0 {
+		return errors.New("fetch-daemon does not accept feed arguments; use feed tags to control fetching")
+	}
+	for _, spec := range cmd.Specs {
+		if spec != "gtfs" && spec != "gtfs-rt" && spec != "gbfs" {
+			return fmt.Errorf("unsupported --spec '%s'", spec)
+		}
+	}
+	if cmd.PollInterval <= 0 {
+		return errors.New("--poll-interval must be positive")
+	}
+	if cmd.ClaimTimeout <= 0 {
+		return errors.New("--claim-timeout must be positive")
+	}
+	return nil
+}
+
+// Run executes this command.
+func (cmd *FetchDaemonCommand) Run(ctx context.Context) error {
+	if cmd.Workers < 1 {
+		cmd.Workers = 1
+	}
+	if cmd.SecretsFile != "" {
+		r, err := dmfr.LoadAndParseRegistry(cmd.SecretsFile)
+		if err != nil {
+			return err
+		}
+		cmd.Options.Secrets = r.Secrets
+	}
+	for _, se := range cmd.SecretEnv {
+		secret, err := parseSecretEnv(se)
+		if err != nil {
+			return err
+		}
+		cmd.Options.Secrets = append(cmd.Options.Secrets, secret)
+	}
+	if cmd.Adapter == nil {
+		writer, err := tldb.OpenWriter(cmd.DBURL, true)
+		if err != nil {
+			return err
+		}
+		cmd.Adapter = writer.Adapter
+		defer writer.Close()
+	}
+	for {
+		if _, err := cmd.FetchDue(ctx); err != nil {
+			if cmd.Once {
+				return err
+			}
+			log.For(ctx).Error().Err(err).Msg("fetch-daemon: error fetching due feeds")
+		}
+		if cmd.Once {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(cmd.PollInterval):
+		}
+	}
+}
+
+// FetchDue claims and fetches every feed that is currently due and schedules its next fetch.
+// Returns the number of feeds fetched.
+func (cmd *FetchDaemonCommand) FetchDue(ctx context.Context) (int, error) {
+	now := cmd.now()
+	q := cmd.Adapter.Sqrl().
+		Select("current_feeds.*").
+		From("current_feeds").
+		LeftJoin("feed_states ON feed_states.feed_id = current_feeds.id").
+		Where("current_feeds.deleted_at IS NULL").
+		Where(sq.Eq{"current_feeds.spec": cmd.Specs}).
+		Where(sq.Or{sq.Eq{"feed_states.next_fetch_at": nil}, sq.LtOrEq{"feed_states.next_fetch_at": now}}).
+		OrderBy("current_feeds.id")
+	qstr, qargs, err := q.ToSql()
+	if err != nil {
+		return 0, err
+	}
+	var feeds []dmfr.Feed
+	if err := cmd.Adapter.Select(ctx, &feeds, qstr, qargs...); err != nil {
+		return 0, err
+	}
+
+	// Skip feeds with nothing to fetch, and defer feeds outside their fetch window
+	var due []daemonTask
+	for _, feed := range feeds {
+		if len(daemonFeedURLs(&feed)) == 0 {
+			continue
+		}
+		fs, err := stats.EnsureFeedState(ctx, cmd.Adapter, feed.ID)
+		if err != nil {
+			return 0, err
+		}
+		policy, err := fetch.PolicyFromFeed(&feed, cmd.defaultPolicy(feed.Spec))
+		if err != nil {
+			log.For(ctx).Error().Err(err).Str("feed_onestop_id", feed.FeedID).Msg("fetch-daemon: invalid fetch policy; using defaults")
+			policy = cmd.defaultPolicy(feed.Spec)
+		}
+		if policy.Window != nil && !policy.Window.Contains(now) {
+			if _, err := cmd.claimFeed(ctx, feed.ID, now, policy.Window.NextOpen(now)); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		// Hold the feed until the fetch completes; skip it if another daemon got there first
+		claimed, err := cmd.claimFeed(ctx, feed.ID, now, now.Add(cmd.claimTimeout()))
+		if err != nil {
+			return 0, err
+		} else if !claimed {
+			continue
+		}
+		due = append(due, daemonTask{feed: feed, state: fs, policy: policy})
+	}
+	if len(due) == 0 {
+		return 0, nil
+	}
+
+	log.For(ctx).Info().Msgf("fetch-daemon: fetching %d due feeds", len(due))
+	tasks := make(chan daemonTask, len(due))
+	for _, task := range due {
+		tasks <- task
+	}
+	close(tasks)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var updateErr error
+	failed := 0
+	for w := 0; w < cmd.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range tasks {
+				fetchErr := cmd.fetchFeed(ctx, &task.feed, cmd.now())
+				fs := task.state
+				if fetchErr != nil {
+					fs.FetchFailures++
+					log.For(ctx).Error().Err(fetchErr).Str("feed_onestop_id", task.feed.FeedID).Int("failures", fs.FetchFailures).Msg("fetch-daemon: fetch failed")
+				} else {
+					fs.FetchFailures = 0
+				}
+				fs.NextFetchAt = tt.NewTime(task.policy.NextFetch(cmd.now(), fs.FetchFailures))
+				err := cmd.Adapter.Update(ctx, &fs, "next_fetch_at", "fetch_failures")
+				mu.Lock()
+				if fetchErr != nil {
+					failed++
+				}
+				if err != nil && updateErr == nil {
+					updateErr = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	log.For(ctx).Info().Int("fetched", len(due)).Int("errors", failed).Msg("fetch-daemon: fetch complete")
+	return len(due), updateErr
+}
+
+// claimFeed moves the feed's next_fetch_at to until if the feed is still due at now.
+// The check and update are a single statement, so only one concurrent caller succeeds.
+func (cmd *FetchDaemonCommand) claimFeed(ctx context.Context, feedID int, now time.Time, until time.Time) (bool, error) {
+	res, err := cmd.Adapter.Sqrl().
+		Update("feed_states").
+		Set("next_fetch_at", until).
+		Where(sq.Eq{"feed_id": feedID}).
+		Where(sq.Or{sq.Eq{"next_fetch_at": nil}, sq.LtOrEq{"next_fetch_at": now}}).
+		ExecContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (cmd *FetchDaemonCommand) claimTimeout() time.Duration {
+	if cmd.ClaimTimeout > 0 {
+		return cmd.ClaimTimeout
+	}
+	return 1 * time.Hour
+}
+
+func (cmd *FetchDaemonCommand) now() time.Time {
+	if cmd.Clock != nil {
+		return cmd.Clock.Now()
+	}
+	return time.Now().UTC()
+}
+
+type daemonTask struct {
+	feed   dmfr.Feed
+	state  dmfr.FeedState
+	policy fetch.Policy
+}
+
+func (cmd *FetchDaemonCommand) defaultPolicy(spec string) fetch.Policy {
+	p := fetch.Policy{Backoff: cmd.Backoff, MaxBackoff: cmd.MaxBackoff}
+	switch spec {
+	case "gtfs-rt":
+		p.Interval = cmd.RTInterval
+	case "gbfs":
+		p.Interval = cmd.GbfsInterval
+	default:
+		p.Interval = cmd.StaticInterval
+	}
+	return p
+}
+
+// fetchFeed fetches every URL of the feed's spec, returning the first fatal or fetch error.
+func (cmd *FetchDaemonCommand) fetchFeed(ctx context.Context, feed *dmfr.Feed, fetchedAt time.Time) error {
+	fm := feedmanager.NewDBFeedManager(cmd.Adapter)
+	var errs []error
+	for urlType, url := range daemonFeedURLs(feed) {
+		opts := cmd.Options.Options
+		opts.FeedID = feed.ID
+		opts.FeedURL = url
+		opts.URLType = urlType
+		opts.FetchedAt = fetchedAt
+		switch feed.Spec {
+		case "gtfs":
+			sopts := cmd.Options
+			sopts.Options = opts
+			result, err := fetch.StaticFetch(ctx, fm, sopts)
+			errs = append(errs, err, result.FetchError)
+		case "gtfs-rt":
+			result, err := fetch.RTFetch(ctx, fm, fetch.RTFetchOptions{Options: opts})
+			errs = append(errs, err, result.FetchError)
+		case "gbfs":
+			_, result, err := gbfs.Fetch(ctx, cmd.Adapter, gbfs.Options{Options: opts})
+			errs = append(errs, err, result.FetchError)
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// daemonFeedURLs returns the URLs fetched for a feed, keyed by URL type.
+func daemonFeedURLs(feed *dmfr.Feed) map[string]string {
+	ret := map[string]string{}
+	switch feed.Spec {
+	case "gtfs":
+		if feed.URLs.StaticCurrent != "" {
+			ret["static_current"] = feed.URLs.StaticCurrent
+		}
+	case "gtfs-rt":
+		for _, urlType := range rtURLTypes {
+			if url, _ := urlForType(feed.URLs, urlType); url != "" {
+				ret[urlType] = url
+			}
+		}
+	case "gbfs":
+		if feed.URLs.GbfsAutoDiscovery != "" {
+			ret["gbfs_auto_discovery"] = feed.URLs.GbfsAutoDiscovery
+		}
+	}
+	return ret
+}
@@ -0,0 +1,138 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/interline-io/transitland-lib/dmfr"
+	"github.com/interline-io/transitland-lib/internal/clock"
+	"github.com/interline-io/transitland-lib/internal/testdb"
+	"github.com/interline-io/transitland-lib/internal/testpath"
+	"github.com/interline-io/transitland-lib/tt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchDaemonCommand(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, err := os.ReadFile(testpath.RelPath(filepath.Join("testdata", r.URL.Path)))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Write(buf)
+	}))
+	defer ts.Close()
+
+	ctx := context.TODO()
+	adapter := testdb.TempSqliteAdapter()
+	f200 := dmfr.Feed{FeedID: "f-200", Spec: "gtfs", URLs: dmfr.FeedUrls{StaticCurrent: fmt.Sprintf("%s/gtfs-examples/example.zip", ts.URL)}}
+	f404 := dmfr.Feed{FeedID: "f-404", Spec: "gtfs", URLs: dmfr.FeedUrls{StaticCurrent: fmt.Sprintf("%s/404", ts.URL)}}
+	fWindow := dmfr.Feed{FeedID: "f-window", Spec: "gtfs", URLs: dmfr.FeedUrls{StaticCurrent: fmt.Sprintf("%s/gtfs-examples/example.zip", ts.URL)}}
+	fWindow.Tags = tt.Tags{Option: tt.NewOption(map[string]string{"fetch_window": "02:00-04:00"})}
+	fNoURL := dmfr.Feed{FeedID: "f-no-url", Spec: "gtfs"}
+	for _, feed := range []*dmfr.Feed{&f200, &f404, &fWindow, &fNoURL} {
+		feed.ID = testdb.ShouldInsert(t, adapter, feed)
+	}
+
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	clk := &clock.Mock{T: now}
+	c := FetchDaemonCommand{
+		Adapter:        adapter,
+		Clock:          clk,
+		Specs:          []string{"gtfs"},
+		StaticInterval: 24 * time.Hour,
+		Backoff:        5 * time.Minute,
+		MaxBackoff:     time.Hour,
+		Workers:        2,
+	}
+	c.Options.Storage = t.TempDir()
+	c.Options.AllowHTTPFetchUnfiltered = true
+
+	getState := func(feedID int) dmfr.FeedState {
+		fs := dmfr.FeedState{}
+		testdb.ShouldGet(t, adapter, &fs, "SELECT * FROM feed_states WHERE feed_id = ?", feedID)
+		return fs
+	}
+
+	t.Run("first poll", func(t *testing.T) {
+		n, err := c.FetchDue(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, 2, n)
+		fs := getState(f200.ID)
+		assert.Equal(t, 0, fs.FetchFailures)
+		assert.True(t, now.Add(24*time.Hour).Equal(fs.NextFetchAt.Val))
+		fs = getState(f404.ID)
+		assert.Equal(t, 1, fs.FetchFailures)
+		assert.True(t, now.Add(5*time.Minute).Equal(fs.NextFetchAt.Val))
+		fs = getState(fWindow.ID)
+		assert.Equal(t, 0, fs.FetchFailures)
+		assert.True(t, time.Date(2026, 3, 3, 2, 0, 0, 0, time.UTC).Equal(fs.NextFetchAt.Val))
+		fvs := []dmfr.FeedVersion{}
+		testdb.ShouldSelect(t, adapter, &fvs, "SELECT * FROM feed_versions")
+		assert.Equal(t, 1, len(fvs))
+	})
+	t.Run("nothing due", func(t *testing.T) {
+		clk.T = now.Add(time.Minute)
+		n, err := c.FetchDue(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, 0, n)
+	})
+	t.Run("retry with backoff", func(t *testing.T) {
+		retryAt := now.Add(5 * time.Minute)
+		clk.T = retryAt
+		n, err := c.FetchDue(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, 1, n)
+		fs := getState(f404.ID)
+		assert.Equal(t, 2, fs.FetchFailures)
+		assert.True(t, retryAt.Add(10*time.Minute).Equal(fs.NextFetchAt.Val))
+	})
+	t.Run("fetch in window", func(t *testing.T) {
+		windowAt := time.Date(2026, 3, 3, 2, 0, 0, 0, time.UTC)
+		clk.T = windowAt
+		n, err := c.FetchDue(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, 2, n)
+		fs := getState(fWindow.ID)
+		assert.Equal(t, 0, fs.FetchFailures)
+		assert.True(t, time.Date(2026, 3, 4, 2, 0, 0, 0, time.UTC).Equal(fs.NextFetchAt.Val))
+	})
+	t.Run("claimed feeds are skipped", func(t *testing.T) {
+		// Only f-404 is due, after its retry at 02:20
+		claimAt := time.Date(2026, 3, 3, 3, 0, 0, 0, time.UTC)
+		claimed, err := c.claimFeed(ctx, f404.ID, claimAt, claimAt.Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.True(t, claimed)
+		// A second claim while the first is held fails
+		claimed, err = c.claimFeed(ctx, f404.ID, claimAt, claimAt.Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.False(t, claimed)
+		clk.T = claimAt
+		n, err := c.FetchDue(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, 0, n)
+		fs := getState(f404.ID)
+		assert.Equal(t, 3, fs.FetchFailures)
+		assert.True(t, claimAt.Add(time.Hour).Equal(fs.NextFetchAt.Val))
+	})
+}
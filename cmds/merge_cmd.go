@@ -2,12 +2,15 @@ package cmds
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"os"
 
+	"github.com/interline-io/log"
 	"github.com/interline-io/transitland-lib/adapters"
-	"github.com/interline-io/transitland-lib/adapters/multireader"
 	"github.com/interline-io/transitland-lib/copier"
 	"github.com/interline-io/transitland-lib/ext"
+	"github.com/interline-io/transitland-lib/merge"
 	"github.com/interline-io/transitland-lib/tlcli"
 	"github.com/interline-io/transitland-lib/tt"
 	"github.com/spf13/pflag"
@@ -15,13 +18,17 @@ import (
 
 // MergeCommand merges multiple GTFS feeds into one.
 type MergeCommand struct {
-	Options     copier.Options
-	readerPaths []string
-	writerPath  string
+	Options      copier.Options
+	MergeOptions merge.Options
+	reportPath   string
+	readerPaths  []string
+	writerPath   string
 }
 
 func (cmd *MergeCommand) HelpDesc() (string, string) {
-	return "Merge multiple GTFS feeds", ""
+	a := "Merge multiple GTFS feeds"
+	b := `By default, when an entity ID is used by more than one feed, only the entity from the first feed is kept. Use --prefix-conflicts to instead rename conflicting IDs in later feeds, and the --dedup options to combine equivalent agencies, stops, shapes, and calendars. Use --report to write a JSON file listing every rename and merge applied.`
+	return a, b
 }
 
 func (cmd *MergeCommand) HelpArgs() string {
@@ -29,6 +36,13 @@ func (cmd *MergeCommand) HelpArgs() string {
 }
 
 func (cmd *MergeCommand) AddFlags(fl *pflag.FlagSet) {
+	fl.BoolVar(&cmd.MergeOptions.PrefixConflicts, "prefix-conflicts", false, "Prefix entity IDs that conflict with an ID from an earlier feed")
+	fl.StringArrayVar(&cmd.MergeOptions.Prefixes, "prefix", nil, "Prefix for conflicting IDs, one per reader in order; default is the reader position, e.g. '2_'")
+	fl.BoolVar(&cmd.MergeOptions.DedupAgencies, "dedup-agencies", false, "Merge agencies with identical values other than agency_id")
+	fl.Float64Var(&cmd.MergeOptions.DedupStopsDistance, "dedup-stops-distance", 0, "Merge stops with the same name and location_type within this distance, in meters")
+	fl.BoolVar(&cmd.MergeOptions.DedupShapes, "dedup-shapes", false, "Merge shapes with identical points")
+	fl.BoolVar(&cmd.MergeOptions.DedupCalendars, "dedup-calendars", false, "Merge calendars with identical service days, dates, and exceptions")
+	fl.StringVar(&cmd.reportPath, "report", "", "Write a JSON report of renamed and merged entities to this file")
 }
 
 func (cmd *MergeCommand) Parse(args []string) error {
@@ -52,7 +66,26 @@ func (cmd *MergeCommand) Run(ctx context.Context) error {
 		readers = append(readers, reader)
 	}
 
-	reader := multireader.NewReader(readers...)
+	// Determine renames and merges
+	plan, err := merge.NewPlan(readers, cmd.MergeOptions)
+	if err != nil {
+		return err
+	}
+	log.For(ctx).Info().Msgf("Merge plan: %d entities renamed, %d entities merged", len(plan.Report.Renamed), len(plan.Report.Merged))
+	if cmd.reportPath != "" {
+		data, err := json.MarshalIndent(plan.Report, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(cmd.reportPath, data, 0644); err != nil {
+			return err
+		}
+	}
+
+	reader, err := plan.NewReader(readers...)
+	if err != nil {
+		return err
+	}
 	if err := reader.Open(); err != nil {
 		return err
 	}
@@ -2,10 +2,14 @@ package cmds
 
 import (
 	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/interline-io/transitland-lib/ext"
 	"github.com/interline-io/transitland-lib/internal/testreader"
+	"github.com/interline-io/transitland-lib/merge"
 	"github.com/interline-io/transitland-lib/tt"
 	"github.com/stretchr/testify/assert"
 )
@@ -48,4 +52,50 @@ func TestMerge(t *testing.T) {
 			t.Fatal("no checks were performed - make sure both example feeds in test_feeds.go have entity counts set")
 		}
 	})
+	t.Run("prefix and dedup", func(t *testing.T) {
+		f1 := testreader.ExampleFeedBART
+		cmd := MergeCommand{}
+		cmd.MergeOptions = merge.Options{
+			PrefixConflicts:    true,
+			DedupAgencies:      true,
+			DedupStopsDistance: 1.0,
+			DedupShapes:        true,
+			DedupCalendars:     true,
+		}
+		tdir := t.TempDir()
+		cmd.reportPath = filepath.Join(t.TempDir(), "report.json")
+		if err := cmd.Parse([]string{tdir, f1.URL, f1.URL}); err != nil {
+			t.Fatal(err)
+		}
+		if err := cmd.Run(ctx); err != nil {
+			t.Fatal(err)
+		}
+		outReader, err := ext.OpenReader(tdir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entCount := map[string]int{}
+		testreader.AllEntities(outReader, func(ent tt.Entity) {
+			entCount[ent.Filename()] += 1
+		})
+		assert.Equal(t, 1, entCount["agency.txt"])
+		assert.Equal(t, f1.Counts["stops.txt"], entCount["stops.txt"])
+		assert.Equal(t, f1.Counts["calendar.txt"], entCount["calendar.txt"])
+		assert.Equal(t, f1.Counts["shapes.txt"], entCount["shapes.txt"])
+		assert.Equal(t, f1.Counts["routes.txt"]*2, entCount["routes.txt"])
+		assert.Equal(t, f1.Counts["trips.txt"]*2, entCount["trips.txt"])
+		assert.Equal(t, f1.Counts["stop_times.txt"]*2, entCount["stop_times.txt"])
+
+		// Check report
+		data, err := os.ReadFile(cmd.reportPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		report := merge.Report{}
+		if err := json.Unmarshal(data, &report); err != nil {
+			t.Fatal(err)
+		}
+		assert.Contains(t, report.Renamed, merge.RenamedEntity{Feed: f1.URL, Filename: "routes.txt", EntityID: "01", NewEntityID: "2_01"})
+		assert.Contains(t, report.Merged, merge.MergedEntity{Feed: f1.URL, Filename: "stops.txt", EntityID: "12TH", IntoFeed: f1.URL, IntoEntityID: "12TH"})
+	})
 }
@@ -0,0 +1,134 @@
+package cmds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/interline-io/log"
+	"github.com/interline-io/transitland-lib/internal/rtrecorder"
+	"github.com/interline-io/transitland-lib/request"
+	"github.com/interline-io/transitland-lib/rt"
+	"github.com/interline-io/transitland-lib/tlcli"
+	"github.com/interline-io/transitland-lib/tldb"
+	"github.com/spf13/pflag"
+)
+
+// RTRecordCommand records GTFS Realtime observations as stop observations.
+type RTRecordCommand struct {
+	DBURL         string
+	FeedOnestopID string
+	FeedVersionID int
+	SourceID      string
+	Interval      time.Duration
+	URLs          []string
+	Adapter       tldb.Adapter // allow for mocks
+}
+
+func (cmd *RTRecordCommand) HelpDesc() (string, string) {
+	return "Record GTFS Realtime observations", `Matches GTFS Realtime trip updates and vehicle positions to the static schedule of a feed version and records the observed arrival and departure times at each stop in ext_performance_stop_observations.
+
+A stop time update is recorded once its time has passed; later times are predictions. A vehicle position is recorded while the vehicle is stopped at a stop, and successive positions extend the observed dwell. Observations of a stop already recorded for the same trip and service date update the existing row, so the same feed can be polled repeatedly with --interval.
+
+The static feed is selected with --feed, which uses its active feed version, or with --fvid.`
+}
+
+func (cmd *RTRecordCommand) HelpExample() string {
+	return `% {{.ParentCommand}} {{.Command}} --feed f-9q9-bart "https://api.bart.gov/gtfsrt/tripupdate.aspx"
+% {{.ParentCommand}} {{.Command}} --fvid 123 --interval 30s trip_updates.pb vehicle_positions.pb`
+}
+
+func (cmd *RTRecordCommand) HelpArgs() string {
+	return "[flags] <realtime urls...>"
+}
+
+func (cmd *RTRecordCommand) AddFlags(fl *pflag.FlagSet) {
+	fl.StringVar(&cmd.DBURL, "dburl", "", "Database URL (default: $TL_DATABASE_URL)")
+	fl.StringVar(&cmd.FeedOnestopID, "feed", "", "Static feed onestop_id; uses its active feed version")
+	fl.IntVar(&cmd.FeedVersionID, "fvid", 0, "Static feed version ID")
+	fl.StringVar(&cmd.SourceID, "source-id", "", "Identifier of the realtime source saved with each observation (default: the realtime url)")
+	fl.DurationVar(&cmd.Interval, "interval", 0, "Poll the realtime urls at this interval; 0 records once")
+}
+
+// Parse command line flags
+func (cmd *RTRecordCommand) Parse(args []string) error {
+	if cmd.DBURL == "" {
+		cmd.DBURL = os.Getenv("TL_DATABASE_URL")
+	}
+	fl := tlcli.NewNArgs(args)
+	if fl.NArg() == 0 {
+		return errors.New("requires at least one realtime url")
+	}
+	cmd.URLs = fl.Args()
+	if (cmd.FeedOnestopID == "") == (cmd.FeedVersionID == 0) {
+		return errors.New("requires one of --feed or --fvid")
+	}
+	return nil
+}
+
+// Run the record command
+func (cmd *RTRecordCommand) Run(ctx context.Context) error {
+	if cmd.Adapter == nil {
+		writer, err := tldb.OpenWriter(cmd.DBURL, true)
+		if err != nil {
+			return fmt.Errorf("failed to open database: %w", err)
+		}
+		cmd.Adapter = writer.Adapter
+		defer writer.Close()
+	}
+	fvid := cmd.FeedVersionID
+	if cmd.FeedOnestopID != "" {
+		var err error
+		fvid, err = rtrecorder.FeedVersionForFeed(ctx, cmd.Adapter.DBX(), cmd.FeedOnestopID)
+		if err != nil {
+			return err
+		}
+	}
+	r := rtrecorder.NewRecorder(cmd.Adapter)
+	if cmd.Interval <= 0 {
+		return cmd.recordAll(ctx, r, fvid)
+	}
+	ticker := time.NewTicker(cmd.Interval)
+	defer ticker.Stop()
+	for {
+		// Polling continues after errors, e.g. a temporarily unavailable feed
+		if err := cmd.recordAll(ctx, r, fvid); err != nil {
+			log.For(ctx).Error().Err(err).Msg("failed to record realtime observations")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (cmd *RTRecordCommand) recordAll(ctx context.Context, r *rtrecorder.Recorder, fvid int) error {
+	var errs []error
+	for _, url := range cmd.URLs {
+		msg, err := rt.ReadURL(ctx, url, request.WithAllowLocal)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read '%s': %w", url, err))
+			continue
+		}
+		sourceID := cmd.SourceID
+		if sourceID == "" {
+			sourceID = url
+		}
+		result, err := r.Record(ctx, fvid, sourceID, msg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to record '%s': %w", url, err))
+			continue
+		}
+		log.For(ctx).Info().
+			Str("url", url).
+			Int("feed_version_id", result.FeedVersionID).
+			Int("observations", result.Observations).
+			Int("inserted", result.Inserted).
+			Int("updated", result.Updated).
+			Msg("recorded realtime observations")
+	}
+	return errors.Join(errs...)
+}
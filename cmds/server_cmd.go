@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/pprof"
 	"os"
+	"strings"
 	"time"
 	_ "time/tzdata"
 
@@ -26,12 +27,15 @@ import (
 	"github.com/interline-io/transitland-lib/tldb"
 
 	"github.com/interline-io/transitland-lib/server/finders/actions"
+	"github.com/interline-io/transitland-lib/server/finders/artifactstore"
 	"github.com/interline-io/transitland-lib/server/finders/dbfinder"
 	"github.com/interline-io/transitland-lib/server/finders/gbfsfinder"
 	"github.com/interline-io/transitland-lib/server/finders/rtfinder"
 	"github.com/interline-io/transitland-lib/server/gql"
 	"github.com/interline-io/transitland-lib/server/jobs"
 	localjobs "github.com/interline-io/transitland-lib/server/jobs/local"
+	pgjobs "github.com/interline-io/transitland-lib/server/jobs/postgres"
+	"github.com/interline-io/transitland-lib/server/jobserver"
 	"github.com/interline-io/transitland-lib/server/model"
 	"github.com/interline-io/transitland-lib/server/playground"
 	"github.com/interline-io/transitland-lib/server/rest"
@@ -42,6 +46,8 @@ import (
 
 	// Import routers
 	_ "github.com/interline-io/transitland-lib/server/directions/awsrouter"
+	_ "github.com/interline-io/transitland-lib/server/directions/csarouter"
+	_ "github.com/interline-io/transitland-lib/server/directions/flexrouter"
 	_ "github.com/interline-io/transitland-lib/server/directions/linerouter"
 	_ "github.com/interline-io/transitland-lib/server/directions/tlrouter"
 	_ "github.com/interline-io/transitland-lib/server/directions/valhalla"
@@ -62,10 +68,19 @@ type ServerCommand struct {
 	SecretsFile             string
 	Storage                 string
 	RTStorage               string
+	ArtifactStorage         string
+	JobsPrefix              string
 	DBURL                   string
 	RedisURL                string
+	JobBackend              string
 	MaxRadius               float64
+	RTMerge                 []string
+	RTMergeMaxAge           time.Duration
+	RTRecord                []string
+	RTRecordInterval        time.Duration
 	secrets                 []dmfr.Secret
+	rtMergeTargets          []rtfinder.MergeTarget
+	rtRecordJobs            []workers.RTRecordWorker
 }
 
 func (cmd *ServerCommand) HelpDesc() (string, string) {
@@ -81,6 +96,9 @@ func (cmd *ServerCommand) AddFlags(fl *pflag.FlagSet) {
 	fl.StringVar(&cmd.RedisURL, "redisurl", "", "Redis URL (default: $TL_REDIS_URL)")
 	fl.StringVar(&cmd.Storage, "storage", "", "Static storage backend")
 	fl.StringVar(&cmd.RTStorage, "rt-storage", "", "RT storage backend")
+	fl.StringVar(&cmd.ArtifactStorage, "artifact-storage", "", "Job artifact storage backend (validation reports, exports); background jobs that produce files fail without it")
+	fl.StringVar(&cmd.JobsPrefix, "jobs-prefix", "", "Public URL prefix of the /jobs mount for generated artifact links")
+	fl.StringVar(&cmd.JobBackend, "job-backend", "local", "Job queue backend: 'local' (in-process) or 'postgres' (durable, shared by all replicas using --dburl)")
 	fl.BoolVar(&cmd.ValidateLargeFiles, "validate-large-files", false, "Allow validation of large files")
 	fl.StringVar(&cmd.RestPrefix, "rest-prefix", "", "Public URL prefix for generated links (e.g. https://transit.land/api/v2)")
 	fl.StringVar(&cmd.Port, "port", "8080", "")
@@ -92,6 +110,10 @@ func (cmd *ServerCommand) AddFlags(fl *pflag.FlagSet) {
 	fl.IntVar(&cmd.LoaderStopTimeBatchSize, "loader-stop-time-batch-size", 1, "GraphQL Loader batch size for StopTimes")
 	fl.Float64Var(&cmd.MaxRadius, "max-radius", 100_000, "Maximum radius for nearby stops")
 	fl.BoolVar(&cmd.UseMaterialized, "use-materialized", false, "Use materialized views for active entities")
+	fl.StringArrayVar(&cmd.RTMerge, "rt-merge", nil, "Publish the merged realtime data of several feeds as target=source1,source2; may be specified multiple times")
+	fl.DurationVar(&cmd.RTMergeMaxAge, "rt-merge-max-age", 5*time.Minute, "Exclude --rt-merge source messages with an older header timestamp; 0 disables")
+	fl.StringArrayVar(&cmd.RTRecord, "rt-record", nil, "Periodically record the realtime data of a static feed as stop observations, as static or static=rt1,rt2; may be specified multiple times")
+	fl.DurationVar(&cmd.RTRecordInterval, "rt-record-interval", time.Minute, "Interval between --rt-record jobs")
 	fl.BoolVar(&cmd.UseGeohashFilter, "use-geohash-filter", false, "Filter feed/feed_version bbox queries by precomputed stop geohash cells (requires populated tl_feed_version_geohashes)")
 }
 
@@ -114,6 +136,32 @@ func (cmd *ServerCommand) Parse(args []string) error {
 		secrets = rr.Secrets
 	}
 	cmd.secrets = secrets
+	for _, v := range cmd.RTMerge {
+		target, err := rtfinder.ParseMergeTarget(v)
+		if err != nil {
+			return err
+		}
+		cmd.rtMergeTargets = append(cmd.rtMergeTargets, target)
+	}
+	for _, v := range cmd.RTRecord {
+		static, rtFeeds, _ := strings.Cut(v, "=")
+		w := workers.RTRecordWorker{FeedOnestopID: strings.TrimSpace(static)}
+		if w.FeedOnestopID == "" {
+			return fmt.Errorf("invalid rt-record feed '%s', expected static or static=rt1,rt2", v)
+		}
+		for _, rtFeed := range strings.Split(rtFeeds, ",") {
+			if rtFeed = strings.TrimSpace(rtFeed); rtFeed != "" {
+				w.RTFeedOnestopIDs = append(w.RTFeedOnestopIDs, rtFeed)
+			}
+		}
+		cmd.rtRecordJobs = append(cmd.rtRecordJobs, w)
+	}
+	if len(cmd.rtRecordJobs) > 0 && cmd.RTRecordInterval <= 0 {
+		return errors.New("rt-record-interval must be positive")
+	}
+	if cmd.JobBackend != "local" && cmd.JobBackend != "postgres" {
+		return errors.New("job-backend must be 'local' or 'postgres'")
+	}
 	return nil
 }
 
@@ -157,6 +205,35 @@ func (cmd *ServerCommand) Run(ctx context.Context) error {
 	rtf := rtfinder.NewFinder(rtStore, db)
 	defer rtf.Close()
 	var rtFinder model.RTFinder = rtf
+	if len(cmd.rtMergeTargets) > 0 {
+		// Merged data is served under the target feed's license
+		licenses := map[string]dmfr.FeedLicense{}
+		adminCtx := model.WithPermFilter(ctx, &model.PermFilter{IsGlobalAdmin: true})
+		for _, t := range cmd.rtMergeTargets {
+			for _, fsid := range append([]string{t.Target}, t.Sources...) {
+				feeds, err := dbFinder.FindFeeds(adminCtx, nil, nil, nil, &model.FeedFilter{OnestopID: &fsid})
+				if err != nil {
+					return err
+				}
+				if len(feeds) > 0 {
+					licenses[fsid] = feeds[0].License
+				}
+			}
+		}
+		if err := rtfinder.CheckMergeLicenses(cmd.rtMergeTargets, licenses); err != nil {
+			return err
+		}
+		merger, err := rtfinder.NewMerger(rtf, cmd.rtMergeTargets)
+		if err != nil {
+			return err
+		}
+		merger.MaxAge = cmd.RTMergeMaxAge
+		go func() {
+			if err := merger.Run(ctx); err != nil {
+				log.For(ctx).Error().Err(err).Msg("rt merger stopped")
+			}
+		}()
+	}
 	var gbfsFinder model.GbfsFinder = gbfsfinder.NewFinder(gbfsStore)
 
 	var actionFinder model.Actions = &actions.Actions{}
@@ -165,20 +242,33 @@ func (cmd *ServerCommand) Run(ctx context.Context) error {
 	// (entity CRUD, DB readers, fetch/import bookkeeping). See model.Config.
 	dbAdapter := postgres.NewPostgresAdapterFromDBX(db)
 
-	// In-process job backend so the import/unimport mutations run on a job
-	// context that outlives the request. Production deployments register their
-	// own workers; these are the reference implementations.
+	// Job backend so the import/unimport mutations and async fetch, validation
+	// and export requests run on a job context that outlives the request.
+	// Production deployments register their own workers; these are the
+	// reference implementations, each on a queue named for its kind.
 	jobRunner := jobs.NewRunner()
-	if err := jobRunner.Register(func() jobs.Worker { return &workers.FeedVersionImportWorker{} }); err != nil {
-		return err
+	var jobKinds []string
+	for _, fn := range workers.All() {
+		if err := jobRunner.Register(fn); err != nil {
+			return err
+		}
+		jobKinds = append(jobKinds, fn().Kind())
 	}
-	if err := jobRunner.Register(func() jobs.Worker { return &workers.FeedVersionUnimportWorker{} }); err != nil {
-		return err
+	var jobBackend jobs.Backend
+	switch cmd.JobBackend {
+	case "postgres":
+		queues := map[string]pgjobs.QueueOpts{}
+		for _, kind := range jobKinds {
+			queues[kind] = pgjobs.QueueOpts{}
+		}
+		jobBackend = pgjobs.NewPostgresBackend(db, jobRunner, queues, nil)
+	default:
+		queues := map[string]localjobs.QueueOpts{}
+		for _, kind := range jobKinds {
+			queues[kind] = localjobs.QueueOpts{}
+		}
+		jobBackend = localjobs.NewLocalBackend(jobRunner, queues, nil)
 	}
-	jobBackend := localjobs.NewLocalBackend(jobRunner, map[string]localjobs.QueueOpts{
-		"feed-version-import":   {},
-		"feed-version-unimport": {},
-	}, nil)
 
 	// Demo binary: authorization disabled. Production deployments should
 	// compose their own binary with a real Checker.
@@ -193,6 +283,9 @@ func (cmd *ServerCommand) Run(ctx context.Context) error {
 		Actions:                 actionFinder,
 		Jobs:                    jobBackend,
 		JobRunner:               jobRunner,
+		ArtifactStorage:         cmd.ArtifactStorage,
+		ArtifactStoreFactory:    artifactstore.NewStore(db, cmd.ArtifactStorage),
+		JobsPrefix:              cmd.JobsPrefix,
 		Secrets:                 cmd.secrets,
 		Storage:                 cmd.Storage,
 		RTStorage:               cmd.RTStorage,
@@ -212,6 +305,11 @@ func (cmd *ServerCommand) Run(ctx context.Context) error {
 			log.For(ctx).Error().Err(err).Msg("job backend stopped")
 		}
 	}()
+	if len(cmd.rtRecordJobs) > 0 {
+		if err := addRTRecordJobs(ctx, jobBackend, cmd.rtRecordJobs, cmd.RTRecordInterval); err != nil {
+			return err
+		}
+	}
 
 	// Setup router
 	root := chi.NewRouter()
@@ -272,6 +370,13 @@ func (cmd *ServerCommand) Run(ctx context.Context) error {
 		root.Mount("/rest", r)
 	}
 
+	// Job API: status, watch and artifact downloads
+	jobServer, err := jobserver.NewServer()
+	if err != nil {
+		return err
+	}
+	root.Mount("/jobs", jobServer)
+
 	// GraphQL Playground
 	root.Handle("/", playground.Handler("GraphQL playground", "/query"))
 
@@ -289,3 +394,27 @@ func (cmd *ServerCommand) Run(ctx context.Context) error {
 }
 
 var allowAllCheckerInstance = &authz.AllowAllChecker{}
+
+// addRTRecordJobs registers a periodic rt-record job for each static feed.
+func addRTRecordJobs(ctx context.Context, jobBackend jobs.Backend, rtRecordJobs []workers.RTRecordWorker, interval time.Duration) error {
+	w := workers.RTRecordWorker{}
+	q, err := jobBackend.Queue(w.Kind())
+	if err != nil {
+		return err
+	}
+	pq, ok := q.(jobs.PeriodicQueue)
+	if !ok {
+		return errors.New("job backend does not support periodic jobs")
+	}
+	for _, w := range rtRecordJobs {
+		args := jobs.Args{"feed_onestop_id": w.FeedOnestopID}
+		if len(w.RTFeedOnestopIDs) > 0 {
+			args["rt_feed_onestop_ids"] = w.RTFeedOnestopIDs
+		}
+		job := jobs.Job{Kind: w.Kind(), Args: args, Opts: jobs.JobOpts{Unique: true}}
+		if _, err := pq.AddPeriodic(ctx, func() jobs.Job { return job }, interval, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -21,22 +21,28 @@ import (
 )
 
 type Command struct {
-	Outpath     string
-	RawDiff     bool
-	ShowDiff    bool
-	ShowSame    bool
-	ShowAdded   bool
-	ShowDeleted bool
-	CheckFiles  []string
-	readerPathA string
-	readerPathB string
+	Outpath           string
+	RawDiff           bool
+	Semantic          bool
+	Format            string
+	MarkdownLimit     int
+	StopMoveThreshold float64
+	ShowDiff          bool
+	ShowSame          bool
+	ShowAdded         bool
+	ShowDeleted       bool
+	CheckFiles        []string
+	readerPathA       string
+	readerPathB       string
 }
 
 func (cmd *Command) HelpDesc() (string, string) {
 	a := "Calculate difference between two feeds, writing output in a GTFS-like format"
 	b := `This command is experimental; it may provide incorrect results or crash on large feeds.
 
-Note: This command only processes CSV files; GeoJSON files (such as locations.geojson) are not included in the diff comparison.`
+Note: This command only processes CSV files; GeoJSON files (such as locations.geojson) are not included in the diff comparison.
+
+With --semantic, entities are compared by key and the output is a single change report instead of a directory. The report includes field-level changes for each entity, including locations.geojson, trips added and removed for each route, the change in scheduled service for each day, and stops that moved. Use --format to select JSON or Markdown output; use "-" as the output path to write to stdout.`
 	return a, b
 }
 
@@ -50,6 +56,10 @@ func (cmd *Command) AddFlags(fl *pflag.FlagSet) {
 	fl.BoolVar(&cmd.ShowAdded, "added", false, "Show entities added in second file")
 	fl.BoolVar(&cmd.ShowDeleted, "deleted", false, "Show entities deleted from first file")
 	fl.BoolVar(&cmd.RawDiff, "raw", false, "Diff based on raw CSV contents")
+	fl.BoolVar(&cmd.Semantic, "semantic", false, "Write a semantic change report comparing entities by key")
+	fl.StringVar(&cmd.Format, "format", "json", "Semantic report format: json or markdown")
+	fl.IntVar(&cmd.MarkdownLimit, "markdown-limit", 100, "Maximum number of entity changes per file in Markdown output; 0 for no limit")
+	fl.Float64Var(&cmd.StopMoveThreshold, "stop-move-threshold", 1.0, "Report stops that moved more than this distance, in meters")
 }
 
 func (cmd *Command) Parse(args []string) error {
@@ -60,7 +70,10 @@ func (cmd *Command) Parse(args []string) error {
 	if fl.NArg() < 3 {
 		return errors.New("requires output directory")
 	}
-	if !cmd.ShowAdded && !cmd.ShowDeleted && !cmd.ShowSame && !cmd.ShowDiff {
+	if cmd.Semantic && cmd.Format != "json" && cmd.Format != "markdown" {
+		return errors.New("format must be json or markdown")
+	}
+	if !cmd.Semantic && !cmd.ShowAdded && !cmd.ShowDeleted && !cmd.ShowSame && !cmd.ShowDiff {
 		log.Print("Using default mode of -same -diff -added -deleted")
 		cmd.ShowAdded = true
 		cmd.ShowDeleted = true
@@ -88,6 +101,9 @@ func (cmd *Command) Run(ctx context.Context) error {
 	if err := readerB.Open(); err != nil {
 		return err
 	}
+	if cmd.Semantic {
+		return cmd.runSemantic(ctx, readerA, readerB)
+	}
 	var df1 *diffAdapter
 	var df2 *diffAdapter
 	if cmd.RawDiff {
@@ -205,6 +221,26 @@ func (cmd *Command) Run(ctx context.Context) error {
 	return nil
 }
 
+func (cmd *Command) runSemantic(ctx context.Context, readerA adapters.Reader, readerB adapters.Reader) error {
+	report, err := SemanticDiff(ctx, readerA, readerB, SemanticOptions{StopMoveThreshold: cmd.StopMoveThreshold})
+	if err != nil {
+		return err
+	}
+	var w io.Writer = os.Stdout
+	if cmd.Outpath != "-" {
+		f, err := os.Create(cmd.Outpath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if cmd.Format == "markdown" {
+		return report.WriteMarkdown(w, cmd.MarkdownLimit)
+	}
+	return report.WriteJSON(w)
+}
+
 type canFileInfos interface {
 	tlcsv.Adapter
 	FileInfos() ([]os.FileInfo, error)
@@ -0,0 +1,515 @@
+package diff
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/interline-io/transitland-lib/adapters"
+	"github.com/interline-io/transitland-lib/copier"
+	"github.com/interline-io/transitland-lib/stats"
+	"github.com/interline-io/transitland-lib/tlcsv"
+	"github.com/interline-io/transitland-lib/tlxy"
+	"github.com/twpayne/go-geom/encoding/geojson"
+)
+
+// SemanticOptions configures a semantic diff.
+type SemanticOptions struct {
+	// Report stops that moved more than this distance, in meters
+	StopMoveThreshold float64
+}
+
+// Report is the result of a semantic diff between two feeds.
+type Report struct {
+	FeedA         string               `json:"feed_a"`
+	FeedB         string               `json:"feed_b"`
+	Files         []FileReport         `json:"files"`
+	RouteTrips    []RouteTripChange    `json:"route_trips"`
+	ServiceLevels []ServiceLevelChange `json:"service_levels"`
+	StopsMoved    []StopMoved          `json:"stops_moved"`
+}
+
+// FileReport summarizes the changes to a single file.
+type FileReport struct {
+	Filename  string         `json:"filename"`
+	Added     int            `json:"added"`
+	Removed   int            `json:"removed"`
+	Changed   int            `json:"changed"`
+	Unchanged int            `json:"unchanged"`
+	Entities  []EntityChange `json:"entities"`
+}
+
+// EntityChange is an entity that was added, removed, or changed.
+type EntityChange struct {
+	Key    string        `json:"key"`
+	Status string        `json:"status"`
+	Fields []FieldChange `json:"fields,omitempty"`
+}
+
+// FieldChange is a single changed value.
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// RouteTripChange is the change in the number of trips on a route.
+type RouteTripChange struct {
+	RouteID     string `json:"route_id"`
+	TripsBefore int    `json:"trips_before"`
+	TripsAfter  int    `json:"trips_after"`
+	Added       int    `json:"added"`
+	Removed     int    `json:"removed"`
+}
+
+// ServiceLevelChange is a day with a different number of scheduled service seconds.
+type ServiceLevelChange struct {
+	Date          string `json:"date"`
+	SecondsBefore int    `json:"seconds_before"`
+	SecondsAfter  int    `json:"seconds_after"`
+}
+
+// StopMoved is a stop with a changed location.
+type StopMoved struct {
+	StopID   string  `json:"stop_id"`
+	StopName string  `json:"stop_name"`
+	Distance float64 `json:"distance"`
+}
+
+// Entity keys used for comparison; files with an empty key are expected to have a single row.
+// Files not listed here are compared by complete row contents.
+var semanticEntityKeys = map[string][]string{
+	"agency.txt":               {"agency_id"},
+	"stops.txt":                {"stop_id"},
+	"routes.txt":               {"route_id"},
+	"trips.txt":                {"trip_id"},
+	"stop_times.txt":           {"trip_id", "stop_sequence"},
+	"calendar.txt":             {"service_id"},
+	"calendar_dates.txt":       {"service_id", "date"},
+	"shapes.txt":               {"shape_id", "shape_pt_sequence"},
+	"frequencies.txt":          {"trip_id", "start_time"},
+	"transfers.txt":            {"from_stop_id", "to_stop_id", "from_route_id", "to_route_id", "from_trip_id", "to_trip_id"},
+	"pathways.txt":             {"pathway_id"},
+	"levels.txt":               {"level_id"},
+	"feed_info.txt":            {},
+	"fare_attributes.txt":      {"fare_id"},
+	"fare_rules.txt":           {"fare_id", "route_id", "origin_id", "destination_id", "contains_id"},
+	"areas.txt":                {"area_id"},
+	"stop_areas.txt":           {"area_id", "stop_id"},
+	"networks.txt":             {"network_id"},
+	"route_networks.txt":       {"route_id"},
+	"location_groups.txt":      {"location_group_id"},
+	"location_group_stops.txt": {"location_group_id", "stop_id"},
+	"booking_rules.txt":        {"booking_rule_id"},
+	"fare_media.txt":           {"fare_media_id"},
+	"rider_categories.txt":     {"rider_category_id"},
+	"fare_products.txt":        {"fare_product_id", "rider_category_id", "fare_media_id"},
+	"timeframes.txt":           {"timeframe_group_id", "start_time", "end_time", "service_id"},
+	"translations.txt":         {"table_name", "field_name", "language", "record_id", "record_sub_id", "field_value"},
+}
+
+// SemanticDiff compares two feeds by entity key.
+func SemanticDiff(ctx context.Context, readerA adapters.Reader, readerB adapters.Reader, opts SemanticOptions) (*Report, error) {
+	a, err := readSemantic(ctx, readerA)
+	if err != nil {
+		return nil, err
+	}
+	b, err := readSemantic(ctx, readerB)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{
+		FeedA: readerA.String(),
+		FeedB: readerB.String(),
+	}
+
+	// Entity changes
+	var files []string
+	for fn := range a.files {
+		files = append(files, fn)
+	}
+	for fn := range b.files {
+		if _, ok := a.files[fn]; !ok {
+			files = append(files, fn)
+		}
+	}
+	sort.Strings(files)
+	for _, fn := range files {
+		report.Files = append(report.Files, compareFile(fn, a.files[fn], b.files[fn]))
+	}
+
+	// Schedule impact
+	report.RouteTrips = compareRouteTrips(a.files["trips.txt"], b.files["trips.txt"])
+	report.StopsMoved = compareStops(a.files["stops.txt"], b.files["stops.txt"], opts.StopMoveThreshold)
+	report.ServiceLevels, err = compareServiceLevels(readerA, readerB)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// WriteJSON writes the report as indented JSON.
+func (report *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// WriteMarkdown writes a summary of the report, listing up to limit entity changes per file.
+func (report *Report) WriteMarkdown(w io.Writer, limit int) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Feed diff\n\n")
+	fmt.Fprintf(&sb, "- Before: `%s`\n- After: `%s`\n\n", report.FeedA, report.FeedB)
+
+	sb.WriteString("## Files\n\n")
+	sb.WriteString("| File | Added | Removed | Changed | Unchanged |\n")
+	sb.WriteString("| --- | ---: | ---: | ---: | ---: |\n")
+	for _, f := range report.Files {
+		fmt.Fprintf(&sb, "| %s | %d | %d | %d | %d |\n", f.Filename, f.Added, f.Removed, f.Changed, f.Unchanged)
+	}
+	sb.WriteString("\n")
+
+	sb.WriteString("## Trips by route\n\n")
+	if len(report.RouteTrips) == 0 {
+		sb.WriteString("No changes.\n\n")
+	} else {
+		sb.WriteString("| Route | Before | After | Added | Removed |\n")
+		sb.WriteString("| --- | ---: | ---: | ---: | ---: |\n")
+		for _, r := range report.RouteTrips {
+			fmt.Fprintf(&sb, "| %s | %d | %d | %d | %d |\n", mdEscape(r.RouteID), r.TripsBefore, r.TripsAfter, r.Added, r.Removed)
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("## Service level by day\n\n")
+	if len(report.ServiceLevels) == 0 {
+		sb.WriteString("No changes.\n\n")
+	} else {
+		sb.WriteString("| Date | Hours before | Hours after | Change |\n")
+		sb.WriteString("| --- | ---: | ---: | ---: |\n")
+		for i, d := range report.ServiceLevels {
+			if limit > 0 && i >= limit {
+				fmt.Fprintf(&sb, "\n%d more days not shown.\n", len(report.ServiceLevels)-limit)
+				break
+			}
+			fmt.Fprintf(&sb, "| %s | %.1f | %.1f | %+.1f |\n", d.Date, float64(d.SecondsBefore)/3600, float64(d.SecondsAfter)/3600, float64(d.SecondsAfter-d.SecondsBefore)/3600)
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("## Stops moved\n\n")
+	if len(report.StopsMoved) == 0 {
+		sb.WriteString("No changes.\n\n")
+	} else {
+		sb.WriteString("| Stop | Name | Distance (m) |\n")
+		sb.WriteString("| --- | --- | ---: |\n")
+		for _, s := range report.StopsMoved {
+			fmt.Fprintf(&sb, "| %s | %s | %.1f |\n", mdEscape(s.StopID), mdEscape(s.StopName), s.Distance)
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("## Entity changes\n\n")
+	for _, f := range report.Files {
+		if len(f.Entities) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "### %s\n\n", f.Filename)
+		for i, ent := range f.Entities {
+			if limit > 0 && i >= limit {
+				fmt.Fprintf(&sb, "\n%d more changes not shown.\n", len(f.Entities)-limit)
+				break
+			}
+			fmt.Fprintf(&sb, "- %s `%s`", ent.Status, ent.Key)
+			if len(ent.Fields) > 0 {
+				var fields []string
+				for _, fc := range ent.Fields {
+					fields = append(fields, fmt.Sprintf("%s: `%s` → `%s`", fc.Field, fc.Before, fc.After))
+				}
+				fmt.Fprintf(&sb, ": %s", strings.Join(fields, ", "))
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func mdEscape(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+//////////
+
+type semanticFile struct {
+	header []string
+	keys   []string
+	rows   map[string]map[string]string
+}
+
+// semanticAdapter collects the copier output for each file as values keyed by entity key.
+type semanticAdapter struct {
+	files map[string]*semanticFile
+}
+
+func readSemantic(ctx context.Context, reader adapters.Reader) (*semanticAdapter, error) {
+	df := &semanticAdapter{files: map[string]*semanticFile{}}
+	writer, err := tlcsv.NewWriter("")
+	if err != nil {
+		return nil, err
+	}
+	writer.WriterAdapter = df
+	if _, err := copier.CopyWithOptions(ctx, reader, writer, copier.Options{
+		AllowEntityErrors:    true,
+		AllowReferenceErrors: true,
+		Quiet:                true,
+	}); err != nil {
+		return nil, err
+	}
+	return df, nil
+}
+
+func (adapter *semanticAdapter) String() string                         { return "semantic-diff" }
+func (adapter *semanticAdapter) OpenFile(string, func(io.Reader)) error { return nil }
+func (adapter *semanticAdapter) ReadRows(string, func(tlcsv.Row)) error { return nil }
+func (adapter *semanticAdapter) Open() error                            { return nil }
+func (adapter *semanticAdapter) Close() error                           { return nil }
+func (adapter *semanticAdapter) Exists() bool                           { return false }
+func (adapter *semanticAdapter) Path() string                           { return "" }
+func (adapter *semanticAdapter) SHA1() (string, error)                  { return "", nil }
+func (adapter *semanticAdapter) DirSHA1() (string, error)               { return "", nil }
+
+func (adapter *semanticAdapter) file(efn string) *semanticFile {
+	f, ok := adapter.files[efn]
+	if !ok {
+		f = &semanticFile{rows: map[string]map[string]string{}}
+		adapter.files[efn] = f
+	}
+	return f
+}
+
+func (adapter *semanticAdapter) WriteRows(efn string, rows [][]string) error {
+	f := adapter.file(efn)
+	for _, row := range rows {
+		if f.header == nil {
+			f.header = row
+			continue
+		}
+		vals := map[string]string{}
+		for i, v := range row {
+			if i < len(f.header) {
+				vals[f.header[i]] = v
+			}
+		}
+		f.add(efn, vals, hashRow(row))
+	}
+	return nil
+}
+
+func (adapter *semanticAdapter) WriteFeatures(efn string, features []*geojson.Feature) error {
+	f := adapter.file(efn)
+	for _, feature := range features {
+		vals := map[string]string{"id": feature.ID}
+		for k, v := range feature.Properties {
+			switch pv := v.(type) {
+			case string:
+				vals[k] = pv
+			default:
+				data, _ := json.Marshal(pv)
+				vals[k] = string(data)
+			}
+		}
+		if feature.Geometry != nil {
+			g, err := geojson.Encode(feature.Geometry)
+			if err != nil {
+				return err
+			}
+			data, err := json.Marshal(g)
+			if err != nil {
+				return err
+			}
+			vals["geometry"] = string(data)
+		}
+		for k := range vals {
+			if !slices.Contains(f.header, k) {
+				f.header = append(f.header, k)
+			}
+		}
+		f.add(efn, vals, feature.ID)
+	}
+	return nil
+}
+
+func (f *semanticFile) add(efn string, vals map[string]string, fallbackKey string) {
+	key := fallbackKey
+	if keyFields, ok := semanticEntityKeys[efn]; ok {
+		var kv []string
+		for _, k := range keyFields {
+			kv = append(kv, vals[k])
+		}
+		key = strings.Join(kv, ":")
+	}
+	if _, ok := f.rows[key]; !ok {
+		f.keys = append(f.keys, key)
+	}
+	f.rows[key] = vals
+}
+
+func compareFile(fn string, a *semanticFile, b *semanticFile) FileReport {
+	if a == nil {
+		a = &semanticFile{}
+	}
+	if b == nil {
+		b = &semanticFile{}
+	}
+	ret := FileReport{Filename: fn}
+	var fields []string
+	fields = append(fields, a.header...)
+	for _, h := range b.header {
+		if !slices.Contains(fields, h) {
+			fields = append(fields, h)
+		}
+	}
+	for _, key := range a.keys {
+		rowA := a.rows[key]
+		rowB, ok := b.rows[key]
+		if !ok {
+			ret.Removed++
+			ret.Entities = append(ret.Entities, EntityChange{Key: key, Status: "removed"})
+			continue
+		}
+		var changes []FieldChange
+		for _, field := range fields {
+			if rowA[field] != rowB[field] {
+				changes = append(changes, FieldChange{Field: field, Before: rowA[field], After: rowB[field]})
+			}
+		}
+		if len(changes) == 0 {
+			ret.Unchanged++
+			continue
+		}
+		ret.Changed++
+		ret.Entities = append(ret.Entities, EntityChange{Key: key, Status: "changed", Fields: changes})
+	}
+	for _, key := range b.keys {
+		if _, ok := a.rows[key]; !ok {
+			ret.Added++
+			ret.Entities = append(ret.Entities, EntityChange{Key: key, Status: "added"})
+		}
+	}
+	return ret
+}
+
+func compareRouteTrips(a *semanticFile, b *semanticFile) []RouteTripChange {
+	changes := map[string]*RouteTripChange{}
+	get := func(routeID string) *RouteTripChange {
+		if _, ok := changes[routeID]; !ok {
+			changes[routeID] = &RouteTripChange{RouteID: routeID}
+		}
+		return changes[routeID]
+	}
+	if a != nil {
+		for key, row := range a.rows {
+			rc := get(row["route_id"])
+			rc.TripsBefore++
+			if b == nil || b.rows[key] == nil || b.rows[key]["route_id"] != row["route_id"] {
+				rc.Removed++
+			}
+		}
+	}
+	if b != nil {
+		for key, row := range b.rows {
+			rc := get(row["route_id"])
+			rc.TripsAfter++
+			if a == nil || a.rows[key] == nil || a.rows[key]["route_id"] != row["route_id"] {
+				rc.Added++
+			}
+		}
+	}
+	var ret []RouteTripChange
+	for _, rc := range changes {
+		if rc.Added > 0 || rc.Removed > 0 {
+			ret = append(ret, *rc)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].RouteID < ret[j].RouteID })
+	return ret
+}
+
+func compareStops(a *semanticFile, b *semanticFile, threshold float64) []StopMoved {
+	if a == nil || b == nil {
+		return nil
+	}
+	var ret []StopMoved
+	for key, rowA := range a.rows {
+		rowB, ok := b.rows[key]
+		if !ok {
+			continue
+		}
+		ptA, okA := rowPoint(rowA)
+		ptB, okB := rowPoint(rowB)
+		if !okA || !okB {
+			continue
+		}
+		if dist := tlxy.DistanceHaversine(ptA, ptB); dist > threshold {
+			ret = append(ret, StopMoved{StopID: key, StopName: rowB["stop_name"], Distance: dist})
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Distance == ret[j].Distance {
+			return ret[i].StopID < ret[j].StopID
+		}
+		return ret[i].Distance > ret[j].Distance
+	})
+	return ret
+}
+
+func rowPoint(row map[string]string) (tlxy.Point, bool) {
+	lat, err1 := strconv.ParseFloat(row["stop_lat"], 64)
+	lon, err2 := strconv.ParseFloat(row["stop_lon"], 64)
+	if err1 != nil || err2 != nil {
+		return tlxy.Point{}, false
+	}
+	return tlxy.Point{Lon: lon, Lat: lat}, true
+}
+
+func compareServiceLevels(readerA adapters.Reader, readerB adapters.Reader) ([]ServiceLevelChange, error) {
+	fvslsA, err := stats.NewFeedVersionServiceLevelsFromReader(readerA)
+	if err != nil {
+		return nil, err
+	}
+	fvslsB, err := stats.NewFeedVersionServiceLevelsFromReader(readerB)
+	if err != nil {
+		return nil, err
+	}
+	var startDate, endDate time.Time
+	for _, fvsl := range append(slices.Clone(fvslsA), fvslsB...) {
+		if startDate.IsZero() || fvsl.StartDate.Val.Before(startDate) {
+			startDate = fvsl.StartDate.Val
+		}
+		if fvsl.EndDate.Val.After(endDate) {
+			endDate = fvsl.EndDate.Val
+		}
+	}
+	if startDate.IsZero() {
+		return nil, nil
+	}
+	before := map[time.Time]int{}
+	for d, v := range stats.ServiceLevelDays(fvslsA, startDate, endDate) {
+		before[d] = v
+	}
+	var ret []ServiceLevelChange
+	for d, v := range stats.ServiceLevelDays(fvslsB, startDate, endDate) {
+		if before[d] != v {
+			ret = append(ret, ServiceLevelChange{Date: d.Format("2006-01-02"), SecondsBefore: before[d], SecondsAfter: v})
+		}
+	}
+	return ret, nil
+}
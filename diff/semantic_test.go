@@ -0,0 +1,192 @@
+package diff
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/interline-io/transitland-lib/internal/testpath"
+	"github.com/interline-io/transitland-lib/tlcsv"
+	"github.com/stretchr/testify/assert"
+)
+
+// copyFeed copies a feed directory, applying edits to the contents of individual files.
+func copyFeed(t *testing.T, src string, edits map[string]func(string) string) string {
+	t.Helper()
+	dst := t.TempDir()
+	fis, err := os.ReadDir(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fi := range fis {
+		if fi.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(src, fi.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := string(data)
+		if edit, ok := edits[fi.Name()]; ok {
+			s = edit(s)
+		}
+		if err := os.WriteFile(filepath.Join(dst, fi.Name()), []byte(s), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dst
+}
+
+func removeLines(substr string) func(string) string {
+	return func(s string) string {
+		var ret []string
+		for _, line := range strings.Split(s, "\n") {
+			if !strings.Contains(line, substr) {
+				ret = append(ret, line)
+			}
+		}
+		return strings.Join(ret, "\n")
+	}
+}
+
+func semanticDiffPaths(t *testing.T, pathA string, pathB string) *Report {
+	t.Helper()
+	readerA, err := tlcsv.NewReader(pathA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readerB, err := tlcsv.NewReader(pathB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := SemanticDiff(context.Background(), readerA, readerB, SemanticOptions{StopMoveThreshold: 1.0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func fileReport(report *Report, fn string) FileReport {
+	for _, f := range report.Files {
+		if f.Filename == fn {
+			return f
+		}
+	}
+	return FileReport{}
+}
+
+func TestSemanticDiff(t *testing.T) {
+	src := testpath.RelPath("testdata/gtfs-examples/example")
+	t.Run("same", func(t *testing.T) {
+		report := semanticDiffPaths(t, src, src)
+		for _, f := range report.Files {
+			assert.Empty(t, f.Entities, f.Filename)
+		}
+		assert.Empty(t, report.RouteTrips)
+		assert.Empty(t, report.ServiceLevels)
+		assert.Empty(t, report.StopsMoved)
+	})
+	t.Run("changes", func(t *testing.T) {
+		dst := copyFeed(t, src, map[string]func(string) string{
+			"stops.txt": func(s string) string {
+				return strings.Replace(s, "BULLFROG,Bullfrog (Demo),,36.88108", "BULLFROG,Bullfrog (Demo),,36.89108", 1)
+			},
+			"routes.txt": func(s string) string {
+				return strings.Replace(s, "Airport - Bullfrog", "Airport to Bullfrog", 1)
+			},
+			"trips.txt":      removeLines("AB2"),
+			"stop_times.txt": removeLines("AB2"),
+		})
+		report := semanticDiffPaths(t, src, dst)
+
+		routes := fileReport(report, "routes.txt")
+		assert.Equal(t, 1, routes.Changed)
+		assert.Equal(t, []EntityChange{{
+			Key:    "AB",
+			Status: "changed",
+			Fields: []FieldChange{{Field: "route_long_name", Before: "Airport - Bullfrog", After: "Airport to Bullfrog"}},
+		}}, routes.Entities)
+
+		trips := fileReport(report, "trips.txt")
+		assert.Equal(t, 1, trips.Removed)
+		assert.Equal(t, 0, trips.Added)
+		assert.Equal(t, []RouteTripChange{{RouteID: "AB", TripsBefore: 2, TripsAfter: 1, Removed: 1}}, report.RouteTrips)
+
+		stopTimes := fileReport(report, "stop_times.txt")
+		assert.Equal(t, 2, stopTimes.Removed)
+		assert.Contains(t, stopTimes.Entities, EntityChange{Key: "AB2:1", Status: "removed"})
+
+		if assert.Len(t, report.StopsMoved, 1) {
+			assert.Equal(t, "BULLFROG", report.StopsMoved[0].StopID)
+			assert.InDelta(t, 1112, report.StopsMoved[0].Distance, 5)
+		}
+
+		// AB2 runs every day
+		if assert.NotEmpty(t, report.ServiceLevels) {
+			for _, d := range report.ServiceLevels {
+				assert.Less(t, d.SecondsAfter, d.SecondsBefore)
+			}
+		}
+	})
+	t.Run("locations.geojson", func(t *testing.T) {
+		flexSrc := testpath.RelPath("testdata/flex/stops-absent-flex")
+		dst := copyFeed(t, flexSrc, map[string]func(string) string{
+			"locations.geojson": func(s string) string {
+				return strings.ReplaceAll(s, "-121.9", "-121.8")
+			},
+		})
+		report := semanticDiffPaths(t, flexSrc, dst)
+		locations := fileReport(report, "locations.geojson")
+		assert.Equal(t, 1, locations.Changed)
+		if assert.Len(t, locations.Entities, 1) {
+			ent := locations.Entities[0]
+			assert.Equal(t, "area1", ent.Key)
+			if assert.Len(t, ent.Fields, 1) {
+				assert.Equal(t, "geometry", ent.Fields[0].Field)
+				assert.Contains(t, ent.Fields[0].After, "-121.8")
+			}
+		}
+	})
+}
+
+func TestReport_Write(t *testing.T) {
+	report := &Report{
+		FeedA:      "a.zip",
+		FeedB:      "b.zip",
+		Files:      []FileReport{{Filename: "routes.txt", Changed: 1, Entities: []EntityChange{{Key: "AB", Status: "changed", Fields: []FieldChange{{Field: "route_long_name", Before: "x", After: "y"}}}}}},
+		RouteTrips: []RouteTripChange{{RouteID: "AB", TripsBefore: 2, TripsAfter: 1, Removed: 1}},
+		StopsMoved: []StopMoved{{StopID: "BULLFROG", StopName: "Bullfrog", Distance: 1112.0}},
+		ServiceLevels: []ServiceLevelChange{
+			{Date: "2007-01-01", SecondsBefore: 7200, SecondsAfter: 3600},
+			{Date: "2007-01-02", SecondsBefore: 7200, SecondsAfter: 3600},
+		},
+	}
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := report.WriteJSON(&buf); err != nil {
+			t.Fatal(err)
+		}
+		var check Report
+		if err := json.Unmarshal(buf.Bytes(), &check); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, *report, check)
+	})
+	t.Run("markdown", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := report.WriteMarkdown(&buf, 1); err != nil {
+			t.Fatal(err)
+		}
+		md := buf.String()
+		assert.Contains(t, md, "| routes.txt | 0 | 0 | 1 | 0 |")
+		assert.Contains(t, md, "| AB | 2 | 1 | 0 | 1 |")
+		assert.Contains(t, md, "| 2007-01-01 | 2.0 | 1.0 | -1.0 |")
+		assert.Contains(t, md, "1 more days not shown.")
+		assert.Contains(t, md, "| BULLFROG | Bullfrog | 1112.0 |")
+		assert.Contains(t, md, "- changed `AB`: route_long_name: `x` → `y`")
+	})
+}
@@ -21,6 +21,8 @@ type FeedFetch struct {
 	ValidationDurationMs tt.Int
 	UploadDurationMs     tt.Int
 	StorageKey           tt.String // archive object key, if archived
+	ResponseETag         tt.String `db:"response_etag"`          // ETag header, for conditional fetches
+	ResponseLastModified tt.String `db:"response_last_modified"` // Last-Modified header, for conditional fetches
 	tt.Timestamps
 	tt.DatabaseEntity
 }
@@ -18,8 +18,10 @@ type FeedState struct {
 	FetchWait                 tt.Int
 	FeedRealtimeEnabled       bool
 	Public                    bool
-	RTRetentionPeriod         int // days to retain archived RT messages; 0 disables
-	OnestopIDRetentionPeriod  int // days to retain onestop_id stats; 0 keeps forever, N>0 culls after N days
+	RTRetentionPeriod         int     // days to retain archived RT messages; 0 disables
+	OnestopIDRetentionPeriod  int     // days to retain onestop_id stats; 0 keeps forever, N>0 culls after N days
+	NextFetchAt               tt.Time // when the fetch daemon next considers this feed due; null is due now
+	FetchFailures             int     // consecutive failed fetches, for retry backoff
 	tt.DatabaseEntity
 	tt.Timestamps
 }
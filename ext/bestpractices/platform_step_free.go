@@ -0,0 +1,70 @@
+package bestpractices
+
+import (
+	"fmt"
+
+	"github.com/interline-io/transitland-lib/adapters"
+	"github.com/interline-io/transitland-lib/gtfs"
+	"github.com/interline-io/transitland-lib/internal/graph"
+	"github.com/interline-io/transitland-lib/tt"
+)
+
+// PlatformNotStepFreeError reports when no entrance of a station with pathways reaches a platform without stairs or escalators.
+type PlatformNotStepFreeError struct {
+	StopID        string
+	ParentStation string
+	bc
+}
+
+func (e *PlatformNotStepFreeError) Error() string {
+	return fmt.Sprintf(
+		"platform '%s' cannot be reached from any entrance of station '%s' without stairs or escalators",
+		e.StopID,
+		e.ParentStation,
+	)
+}
+
+// PlatformStepFreeCheck checks for PlatformNotStepFreeErrors.
+// The pathway graph is built from the reader before copying, since pathways are copied after stops.
+// Platforms with wheelchair_boarding=2 are already marked as inaccessible and are not reported.
+type PlatformStepFreeCheck struct {
+	unreachable map[string]string // platform stop_id -> station stop_id
+}
+
+func (e *PlatformStepFreeCheck) Prepare(reader adapters.Reader, emap *tt.EntityMap) error {
+	e.unreachable = map[string]string{}
+	g := graph.NewPathwayGraph()
+	for ent := range reader.Stops() {
+		ent := ent
+		g.AddStop(&ent)
+	}
+	for ent := range reader.Pathways() {
+		ent := ent
+		g.AddPathway(&ent)
+	}
+	for _, station := range g.Stations() {
+		stationStop, _ := g.Stop(station)
+		for _, platform := range g.StepFreeUnreachablePlatforms(station, graph.PathwayRouteOptions{}) {
+			if p, ok := g.Stop(platform); ok {
+				e.unreachable[p.StopID.Val] = stationStop.StopID.Val
+			}
+		}
+	}
+	return nil
+}
+
+func (e *PlatformStepFreeCheck) Validate(ent tt.Entity) []error {
+	v, ok := ent.(*gtfs.Stop)
+	if !ok || v.LocationType.Val != 0 || v.WheelchairBoarding.Val == 2 {
+		return nil
+	}
+	station, ok := e.unreachable[v.StopID.Val]
+	if !ok {
+		return nil
+	}
+	return []error{&PlatformNotStepFreeError{
+		StopID:        v.StopID.Val,
+		ParentStation: station,
+		bc:            bc{Field: "stop_id", EntityID: v.StopID.Val},
+	}}
+}
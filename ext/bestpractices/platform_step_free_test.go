@@ -0,0 +1,79 @@
+package bestpractices
+
+import (
+	"testing"
+
+	"github.com/interline-io/transitland-lib/adapters/direct"
+	"github.com/interline-io/transitland-lib/gtfs"
+	"github.com/interline-io/transitland-lib/tt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlatformStepFreeCheck(t *testing.T) {
+	stop := func(stopID string, locationType int, parent string, wheelchairBoarding int) gtfs.Stop {
+		return gtfs.Stop{
+			StopID:             tt.NewString(stopID),
+			LocationType:       tt.NewInt(locationType),
+			ParentStation:      tt.NewKey(parent),
+			WheelchairBoarding: tt.NewInt(wheelchairBoarding),
+		}
+	}
+	pathway := func(pathwayID string, from string, to string, mode int) gtfs.Pathway {
+		return gtfs.Pathway{
+			PathwayID:       tt.NewString(pathwayID),
+			FromStopID:      tt.NewString(from),
+			ToStopID:        tt.NewString(to),
+			PathwayMode:     tt.NewInt(mode),
+			IsBidirectional: tt.NewInt(1),
+		}
+	}
+	reader := direct.NewReader()
+	reader.StopList = []gtfs.Stop{
+		stop("station", 1, "", 0),
+		stop("entrance", 2, "station", 0),
+		stop("mezzanine", 3, "station", 0),
+		stop("elevator_platform", 0, "station", 0),
+		stop("stairs_platform", 0, "station", 0),
+		stop("marked_platform", 0, "station", 2),
+		stop("bus_stop", 0, "", 0),
+	}
+	reader.PathwayList = []gtfs.Pathway{
+		pathway("p1", "entrance", "mezzanine", 1),
+		pathway("p2", "mezzanine", "elevator_platform", 5),
+		pathway("p3", "mezzanine", "stairs_platform", 2),
+		pathway("p4", "mezzanine", "marked_platform", 4),
+	}
+	check := &PlatformStepFreeCheck{}
+	assert.NoError(t, check.Prepare(reader, tt.NewEntityMap()))
+
+	tcs := []struct {
+		stopID      string
+		expectError bool
+	}{
+		{"elevator_platform", false},
+		{"stairs_platform", true},
+		{"marked_platform", false},
+		{"bus_stop", false},
+		{"mezzanine", false},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.stopID, func(t *testing.T) {
+			for _, ent := range reader.StopList {
+				if ent.StopID.Val != tc.stopID {
+					continue
+				}
+				errs := check.Validate(&ent)
+				if !tc.expectError {
+					assert.Empty(t, errs)
+					return
+				}
+				if assert.Len(t, errs, 1) {
+					err, ok := errs[0].(*PlatformNotStepFreeError)
+					if assert.True(t, ok) {
+						assert.Equal(t, "station", err.ParentStation)
+					}
+				}
+			}
+		})
+	}
+}
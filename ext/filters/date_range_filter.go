@@ -0,0 +1,79 @@
+package filters
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/interline-io/transitland-lib/gtfs"
+	"github.com/interline-io/transitland-lib/service"
+	"github.com/interline-io/transitland-lib/tt"
+)
+
+// DateRangeFilter clips calendars and calendar_dates to a date range using a copier filter.
+type DateRangeFilter struct {
+	StartDate time.Time
+	EndDate   time.Time
+}
+
+// NewDateRangeFilter returns a DateRangeFilter for the inclusive range between startDate and endDate.
+func NewDateRangeFilter(startDate time.Time, endDate time.Time) (*DateRangeFilter, error) {
+	if startDate.IsZero() || endDate.IsZero() {
+		return nil, errors.New("start date and end date are required")
+	}
+	if endDate.Before(startDate) {
+		return nil, errors.New("end date must not be before start date")
+	}
+	return &DateRangeFilter{StartDate: startDate, EndDate: endDate}, nil
+}
+
+func newDateRangeFilterFromJson(args string) (*DateRangeFilter, error) {
+	opts := &struct {
+		StartDate tt.Date
+		EndDate   tt.Date
+	}{}
+	if err := json.Unmarshal([]byte(args), opts); err != nil {
+		return nil, err
+	}
+	return NewDateRangeFilter(opts.StartDate.Val, opts.EndDate.Val)
+}
+
+// Filter rewrites calendars to the active days within the date range, and removes calendar_dates outside of it.
+func (tf *DateRangeFilter) Filter(ent tt.Entity, emap *tt.EntityMap) error {
+	switch v := ent.(type) {
+	case *gtfs.CalendarDate:
+		if v.Date.Val.Before(tf.StartDate) || v.Date.Val.After(tf.EndDate) {
+			return fmt.Errorf("calendar date not in date range")
+		}
+	case *gtfs.Calendar:
+		svc := service.NewService(*v, v.CalendarDates...)
+		startDate, endDate := svc.ServicePeriod()
+		if startDate.Before(tf.StartDate) {
+			startDate = tf.StartDate
+		}
+		if endDate.After(tf.EndDate) {
+			endDate = tf.EndDate
+		}
+		// Copy active service days in range into new calendar
+		active := false
+		newSvc := service.NewService(gtfs.Calendar{ServiceID: v.ServiceID, StartDate: tt.NewDate(startDate), EndDate: tt.NewDate(endDate)})
+		for d := startDate; !d.After(endDate); d = d.AddDate(0, 0, 1) {
+			if svc.IsActive(d) {
+				newSvc.AddCalendarDate(gtfs.CalendarDate{
+					Date:          tt.NewDate(d),
+					ExceptionType: tt.NewInt(1),
+				})
+				active = true
+			}
+		}
+		if !active {
+			return fmt.Errorf("service not in date range")
+		}
+		// Simplify back to regular calendar and update in place
+		if err := setSimplifiedCalendar(v, newSvc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package filters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/interline-io/transitland-lib/ext"
+	"github.com/interline-io/transitland-lib/gtfs"
+	"github.com/interline-io/transitland-lib/internal/testreader"
+	"github.com/interline-io/transitland-lib/service"
+	"github.com/interline-io/transitland-lib/tlcsv"
+	"github.com/interline-io/transitland-lib/tt"
+)
+
+func TestDateRangeFilter(t *testing.T) {
+	reader, err := tlcsv.NewReader(testreader.ExampleFeedBART.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svcs := service.NewServicesFromReader(reader)
+	startDate := time.Date(2018, 5, 26, 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(2018, 6, 8, 0, 0, 0, 0, time.UTC)
+	tf, err := NewDateRangeFilter(startDate, endDate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, svc := range svcs {
+		orig := service.NewService(svc.Calendar, svc.CalendarDates()...)
+		c := svc.Calendar
+		c.CalendarDates = svc.CalendarDates()
+		if err := tf.Filter(&c, nil); err != nil {
+			t.Fatal(err)
+		}
+		if c.StartDate.Val.Before(startDate) || c.EndDate.Val.After(endDate) {
+			t.Errorf("service %s: got service period %s - %s, expected within date range", c.ServiceID, c.StartDate.String(), c.EndDate.String())
+		}
+		clipped := service.NewService(c, c.CalendarDates...)
+		for d := startDate; !d.After(endDate); d = d.AddDate(0, 0, 1) {
+			if orig.IsActive(d) != clipped.IsActive(d) {
+				t.Errorf("service %s: got active %t on %s, expected %t", c.ServiceID, clipped.IsActive(d), d.Format("2006-01-02"), orig.IsActive(d))
+			}
+		}
+	}
+	t.Run("calendar dates", func(t *testing.T) {
+		cd := gtfs.CalendarDate{Date: tt.NewDate(time.Date(2018, 7, 4, 0, 0, 0, 0, time.UTC)), ExceptionType: tt.NewInt(2)}
+		if err := tf.Filter(&cd, nil); err == nil {
+			t.Error("expected calendar date outside date range to be removed")
+		}
+		cd.Date = tt.NewDate(time.Date(2018, 5, 28, 0, 0, 0, 0, time.UTC))
+		if err := tf.Filter(&cd, nil); err != nil {
+			t.Error(err)
+		}
+	})
+	t.Run("no service in range", func(t *testing.T) {
+		tf2, err := NewDateRangeFilter(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := svcs[0].Calendar
+		if err := tf2.Filter(&c, nil); err == nil {
+			t.Error("expected calendar outside date range to be removed")
+		}
+	})
+}
+
+func TestDateRangeFilter_Extension(t *testing.T) {
+	name, args, err := ext.ParseExtensionArgs("DateRange:startdate=2018-06-01,enddate=2018-06-30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := ext.GetExtension(name, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, ok := e.(*DateRangeFilter)
+	if !ok {
+		t.Fatalf("got %T", e)
+	}
+	if !f.StartDate.Equal(time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)) || !f.EndDate.Equal(time.Date(2018, 6, 30, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("got %s - %s", f.StartDate, f.EndDate)
+	}
+	if _, err := ext.GetExtension("DateRange", `{"StartDate":"2018-06-01"}`); err == nil {
+		t.Error("expected error for missing end date")
+	}
+}
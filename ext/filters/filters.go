@@ -14,4 +14,6 @@ func init() {
 	ext.RegisterExtension("BasicRouteType", func(string) (ext.Extension, error) { return &BasicRouteTypeFilter{}, nil })
 	ext.RegisterExtension("NormalizeTimezone", func(string) (ext.Extension, error) { return &NormalizeTimezoneFilter{}, nil })
 	ext.RegisterExtension("ApplyTimezone", func(args string) (ext.Extension, error) { return newApplyTimezoneFilterFromJson(args) })
+	ext.RegisterExtension("DateRange", func(args string) (ext.Extension, error) { return newDateRangeFilterFromJson(args) })
+	ext.RegisterExtension("TimeWindow", func(args string) (ext.Extension, error) { return newTimeWindowFilterFromJson(args) })
 }
@@ -142,29 +142,35 @@ func (tf *RedateFilter) Filter(ent tt.Entity, emap *tt.EntityMap) error {
 			tf.excluded[v.ServiceID.Val] = true
 			return fmt.Errorf("service not in redate window")
 		}
-		// Simplify back to regular calendar
-		newSvc, err := newSvc.Simplify()
-		if err != nil {
+		// Simplify back to regular calendar and update in place
+		if err := setSimplifiedCalendar(v, newSvc); err != nil {
 			return err
 		}
-		newSvc.Generated.Set(false)
-		// Reset and update in place
-		v.StartDate.Set(newSvc.StartDate.Val)
-		v.EndDate.Set(newSvc.EndDate.Val)
-		v.Generated.Set(newSvc.Generated.Val)
-		v.Monday.Set(newSvc.Monday.Val)
-		v.Tuesday.Set(newSvc.Tuesday.Val)
-		v.Wednesday.Set(newSvc.Wednesday.Val)
-		v.Thursday.Set(newSvc.Thursday.Val)
-		v.Friday.Set(newSvc.Friday.Val)
-		v.Saturday.Set(newSvc.Saturday.Val)
-		v.Sunday.Set(newSvc.Sunday.Val)
-		v.CalendarDates = newSvc.CalendarDates()
 		return nil
 	}
 	return nil
 }
 
+// setSimplifiedCalendar simplifies svc back to a regular calendar and resets v to match it.
+func setSimplifiedCalendar(v *gtfs.Calendar, svc *service.Service) error {
+	newSvc, err := svc.Simplify()
+	if err != nil {
+		return err
+	}
+	v.StartDate.Set(newSvc.StartDate.Val)
+	v.EndDate.Set(newSvc.EndDate.Val)
+	v.Generated.Set(false)
+	v.Monday.Set(newSvc.Monday.Val)
+	v.Tuesday.Set(newSvc.Tuesday.Val)
+	v.Wednesday.Set(newSvc.Wednesday.Val)
+	v.Thursday.Set(newSvc.Thursday.Val)
+	v.Friday.Set(newSvc.Friday.Val)
+	v.Saturday.Set(newSvc.Saturday.Val)
+	v.Sunday.Set(newSvc.Sunday.Val)
+	v.CalendarDates = newSvc.CalendarDates()
+	return nil
+}
+
 func daysBetween(t1 time.Time, t2 time.Time) int {
 	days := 0
 	flip := 1
@@ -0,0 +1,142 @@
+package filters
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/interline-io/transitland-lib/gtfs"
+	"github.com/interline-io/transitland-lib/tt"
+)
+
+const secondsPerDay = 24 * 3600
+
+// TimeWindow is a range of times of day. Times are compared modulo 24 hours,
+// so a departure at 25:30:00 falls in a window from 01:00:00 to 02:00:00.
+// A window ending after 24:00:00 spans midnight.
+type TimeWindow struct {
+	start int
+	end   int
+}
+
+// NewTimeWindow returns a window for the given bounds.
+// An unset start time is 00:00:00 and an unset end time is 24:00:00.
+func NewTimeWindow(startTime tt.Seconds, endTime tt.Seconds) TimeWindow {
+	w := TimeWindow{start: 0, end: secondsPerDay}
+	if startTime.Valid {
+		w.start = startTime.Int()
+	}
+	if endTime.Valid {
+		w.end = endTime.Int()
+	}
+	for w.start >= secondsPerDay {
+		w.start -= secondsPerDay
+		w.end -= secondsPerDay
+	}
+	return w
+}
+
+// Contains returns if the time falls in the window.
+func (w TimeWindow) Contains(t int) bool {
+	return len(w.spans(t, t+1)) > 0
+}
+
+// ClipFrequency returns the frequency clipped to each part of its service period within the window.
+// A frequency that crosses the window more than once, e.g. a window spanning midnight,
+// returns one frequency for each overlapping part.
+// Exact time frequencies keep their departures aligned to the original headway.
+func (w TimeWindow) ClipFrequency(freq gtfs.Frequency) []gtfs.Frequency {
+	var ret []gtfs.Frequency
+	for _, span := range w.spans(freq.StartTime.Int(), freq.EndTime.Int()) {
+		start, end := span[0], span[1]
+		if headway := freq.HeadwaySecs.Int(); freq.ExactTimes.Int() == 1 && headway > 0 {
+			if offset := (start - freq.StartTime.Int()) % headway; offset > 0 {
+				start += headway - offset
+			}
+		}
+		if start >= end {
+			continue
+		}
+		clipped := freq
+		clipped.StartTime = tt.NewSeconds(start)
+		clipped.EndTime = tt.NewSeconds(end)
+		ret = append(ret, clipped)
+	}
+	return ret
+}
+
+// spans returns the parts of the range from start to end that overlap the window,
+// repeated every 24 hours. Adjacent parts are merged.
+func (w TimeWindow) spans(start int, end int) [][2]int {
+	var ret [][2]int
+	for day := -1; day <= end/secondsPerDay; day++ {
+		s, e := max(w.start+day*secondsPerDay, start), min(w.end+day*secondsPerDay, end)
+		if s >= e {
+			continue
+		}
+		if n := len(ret); n > 0 && s <= ret[n-1][1] {
+			ret[n-1][1] = max(ret[n-1][1], e)
+			continue
+		}
+		ret = append(ret, [2]int{s, e})
+	}
+	return ret
+}
+
+// TimeWindowFilter clips frequencies to a time window using a copier filter.
+// It is used together with the extract Marker's SetTimeWindow, which excludes trips
+// without departures in the same window.
+type TimeWindowFilter struct {
+	window TimeWindow
+}
+
+// NewTimeWindowFilter returns a TimeWindowFilter for trips departing at or after startTime and before endTime.
+// Either bound may be left unset.
+func NewTimeWindowFilter(startTime tt.Seconds, endTime tt.Seconds) (*TimeWindowFilter, error) {
+	if startTime.Valid && endTime.Valid && endTime.Int() <= startTime.Int() {
+		return nil, errors.New("end time must be after start time")
+	}
+	return &TimeWindowFilter{window: NewTimeWindow(startTime, endTime)}, nil
+}
+
+func newTimeWindowFilterFromJson(args string) (*TimeWindowFilter, error) {
+	opts := &struct {
+		StartTime tt.Seconds
+		EndTime   tt.Seconds
+	}{}
+	if err := json.Unmarshal([]byte(args), opts); err != nil {
+		return nil, err
+	}
+	return NewTimeWindowFilter(opts.StartTime, opts.EndTime)
+}
+
+// Expand splits frequencies that overlap the time window more than once.
+func (tf *TimeWindowFilter) Expand(ent tt.Entity, emap *tt.EntityMap) ([]tt.Entity, bool, error) {
+	v, ok := ent.(*gtfs.Frequency)
+	if !ok {
+		return nil, false, nil
+	}
+	clipped := tf.window.ClipFrequency(*v)
+	if len(clipped) < 2 {
+		return nil, false, nil
+	}
+	var ret []tt.Entity
+	for i := range clipped {
+		ret = append(ret, &clipped[i])
+	}
+	return ret, true, nil
+}
+
+// Filter rewrites frequencies to the part of their service period within the time window, and removes frequencies outside of it.
+func (tf *TimeWindowFilter) Filter(ent tt.Entity, emap *tt.EntityMap) error {
+	v, ok := ent.(*gtfs.Frequency)
+	if !ok {
+		return nil
+	}
+	clipped := tf.window.ClipFrequency(*v)
+	if len(clipped) == 0 {
+		return errors.New("frequency not in time window")
+	}
+	v.StartTime = clipped[0].StartTime
+	v.EndTime = clipped[0].EndTime
+	return nil
+}
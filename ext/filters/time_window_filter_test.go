@@ -0,0 +1,167 @@
+package filters
+
+import (
+	"testing"
+
+	"github.com/interline-io/transitland-lib/ext"
+	"github.com/interline-io/transitland-lib/gtfs"
+	"github.com/interline-io/transitland-lib/tt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeWindow_Contains(t *testing.T) {
+	tcs := []struct {
+		name   string
+		start  tt.Seconds
+		end    tt.Seconds
+		t      int
+		expect bool
+	}{
+		{"in window", tt.NewSeconds(3600), tt.NewSeconds(7200), 3600, true},
+		{"end is exclusive", tt.NewSeconds(3600), tt.NewSeconds(7200), 7200, false},
+		{"next service day", tt.NewSeconds(3600), tt.NewSeconds(7200), 25 * 3600, true},
+		{"window on next service day", tt.NewSeconds(25 * 3600), tt.NewSeconds(26 * 3600), 3600, true},
+		{"spans midnight before", tt.NewSeconds(23 * 3600), tt.NewSeconds(25 * 3600), 23*3600 + 1800, true},
+		{"spans midnight after", tt.NewSeconds(23 * 3600), tt.NewSeconds(25 * 3600), 1800, true},
+		{"spans midnight outside", tt.NewSeconds(23 * 3600), tt.NewSeconds(25 * 3600), 2 * 3600, false},
+		{"no start time", tt.Seconds{}, tt.NewSeconds(3600), 25*3600 - 1, true},
+		{"no end time", tt.NewSeconds(23 * 3600), tt.Seconds{}, 24*3600 + 1800, false},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			w := NewTimeWindow(tc.start, tc.end)
+			if got := w.Contains(tc.t); got != tc.expect {
+				t.Errorf("got %t, expected %t", got, tc.expect)
+			}
+		})
+	}
+}
+
+func TestTimeWindowFilter(t *testing.T) {
+	tcs := []struct {
+		name        string
+		start       tt.Seconds
+		end         tt.Seconds
+		freqStart   int
+		freqEnd     int
+		exactTimes  int
+		expectStart int
+		expectEnd   int
+		expectOk    bool
+	}{
+		{"clipped", tt.NewSeconds(8 * 3600), tt.NewSeconds(9 * 3600), 6 * 3600, 10 * 3600, 0, 8 * 3600, 9 * 3600, true},
+		{"inside", tt.NewSeconds(8 * 3600), tt.NewSeconds(9 * 3600), 8*3600 + 600, 8*3600 + 1200, 0, 8*3600 + 600, 8*3600 + 1200, true},
+		{"outside", tt.NewSeconds(8 * 3600), tt.NewSeconds(9 * 3600), 10 * 3600, 11 * 3600, 0, 0, 0, false},
+		{"next service day", tt.NewSeconds(3600), tt.NewSeconds(2 * 3600), 24 * 3600, 26 * 3600, 0, 25 * 3600, 26 * 3600, true},
+		{"exact times aligned to headway", tt.NewSeconds(8*3600 + 60), tt.NewSeconds(9 * 3600), 6 * 3600, 10 * 3600, 1, 8*3600 + 600, 9 * 3600, true},
+		{"exact times without departure in window", tt.NewSeconds(8*3600 + 60), tt.NewSeconds(8*3600 + 120), 6 * 3600, 10 * 3600, 1, 0, 0, false},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			tf, err := NewTimeWindowFilter(tc.start, tc.end)
+			if err != nil {
+				t.Fatal(err)
+			}
+			freq := gtfs.Frequency{
+				TripID:      tt.NewString("trip"),
+				HeadwaySecs: tt.NewInt(600),
+				StartTime:   tt.NewSeconds(tc.freqStart),
+				EndTime:     tt.NewSeconds(tc.freqEnd),
+				ExactTimes:  tt.NewInt(tc.exactTimes),
+			}
+			err = tf.Filter(&freq, nil)
+			if !tc.expectOk {
+				if err == nil {
+					t.Error("expected frequency outside time window to be removed")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if freq.StartTime.Int() != tc.expectStart || freq.EndTime.Int() != tc.expectEnd {
+				t.Errorf("got %s - %s, expected %s - %s", freq.StartTime.String(), freq.EndTime.String(), tt.NewSeconds(tc.expectStart).String(), tt.NewSeconds(tc.expectEnd).String())
+			}
+		})
+	}
+}
+
+func TestTimeWindowFilter_Expand(t *testing.T) {
+	type span struct {
+		start int
+		end   int
+	}
+	tcs := []struct {
+		name       string
+		start      tt.Seconds
+		end        tt.Seconds
+		freqStart  int
+		freqEnd    int
+		exactTimes int
+		expect     []span
+	}{
+		{"single overlap is not expanded", tt.NewSeconds(8 * 3600), tt.NewSeconds(9 * 3600), 6 * 3600, 10 * 3600, 0, nil},
+		{"wraps past midnight", tt.NewSeconds(23 * 3600), tt.NewSeconds(25 * 3600), 0, 24 * 3600, 0, []span{{0, 3600}, {23 * 3600, 24 * 3600}}},
+		{"wraps past midnight, partial overlaps", tt.NewSeconds(23 * 3600), tt.NewSeconds(25 * 3600), 1800, 23*3600 + 1800, 0, []span{{1800, 3600}, {23 * 3600, 23*3600 + 1800}}},
+		{"wraps past midnight, contiguous", tt.NewSeconds(23 * 3600), tt.NewSeconds(25 * 3600), 22 * 3600, 26 * 3600, 0, nil},
+		{"crosses window on two service days", tt.NewSeconds(8 * 3600), tt.NewSeconds(9 * 3600), 7 * 3600, 33 * 3600, 0, []span{{8 * 3600, 9 * 3600}, {32 * 3600, 33 * 3600}}},
+		{"exact times aligned in each span", tt.NewSeconds(23*3600 + 60), tt.NewSeconds(25 * 3600), 300, 24 * 3600, 1, []span{{300, 3600}, {23*3600 + 300, 24 * 3600}}},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			tf, err := NewTimeWindowFilter(tc.start, tc.end)
+			if err != nil {
+				t.Fatal(err)
+			}
+			freq := gtfs.Frequency{
+				TripID:      tt.NewString("trip"),
+				HeadwaySecs: tt.NewInt(600),
+				StartTime:   tt.NewSeconds(tc.freqStart),
+				EndTime:     tt.NewSeconds(tc.freqEnd),
+				ExactTimes:  tt.NewInt(tc.exactTimes),
+			}
+			ents, ok, err := tf.Expand(&freq, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.expect == nil {
+				assert.False(t, ok)
+				return
+			}
+			assert.True(t, ok)
+			var got []span
+			for _, ent := range ents {
+				v, ok := ent.(*gtfs.Frequency)
+				if !ok {
+					t.Fatalf("got %T", ent)
+				}
+				assert.Equal(t, "trip", v.TripID.Val)
+				// Expanded frequencies are unchanged by Filter
+				if err := tf.Filter(v, nil); err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, span{v.StartTime.Int(), v.EndTime.Int()})
+			}
+			assert.Equal(t, tc.expect, got)
+			assert.Equal(t, tc.freqStart, freq.StartTime.Int(), "original frequency should not be modified")
+		})
+	}
+}
+
+func TestTimeWindowFilter_Extension(t *testing.T) {
+	name, args, err := ext.ParseExtensionArgs("TimeWindow:starttime=23:00:00,endtime=25:00:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := ext.GetExtension(name, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, ok := e.(*TimeWindowFilter)
+	if !ok {
+		t.Fatalf("got %T", e)
+	}
+	assert.Equal(t, TimeWindow{start: 23 * 3600, end: 25 * 3600}, f.window)
+	_, err = ext.GetExtension("TimeWindow", `{"StartTime":"09:00:00","EndTime":"08:00:00"}`)
+	assert.Error(t, err, "end time before start time should be rejected")
+}
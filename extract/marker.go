@@ -2,10 +2,15 @@
 package extract
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/interline-io/transitland-lib/adapters"
+	"github.com/interline-io/transitland-lib/ext/filters"
+	"github.com/interline-io/transitland-lib/gtfs"
 	"github.com/interline-io/transitland-lib/internal/graph"
+	"github.com/interline-io/transitland-lib/service"
 	"github.com/interline-io/transitland-lib/tlxy"
 	"github.com/interline-io/transitland-lib/tt"
 )
@@ -19,6 +24,10 @@ type Marker struct {
 	fm             map[string][]string
 	ex             map[string][]string
 	bbox           string
+	startDate      time.Time
+	endDate        time.Time
+	startTime      tt.Seconds
+	endTime        tt.Seconds
 	defaultExclude bool
 }
 
@@ -41,6 +50,33 @@ func (em *Marker) SetBbox(bbox string) error {
 	return nil
 }
 
+// SetDateRange keeps only trips whose service is active on at least one day
+// between startDate and endDate, inclusive.
+func (em *Marker) SetDateRange(startDate time.Time, endDate time.Time) error {
+	if startDate.IsZero() || endDate.IsZero() {
+		return errors.New("start date and end date are required")
+	}
+	if endDate.Before(startDate) {
+		return errors.New("end date must not be before start date")
+	}
+	em.startDate = startDate
+	em.endDate = endDate
+	return nil
+}
+
+// SetTimeWindow keeps only trips departing at or after startTime and before endTime.
+// Either bound may be left unset. Times are compared modulo 24 hours, and an endTime
+// after 24:00:00 spans midnight. Frequency-based trips are kept if any of their
+// departures fall in the window; use filters.TimeWindowFilter to clip their frequencies.
+func (em *Marker) SetTimeWindow(startTime tt.Seconds, endTime tt.Seconds) error {
+	if startTime.Valid && endTime.Valid && endTime.Int() <= startTime.Int() {
+		return errors.New("end time must be after start time")
+	}
+	em.startTime = startTime
+	em.endTime = endTime
+	return nil
+}
+
 func (em *Marker) Mark(filename string, eid string, val bool) {
 	n, _ := em.graph.Node(graph.NewNode(filename, eid))
 	em.found[n] = val
@@ -83,6 +119,12 @@ func (em *Marker) Count() int {
 	if em.bbox != "" {
 		c += 1
 	}
+	if !em.startDate.IsZero() {
+		c += 1
+	}
+	if em.startTime.Valid || em.endTime.Valid {
+		c += 1
+	}
 	for _, v := range em.fm {
 		c += len(v)
 	}
@@ -169,6 +211,110 @@ func (em *Marker) Filter(reader adapters.Reader) error {
 		em.Mark("stops.txt", sid, false)
 	}
 
+	// Exclude trips outside of provided date range and time window
+	if !em.startDate.IsZero() || em.startTime.Valid || em.endTime.Valid {
+		if err := em.filterService(reader); err != nil {
+			return err
+		}
+	}
+
 	// log.For(ctx).Debug().Msgf("result: %#v\n", result)
 	return nil
 }
+
+// filterService excludes trips without service in the date range or departing outside the time window.
+// Entities referenced only by excluded trips, such as stops, shapes and routes, are excluded with them.
+func (em *Marker) filterService(reader adapters.Reader) error {
+	// Find services with no active days in the date range
+	inactiveServices := map[string]bool{}
+	if !em.startDate.IsZero() {
+		for _, svc := range service.NewServicesFromReader(reader) {
+			if !serviceActiveBetween(svc, em.startDate, em.endDate) {
+				inactiveServices[svc.ServiceID.Val] = true
+			}
+		}
+	}
+
+	// Find the first departure of each trip, and frequency-based service periods
+	type tripDeparture struct {
+		stopSequence int
+		departure    tt.Seconds
+	}
+	departures := map[string]tripDeparture{}
+	frequencies := map[string][]gtfs.Frequency{}
+	window := filters.NewTimeWindow(em.startTime, em.endTime)
+	if em.startTime.Valid || em.endTime.Valid {
+		for st := range reader.StopTimes() {
+			dep := st.DepartureTime
+			if !dep.Valid {
+				dep = st.ArrivalTime
+			}
+			if cur, ok := departures[st.TripID.Val]; ok && cur.stopSequence <= st.StopSequence.Int() {
+				continue
+			}
+			departures[st.TripID.Val] = tripDeparture{stopSequence: st.StopSequence.Int(), departure: dep}
+		}
+		for freq := range reader.Frequencies() {
+			frequencies[freq.TripID.Val] = append(frequencies[freq.TripID.Val], freq)
+		}
+	}
+	inTimeWindow := func(tripID string) bool {
+		if !em.startTime.Valid && !em.endTime.Valid {
+			return true
+		}
+		if freqs, ok := frequencies[tripID]; ok {
+			for _, freq := range freqs {
+				if len(window.ClipFrequency(freq)) > 0 {
+					return true
+				}
+			}
+			return false
+		}
+		dep, ok := departures[tripID]
+		if !ok || !dep.departure.Valid {
+			return false
+		}
+		return window.Contains(dep.departure.Int())
+	}
+
+	// Split the currently selected trips
+	var keepTrips, dropTrips []*graph.Node
+	for trip := range reader.Trips() {
+		n, ok := em.graph.Node(graph.NewNode("trips.txt", trip.TripID.Val))
+		if !ok || !em.IsMarked(n.Filename, n.ID) {
+			continue
+		}
+		if inactiveServices[trip.ServiceID.Val] || !inTimeWindow(trip.TripID.Val) {
+			dropTrips = append(dropTrips, n)
+		} else {
+			keepTrips = append(keepTrips, n)
+		}
+	}
+
+	// Exclude the dropped trips and any parents no longer referenced by a kept trip
+	referenced := map[*graph.Node]bool{}
+	em.graph.Search(keepTrips[:], true, func(n *graph.Node) {
+		referenced[n] = true
+	})
+	em.graph.Search(dropTrips[:], true, func(n *graph.Node) {
+		if !referenced[n] {
+			em.Mark(n.Filename, n.ID, false)
+		}
+	})
+	for sid := range inactiveServices {
+		if n, ok := em.graph.Node(graph.NewNode("calendar.txt", sid)); ok {
+			em.Mark(n.Filename, n.ID, false)
+		}
+	}
+	return nil
+}
+
+// serviceActiveBetween returns if the service is active on any day between start and end, inclusive.
+func serviceActiveBetween(svc *service.Service, start time.Time, end time.Time) bool {
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		if svc.IsActive(d) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,11 +2,13 @@ package extract
 
 import (
 	"testing"
+	"time"
 
 	"github.com/interline-io/transitland-lib/internal/graph"
 	"github.com/interline-io/transitland-lib/internal/testpath"
 	"github.com/interline-io/transitland-lib/internal/testreader"
 	"github.com/interline-io/transitland-lib/tlcsv"
+	"github.com/interline-io/transitland-lib/tt"
 )
 
 type mss = map[string][]string
@@ -66,6 +68,96 @@ func TestExtract_Bbox(t *testing.T) {
 	}
 }
 
+func TestExtract_DateRange(t *testing.T) {
+	em := NewMarker()
+	reader, err := tlcsv.NewReader(testreader.ExampleFeedBART.URL)
+	if err != nil {
+		t.Error(err)
+	}
+	if err := em.SetDateRange(time.Date(2018, 6, 2, 0, 0, 0, 0, time.UTC), time.Date(2018, 6, 3, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	em.Filter(reader)
+	if em.IsMarked("calendar.txt", "WKDY") {
+		t.Error("expected no calendar WKDY")
+	}
+	if !em.IsMarked("calendar.txt", "SAT") {
+		t.Error("expected calendar SAT")
+	}
+	if !em.IsMarked("calendar.txt", "SUN") {
+		t.Error("expected calendar SUN")
+	}
+	if em.IsMarked("trips.txt", "3610403WKDY") {
+		t.Error("expected no trip 3610403WKDY")
+	}
+	if !em.IsMarked("trips.txt", "3650800SUN") {
+		t.Error("expected trip 3650800SUN")
+	}
+	if !em.IsMarked("stops.txt", "LAFY") {
+		t.Error("expected stop LAFY")
+	}
+}
+
+func TestExtract_TimeWindow(t *testing.T) {
+	tcs := []struct {
+		name  string
+		start tt.Seconds
+		end   tt.Seconds
+	}{
+		{"window", tt.NewSeconds(3 * 3600), tt.NewSeconds(3*3600 + 50*60)},
+		{"next service day", tt.NewSeconds(27 * 3600), tt.NewSeconds(27*3600 + 50*60)},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			em := NewMarker()
+			reader, err := tlcsv.NewReader(testreader.ExampleFeedBART.URL)
+			if err != nil {
+				t.Error(err)
+			}
+			if err := em.SetTimeWindow(tc.start, tc.end); err != nil {
+				t.Fatal(err)
+			}
+			em.Filter(reader)
+			if !em.IsMarked("trips.txt", "3610403WKDY") {
+				t.Error("expected trip 3610403WKDY")
+			}
+			if em.IsMarked("trips.txt", "3750351WKDY") {
+				t.Error("expected no trip 3750351WKDY")
+			}
+			if !em.IsMarked("stops.txt", "ANTC") {
+				t.Error("expected stop ANTC")
+			}
+			if em.IsMarked("stops.txt", "FRMT") {
+				t.Error("expected no stop FRMT")
+			}
+			if !em.IsMarked("routes.txt", "01") {
+				t.Error("expected route 01")
+			}
+			if em.IsMarked("routes.txt", "03") {
+				t.Error("expected no route 03")
+			}
+			if em.IsMarked("calendar.txt", "SUN") {
+				t.Error("expected no calendar SUN")
+			}
+		})
+	}
+}
+
+func TestExtract_SetDateRange(t *testing.T) {
+	em := NewMarker()
+	d1 := time.Date(2018, 6, 2, 0, 0, 0, 0, time.UTC)
+	d2 := time.Date(2018, 6, 3, 0, 0, 0, 0, time.UTC)
+	if err := em.SetDateRange(d2, d1); err == nil {
+		t.Error("expected error for end date before start date")
+	}
+	if err := em.SetDateRange(d1, time.Time{}); err == nil {
+		t.Error("expected error for missing end date")
+	}
+	if err := em.SetTimeWindow(tt.NewSeconds(3600), tt.NewSeconds(3600)); err == nil {
+		t.Error("expected error for empty time window")
+	}
+}
+
 func TestExtract_Filter_ExampleFeed(t *testing.T) {
 	reader, err := tlcsv.NewReader(testpath.RelPath("testdata/gtfs-examples/extract-examples"))
 	if err != nil {
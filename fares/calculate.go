@@ -0,0 +1,386 @@
+package fares
+
+import (
+	"math"
+	"time"
+
+	"github.com/interline-io/transitland-lib/gtfs"
+)
+
+// fareLeg is one or more legs treated as a single leg for fare purposes.
+type fareLeg struct {
+	indexes   []int
+	networks  []string
+	fromAreas []string
+	toAreas   []string
+	start     time.Time
+	end       time.Time
+	rules     []gtfs.FareLegRule
+}
+
+type productKey struct {
+	riderCategoryID string
+	fareMediaID     string
+}
+
+type pricedLeg struct {
+	fl      *fareLeg
+	rule    gtfs.FareLegRule
+	product gtfs.FareProduct
+}
+
+// joinLegs combines consecutive legs that match a fare_leg_join_rule.
+func (d *Data) joinLegs(legs []Leg) []fareLeg {
+	var ret []fareLeg
+	for i, leg := range legs {
+		if i > 0 {
+			if networks, ok := d.matchJoinRule(legs[i-1], leg); ok {
+				fl := &ret[len(ret)-1]
+				fl.indexes = append(fl.indexes, i)
+				fl.networks = mergeValues(fl.networks, networks)
+				fl.toAreas = d.legAreas(leg.ToStopID, leg.ToAreaID)
+				fl.end = leg.EndTime
+				continue
+			}
+		}
+		ret = append(ret, fareLeg{
+			indexes:   []int{i},
+			networks:  d.legNetworks(leg),
+			fromAreas: d.legAreas(leg.FromStopID, leg.FromAreaID),
+			toAreas:   d.legAreas(leg.ToStopID, leg.ToAreaID),
+			start:     leg.StartTime,
+			end:       leg.EndTime,
+		})
+	}
+	return ret
+}
+
+func (d *Data) matchJoinRule(from Leg, to Leg) ([]string, bool) {
+	fromNetworks := d.legNetworks(from)
+	toNetworks := d.legNetworks(to)
+	for _, rule := range d.FareLegJoinRules {
+		if !contains(fromNetworks, rule.FromNetworkID.Val) || !contains(toNetworks, rule.ToNetworkID.Val) {
+			continue
+		}
+		if rule.FromStopID.Val != "" && (rule.FromStopID.Val != from.ToStopID || rule.ToStopID.Val != to.FromStopID) {
+			continue
+		}
+		return mergeValues([]string{rule.FromNetworkID.Val}, []string{rule.ToNetworkID.Val}), true
+	}
+	return nil, false
+}
+
+func (d *Data) legNetworks(leg Leg) []string {
+	if leg.NetworkID != "" {
+		return []string{leg.NetworkID}
+	}
+	return d.RouteNetworks[leg.RouteID]
+}
+
+func (d *Data) legAreas(stopID string, areaID string) []string {
+	if areaID != "" {
+		return []string{areaID}
+	}
+	return d.StopAreas[stopID]
+}
+
+// activeTimeframeGroups returns the timeframe groups that contain the given time.
+func (d *Data) activeTimeframeGroups(t time.Time) []string {
+	loc := d.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	lt := t.In(loc)
+	secs := lt.Hour()*3600 + lt.Minute()*60 + lt.Second()
+	var ret []string
+	for _, tf := range d.Timeframes {
+		start, end := 0, 24*3600
+		if tf.StartTime.Valid {
+			start = tf.StartTime.Int()
+		}
+		if tf.EndTime.Valid {
+			end = tf.EndTime.Int()
+		}
+		if secs < start || secs >= end {
+			continue
+		}
+		if svc, ok := d.Services[tf.ServiceID.Val]; !ok || !svc.IsActive(lt) {
+			continue
+		}
+		ret = mergeValues(ret, []string{tf.TimeframeGroupID.Val})
+	}
+	return ret
+}
+
+// matchLegRules returns the fare_leg_rules that apply to a fare leg.
+// Without rule_priority, an empty field matches all values except those listed by another rule.
+// With rule_priority, an empty field matches any value and only the highest priority rules are kept.
+func (d *Data) matchLegRules(fl fareLeg, transfer bool) []gtfs.FareLegRule {
+	usePriority := false
+	for _, rule := range d.FareLegRules {
+		if rule.RulePriority.Valid {
+			usePriority = true
+		}
+	}
+	fields := []struct {
+		get    func(*gtfs.FareLegRule) string
+		values []string
+		listed map[string]bool
+	}{
+		{get: func(r *gtfs.FareLegRule) string { return r.NetworkID.Val }, values: fl.networks},
+		{get: func(r *gtfs.FareLegRule) string { return r.FromAreaID.Val }, values: fl.fromAreas},
+		{get: func(r *gtfs.FareLegRule) string { return r.ToAreaID.Val }, values: fl.toAreas},
+		{get: func(r *gtfs.FareLegRule) string { return r.FromTimeframeGroupID.Val }, values: d.activeTimeframeGroups(fl.start)},
+		{get: func(r *gtfs.FareLegRule) string { return r.ToTimeframeGroupID.Val }, values: d.activeTimeframeGroups(fl.end)},
+	}
+	for i := range fields {
+		fields[i].listed = map[string]bool{}
+		for j := range d.FareLegRules {
+			if v := fields[i].get(&d.FareLegRules[j]); v != "" {
+				fields[i].listed[v] = true
+			}
+		}
+	}
+	var ret []gtfs.FareLegRule
+	for i := range d.FareLegRules {
+		rule := &d.FareLegRules[i]
+		if rule.TransferOnly.Val == 1 && !transfer {
+			continue
+		}
+		match := true
+		for _, f := range fields {
+			if v := f.get(rule); v != "" {
+				match = contains(f.values, v)
+			} else if !usePriority {
+				for _, value := range f.values {
+					if f.listed[value] {
+						match = false
+					}
+				}
+			}
+			if !match {
+				break
+			}
+		}
+		if match {
+			ret = append(ret, *rule)
+		}
+	}
+	if !usePriority || len(ret) == 0 {
+		return ret
+	}
+	maxPriority := int64(0)
+	for _, rule := range ret {
+		maxPriority = max(maxPriority, rule.RulePriority.Val)
+	}
+	var keep []gtfs.FareLegRule
+	for _, rule := range ret {
+		if rule.RulePriority.Val == maxPriority {
+			keep = append(keep, rule)
+		}
+	}
+	return keep
+}
+
+// productKeys returns each rider category and fare media combination offered by the matched fare products.
+func (d *Data) productKeys(fareLegs []fareLeg) []productKey {
+	productIDs := map[string]bool{}
+	for _, fl := range fareLegs {
+		for _, rule := range fl.rules {
+			productIDs[rule.FareProductID.Val] = true
+		}
+	}
+	var ret []productKey
+	seen := map[productKey]bool{}
+	for _, fp := range d.FareProducts {
+		key := productKey{riderCategoryID: fp.RiderCategoryID.Val, fareMediaID: fp.FareMediaID.Val}
+		if productIDs[fp.FareProductID.Val] && !seen[key] {
+			seen[key] = true
+			ret = append(ret, key)
+		}
+	}
+	return ret
+}
+
+// findProduct returns the cheapest fare product available to a rider category and fare media.
+// Fare products without a rider category or fare media are available to all.
+func (d *Data) findProduct(fareProductID string, key productKey) (gtfs.FareProduct, bool) {
+	var ret gtfs.FareProduct
+	found := false
+	for _, fp := range d.FareProducts {
+		if fp.FareProductID.Val != fareProductID {
+			continue
+		}
+		if fp.RiderCategoryID.Val != "" && fp.RiderCategoryID.Val != key.riderCategoryID {
+			continue
+		}
+		if fp.FareMediaID.Val != "" && fp.FareMediaID.Val != key.fareMediaID {
+			continue
+		}
+		if !found || fp.Amount.Val < ret.Amount.Val {
+			ret = fp
+			found = true
+		}
+	}
+	return ret, found
+}
+
+func (d *Data) calculateFor(key productKey, fareLegs []fareLeg) (Result, bool) {
+	ret := Result{RiderCategoryID: key.riderCategoryID, FareMediaID: key.fareMediaID}
+	var priced []pricedLeg
+	for i := range fareLegs {
+		fl := &fareLegs[i]
+		var best *pricedLeg
+		for _, rule := range fl.rules {
+			fp, ok := d.findProduct(rule.FareProductID.Val, key)
+			if ok && (best == nil || fp.Amount.Val < best.product.Amount.Val) {
+				best = &pricedLeg{fl: fl, rule: rule, product: fp}
+			}
+		}
+		if best == nil {
+			return ret, false
+		}
+		if i == 0 {
+			ret.Currency = best.product.Currency.Val
+		} else if best.product.Currency.Val != ret.Currency {
+			return ret, false
+		}
+		priced = append(priced, *best)
+	}
+
+	// Apply transfers within each sub-journey
+	subStart := 0
+	transfers := 0
+	for i, p := range priced {
+		lf := LegFare{
+			LegIndexes:  p.fl.indexes,
+			LegGroupID:  p.rule.LegGroupID.Val,
+			FareProduct: p.product,
+			Amount:      p.product.Amount.Val,
+		}
+		applied := false
+		if i > 0 {
+			prevAmount := ret.Legs[i-1].Amount
+			if tr, tp, amount, ok := d.matchTransferRule(key, ret.Currency, priced[subStart], priced[i-1], p, transfers, prevAmount); ok {
+				lf.TransferRule = &tr
+				lf.TransferProduct = tp
+				lf.Amount = amount
+				if tr.FareTransferType.Val == 2 {
+					// The transfer product covers both legs
+					ret.Legs[i-1].Amount = 0
+				}
+				transfers++
+				applied = true
+			}
+		}
+		if !applied {
+			subStart = i
+			transfers = 0
+		}
+		ret.Legs = append(ret.Legs, lf)
+	}
+	for _, lf := range ret.Legs {
+		ret.Amount += lf.Amount
+	}
+	// Avoid floating point artifacts when summing decimal amounts
+	ret.Amount = math.Round(ret.Amount*1e6) / 1e6
+	return ret, true
+}
+
+// matchTransferRule returns the fare_transfer_rule with the lowest resulting cost for boarding the next leg,
+// along with the transfer fare product and the amount charged for the next leg.
+func (d *Data) matchTransferRule(key productKey, currency string, first pricedLeg, from pricedLeg, to pricedLeg, transfers int, prevAmount float64) (gtfs.FareTransferRule, *gtfs.FareProduct, float64, bool) {
+	listedFrom := map[string]bool{}
+	listedTo := map[string]bool{}
+	for _, rule := range d.FareTransferRules {
+		listedFrom[rule.FromLegGroupID.Val] = true
+		listedTo[rule.ToLegGroupID.Val] = true
+	}
+	var ret gtfs.FareTransferRule
+	var retProduct *gtfs.FareProduct
+	retAmount := 0.0
+	retDelta := 0.0
+	found := false
+	fromGroup := from.rule.LegGroupID.Val
+	toGroup := to.rule.LegGroupID.Val
+	for _, rule := range d.FareTransferRules {
+		if v := rule.FromLegGroupID.Val; v != fromGroup && (v != "" || listedFrom[fromGroup]) {
+			continue
+		}
+		if v := rule.ToLegGroupID.Val; v != toGroup && (v != "" || listedTo[toGroup]) {
+			continue
+		}
+		if v := rule.FilterFareProductID.Val; v != "" && v != from.product.FareProductID.Val {
+			continue
+		}
+		if rule.TransferCount.Valid && rule.TransferCount.Val >= 0 && int64(transfers+1) > rule.TransferCount.Val {
+			continue
+		}
+		if rule.DurationLimit.Valid {
+			var dt time.Duration
+			switch rule.DurationLimitType.Val {
+			case 1:
+				dt = to.fl.start.Sub(first.fl.start)
+			case 2:
+				dt = to.fl.start.Sub(first.fl.end)
+			case 3:
+				dt = to.fl.end.Sub(first.fl.end)
+			default:
+				dt = to.fl.end.Sub(first.fl.start)
+			}
+			if dt > time.Duration(rule.DurationLimit.Val)*time.Second {
+				continue
+			}
+		}
+		var product *gtfs.FareProduct
+		transferAmount := 0.0
+		if rule.FareProductID.Val != "" {
+			fp, ok := d.findProduct(rule.FareProductID.Val, key)
+			if !ok || fp.Currency.Val != currency {
+				continue
+			}
+			product = &fp
+			transferAmount = fp.Amount.Val
+		}
+		amount := 0.0
+		delta := 0.0
+		switch rule.FareTransferType.Val {
+		case 1:
+			amount = transferAmount + to.product.Amount.Val
+			delta = amount
+		case 2:
+			amount = transferAmount
+			delta = amount - prevAmount
+		default:
+			amount = transferAmount
+			delta = amount
+		}
+		if !found || delta < retDelta {
+			ret = rule
+			retProduct = product
+			retAmount = amount
+			retDelta = delta
+			found = true
+		}
+	}
+	return ret, retProduct, retAmount, found
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func mergeValues(a []string, b []string) []string {
+	ret := append([]string{}, a...)
+	for _, v := range b {
+		if !contains(ret, v) {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
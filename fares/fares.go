@@ -0,0 +1,152 @@
+// Package fares calculates GTFS Fares v2 prices for a sequence of journey legs.
+package fares
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/interline-io/transitland-lib/adapters"
+	"github.com/interline-io/transitland-lib/gtfs"
+	"github.com/interline-io/transitland-lib/service"
+)
+
+// Leg is a single transit leg of a journey.
+// Network and area values are looked up from the route and stops when not provided.
+type Leg struct {
+	RouteID    string
+	NetworkID  string
+	FromStopID string
+	FromAreaID string
+	ToStopID   string
+	ToAreaID   string
+	StartTime  time.Time
+	EndTime    time.Time
+}
+
+// Result is the fare for a complete journey, for a single rider category and fare media.
+type Result struct {
+	RiderCategoryID string
+	FareMediaID     string
+	Currency        string
+	Amount          float64
+	Legs            []LegFare
+}
+
+// LegFare is the fare charged for one fare leg of a journey.
+type LegFare struct {
+	LegIndexes      []int // more than one leg when joined by fare_leg_join_rules
+	LegGroupID      string
+	FareProduct     gtfs.FareProduct
+	TransferRule    *gtfs.FareTransferRule
+	TransferProduct *gtfs.FareProduct
+	Amount          float64
+}
+
+// Data holds the fare entities for a single feed version.
+// All references are GTFS identifiers.
+type Data struct {
+	FareProducts      []gtfs.FareProduct
+	FareLegRules      []gtfs.FareLegRule
+	FareLegJoinRules  []gtfs.FareLegJoinRule
+	FareTransferRules []gtfs.FareTransferRule
+	Timeframes        []gtfs.Timeframe
+	Services          map[string]*service.Service // keyed by timeframes.service_id
+	RouteNetworks     map[string][]string         // route_id to network_ids
+	StopAreas         map[string][]string         // stop_id to area_ids, including the areas of the parent station
+	Location          *time.Location              // local time for timeframes; UTC if nil
+}
+
+// NewData returns an empty Data.
+func NewData() *Data {
+	return &Data{
+		Services:      map[string]*service.Service{},
+		RouteNetworks: map[string][]string{},
+		StopAreas:     map[string][]string{},
+	}
+}
+
+// NewDataFromReader loads fare data from a reader that returns GTFS identifiers, e.g. tlcsv.
+func NewDataFromReader(reader adapters.Reader) (*Data, error) {
+	d := NewData()
+	for ent := range reader.Agencies() {
+		if d.Location != nil {
+			continue
+		}
+		loc, err := time.LoadLocation(ent.AgencyTimezone.Val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid agency_timezone '%s': %w", ent.AgencyTimezone.Val, err)
+		}
+		d.Location = loc
+	}
+	for ent := range reader.Routes() {
+		if ent.NetworkID.Val != "" {
+			d.RouteNetworks[ent.RouteID.Val] = append(d.RouteNetworks[ent.RouteID.Val], ent.NetworkID.Val)
+		}
+	}
+	for ent := range reader.RouteNetworks() {
+		d.RouteNetworks[ent.RouteID.Val] = append(d.RouteNetworks[ent.RouteID.Val], ent.NetworkID.Val)
+	}
+	stopParents := map[string]string{}
+	for ent := range reader.Stops() {
+		if ent.ParentStation.Val != "" {
+			stopParents[ent.StopID.Val] = ent.ParentStation.Val
+		}
+	}
+	stopAreas := map[string][]string{}
+	for ent := range reader.StopAreas() {
+		stopAreas[ent.StopID.Val] = append(stopAreas[ent.StopID.Val], ent.AreaID.Val)
+	}
+	for stopID, areaIDs := range stopAreas {
+		d.StopAreas[stopID] = append(d.StopAreas[stopID], areaIDs...)
+	}
+	for stopID, parentID := range stopParents {
+		if areaIDs, ok := stopAreas[parentID]; ok {
+			d.StopAreas[stopID] = append(d.StopAreas[stopID], areaIDs...)
+		}
+	}
+	for ent := range reader.FareProducts() {
+		d.FareProducts = append(d.FareProducts, ent)
+	}
+	for ent := range reader.FareLegRules() {
+		d.FareLegRules = append(d.FareLegRules, ent)
+	}
+	for ent := range reader.FareLegJoinRules() {
+		d.FareLegJoinRules = append(d.FareLegJoinRules, ent)
+	}
+	for ent := range reader.FareTransferRules() {
+		d.FareTransferRules = append(d.FareTransferRules, ent)
+	}
+	for ent := range reader.Timeframes() {
+		d.Timeframes = append(d.Timeframes, ent)
+	}
+	for _, svc := range service.NewServicesFromReader(reader) {
+		d.Services[svc.ServiceID.Val] = svc
+	}
+	return d, nil
+}
+
+// Calculate returns the fare for each rider category and fare media combination
+// that prices every leg of the journey. Results are sorted by rider category and fare media.
+func (d *Data) Calculate(legs []Leg) []Result {
+	if len(legs) == 0 {
+		return nil
+	}
+	fareLegs := d.joinLegs(legs)
+	for i := range fareLegs {
+		fareLegs[i].rules = d.matchLegRules(fareLegs[i], i > 0)
+	}
+	var ret []Result
+	for _, key := range d.productKeys(fareLegs) {
+		if r, ok := d.calculateFor(key, fareLegs); ok {
+			ret = append(ret, r)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].RiderCategoryID != ret[j].RiderCategoryID {
+			return ret[i].RiderCategoryID < ret[j].RiderCategoryID
+		}
+		return ret[i].FareMediaID < ret[j].FareMediaID
+	})
+	return ret
+}
@@ -0,0 +1,272 @@
+package fares
+
+import (
+	"testing"
+	"time"
+
+	"github.com/interline-io/transitland-lib/gtfs"
+	"github.com/interline-io/transitland-lib/internal/testpath"
+	"github.com/interline-io/transitland-lib/service"
+	"github.com/interline-io/transitland-lib/tlcsv"
+	"github.com/interline-io/transitland-lib/tt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testTime(t testing.TB, v string) time.Time {
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	require.NoError(t, err)
+	ret, err := time.ParseInLocation("2006-01-02 15:04", v, loc)
+	require.NoError(t, err)
+	return ret
+}
+
+func TestNewDataFromReader(t *testing.T) {
+	reader, err := tlcsv.NewReader(testpath.RelPath("testdata/gtfs-external/ctran-flex.zip"))
+	require.NoError(t, err)
+	d, err := NewDataFromReader(reader)
+	require.NoError(t, err)
+	assert.Equal(t, 14, len(d.FareProducts))
+	assert.Equal(t, 3, len(d.FareLegRules))
+	assert.Equal(t, 1, len(d.FareTransferRules))
+	assert.Equal(t, []string{"LOCAL"}, d.RouteNetworks["2bc6804f-9e24-4b91-8947-c73a2363e7b6"])
+	assert.Equal(t, "US/Pacific", d.Location.String())
+
+	routeID := "2bc6804f-9e24-4b91-8947-c73a2363e7b6"
+	tcs := []struct {
+		name   string
+		legs   []Leg
+		expect map[productKey]float64
+	}{
+		{
+			name: "single leg",
+			legs: []Leg{
+				{RouteID: routeID, StartTime: testTime(t, "2024-06-03 08:00"), EndTime: testTime(t, "2024-06-03 08:20")},
+			},
+			expect: map[productKey]float64{
+				{"ADULT", "0"}:           1.25,
+				{"HONORED_CITIZEN", "1"}: 0.60,
+				{"YOUTH", "2"}:           0,
+			},
+		},
+		{
+			name: "transfer within duration limit",
+			legs: []Leg{
+				{RouteID: routeID, StartTime: testTime(t, "2024-06-03 08:00"), EndTime: testTime(t, "2024-06-03 08:20")},
+				{RouteID: routeID, StartTime: testTime(t, "2024-06-03 09:00"), EndTime: testTime(t, "2024-06-03 09:20")},
+			},
+			expect: map[productKey]float64{
+				{"ADULT", "0"}:           1.25,
+				{"HONORED_CITIZEN", "1"}: 0.60,
+			},
+		},
+		{
+			name: "transfer outside duration limit",
+			legs: []Leg{
+				{RouteID: routeID, StartTime: testTime(t, "2024-06-03 08:00"), EndTime: testTime(t, "2024-06-03 08:20")},
+				{RouteID: routeID, StartTime: testTime(t, "2024-06-03 11:00"), EndTime: testTime(t, "2024-06-03 11:20")},
+			},
+			expect: map[productKey]float64{
+				{"ADULT", "0"}:           2.50,
+				{"HONORED_CITIZEN", "1"}: 1.20,
+			},
+		},
+		{
+			name: "no matching network",
+			legs: []Leg{
+				{RouteID: "unknown", StartTime: testTime(t, "2024-06-03 08:00"), EndTime: testTime(t, "2024-06-03 08:20")},
+			},
+			expect: map[productKey]float64{},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			results := d.Calculate(tc.legs)
+			if len(tc.expect) == 0 {
+				assert.Equal(t, 0, len(results))
+				return
+			}
+			assert.Equal(t, 14, len(results))
+			got := map[productKey]float64{}
+			for _, r := range results {
+				assert.Equal(t, "USD", r.Currency)
+				got[productKey{r.RiderCategoryID, r.FareMediaID}] = r.Amount
+			}
+			for k, v := range tc.expect {
+				assert.InDelta(t, v, got[k], 0.001, "rider category '%s' fare media '%s'", k.riderCategoryID, k.fareMediaID)
+			}
+		})
+	}
+}
+
+func newTestData(t testing.TB) *Data {
+	d := NewData()
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	require.NoError(t, err)
+	d.Location = loc
+	d.RouteNetworks = map[string][]string{"bus": {"bus"}, "rail": {"rail"}}
+	d.StopAreas = map[string][]string{"a1": {"zone1"}, "a2": {"zone1"}, "b1": {"zone2"}}
+	d.Services["weekday"] = service.NewService(gtfs.Calendar{
+		ServiceID: tt.NewString("weekday"),
+		StartDate: tt.NewDate(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+		EndDate:   tt.NewDate(time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)),
+		Monday:    tt.NewInt(1),
+		Tuesday:   tt.NewInt(1),
+		Wednesday: tt.NewInt(1),
+		Thursday:  tt.NewInt(1),
+		Friday:    tt.NewInt(1),
+		Saturday:  tt.NewInt(0),
+		Sunday:    tt.NewInt(0),
+	})
+	d.Timeframes = []gtfs.Timeframe{
+		{TimeframeGroupID: tt.NewString("peak"), StartTime: tt.NewSeconds(7 * 3600), EndTime: tt.NewSeconds(9 * 3600), ServiceID: tt.NewKey("weekday")},
+	}
+	product := func(id string, amount float64) gtfs.FareProduct {
+		return gtfs.FareProduct{FareProductID: tt.NewString(id), Amount: tt.NewCurrencyAmount(amount), Currency: tt.NewCurrency("USD")}
+	}
+	d.FareProducts = []gtfs.FareProduct{
+		product("bus", 2.00),
+		product("rail_local", 3.00),
+		product("rail_zone", 5.00),
+		product("rail_peak", 4.00),
+		product("bus_to_rail", 1.00),
+	}
+	legRule := func(group, network, fromArea, toArea, tf, product string) gtfs.FareLegRule {
+		return gtfs.FareLegRule{
+			LegGroupID:           tt.NewString(group),
+			NetworkID:            tt.NewString(network),
+			FromAreaID:           tt.NewString(fromArea),
+			ToAreaID:             tt.NewString(toArea),
+			FromTimeframeGroupID: tt.NewString(tf),
+			FareProductID:        tt.NewString(product),
+		}
+	}
+	d.FareLegRules = []gtfs.FareLegRule{
+		legRule("bus", "bus", "", "", "", "bus"),
+		legRule("rail", "rail", "", "", "", "rail_local"),
+		legRule("rail", "rail", "zone1", "zone2", "", "rail_zone"),
+		legRule("rail", "rail", "zone1", "zone1", "peak", "rail_peak"),
+	}
+	d.FareTransferRules = []gtfs.FareTransferRule{
+		{FromLegGroupID: tt.NewString("bus"), ToLegGroupID: tt.NewString("rail"), FareProductID: tt.NewString("bus_to_rail"), DurationLimit: tt.NewInt(3600), DurationLimitType: tt.NewInt(1), FareTransferType: tt.NewInt(1)},
+	}
+	return d
+}
+
+func TestCalculate(t *testing.T) {
+	tcs := []struct {
+		name     string
+		legs     []Leg
+		join     []gtfs.FareLegJoinRule
+		products []string
+		amount   float64
+	}{
+		{
+			name:     "bus",
+			legs:     []Leg{{RouteID: "bus", StartTime: testTime(t, "2024-06-03 12:00"), EndTime: testTime(t, "2024-06-03 12:10")}},
+			products: []string{"bus"},
+			amount:   2.00,
+		},
+		{
+			name:     "rail without areas matches empty areas",
+			legs:     []Leg{{RouteID: "rail", FromStopID: "x", ToStopID: "y", StartTime: testTime(t, "2024-06-03 12:00"), EndTime: testTime(t, "2024-06-03 12:10")}},
+			products: []string{"rail_local"},
+			amount:   3.00,
+		},
+		{
+			name:     "rail between areas",
+			legs:     []Leg{{RouteID: "rail", FromStopID: "a1", ToStopID: "b1", StartTime: testTime(t, "2024-06-03 12:00"), EndTime: testTime(t, "2024-06-03 12:10")}},
+			products: []string{"rail_zone"},
+			amount:   5.00,
+		},
+		{
+			name:     "rail within area at peak",
+			legs:     []Leg{{RouteID: "rail", FromStopID: "a1", ToStopID: "a2", StartTime: testTime(t, "2024-06-03 08:00"), EndTime: testTime(t, "2024-06-03 08:10")}},
+			products: []string{"rail_peak"},
+			amount:   4.00,
+		},
+		{
+			name:     "rail within area off peak has no fare",
+			legs:     []Leg{{RouteID: "rail", FromStopID: "a1", ToStopID: "a2", StartTime: testTime(t, "2024-06-03 12:00"), EndTime: testTime(t, "2024-06-03 12:10")}},
+			products: nil,
+		},
+		{
+			name:     "rail within area on weekend has no fare",
+			legs:     []Leg{{RouteID: "rail", FromStopID: "a1", ToStopID: "a2", StartTime: testTime(t, "2024-06-08 08:00"), EndTime: testTime(t, "2024-06-08 08:10")}},
+			products: nil,
+		},
+		{
+			name: "bus to rail transfer",
+			legs: []Leg{
+				{RouteID: "bus", StartTime: testTime(t, "2024-06-03 12:00"), EndTime: testTime(t, "2024-06-03 12:10")},
+				{RouteID: "rail", FromStopID: "a1", ToStopID: "b1", StartTime: testTime(t, "2024-06-03 12:20"), EndTime: testTime(t, "2024-06-03 12:30")},
+			},
+			products: []string{"bus", "rail_zone"},
+			amount:   8.00,
+		},
+		{
+			name: "bus to rail transfer outside duration limit",
+			legs: []Leg{
+				{RouteID: "bus", StartTime: testTime(t, "2024-06-03 12:00"), EndTime: testTime(t, "2024-06-03 12:10")},
+				{RouteID: "rail", FromStopID: "a1", ToStopID: "b1", StartTime: testTime(t, "2024-06-03 13:20"), EndTime: testTime(t, "2024-06-03 13:30")},
+			},
+			products: []string{"bus", "rail_zone"},
+			amount:   7.00,
+		},
+		{
+			name: "joined rail legs",
+			legs: []Leg{
+				{RouteID: "rail", FromStopID: "a1", ToStopID: "x", StartTime: testTime(t, "2024-06-03 12:00"), EndTime: testTime(t, "2024-06-03 12:10")},
+				{RouteID: "rail", FromStopID: "x", ToStopID: "b1", StartTime: testTime(t, "2024-06-03 12:20"), EndTime: testTime(t, "2024-06-03 12:30")},
+			},
+			join:     []gtfs.FareLegJoinRule{{FromNetworkID: tt.NewString("rail"), ToNetworkID: tt.NewString("rail")}},
+			products: []string{"rail_zone"},
+			amount:   5.00,
+		},
+		{
+			name: "rail legs not joined",
+			legs: []Leg{
+				{RouteID: "rail", FromStopID: "x", ToStopID: "y", StartTime: testTime(t, "2024-06-03 12:00"), EndTime: testTime(t, "2024-06-03 12:10")},
+				{RouteID: "rail", FromStopID: "y", ToStopID: "z", StartTime: testTime(t, "2024-06-03 12:20"), EndTime: testTime(t, "2024-06-03 12:30")},
+			},
+			join:     []gtfs.FareLegJoinRule{{FromNetworkID: tt.NewString("rail"), ToNetworkID: tt.NewString("rail"), FromStopID: tt.NewString("q"), ToStopID: tt.NewString("q")}},
+			products: []string{"rail_local", "rail_local"},
+			amount:   6.00,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			d := newTestData(t)
+			d.FareLegJoinRules = tc.join
+			results := d.Calculate(tc.legs)
+			if tc.products == nil {
+				assert.Equal(t, 0, len(results))
+				return
+			}
+			require.Equal(t, 1, len(results))
+			var products []string
+			for _, lf := range results[0].Legs {
+				products = append(products, lf.FareProduct.FareProductID.Val)
+			}
+			assert.Equal(t, tc.products, products)
+			assert.InDelta(t, tc.amount, results[0].Amount, 0.001)
+		})
+	}
+}
+
+func TestCalculate_RulePriority(t *testing.T) {
+	d := newTestData(t)
+	for i := range d.FareLegRules {
+		d.FareLegRules[i].RulePriority = tt.NewInt(0)
+	}
+	// With rule_priority, the highest priority match wins and empty fields match any value
+	d.FareLegRules[2].RulePriority = tt.NewInt(1)
+	results := d.Calculate([]Leg{{RouteID: "rail", FromStopID: "a1", ToStopID: "b1", StartTime: testTime(t, "2024-06-03 12:00"), EndTime: testTime(t, "2024-06-03 12:10")}})
+	require.Equal(t, 1, len(results))
+	assert.Equal(t, "rail_zone", results[0].Legs[0].FareProduct.FareProductID.Val)
+	// Without a higher priority, the cheapest of the matching rules is used
+	d.FareLegRules[2].RulePriority = tt.NewInt(0)
+	results = d.Calculate([]Leg{{RouteID: "rail", FromStopID: "a1", ToStopID: "b1", StartTime: testTime(t, "2024-06-03 12:00"), EndTime: testTime(t, "2024-06-03 12:10")}})
+	require.Equal(t, 1, len(results))
+	assert.Equal(t, "rail_local", results[0].Legs[0].FareProduct.FareProductID.Val)
+}
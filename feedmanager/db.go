@@ -115,6 +115,18 @@ func (m *DBFeedManager) CreateFeedFetch(ctx context.Context, ff *dmfr.FeedFetch)
 	return nil
 }
 
+func (m *DBFeedManager) GetLastFeedFetch(ctx context.Context, feedID int, urlType string, url string) (*dmfr.FeedFetch, error) {
+	ff := dmfr.FeedFetch{}
+	err := m.adapter.Get(ctx, &ff, "SELECT * FROM feed_fetches WHERE feed_id = ? AND url_type = ? AND url = ? AND success = true ORDER BY fetched_at DESC, id DESC LIMIT 1", feedID, urlType, url)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ff, nil
+}
+
 func (m *DBFeedManager) WriteFeedVersionStats(ctx context.Context, fvid int, fvstats stats.FeedVersionStats) error {
 	return stats.WriteFeedVersionStats(ctx, m.adapter, fvstats, fvid, stats.WriteOptions{})
 }
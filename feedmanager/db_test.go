@@ -2,7 +2,9 @@ package feedmanager
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/interline-io/transitland-lib/dmfr"
 	"github.com/interline-io/transitland-lib/internal/testdb"
@@ -75,6 +77,31 @@ func TestDBFeedManager(t *testing.T) {
 			t.Errorf("after update = %+v; want success=true in_progress=false", imp)
 		}
 
+		// No successful fetch yet → (nil, nil).
+		if ff, err := fm.GetLastFeedFetch(ctx, feedID, "static_current", "http://example.com/feed.zip"); err != nil || ff != nil {
+			t.Errorf("GetLastFeedFetch(none) = %v, %v; want nil, nil", ff, err)
+		}
+
+		// The latest successful fetch of the url is returned; failures are skipped.
+		for i, success := range []bool{true, true, false} {
+			ff := dmfr.FeedFetch{FeedID: feedID, URLType: "static_current", URL: "http://example.com/feed.zip", Success: success}
+			ff.FetchedAt.Set(time.Date(2026, 1, 1+i, 0, 0, 0, 0, time.UTC))
+			ff.ResponseETag.Set(fmt.Sprintf(`"etag-%d"`, i))
+			if err := fm.CreateFeedFetch(ctx, &ff); err != nil {
+				t.Fatalf("CreateFeedFetch: %v", err)
+			}
+		}
+		lastFetch, err := fm.GetLastFeedFetch(ctx, feedID, "static_current", "http://example.com/feed.zip")
+		if err != nil {
+			t.Fatalf("GetLastFeedFetch: %v", err)
+		}
+		if lastFetch == nil || lastFetch.ResponseETag.Val != `"etag-1"` {
+			t.Errorf("GetLastFeedFetch = %+v; want etag-1", lastFetch)
+		}
+		if ff, err := fm.GetLastFeedFetch(ctx, feedID, "static_current", "http://example.com/other.zip"); err != nil || ff != nil {
+			t.Errorf("GetLastFeedFetch(other url) = %v, %v; want nil, nil", ff, err)
+		}
+
 		// WithTx on an in-tx manager joins the existing tx (re-entrant) and runs fn.
 		ran := false
 		if err := fm.WithTx(ctx, func(ctx context.Context, tx FeedManager) error {
@@ -48,6 +48,10 @@ type FeedManager interface {
 	// Records a fetch attempt (response metadata, success).
 	CreateFeedFetch(ctx context.Context, ff *dmfr.FeedFetch) error
 
+	// Returns the most recent successful fetch of a feed url, whose cache
+	// validators drive conditional fetches; (nil, nil) when none.
+	GetLastFeedFetch(ctx context.Context, feedID int, urlType string, url string) (*dmfr.FeedFetch, error)
+
 	// Persists the computed feed-version stats (service levels/window, file infos,
 	// onestop ids).
 	WriteFeedVersionStats(ctx context.Context, fvid int, fvstats stats.FeedVersionStats) error
@@ -26,6 +26,9 @@ type Options struct {
 	HideURL                  bool
 	FetchedAt                time.Time
 	Secrets                  []dmfr.Secret
+	// IgnoreConditional always downloads the full response, instead of sending
+	// the cache validators of the last successful static fetch.
+	IgnoreConditional bool
 }
 
 // Result contains results of a fetch operation.
@@ -38,6 +41,9 @@ type Result struct {
 	ResponseTtfbMs int
 	ResponseTimeMs int
 	ResponseSHA1   string
+	ETag           string
+	LastModified   string
+	NotModified    bool // the server answered a conditional request with 304
 	FetchError     error
 	FeedVersionID  tt.Int
 }
@@ -51,8 +57,9 @@ type fetchDurations struct {
 // download is the shared front half of every fetch: load the feed, apply auth,
 // and download the URL to a tmpfile. The caller is responsible for removing the
 // returned tmpfile. A non-nil error is fatal; a regular failure (no URL, bad
-// secret, 404) is left on the returned response's FetchError.
-func download(ctx context.Context, fm feedmanager.FeedManager, opts Options) (*dmfr.Feed, string, request.FetchResponse, error) {
+// secret, 404) is left on the returned response's FetchError. If prev is set,
+// its cache validators are sent and a 304 is returned as NotModified.
+func download(ctx context.Context, fm feedmanager.FeedManager, opts Options, prev *dmfr.FeedFetch) (*dmfr.Feed, string, request.FetchResponse, error) {
 	feed, err := fm.GetFeed(ctx, opts.FeedID)
 	if err != nil {
 		return nil, "", request.FetchResponse{}, err
@@ -76,6 +83,9 @@ func download(ctx context.Context, fm feedmanager.FeedManager, opts Options) (*d
 	if opts.MaxSize > 0 {
 		reqOpts = append(reqOpts, request.WithMaxSize(opts.MaxSize))
 	}
+	if prev != nil {
+		reqOpts = append(reqOpts, request.WithConditional(prev.ResponseETag.Val, prev.ResponseLastModified.Val))
+	}
 	if feed.Authorization.Type != "" {
 		secret, err := feed.MatchSecrets(opts.Secrets, opts.URLType)
 		if err != nil {
@@ -97,6 +107,9 @@ func resultFromResponse(url string, resp request.FetchResponse) Result {
 		ResponseSHA1:   resp.ResponseSHA1,
 		ResponseTimeMs: resp.ResponseTimeMs,
 		ResponseTtfbMs: resp.ResponseTtfbMs,
+		ETag:           resp.ETag,
+		LastModified:   resp.LastModified,
+		NotModified:    resp.NotModified,
 	}
 }
 
@@ -144,6 +157,12 @@ func recordFeedFetch(ctx context.Context, fm feedmanager.FeedManager, feed *dmfr
 	tlfetch.ResponseSHA1.Set(result.ResponseSHA1)
 	tlfetch.ValidationDurationMs.SetInt(dur.validationMs)
 	tlfetch.UploadDurationMs.SetInt(dur.uploadMs)
+	if result.ETag != "" {
+		tlfetch.ResponseETag.Set(result.ETag)
+	}
+	if result.LastModified != "" {
+		tlfetch.ResponseLastModified.Set(result.LastModified)
+	}
 	if !opts.HideURL {
 		tlfetch.URL = opts.FeedURL
 	}
@@ -0,0 +1,133 @@
+package fetch
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/interline-io/transitland-lib/dmfr"
+)
+
+// Feed tags read by PolicyFromFeed.
+const (
+	PolicyTagInterval       = "fetch_interval"        // Go duration between successful fetches, e.g. "6h"
+	PolicyTagBackoff        = "fetch_backoff"         // Go duration before the first retry after a failure
+	PolicyTagMaxBackoff     = "fetch_max_backoff"     // Go duration cap on the doubling retry delay
+	PolicyTagWindow         = "fetch_window"          // allowed time of day as "HH:MM-HH:MM"; may wrap midnight
+	PolicyTagWindowTimezone = "fetch_window_timezone" // IANA timezone for fetch_window; default UTC
+)
+
+// Policy controls when a feed is due for a scheduled fetch.
+type Policy struct {
+	Interval   time.Duration
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	Window     *Window
+}
+
+// Window is a daily time-of-day range in which fetches are allowed.
+// Start and End are minutes after midnight; End before Start wraps midnight.
+type Window struct {
+	Start    int
+	End      int
+	Location *time.Location
+}
+
+// PolicyFromFeed overrides the defaults with any fetch policy tags set on the feed.
+func PolicyFromFeed(feed *dmfr.Feed, defaults Policy) (Policy, error) {
+	p := defaults
+	for _, d := range []struct {
+		tag string
+		val *time.Duration
+	}{
+		{PolicyTagInterval, &p.Interval},
+		{PolicyTagBackoff, &p.Backoff},
+		{PolicyTagMaxBackoff, &p.MaxBackoff},
+	} {
+		v, ok := feed.Tags.Get(d.tag)
+		if !ok || v == "" {
+			continue
+		}
+		dur, err := time.ParseDuration(v)
+		if err != nil || dur <= 0 {
+			return p, fmt.Errorf("invalid %s tag '%s'", d.tag, v)
+		}
+		*d.val = dur
+	}
+	if v, ok := feed.Tags.Get(PolicyTagWindow); ok && v != "" {
+		tz, _ := feed.Tags.Get(PolicyTagWindowTimezone)
+		w, err := ParseWindow(v, tz)
+		if err != nil {
+			return p, err
+		}
+		p.Window = w
+	}
+	return p, nil
+}
+
+// ParseWindow parses a "HH:MM-HH:MM" window in the named timezone.
+func ParseWindow(value string, tz string) (*Window, error) {
+	var sh, sm, eh, em int
+	if _, err := fmt.Sscanf(value, "%d:%d-%d:%d", &sh, &sm, &eh, &em); err != nil {
+		return nil, fmt.Errorf("invalid %s tag '%s'", PolicyTagWindow, value)
+	}
+	if sh < 0 || sh > 23 || eh < 0 || eh > 24 || (eh == 24 && em != 0) || sm < 0 || sm > 59 || em < 0 || em > 59 {
+		return nil, fmt.Errorf("invalid %s tag '%s'", PolicyTagWindow, value)
+	}
+	w := Window{Start: sh*60 + sm, End: eh*60 + em, Location: time.UTC}
+	if w.Start == w.End {
+		return nil, fmt.Errorf("invalid %s tag '%s': empty window", PolicyTagWindow, value)
+	}
+	if tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s tag '%s'", PolicyTagWindowTimezone, tz)
+		}
+		w.Location = loc
+	}
+	return &w, nil
+}
+
+// Contains reports whether t falls inside the window.
+func (w *Window) Contains(t time.Time) bool {
+	lt := t.In(w.Location)
+	m := lt.Hour()*60 + lt.Minute()
+	if w.Start < w.End {
+		return m >= w.Start && m < w.End
+	}
+	return m >= w.Start || m < w.End
+}
+
+// NextOpen returns t if it is inside the window, otherwise the next time the window opens.
+func (w *Window) NextOpen(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+	lt := t.In(w.Location)
+	open := time.Date(lt.Year(), lt.Month(), lt.Day(), 0, w.Start, 0, 0, w.Location)
+	if !open.After(t) {
+		open = time.Date(lt.Year(), lt.Month(), lt.Day()+1, 0, w.Start, 0, 0, w.Location)
+	}
+	return open.UTC()
+}
+
+// NextFetch returns when a feed is next due, given the time of the last fetch attempt
+// and the number of consecutive failures including that attempt.
+// Retries start at Backoff and double up to MaxBackoff; the result is moved
+// forward into the fetch window, if any.
+func (p Policy) NextFetch(now time.Time, failures int) time.Time {
+	delay := p.Interval
+	if failures > 0 && p.Backoff > 0 {
+		delay = p.Backoff
+		for i := 1; i < failures && (p.MaxBackoff <= 0 || delay < p.MaxBackoff); i++ {
+			delay *= 2
+		}
+		if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+			delay = p.MaxBackoff
+		}
+	}
+	next := now.Add(delay)
+	if p.Window != nil {
+		next = p.Window.NextOpen(next)
+	}
+	return next.UTC()
+}
@@ -0,0 +1,136 @@
+package fetch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/interline-io/transitland-lib/dmfr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyFromFeed(t *testing.T) {
+	defaults := Policy{Interval: 24 * time.Hour, Backoff: 5 * time.Minute, MaxBackoff: 6 * time.Hour}
+	tcs := []struct {
+		name   string
+		tags   map[string]string
+		expect Policy
+		errMsg string
+	}{
+		{
+			name:   "defaults",
+			expect: defaults,
+		},
+		{
+			name:   "tags",
+			tags:   map[string]string{"fetch_interval": "1h", "fetch_backoff": "1m", "fetch_max_backoff": "30m"},
+			expect: Policy{Interval: time.Hour, Backoff: time.Minute, MaxBackoff: 30 * time.Minute},
+		},
+		{
+			name:   "window",
+			tags:   map[string]string{"fetch_window": "22:00-04:30", "fetch_window_timezone": "UTC"},
+			expect: Policy{Interval: 24 * time.Hour, Backoff: 5 * time.Minute, MaxBackoff: 6 * time.Hour, Window: &Window{Start: 22 * 60, End: 4*60 + 30, Location: time.UTC}},
+		},
+		{
+			name:   "invalid interval",
+			tags:   map[string]string{"fetch_interval": "daily"},
+			errMsg: "invalid fetch_interval tag",
+		},
+		{
+			name:   "negative interval",
+			tags:   map[string]string{"fetch_interval": "-1h"},
+			errMsg: "invalid fetch_interval tag",
+		},
+		{
+			name:   "invalid window",
+			tags:   map[string]string{"fetch_window": "25:00-03:00"},
+			errMsg: "invalid fetch_window tag",
+		},
+		{
+			name:   "empty window",
+			tags:   map[string]string{"fetch_window": "03:00-03:00"},
+			errMsg: "empty window",
+		},
+		{
+			name:   "invalid timezone",
+			tags:   map[string]string{"fetch_window": "01:00-03:00", "fetch_window_timezone": "Mars/Olympus"},
+			errMsg: "invalid fetch_window_timezone tag",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			feed := dmfr.Feed{}
+			for k, v := range tc.tags {
+				feed.Tags.Set(k, v)
+			}
+			p, err := PolicyFromFeed(&feed, defaults)
+			if tc.errMsg != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tc.errMsg)
+				}
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.expect, p)
+			}
+		})
+	}
+}
+
+func TestWindow(t *testing.T) {
+	la, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Skip(err)
+	}
+	w, err := ParseWindow("22:00-04:00", "America/Los_Angeles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcs := []struct {
+		name     string
+		t        time.Time
+		contains bool
+		nextOpen time.Time
+	}{
+		{"before midnight", time.Date(2026, 3, 1, 23, 0, 0, 0, la), true, time.Date(2026, 3, 1, 23, 0, 0, 0, la)},
+		{"after midnight", time.Date(2026, 3, 2, 3, 59, 0, 0, la), true, time.Date(2026, 3, 2, 3, 59, 0, 0, la)},
+		{"at close", time.Date(2026, 3, 2, 4, 0, 0, 0, la), false, time.Date(2026, 3, 2, 22, 0, 0, 0, la)},
+		{"afternoon", time.Date(2026, 3, 2, 15, 0, 0, 0, la), false, time.Date(2026, 3, 2, 22, 0, 0, 0, la)},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.contains, w.Contains(tc.t))
+			assert.True(t, tc.nextOpen.Equal(w.NextOpen(tc.t)), "got %s, expected %s", w.NextOpen(tc.t), tc.nextOpen)
+		})
+	}
+	t.Run("daytime window", func(t *testing.T) {
+		w, err := ParseWindow("09:00-17:00", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		now := time.Date(2026, 3, 2, 18, 0, 0, 0, time.UTC)
+		assert.False(t, w.Contains(now))
+		assert.Equal(t, time.Date(2026, 3, 3, 9, 0, 0, 0, time.UTC), w.NextOpen(now))
+		now = time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+		assert.Equal(t, time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC), w.NextOpen(now))
+	})
+}
+
+func TestPolicy_NextFetch(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	p := Policy{Interval: 24 * time.Hour, Backoff: 5 * time.Minute, MaxBackoff: 30 * time.Minute}
+	assert.Equal(t, now.Add(24*time.Hour), p.NextFetch(now, 0))
+	assert.Equal(t, now.Add(5*time.Minute), p.NextFetch(now, 1))
+	assert.Equal(t, now.Add(10*time.Minute), p.NextFetch(now, 2))
+	assert.Equal(t, now.Add(20*time.Minute), p.NextFetch(now, 3))
+	assert.Equal(t, now.Add(30*time.Minute), p.NextFetch(now, 4))
+	assert.Equal(t, now.Add(30*time.Minute), p.NextFetch(now, 100))
+	t.Run("no backoff retries at interval", func(t *testing.T) {
+		p := Policy{Interval: time.Hour}
+		assert.Equal(t, now.Add(time.Hour), p.NextFetch(now, 3))
+	})
+	t.Run("moved into window", func(t *testing.T) {
+		p := Policy{Interval: time.Hour}
+		p.Window = &Window{Start: 2 * 60, End: 4 * 60, Location: time.UTC}
+		assert.Equal(t, time.Date(2026, 3, 3, 2, 0, 0, 0, time.UTC), p.NextFetch(now, 0))
+	})
+}
@@ -28,7 +28,8 @@ func RTFetch(ctx context.Context, fm feedmanager.FeedManager, opts RTFetchOption
 	if opts.FetchedAt.IsZero() {
 		opts.FetchedAt = time.Now().UTC()
 	}
-	feed, tmpfile, resp, fatal := download(ctx, fm, opts.Options)
+	// Never conditional: consumers need the message on every poll.
+	feed, tmpfile, resp, fatal := download(ctx, fm, opts.Options, nil)
 	if tmpfile != "" {
 		defer os.Remove(tmpfile)
 	}
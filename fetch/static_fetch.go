@@ -2,6 +2,8 @@ package fetch
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -40,12 +42,31 @@ type StaticFetchOptions struct {
 // file and creates the FeedVersion + stats records. Creates a FeedFetch record
 // either way. Returns an error only on a serious (db/filesystem) failure; a
 // regular failure such as a 404 or strict-validation error is on Result.FetchError.
+//
+// Unless IgnoreConditional is set, the ETag/Last-Modified of the last successful
+// fetch of the same url are sent as a conditional request; a 304 response reuses
+// that fetch's feed version without downloading (Result.NotModified).
 func StaticFetch(ctx context.Context, fm feedmanager.FeedManager, opts StaticFetchOptions) (StaticFetchResult, error) {
 	out := StaticFetchResult{}
 	if opts.FetchedAt.IsZero() {
 		opts.FetchedAt = time.Now().UTC()
 	}
-	feed, tmpfile, resp, fatal := download(ctx, fm, opts.Options)
+	// Send the cache validators of the last successful fetch of this url.
+	var prev *dmfr.FeedFetch
+	if !opts.IgnoreConditional {
+		recordedURL := opts.FeedURL
+		if opts.HideURL {
+			recordedURL = ""
+		}
+		var err error
+		if prev, err = fm.GetLastFeedFetch(ctx, opts.FeedID, opts.URLType, recordedURL); err != nil {
+			return out, err
+		}
+		if prev != nil && (!prev.FeedVersionID.Valid || (prev.ResponseETag.Val == "" && prev.ResponseLastModified.Val == "")) {
+			prev = nil
+		}
+	}
+	feed, tmpfile, resp, fatal := download(ctx, fm, opts.Options, prev)
 	if tmpfile != "" {
 		defer os.Remove(tmpfile)
 	}
@@ -56,7 +77,28 @@ func StaticFetch(ctx context.Context, fm feedmanager.FeedManager, opts StaticFet
 	}
 
 	var dur fetchDurations
-	if out.FetchError == nil {
+	if out.FetchError == nil && out.NotModified {
+		// Unchanged since the previous fetch: reuse its feed version.
+		fv, err := fm.GetFeedVersion(ctx, prev.FeedVersionID.Int())
+		if errors.Is(err, sql.ErrNoRows) {
+			// The feed version was deleted; download it again in full.
+			log.For(ctx).Info().Int("feed_version_id", prev.FeedVersionID.Int()).Msg("not modified, but previous feed version is missing; fetching again")
+			opts.IgnoreConditional = true
+			return StaticFetch(ctx, fm, opts)
+		} else if err != nil {
+			return out, err
+		}
+		out.Found = true
+		out.FeedVersionID.SetInt(fv.ID)
+		out.FeedVersion = fv
+		out.ResponseSHA1 = prev.ResponseSHA1.Val
+		if out.ETag == "" {
+			out.ETag = prev.ResponseETag.Val
+		}
+		if out.LastModified == "" {
+			out.LastModified = prev.ResponseLastModified.Val
+		}
+	} else if out.FetchError == nil {
 		if err := staticProcess(ctx, fm, tmpfile, opts, &out, &dur); err != nil {
 			log.For(ctx).Error().Err(err).Msg("fatal error during static fetch")
 			return out, err
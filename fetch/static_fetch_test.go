@@ -208,6 +208,82 @@ func TestStaticFetch_Exists(t *testing.T) {
 	})
 }
 
+func TestStaticFetch_Conditional(t *testing.T) {
+	const etag = `"example-v1"`
+	const lastModified = "Mon, 02 Mar 2026 12:00:00 GMT"
+	downloads := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		buf, err := os.ReadFile(ExampleZip.URL)
+		if err != nil {
+			t.Error(err)
+		}
+		downloads++
+		w.Write(buf)
+	}))
+	defer ts.Close()
+	ctx := context.TODO()
+	testdb.TempSqlite(func(atx tldb.Adapter) error {
+		feed := testdb.CreateTestFeed(atx, ts.URL)
+		tmpdir := t.TempDir()
+		fetchOpts := StaticFetchOptions{Options: Options{FeedID: feed.ID, FeedURL: ts.URL, Storage: tmpdir, AllowHTTPFetchUnfiltered: true}}
+		lastFetch := func() dmfr.FeedFetch {
+			tlff := dmfr.FeedFetch{}
+			testdb.ShouldGet(t, atx, &tlff, `SELECT * FROM feed_fetches WHERE feed_id = ? ORDER BY id DESC LIMIT 1`, feed.ID)
+			return tlff
+		}
+
+		// First fetch downloads and records the validators
+		fr1, err := StaticFetch(ctx, feedmanager.NewDBFeedManager(atx), fetchOpts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.False(t, fr1.NotModified)
+		assert.False(t, fr1.Found)
+		tlff := lastFetch()
+		assert.Equal(t, etag, tlff.ResponseETag.Val)
+		assert.Equal(t, lastModified, tlff.ResponseLastModified.Val)
+
+		// Second fetch is answered with 304 and reuses the feed version
+		fr2, err := StaticFetch(ctx, feedmanager.NewDBFeedManager(atx), fetchOpts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, 1, downloads)
+		assert.True(t, fr2.NotModified)
+		assert.True(t, fr2.Found)
+		assert.Nil(t, fr2.FetchError)
+		assert.Equal(t, http.StatusNotModified, fr2.ResponseCode)
+		assert.Equal(t, fr1.FeedVersion.ID, fr2.FeedVersionID.Int())
+		if assert.NotNil(t, fr2.FeedVersion) {
+			assert.Equal(t, ExampleZip.SHA1, fr2.FeedVersion.SHA1)
+		}
+		tlff = lastFetch()
+		assert.True(t, tlff.Success)
+		assert.Equal(t, http.StatusNotModified, tlff.ResponseCode.Int())
+		assert.Equal(t, ExampleZip.SHA1, tlff.ResponseSHA1.Val)
+		assert.Equal(t, etag, tlff.ResponseETag.Val)
+		assert.Equal(t, fr1.FeedVersion.ID, tlff.FeedVersionID.Int())
+
+		// IgnoreConditional always downloads
+		ignoreOpts := fetchOpts
+		ignoreOpts.IgnoreConditional = true
+		fr3, err := StaticFetch(ctx, feedmanager.NewDBFeedManager(atx), ignoreOpts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, 2, downloads)
+		assert.False(t, fr3.NotModified)
+		assert.True(t, fr3.Found)
+		return nil
+	})
+}
+
 func TestStaticFetch_AdditionalTests(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		buf, err := os.ReadFile(ExampleZip.URL)
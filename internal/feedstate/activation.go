@@ -0,0 +1,265 @@
+package feedstate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/interline-io/log"
+	"github.com/interline-io/transitland-lib/dmfr"
+	"github.com/interline-io/transitland-lib/server/dbutil"
+	"github.com/interline-io/transitland-lib/tt"
+	sq "github.com/irees/squirrel"
+)
+
+// ActivationPolicy is a set of rules for choosing the active feed version of a feed.
+//
+// Candidates are the successfully imported feed versions of the feed, newest
+// (by fetched_at) first. The first candidate that passes every enabled rule is
+// chosen; reaching the currently active version keeps it. Older versions never
+// replace the active one, so a feed with no passing newer version is unchanged.
+type ActivationPolicy struct {
+	// Date the rules are evaluated for; zero means today (UTC).
+	Date time.Time `json:"date"`
+	// RequireStarted rejects versions whose service has not started by Date,
+	// keeping the previous version active until the new one's start date.
+	RequireStarted bool `json:"require_started"`
+	// RequireCoverage rejects versions whose service window does not cover Date.
+	RequireCoverage bool `json:"require_coverage"`
+	// MaxServiceDrop rejects versions whose weekly scheduled service is lower
+	// than the active version's by more than this fraction, e.g. 0.3.
+	MaxServiceDrop tt.Float `json:"max_service_drop"`
+	// MaxErrorRate rejects versions whose latest validation report has more
+	// errors per csv row than this fraction, or that have no validation report.
+	MaxErrorRate tt.Float `json:"max_error_rate"`
+}
+
+// ActivationRejection records why a candidate feed version was not chosen.
+type ActivationRejection struct {
+	FeedVersionID int    `json:"feed_version_id"`
+	Reason        string `json:"reason"`
+}
+
+// ActivationDecision is the result of evaluating an ActivationPolicy for one feed.
+// A zero feed version ID means none.
+type ActivationDecision struct {
+	FeedID              int                   `json:"feed_id"`
+	FeedOnestopID       string                `json:"feed_onestop_id"`
+	ActiveFeedVersionID int                   `json:"active_feed_version_id"`
+	TargetFeedVersionID int                   `json:"target_feed_version_id"`
+	Rejected            []ActivationRejection `json:"rejected,omitempty"`
+}
+
+// Changed reports whether applying the decision changes the active feed version.
+func (d ActivationDecision) Changed() bool {
+	return d.TargetFeedVersionID != 0 && d.TargetFeedVersionID != d.ActiveFeedVersionID
+}
+
+type activationFeed struct {
+	ID                  int    `db:"id"`
+	OnestopID           string `db:"onestop_id"`
+	ActiveFeedVersionID tt.Int `db:"active_feed_version_id"`
+}
+
+type activationCandidate struct {
+	ID                   int     `db:"id"`
+	FeedID               int     `db:"feed_id"`
+	EarliestCalendarDate tt.Date `db:"earliest_calendar_date"`
+	LatestCalendarDate   tt.Date `db:"latest_calendar_date"`
+}
+
+// PlanActivation evaluates the policy for the given feeds, or every gtfs feed if none
+// are given, and logs the planned changes without making them.
+func (m *Manager) PlanActivation(ctx context.Context, policy ActivationPolicy, feedIDs []int) ([]ActivationDecision, error) {
+	date := policy.Date
+	if date.IsZero() {
+		date = time.Now().UTC()
+	}
+	day := date.Format("2006-01-02")
+
+	q := m.adapter.Sqrl().
+		Select("current_feeds.id", "current_feeds.onestop_id", "feed_states.active_feed_version_id").
+		From("current_feeds").
+		LeftJoin("feed_states ON feed_states.feed_id = current_feeds.id").
+		Where("current_feeds.deleted_at IS NULL").
+		Where(sq.Eq{"current_feeds.spec": "gtfs"}).
+		OrderBy("current_feeds.id")
+	if len(feedIDs) > 0 {
+		q = q.Where(sq.Eq{"current_feeds.id": feedIDs})
+	}
+	var feeds []activationFeed
+	if err := dbutil.Select(ctx, m.adapter.DBX(), q, &feeds); err != nil {
+		return nil, fmt.Errorf("failed to query feeds: %w", err)
+	}
+
+	var ret []ActivationDecision
+	for _, feed := range feeds {
+		var candidates []activationCandidate
+		if err := dbutil.Select(ctx, m.adapter.DBX(), m.adapter.Sqrl().
+			Select("feed_versions.id", "feed_versions.feed_id", "feed_versions.earliest_calendar_date", "feed_versions.latest_calendar_date").
+			From("feed_versions").
+			Join("feed_version_gtfs_imports ON feed_version_gtfs_imports.feed_version_id = feed_versions.id").
+			Where(sq.Eq{"feed_versions.feed_id": feed.ID}).
+			Where("feed_versions.deleted_at IS NULL").
+			Where(sq.Eq{"feed_version_gtfs_imports.success": true}).
+			OrderBy("feed_versions.fetched_at DESC", "feed_versions.id DESC"), &candidates); err != nil {
+			return nil, fmt.Errorf("failed to query feed versions for feed %d: %w", feed.ID, err)
+		}
+		decision := ActivationDecision{
+			FeedID:              feed.ID,
+			FeedOnestopID:       feed.OnestopID,
+			ActiveFeedVersionID: feed.ActiveFeedVersionID.Int(),
+		}
+		for _, c := range candidates {
+			if c.ID == decision.ActiveFeedVersionID {
+				decision.TargetFeedVersionID = c.ID
+				break
+			}
+			reason, err := m.checkActivationCandidate(ctx, policy, date, day, c, decision.ActiveFeedVersionID)
+			if err != nil {
+				return nil, err
+			}
+			if reason != "" {
+				decision.Rejected = append(decision.Rejected, ActivationRejection{FeedVersionID: c.ID, Reason: reason})
+				continue
+			}
+			decision.TargetFeedVersionID = c.ID
+			break
+		}
+		if decision.TargetFeedVersionID == 0 {
+			decision.TargetFeedVersionID = decision.ActiveFeedVersionID
+		}
+		ret = append(ret, decision)
+	}
+	return ret, nil
+}
+
+// ApplyActivation activates the target feed version of every changed decision.
+func (m *Manager) ApplyActivation(ctx context.Context, decisions []ActivationDecision) error {
+	for _, d := range decisions {
+		if !d.Changed() {
+			continue
+		}
+		log.For(ctx).Info().
+			Str("feed_onestop_id", d.FeedOnestopID).
+			Int("feed_version_id", d.TargetFeedVersionID).
+			Int("previous_feed_version_id", d.ActiveFeedVersionID).
+			Msg("activating feed version by policy")
+		if err := m.ActivateFeedVersion(ctx, d.TargetFeedVersionID); err != nil {
+			return fmt.Errorf("failed to activate feed version %d: %w", d.TargetFeedVersionID, err)
+		}
+	}
+	return nil
+}
+
+// checkActivationCandidate returns the reason a candidate fails the policy, or "" if it passes.
+func (m *Manager) checkActivationCandidate(ctx context.Context, policy ActivationPolicy, date time.Time, day string, c activationCandidate, activeID int) (string, error) {
+	start := c.EarliestCalendarDate.Format("2006-01-02")
+	end := c.LatestCalendarDate.Format("2006-01-02")
+	if (policy.RequireStarted || policy.RequireCoverage) && c.EarliestCalendarDate.Valid && start > day {
+		return fmt.Sprintf("service starts %s", start), nil
+	}
+	if policy.RequireCoverage && c.LatestCalendarDate.Valid && end < day {
+		return fmt.Sprintf("service ended %s", end), nil
+	}
+	if policy.MaxServiceDrop.Valid && activeID != 0 {
+		// Compare the first week the candidate would be in service.
+		week := date
+		if c.EarliestCalendarDate.Valid && c.EarliestCalendarDate.Val.After(week) {
+			week = c.EarliestCalendarDate.Val
+		}
+		activeService, ok, err := m.weeklyService(ctx, activeID, week)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			activeService, _, err = m.weeklyService(ctx, activeID, date)
+			if err != nil {
+				return "", err
+			}
+		}
+		if activeService > 0 {
+			candidateService, _, err := m.weeklyService(ctx, c.ID, week)
+			if err != nil {
+				return "", err
+			}
+			drop := 1 - float64(candidateService)/float64(activeService)
+			if drop > policy.MaxServiceDrop.Val {
+				return fmt.Sprintf("weekly service drops %.0f%% vs active feed version %d", drop*100, activeID), nil
+			}
+		}
+	}
+	if policy.MaxErrorRate.Valid {
+		rate, ok, err := m.validationErrorRate(ctx, c.ID)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "no validation report", nil
+		}
+		if rate > policy.MaxErrorRate.Val {
+			return fmt.Sprintf("validation error rate %.4f exceeds %.4f", rate, policy.MaxErrorRate.Val), nil
+		}
+	}
+	return "", nil
+}
+
+// weeklyService returns the scheduled service seconds of the service level week
+// containing date, and whether there is one.
+func (m *Manager) weeklyService(ctx context.Context, fvid int, date time.Time) (int, bool, error) {
+	var levels []dmfr.FeedVersionServiceLevel
+	day := date.Format("2006-01-02")
+	if err := dbutil.Select(ctx, m.adapter.DBX(), m.adapter.Sqrl().
+		Select("*").
+		From("feed_version_service_levels").
+		Where(sq.Eq{"feed_version_id": fvid}).
+		OrderBy("start_date"), &levels); err != nil {
+		return 0, false, fmt.Errorf("failed to query service levels for feed version %d: %w", fvid, err)
+	}
+	for _, level := range levels {
+		if level.StartDate.Format("2006-01-02") <= day && day <= level.EndDate.Format("2006-01-02") {
+			return level.Total(), true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// validationErrorRate returns the errors per csv row of the feed version's latest
+// validation report, and whether there is a report.
+func (m *Manager) validationErrorRate(ctx context.Context, fvid int) (float64, bool, error) {
+	var reportIDs []int
+	if err := dbutil.Select(ctx, m.adapter.DBX(), m.adapter.Sqrl().
+		Select("id").
+		From("tl_validation_reports").
+		Where(sq.Eq{"feed_version_id": fvid}).
+		Where(sq.Eq{"includes_static": true}).
+		OrderBy("id DESC").
+		Limit(1), &reportIDs); err != nil {
+		return 0, false, fmt.Errorf("failed to query validation reports for feed version %d: %w", fvid, err)
+	}
+	if len(reportIDs) == 0 {
+		return 0, false, nil
+	}
+	var errorCount, rowCount int
+	if err := dbutil.Get(ctx, m.adapter.DBX(), m.adapter.Sqrl().
+		Select("COALESCE(SUM(count), 0)").
+		From("tl_validation_report_error_groups").
+		Where(sq.Eq{"validation_report_id": reportIDs[0]}).
+		Where(sq.Eq{"level": 0}), &errorCount); err != nil {
+		return 0, false, fmt.Errorf("failed to count validation errors for feed version %d: %w", fvid, err)
+	}
+	if err := dbutil.Get(ctx, m.adapter.DBX(), m.adapter.Sqrl().
+		Select("COALESCE(SUM(rows), 0)").
+		From("feed_version_file_infos").
+		Where(sq.Eq{"feed_version_id": fvid}).
+		Where(sq.Eq{"csv_like": true}), &rowCount); err != nil {
+		return 0, false, fmt.Errorf("failed to count rows for feed version %d: %w", fvid, err)
+	}
+	if rowCount == 0 {
+		if errorCount > 0 {
+			return 1, true, nil
+		}
+		return 0, true, nil
+	}
+	return float64(errorCount) / float64(rowCount), true, nil
+}
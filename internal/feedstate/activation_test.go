@@ -0,0 +1,194 @@
+package feedstate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/interline-io/transitland-lib/dmfr"
+	"github.com/interline-io/transitland-lib/tldb"
+	"github.com/interline-io/transitland-lib/tt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustDate(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse("2006-01-02", s)
+	require.NoError(t, err)
+	return d
+}
+
+// addActivationVersion inserts an imported feed version with a single service level
+// spanning its service window, and returns its id.
+func addActivationVersion(t *testing.T, adapter tldb.Adapter, feedID int, fetchedAt string, start string, end string, weeklyService int) int {
+	t.Helper()
+	ctx := context.Background()
+	fv := dmfr.FeedVersion{SHA1: "sha1-" + fetchedAt, File: fetchedAt + ".zip"}
+	fv.FeedID = feedID
+	fv.FetchedAt = mustDate(t, fetchedAt)
+	fv.EarliestCalendarDate = tt.NewDate(mustDate(t, start))
+	fv.LatestCalendarDate = tt.NewDate(mustDate(t, end))
+	fvid, err := adapter.Insert(ctx, &fv)
+	require.NoError(t, err)
+	fvi := dmfr.FeedVersionImport{Success: true, ImportSource: dmfr.ImportSourceManual}
+	fvi.FeedVersionID = fvid
+	_, err = adapter.Insert(ctx, &fvi)
+	require.NoError(t, err)
+	sl := dmfr.FeedVersionServiceLevel{StartDate: fv.EarliestCalendarDate, EndDate: fv.LatestCalendarDate, Monday: weeklyService}
+	sl.FeedVersionID = fvid
+	_, err = adapter.Insert(ctx, &sl)
+	require.NoError(t, err)
+	return fvid
+}
+
+// addValidationReport records a static validation report with errorCount errors over rowCount rows.
+func addValidationReport(t *testing.T, adapter tldb.Adapter, fvid int, errorCount int, rowCount int) {
+	t.Helper()
+	ctx := context.Background()
+	var reportID int
+	err := adapter.Sqrl().
+		Insert("tl_validation_reports").
+		Columns("feed_version_id", "success", "includes_static").
+		Values(fvid, true, true).
+		Suffix("RETURNING id").
+		QueryRowContext(ctx).
+		Scan(&reportID)
+	require.NoError(t, err)
+	_, err = adapter.Sqrl().
+		Insert("tl_validation_report_error_groups").
+		Columns("validation_report_id", "filename", "field", "error_type", "error_code", "group_key", "count", "level").
+		Values(reportID, "stops.txt", "stop_name", "InvalidFieldError", "", "", errorCount, 0).
+		ExecContext(ctx)
+	require.NoError(t, err)
+	fi := dmfr.FeedVersionFileInfo{Name: "stops.txt", Rows: int64(rowCount), CSVLike: true}
+	fi.FeedVersionID = fvid
+	_, err = adapter.Insert(ctx, &fi)
+	require.NoError(t, err)
+}
+
+func TestManager_PlanActivation(t *testing.T) {
+	ctx := context.Background()
+	setup := func(t *testing.T) (tldb.Adapter, int, int, int) {
+		adapter, feedID, _ := setupTestDB(t, testFeedOnestopID, "")
+		t.Cleanup(func() { adapter.Close() })
+		_, err := adapter.Sqrl().Update("current_feeds").Set("spec", "gtfs").ExecContext(ctx)
+		require.NoError(t, err)
+		oldFV := addActivationVersion(t, adapter, feedID, "2026-01-01", "2026-01-01", "2026-06-30", 1000)
+		newFV := addActivationVersion(t, adapter, feedID, "2026-02-01", "2026-03-01", "2026-12-31", 900)
+		require.NoError(t, NewManager(adapter).ActivateFeedVersion(ctx, oldFV))
+		return adapter, feedID, oldFV, newFV
+	}
+	tcs := []struct {
+		name       string
+		policy     ActivationPolicy
+		keepActive bool
+		reason     string
+	}{
+		{
+			name:   "no rules activates newest",
+			policy: ActivationPolicy{Date: mustDate(t, "2026-02-15")},
+		},
+		{
+			name:       "keep active until start",
+			policy:     ActivationPolicy{Date: mustDate(t, "2026-02-15"), RequireStarted: true},
+			keepActive: true,
+			reason:     "service starts 2026-03-01",
+		},
+		{
+			name:   "started",
+			policy: ActivationPolicy{Date: mustDate(t, "2026-03-01"), RequireStarted: true},
+		},
+		{
+			name:       "coverage",
+			policy:     ActivationPolicy{Date: mustDate(t, "2027-01-15"), RequireCoverage: true},
+			keepActive: true,
+			reason:     "service ended 2026-12-31",
+		},
+		{
+			name:   "service drop within limit",
+			policy: ActivationPolicy{Date: mustDate(t, "2026-03-01"), MaxServiceDrop: tt.NewFloat(0.3)},
+		},
+		{
+			name:       "service drop exceeds limit",
+			policy:     ActivationPolicy{Date: mustDate(t, "2026-03-01"), MaxServiceDrop: tt.NewFloat(0.05)},
+			keepActive: true,
+			reason:     "weekly service drops 10%",
+		},
+		{
+			name:       "error rate requires report",
+			policy:     ActivationPolicy{Date: mustDate(t, "2026-03-01"), MaxErrorRate: tt.NewFloat(0.01)},
+			keepActive: true,
+			reason:     "no validation report",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			adapter, feedID, oldFV, newFV := setup(t)
+			m := NewManager(adapter)
+			decisions, err := m.PlanActivation(ctx, tc.policy, nil)
+			require.NoError(t, err)
+			require.Len(t, decisions, 1)
+			d := decisions[0]
+			assert.Equal(t, feedID, d.FeedID)
+			assert.Equal(t, testFeedOnestopID, d.FeedOnestopID)
+			assert.Equal(t, oldFV, d.ActiveFeedVersionID)
+			if tc.keepActive {
+				assert.Equal(t, oldFV, d.TargetFeedVersionID)
+				assert.False(t, d.Changed())
+				if assert.Len(t, d.Rejected, 1) {
+					assert.Equal(t, newFV, d.Rejected[0].FeedVersionID)
+					assert.Contains(t, d.Rejected[0].Reason, tc.reason)
+				}
+			} else {
+				assert.Equal(t, newFV, d.TargetFeedVersionID)
+				assert.True(t, d.Changed())
+			}
+			// Planning changes nothing
+			verifyPointers(t, adapter, feedID, &oldFV, &oldFV)
+		})
+	}
+	t.Run("error rate", func(t *testing.T) {
+		adapter, _, oldFV, newFV := setup(t)
+		m := NewManager(adapter)
+		addValidationReport(t, adapter, newFV, 5, 100)
+		policy := ActivationPolicy{Date: mustDate(t, "2026-03-01"), MaxErrorRate: tt.NewFloat(0.01)}
+		decisions, err := m.PlanActivation(ctx, policy, nil)
+		require.NoError(t, err)
+		require.Len(t, decisions, 1)
+		assert.Equal(t, oldFV, decisions[0].TargetFeedVersionID)
+		if assert.Len(t, decisions[0].Rejected, 1) {
+			assert.Contains(t, decisions[0].Rejected[0].Reason, "validation error rate 0.0500")
+		}
+		policy.MaxErrorRate = tt.NewFloat(0.1)
+		decisions, err = m.PlanActivation(ctx, policy, nil)
+		require.NoError(t, err)
+		assert.Equal(t, newFV, decisions[0].TargetFeedVersionID)
+	})
+	t.Run("apply", func(t *testing.T) {
+		adapter, feedID, _, newFV := setup(t)
+		m := NewManager(adapter)
+		decisions, err := m.PlanActivation(ctx, ActivationPolicy{Date: mustDate(t, "2026-03-01"), RequireCoverage: true}, []int{feedID})
+		require.NoError(t, err)
+		require.NoError(t, m.ApplyActivation(ctx, decisions))
+		verifyPointers(t, adapter, feedID, &newFV, &newFV)
+		// Re-planning is a no-op
+		decisions, err = m.PlanActivation(ctx, ActivationPolicy{Date: mustDate(t, "2026-03-01"), RequireCoverage: true}, []int{feedID})
+		require.NoError(t, err)
+		assert.False(t, decisions[0].Changed())
+	})
+	t.Run("no active version", func(t *testing.T) {
+		adapter, feedID, _ := setupTestDB(t, testFeedOnestopID, "")
+		defer adapter.Close()
+		_, err := adapter.Sqrl().Update("current_feeds").Set("spec", "gtfs").ExecContext(ctx)
+		require.NoError(t, err)
+		oldFV := addActivationVersion(t, adapter, feedID, "2026-01-01", "2026-01-01", "2026-06-30", 1000)
+		addActivationVersion(t, adapter, feedID, "2026-02-01", "2026-03-01", "2026-12-31", 900)
+		decisions, err := NewManager(adapter).PlanActivation(ctx, ActivationPolicy{Date: mustDate(t, "2026-02-15"), RequireStarted: true}, nil)
+		require.NoError(t, err)
+		require.Len(t, decisions, 1)
+		assert.Equal(t, 0, decisions[0].ActiveFeedVersionID)
+		assert.Equal(t, oldFV, decisions[0].TargetFeedVersionID)
+		assert.True(t, decisions[0].Changed())
+	})
+}
@@ -182,6 +182,23 @@ func (m *Manager) GetFeedIDForFeedVersion(ctx context.Context, fvid int) (int, e
 	return feedID, err
 }
 
+// GetFeedIDsForOnestopIDs gets the feed ids for the given feed onestop ids, in order
+func (m *Manager) GetFeedIDsForOnestopIDs(ctx context.Context, onestopIDs []string) ([]int, error) {
+	var ret []int
+	for _, osid := range onestopIDs {
+		var feedID int
+		if err := dbutil.Get(ctx, m.adapter.DBX(), m.adapter.Sqrl().
+			Select("id").
+			From("current_feeds").
+			Where(sq.Eq{"onestop_id": osid}).
+			Where("deleted_at IS NULL"), &feedID); err != nil {
+			return nil, fmt.Errorf("problem with feed '%s': %w", osid, err)
+		}
+		ret = append(ret, feedID)
+	}
+	return ret, nil
+}
+
 // sortedColumnsAndSelects converts a map of column->expression into sorted parallel slices
 func sortedColumnsAndSelects(fields map[string]string) ([]string, []string) {
 	columns := slices.Sorted(maps.Keys(fields))
@@ -0,0 +1,280 @@
+package graph
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+
+	"github.com/interline-io/transitland-lib/gtfs"
+)
+
+// PathwayWalkSpeed is used to estimate the traversal time of pathways without
+// traversal_time, in meters per second.
+const PathwayWalkSpeed = 1.0
+
+// DefaultPathwayTraversalTime is used for pathways without traversal_time or length, in seconds.
+const DefaultPathwayTraversalTime = 30
+
+// PathwayGraph is a directed graph of the pathways within stations.
+// Nodes are stops keyed by EntityID; pathways reference stops the same way,
+// so both tlcsv and tldb entities can be added.
+type PathwayGraph struct {
+	stops    map[string]*gtfs.Stop
+	edges    map[string][]PathwayEdge
+	children map[string][]string
+}
+
+// PathwayEdge is one direction of a pathway. Pathway is nil for the implicit
+// link between a boarding area and its platform.
+type PathwayEdge struct {
+	Pathway  *gtfs.Pathway
+	From     string
+	To       string
+	Reversed bool
+}
+
+// StepFree reports whether the edge avoids stairs and escalators.
+func (e PathwayEdge) StepFree() bool {
+	if e.Pathway == nil {
+		return true
+	}
+	mode := e.Pathway.PathwayMode.Val
+	return mode != 2 && mode != 4 && e.Pathway.StairCount.Val == 0
+}
+
+// TraversalTime returns the traversal time of the edge in seconds, estimated
+// from its length when traversal_time is not set.
+func (e PathwayEdge) TraversalTime() int {
+	if e.Pathway == nil {
+		return 0
+	}
+	if e.Pathway.TraversalTime.Valid {
+		return e.Pathway.TraversalTime.Int()
+	}
+	if e.Pathway.Length.Valid {
+		return int(math.Ceil(e.Pathway.Length.Val / PathwayWalkSpeed))
+	}
+	return DefaultPathwayTraversalTime
+}
+
+// PathwayRouteOptions constrain a route search.
+type PathwayRouteOptions struct {
+	// StepFree avoids stairs and escalators.
+	StepFree bool
+	// Unavailable pathways, by EntityID, e.g. elevators that are out of service.
+	Unavailable map[string]bool
+}
+
+// PathwayRoute is the fastest route between two stops of a station.
+type PathwayRoute struct {
+	From          string
+	To            string
+	Edges         []PathwayEdge
+	TraversalTime int
+}
+
+// StepFree reports whether every edge of the route avoids stairs and escalators.
+func (r PathwayRoute) StepFree() bool {
+	for _, e := range r.Edges {
+		if !e.StepFree() {
+			return false
+		}
+	}
+	return true
+}
+
+// NewPathwayGraph returns an empty PathwayGraph.
+func NewPathwayGraph() *PathwayGraph {
+	return &PathwayGraph{
+		stops:    map[string]*gtfs.Stop{},
+		edges:    map[string][]PathwayEdge{},
+		children: map[string][]string{},
+	}
+}
+
+// AddStop adds a stop. A boarding area is linked to its platform in both directions.
+func (g *PathwayGraph) AddStop(ent *gtfs.Stop) {
+	eid := ent.EntityID()
+	g.stops[eid] = ent
+	if parent := ent.ParentStation.Val; parent != "" {
+		g.children[parent] = append(g.children[parent], eid)
+		if ent.LocationType.Val == 4 {
+			g.edges[eid] = append(g.edges[eid], PathwayEdge{From: eid, To: parent})
+			g.edges[parent] = append(g.edges[parent], PathwayEdge{From: parent, To: eid})
+		}
+	}
+}
+
+// AddPathway adds a pathway, and its reverse direction if it is bidirectional.
+func (g *PathwayGraph) AddPathway(ent *gtfs.Pathway) {
+	from, to := ent.FromStopID.Val, ent.ToStopID.Val
+	g.edges[from] = append(g.edges[from], PathwayEdge{Pathway: ent, From: from, To: to})
+	if ent.IsBidirectional.Val == 1 {
+		g.edges[to] = append(g.edges[to], PathwayEdge{Pathway: ent, From: to, To: from, Reversed: true})
+	}
+}
+
+// Stop returns a stop by EntityID.
+func (g *PathwayGraph) Stop(eid string) (*gtfs.Stop, bool) {
+	s, ok := g.stops[eid]
+	return s, ok
+}
+
+// Stations returns the stations with at least one pathway, sorted by EntityID.
+func (g *PathwayGraph) Stations() []string {
+	var ret []string
+	for eid, s := range g.stops {
+		if s.LocationType.Val != 1 {
+			continue
+		}
+		for _, child := range g.children[eid] {
+			if g.hasPathway(child) {
+				ret = append(ret, eid)
+				break
+			}
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// Entrances returns the entrances of a station, sorted by EntityID.
+func (g *PathwayGraph) Entrances(station string) []string {
+	return g.stationStops(station, 2)
+}
+
+// Platforms returns the platforms of a station, sorted by EntityID.
+func (g *PathwayGraph) Platforms(station string) []string {
+	return g.stationStops(station, 0)
+}
+
+func (g *PathwayGraph) stationStops(station string, locationType int64) []string {
+	var ret []string
+	for _, eid := range g.children[station] {
+		if s, ok := g.stops[eid]; ok && s.LocationType.Val == locationType {
+			ret = append(ret, eid)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func (g *PathwayGraph) hasPathway(eid string) bool {
+	for _, e := range g.edges[eid] {
+		if e.Pathway != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Routes returns the fastest route from a stop to each of the given stops it can reach.
+func (g *PathwayGraph) Routes(from string, to []string, opts PathwayRouteOptions) []PathwayRoute {
+	dist, prev := g.search(from, opts)
+	var ret []PathwayRoute
+	for _, t := range to {
+		d, ok := dist[t]
+		if !ok {
+			continue
+		}
+		r := PathwayRoute{From: from, To: t, TraversalTime: d}
+		for cur := t; cur != from; {
+			e := prev[cur]
+			r.Edges = append(r.Edges, e)
+			cur = e.From
+		}
+		for i, j := 0, len(r.Edges)-1; i < j; i, j = i+1, j-1 {
+			r.Edges[i], r.Edges[j] = r.Edges[j], r.Edges[i]
+		}
+		ret = append(ret, r)
+	}
+	return ret
+}
+
+// Route returns the fastest route between two stops, if any.
+func (g *PathwayGraph) Route(from string, to string, opts PathwayRouteOptions) (PathwayRoute, bool) {
+	routes := g.Routes(from, []string{to}, opts)
+	if len(routes) == 0 {
+		return PathwayRoute{}, false
+	}
+	return routes[0], true
+}
+
+// StepFreeUnreachablePlatforms returns the platforms of a station that no
+// entrance reaches without stairs or escalators. Stations without entrances are skipped.
+func (g *PathwayGraph) StepFreeUnreachablePlatforms(station string, opts PathwayRouteOptions) []string {
+	entrances := g.Entrances(station)
+	if len(entrances) == 0 {
+		return nil
+	}
+	opts.StepFree = true
+	platforms := g.Platforms(station)
+	reached := map[string]bool{}
+	for _, ent := range entrances {
+		for _, r := range g.Routes(ent, platforms, opts) {
+			reached[r.To] = true
+		}
+	}
+	var ret []string
+	for _, p := range platforms {
+		if !reached[p] {
+			ret = append(ret, p)
+		}
+	}
+	return ret
+}
+
+// search finds the fastest times from a stop using Dijkstra's algorithm.
+func (g *PathwayGraph) search(from string, opts PathwayRouteOptions) (map[string]int, map[string]PathwayEdge) {
+	dist := map[string]int{from: 0}
+	prev := map[string]PathwayEdge{}
+	done := map[string]bool{}
+	pq := &pathwayQueue{{stop: from}}
+	for pq.Len() > 0 {
+		cur := heap.Pop(pq).(pathwayQueueItem)
+		if done[cur.stop] {
+			continue
+		}
+		done[cur.stop] = true
+		for _, e := range g.edges[cur.stop] {
+			if opts.StepFree && !e.StepFree() {
+				continue
+			}
+			if e.Pathway != nil && opts.Unavailable[e.Pathway.EntityID()] {
+				continue
+			}
+			d := cur.dist + e.TraversalTime()
+			if old, ok := dist[e.To]; ok && old <= d {
+				continue
+			}
+			dist[e.To] = d
+			prev[e.To] = e
+			heap.Push(pq, pathwayQueueItem{stop: e.To, dist: d})
+		}
+	}
+	return dist, prev
+}
+
+type pathwayQueueItem struct {
+	stop string
+	dist int
+}
+
+type pathwayQueue []pathwayQueueItem
+
+func (q pathwayQueue) Len() int { return len(q) }
+func (q pathwayQueue) Less(i, j int) bool {
+	if q[i].dist == q[j].dist {
+		return q[i].stop < q[j].stop
+	}
+	return q[i].dist < q[j].dist
+}
+func (q pathwayQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *pathwayQueue) Push(x any)   { *q = append(*q, x.(pathwayQueueItem)) }
+func (q *pathwayQueue) Pop() any {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	*q = old[:n-1]
+	return item
+}
@@ -0,0 +1,74 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/interline-io/transitland-lib/internal/testpath"
+	"github.com/interline-io/transitland-lib/tlcsv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Pentagon City (STN_C08) in the WMATA feed has a street elevator entrance
+// (ENT_C08_E_EL), escalator-only entrances, and mezzanine-to-platform
+// elevators and escalators.
+func newTestPathwayGraph(t *testing.T) *PathwayGraph {
+	reader, err := tlcsv.NewReader(testpath.RelPath("testdata/server/gtfs/wmata.zip"))
+	require.NoError(t, err)
+	g := NewPathwayGraph()
+	for ent := range reader.Stops() {
+		ent := ent
+		g.AddStop(&ent)
+	}
+	for ent := range reader.Pathways() {
+		ent := ent
+		g.AddPathway(&ent)
+	}
+	return g
+}
+
+func pathwayIDs(r PathwayRoute) []string {
+	var ret []string
+	for _, e := range r.Edges {
+		ret = append(ret, e.Pathway.PathwayID.Val)
+	}
+	return ret
+}
+
+func TestPathwayGraph(t *testing.T) {
+	g := newTestPathwayGraph(t)
+	assert.Contains(t, g.Stations(), "STN_C08")
+	assert.Equal(t, []string{"ENT_C08_E", "ENT_C08_E_EL", "ENT_C08_W"}, g.Entrances("STN_C08"))
+	assert.Equal(t, []string{"PF_C08_1", "PF_C08_2"}, g.Platforms("STN_C08"))
+
+	t.Run("fastest", func(t *testing.T) {
+		r, ok := g.Route("ENT_C08_W", "PF_C08_2", PathwayRouteOptions{})
+		require.True(t, ok)
+		assert.Equal(t, 175, r.TraversalTime)
+		assert.Equal(t, []string{"C08_137110", "C08_137112", "C08_137119", "C08_137120", "C08_137128", "C08_137129", "C08_137130", "C08_137146"}, pathwayIDs(r))
+		assert.False(t, r.StepFree())
+		// Bidirectional pathways may be traversed in reverse
+		back, ok := g.Route("PF_C08_2", "NODE_C08_FG_PAID", PathwayRouteOptions{})
+		require.True(t, ok)
+		assert.True(t, back.Edges[0].Reversed)
+	})
+
+	t.Run("step-free", func(t *testing.T) {
+		_, ok := g.Route("ENT_C08_W", "PF_C08_2", PathwayRouteOptions{StepFree: true})
+		assert.False(t, ok, "west entrance has only escalators")
+		r, ok := g.Route("ENT_C08_E_EL", "PF_C08_2", PathwayRouteOptions{StepFree: true})
+		require.True(t, ok)
+		assert.True(t, r.StepFree())
+		assert.Equal(t, 478, r.TraversalTime)
+		assert.Contains(t, pathwayIDs(r), "C08_137123")
+		assert.Empty(t, g.StepFreeUnreachablePlatforms("STN_C08", PathwayRouteOptions{}))
+	})
+
+	t.Run("elevator out", func(t *testing.T) {
+		opts := PathwayRouteOptions{Unavailable: map[string]bool{"C08_137123": true}}
+		assert.Equal(t, []string{"PF_C08_2"}, g.StepFreeUnreachablePlatforms("STN_C08", opts))
+		// Still reachable using the escalators
+		_, ok := g.Route("ENT_C08_E_EL", "PF_C08_2", opts)
+		assert.True(t, ok)
+	})
+}
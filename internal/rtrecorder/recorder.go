@@ -0,0 +1,387 @@
+// Package rtrecorder records GTFS-RT observations as historical performance data.
+package rtrecorder
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/interline-io/log"
+	"github.com/interline-io/transitland-lib/gtfs"
+	"github.com/interline-io/transitland-lib/rt"
+	"github.com/interline-io/transitland-lib/rt/pb"
+	"github.com/interline-io/transitland-lib/server/dbutil"
+	"github.com/interline-io/transitland-lib/tldb"
+	"github.com/interline-io/transitland-lib/tt"
+	sq "github.com/irees/squirrel"
+)
+
+// StopObservation is a row of ext_performance_stop_observations.
+type StopObservation struct {
+	FeedVersionID          int
+	Source                 tt.String
+	SourceID               tt.String
+	TripID                 tt.String
+	RouteID                tt.String
+	AgencyID               tt.String
+	DirectionID            tt.Int
+	TripStartDate          tt.Date
+	TripStartTime          tt.Seconds
+	ScheduleRelationship   tt.String
+	VehicleID              tt.String
+	StopSequence           tt.Int
+	FromStopID             tt.String
+	ToStopID               tt.String
+	ScheduledArrivalTime   tt.Seconds
+	ScheduledDepartureTime tt.Seconds
+	ObservedArrivalTime    tt.Seconds
+	ObservedDepartureTime  tt.Seconds
+	ObservedArrivalDelay   tt.Int
+	DwellTimeSecs          tt.Int
+	ScheduledDwellTimeSecs tt.Int
+	tt.DatabaseEntity
+	tt.Timestamps
+}
+
+func (ent *StopObservation) TableName() string {
+	return "ext_performance_stop_observations"
+}
+
+// key identifies the trip segment an observation belongs to, for deduplication across polls.
+// The trip start time distinguishes the trips of a frequency-based trip_id.
+func (ent *StopObservation) key() string {
+	return fmt.Sprintf("%s|%s|%s|%d|%s|%s", ent.Source.Val, ent.TripID.Val, ent.TripStartDate.Format("2006-01-02"), ent.TripStartTime.Int(), ent.FromStopID.Val, ent.ToStopID.Val)
+}
+
+// merge updates ent with a newer observation of the same segment, reporting whether it changed.
+// A trip update replaces the observed times, since later messages refine them, and the
+// schedule relationship, e.g. when a trip is canceled;
+// vehicle positions extend the observed dwell from the earliest to the latest position.
+func (ent *StopObservation) merge(obs *StopObservation) bool {
+	arrival, departure := obs.ObservedArrivalTime, obs.ObservedDepartureTime
+	if ent.Source.Val == "VehiclePosition" {
+		if ent.ObservedArrivalTime.Valid && ent.ObservedArrivalTime.Val < arrival.Val {
+			arrival = ent.ObservedArrivalTime
+		}
+		if ent.ObservedDepartureTime.Valid && ent.ObservedDepartureTime.Val > departure.Val {
+			departure = ent.ObservedDepartureTime
+		}
+	} else {
+		if !arrival.Valid {
+			arrival = ent.ObservedArrivalTime
+		}
+		if !departure.Valid {
+			departure = ent.ObservedDepartureTime
+		}
+	}
+	changed := arrival != ent.ObservedArrivalTime || departure != ent.ObservedDepartureTime || (obs.VehicleID.IsPresent() && obs.VehicleID.Val != ent.VehicleID.Val) || obs.ScheduleRelationship.Val != ent.ScheduleRelationship.Val
+	ent.ObservedArrivalTime, ent.ObservedDepartureTime = arrival, departure
+	ent.ScheduleRelationship = obs.ScheduleRelationship
+	if obs.VehicleID.IsPresent() {
+		ent.VehicleID = obs.VehicleID
+	}
+	ent.setDerived()
+	return changed
+}
+
+// setDerived sets the delay and dwell times from the observed and scheduled times.
+func (ent *StopObservation) setDerived() {
+	ent.ObservedArrivalDelay = tt.Int{}
+	ent.DwellTimeSecs = tt.Int{}
+	ent.ScheduledDwellTimeSecs = tt.Int{}
+	if ent.ObservedArrivalTime.Valid && ent.ScheduledArrivalTime.Valid {
+		ent.ObservedArrivalDelay = tt.NewInt(int(ent.ObservedArrivalTime.Val - ent.ScheduledArrivalTime.Val))
+	}
+	if ent.ObservedArrivalTime.Valid && ent.ObservedDepartureTime.Valid {
+		ent.DwellTimeSecs = tt.NewInt(int(ent.ObservedDepartureTime.Val - ent.ObservedArrivalTime.Val))
+	}
+	if ent.ScheduledArrivalTime.Valid && ent.ScheduledDepartureTime.Valid {
+		ent.ScheduledDwellTimeSecs = tt.NewInt(int(ent.ScheduledDepartureTime.Val - ent.ScheduledArrivalTime.Val))
+	}
+}
+
+// RecordResult counts the observations written for a message.
+type RecordResult struct {
+	FeedVersionID int `json:"feed_version_id"`
+	Observations  int `json:"observations"`
+	Inserted      int `json:"inserted"`
+	Updated       int `json:"updated"`
+}
+
+// Recorder matches realtime messages to the schedule of a feed version and
+// writes the resulting stop observations. Schedules are loaded once per feed version,
+// and only the schedule of the most recently recorded feed version of each feed is kept.
+type Recorder struct {
+	adapter    tldb.Adapter
+	lock       sync.Mutex
+	validators map[int]*rt.Validator
+	feedIDs    map[int]int // feed id of each loaded feed version
+}
+
+// NewRecorder returns a Recorder writing to the given database.
+func NewRecorder(adapter tldb.Adapter) *Recorder {
+	return &Recorder{
+		adapter:    adapter,
+		validators: map[int]*rt.Validator{},
+		feedIDs:    map[int]int{},
+	}
+}
+
+// Record writes the stop observations in msg for the feed version.
+// sourceID identifies the realtime feed, e.g. its onestop_id.
+// An observation of a segment already recorded for the same trip and service date updates it.
+func (r *Recorder) Record(ctx context.Context, fvid int, sourceID string, msg *pb.FeedMessage) (RecordResult, error) {
+	result := RecordResult{FeedVersionID: fvid}
+	fi, err := r.validator(ctx, fvid)
+	if err != nil {
+		return result, err
+	}
+	observations := fi.StopObservations(msg)
+	result.Observations = len(observations)
+	if len(observations) == 0 {
+		return result, nil
+	}
+	var ents []*StopObservation
+	tripIDs := map[string]bool{}
+	tripStartDates := map[string]tt.Date{}
+	for _, obs := range observations {
+		ent := newStopObservation(fvid, sourceID, obs)
+		ents = append(ents, ent)
+		tripIDs[ent.TripID.Val] = true
+		tripStartDates[ent.TripStartDate.Format("2006-01-02")] = ent.TripStartDate
+	}
+	err = r.adapter.Tx(func(atx tldb.Adapter) error {
+		existing, err := existingObservations(ctx, atx, fvid, tripIDs, tripStartDates)
+		if err != nil {
+			return err
+		}
+		for _, ent := range ents {
+			if prev, ok := existing[ent.key()]; ok {
+				if !prev.merge(ent) {
+					continue
+				}
+				if err := atx.Update(ctx, prev, "observed_arrival_time", "observed_departure_time", "observed_arrival_delay", "dwell_time_secs", "vehicle_id", "schedule_relationship", "updated_at"); err != nil {
+					return err
+				}
+				result.Updated++
+				continue
+			}
+			if _, err := atx.Insert(ctx, ent); err != nil {
+				return err
+			}
+			existing[ent.key()] = ent
+			result.Inserted++
+		}
+		return nil
+	})
+	return result, err
+}
+
+// validator returns the validator for a feed version, loading its schedule on first use.
+// Loading a feed version evicts the schedules of the other feed versions of the same feed,
+// since observations are recorded against the active feed version.
+func (r *Recorder) validator(ctx context.Context, fvid int) (*rt.Validator, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if fi, ok := r.validators[fvid]; ok {
+		return fi, nil
+	}
+	var feedID int
+	if err := dbutil.Get(ctx, r.adapter.DBX(), sq.StatementBuilder.Select("feed_id").From("feed_versions").Where("id = ?", fvid), &feedID); err != nil {
+		return nil, err
+	}
+	fi, err := loadValidator(ctx, r.adapter.DBX(), fvid)
+	if err != nil {
+		return nil, err
+	}
+	log.For(ctx).Info().Int("feed_version_id", fvid).Msg("rtrecorder: loaded schedule")
+	for prevFvid, prevFeedID := range r.feedIDs {
+		if prevFeedID == feedID {
+			delete(r.validators, prevFvid)
+			delete(r.feedIDs, prevFvid)
+		}
+	}
+	r.validators[fvid] = fi
+	r.feedIDs[fvid] = feedID
+	return fi, nil
+}
+
+// loadValidator builds an rt.Validator with the stop times of a feed version.
+// Entities are loaded with their GTFS ids, since realtime messages reference those.
+func loadValidator(ctx context.Context, db tldb.Ext, fvid int) (*rt.Validator, error) {
+	fi := rt.NewValidator()
+	fi.KeepStopTimes = true
+	q := sq.StatementBuilder
+
+	var agencies []gtfs.Agency
+	if err := dbutil.Select(ctx, db, q.Select("agency_id", "agency_timezone").From("gtfs_agencies").Where("feed_version_id = ?", fvid).OrderBy("id"), &agencies); err != nil {
+		return nil, err
+	}
+	if len(agencies) == 0 {
+		return nil, fmt.Errorf("feed version %d has no agencies", fvid)
+	}
+	for i := range agencies {
+		fi.Validate(&agencies[i])
+	}
+
+	var routes []gtfs.Route
+	if err := dbutil.Select(ctx, db, q.Select("gtfs_routes.route_id", "gtfs_routes.route_type", "gtfs_agencies.agency_id").
+		From("gtfs_routes").
+		Join("gtfs_agencies on gtfs_agencies.id = gtfs_routes.agency_id").
+		Where("gtfs_routes.feed_version_id = ?", fvid), &routes); err != nil {
+		return nil, err
+	}
+	for i := range routes {
+		fi.Validate(&routes[i])
+	}
+
+	var trips []gtfs.Trip
+	if err := dbutil.Select(ctx, db, q.Select("gtfs_trips.trip_id", "gtfs_trips.direction_id", "gtfs_routes.route_id").
+		From("gtfs_trips").
+		Join("gtfs_routes on gtfs_routes.id = gtfs_trips.route_id").
+		Where("gtfs_trips.feed_version_id = ?", fvid), &trips); err != nil {
+		return nil, err
+	}
+	var stopTimes []gtfs.StopTime
+	if err := dbutil.Select(ctx, db, q.Select("gtfs_trips.trip_id", "gtfs_stops.stop_id", "gtfs_stop_times.stop_sequence", "gtfs_stop_times.arrival_time", "gtfs_stop_times.departure_time").
+		From("gtfs_stop_times").
+		Join("gtfs_trips on gtfs_trips.id = gtfs_stop_times.trip_id").
+		Join("gtfs_stops on gtfs_stops.id = gtfs_stop_times.stop_id").
+		Where("gtfs_stop_times.feed_version_id = ?", fvid).
+		OrderBy("gtfs_stop_times.trip_id", "gtfs_stop_times.stop_sequence"), &stopTimes); err != nil {
+		return nil, err
+	}
+	tripStopTimes := map[string][]gtfs.StopTime{}
+	for _, st := range stopTimes {
+		tripStopTimes[st.TripID.Val] = append(tripStopTimes[st.TripID.Val], st)
+	}
+	for i := range trips {
+		trips[i].StopTimes = tripStopTimes[trips[i].TripID.Val]
+		fi.Validate(&trips[i])
+	}
+
+	// Frequencies follow trips, which reset the trip info
+	var frequencies []gtfs.Frequency
+	if err := dbutil.Select(ctx, db, q.Select("gtfs_trips.trip_id", "gtfs_frequencies.start_time", "gtfs_frequencies.end_time", "gtfs_frequencies.headway_secs").
+		From("gtfs_frequencies").
+		Join("gtfs_trips on gtfs_trips.id = gtfs_frequencies.trip_id").
+		Where("gtfs_frequencies.feed_version_id = ?", fvid), &frequencies); err != nil {
+		return nil, err
+	}
+	for i := range frequencies {
+		fi.Validate(&frequencies[i])
+	}
+	return fi, nil
+}
+
+var observationColumns = []string{
+	"id",
+	"created_at",
+	"updated_at",
+	"feed_version_id",
+	"source",
+	"source_id",
+	"trip_id",
+	"route_id",
+	"agency_id",
+	"direction_id",
+	"trip_start_date",
+	"trip_start_time",
+	"schedule_relationship",
+	"vehicle_id",
+	"stop_sequence",
+	"from_stop_id",
+	"to_stop_id",
+	"scheduled_arrival_time",
+	"scheduled_departure_time",
+	"observed_arrival_time",
+	"observed_departure_time",
+	"observed_arrival_delay",
+	"dwell_time_secs",
+	"scheduled_dwell_time_secs",
+}
+
+// existingObservations returns the recorded observations of the trips on the service dates, by key.
+func existingObservations(ctx context.Context, atx tldb.Adapter, fvid int, tripIDs map[string]bool, tripStartDates map[string]tt.Date) (map[string]*StopObservation, error) {
+	var ids []string
+	for k := range tripIDs {
+		ids = append(ids, k)
+	}
+	var dates []tt.Date
+	for _, d := range tripStartDates {
+		dates = append(dates, d)
+	}
+	var ents []*StopObservation
+	if err := dbutil.Select(ctx, atx.DBX(), sq.StatementBuilder.Select(observationColumns...).
+		From("ext_performance_stop_observations").
+		Where("feed_version_id = ?", fvid).
+		Where(sq.Eq{"trip_id": ids}).
+		Where(sq.Eq{"trip_start_date": dates}), &ents); err != nil {
+		return nil, err
+	}
+	ret := map[string]*StopObservation{}
+	for _, ent := range ents {
+		ret[ent.key()] = ent
+	}
+	return ret, nil
+}
+
+func newStopObservation(fvid int, sourceID string, obs rt.StopObservation) *StopObservation {
+	ent := &StopObservation{
+		FeedVersionID:          fvid,
+		Source:                 tt.NewString(obs.Source),
+		SourceID:               tt.NewString(sourceID),
+		TripID:                 tt.NewString(obs.TripID),
+		RouteID:                tt.NewString(obs.RouteID),
+		AgencyID:               tt.NewString(obs.AgencyID),
+		DirectionID:            tt.NewInt(obs.DirectionID),
+		TripStartDate:          obs.TripStartDate,
+		TripStartTime:          obs.TripStartTime,
+		ScheduleRelationship:   tt.NewString(obs.ScheduleRelationship),
+		VehicleID:              tt.NewString(obs.VehicleID),
+		StopSequence:           obs.StopSequence,
+		FromStopID:             tt.NewString(obs.FromStopID),
+		ToStopID:               tt.NewString(obs.ToStopID),
+		ScheduledArrivalTime:   obs.ScheduledArrivalTime,
+		ScheduledDepartureTime: obs.ScheduledDepartureTime,
+		ObservedArrivalTime:    obs.ObservedArrivalTime,
+		ObservedDepartureTime:  obs.ObservedDepartureTime,
+	}
+	ent.setDerived()
+	return ent
+}
+
+// FeedVersionForFeed returns the active feed version of a static feed,
+// or its most recently fetched successfully imported feed version.
+func FeedVersionForFeed(ctx context.Context, db tldb.Ext, feedOnestopID string) (int, error) {
+	var fvid tt.Int
+	err := dbutil.Get(ctx, db, sq.StatementBuilder.
+		Select("feed_states.active_feed_version_id").
+		From("current_feeds").
+		Join("feed_states on feed_states.feed_id = current_feeds.id").
+		Where("current_feeds.onestop_id = ?", feedOnestopID).
+		Where("current_feeds.deleted_at is null"), &fvid)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	if fvid.Valid {
+		return fvid.Int(), nil
+	}
+	err = dbutil.Get(ctx, db, sq.StatementBuilder.
+		Select("feed_versions.id").
+		From("feed_versions").
+		Join("current_feeds on current_feeds.id = feed_versions.feed_id").
+		Join("feed_version_gtfs_imports on feed_version_gtfs_imports.feed_version_id = feed_versions.id").
+		Where("current_feeds.onestop_id = ?", feedOnestopID).
+		Where("feed_version_gtfs_imports.success = ?", true).
+		OrderBy("feed_versions.fetched_at desc", "feed_versions.id desc").
+		Limit(1), &fvid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("no imported feed version for feed '%s'", feedOnestopID)
+	} else if err != nil {
+		return 0, err
+	}
+	return fvid.Int(), nil
+}
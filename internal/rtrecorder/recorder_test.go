@@ -0,0 +1,224 @@
+package rtrecorder
+
+import (
+	"context"
+	"testing"
+
+	"github.com/interline-io/transitland-lib/copier"
+	"github.com/interline-io/transitland-lib/dmfr"
+	"github.com/interline-io/transitland-lib/internal/testpath"
+	"github.com/interline-io/transitland-lib/rt"
+	"github.com/interline-io/transitland-lib/rt/pb"
+	"github.com/interline-io/transitland-lib/server/dbutil"
+	"github.com/interline-io/transitland-lib/tlcsv"
+	"github.com/interline-io/transitland-lib/tldb"
+	_ "github.com/interline-io/transitland-lib/tldb/sqlite"
+	"github.com/interline-io/transitland-lib/tt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+const testFeedOnestopID = "f-bart"
+
+// setupTestDB imports the BART test feed into an in-memory database.
+func setupTestDB(t *testing.T) (tldb.Adapter, int) {
+	t.Helper()
+	ctx := context.Background()
+	writer, err := tldb.OpenWriter("sqlite3://:memory:", true)
+	require.NoError(t, err)
+	adapter := writer.Adapter
+	_, err = adapter.Sqrl().
+		Insert("current_feeds").
+		Columns("id", "onestop_id", "name").
+		Values(1, testFeedOnestopID, "BART").
+		RunWith(adapter.DBX()).
+		ExecContext(ctx)
+	require.NoError(t, err)
+	var fvid int
+	query, args, err := adapter.Sqrl().
+		Insert("feed_versions").
+		Columns("feed_id", "sha1", "name").
+		Values(1, "test-sha1", "BART").
+		Suffix("RETURNING id").
+		ToSql()
+	require.NoError(t, err)
+	require.NoError(t, adapter.DBX().QueryRowxContext(ctx, query, args...).Scan(&fvid))
+	importTestFeedVersion(t, writer, fvid)
+	return adapter, fvid
+}
+
+// addTestFeedVersion imports the BART test feed again as a new feed version of the same feed.
+func addTestFeedVersion(t *testing.T, adapter tldb.Adapter, sha1 string) int {
+	t.Helper()
+	var fvid int
+	query, args, err := adapter.Sqrl().
+		Insert("feed_versions").
+		Columns("feed_id", "sha1", "name").
+		Values(1, sha1, "BART").
+		Suffix("RETURNING id").
+		ToSql()
+	require.NoError(t, err)
+	require.NoError(t, adapter.DBX().QueryRowxContext(context.Background(), query, args...).Scan(&fvid))
+	importTestFeedVersion(t, &tldb.Writer{Adapter: adapter}, fvid)
+	return fvid
+}
+
+func importTestFeedVersion(t *testing.T, writer *tldb.Writer, fvid int) {
+	t.Helper()
+	reader, err := tlcsv.NewReader(testpath.RelPath("testdata/rt/bart-rt.zip"))
+	require.NoError(t, err)
+	writer.FeedVersionID = fvid
+	result, err := copier.CopyWithOptions(context.Background(), reader, writer, copier.Options{})
+	require.NoError(t, err)
+	require.Empty(t, result.Errors)
+}
+
+func TestRecorder_Record(t *testing.T) {
+	ctx := context.Background()
+	adapter, fvid := setupTestDB(t)
+	r := NewRecorder(adapter)
+
+	// 2019-08-07 00:00 America/Los_Angeles
+	const midnight = 1565161200
+	tripUpdates, err := rt.ReadFile(testpath.RelPath("testdata/rt/bart-trip-updates.pb"))
+	require.NoError(t, err)
+	var tripEnt *pb.FeedEntity
+	for _, ent := range tripUpdates.Entity {
+		if ent.GetTripUpdate().GetTrip().GetTripId() == "1011112WKDY" {
+			tripEnt = ent
+		}
+	}
+	require.NotNil(t, tripEnt)
+	msg := func(ts uint64, ents ...*pb.FeedEntity) *pb.FeedMessage {
+		return &pb.FeedMessage{
+			Header: &pb.FeedHeader{GtfsRealtimeVersion: proto.String("2.0"), Timestamp: proto.Uint64(ts)},
+			Entity: ents,
+		}
+	}
+	countRows := func() int {
+		var count int
+		require.NoError(t, dbutil.Get(ctx, adapter.DBX(), adapter.Sqrl().Select("count(*)").From("ext_performance_stop_observations"), &count))
+		return count
+	}
+
+	t.Run("trip updates", func(t *testing.T) {
+		result, err := r.Record(ctx, fvid, "f-bart~rt", msg(1565201900, tripEnt))
+		require.NoError(t, err)
+		assert.Equal(t, RecordResult{FeedVersionID: fvid, Observations: 2, Inserted: 2}, result)
+
+		var ents []*StopObservation
+		require.NoError(t, dbutil.Select(ctx, adapter.DBX(), adapter.Sqrl().
+			Select(observationColumns...).
+			From("ext_performance_stop_observations").
+			Where("to_stop_id = ?", "BALB"), &ents))
+		require.Len(t, ents, 1)
+		balb := ents[0]
+		assert.Equal(t, "f-bart~rt", balb.SourceID.Val)
+		assert.Equal(t, "TripUpdate", balb.Source.Val)
+		assert.Equal(t, "DALY", balb.FromStopID.Val)
+		assert.Equal(t, "2019-08-07", balb.TripStartDate.Format("2006-01-02"))
+		assert.EqualValues(t, 1565201802-midnight, balb.ObservedArrivalTime.Val)
+		assert.Equal(t, int(balb.ObservedArrivalTime.Val-balb.ScheduledArrivalTime.Val), balb.ObservedArrivalDelay.Int())
+
+		// The same poll again does not add rows
+		result, err = r.Record(ctx, fvid, "f-bart~rt", msg(1565201900, tripEnt))
+		require.NoError(t, err)
+		assert.Equal(t, RecordResult{FeedVersionID: fvid, Observations: 2}, result)
+		assert.Equal(t, 2, countRows())
+
+		// A revised time updates the row
+		revised := proto.Clone(tripEnt).(*pb.FeedEntity)
+		revised.TripUpdate.StopTimeUpdate[1].Arrival.Time = proto.Int64(1565201812)
+		result, err = r.Record(ctx, fvid, "f-bart~rt", msg(1565201900, revised))
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Updated)
+		assert.Equal(t, 2, countRows())
+
+		// Canceling the trip updates the observed stops and adds the others
+		canceled := proto.Clone(tripEnt).(*pb.FeedEntity)
+		canceled.TripUpdate.Trip.ScheduleRelationship = pb.TripDescriptor_CANCELED.Enum()
+		result, err = r.Record(ctx, fvid, "f-bart~rt", msg(1565201900, canceled))
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Updated)
+		assert.Equal(t, result.Observations, countRows())
+		var canceledCount int
+		require.NoError(t, dbutil.Get(ctx, adapter.DBX(), adapter.Sqrl().Select("count(*)").From("ext_performance_stop_observations").Where("schedule_relationship = ?", "CANCELED"), &canceledCount))
+		assert.Equal(t, result.Observations, canceledCount)
+	})
+
+	t.Run("vehicle positions", func(t *testing.T) {
+		vp := func(ts uint64) *pb.FeedEntity {
+			return &pb.FeedEntity{
+				Id: proto.String("v1"),
+				Vehicle: &pb.VehiclePosition{
+					Trip:          &pb.TripDescriptor{TripId: proto.String("1011112WKDY")},
+					Vehicle:       &pb.VehicleDescriptor{Id: proto.String("bus1")},
+					StopId:        proto.String("BALB"),
+					CurrentStatus: pb.VehiclePosition_STOPPED_AT.Enum(),
+					Timestamp:     proto.Uint64(ts),
+				},
+			}
+		}
+		result, err := r.Record(ctx, fvid, "f-bart~vp", msg(1565201900, vp(1565201810)))
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Inserted)
+		result, err = r.Record(ctx, fvid, "f-bart~vp", msg(1565201900, vp(1565201850)))
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Updated)
+
+		var ents []*StopObservation
+		require.NoError(t, dbutil.Select(ctx, adapter.DBX(), adapter.Sqrl().
+			Select(observationColumns...).
+			From("ext_performance_stop_observations").
+			Where("source = ?", "VehiclePosition"), &ents))
+		require.Len(t, ents, 1)
+		assert.EqualValues(t, 1565201810-midnight, ents[0].ObservedArrivalTime.Val)
+		assert.EqualValues(t, 1565201850-midnight, ents[0].ObservedDepartureTime.Val)
+		assert.Equal(t, 40, ents[0].DwellTimeSecs.Int())
+		assert.Equal(t, "bus1", ents[0].VehicleID.Val)
+	})
+
+	t.Run("existing observations by service date", func(t *testing.T) {
+		var ents []*StopObservation
+		require.NoError(t, dbutil.Select(ctx, adapter.DBX(), adapter.Sqrl().Select(observationColumns...).From("ext_performance_stop_observations").Limit(1), &ents))
+		require.Len(t, ents, 1)
+		tripIDs := map[string]bool{"1011112WKDY": true}
+		serviceDate := ents[0].TripStartDate
+		existing, err := existingObservations(ctx, adapter, fvid, tripIDs, map[string]tt.Date{"2019-08-07": serviceDate})
+		require.NoError(t, err)
+		assert.Equal(t, countRows(), len(existing))
+		otherDate := tt.NewDate(serviceDate.Val.AddDate(0, 0, -1))
+		existing, err = existingObservations(ctx, adapter, fvid, tripIDs, map[string]tt.Date{"2019-08-06": otherDate})
+		require.NoError(t, err)
+		assert.Empty(t, existing)
+	})
+
+	t.Run("schedules of previous feed versions are evicted", func(t *testing.T) {
+		fvid2 := addTestFeedVersion(t, adapter, "test-sha1-2")
+		_, err := r.Record(ctx, fvid2, "f-bart~rt", msg(1565201900, tripEnt))
+		require.NoError(t, err)
+		assert.Len(t, r.validators, 1)
+		assert.Contains(t, r.validators, fvid2)
+	})
+}
+
+func TestFeedVersionForFeed(t *testing.T) {
+	ctx := context.Background()
+	adapter, fvid := setupTestDB(t)
+
+	// Not imported yet
+	_, err := FeedVersionForFeed(ctx, adapter.DBX(), testFeedOnestopID)
+	assert.Error(t, err)
+	fvi := dmfr.NewFeedVersionImport()
+	fvi.FeedVersionID = fvid
+	fvi.Success = true
+	_, err = adapter.Insert(ctx, fvi)
+	require.NoError(t, err)
+	ret, err := FeedVersionForFeed(ctx, adapter.DBX(), testFeedOnestopID)
+	require.NoError(t, err)
+	assert.Equal(t, fvid, ret)
+
+	_, err = FeedVersionForFeed(ctx, adapter.DBX(), "f-unknown")
+	assert.Error(t, err)
+}
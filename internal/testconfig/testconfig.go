@@ -4,11 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"testing"
 	"time"
 
 	"github.com/interline-io/transitland-lib/feedmanager"
 	"github.com/interline-io/transitland-lib/internal/clock"
+	tlutil "github.com/interline-io/transitland-lib/internal/testutil"
 	"github.com/interline-io/transitland-lib/rt"
 	"github.com/interline-io/transitland-lib/server/auth/authz"
 	"github.com/interline-io/transitland-lib/server/auth/azchecker"
@@ -78,6 +80,36 @@ func ConfigTxRollback(t testing.TB, opts Options, cb func(model.Config)) {
 	})
 }
 
+// ImportFeed fetches and imports a feed fixture directory as a new version of an existing feed.
+// Use with ConfigTxRollback and AllowAll, so the feed version is removed after the test.
+func ImportFeed(t testing.TB, cfg model.Config, feedOnestopId string, dir string) *model.FeedVersion {
+	t.Helper()
+	ctx := model.WithConfig(context.Background(), cfg)
+	f, err := os.Open(tlutil.ZipDirToTemp(t, dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fr, err := cfg.Actions.StaticFetch(ctx, feedOnestopId, f, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fr.FetchError != nil {
+		t.Fatal(*fr.FetchError)
+	}
+	if fr.FeedVersion == nil {
+		t.Fatal("expected feed version")
+	}
+	ir, err := cfg.Actions.FeedVersionImport(ctx, fr.FeedVersion.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ir.Success {
+		t.Fatal("expected successful import")
+	}
+	return fr.FeedVersion
+}
+
 type RTJsonFile struct {
 	Feed  string
 	Ftype string
@@ -175,13 +207,16 @@ func newTestConfig(t testing.TB, ctx context.Context, db tldb.Ext, opts Options)
 	}
 
 	// Initialize job runner + backend - do not start. Named queues for the
-	// feed-version import/unimport mutations are declared so tests that exercise
-	// those mutations can Submit/Watch after registering workers and starting
+	// job kinds that mutations and REST handlers enqueue are declared so tests
+	// that exercise them can Submit/Watch after registering workers and starting
 	// the backend themselves.
 	jobRunner := jobs.NewRunner()
 	jobBackend := localjobs.NewLocalBackend(jobRunner, map[string]localjobs.QueueOpts{
 		"feed-version-import":   {},
 		"feed-version-unimport": {},
+		"feed-version-export":   {},
+		"static-fetch":          {},
+		"validate-upload":       {},
 	}, nil)
 
 	// Action finder
@@ -10,7 +10,7 @@ import (
 // ZipDirToTemp writes every regular file in dir into a new temp .zip and returns
 // its path. Feed fixtures are stored as directories so they stay the single source
 // of truth; tests that need a zip (fetch, import) zip them on the fly.
-func ZipDirToTemp(t *testing.T, dir string) string {
+func ZipDirToTemp(t testing.TB, dir string) string {
 	t.Helper()
 	tmp, err := os.CreateTemp("", "gtfs-*.zip")
 	if err != nil {
@@ -0,0 +1,428 @@
+// Package merge provides tools for combining multiple GTFS feeds into one, resolving entity ID conflicts between feeds.
+package merge
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/interline-io/transitland-lib/adapters"
+	"github.com/interline-io/transitland-lib/adapters/multireader"
+	"github.com/interline-io/transitland-lib/gtfs"
+	"github.com/interline-io/transitland-lib/tlxy"
+	"github.com/interline-io/transitland-lib/tt"
+)
+
+// Options defines how conflicts between feeds are resolved.
+type Options struct {
+	// Prefix IDs that collide with an ID used by an earlier feed.
+	// When false, the entity from the earliest feed is kept.
+	PrefixConflicts bool
+	// Prefix for each feed, in reader order; defaults to "<n>_", e.g. "2_" for the second feed.
+	Prefixes []string
+	// Merge agencies with identical values other than agency_id.
+	DedupAgencies bool
+	// Merge stops with the same stop_name and location_type within this distance, in meters.
+	DedupStopsDistance float64
+	// Merge shapes with identical points.
+	DedupShapes bool
+	// Merge calendars with identical days, date ranges, and calendar_dates exceptions.
+	DedupCalendars bool
+}
+
+func (opts Options) enabled() bool {
+	return opts.PrefixConflicts || opts.DedupAgencies || opts.DedupStopsDistance > 0 || opts.DedupShapes || opts.DedupCalendars
+}
+
+func (opts Options) prefix(feed int) string {
+	if feed < len(opts.Prefixes) && opts.Prefixes[feed] != "" {
+		return opts.Prefixes[feed]
+	}
+	return fmt.Sprintf("%d_", feed+1)
+}
+
+// Report lists every rename and merge applied.
+type Report struct {
+	Renamed []RenamedEntity `json:"renamed"`
+	Merged  []MergedEntity  `json:"merged"`
+}
+
+// RenamedEntity is an entity that was given a new ID to avoid a conflict.
+type RenamedEntity struct {
+	Feed        string `json:"feed"`
+	Filename    string `json:"filename"`
+	EntityID    string `json:"entity_id"`
+	NewEntityID string `json:"new_entity_id"`
+}
+
+// MergedEntity is an entity that was replaced by an equivalent entity from an earlier feed.
+type MergedEntity struct {
+	Feed         string `json:"feed"`
+	Filename     string `json:"filename"`
+	EntityID     string `json:"entity_id"`
+	IntoFeed     string `json:"into_feed"`
+	IntoEntityID string `json:"into_entity_id"`
+}
+
+// Plan holds the renames and merges for each feed.
+type Plan struct {
+	Report Report
+	feeds  []*feedPlan
+}
+
+type feedPlan struct {
+	renamed map[string]map[string]string
+	merged  map[string]map[string]string
+	emap    *tt.EntityMap
+}
+
+func newFeedPlan() *feedPlan {
+	return &feedPlan{
+		renamed: map[string]map[string]string{},
+		merged:  map[string]map[string]string{},
+		emap:    tt.NewEntityMap(),
+	}
+}
+
+func (fp *feedPlan) empty() bool {
+	return len(fp.renamed) == 0 && len(fp.merged) == 0
+}
+
+func (fp *feedPlan) finalID(fn string, eid string) string {
+	if v, ok := fp.renamed[fn][eid]; ok {
+		return v
+	}
+	if v, ok := fp.merged[fn][eid]; ok {
+		return v
+	}
+	return eid
+}
+
+func (fp *feedPlan) set(m map[string]map[string]string, spec fileSpec, eid string, newID string) {
+	if _, ok := m[spec.filename]; !ok {
+		m[spec.filename] = map[string]string{}
+	}
+	m[spec.filename][eid] = newID
+	for _, ref := range spec.refKeys() {
+		fp.emap.Set(ref, eid, newID)
+	}
+}
+
+// NewPlan scans the readers and determines the renames and merges needed to combine them.
+// Readers must be open.
+func NewPlan(readers []adapters.Reader, opts Options) (*Plan, error) {
+	plan := &Plan{}
+	if !opts.enabled() {
+		for range readers {
+			plan.feeds = append(plan.feeds, newFeedPlan())
+		}
+		return plan, nil
+	}
+	used := map[string]map[string]bool{}
+	indexes := map[string]map[string][]dedupEntry{}
+	for feed, reader := range readers {
+		fp := newFeedPlan()
+		plan.feeds = append(plan.feeds, fp)
+		for _, spec := range fileSpecs {
+			if used[spec.filename] == nil {
+				used[spec.filename] = map[string]bool{}
+				indexes[spec.filename] = map[string][]dedupEntry{}
+			}
+			fileUsed := used[spec.filename]
+			index := indexes[spec.filename]
+
+			// Find duplicates of entities kept from earlier feeds
+			var ids []string
+			var cands []dedupCandidate
+			if spec.dedup != nil {
+				cands = spec.dedup(reader, opts)
+			}
+			if cands != nil {
+				for _, cand := range cands {
+					ids = append(ids, cand.id)
+					if cand.key == "" {
+						continue
+					}
+					if match, ok := findDedup(index[cand.key], cand, opts.DedupStopsDistance); ok {
+						into := plan.feeds[match.feed].finalID(spec.filename, match.id)
+						fp.set(fp.merged, spec, cand.id, into)
+						plan.Report.Merged = append(plan.Report.Merged, MergedEntity{
+							Feed:         reader.String(),
+							Filename:     spec.filename,
+							EntityID:     cand.id,
+							IntoFeed:     readers[match.feed].String(),
+							IntoEntityID: into,
+						})
+					}
+				}
+			} else {
+				ids = spec.ids(reader)
+			}
+
+			// Rename remaining IDs that conflict with earlier feeds
+			var conflicts []string
+			for _, eid := range ids {
+				if _, ok := fp.merged[spec.filename][eid]; ok {
+					continue
+				}
+				if fileUsed[eid] && opts.PrefixConflicts {
+					conflicts = append(conflicts, eid)
+				}
+			}
+			for _, eid := range ids {
+				if _, ok := fp.merged[spec.filename][eid]; !ok {
+					fileUsed[eid] = true
+				}
+			}
+			for _, eid := range conflicts {
+				newID := opts.prefix(feed) + eid
+				for n := 2; fileUsed[newID]; n++ {
+					newID = fmt.Sprintf("%s%s_%d", opts.prefix(feed), eid, n)
+				}
+				fileUsed[newID] = true
+				fp.set(fp.renamed, spec, eid, newID)
+				plan.Report.Renamed = append(plan.Report.Renamed, RenamedEntity{
+					Feed:        reader.String(),
+					Filename:    spec.filename,
+					EntityID:    eid,
+					NewEntityID: newID,
+				})
+			}
+
+			// Kept entities are candidates for later feeds
+			for _, cand := range cands {
+				if _, ok := fp.merged[spec.filename][cand.id]; ok || cand.key == "" {
+					continue
+				}
+				index[cand.key] = append(index[cand.key], dedupEntry{feed: feed, id: cand.id, pt: cand.pt})
+			}
+		}
+	}
+	return plan, nil
+}
+
+// NewReader returns a reader that combines the readers, applying the plan to each.
+func (plan *Plan) NewReader(readers ...adapters.Reader) (adapters.Reader, error) {
+	if len(readers) != len(plan.feeds) {
+		return nil, fmt.Errorf("plan has %d feeds, got %d readers", len(plan.feeds), len(readers))
+	}
+	var wrapped []adapters.Reader
+	for i, r := range readers {
+		wrapped = append(wrapped, &feedReader{Reader: r, plan: plan.feeds[i]})
+	}
+	return multireader.NewReader(wrapped...), nil
+}
+
+//////////
+
+type dedupCandidate struct {
+	id  string
+	key string
+	pt  *tlxy.Point
+}
+
+type dedupEntry struct {
+	feed int
+	id   string
+	pt   *tlxy.Point
+}
+
+// findDedup returns the closest matching entry, preferring an entry with the same ID.
+func findDedup(entries []dedupEntry, cand dedupCandidate, maxDist float64) (dedupEntry, bool) {
+	var best dedupEntry
+	bestDist := -1.0
+	for _, entry := range entries {
+		dist := 0.0
+		if cand.pt != nil && entry.pt != nil {
+			dist = tlxy.DistanceHaversine(*cand.pt, *entry.pt)
+		}
+		if dist > maxDist && (cand.pt != nil && entry.pt != nil) {
+			continue
+		}
+		if bestDist < 0 || dist < bestDist || (dist == bestDist && entry.id == cand.id) {
+			best = entry
+			bestDist = dist
+		}
+	}
+	return best, bestDist >= 0
+}
+
+// fileSpec describes a file with entity IDs that may conflict between feeds.
+type fileSpec struct {
+	filename string
+	// Entity map keys used for references, if different from filename
+	refs  []string
+	ids   func(adapters.Reader) []string
+	dedup func(adapters.Reader, Options) []dedupCandidate
+}
+
+func (spec fileSpec) refKeys() []string {
+	if len(spec.refs) > 0 {
+		return spec.refs
+	}
+	return []string{spec.filename}
+}
+
+var fileSpecs = []fileSpec{
+	{filename: "agency.txt", ids: func(r adapters.Reader) []string { return entityIDs(r.Agencies()) }, dedup: dedupAgencies},
+	{filename: "levels.txt", ids: func(r adapters.Reader) []string { return entityIDs(r.Levels()) }},
+	{filename: "stops.txt", ids: func(r adapters.Reader) []string { return entityIDs(r.Stops()) }, dedup: dedupStops},
+	{filename: "calendar.txt", ids: calendarIDs, dedup: dedupCalendars},
+	{filename: "shapes.txt", ids: func(r adapters.Reader) []string { return entityIDs(r.Shapes()) }, dedup: dedupShapes},
+	{filename: "routes.txt", ids: func(r adapters.Reader) []string { return entityIDs(r.Routes()) }},
+	{filename: "trips.txt", ids: func(r adapters.Reader) []string { return entityIDs(r.Trips()) }},
+	{filename: "pathways.txt", ids: func(r adapters.Reader) []string { return entityIDs(r.Pathways()) }},
+	{filename: "fare_attributes.txt", ids: func(r adapters.Reader) []string { return entityIDs(r.FareAttributes()) }},
+	{filename: "areas.txt", ids: func(r adapters.Reader) []string { return entityIDs(r.Areas()) }},
+	{filename: "networks.txt", ids: func(r adapters.Reader) []string { return entityIDs(r.Networks()) }},
+	{filename: "booking_rules.txt", ids: func(r adapters.Reader) []string { return entityIDs(r.BookingRules()) }},
+	{filename: "location_groups.txt", ids: func(r adapters.Reader) []string { return entityIDs(r.LocationGroups()) }},
+	{filename: "locations.geojson", ids: func(r adapters.Reader) []string { return entityIDs(r.Locations()) }},
+	{filename: "fare_media.txt", ids: func(r adapters.Reader) []string { return entityIDs(r.FareMedia()) }},
+	{filename: "rider_categories.txt", refs: []string{"rider_categories.txt:rider_category_id"}, ids: func(r adapters.Reader) []string { return entityIDs(r.RiderCategories()) }},
+	{filename: "fare_products.txt", refs: []string{"fare_products.txt:fare_product_id"}, ids: func(r adapters.Reader) []string { return entityIDs(r.FareProducts()) }},
+	{filename: "timeframes.txt", refs: []string{"timeframes.txt:timeframe_group_id"}, ids: func(r adapters.Reader) []string { return entityIDs(r.Timeframes()) }},
+	{filename: "fare_leg_rules.txt", refs: []string{"fare_leg_rules.txt:leg_group_id"}, ids: func(r adapters.Reader) []string { return entityIDs(r.FareLegRules()) }},
+}
+
+// entityIDs returns the unique, non-empty entity IDs in the order they are read.
+func entityIDs[T any, PT interface {
+	*T
+	tt.Entity
+}](ch chan T) []string {
+	var ret []string
+	seen := map[string]bool{}
+	for ent := range ch {
+		eid := PT(&ent).EntityID()
+		if eid == "" || seen[eid] {
+			continue
+		}
+		seen[eid] = true
+		ret = append(ret, eid)
+	}
+	return ret
+}
+
+// calendarIDs includes service_ids that are only defined in calendar_dates.txt
+func calendarIDs(r adapters.Reader) []string {
+	ids := entityIDs(r.Calendars())
+	seen := map[string]bool{}
+	for _, eid := range ids {
+		seen[eid] = true
+	}
+	for cd := range r.CalendarDates() {
+		if eid := cd.ServiceID.Val; eid != "" && !seen[eid] {
+			seen[eid] = true
+			ids = append(ids, eid)
+		}
+	}
+	return ids
+}
+
+func dedupAgencies(r adapters.Reader, opts Options) []dedupCandidate {
+	if !opts.DedupAgencies {
+		return nil
+	}
+	var ret []dedupCandidate
+	seen := map[string]bool{}
+	for ent := range r.Agencies() {
+		eid := ent.EntityID()
+		if eid == "" || seen[eid] {
+			continue
+		}
+		seen[eid] = true
+		key := strings.Join([]string{
+			ent.AgencyName.String(),
+			ent.AgencyURL.String(),
+			ent.AgencyTimezone.String(),
+			ent.AgencyLang.String(),
+			ent.AgencyPhone.String(),
+			ent.AgencyFareURL.String(),
+			ent.AgencyEmail.String(),
+		}, "\x00")
+		ret = append(ret, dedupCandidate{id: eid, key: key})
+	}
+	return ret
+}
+
+func dedupStops(r adapters.Reader, opts Options) []dedupCandidate {
+	if opts.DedupStopsDistance <= 0 {
+		return nil
+	}
+	var ret []dedupCandidate
+	seen := map[string]bool{}
+	for ent := range r.Stops() {
+		eid := ent.EntityID()
+		if eid == "" || seen[eid] {
+			continue
+		}
+		seen[eid] = true
+		// Stops without a name or location are never merged
+		cand := dedupCandidate{id: eid}
+		if ent.StopName.Val != "" && ent.Geometry.Valid {
+			pt := ent.ToPoint()
+			cand.key = fmt.Sprintf("%s\x00%d", ent.StopName.Val, ent.LocationType.Int())
+			cand.pt = &pt
+		}
+		ret = append(ret, cand)
+	}
+	return ret
+}
+
+func dedupShapes(r adapters.Reader, opts Options) []dedupCandidate {
+	if !opts.DedupShapes {
+		return nil
+	}
+	var ret []dedupCandidate
+	for shapes := range r.ShapesByShapeID() {
+		if len(shapes) == 0 {
+			continue
+		}
+		sort.Slice(shapes, func(i, j int) bool { return shapes[i].ShapePtSequence.Val < shapes[j].ShapePtSequence.Val })
+		h := sha1.New()
+		for _, pt := range shapes {
+			fmt.Fprintf(h, "%s,%s,%s;", pt.ShapePtLat.String(), pt.ShapePtLon.String(), pt.ShapeDistTraveled.String())
+		}
+		ret = append(ret, dedupCandidate{id: shapes[0].EntityID(), key: hex.EncodeToString(h.Sum(nil))})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].id < ret[j].id })
+	return ret
+}
+
+func dedupCalendars(r adapters.Reader, opts Options) []dedupCandidate {
+	if !opts.DedupCalendars {
+		return nil
+	}
+	keys := map[string]string{}
+	for ent := range r.Calendars() {
+		keys[ent.EntityID()] = calendarKey(&ent)
+	}
+	dates := map[string][]string{}
+	for ent := range r.CalendarDates() {
+		dates[ent.ServiceID.Val] = append(dates[ent.ServiceID.Val], fmt.Sprintf("%s:%d", ent.Date.String(), ent.ExceptionType.Int()))
+	}
+	var ret []dedupCandidate
+	for _, eid := range calendarIDs(r) {
+		d := dates[eid]
+		sort.Strings(d)
+		ret = append(ret, dedupCandidate{id: eid, key: keys[eid] + "|" + strings.Join(d, ",")})
+	}
+	return ret
+}
+
+func calendarKey(ent *gtfs.Calendar) string {
+	return fmt.Sprintf(
+		"%d%d%d%d%d%d%d:%s:%s",
+		ent.Monday.Int(),
+		ent.Tuesday.Int(),
+		ent.Wednesday.Int(),
+		ent.Thursday.Int(),
+		ent.Friday.Int(),
+		ent.Saturday.Int(),
+		ent.Sunday.Int(),
+		ent.StartDate.String(),
+		ent.EndDate.String(),
+	)
+}
@@ -0,0 +1,164 @@
+package merge
+
+import (
+	"testing"
+
+	"github.com/interline-io/transitland-lib/adapters"
+	"github.com/interline-io/transitland-lib/internal/testreader"
+	"github.com/interline-io/transitland-lib/tlcsv"
+	"github.com/interline-io/transitland-lib/tt"
+	"github.com/stretchr/testify/assert"
+)
+
+func openReaders(t *testing.T, paths ...string) []adapters.Reader {
+	t.Helper()
+	var ret []adapters.Reader
+	for _, p := range paths {
+		reader, err := tlcsv.NewReader(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := reader.Open(); err != nil {
+			t.Fatal(err)
+		}
+		ret = append(ret, reader)
+	}
+	return ret
+}
+
+func countEntities(t *testing.T, plan *Plan, readers []adapters.Reader) map[string]int {
+	t.Helper()
+	reader, err := plan.NewReader(readers...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret := map[string]int{}
+	testreader.AllEntities(reader, func(ent tt.Entity) {
+		ret[ent.Filename()] += 1
+	})
+	return ret
+}
+
+func TestNewPlan(t *testing.T) {
+	bart := testreader.ExampleFeedBART
+	t.Run("disabled", func(t *testing.T) {
+		readers := openReaders(t, bart.URL, bart.URL)
+		plan, err := NewPlan(readers, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Empty(t, plan.Report.Renamed)
+		assert.Empty(t, plan.Report.Merged)
+		counts := countEntities(t, plan, readers)
+		assert.Equal(t, bart.Counts["stops.txt"]*2, counts["stops.txt"])
+	})
+	t.Run("prefix conflicts", func(t *testing.T) {
+		readers := openReaders(t, bart.URL, bart.URL)
+		plan, err := NewPlan(readers, Options{PrefixConflicts: true, Prefixes: []string{"", "b2:"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Empty(t, plan.Report.Merged)
+		renamed := map[string]int{}
+		for _, r := range plan.Report.Renamed {
+			renamed[r.Filename] += 1
+		}
+		assert.Equal(t, 1, renamed["agency.txt"])
+		assert.Equal(t, bart.Counts["stops.txt"], renamed["stops.txt"])
+		assert.Equal(t, bart.Counts["routes.txt"], renamed["routes.txt"])
+		assert.Equal(t, bart.Counts["trips.txt"], renamed["trips.txt"])
+		assert.Contains(t, plan.Report.Renamed, RenamedEntity{Feed: bart.URL, Filename: "stops.txt", EntityID: "12TH", NewEntityID: "b2:12TH"})
+
+		// Check references in the second feed
+		reader, err := plan.NewReader(readers...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tripRoutes := map[string]string{}
+		for ent := range reader.Trips() {
+			tripRoutes[ent.TripID.Val] = ent.RouteID.Val
+		}
+		assert.Equal(t, "01", tripRoutes["3610403WKDY"])
+		assert.Equal(t, "b2:01", tripRoutes["b2:3610403WKDY"])
+		for ent := range reader.Routes() {
+			if ent.RouteID.Val == "b2:01" {
+				assert.Equal(t, "b2:BART", ent.AgencyID.Val)
+			}
+		}
+		counts := countEntities(t, plan, readers)
+		assert.Equal(t, bart.Counts["stops.txt"]*2, counts["stops.txt"])
+		assert.Equal(t, bart.Counts["stop_times.txt"]*2, counts["stop_times.txt"])
+	})
+	t.Run("dedup", func(t *testing.T) {
+		readers := openReaders(t, bart.URL, bart.URL)
+		plan, err := NewPlan(readers, Options{
+			PrefixConflicts:    true,
+			DedupAgencies:      true,
+			DedupStopsDistance: 1.0,
+			DedupShapes:        true,
+			DedupCalendars:     true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		merged := map[string]int{}
+		for _, r := range plan.Report.Merged {
+			merged[r.Filename] += 1
+			assert.Equal(t, r.EntityID, r.IntoEntityID)
+		}
+		assert.Equal(t, 1, merged["agency.txt"])
+		assert.Equal(t, bart.Counts["stops.txt"], merged["stops.txt"])
+		assert.Equal(t, bart.Counts["calendar.txt"], merged["calendar.txt"])
+		assert.Equal(t, 12, merged["shapes.txt"])
+
+		// Routes and trips are not deduplicated
+		renamed := map[string]int{}
+		for _, r := range plan.Report.Renamed {
+			renamed[r.Filename] += 1
+		}
+		assert.Equal(t, 0, renamed["stops.txt"])
+		assert.Equal(t, bart.Counts["routes.txt"], renamed["routes.txt"])
+		assert.Equal(t, bart.Counts["trips.txt"], renamed["trips.txt"])
+
+		reader, err := plan.NewReader(readers...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for ent := range reader.Trips() {
+			if ent.TripID.Val == "2_3610403WKDY" {
+				assert.Equal(t, "2_01", ent.RouteID.Val)
+				assert.Equal(t, "WKDY", ent.ServiceID.Val)
+				assert.Equal(t, "01_shp", ent.ShapeID.Val)
+			}
+		}
+		counts := countEntities(t, plan, readers)
+		assert.Equal(t, 1, counts["agency.txt"])
+		assert.Equal(t, bart.Counts["stops.txt"], counts["stops.txt"])
+		assert.Equal(t, bart.Counts["calendar.txt"], counts["calendar.txt"])
+		assert.Equal(t, bart.Counts["calendar_dates.txt"], counts["calendar_dates.txt"])
+		assert.Equal(t, bart.Counts["shapes.txt"], counts["shapes.txt"])
+		assert.Equal(t, bart.Counts["routes.txt"]*2, counts["routes.txt"])
+		assert.Equal(t, bart.Counts["trips.txt"]*2, counts["trips.txt"])
+	})
+	t.Run("dedup stops distance", func(t *testing.T) {
+		readers := openReaders(t, bart.URL, bart.URL)
+		plan, err := NewPlan(readers, Options{DedupStopsDistance: 1.0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Empty(t, plan.Report.Renamed)
+		assert.Equal(t, bart.Counts["stops.txt"], len(plan.Report.Merged))
+		for _, r := range plan.Report.Merged {
+			assert.Equal(t, "stops.txt", r.Filename)
+		}
+	})
+	t.Run("no conflicts", func(t *testing.T) {
+		readers := openReaders(t, bart.URL, testreader.ExampleFeedCaltrain.URL)
+		plan, err := NewPlan(readers, Options{PrefixConflicts: true, DedupAgencies: true, DedupStopsDistance: 10.0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Empty(t, plan.Report.Renamed)
+		assert.Empty(t, plan.Report.Merged)
+	})
+}
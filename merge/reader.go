@@ -0,0 +1,300 @@
+package merge
+
+import (
+	"github.com/interline-io/transitland-lib/adapters"
+	"github.com/interline-io/transitland-lib/gtfs"
+	"github.com/interline-io/transitland-lib/tt"
+)
+
+var bufferSize = 1000
+
+// feedReader applies a feed plan to the entities read from a single feed.
+// IDs are updated before the entities reach the copier, so the copier sees unique IDs across all feeds.
+type feedReader struct {
+	adapters.Reader
+	plan *feedPlan
+}
+
+// apply updates the entity ID and references, returning false if the entity was merged.
+func (r *feedReader) apply(ent tt.Entity) bool {
+	fn := ent.Filename()
+	if idf := entityIDField(ent); idf != nil {
+		if _, ok := r.plan.merged[fn][idf.Val]; ok {
+			return false
+		}
+		if newID, ok := r.plan.renamed[fn][idf.Val]; ok {
+			idf.Set(newID)
+		}
+	}
+	switch v := ent.(type) {
+	case *gtfs.CalendarDate:
+		// Exceptions are already present on the calendar this was merged into
+		if _, ok := r.plan.merged["calendar.txt"][v.ServiceID.Val]; ok {
+			return false
+		}
+	case *gtfs.Translation:
+		if v.RecordID.Val != "" {
+			tfn := v.TableNameValue.Val + ".txt"
+			if tfn == "stop_times.txt" {
+				tfn = "trips.txt"
+			}
+			v.RecordID.Set(r.plan.finalID(tfn, v.RecordID.Val))
+		}
+	}
+	// References not in the plan are left unchanged
+	if extEnt, ok := ent.(tt.EntityWithReferences); ok {
+		extEnt.UpdateKeys(r.plan.emap)
+	} else {
+		tt.ReflectUpdateKeys(r.plan.emap, ent)
+	}
+	return true
+}
+
+// sourceIDs maps final IDs back to IDs in this feed.
+func (r *feedReader) sourceIDs(fn string, ids []string) []string {
+	if len(ids) == 0 {
+		return nil
+	}
+	rev := map[string]string{}
+	for src, eid := range r.plan.renamed[fn] {
+		rev[eid] = src
+	}
+	var ret []string
+	for _, eid := range ids {
+		if src, ok := rev[eid]; ok {
+			ret = append(ret, src)
+		} else if _, ok := r.plan.renamed[fn][eid]; !ok {
+			ret = append(ret, eid)
+		}
+	}
+	if len(ret) == 0 {
+		// Nothing in this feed matches; avoid reading everything
+		ret = append(ret, "")
+	}
+	return ret
+}
+
+func mapEntities[T any, PT interface {
+	*T
+	tt.Entity
+}](r *feedReader, in chan T) chan T {
+	if r.plan.empty() {
+		return in
+	}
+	out := make(chan T, bufferSize)
+	go func() {
+		defer close(out)
+		for ent := range in {
+			if r.apply(PT(&ent)) {
+				out <- ent
+			}
+		}
+	}()
+	return out
+}
+
+func mapGroups[T any, PT interface {
+	*T
+	tt.Entity
+}](r *feedReader, in chan []T) chan []T {
+	if r.plan.empty() {
+		return in
+	}
+	out := make(chan []T, bufferSize)
+	go func() {
+		defer close(out)
+		for grp := range in {
+			var ret []T
+			for _, ent := range grp {
+				if r.apply(PT(&ent)) {
+					ret = append(ret, ent)
+				}
+			}
+			if len(ret) > 0 {
+				out <- ret
+			}
+		}
+	}()
+	return out
+}
+
+// entityIDField returns the ID field for entities with IDs that may be renamed.
+func entityIDField(ent tt.Entity) *tt.String {
+	switch v := ent.(type) {
+	case *gtfs.Agency:
+		return &v.AgencyID
+	case *gtfs.Level:
+		return &v.LevelID
+	case *gtfs.Stop:
+		return &v.StopID
+	case *gtfs.Calendar:
+		return &v.ServiceID
+	case *gtfs.Shape:
+		return &v.ShapeID
+	case *gtfs.Route:
+		return &v.RouteID
+	case *gtfs.Trip:
+		return &v.TripID
+	case *gtfs.Pathway:
+		return &v.PathwayID
+	case *gtfs.FareAttribute:
+		return &v.FareID
+	case *gtfs.Area:
+		return &v.AreaID
+	case *gtfs.Network:
+		return &v.NetworkID
+	case *gtfs.BookingRule:
+		return &v.BookingRuleID
+	case *gtfs.LocationGroup:
+		return &v.LocationGroupID
+	case *gtfs.Location:
+		return &v.LocationID
+	case *gtfs.FareMedia:
+		return &v.FareMediaID
+	case *gtfs.RiderCategory:
+		return &v.RiderCategoryID
+	case *gtfs.FareProduct:
+		return &v.FareProductID
+	case *gtfs.Timeframe:
+		return &v.TimeframeGroupID
+	case *gtfs.FareLegRule:
+		return &v.LegGroupID
+	}
+	return nil
+}
+
+func (r *feedReader) StopTimesByTripID(ids ...string) chan []gtfs.StopTime {
+	return mapGroups(r, r.Reader.StopTimesByTripID(r.sourceIDs("trips.txt", ids)...))
+}
+
+func (r *feedReader) ShapesByShapeID(ids ...string) chan []gtfs.Shape {
+	return mapGroups(r, r.Reader.ShapesByShapeID(r.sourceIDs("shapes.txt", ids)...))
+}
+
+func (r *feedReader) Stops() chan gtfs.Stop {
+	return mapEntities(r, r.Reader.Stops())
+}
+
+func (r *feedReader) StopTimes() chan gtfs.StopTime {
+	return mapEntities(r, r.Reader.StopTimes())
+}
+
+func (r *feedReader) Agencies() chan gtfs.Agency {
+	return mapEntities(r, r.Reader.Agencies())
+}
+
+func (r *feedReader) Calendars() chan gtfs.Calendar {
+	return mapEntities(r, r.Reader.Calendars())
+}
+
+func (r *feedReader) CalendarDates() chan gtfs.CalendarDate {
+	return mapEntities(r, r.Reader.CalendarDates())
+}
+
+func (r *feedReader) FareAttributes() chan gtfs.FareAttribute {
+	return mapEntities(r, r.Reader.FareAttributes())
+}
+
+func (r *feedReader) FareRules() chan gtfs.FareRule {
+	return mapEntities(r, r.Reader.FareRules())
+}
+
+func (r *feedReader) FeedInfos() chan gtfs.FeedInfo {
+	return mapEntities(r, r.Reader.FeedInfos())
+}
+
+func (r *feedReader) Frequencies() chan gtfs.Frequency {
+	return mapEntities(r, r.Reader.Frequencies())
+}
+
+func (r *feedReader) Routes() chan gtfs.Route {
+	return mapEntities(r, r.Reader.Routes())
+}
+
+func (r *feedReader) Shapes() chan gtfs.Shape {
+	return mapEntities(r, r.Reader.Shapes())
+}
+
+func (r *feedReader) Transfers() chan gtfs.Transfer {
+	return mapEntities(r, r.Reader.Transfers())
+}
+
+func (r *feedReader) Pathways() chan gtfs.Pathway {
+	return mapEntities(r, r.Reader.Pathways())
+}
+
+func (r *feedReader) Levels() chan gtfs.Level {
+	return mapEntities(r, r.Reader.Levels())
+}
+
+func (r *feedReader) Trips() chan gtfs.Trip {
+	return mapEntities(r, r.Reader.Trips())
+}
+
+func (r *feedReader) Translations() chan gtfs.Translation {
+	return mapEntities(r, r.Reader.Translations())
+}
+
+func (r *feedReader) Attributions() chan gtfs.Attribution {
+	return mapEntities(r, r.Reader.Attributions())
+}
+
+func (r *feedReader) Areas() chan gtfs.Area {
+	return mapEntities(r, r.Reader.Areas())
+}
+
+func (r *feedReader) StopAreas() chan gtfs.StopArea {
+	return mapEntities(r, r.Reader.StopAreas())
+}
+
+func (r *feedReader) FareLegRules() chan gtfs.FareLegRule {
+	return mapEntities(r, r.Reader.FareLegRules())
+}
+
+func (r *feedReader) FareLegJoinRules() chan gtfs.FareLegJoinRule {
+	return mapEntities(r, r.Reader.FareLegJoinRules())
+}
+
+func (r *feedReader) FareTransferRules() chan gtfs.FareTransferRule {
+	return mapEntities(r, r.Reader.FareTransferRules())
+}
+
+func (r *feedReader) FareProducts() chan gtfs.FareProduct {
+	return mapEntities(r, r.Reader.FareProducts())
+}
+
+func (r *feedReader) RiderCategories() chan gtfs.RiderCategory {
+	return mapEntities(r, r.Reader.RiderCategories())
+}
+
+func (r *feedReader) FareMedia() chan gtfs.FareMedia {
+	return mapEntities(r, r.Reader.FareMedia())
+}
+
+func (r *feedReader) Timeframes() chan gtfs.Timeframe {
+	return mapEntities(r, r.Reader.Timeframes())
+}
+
+func (r *feedReader) Networks() chan gtfs.Network {
+	return mapEntities(r, r.Reader.Networks())
+}
+
+func (r *feedReader) RouteNetworks() chan gtfs.RouteNetwork {
+	return mapEntities(r, r.Reader.RouteNetworks())
+}
+
+func (r *feedReader) LocationGroups() chan gtfs.LocationGroup {
+	return mapEntities(r, r.Reader.LocationGroups())
+}
+
+func (r *feedReader) LocationGroupStops() chan gtfs.LocationGroupStop {
+	return mapEntities(r, r.Reader.LocationGroupStops())
+}
+
+func (r *feedReader) BookingRules() chan gtfs.BookingRule {
+	return mapEntities(r, r.Reader.BookingRules())
+}
+
+func (r *feedReader) Locations() chan gtfs.Location {
+	return mapEntities(r, r.Reader.Locations())
+}
@@ -0,0 +1,387 @@
+// Package netex provides adapters to convert GTFS to and from NeTEx XML, using the European Passenger Information Profile (EPIP).
+package netex
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+const (
+	netexNamespace = "http://www.netex.org.uk/netex"
+	gmlNamespace   = "http://www.opengis.net/gml/3.2"
+	netexVersion   = "1.1"
+	objectVersion  = "1"
+)
+
+// PublicationDelivery is the root element of a NeTEx document.
+type PublicationDelivery struct {
+	XMLName              xml.Name    `xml:"PublicationDelivery"`
+	Xmlns                string      `xml:"xmlns,attr,omitempty"`
+	XmlnsGml             string      `xml:"xmlns:gml,attr,omitempty"`
+	Version              string      `xml:"version,attr,omitempty"`
+	PublicationTimestamp string      `xml:"PublicationTimestamp"`
+	ParticipantRef       string      `xml:"ParticipantRef"`
+	Description          string      `xml:"Description,omitempty"`
+	DataObjects          DataObjects `xml:"dataObjects"`
+}
+
+// DataObjects contains frames, either grouped in a CompositeFrame or directly.
+type DataObjects struct {
+	CompositeFrames []CompositeFrame `xml:"CompositeFrame"`
+	Frames
+}
+
+type CompositeFrame struct {
+	ID            string         `xml:"id,attr"`
+	Version       string         `xml:"version,attr"`
+	ValidBetween  *ValidBetween  `xml:"ValidBetween,omitempty"`
+	FrameDefaults *FrameDefaults `xml:"FrameDefaults,omitempty"`
+	Frames        Frames         `xml:"frames"`
+}
+
+type ValidBetween struct {
+	FromDate string `xml:"FromDate,omitempty"`
+	ToDate   string `xml:"ToDate,omitempty"`
+}
+
+type FrameDefaults struct {
+	DefaultLocale *Locale `xml:"DefaultLocale,omitempty"`
+}
+
+type Locale struct {
+	TimeZone        string `xml:"TimeZone,omitempty"`
+	DefaultLanguage string `xml:"DefaultLanguage,omitempty"`
+}
+
+type Frames struct {
+	ResourceFrames        []ResourceFrame        `xml:"ResourceFrame"`
+	SiteFrames            []SiteFrame            `xml:"SiteFrame"`
+	ServiceCalendarFrames []ServiceCalendarFrame `xml:"ServiceCalendarFrame"`
+	ServiceFrames         []ServiceFrame         `xml:"ServiceFrame"`
+	TimetableFrames       []TimetableFrame       `xml:"TimetableFrame"`
+}
+
+// Ref is a reference to another NeTEx object.
+type Ref struct {
+	Ref     string `xml:"ref,attr"`
+	Version string `xml:"version,attr,omitempty"`
+}
+
+func newRef(ref string) *Ref {
+	return &Ref{Ref: ref, Version: objectVersion}
+}
+
+// Resources
+
+type ResourceFrame struct {
+	ID          string     `xml:"id,attr"`
+	Version     string     `xml:"version,attr"`
+	Authorities []Operator `xml:"organisations>Authority"`
+	Operators   []Operator `xml:"organisations>Operator"`
+}
+
+type Operator struct {
+	ID               string          `xml:"id,attr"`
+	Version          string          `xml:"version,attr"`
+	Name             string          `xml:"Name"`
+	ContactDetails   *ContactDetails `xml:"ContactDetails,omitempty"`
+	OrganisationType string          `xml:"OrganisationType,omitempty"`
+}
+
+type ContactDetails struct {
+	Email string `xml:"Email,omitempty"`
+	Phone string `xml:"Phone,omitempty"`
+	Url   string `xml:"Url,omitempty"`
+}
+
+// Sites
+
+type SiteFrame struct {
+	ID         string      `xml:"id,attr"`
+	Version    string      `xml:"version,attr"`
+	StopPlaces []StopPlace `xml:"stopPlaces>StopPlace"`
+}
+
+type StopPlace struct {
+	ID                      string                   `xml:"id,attr"`
+	Version                 string                   `xml:"version,attr"`
+	Name                    string                   `xml:"Name"`
+	Description             string                   `xml:"Description,omitempty"`
+	Centroid                *Centroid                `xml:"Centroid,omitempty"`
+	Url                     string                   `xml:"Url,omitempty"`
+	PublicCode              string                   `xml:"PublicCode,omitempty"`
+	AccessibilityAssessment *AccessibilityAssessment `xml:"AccessibilityAssessment,omitempty"`
+	TransportMode           string                   `xml:"TransportMode,omitempty"`
+	Quays                   []Quay                   `xml:"quays>Quay"`
+}
+
+type Quay struct {
+	ID                      string                   `xml:"id,attr"`
+	Version                 string                   `xml:"version,attr"`
+	Name                    string                   `xml:"Name,omitempty"`
+	Description             string                   `xml:"Description,omitempty"`
+	Centroid                *Centroid                `xml:"Centroid,omitempty"`
+	PublicCode              string                   `xml:"PublicCode,omitempty"`
+	AccessibilityAssessment *AccessibilityAssessment `xml:"AccessibilityAssessment,omitempty"`
+}
+
+type Centroid struct {
+	Location Location `xml:"Location"`
+}
+
+type Location struct {
+	Longitude float64 `xml:"Longitude"`
+	Latitude  float64 `xml:"Latitude"`
+}
+
+type AccessibilityAssessment struct {
+	ID                     string `xml:"id,attr"`
+	Version                string `xml:"version,attr"`
+	MobilityImpairedAccess string `xml:"MobilityImpairedAccess"`
+	WheelchairAccess       string `xml:"limitations>AccessibilityLimitation>WheelchairAccess"`
+}
+
+// Calendars
+
+type ServiceCalendarFrame struct {
+	ID                 string              `xml:"id,attr"`
+	Version            string              `xml:"version,attr"`
+	DayTypes           []DayType           `xml:"dayTypes>DayType"`
+	OperatingDays      []OperatingDay      `xml:"operatingDays>OperatingDay"`
+	OperatingPeriods   []OperatingPeriod   `xml:"operatingPeriods>OperatingPeriod"`
+	DayTypeAssignments []DayTypeAssignment `xml:"dayTypeAssignments>DayTypeAssignment"`
+}
+
+type DayType struct {
+	ID         string          `xml:"id,attr"`
+	Version    string          `xml:"version,attr"`
+	Name       string          `xml:"Name,omitempty"`
+	Properties []PropertyOfDay `xml:"properties>PropertyOfDay"`
+}
+
+type PropertyOfDay struct {
+	DaysOfWeek string `xml:"DaysOfWeek"`
+}
+
+type OperatingDay struct {
+	ID           string `xml:"id,attr"`
+	Version      string `xml:"version,attr"`
+	CalendarDate string `xml:"CalendarDate"`
+}
+
+type OperatingPeriod struct {
+	ID       string `xml:"id,attr"`
+	Version  string `xml:"version,attr"`
+	FromDate string `xml:"FromDate"`
+	ToDate   string `xml:"ToDate"`
+}
+
+type DayTypeAssignment struct {
+	ID                 string `xml:"id,attr"`
+	Version            string `xml:"version,attr"`
+	Order              int    `xml:"order,attr"`
+	OperatingPeriodRef *Ref   `xml:"OperatingPeriodRef,omitempty"`
+	OperatingDayRef    *Ref   `xml:"OperatingDayRef,omitempty"`
+	Date               string `xml:"Date,omitempty"`
+	DayTypeRef         Ref    `xml:"DayTypeRef"`
+	IsAvailable        *bool  `xml:"isAvailable,omitempty"`
+}
+
+// Services
+
+type ServiceFrame struct {
+	ID                   string                    `xml:"id,attr"`
+	Version              string                    `xml:"version,attr"`
+	Routes               []Route                   `xml:"routes>Route"`
+	Lines                []Line                    `xml:"lines>Line"`
+	ScheduledStopPoints  []ScheduledStopPoint      `xml:"scheduledStopPoints>ScheduledStopPoint"`
+	ServiceLinks         []ServiceLink             `xml:"serviceLinks>ServiceLink"`
+	StopAssignments      []PassengerStopAssignment `xml:"stopAssignments>PassengerStopAssignment"`
+	JourneyPatterns      []ServiceJourneyPattern   `xml:"journeyPatterns>ServiceJourneyPattern"`
+	OtherJourneyPatterns []ServiceJourneyPattern   `xml:"journeyPatterns>JourneyPattern"`
+}
+
+type Route struct {
+	ID      string `xml:"id,attr"`
+	Version string `xml:"version,attr"`
+	Name    string `xml:"Name,omitempty"`
+	LineRef *Ref   `xml:"LineRef,omitempty"`
+}
+
+type Line struct {
+	ID            string        `xml:"id,attr"`
+	Version       string        `xml:"version,attr"`
+	Name          string        `xml:"Name"`
+	Description   string        `xml:"Description,omitempty"`
+	TransportMode string        `xml:"TransportMode"`
+	Url           string        `xml:"Url,omitempty"`
+	PublicCode    string        `xml:"PublicCode,omitempty"`
+	PrivateCode   string        `xml:"PrivateCode,omitempty"`
+	AuthorityRef  *Ref          `xml:"AuthorityRef,omitempty"`
+	OperatorRef   *Ref          `xml:"OperatorRef,omitempty"`
+	Presentation  *Presentation `xml:"Presentation,omitempty"`
+}
+
+type Presentation struct {
+	Colour     string `xml:"Colour,omitempty"`
+	TextColour string `xml:"TextColour,omitempty"`
+}
+
+type ScheduledStopPoint struct {
+	ID       string    `xml:"id,attr"`
+	Version  string    `xml:"version,attr"`
+	Name     string    `xml:"Name,omitempty"`
+	Location *Location `xml:"Location,omitempty"`
+}
+
+type ServiceLink struct {
+	ID           string      `xml:"id,attr"`
+	Version      string      `xml:"version,attr"`
+	Distance     float64     `xml:"Distance,omitempty"`
+	LineString   *LineString `xml:"LineString,omitempty"`
+	Projection   *LineString `xml:"projections>LinkSequenceProjection>LineString,omitempty"`
+	FromPointRef Ref         `xml:"FromPointRef"`
+	ToPointRef   Ref         `xml:"ToPointRef"`
+}
+
+// LineString is a GML line geometry.
+// Elements are decoded by local name, but must be written with the gml prefix.
+type LineString struct {
+	ID      string   `xml:"id,attr"`
+	PosList string   `xml:"posList"`
+	Pos     []string `xml:"pos"`
+}
+
+func (ent LineString) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	v := struct {
+		XMLName xml.Name `xml:"gml:LineString"`
+		ID      string   `xml:"gml:id,attr"`
+		PosList string   `xml:"gml:posList"`
+	}{ID: ent.ID, PosList: ent.PosList}
+	return e.Encode(v)
+}
+
+type PassengerStopAssignment struct {
+	ID                    string `xml:"id,attr"`
+	Version               string `xml:"version,attr"`
+	Order                 int    `xml:"order,attr"`
+	ScheduledStopPointRef Ref    `xml:"ScheduledStopPointRef"`
+	StopPlaceRef          *Ref   `xml:"StopPlaceRef,omitempty"`
+	QuayRef               *Ref   `xml:"QuayRef,omitempty"`
+}
+
+type ServiceJourneyPattern struct {
+	ID               string                        `xml:"id,attr"`
+	Version          string                        `xml:"version,attr"`
+	Name             string                        `xml:"Name,omitempty"`
+	RouteRef         *Ref                          `xml:"RouteRef,omitempty"`
+	DirectionType    string                        `xml:"DirectionType,omitempty"`
+	PointsInSequence []StopPointInJourneyPattern   `xml:"pointsInSequence>StopPointInJourneyPattern"`
+	LinksInSequence  []ServiceLinkInJourneyPattern `xml:"linksInSequence>ServiceLinkInJourneyPattern"`
+}
+
+type StopPointInJourneyPattern struct {
+	ID                    string `xml:"id,attr"`
+	Version               string `xml:"version,attr"`
+	Order                 int    `xml:"order,attr"`
+	ScheduledStopPointRef Ref    `xml:"ScheduledStopPointRef"`
+	ForAlighting          *bool  `xml:"ForAlighting,omitempty"`
+	ForBoarding           *bool  `xml:"ForBoarding,omitempty"`
+}
+
+type ServiceLinkInJourneyPattern struct {
+	ID             string `xml:"id,attr"`
+	Version        string `xml:"version,attr"`
+	Order          int    `xml:"order,attr"`
+	ServiceLinkRef Ref    `xml:"ServiceLinkRef"`
+}
+
+// Timetables
+
+type TimetableFrame struct {
+	ID              string           `xml:"id,attr"`
+	Version         string           `xml:"version,attr"`
+	ServiceJourneys []ServiceJourney `xml:"vehicleJourneys>ServiceJourney"`
+}
+
+type ServiceJourney struct {
+	ID                       string                  `xml:"id,attr"`
+	Version                  string                  `xml:"version,attr"`
+	Name                     string                  `xml:"Name,omitempty"`
+	PrivateCode              string                  `xml:"PrivateCode,omitempty"`
+	DayTypes                 []Ref                   `xml:"dayTypes>DayTypeRef"`
+	ServiceJourneyPatternRef *Ref                    `xml:"ServiceJourneyPatternRef,omitempty"`
+	JourneyPatternRef        *Ref                    `xml:"JourneyPatternRef,omitempty"`
+	OperatorRef              *Ref                    `xml:"OperatorRef,omitempty"`
+	LineRef                  *Ref                    `xml:"LineRef,omitempty"`
+	PassingTimes             []TimetabledPassingTime `xml:"passingTimes>TimetabledPassingTime"`
+}
+
+type TimetabledPassingTime struct {
+	Version                      string `xml:"version,attr,omitempty"`
+	StopPointInJourneyPatternRef Ref    `xml:"StopPointInJourneyPatternRef"`
+	ArrivalTime                  string `xml:"ArrivalTime,omitempty"`
+	ArrivalDayOffset             int    `xml:"ArrivalDayOffset,omitempty"`
+	DepartureTime                string `xml:"DepartureTime,omitempty"`
+	DepartureDayOffset           int    `xml:"DepartureDayOffset,omitempty"`
+}
+
+// Helpers
+
+// transportModes maps basic GTFS route types to NeTEx transport modes.
+var transportModes = map[int]string{
+	0:  "tram",
+	1:  "metro",
+	2:  "rail",
+	3:  "bus",
+	4:  "water",
+	5:  "tram",
+	6:  "cableway",
+	7:  "funicular",
+	11: "trolleyBus",
+	12: "rail",
+}
+
+// transportMode returns the NeTEx transport mode for a GTFS route type, including extended route types.
+func transportMode(routeType int) string {
+	if mode, ok := transportModes[routeType]; ok {
+		return mode
+	}
+	switch {
+	case routeType >= 100 && routeType < 200:
+		return "rail"
+	case routeType >= 200 && routeType < 300:
+		return "coach"
+	case routeType >= 400 && routeType < 500:
+		return "metro"
+	case routeType >= 700 && routeType < 800:
+		return "bus"
+	case routeType >= 800 && routeType < 900:
+		return "trolleyBus"
+	case routeType >= 900 && routeType < 1000:
+		return "tram"
+	case routeType >= 1000 && routeType < 1100:
+		return "water"
+	case routeType >= 1100 && routeType < 1200:
+		return "air"
+	case routeType >= 1300 && routeType < 1400:
+		return "cableway"
+	case routeType >= 1400 && routeType < 1500:
+		return "funicular"
+	case routeType >= 1500 && routeType < 1600:
+		return "taxi"
+	}
+	return "other"
+}
+
+// daysOfWeek returns the NeTEx DaysOfWeek list for GTFS calendar flags, in Monday to Sunday order.
+func daysOfWeek(days [7]int) string {
+	names := []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+	var ret []string
+	for i, v := range days {
+		if v == 1 {
+			ret = append(ret, names[i])
+		}
+	}
+	return strings.Join(ret, " ")
+}
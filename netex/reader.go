@@ -0,0 +1,887 @@
+package netex
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/interline-io/transitland-lib/adapters"
+	"github.com/interline-io/transitland-lib/adapters/empty"
+	"github.com/interline-io/transitland-lib/ext"
+	"github.com/interline-io/transitland-lib/gtfs"
+	"github.com/interline-io/transitland-lib/service"
+	"github.com/interline-io/transitland-lib/tlcsv"
+	"github.com/interline-io/transitland-lib/tt"
+)
+
+func init() {
+	// Register readers
+	r := func(url string) (adapters.Reader, error) { return NewReader(url) }
+	ext.RegisterReader("netex", r)
+}
+
+var bufferSize = 1000
+
+// DefaultTimezone is used for agencies when the NeTEx frame defaults do not provide a timezone.
+var DefaultTimezone = "UTC"
+
+// Reader reads NeTEx XML files from a zip archive, directory, or single XML file and converts them to GTFS.
+// All XML files are parsed when the Reader is opened; GTFS files without a NeTEx equivalent are empty.
+// Entity IDs are the NeTEx object IDs.
+type Reader struct {
+	empty.Reader
+	DefaultTimezone string
+	path            string
+	adapter         tlcsv.Adapter
+	files           []string
+	agencies        []gtfs.Agency
+	stops           []gtfs.Stop
+	routes          []gtfs.Route
+	trips           []gtfs.Trip
+	calendars       []gtfs.Calendar
+	calendarDates   []gtfs.CalendarDate
+	stopTimes       map[string][]gtfs.StopTime
+	shapeIDs        []string
+	shapes          map[string][]gtfs.Shape
+}
+
+// NewReader returns a new Reader for the given path, with or without the netex:// prefix.
+func NewReader(path string) (*Reader, error) {
+	path = strings.TrimPrefix(path, "netex://")
+	if path == "" {
+		return nil, errors.New("no input path")
+	}
+	reader := &Reader{
+		DefaultTimezone: DefaultTimezone,
+		path:            path,
+	}
+	if strings.HasSuffix(path, ".xml") {
+		reader.adapter = tlcsv.NewDirAdapter(filepath.Dir(path))
+		reader.files = []string{filepath.Base(path)}
+	} else {
+		a, err := tlcsv.NewAdapter(path)
+		if err != nil {
+			return nil, err
+		}
+		reader.adapter = a
+	}
+	return reader, nil
+}
+
+func (reader *Reader) String() string {
+	return "netex://" + reader.path
+}
+
+// Path returns the input path.
+func (reader *Reader) Path() string {
+	return reader.path
+}
+
+// SHA1 returns the SHA1 of the input.
+func (reader *Reader) SHA1() (string, error) {
+	return reader.adapter.SHA1()
+}
+
+// DirSHA1 returns the SHA1 of the files in the input.
+func (reader *Reader) DirSHA1() (string, error) {
+	return reader.adapter.DirSHA1()
+}
+
+// Open parses all NeTEx XML files.
+func (reader *Reader) Open() error {
+	if err := reader.adapter.Open(); err != nil {
+		return err
+	}
+	if len(reader.files) == 0 {
+		a, ok := reader.adapter.(interface{ FileInfos() ([]os.FileInfo, error) })
+		if !ok {
+			return errors.New("could not list files")
+		}
+		fis, err := a.FileInfos()
+		if err != nil {
+			return err
+		}
+		for _, fi := range fis {
+			if strings.HasSuffix(strings.ToLower(fi.Name()), ".xml") {
+				reader.files = append(reader.files, fi.Name())
+			}
+		}
+	}
+	if len(reader.files) == 0 {
+		return errors.New("no NeTEx xml files found")
+	}
+	idx := newIndex()
+	for _, fn := range reader.files {
+		var decodeErr error
+		err := reader.adapter.OpenFile(fn, func(r io.Reader) {
+			doc := PublicationDelivery{}
+			if decodeErr = xml.NewDecoder(r).Decode(&doc); decodeErr == nil {
+				idx.addDocument(doc)
+			}
+		})
+		if err == nil {
+			err = decodeErr
+		}
+		if err != nil {
+			return fmt.Errorf("could not read '%s': %s", fn, err.Error())
+		}
+	}
+	reader.convert(idx)
+	return nil
+}
+
+// Close the Reader.
+func (reader *Reader) Close() error {
+	return reader.adapter.Close()
+}
+
+// ValidateStructure checks that NeTEx files were found and parsed.
+func (reader *Reader) ValidateStructure() []error {
+	if len(reader.files) == 0 {
+		return []error{errors.New("no NeTEx xml files found")}
+	}
+	return nil
+}
+
+// ReadEntities closes the channel; extension files are not supported.
+func (reader *Reader) ReadEntities(c any) error {
+	reflect.ValueOf(c).Close()
+	return nil
+}
+
+func (reader *Reader) Agencies() chan gtfs.Agency {
+	return readEntities(reader.agencies)
+}
+
+func (reader *Reader) Stops() chan gtfs.Stop {
+	return readEntities(reader.stops)
+}
+
+func (reader *Reader) Routes() chan gtfs.Route {
+	return readEntities(reader.routes)
+}
+
+func (reader *Reader) Trips() chan gtfs.Trip {
+	return readEntities(reader.trips)
+}
+
+func (reader *Reader) Calendars() chan gtfs.Calendar {
+	return readEntities(reader.calendars)
+}
+
+func (reader *Reader) CalendarDates() chan gtfs.CalendarDate {
+	return readEntities(reader.calendarDates)
+}
+
+func (reader *Reader) StopTimes() chan gtfs.StopTime {
+	var ents []gtfs.StopTime
+	for _, trip := range reader.trips {
+		ents = append(ents, reader.stopTimes[trip.TripID.Val]...)
+	}
+	return readEntities(ents)
+}
+
+func (reader *Reader) Shapes() chan gtfs.Shape {
+	var ents []gtfs.Shape
+	for _, shapeID := range reader.shapeIDs {
+		ents = append(ents, reader.shapes[shapeID]...)
+	}
+	return readEntities(ents)
+}
+
+// StopTimesByTripID returns stop times grouped by trip, optionally filtered by trip ID.
+func (reader *Reader) StopTimesByTripID(ids ...string) chan []gtfs.StopTime {
+	var groups [][]gtfs.StopTime
+	for _, trip := range reader.trips {
+		if len(ids) > 0 && !slices.Contains(ids, trip.TripID.Val) {
+			continue
+		}
+		if sts := reader.stopTimes[trip.TripID.Val]; len(sts) > 0 {
+			groups = append(groups, sts)
+		}
+	}
+	return readEntities(groups)
+}
+
+// ShapesByShapeID returns shape points grouped by shape, optionally filtered by shape ID.
+func (reader *Reader) ShapesByShapeID(ids ...string) chan []gtfs.Shape {
+	var groups [][]gtfs.Shape
+	for _, shapeID := range reader.shapeIDs {
+		if len(ids) > 0 && !slices.Contains(ids, shapeID) {
+			continue
+		}
+		groups = append(groups, reader.shapes[shapeID])
+	}
+	return readEntities(groups)
+}
+
+// set sets the value when not empty.
+func set[T interface{ Set(string) }](field T, v string) {
+	if v != "" {
+		field.Set(v)
+	}
+}
+
+func readEntities[T any](ents []T) chan T {
+	out := make(chan T, bufferSize)
+	go func() {
+		for _, ent := range ents {
+			out <- ent
+		}
+		close(out)
+	}()
+	return out
+}
+
+// index holds NeTEx objects from all files, by ID.
+type index struct {
+	timezone         string
+	language         string
+	operators        []Operator
+	authorities      map[string]Operator
+	stopPlaces       []StopPlace
+	dayTypes         []DayType
+	operatingDays    map[string]string
+	operatingPeriods map[string]OperatingPeriod
+	dtAssignments    map[string][]DayTypeAssignment
+	lines            []Line
+	routes           map[string]Route
+	ssps             map[string]ScheduledStopPoint
+	sspAssignments   map[string]PassengerStopAssignment
+	serviceLinks     map[string]ServiceLink
+	patterns         map[string]ServiceJourneyPattern
+	journeys         []ServiceJourney
+}
+
+func newIndex() *index {
+	return &index{
+		authorities:      map[string]Operator{},
+		operatingDays:    map[string]string{},
+		operatingPeriods: map[string]OperatingPeriod{},
+		dtAssignments:    map[string][]DayTypeAssignment{},
+		routes:           map[string]Route{},
+		ssps:             map[string]ScheduledStopPoint{},
+		sspAssignments:   map[string]PassengerStopAssignment{},
+		serviceLinks:     map[string]ServiceLink{},
+		patterns:         map[string]ServiceJourneyPattern{},
+	}
+}
+
+func (idx *index) addDocument(doc PublicationDelivery) {
+	for _, cf := range doc.DataObjects.CompositeFrames {
+		if cf.FrameDefaults != nil && cf.FrameDefaults.DefaultLocale != nil {
+			if idx.timezone == "" {
+				idx.timezone = cf.FrameDefaults.DefaultLocale.TimeZone
+			}
+			if idx.language == "" {
+				idx.language = cf.FrameDefaults.DefaultLocale.DefaultLanguage
+			}
+		}
+		idx.addFrames(cf.Frames)
+	}
+	idx.addFrames(doc.DataObjects.Frames)
+}
+
+func (idx *index) addFrames(frames Frames) {
+	for _, f := range frames.ResourceFrames {
+		idx.operators = append(idx.operators, f.Operators...)
+		for _, ent := range f.Authorities {
+			idx.authorities[ent.ID] = ent
+		}
+	}
+	for _, f := range frames.SiteFrames {
+		idx.stopPlaces = append(idx.stopPlaces, f.StopPlaces...)
+	}
+	for _, f := range frames.ServiceCalendarFrames {
+		idx.dayTypes = append(idx.dayTypes, f.DayTypes...)
+		for _, ent := range f.OperatingDays {
+			idx.operatingDays[ent.ID] = ent.CalendarDate
+		}
+		for _, ent := range f.OperatingPeriods {
+			idx.operatingPeriods[ent.ID] = ent
+		}
+		for _, ent := range f.DayTypeAssignments {
+			idx.dtAssignments[ent.DayTypeRef.Ref] = append(idx.dtAssignments[ent.DayTypeRef.Ref], ent)
+		}
+	}
+	for _, f := range frames.ServiceFrames {
+		idx.lines = append(idx.lines, f.Lines...)
+		for _, ent := range f.Routes {
+			idx.routes[ent.ID] = ent
+		}
+		for _, ent := range f.ScheduledStopPoints {
+			idx.ssps[ent.ID] = ent
+		}
+		for _, ent := range f.StopAssignments {
+			idx.sspAssignments[ent.ScheduledStopPointRef.Ref] = ent
+		}
+		for _, ent := range f.ServiceLinks {
+			idx.serviceLinks[ent.ID] = ent
+		}
+		for _, ent := range f.JourneyPatterns {
+			idx.patterns[ent.ID] = ent
+		}
+		for _, ent := range f.OtherJourneyPatterns {
+			idx.patterns[ent.ID] = ent
+		}
+	}
+	for _, f := range frames.TimetableFrames {
+		idx.journeys = append(idx.journeys, f.ServiceJourneys...)
+	}
+}
+
+// convert builds GTFS entities from the index.
+func (reader *Reader) convert(idx *index) {
+	reader.stopTimes = map[string][]gtfs.StopTime{}
+	reader.shapes = map[string][]gtfs.Shape{}
+	tz := idx.timezone
+	if tz == "" {
+		tz = reader.DefaultTimezone
+	}
+
+	// Routes, and the agencies they reference
+	agencyIDs := map[string]bool{}
+	addAgency := func(ent Operator) {
+		if agencyIDs[ent.ID] {
+			return
+		}
+		agencyIDs[ent.ID] = true
+		agency := gtfs.Agency{
+			AgencyID:       tt.NewString(ent.ID),
+			AgencyTimezone: tt.NewTimezone(tz),
+		}
+		set(&agency.AgencyName, ent.Name)
+		set(&agency.AgencyLang, idx.language)
+		if cd := ent.ContactDetails; cd != nil {
+			set(&agency.AgencyURL, cd.Url)
+			set(&agency.AgencyPhone, cd.Phone)
+			set(&agency.AgencyEmail, cd.Email)
+		}
+		reader.agencies = append(reader.agencies, agency)
+	}
+	for _, ent := range idx.operators {
+		addAgency(ent)
+	}
+	routeIDs := map[string]bool{}
+	for _, ent := range idx.lines {
+		routeIDs[ent.ID] = true
+		route := gtfs.Route{
+			RouteID:   tt.NewString(ent.ID),
+			RouteType: tt.NewInt(routeType(ent.TransportMode)),
+		}
+		set(&route.RouteShortName, ent.PublicCode)
+		set(&route.RouteDesc, ent.Description)
+		set(&route.RouteURL, ent.Url)
+		if ent.Name != ent.PublicCode {
+			set(&route.RouteLongName, ent.Name)
+		}
+		if ent.OperatorRef != nil {
+			set(&route.AgencyID, ent.OperatorRef.Ref)
+		} else if ent.AuthorityRef != nil {
+			set(&route.AgencyID, ent.AuthorityRef.Ref)
+			if a, ok := idx.authorities[ent.AuthorityRef.Ref]; ok {
+				addAgency(a)
+			}
+		}
+		if p := ent.Presentation; p != nil {
+			set(&route.RouteColor, p.Colour)
+			set(&route.RouteTextColor, p.TextColour)
+		}
+		reader.routes = append(reader.routes, route)
+	}
+
+	// Stops
+	stopIDs := map[string]bool{}
+	addStop := func(stopID string, name string, code string, desc string, c *Centroid, aa *AccessibilityAssessment, locationType int, parent string) {
+		stopIDs[stopID] = true
+		stop := gtfs.Stop{
+			StopID:             tt.NewString(stopID),
+			LocationType:       tt.NewInt(locationType),
+			WheelchairBoarding: tt.NewInt(wheelchairBoarding(aa)),
+		}
+		set(&stop.StopName, name)
+		set(&stop.StopCode, code)
+		set(&stop.StopDesc, desc)
+		set(&stop.ParentStation, parent)
+		if c != nil {
+			stop.StopLon = tt.NewFloat(c.Location.Longitude)
+			stop.StopLat = tt.NewFloat(c.Location.Latitude)
+			stop.SetCoordinates([2]float64{c.Location.Longitude, c.Location.Latitude})
+		}
+		reader.stops = append(reader.stops, stop)
+	}
+	stopPlaceQuays := map[string]string{}
+	for _, sp := range idx.stopPlaces {
+		// A StopPlace without Quays is used directly as a stop
+		if len(sp.Quays) == 0 {
+			addStop(sp.ID, sp.Name, sp.PublicCode, sp.Description, sp.Centroid, sp.AccessibilityAssessment, 0, "")
+			stopPlaceQuays[sp.ID] = sp.ID
+			continue
+		}
+		centroid := sp.Centroid
+		if centroid == nil {
+			centroid = quayCentroid(sp.Quays)
+		}
+		addStop(sp.ID, sp.Name, sp.PublicCode, sp.Description, centroid, sp.AccessibilityAssessment, 1, "")
+		stopPlaceQuays[sp.ID] = sp.Quays[0].ID
+		for _, q := range sp.Quays {
+			name := q.Name
+			if name == "" {
+				name = sp.Name
+			}
+			c := q.Centroid
+			if c == nil {
+				c = centroid
+			}
+			aa := q.AccessibilityAssessment
+			if aa == nil {
+				aa = sp.AccessibilityAssessment
+			}
+			addStop(q.ID, name, q.PublicCode, q.Description, c, aa, 0, sp.ID)
+		}
+	}
+	// Resolve scheduled stop points to stops
+	sspStops := map[string]string{}
+	var sspIDs []string
+	for sspID := range idx.ssps {
+		sspIDs = append(sspIDs, sspID)
+	}
+	slices.Sort(sspIDs)
+	for _, sspID := range sspIDs {
+		ssp := idx.ssps[sspID]
+		if psa, ok := idx.sspAssignments[sspID]; ok {
+			if psa.QuayRef != nil && stopIDs[psa.QuayRef.Ref] {
+				sspStops[sspID] = psa.QuayRef.Ref
+				continue
+			}
+			if psa.StopPlaceRef != nil {
+				if q, ok := stopPlaceQuays[psa.StopPlaceRef.Ref]; ok {
+					sspStops[sspID] = q
+					continue
+				}
+			}
+		}
+		// Unassigned stop points with a location become stops
+		if ssp.Location != nil {
+			addStop(ssp.ID, ssp.Name, "", "", &Centroid{Location: *ssp.Location}, nil, 0, "")
+			sspStops[sspID] = ssp.ID
+		}
+	}
+
+	// Calendars
+	services := map[string]*service.Service{}
+	for _, dt := range idx.dayTypes {
+		svc := idx.service(dt)
+		services[dt.ID] = svc
+		reader.addService(svc)
+	}
+
+	// Trips
+	for _, sj := range idx.journeys {
+		patternRef := sj.ServiceJourneyPatternRef
+		if patternRef == nil {
+			patternRef = sj.JourneyPatternRef
+		}
+		var jp ServiceJourneyPattern
+		if patternRef != nil {
+			jp = idx.patterns[patternRef.Ref]
+		}
+		trip := gtfs.Trip{TripID: tt.NewString(sj.ID)}
+		set(&trip.TripHeadsign, sj.Name)
+		set(&trip.TripShortName, sj.PrivateCode)
+		if sj.LineRef != nil {
+			set(&trip.RouteID, sj.LineRef.Ref)
+		} else if jp.RouteRef != nil {
+			if r, ok := idx.routes[jp.RouteRef.Ref]; ok && r.LineRef != nil {
+				set(&trip.RouteID, r.LineRef.Ref)
+			}
+		}
+		switch jp.DirectionType {
+		case "outbound":
+			trip.DirectionID = tt.NewInt(0)
+		case "inbound":
+			trip.DirectionID = tt.NewInt(1)
+		}
+		// Service
+		var dtRefs []string
+		for _, ref := range sj.DayTypes {
+			dtRefs = append(dtRefs, ref.Ref)
+		}
+		if len(dtRefs) == 1 {
+			trip.ServiceID = tt.NewKey(dtRefs[0])
+		} else if len(dtRefs) > 1 {
+			serviceID := strings.Join(dtRefs, "+")
+			if _, ok := services[serviceID]; !ok {
+				var svcs []*service.Service
+				for _, ref := range dtRefs {
+					if svc, ok := services[ref]; ok {
+						svcs = append(svcs, svc)
+					}
+				}
+				svc := unionService(serviceID, svcs)
+				services[serviceID] = svc
+				reader.addService(svc)
+			}
+			trip.ServiceID = tt.NewKey(serviceID)
+		}
+		// Shape
+		if _, ok := reader.shapes[jp.ID]; ok {
+			trip.ShapeID = tt.NewKey(jp.ID)
+		} else if shape := idx.shape(jp); len(shape) > 0 {
+			reader.shapeIDs = append(reader.shapeIDs, jp.ID)
+			reader.shapes[jp.ID] = shape
+			trip.ShapeID = tt.NewKey(jp.ID)
+		}
+		// Stop times
+		points := map[string]StopPointInJourneyPattern{}
+		for _, p := range jp.PointsInSequence {
+			points[p.ID] = p
+		}
+		var sts []gtfs.StopTime
+		for i, pt := range sj.PassingTimes {
+			p, ok := points[pt.StopPointInJourneyPatternRef.Ref]
+			if !ok && pt.StopPointInJourneyPatternRef.Ref == "" && i < len(jp.PointsInSequence) {
+				p, ok = jp.PointsInSequence[i], true
+			}
+			if !ok {
+				continue
+			}
+			stopID, ok := sspStops[p.ScheduledStopPointRef.Ref]
+			if !ok {
+				continue
+			}
+			st := gtfs.StopTime{
+				TripID:       tt.NewString(sj.ID),
+				StopID:       tt.NewKey(stopID),
+				StopSequence: tt.NewInt(p.Order),
+			}
+			if p.Order == 0 {
+				st.StopSequence = tt.NewInt(i + 1)
+			}
+			if p.ForBoarding != nil && !*p.ForBoarding {
+				st.PickupType = tt.NewInt(1)
+			}
+			if p.ForAlighting != nil && !*p.ForAlighting {
+				st.DropOffType = tt.NewInt(1)
+			}
+			st.ArrivalTime = passingSeconds(pt.ArrivalTime, pt.ArrivalDayOffset)
+			st.DepartureTime = passingSeconds(pt.DepartureTime, pt.DepartureDayOffset)
+			if !st.ArrivalTime.Valid {
+				st.ArrivalTime = st.DepartureTime
+			}
+			if !st.DepartureTime.Valid {
+				st.DepartureTime = st.ArrivalTime
+			}
+			sts = append(sts, st)
+		}
+		reader.trips = append(reader.trips, trip)
+		reader.stopTimes[sj.ID] = sts
+	}
+}
+
+// addService adds the Calendar, unless the service only has added dates, and the CalendarDates.
+func (reader *Reader) addService(svc *service.Service) {
+	days := 0
+	for dow := 0; dow < 7; dow++ {
+		v, _ := svc.GetWeekday(dow)
+		days += v
+	}
+	if days > 0 {
+		cal := svc.Calendar
+		cal.Generated = tt.Bool{}
+		reader.calendars = append(reader.calendars, cal)
+	}
+	reader.calendarDates = append(reader.calendarDates, svc.CalendarDates()...)
+}
+
+// service builds a Service from a DayType and its assignments.
+// A DayType with a single OperatingPeriod maps directly to a Calendar with exceptions.
+// Otherwise the active dates are expanded and simplified.
+func (idx *index) service(dt DayType) *service.Service {
+	days := dayTypeDays(dt)
+	var periods []OperatingPeriod
+	var cds []gtfs.CalendarDate
+	for _, dta := range idx.dtAssignments[dt.ID] {
+		if dta.OperatingPeriodRef != nil {
+			if op, ok := idx.operatingPeriods[dta.OperatingPeriodRef.Ref]; ok {
+				periods = append(periods, op)
+			}
+			continue
+		}
+		date := dta.Date
+		if dta.OperatingDayRef != nil {
+			date = idx.operatingDays[dta.OperatingDayRef.Ref]
+		}
+		d, err := parseDate(date)
+		if err != nil {
+			continue
+		}
+		exceptionType := 1
+		if dta.IsAvailable != nil && !*dta.IsAvailable {
+			exceptionType = 2
+		}
+		cds = append(cds, gtfs.CalendarDate{
+			ServiceID:     tt.NewKey(dt.ID),
+			Date:          tt.NewDate(d),
+			ExceptionType: tt.NewInt(exceptionType),
+		})
+	}
+	if len(periods) == 1 {
+		start, err1 := parseDate(periods[0].FromDate)
+		end, err2 := parseDate(periods[0].ToDate)
+		if err1 == nil && err2 == nil {
+			cal := gtfs.Calendar{
+				ServiceID: tt.NewString(dt.ID),
+				StartDate: tt.NewDate(start),
+				EndDate:   tt.NewDate(end),
+			}
+			svc := service.NewService(cal, cds...)
+			for dow, v := range days {
+				svc.SetWeekday(dow, v)
+			}
+			return svc
+		}
+	}
+	// Expand periods into dates
+	active := map[string]time.Time{}
+	for _, op := range periods {
+		start, err1 := parseDate(op.FromDate)
+		end, err2 := parseDate(op.ToDate)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+			if days[int(d.Weekday())] == 1 {
+				active[d.Format("20060102")] = d
+			}
+		}
+	}
+	for _, cd := range cds {
+		key := cd.Date.Val.Format("20060102")
+		if cd.ExceptionType.Val == 1 {
+			active[key] = cd.Date.Val
+		} else {
+			delete(active, key)
+		}
+	}
+	var dates []time.Time
+	for _, d := range active {
+		dates = append(dates, d)
+	}
+	return datesService(dt.ID, dates, len(periods) > 0)
+}
+
+// shape joins the ServiceLink geometries of a journey pattern.
+// No shape is returned unless every link has a geometry.
+func (idx *index) shape(jp ServiceJourneyPattern) []gtfs.Shape {
+	var ret []gtfs.Shape
+	for _, lijp := range jp.LinksInSequence {
+		link, ok := idx.serviceLinks[lijp.ServiceLinkRef.Ref]
+		if !ok {
+			return nil
+		}
+		ls := link.LineString
+		if ls == nil {
+			ls = link.Projection
+		}
+		if ls == nil {
+			return nil
+		}
+		coords := lineStringCoords(*ls)
+		if len(coords) == 0 {
+			return nil
+		}
+		for i, c := range coords {
+			// Skip the shared point between consecutive links
+			if i == 0 && len(ret) > 0 {
+				last := ret[len(ret)-1]
+				if last.ShapePtLon.Val == c[0] && last.ShapePtLat.Val == c[1] {
+					continue
+				}
+			}
+			ret = append(ret, gtfs.Shape{
+				ShapeID:         tt.NewString(jp.ID),
+				ShapePtLon:      tt.NewFloat(c[0]),
+				ShapePtLat:      tt.NewFloat(c[1]),
+				ShapePtSequence: tt.NewInt(len(ret)),
+			})
+		}
+	}
+	if len(ret) < 2 {
+		return nil
+	}
+	return ret
+}
+
+// unionService combines the active dates of several services.
+func unionService(serviceID string, svcs []*service.Service) *service.Service {
+	active := map[string]time.Time{}
+	for _, svc := range svcs {
+		start, end := svc.ServicePeriod()
+		for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+			if svc.IsActive(d) {
+				active[d.Format("20060102")] = d
+			}
+		}
+	}
+	var dates []time.Time
+	for _, d := range active {
+		dates = append(dates, d)
+	}
+	return datesService(serviceID, dates, true)
+}
+
+// datesService returns a Service for a set of active dates, optionally simplified to a Calendar with exceptions.
+func datesService(serviceID string, dates []time.Time, simplify bool) *service.Service {
+	slices.SortFunc(dates, func(a, b time.Time) int { return a.Compare(b) })
+	var cds []gtfs.CalendarDate
+	for _, d := range dates {
+		cds = append(cds, gtfs.CalendarDate{
+			ServiceID:     tt.NewKey(serviceID),
+			Date:          tt.NewDate(d),
+			ExceptionType: tt.NewInt(1),
+		})
+	}
+	svc := service.NewService(gtfs.Calendar{ServiceID: tt.NewString(serviceID), Generated: tt.NewBool(true)}, cds...)
+	if simplify && len(dates) > 0 {
+		if s, err := svc.Simplify(); err == nil {
+			return s
+		}
+	}
+	return svc
+}
+
+// dayTypeDays returns active days indexed by time.Weekday.
+// A DayType without properties is active on every day of its periods.
+func dayTypeDays(dt DayType) [7]int {
+	var ret [7]int
+	if len(dt.Properties) == 0 {
+		return [7]int{1, 1, 1, 1, 1, 1, 1}
+	}
+	for _, prop := range dt.Properties {
+		for _, day := range strings.Fields(prop.DaysOfWeek) {
+			switch day {
+			case "Everyday":
+				ret = [7]int{1, 1, 1, 1, 1, 1, 1}
+			case "Weekdays":
+				for i := time.Monday; i <= time.Friday; i++ {
+					ret[i] = 1
+				}
+			case "Weekend":
+				ret[time.Saturday] = 1
+				ret[time.Sunday] = 1
+			default:
+				for i := time.Sunday; i <= time.Saturday; i++ {
+					if i.String() == day {
+						ret[i] = 1
+					}
+				}
+			}
+		}
+	}
+	return ret
+}
+
+// routeTypes maps NeTEx transport modes to basic or extended GTFS route types.
+var routeTypes = map[string]int{
+	"tram":       0,
+	"metro":      1,
+	"rail":       2,
+	"bus":        3,
+	"water":      4,
+	"cableway":   6,
+	"funicular":  7,
+	"trolleyBus": 11,
+	"coach":      200,
+	"air":        1100,
+	"lift":       1300,
+	"taxi":       1500,
+}
+
+func routeType(mode string) int {
+	if v, ok := routeTypes[mode]; ok {
+		return v
+	}
+	return 1700
+}
+
+func wheelchairBoarding(aa *AccessibilityAssessment) int {
+	if aa == nil {
+		return 0
+	}
+	access := aa.WheelchairAccess
+	if access == "" {
+		access = aa.MobilityImpairedAccess
+	}
+	switch access {
+	case "true":
+		return 1
+	case "false":
+		return 2
+	}
+	return 0
+}
+
+func quayCentroid(quays []Quay) *Centroid {
+	lon, lat, n := 0.0, 0.0, 0
+	for _, q := range quays {
+		if q.Centroid != nil {
+			lon += q.Centroid.Location.Longitude
+			lat += q.Centroid.Location.Latitude
+			n += 1
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+	return &Centroid{Location: Location{Longitude: lon / float64(n), Latitude: lat / float64(n)}}
+}
+
+// lineStringCoords returns lon,lat coordinates from a GML LineString in lat/lon order.
+func lineStringCoords(ls LineString) [][2]float64 {
+	values := strings.Fields(ls.PosList)
+	for _, pos := range ls.Pos {
+		values = append(values, strings.Fields(pos)...)
+	}
+	if len(values)%2 != 0 {
+		return nil
+	}
+	var ret [][2]float64
+	for i := 0; i < len(values); i += 2 {
+		lat, err1 := strconv.ParseFloat(values[i], 64)
+		lon, err2 := strconv.ParseFloat(values[i+1], 64)
+		if err1 != nil || err2 != nil {
+			return nil
+		}
+		ret = append(ret, [2]float64{lon, lat})
+	}
+	return ret
+}
+
+// parseDate parses an xsd:date or xsd:dateTime.
+func parseDate(v string) (time.Time, error) {
+	if len(v) > 10 {
+		v = v[:10]
+	}
+	return time.Parse("2006-01-02", v)
+}
+
+// passingSeconds returns seconds since midnight for a passing time and day offset.
+func passingSeconds(v string, dayOffset int) tt.Seconds {
+	if v == "" {
+		return tt.Seconds{}
+	}
+	s, err := tt.NewSecondsFromString(v)
+	if err != nil {
+		return tt.Seconds{}
+	}
+	return tt.NewSeconds(s.Int() + dayOffset*86400)
+}
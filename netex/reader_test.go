@@ -0,0 +1,167 @@
+package netex
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/interline-io/transitland-lib/adapters/direct"
+	"github.com/interline-io/transitland-lib/copier"
+	"github.com/interline-io/transitland-lib/ext"
+	"github.com/interline-io/transitland-lib/gtfs"
+	"github.com/interline-io/transitland-lib/internal/testpath"
+	"github.com/interline-io/transitland-lib/internal/testreader"
+	"github.com/interline-io/transitland-lib/tt"
+	"github.com/interline-io/transitland-lib/validator"
+	"github.com/stretchr/testify/assert"
+)
+
+func collect[T any](c chan T) []T {
+	var ret []T
+	for ent := range c {
+		ret = append(ret, ent)
+	}
+	return ret
+}
+
+func TestReader(t *testing.T) {
+	reader, err := ext.OpenReader("netex://" + testpath.RelPath("testdata/netex/example.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	assert.Empty(t, reader.ValidateStructure())
+	t.Run("agencies", func(t *testing.T) {
+		ents := collect(reader.Agencies())
+		if assert.Len(t, ents, 1) {
+			assert.Equal(t, "EX:Authority:1", ents[0].AgencyID.Val)
+			assert.Equal(t, "Example Transit", ents[0].AgencyName.Val)
+			assert.Equal(t, "https://example.com", ents[0].AgencyURL.Val)
+			assert.Equal(t, DefaultTimezone, ents[0].AgencyTimezone.Val)
+		}
+	})
+	t.Run("stops", func(t *testing.T) {
+		stops := map[string]gtfs.Stop{}
+		for _, ent := range collect(reader.Stops()) {
+			stops[ent.StopID.Val] = ent
+		}
+		assert.Len(t, stops, 6)
+		assert.Equal(t, 1, stops["EX:StopPlace:A"].LocationType.Int())
+		assert.Equal(t, "EX:StopPlace:A", stops["EX:Quay:A1"].ParentStation.Val)
+		assert.Equal(t, "Alpha", stops["EX:Quay:A1"].StopName.Val)
+		assert.Equal(t, "1", stops["EX:Quay:A1"].StopCode.Val)
+		assert.Equal(t, 0, stops["EX:StopPlace:B"].LocationType.Int())
+		assert.Equal(t, 1, stops["EX:StopPlace:B"].WheelchairBoarding.Int())
+		assert.Equal(t, "Gamma Quay", stops["EX:Quay:C1"].StopName.Val)
+		// Station location from quays
+		c := stops["EX:StopPlace:C"]
+		assert.InDelta(t, 10.02, c.StopLon.Val, 1e-6)
+		assert.InDelta(t, 59.02, c.StopLat.Val, 1e-6)
+	})
+	t.Run("routes", func(t *testing.T) {
+		ents := collect(reader.Routes())
+		if assert.Len(t, ents, 1) {
+			assert.Equal(t, "EX:Line:1", ents[0].RouteID.Val)
+			assert.Equal(t, "EX:Authority:1", ents[0].AgencyID.Val)
+			assert.Equal(t, "1", ents[0].RouteShortName.Val)
+			assert.Equal(t, "Alpha - Gamma", ents[0].RouteLongName.Val)
+			assert.Equal(t, 3, ents[0].RouteType.Int())
+			assert.Equal(t, "FF0000", ents[0].RouteColor.Val)
+		}
+	})
+	t.Run("calendars", func(t *testing.T) {
+		cals := map[string]gtfs.Calendar{}
+		for _, ent := range collect(reader.Calendars()) {
+			cals[ent.ServiceID.Val] = ent
+		}
+		assert.Len(t, cals, 2)
+		wd := cals["EX:DayType:WD"]
+		assert.Equal(t, []int64{1, 1, 1, 1, 1, 0, 0}, []int64{wd.Monday.Val, wd.Tuesday.Val, wd.Wednesday.Val, wd.Thursday.Val, wd.Friday.Val, wd.Saturday.Val, wd.Sunday.Val})
+		assert.Equal(t, "2024-01-01", wd.StartDate.Format("2006-01-02"))
+		assert.Equal(t, "2024-12-31", wd.EndDate.Format("2006-01-02"))
+		// Union of WD and HOL covers every weekday
+		union := cals["EX:DayType:WD+EX:DayType:HOL"]
+		assert.Equal(t, []int64{1, 1, 1, 1, 1, 0, 0}, []int64{union.Monday.Val, union.Tuesday.Val, union.Wednesday.Val, union.Thursday.Val, union.Friday.Val, union.Saturday.Val, union.Sunday.Val})
+		var cds []string
+		for _, ent := range collect(reader.CalendarDates()) {
+			cds = append(cds, ent.ServiceID.Val+" "+ent.Date.Format("2006-01-02")+" "+ent.ExceptionType.String())
+		}
+		assert.ElementsMatch(t, []string{"EX:DayType:WD 2024-12-25 2", "EX:DayType:HOL 2024-12-25 1"}, cds)
+	})
+	t.Run("trips", func(t *testing.T) {
+		trips := map[string]gtfs.Trip{}
+		for _, ent := range collect(reader.Trips()) {
+			trips[ent.TripID.Val] = ent
+		}
+		assert.Len(t, trips, 2)
+		trip := trips["EX:ServiceJourney:1"]
+		assert.Equal(t, "EX:Line:1", trip.RouteID.Val)
+		assert.Equal(t, "EX:DayType:WD", trip.ServiceID.Val)
+		assert.Equal(t, "EX:JourneyPattern:1", trip.ShapeID.Val)
+		assert.Equal(t, "101", trip.TripShortName.Val)
+		assert.Equal(t, 1, trip.DirectionID.Int())
+		assert.Equal(t, "EX:DayType:WD+EX:DayType:HOL", trips["EX:ServiceJourney:2"].ServiceID.Val)
+	})
+	t.Run("StopTimesByTripID", func(t *testing.T) {
+		groups := collect(reader.StopTimesByTripID("EX:ServiceJourney:1"))
+		if !assert.Len(t, groups, 1) || !assert.Len(t, groups[0], 3) {
+			return
+		}
+		sts := groups[0]
+		assert.Equal(t, "EX:Quay:A1", sts[0].StopID.Val)
+		assert.Equal(t, "EX:StopPlace:B", sts[1].StopID.Val)
+		assert.Equal(t, "EX:Quay:C1", sts[2].StopID.Val)
+		assert.Equal(t, tt.NewSeconds(85800), sts[0].ArrivalTime)
+		assert.Equal(t, tt.NewSeconds(85800), sts[0].DepartureTime)
+		assert.Equal(t, tt.NewSeconds(86280), sts[1].ArrivalTime)
+		assert.Equal(t, tt.NewSeconds(86520), sts[1].DepartureTime)
+		assert.Equal(t, tt.NewSeconds(87000), sts[2].ArrivalTime)
+		assert.Equal(t, 1, sts[1].PickupType.Int())
+		assert.Len(t, collect(reader.StopTimesByTripID()), 2)
+	})
+	t.Run("ShapesByShapeID", func(t *testing.T) {
+		groups := collect(reader.ShapesByShapeID("EX:JourneyPattern:1"))
+		if assert.Len(t, groups, 1) && assert.Len(t, groups[0], 4) {
+			assert.InDelta(t, 10.0001, groups[0][0].ShapePtLon.Val, 1e-6)
+			assert.InDelta(t, 59.0001, groups[0][0].ShapePtLat.Val, 1e-6)
+		}
+		assert.Empty(t, collect(reader.ShapesByShapeID("missing")))
+	})
+	t.Run("validate", func(t *testing.T) {
+		v, err := validator.NewValidator(reader, validator.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := v.Validate(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Empty(t, result.FailureReason.Val)
+		assert.Empty(t, result.Errors)
+	})
+}
+
+func TestReader_RoundTrip(t *testing.T) {
+	fe := testreader.ExampleFeedBART
+	outfn := filepath.Join(t.TempDir(), "bart.zip")
+	writeNetex(t, fe.URL, outfn)
+	reader, err := ext.OpenReader("netex://" + outfn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	writer := direct.NewWriter()
+	if _, err := copier.QuietCopy(context.Background(), reader, writer); err != nil {
+		t.Fatal(err)
+	}
+	r := writer.Reader
+	assert.Len(t, r.AgencyList, fe.Counts["agency.txt"])
+	assert.Len(t, r.RouteList, fe.Counts["routes.txt"])
+	// Each stop becomes a station and a platform
+	assert.Len(t, r.StopList, fe.Counts["stops.txt"]*2)
+	assert.Len(t, r.TripList, fe.Counts["trips.txt"])
+	assert.Len(t, r.StopTimeList, fe.Counts["stop_times.txt"])
+	assert.Len(t, r.CalendarList, fe.Counts["calendar.txt"])
+	assert.Len(t, r.CalendarDateList, fe.Counts["calendar_dates.txt"])
+	assert.NotEmpty(t, r.ShapeList)
+}
@@ -0,0 +1,572 @@
+package netex
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/interline-io/transitland-lib/adapters"
+	"github.com/interline-io/transitland-lib/ext"
+	"github.com/interline-io/transitland-lib/gtfs"
+	"github.com/interline-io/transitland-lib/service"
+	"github.com/interline-io/transitland-lib/tlxy"
+	"github.com/interline-io/transitland-lib/tt"
+)
+
+func init() {
+	// Register writers
+	w := func(url string) (adapters.Writer, error) { return NewWriter(url) }
+	ext.RegisterWriter("netex", w)
+}
+
+// DefaultCodespace is used to prefix NeTEx object IDs.
+var DefaultCodespace = "TL"
+
+// Writer writes the copier stream as a single NeTEx EPIP PublicationDelivery document.
+// Entities are buffered in memory and the document is written on Close.
+// Paths ending in .zip are written as a zip archive containing one XML file.
+type Writer struct {
+	Codespace     string
+	path          string
+	agencies      []gtfs.Agency
+	stops         []gtfs.Stop
+	routes        []gtfs.Route
+	trips         []gtfs.Trip
+	calendars     []gtfs.Calendar
+	calendarDates []gtfs.CalendarDate
+	stopTimes     map[string][]gtfs.StopTime
+	shapes        map[string][]tlxy.Point
+}
+
+// NewWriter returns a new Writer for the given path, with or without the netex:// prefix.
+func NewWriter(path string) (*Writer, error) {
+	path = strings.TrimPrefix(path, "netex://")
+	if path == "" {
+		return nil, errors.New("no output path")
+	}
+	return &Writer{
+		Codespace: DefaultCodespace,
+		path:      path,
+		stopTimes: map[string][]gtfs.StopTime{},
+		shapes:    map[string][]tlxy.Point{},
+	}, nil
+}
+
+func (writer *Writer) String() string {
+	return "netex://" + writer.path
+}
+
+// Open the Writer.
+func (writer *Writer) Open() error {
+	return nil
+}
+
+// Create the Writer.
+func (writer *Writer) Create() error {
+	return nil
+}
+
+// Delete the Writer.
+func (writer *Writer) Delete() error {
+	return nil
+}
+
+// NewReader returns a new Reader for the Writer destination.
+func (writer *Writer) NewReader() (adapters.Reader, error) {
+	return NewReader(writer.path)
+}
+
+// Close writes the buffered entities as NeTEx.
+func (writer *Writer) Close() error {
+	doc := writer.build(time.Now().UTC())
+	if strings.HasSuffix(writer.path, ".zip") {
+		f, err := os.Create(writer.path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		zw := zip.NewWriter(f)
+		zf, err := zw.Create("netex.xml")
+		if err != nil {
+			return err
+		}
+		if err := writeDocument(zf, doc); err != nil {
+			return err
+		}
+		return zw.Close()
+	}
+	f, err := os.Create(writer.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return writeDocument(f, doc)
+}
+
+// AddEntity buffers an entity.
+func (writer *Writer) AddEntity(ent tt.Entity) (string, error) {
+	eids, err := writer.AddEntities([]tt.Entity{ent})
+	if err != nil {
+		return "", err
+	}
+	if len(eids) == 0 {
+		return "", errors.New("did not write expected number of entities")
+	}
+	return eids[0], nil
+}
+
+// AddEntities buffers entities. Entities without a NeTEx mapping are accepted and ignored.
+func (writer *Writer) AddEntities(ents []tt.Entity) ([]string, error) {
+	eids := make([]string, 0, len(ents))
+	for _, ent := range ents {
+		switch v := ent.(type) {
+		case *gtfs.Agency:
+			writer.agencies = append(writer.agencies, *v)
+		case *gtfs.Stop:
+			writer.stops = append(writer.stops, *v)
+		case *gtfs.Route:
+			writer.routes = append(writer.routes, *v)
+		case *gtfs.Trip:
+			writer.trips = append(writer.trips, *v)
+		case *gtfs.Calendar:
+			writer.calendars = append(writer.calendars, *v)
+		case *gtfs.CalendarDate:
+			writer.calendarDates = append(writer.calendarDates, *v)
+		case *gtfs.StopTime:
+			writer.stopTimes[v.TripID.Val] = append(writer.stopTimes[v.TripID.Val], *v)
+		case *service.ShapeLine:
+			writer.shapes[v.ShapeID.Val] = v.Geometry.ToPoints()
+		}
+		eids = append(eids, ent.EntityID())
+	}
+	return eids, nil
+}
+
+func writeDocument(w io.Writer, doc *PublicationDelivery) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// id returns a NeTEx object ID in the form codespace:type:id.
+func (writer *Writer) id(typ string, eid string) string {
+	return writer.Codespace + ":" + typ + ":" + eid
+}
+
+// build assembles the NeTEx document from the buffered entities.
+func (writer *Writer) build(now time.Time) *PublicationDelivery {
+	frame := CompositeFrame{
+		ID:      writer.id("CompositeFrame", "1"),
+		Version: objectVersion,
+	}
+	if len(writer.agencies) > 0 {
+		a := writer.agencies[0]
+		frame.FrameDefaults = &FrameDefaults{DefaultLocale: &Locale{
+			TimeZone:        a.AgencyTimezone.Val,
+			DefaultLanguage: a.AgencyLang.Val,
+		}}
+	}
+	calFrame, validBetween := writer.buildCalendars()
+	frame.ValidBetween = validBetween
+	frame.Frames = Frames{
+		ResourceFrames:        []ResourceFrame{writer.buildResources()},
+		SiteFrames:            []SiteFrame{writer.buildSites()},
+		ServiceCalendarFrames: []ServiceCalendarFrame{calFrame},
+	}
+	serviceFrame, timetableFrame := writer.buildServices()
+	frame.Frames.ServiceFrames = []ServiceFrame{serviceFrame}
+	frame.Frames.TimetableFrames = []TimetableFrame{timetableFrame}
+	return &PublicationDelivery{
+		Xmlns:                netexNamespace,
+		XmlnsGml:             gmlNamespace,
+		Version:              netexVersion,
+		PublicationTimestamp: now.Format("2006-01-02T15:04:05"),
+		ParticipantRef:       writer.Codespace,
+		DataObjects:          DataObjects{CompositeFrames: []CompositeFrame{frame}},
+	}
+}
+
+func (writer *Writer) buildResources() ResourceFrame {
+	frame := ResourceFrame{ID: writer.id("ResourceFrame", "1"), Version: objectVersion}
+	for _, ent := range writer.agencies {
+		op := Operator{
+			ID:               writer.id("Operator", ent.AgencyID.Val),
+			Version:          objectVersion,
+			Name:             ent.AgencyName.Val,
+			OrganisationType: "operator",
+		}
+		if ent.AgencyURL.Val != "" || ent.AgencyPhone.Val != "" || ent.AgencyEmail.Val != "" {
+			op.ContactDetails = &ContactDetails{
+				Email: ent.AgencyEmail.Val,
+				Phone: ent.AgencyPhone.Val,
+				Url:   ent.AgencyURL.Val,
+			}
+		}
+		frame.Operators = append(frame.Operators, op)
+	}
+	return frame
+}
+
+// buildSites maps stations to StopPlaces and their platforms to Quays.
+// Platforms without a parent station become a StopPlace with a single Quay.
+// Entrances, generic nodes and boarding areas are not included.
+func (writer *Writer) buildSites() SiteFrame {
+	frame := SiteFrame{ID: writer.id("SiteFrame", "1"), Version: objectVersion}
+	stationIdx := map[string]int{}
+	for _, ent := range writer.stops {
+		if ent.LocationType.Val == 1 {
+			stationIdx[ent.StopID.Val] = len(frame.StopPlaces)
+			frame.StopPlaces = append(frame.StopPlaces, StopPlace{
+				ID:                      writer.id("StopPlace", ent.StopID.Val),
+				Version:                 objectVersion,
+				Name:                    ent.StopName.Val,
+				Description:             ent.StopDesc.Val,
+				Centroid:                stopCentroid(ent),
+				Url:                     ent.StopURL.Val,
+				PublicCode:              ent.StopCode.Val,
+				AccessibilityAssessment: writer.accessibility(ent),
+			})
+		}
+	}
+	for _, ent := range writer.stops {
+		if ent.LocationType.Val != 0 {
+			continue
+		}
+		quay := Quay{
+			ID:                      writer.id("Quay", ent.StopID.Val),
+			Version:                 objectVersion,
+			Name:                    ent.StopName.Val,
+			Description:             ent.StopDesc.Val,
+			Centroid:                stopCentroid(ent),
+			PublicCode:              ent.StopCode.Val,
+			AccessibilityAssessment: writer.accessibility(ent),
+		}
+		if idx, ok := stationIdx[ent.ParentStation.Val]; ok {
+			frame.StopPlaces[idx].Quays = append(frame.StopPlaces[idx].Quays, quay)
+			continue
+		}
+		frame.StopPlaces = append(frame.StopPlaces, StopPlace{
+			ID:                      writer.id("StopPlace", ent.StopID.Val),
+			Version:                 objectVersion,
+			Name:                    ent.StopName.Val,
+			Description:             ent.StopDesc.Val,
+			Centroid:                stopCentroid(ent),
+			Url:                     ent.StopURL.Val,
+			PublicCode:              ent.StopCode.Val,
+			AccessibilityAssessment: writer.accessibility(ent),
+			Quays:                   []Quay{quay},
+		})
+	}
+	return frame
+}
+
+func (writer *Writer) accessibility(ent gtfs.Stop) *AccessibilityAssessment {
+	access := ""
+	switch ent.WheelchairBoarding.Val {
+	case 1:
+		access = "true"
+	case 2:
+		access = "false"
+	default:
+		return nil
+	}
+	return &AccessibilityAssessment{
+		ID:                     writer.id("AccessibilityAssessment", ent.StopID.Val),
+		Version:                objectVersion,
+		MobilityImpairedAccess: access,
+		WheelchairAccess:       access,
+	}
+}
+
+func stopCentroid(ent gtfs.Stop) *Centroid {
+	c := ent.Coordinates()
+	return &Centroid{Location: Location{Longitude: c[0], Latitude: c[1]}}
+}
+
+// buildCalendars maps calendars to DayTypes with an OperatingPeriod, and calendar_dates to dated DayTypeAssignments.
+// The returned ValidBetween covers all service dates.
+func (writer *Writer) buildCalendars() (ServiceCalendarFrame, *ValidBetween) {
+	frame := ServiceCalendarFrame{ID: writer.id("ServiceCalendarFrame", "1"), Version: objectVersion}
+	var startDate, endDate time.Time
+	expand := func(d time.Time) {
+		if startDate.IsZero() || d.Before(startDate) {
+			startDate = d
+		}
+		if endDate.IsZero() || d.After(endDate) {
+			endDate = d
+		}
+	}
+	order := map[string]int{}
+	nextOrder := func(serviceID string) int {
+		order[serviceID] += 1
+		return order[serviceID]
+	}
+	for _, ent := range writer.calendars {
+		sid := ent.ServiceID.Val
+		dt := DayType{ID: writer.id("DayType", sid), Version: objectVersion}
+		days := daysOfWeek([7]int{
+			ent.Monday.Int(),
+			ent.Tuesday.Int(),
+			ent.Wednesday.Int(),
+			ent.Thursday.Int(),
+			ent.Friday.Int(),
+			ent.Saturday.Int(),
+			ent.Sunday.Int(),
+		})
+		if days != "" {
+			dt.Properties = append(dt.Properties, PropertyOfDay{DaysOfWeek: days})
+		}
+		frame.DayTypes = append(frame.DayTypes, dt)
+		if days == "" || ent.Generated.Val || !ent.StartDate.Valid || !ent.EndDate.Valid {
+			continue
+		}
+		expand(ent.StartDate.Val)
+		expand(ent.EndDate.Val)
+		op := OperatingPeriod{
+			ID:       writer.id("OperatingPeriod", sid),
+			Version:  objectVersion,
+			FromDate: ent.StartDate.Val.Format("2006-01-02") + "T00:00:00",
+			ToDate:   ent.EndDate.Val.Format("2006-01-02") + "T00:00:00",
+		}
+		frame.OperatingPeriods = append(frame.OperatingPeriods, op)
+		n := nextOrder(sid)
+		frame.DayTypeAssignments = append(frame.DayTypeAssignments, DayTypeAssignment{
+			ID:                 writer.id("DayTypeAssignment", fmt.Sprintf("%s_%d", sid, n)),
+			Version:            objectVersion,
+			Order:              n,
+			OperatingPeriodRef: newRef(op.ID),
+			DayTypeRef:         *newRef(dt.ID),
+		})
+	}
+	for _, ent := range writer.calendarDates {
+		sid := ent.ServiceID.Val
+		if ent.Date.Valid && ent.ExceptionType.Val == 1 {
+			expand(ent.Date.Val)
+		}
+		n := nextOrder(sid)
+		available := ent.ExceptionType.Val == 1
+		frame.DayTypeAssignments = append(frame.DayTypeAssignments, DayTypeAssignment{
+			ID:          writer.id("DayTypeAssignment", fmt.Sprintf("%s_%d", sid, n)),
+			Version:     objectVersion,
+			Order:       n,
+			Date:        ent.Date.Val.Format("2006-01-02"),
+			DayTypeRef:  *newRef(writer.id("DayType", sid)),
+			IsAvailable: &available,
+		})
+	}
+	if startDate.IsZero() {
+		return frame, nil
+	}
+	return frame, &ValidBetween{
+		FromDate: startDate.Format("2006-01-02") + "T00:00:00",
+		ToDate:   endDate.Format("2006-01-02") + "T23:59:59",
+	}
+}
+
+// buildServices maps routes to Lines and trips to ServiceJourneys.
+// Trips with the same route, direction, shape and stop pattern share a ServiceJourneyPattern.
+// When a shape is available, the pattern is linked by ServiceLinks cut from the shape between each pair of stops.
+func (writer *Writer) buildServices() (ServiceFrame, TimetableFrame) {
+	frame := ServiceFrame{ID: writer.id("ServiceFrame", "1"), Version: objectVersion}
+	ttFrame := TimetableFrame{ID: writer.id("TimetableFrame", "1"), Version: objectVersion}
+
+	// Lines
+	routeAgencies := map[string]string{}
+	for _, ent := range writer.routes {
+		line := Line{
+			ID:            writer.id("Line", ent.RouteID.Val),
+			Version:       objectVersion,
+			Name:          ent.RouteLongName.Val,
+			Description:   ent.RouteDesc.Val,
+			TransportMode: transportMode(ent.RouteType.Int()),
+			Url:           ent.RouteURL.Val,
+			PublicCode:    ent.RouteShortName.Val,
+		}
+		if line.Name == "" {
+			line.Name = ent.RouteShortName.Val
+		}
+		agencyID := ent.AgencyID.Val
+		if agencyID == "" && len(writer.agencies) == 1 {
+			agencyID = writer.agencies[0].AgencyID.Val
+		}
+		if agencyID != "" {
+			routeAgencies[ent.RouteID.Val] = agencyID
+			line.OperatorRef = newRef(writer.id("Operator", agencyID))
+		}
+		if ent.RouteColor.Val != "" || ent.RouteTextColor.Val != "" {
+			line.Presentation = &Presentation{
+				Colour:     strings.ToUpper(ent.RouteColor.Val),
+				TextColour: strings.ToUpper(ent.RouteTextColor.Val),
+			}
+		}
+		frame.Lines = append(frame.Lines, line)
+	}
+
+	// Scheduled stop points and their assignments
+	stops := map[string]gtfs.Stop{}
+	for _, ent := range writer.stops {
+		stops[ent.StopID.Val] = ent
+		if ent.LocationType.Val != 0 {
+			continue
+		}
+		ssp := ScheduledStopPoint{
+			ID:      writer.id("ScheduledStopPoint", ent.StopID.Val),
+			Version: objectVersion,
+			Name:    ent.StopName.Val,
+		}
+		frame.ScheduledStopPoints = append(frame.ScheduledStopPoints, ssp)
+		stopPlaceID := ent.StopID.Val
+		if ent.ParentStation.Val != "" {
+			stopPlaceID = ent.ParentStation.Val
+		}
+		frame.StopAssignments = append(frame.StopAssignments, PassengerStopAssignment{
+			ID:                    writer.id("PassengerStopAssignment", ent.StopID.Val),
+			Version:               objectVersion,
+			Order:                 len(frame.StopAssignments) + 1,
+			ScheduledStopPointRef: *newRef(ssp.ID),
+			StopPlaceRef:          newRef(writer.id("StopPlace", stopPlaceID)),
+			QuayRef:               newRef(writer.id("Quay", ent.StopID.Val)),
+		})
+	}
+
+	// Journey patterns and service journeys
+	patterns := map[string]string{}
+	routePatterns := map[string]int{}
+	links := map[string]string{}
+	for _, trip := range writer.trips {
+		var sts []gtfs.StopTime
+		for _, st := range writer.stopTimes[trip.TripID.Val] {
+			if _, ok := stops[st.StopID.Val]; ok {
+				sts = append(sts, st)
+			}
+		}
+		if len(sts) == 0 {
+			continue
+		}
+		slices.SortStableFunc(sts, func(a, b gtfs.StopTime) int {
+			return a.StopSequence.Int() - b.StopSequence.Int()
+		})
+
+		// Find or create journey pattern
+		patternKey := []string{trip.RouteID.Val, strconv.Itoa(trip.DirectionID.Int()), trip.ShapeID.Val}
+		for _, st := range sts {
+			patternKey = append(patternKey, fmt.Sprintf("%s:%d:%d", st.StopID.Val, st.PickupType.Int(), st.DropOffType.Int()))
+		}
+		pk := strings.Join(patternKey, "|")
+		patternID, ok := patterns[pk]
+		if !ok {
+			routePatterns[trip.RouteID.Val] += 1
+			patternID = writer.id("ServiceJourneyPattern", fmt.Sprintf("%s_%d", trip.RouteID.Val, routePatterns[trip.RouteID.Val]))
+			patterns[pk] = patternID
+			jp := ServiceJourneyPattern{ID: patternID, Version: objectVersion}
+			if trip.DirectionID.Valid {
+				jp.DirectionType = "outbound"
+				if trip.DirectionID.Val == 1 {
+					jp.DirectionType = "inbound"
+				}
+			}
+			for i, st := range sts {
+				spjp := StopPointInJourneyPattern{
+					ID:                    fmt.Sprintf("%s_%d", patternID, i+1),
+					Version:               objectVersion,
+					Order:                 i + 1,
+					ScheduledStopPointRef: *newRef(writer.id("ScheduledStopPoint", st.StopID.Val)),
+				}
+				if st.DropOffType.Val == 1 {
+					spjp.ForAlighting = new(bool)
+				}
+				if st.PickupType.Val == 1 {
+					spjp.ForBoarding = new(bool)
+				}
+				jp.PointsInSequence = append(jp.PointsInSequence, spjp)
+			}
+			if shape := writer.shapes[trip.ShapeID.Val]; len(shape) > 1 {
+				for i := 1; i < len(sts); i++ {
+					from := stops[sts[i-1].StopID.Val]
+					to := stops[sts[i].StopID.Val]
+					linkKey := strings.Join([]string{trip.ShapeID.Val, from.StopID.Val, to.StopID.Val}, "|")
+					linkID, ok := links[linkKey]
+					if !ok {
+						linkID = writer.id("ServiceLink", fmt.Sprintf("%s_%s_%s", trip.ShapeID.Val, from.StopID.Val, to.StopID.Val))
+						links[linkKey] = linkID
+						frame.ServiceLinks = append(frame.ServiceLinks, writer.serviceLink(linkID, shape, from, to))
+					}
+					jp.LinksInSequence = append(jp.LinksInSequence, ServiceLinkInJourneyPattern{
+						ID:             fmt.Sprintf("%s_link_%d", patternID, i),
+						Version:        objectVersion,
+						Order:          i,
+						ServiceLinkRef: *newRef(linkID),
+					})
+				}
+			}
+			frame.JourneyPatterns = append(frame.JourneyPatterns, jp)
+		}
+
+		// Service journey
+		sj := ServiceJourney{
+			ID:                       writer.id("ServiceJourney", trip.TripID.Val),
+			Version:                  objectVersion,
+			Name:                     trip.TripHeadsign.Val,
+			PrivateCode:              trip.TripShortName.Val,
+			DayTypes:                 []Ref{*newRef(writer.id("DayType", trip.ServiceID.Val))},
+			ServiceJourneyPatternRef: newRef(patternID),
+			LineRef:                  newRef(writer.id("Line", trip.RouteID.Val)),
+		}
+		if agencyID, ok := routeAgencies[trip.RouteID.Val]; ok {
+			sj.OperatorRef = newRef(writer.id("Operator", agencyID))
+		}
+		for i, st := range sts {
+			pt := TimetabledPassingTime{
+				Version:                      objectVersion,
+				StopPointInJourneyPatternRef: *newRef(fmt.Sprintf("%s_%d", patternID, i+1)),
+			}
+			if st.ArrivalTime.Valid {
+				pt.ArrivalTime, pt.ArrivalDayOffset = passingTime(st.ArrivalTime)
+			}
+			if st.DepartureTime.Valid {
+				pt.DepartureTime, pt.DepartureDayOffset = passingTime(st.DepartureTime)
+			}
+			sj.PassingTimes = append(sj.PassingTimes, pt)
+		}
+		ttFrame.ServiceJourneys = append(ttFrame.ServiceJourneys, sj)
+	}
+	return frame, ttFrame
+}
+
+// serviceLink cuts the shape between two stops.
+// Positions are written in GML lat/lon order.
+func (writer *Writer) serviceLink(linkID string, shape []tlxy.Point, from gtfs.Stop, to gtfs.Stop) ServiceLink {
+	line := tlxy.CutBetweenPoints(shape, from.ToPoint(), to.ToPoint())
+	var pos []string
+	for _, p := range line {
+		pos = append(pos, strconv.FormatFloat(p.Lat, 'f', -1, 64), strconv.FormatFloat(p.Lon, 'f', -1, 64))
+	}
+	return ServiceLink{
+		ID:           linkID,
+		Version:      objectVersion,
+		Distance:     tlxy.LengthHaversine(line),
+		LineString:   &LineString{ID: strings.ReplaceAll(linkID, ":", "_"), PosList: strings.Join(pos, " ")},
+		FromPointRef: *newRef(writer.id("ScheduledStopPoint", from.StopID.Val)),
+		ToPointRef:   *newRef(writer.id("ScheduledStopPoint", to.StopID.Val)),
+	}
+}
+
+// passingTime returns a time of day and day offset for times past midnight.
+func passingTime(s tt.Seconds) (string, int) {
+	v := s.Int()
+	return tt.NewSeconds(v % 86400).String(), v / 86400
+}
@@ -0,0 +1,166 @@
+package netex
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/interline-io/transitland-lib/copier"
+	"github.com/interline-io/transitland-lib/ext"
+	"github.com/interline-io/transitland-lib/internal/testpath"
+	"github.com/interline-io/transitland-lib/internal/testreader"
+	"github.com/interline-io/transitland-lib/tlcsv"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeNetex(t *testing.T, src string, outfn string) {
+	t.Helper()
+	reader, err := tlcsv.NewReader(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reader.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	writer, err := ext.NewWriter("netex://" + outfn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := copier.QuietCopy(context.Background(), reader, writer); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readZipDocument(t *testing.T, fn string) []byte {
+	t.Helper()
+	zr, err := zip.OpenReader(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	if len(zr.File) != 1 {
+		t.Fatalf("expected 1 file, got %d", len(zr.File))
+	}
+	f, err := zr.File[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestWriter(t *testing.T) {
+	t.Run("bart", func(t *testing.T) {
+		fe := testreader.ExampleFeedBART
+		outfn := filepath.Join(t.TempDir(), "bart.zip")
+		writeNetex(t, fe.URL, outfn)
+		data := readZipDocument(t, outfn)
+		doc := PublicationDelivery{}
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			t.Fatal(err)
+		}
+		if !assert.Len(t, doc.DataObjects.CompositeFrames, 1) {
+			return
+		}
+		frames := doc.DataObjects.CompositeFrames[0].Frames
+		assert.Equal(t, "America/Los_Angeles", doc.DataObjects.CompositeFrames[0].FrameDefaults.DefaultLocale.TimeZone)
+		assert.Len(t, frames.ResourceFrames[0].Operators, fe.Counts["agency.txt"])
+		// All BART stops are platforms without a parent station
+		assert.Len(t, frames.SiteFrames[0].StopPlaces, fe.Counts["stops.txt"])
+		for _, sp := range frames.SiteFrames[0].StopPlaces {
+			assert.Len(t, sp.Quays, 1)
+		}
+		assert.Len(t, frames.ServiceCalendarFrames[0].DayTypes, fe.Counts["calendar.txt"])
+		assert.Len(t, frames.ServiceCalendarFrames[0].OperatingPeriods, fe.Counts["calendar.txt"])
+		assert.Len(t, frames.ServiceCalendarFrames[0].DayTypeAssignments, fe.Counts["calendar.txt"]+fe.Counts["calendar_dates.txt"])
+		assert.Len(t, frames.ServiceFrames[0].Lines, fe.Counts["routes.txt"])
+		assert.NotEmpty(t, frames.ServiceFrames[0].JourneyPatterns)
+		assert.NotEmpty(t, frames.ServiceFrames[0].ServiceLinks)
+		assert.Len(t, frames.TimetableFrames[0].ServiceJourneys, fe.Counts["trips.txt"])
+		passingTimes := 0
+		for _, sj := range frames.TimetableFrames[0].ServiceJourneys {
+			passingTimes += len(sj.PassingTimes)
+		}
+		assert.Equal(t, fe.Counts["stop_times.txt"], passingTimes)
+
+		// Check references
+		patterns := map[string]ServiceJourneyPattern{}
+		for _, jp := range frames.ServiceFrames[0].JourneyPatterns {
+			patterns[jp.ID] = jp
+			if len(jp.LinksInSequence) > 0 {
+				assert.Equal(t, len(jp.PointsInSequence)-1, len(jp.LinksInSequence))
+			}
+		}
+		for _, sj := range frames.TimetableFrames[0].ServiceJourneys {
+			jp, ok := patterns[sj.ServiceJourneyPatternRef.Ref]
+			if assert.True(t, ok) {
+				assert.Equal(t, len(jp.PointsInSequence), len(sj.PassingTimes))
+			}
+		}
+		for _, link := range frames.ServiceFrames[0].ServiceLinks {
+			if assert.NotNil(t, link.LineString) {
+				assert.NotEmpty(t, link.LineString.PosList)
+			}
+		}
+		assert.Contains(t, string(data), "<gml:LineString gml:id=")
+		assert.NotContains(t, string(data), "<projections>")
+	})
+	t.Run("xml", func(t *testing.T) {
+		outfn := filepath.Join(t.TempDir(), "example.xml")
+		writeNetex(t, testpath.RelPath("testdata/gtfs-examples/example"), outfn)
+		data, err := os.ReadFile(outfn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc := PublicationDelivery{}
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			t.Fatal(err)
+		}
+		frames := doc.DataObjects.CompositeFrames[0].Frames
+		var removed []string
+		for _, dta := range frames.ServiceCalendarFrames[0].DayTypeAssignments {
+			if dta.IsAvailable != nil && !*dta.IsAvailable {
+				removed = append(removed, dta.DayTypeRef.Ref+" "+dta.Date)
+			}
+		}
+		assert.Equal(t, []string{"TL:DayType:FULLW 2007-06-04"}, removed)
+		for _, sj := range frames.TimetableFrames[0].ServiceJourneys {
+			if sj.ID == "TL:ServiceJourney:AB1" {
+				assert.Equal(t, "to Bullfrog", sj.Name)
+				assert.Equal(t, "TL:Line:AB", sj.LineRef.Ref)
+				assert.Equal(t, "08:00:00", sj.PassingTimes[0].DepartureTime)
+			}
+		}
+		assert.True(t, strings.HasPrefix(string(data), xml.Header))
+	})
+}
+
+func TestTransportMode(t *testing.T) {
+	tcs := map[int]string{
+		0:    "tram",
+		1:    "metro",
+		2:    "rail",
+		3:    "bus",
+		109:  "rail",
+		200:  "coach",
+		715:  "bus",
+		1100: "air",
+		9999: "other",
+	}
+	for routeType, expect := range tcs {
+		assert.Equal(t, expect, transportMode(routeType))
+	}
+}
@@ -92,6 +92,11 @@ type Http struct {
 	// IPs are allowed). Off by default — only set in CLI contexts where the
 	// operator legitimately fetches from internal addresses.
 	AllowHTTPUnfiltered bool
+	// IfNoneMatch and IfModifiedSince are sent as conditional request headers
+	// when set, typically the ETag and Last-Modified of a previous response.
+	// A 304 Not Modified response is returned as a success with an empty body.
+	IfNoneMatch     string
+	IfModifiedSince string
 }
 
 func (r *Http) SetSecret(secret dmfr.Secret) error {
@@ -237,6 +242,12 @@ func (r Http) DownloadAuth(ctx context.Context, ustr string, auth dmfr.FeedAutho
 	// If the following headers are not set, some CDNs may block the request as coming from a bot rather than a browser
 	req.Header.Set("Accept", "application/zip,application/x-zip-compressed,application/octet-stream;q=0.9,*/*;q=0.8")
 	req.Header.Set("Accept-Language", "")
+	if r.IfNoneMatch != "" {
+		req.Header.Set("If-None-Match", r.IfNoneMatch)
+	}
+	if r.IfModifiedSince != "" {
+		req.Header.Set("If-Modified-Since", r.IfModifiedSince)
+	}
 
 	// Remove default ports from host header if explicitly specified as it
 	// may break pre-signed S3 URLs or other systems that rely on the host header
@@ -276,6 +287,8 @@ func (r Http) DownloadAuth(ctx context.Context, ustr string, auth dmfr.FeedAutho
 			return &httpResponseReader{
 				ReadCloser:    resp.Body,
 				ContentLength: resp.ContentLength,
+				ETag:          resp.Header.Get("ETag"),
+				LastModified:  resp.Header.Get("Last-Modified"),
 			}, resp.StatusCode, nil
 		}
 
@@ -316,7 +329,10 @@ func (r Http) DownloadAuth(ctx context.Context, ustr string, auth dmfr.FeedAutho
 }
 
 // httpResponseReader wraps http.Response.Body to preserve Content-Length
+// and the cache validators for later conditional requests
 type httpResponseReader struct {
 	io.ReadCloser
 	ContentLength int64
+	ETag          string
+	LastModified  string
 }
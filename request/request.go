@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -67,6 +68,9 @@ type FetchResponse struct {
 	ResponseTimeMs int
 	ResponseTtfbMs int
 	ResponseSHA1   string
+	ETag           string
+	LastModified   string
+	NotModified    bool // 304 response to a conditional request; nothing was written
 	FetchError     error
 }
 
@@ -79,6 +83,8 @@ type Request struct {
 	MaxSize             uint64
 	Secret              dmfr.Secret
 	Auth                dmfr.FeedAuthorization
+	IfNoneMatch         string
+	IfModifiedSince     string
 }
 
 func (req *Request) Request(ctx context.Context) (io.ReadCloser, int, error) {
@@ -103,10 +109,8 @@ func (req *Request) newDownloader(ustr string) (Downloader, string, error) {
 	var reqErr error
 	reqUrl := req.URL
 	switch u.Scheme {
-	case "http":
-		downloader = &Http{AllowHTTPUnfiltered: req.AllowHTTPUnfiltered}
-	case "https":
-		downloader = &Http{AllowHTTPUnfiltered: req.AllowHTTPUnfiltered}
+	case "http", "https":
+		downloader = &Http{AllowHTTPUnfiltered: req.AllowHTTPUnfiltered, IfNoneMatch: req.IfNoneMatch, IfModifiedSince: req.IfModifiedSince}
 	case "ftp":
 		if req.AllowFTP {
 			downloader = &Ftp{}
@@ -168,6 +172,15 @@ func WithMaxSize(s uint64) RequestOption {
 	}
 }
 
+// WithConditional sends If-None-Match and If-Modified-Since headers on http(s)
+// requests, using the ETag and Last-Modified values of a previous response.
+func WithConditional(etag string, lastModified string) RequestOption {
+	return func(req *Request) {
+		req.IfNoneMatch = etag
+		req.IfModifiedSince = lastModified
+	}
+}
+
 func WithAuth(secret dmfr.Secret, auth dmfr.FeedAuthorization) func(req *Request) {
 	return func(req *Request) {
 		req.Secret = secret
@@ -218,6 +231,16 @@ func AuthenticatedRequest(ctx context.Context, out io.Writer, address string, op
 		if httpRespReader.ContentLength > 0 {
 			expectedSize = httpRespReader.ContentLength
 		}
+		fr.ETag = httpRespReader.ETag
+		fr.LastModified = httpRespReader.LastModified
+	}
+
+	// Conditional request matched; there is no body to copy
+	if fr.ResponseCode == http.StatusNotModified {
+		fr.NotModified = true
+		fr.ResponseTtfbMs = int(time.Since(t) / time.Millisecond)
+		fr.ResponseTimeMs = fr.ResponseTtfbMs
+		return fr, nil
 	}
 
 	// Write response
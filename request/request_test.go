@@ -280,3 +280,45 @@ func TestAuthorizedRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthenticatedRequest_Conditional(t *testing.T) {
+	const etag = `"v1"`
+	const lastModified = "Mon, 02 Mar 2026 12:00:00 GMT"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		if r.Header.Get("If-None-Match") == etag || r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("hello world"))
+	}))
+	defer ts.Close()
+	tcs := []struct {
+		name        string
+		opts        []RequestOption
+		notModified bool
+		size        int
+	}{
+		{"unconditional", nil, false, 11},
+		{"if-none-match", []RequestOption{WithConditional(etag, "")}, true, 0},
+		{"if-modified-since", []RequestOption{WithConditional("", lastModified)}, true, 0},
+		{"changed", []RequestOption{WithConditional(`"v0"`, "")}, false, 11},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := append([]RequestOption{WithAllowHTTPUnfiltered}, tc.opts...)
+			fr, err := AuthenticatedRequest(context.Background(), &buf, ts.URL, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Nil(t, fr.FetchError)
+			assert.Equal(t, tc.notModified, fr.NotModified)
+			assert.Equal(t, tc.size, fr.ResponseSize)
+			assert.Equal(t, tc.size, buf.Len())
+			assert.Equal(t, etag, fr.ETag)
+			assert.Equal(t, lastModified, fr.LastModified)
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package rt
+
+import (
+	"slices"
+
+	"github.com/interline-io/transitland-lib/rt/pb"
+	"github.com/interline-io/transitland-lib/tlxy"
+	"google.golang.org/protobuf/proto"
+)
+
+// FeedMessageFilter selects entities from a FeedMessage.
+// Empty criteria match everything; when several criteria are set, an entity must match all of them.
+type FeedMessageFilter struct {
+	AgencyIDs []string
+	RouteIDs  []string
+	TripIDs   []string
+	StopIDs   []string
+	Bbox      *tlxy.BoundingBox
+	// TripRoutes maps trip_id to route_id, used when a trip descriptor does not include a route_id.
+	TripRoutes map[string]string
+	// RouteAgencies maps route_id to agency_id, used for agency filtering.
+	RouteAgencies map[string]string
+	// StopLocations maps stop_id to location, used for bbox filtering of entities without a position.
+	StopLocations map[string]tlxy.Point
+	// Differential returns only entities updated after Since (POSIX time),
+	// with the header incrementality set to DIFFERENTIAL. Entities in Matched that were
+	// updated after Since and no longer match the filter, and entities in Removed, are reported as deleted.
+	Differential bool
+	Since        uint64
+	// Matched is the set of entity ids that matched the filter at Since, see MatchedEntityIDs.
+	// A differential for a filter with criteria and no Matched set is returned as a FULL_DATASET.
+	Matched map[string]bool
+	// Removed maps the ids of entities removed from the source feed to the time they were removed.
+	// RemovedSince is the time from which Removed is complete; a differential for an earlier
+	// Since, or when RemovedSince is not set, cannot report every removal and is returned as a FULL_DATASET.
+	Removed      map[string]uint64
+	RemovedSince uint64
+}
+
+// FilterFeedMessage returns a new FeedMessage containing the entities that match the filter.
+// Entities are shared with the original message, not copied.
+func FilterFeedMessage(msg *pb.FeedMessage, filter FeedMessageFilter) *pb.FeedMessage {
+	ret := &pb.FeedMessage{}
+	if msg.GetHeader() != nil {
+		ret.Header = proto.Clone(msg.GetHeader()).(*pb.FeedHeader)
+	} else {
+		ret.Header = &pb.FeedHeader{GtfsRealtimeVersion: proto.String("2.0")}
+	}
+	differential := filter.Differential && filter.RemovedSince > 0 && filter.Since >= filter.RemovedSince
+	if filter.hasCriteria() && filter.Matched == nil {
+		differential = false
+	}
+	incrementality := pb.FeedHeader_FULL_DATASET
+	if differential {
+		incrementality = pb.FeedHeader_DIFFERENTIAL
+	}
+	ret.Header.Incrementality = &incrementality
+	var deleted []string
+	for _, ent := range msg.GetEntity() {
+		if ent == nil {
+			continue
+		}
+		if differential && !filter.updatedSince(ent, msg.GetHeader().GetTimestamp()) {
+			continue
+		}
+		if filter.matchEntity(ent) {
+			ret.Entity = append(ret.Entity, ent)
+		} else if differential && filter.Matched[ent.GetId()] {
+			// The entity matched before this update
+			deleted = append(deleted, ent.GetId())
+		}
+	}
+	if differential {
+		for eid, ts := range filter.Removed {
+			if ts > filter.Since {
+				deleted = append(deleted, eid)
+			}
+		}
+		slices.Sort(deleted)
+		for _, eid := range slices.Compact(deleted) {
+			ret.Entity = append(ret.Entity, &pb.FeedEntity{Id: proto.String(eid), IsDeleted: proto.Bool(true)})
+		}
+	}
+	return ret
+}
+
+// MatchedEntityIDs returns the ids of the entities in msg that match the filter,
+// for use as Matched in a later differential.
+func MatchedEntityIDs(msg *pb.FeedMessage, filter FeedMessageFilter) map[string]bool {
+	ret := map[string]bool{}
+	for _, ent := range msg.GetEntity() {
+		if ent != nil && ent.GetId() != "" && filter.matchEntity(ent) {
+			ret[ent.GetId()] = true
+		}
+	}
+	return ret
+}
+
+// hasCriteria checks if the filter can exclude any entity.
+func (f *FeedMessageFilter) hasCriteria() bool {
+	return len(f.AgencyIDs) > 0 ||
+		len(f.RouteIDs) > 0 ||
+		len(f.TripIDs) > 0 ||
+		len(f.StopIDs) > 0 ||
+		f.Bbox != nil
+}
+
+// updatedSince checks the entity timestamp, falling back to the header timestamp.
+func (f *FeedMessageFilter) updatedSince(ent *pb.FeedEntity, headerTimestamp uint64) bool {
+	ts := headerTimestamp
+	if v := ent.GetTripUpdate().GetTimestamp(); v > 0 {
+		ts = v
+	} else if v := ent.GetVehicle().GetTimestamp(); v > 0 {
+		ts = v
+	}
+	return ts > f.Since
+}
+
+func (f *FeedMessageFilter) matchEntity(ent *pb.FeedEntity) bool {
+	if tu := ent.GetTripUpdate(); tu != nil {
+		var stopIDs []string
+		for _, stu := range tu.GetStopTimeUpdate() {
+			stopIDs = append(stopIDs, stu.GetStopId())
+		}
+		return f.matchTrip(tu.GetTrip()) &&
+			f.matchStops(stopIDs) &&
+			f.matchLocation(nil, stopIDs)
+	}
+	if vp := ent.GetVehicle(); vp != nil {
+		var stopIDs []string
+		if vp.GetStopId() != "" {
+			stopIDs = append(stopIDs, vp.GetStopId())
+		}
+		var pt *tlxy.Point
+		if pos := vp.GetPosition(); pos != nil {
+			pt = &tlxy.Point{Lon: float64(pos.GetLongitude()), Lat: float64(pos.GetLatitude())}
+		}
+		return f.matchTrip(vp.GetTrip()) &&
+			f.matchStops(stopIDs) &&
+			f.matchLocation(pt, stopIDs)
+	}
+	if alert := ent.GetAlert(); alert != nil {
+		// At least one informed entity must match all criteria
+		for _, sel := range alert.GetInformedEntity() {
+			if sel != nil && f.matchSelector(sel) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// matchTrip checks trip, route, and agency criteria.
+func (f *FeedMessageFilter) matchTrip(trip *pb.TripDescriptor) bool {
+	if len(f.TripIDs) > 0 && !slices.Contains(f.TripIDs, trip.GetTripId()) {
+		return false
+	}
+	routeID := trip.GetRouteId()
+	if routeID == "" {
+		routeID = f.TripRoutes[trip.GetTripId()]
+	}
+	if len(f.RouteIDs) > 0 && !slices.Contains(f.RouteIDs, routeID) {
+		return false
+	}
+	if len(f.AgencyIDs) > 0 && !slices.Contains(f.AgencyIDs, f.RouteAgencies[routeID]) {
+		return false
+	}
+	return true
+}
+
+// matchStops checks that at least one stop matches the stop criteria.
+func (f *FeedMessageFilter) matchStops(stopIDs []string) bool {
+	if len(f.StopIDs) == 0 {
+		return true
+	}
+	for _, stopID := range stopIDs {
+		if slices.Contains(f.StopIDs, stopID) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchLocation checks the bbox against a position or, if missing, against stop locations.
+func (f *FeedMessageFilter) matchLocation(pt *tlxy.Point, stopIDs []string) bool {
+	if f.Bbox == nil {
+		return true
+	}
+	if pt != nil {
+		return f.Bbox.Contains(*pt)
+	}
+	for _, stopID := range stopIDs {
+		if stopPt, ok := f.StopLocations[stopID]; ok && f.Bbox.Contains(stopPt) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSelector checks an alert informed entity against all criteria.
+func (f *FeedMessageFilter) matchSelector(sel *pb.EntitySelector) bool {
+	routeID := sel.GetRouteId()
+	if routeID == "" {
+		routeID = sel.GetTrip().GetRouteId()
+	}
+	if routeID == "" {
+		routeID = f.TripRoutes[sel.GetTrip().GetTripId()]
+	}
+	agencyID := sel.GetAgencyId()
+	if agencyID == "" {
+		agencyID = f.RouteAgencies[routeID]
+	}
+	if len(f.TripIDs) > 0 && !slices.Contains(f.TripIDs, sel.GetTrip().GetTripId()) {
+		return false
+	}
+	if len(f.RouteIDs) > 0 && !slices.Contains(f.RouteIDs, routeID) {
+		return false
+	}
+	if len(f.AgencyIDs) > 0 && !slices.Contains(f.AgencyIDs, agencyID) {
+		return false
+	}
+	var stopIDs []string
+	if sel.GetStopId() != "" {
+		stopIDs = append(stopIDs, sel.GetStopId())
+	}
+	return f.matchStops(stopIDs) && f.matchLocation(nil, stopIDs)
+}
@@ -0,0 +1,210 @@
+package rt
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/interline-io/transitland-lib/internal/testpath"
+	"github.com/interline-io/transitland-lib/rt/pb"
+	"github.com/interline-io/transitland-lib/tlxy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterFeedMessage(t *testing.T) {
+	vehicles, err := ReadFile(testpath.RelPath("testdata/rt/ct-vehicle-positions.pb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tripUpdates, err := ReadFile(testpath.RelPath("testdata/rt/bart-trip-updates.pb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	alerts, err := ReadFile(testpath.RelPath("testdata/rt/bart-alerts.pb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entityIDs := func(msg *pb.FeedMessage) []string {
+		var ret []string
+		for _, ent := range msg.Entity {
+			ret = append(ret, ent.GetId())
+		}
+		return ret
+	}
+	tcs := []struct {
+		name   string
+		msg    *pb.FeedMessage
+		filter FeedMessageFilter
+		expect []string
+		count  int
+	}{
+		{
+			name:  "no filter",
+			msg:   vehicles,
+			count: len(vehicles.Entity),
+		},
+		{
+			name:   "vehicles by route",
+			msg:    vehicles,
+			filter: FeedMessageFilter{RouteIDs: []string{"L3", "B7"}},
+			expect: []string{"308", "310", "311", "312", "709", "710"},
+		},
+		{
+			name:   "vehicles by trip",
+			msg:    vehicles,
+			filter: FeedMessageFilter{TripIDs: []string{"124"}},
+			expect: []string{"124"},
+		},
+		{
+			name:   "vehicles by bbox",
+			msg:    vehicles,
+			filter: FeedMessageFilter{Bbox: &tlxy.BoundingBox{MinLon: -122.40, MinLat: 37.77, MaxLon: -122.39, MaxLat: 37.78}},
+			expect: []string{"312", "412", "414", "710"},
+		},
+		{
+			name:   "vehicles by bbox and route",
+			msg:    vehicles,
+			filter: FeedMessageFilter{RouteIDs: []string{"L4"}, Bbox: &tlxy.BoundingBox{MinLon: -122.40, MinLat: 37.77, MaxLon: -122.39, MaxLat: 37.78}},
+			expect: []string{"412", "414"},
+		},
+		{
+			name:   "vehicles by agency",
+			msg:    vehicles,
+			filter: FeedMessageFilter{AgencyIDs: []string{"other"}, RouteAgencies: map[string]string{"L1": "caltrain", "B7": "other"}},
+			expect: []string{"709", "710"},
+		},
+		{
+			name:   "trip updates by stop",
+			msg:    tripUpdates,
+			filter: FeedMessageFilter{StopIDs: []string{"DALY"}, TripIDs: []string{"1011112WKDY", "missing"}},
+			expect: []string{"1011112WKDY"},
+		},
+		{
+			name:   "trip updates by route using trip routes",
+			msg:    tripUpdates,
+			filter: FeedMessageFilter{RouteIDs: []string{"11"}, TripRoutes: map[string]string{"1011112WKDY": "11"}},
+			expect: []string{"1011112WKDY"},
+		},
+		{
+			name:   "trip updates by bbox using stop locations",
+			msg:    tripUpdates,
+			filter: FeedMessageFilter{TripIDs: []string{"1011112WKDY"}, Bbox: &tlxy.BoundingBox{MinLon: -122.5, MinLat: 37.7, MaxLon: -122.4, MaxLat: 37.8}, StopLocations: map[string]tlxy.Point{"DALY": {Lon: -122.469, Lat: 37.706}}},
+			expect: []string{"1011112WKDY"},
+		},
+		{
+			name:   "trip updates by bbox without stop locations",
+			msg:    tripUpdates,
+			filter: FeedMessageFilter{Bbox: &tlxy.BoundingBox{MinLon: -122.5, MinLat: 37.7, MaxLon: -122.4, MaxLat: 37.8}},
+			expect: nil,
+		},
+		{
+			name:   "alerts by agency",
+			msg:    alerts,
+			filter: FeedMessageFilter{AgencyIDs: []string{"BART"}},
+			expect: []string{"BSA_187874"},
+		},
+		{
+			name:   "alerts by other agency",
+			msg:    alerts,
+			filter: FeedMessageFilter{AgencyIDs: []string{"CT"}},
+			expect: nil,
+		},
+		{
+			name:   "differential before header timestamp",
+			msg:    tripUpdates,
+			filter: FeedMessageFilter{Differential: true, Since: tripUpdates.Header.GetTimestamp() - 1, RemovedSince: 1},
+			count:  len(tripUpdates.Entity),
+		},
+		{
+			name:   "differential after header timestamp",
+			msg:    tripUpdates,
+			filter: FeedMessageFilter{Differential: true, Since: tripUpdates.Header.GetTimestamp(), RemovedSince: 1},
+			expect: nil,
+		},
+		{
+			name:   "differential uses entity timestamps",
+			msg:    vehicles,
+			filter: FeedMessageFilter{Differential: true, Since: 1699405548, RemovedSince: 1},
+			count:  len(vehicles.Entity),
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			ret := FilterFeedMessage(tc.msg, tc.filter)
+			if tc.count > 0 {
+				assert.Equal(t, tc.count, len(ret.Entity))
+			} else {
+				assert.ElementsMatch(t, tc.expect, entityIDs(ret))
+			}
+			expectIncrementality := pb.FeedHeader_FULL_DATASET
+			if tc.filter.Differential && tc.filter.RemovedSince > 0 {
+				expectIncrementality = pb.FeedHeader_DIFFERENTIAL
+			}
+			assert.Equal(t, expectIncrementality, ret.Header.GetIncrementality())
+			assert.Equal(t, tc.msg.Header.GetTimestamp(), ret.Header.GetTimestamp())
+		})
+	}
+	t.Run("differential deletions", func(t *testing.T) {
+		since := tripUpdates.Header.GetTimestamp() - 1
+		movedID := tripUpdates.Entity[0].GetId()
+		if movedID == "1011112WKDY" {
+			movedID = tripUpdates.Entity[1].GetId()
+		}
+		filter := FeedMessageFilter{
+			TripIDs:      []string{"1011112WKDY"},
+			Differential: true,
+			Since:        since,
+			Matched:      map[string]bool{"1011112WKDY": true, movedID: true},
+			Removed:      map[string]uint64{"removed-before": since - 10, "removed-after": since + 1},
+			RemovedSince: since - 60,
+		}
+		ret := FilterFeedMessage(tripUpdates, filter)
+		assert.Equal(t, pb.FeedHeader_DIFFERENTIAL, ret.Header.GetIncrementality())
+		var updated, deleted []string
+		for _, ent := range ret.Entity {
+			if ent.GetIsDeleted() {
+				deleted = append(deleted, ent.GetId())
+			} else {
+				updated = append(updated, ent.GetId())
+			}
+		}
+		assert.Equal(t, []string{"1011112WKDY"}, updated)
+		// Previously matched entities outside the filter, and entities removed after since
+		assert.ElementsMatch(t, []string{movedID, "removed-after"}, deleted)
+	})
+	t.Run("differential does not delete entities that never matched", func(t *testing.T) {
+		since := tripUpdates.Header.GetTimestamp() - 1
+		filter := FeedMessageFilter{
+			TripIDs:      []string{"1011112WKDY"},
+			Differential: true,
+			Since:        since,
+			Matched:      map[string]bool{"1011112WKDY": true},
+			RemovedSince: since - 60,
+		}
+		ret := FilterFeedMessage(tripUpdates, filter)
+		assert.Equal(t, pb.FeedHeader_DIFFERENTIAL, ret.Header.GetIncrementality())
+		assert.Equal(t, []string{"1011112WKDY"}, entityIDs(ret))
+	})
+	t.Run("differential without matched entities", func(t *testing.T) {
+		since := tripUpdates.Header.GetTimestamp() - 1
+		ret := FilterFeedMessage(tripUpdates, FeedMessageFilter{TripIDs: []string{"1011112WKDY"}, Differential: true, Since: since, RemovedSince: since - 60})
+		assert.Equal(t, pb.FeedHeader_FULL_DATASET, ret.Header.GetIncrementality())
+		assert.Equal(t, []string{"1011112WKDY"}, entityIDs(ret))
+	})
+	t.Run("matched entity ids", func(t *testing.T) {
+		matched := MatchedEntityIDs(vehicles, FeedMessageFilter{TripIDs: []string{"124"}})
+		assert.NotEmpty(t, matched)
+		assert.ElementsMatch(t, entityIDs(FilterFeedMessage(vehicles, FeedMessageFilter{TripIDs: []string{"124"}})), slices.Collect(maps.Keys(matched)))
+	})
+	t.Run("differential without removal history", func(t *testing.T) {
+		ret := FilterFeedMessage(tripUpdates, FeedMessageFilter{Differential: true, Since: tripUpdates.Header.GetTimestamp(), RemovedSince: tripUpdates.Header.GetTimestamp() + 1})
+		assert.Equal(t, pb.FeedHeader_FULL_DATASET, ret.Header.GetIncrementality())
+		assert.Equal(t, len(tripUpdates.Entity), len(ret.Entity))
+	})
+	t.Run("does not modify original", func(t *testing.T) {
+		n := len(vehicles.Entity)
+		FilterFeedMessage(vehicles, FeedMessageFilter{TripIDs: []string{"124"}, Differential: true})
+		assert.Equal(t, n, len(vehicles.Entity))
+		assert.Equal(t, pb.FeedHeader_FULL_DATASET, vehicles.Header.GetIncrementality())
+	})
+}
@@ -0,0 +1,123 @@
+package rt
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/interline-io/transitland-lib/rt/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// MergeFeedMessages combines the entities of several FeedMessages, such as
+// vehicle positions and predictions for the same agency from separate vendors.
+//
+// Entities describing the same thing are reconciled and only the freshest is kept:
+// trip updates by trip descriptor and vehicle positions by vehicle id, falling back to
+// the trip descriptor. Other entities, such as alerts, are keyed by entity id within
+// their own message only, since sources assign entity ids independently. Freshness is the entity
+// timestamp, or the header timestamp of its message; on a tie the earlier message wins.
+// Entity ids are kept unless they would collide, in which case a suffix is added.
+// The result is a FULL_DATASET with the latest header timestamp of the inputs.
+// Entities are shared with the inputs, not copied, unless renamed.
+func MergeFeedMessages(msgs ...*pb.FeedMessage) *pb.FeedMessage {
+	type mergeEntity struct {
+		ent *pb.FeedEntity
+		ts  uint64
+	}
+	var keys []string
+	found := map[string]mergeEntity{}
+	headerTimestamp := uint64(0)
+	for idx, msg := range msgs {
+		defaultTimestamp := msg.GetHeader().GetTimestamp()
+		headerTimestamp = max(headerTimestamp, defaultTimestamp)
+		for _, ent := range msg.GetEntity() {
+			if ent == nil {
+				continue
+			}
+			key := mergeEntityKey(idx, ent)
+			ts := defaultTimestamp
+			if v := entityTimestamp(ent); v > 0 {
+				ts = v
+			}
+			prev, ok := found[key]
+			if !ok {
+				keys = append(keys, key)
+			} else if prev.ts >= ts {
+				continue
+			}
+			found[key] = mergeEntity{ent: ent, ts: ts}
+		}
+	}
+	incrementality := pb.FeedHeader_FULL_DATASET
+	ret := &pb.FeedMessage{
+		Header: &pb.FeedHeader{
+			GtfsRealtimeVersion: proto.String("2.0"),
+			Incrementality:      &incrementality,
+		},
+	}
+	if headerTimestamp > 0 {
+		ret.Header.Timestamp = proto.Uint64(headerTimestamp)
+	}
+	usedIds := map[string]bool{}
+	for _, key := range keys {
+		ent := found[key].ent
+		eid := ent.GetId()
+		if usedIds[eid] {
+			// Sources assign entity ids independently
+			ent = proto.Clone(ent).(*pb.FeedEntity)
+			for i := 2; usedIds[eid]; i++ {
+				eid = ent.GetId() + "-" + strconv.Itoa(i)
+			}
+			ent.Id = proto.String(eid)
+		}
+		usedIds[eid] = true
+		ret.Entity = append(ret.Entity, ent)
+	}
+	return ret
+}
+
+// mergeEntityKey identifies the thing an entity describes.
+// Entities identified only by entity id are keyed by the index of their source message.
+func mergeEntityKey(idx int, ent *pb.FeedEntity) string {
+	if tu := ent.GetTripUpdate(); tu != nil {
+		if k, ok := tripDescriptorKey(tu.GetTrip()); ok {
+			return "trip_update:" + k
+		}
+	} else if vp := ent.GetVehicle(); vp != nil {
+		if v := vp.GetVehicle().GetId(); v != "" {
+			return "vehicle:" + v
+		}
+		if k, ok := tripDescriptorKey(vp.GetTrip()); ok {
+			return "vehicle_trip:" + k
+		}
+	}
+	kind := "entity"
+	if ent.GetTripUpdate() != nil {
+		kind = "trip_update"
+	} else if ent.GetVehicle() != nil {
+		kind = "vehicle"
+	} else if ent.GetAlert() != nil {
+		kind = "alert"
+	}
+	return fmt.Sprintf("%s_entity:%d:%s", kind, idx, ent.GetId())
+}
+
+// tripDescriptorKey identifies a trip by trip_id and start time, or by route,
+// direction and start time when the descriptor has no trip_id.
+// The route is not compared when a trip_id is present, since it is often omitted.
+func tripDescriptorKey(td *pb.TripDescriptor) (string, bool) {
+	if v := td.GetTripId(); v != "" {
+		return fmt.Sprintf("%s|%s|%s", v, td.GetStartDate(), td.GetStartTime()), true
+	}
+	if v := td.GetRouteId(); v != "" {
+		return fmt.Sprintf("route:%s|%d|%s|%s", v, td.GetDirectionId(), td.GetStartDate(), td.GetStartTime()), true
+	}
+	return "", false
+}
+
+func entityTimestamp(ent *pb.FeedEntity) uint64 {
+	if v := ent.GetTripUpdate().GetTimestamp(); v > 0 {
+		return v
+	}
+	return ent.GetVehicle().GetTimestamp()
+}
@@ -0,0 +1,138 @@
+package rt
+
+import (
+	"testing"
+
+	"github.com/interline-io/transitland-lib/internal/testpath"
+	"github.com/interline-io/transitland-lib/rt/pb"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestMergeFeedMessages(t *testing.T) {
+	msg := func(ts uint64, ents ...*pb.FeedEntity) *pb.FeedMessage {
+		return &pb.FeedMessage{
+			Header: &pb.FeedHeader{GtfsRealtimeVersion: proto.String("2.0"), Timestamp: proto.Uint64(ts)},
+			Entity: ents,
+		}
+	}
+	tripUpdate := func(eid string, tripID string, ts uint64) *pb.FeedEntity {
+		tu := &pb.TripUpdate{Trip: &pb.TripDescriptor{TripId: proto.String(tripID)}}
+		if ts > 0 {
+			tu.Timestamp = proto.Uint64(ts)
+		}
+		return &pb.FeedEntity{Id: proto.String(eid), TripUpdate: tu}
+	}
+	vehicle := func(eid string, vehicleID string, tripID string, ts uint64) *pb.FeedEntity {
+		vp := &pb.VehiclePosition{}
+		if vehicleID != "" {
+			vp.Vehicle = &pb.VehicleDescriptor{Id: proto.String(vehicleID)}
+		}
+		if tripID != "" {
+			vp.Trip = &pb.TripDescriptor{TripId: proto.String(tripID)}
+		}
+		if ts > 0 {
+			vp.Timestamp = proto.Uint64(ts)
+		}
+		return &pb.FeedEntity{Id: proto.String(eid), Vehicle: vp}
+	}
+	alert := func(eid string) *pb.FeedEntity {
+		return &pb.FeedEntity{Id: proto.String(eid), Alert: &pb.Alert{}}
+	}
+	type expectEntity struct {
+		id string
+		ts uint64
+	}
+	tcs := []struct {
+		name            string
+		msgs            []*pb.FeedMessage
+		expect          []expectEntity
+		expectTimestamp uint64
+	}{
+		{
+			name:            "separate vendors",
+			msgs:            []*pb.FeedMessage{msg(100, vehicle("v1", "bus1", "t1", 90)), msg(110, tripUpdate("tu1", "t1", 105))},
+			expect:          []expectEntity{{"v1", 90}, {"tu1", 105}},
+			expectTimestamp: 110,
+		},
+		{
+			name:   "trip updates by trip descriptor",
+			msgs:   []*pb.FeedMessage{msg(100, tripUpdate("a", "t1", 90), tripUpdate("b", "t2", 95)), msg(110, tripUpdate("c", "t1", 100))},
+			expect: []expectEntity{{"c", 100}, {"b", 95}},
+		},
+		{
+			name:   "older trip update ignored",
+			msgs:   []*pb.FeedMessage{msg(100, tripUpdate("a", "t1", 100)), msg(110, tripUpdate("c", "t1", 90))},
+			expect: []expectEntity{{"a", 100}},
+		},
+		{
+			name:   "header timestamp used when entity has none",
+			msgs:   []*pb.FeedMessage{msg(100, tripUpdate("a", "t1", 0)), msg(110, tripUpdate("c", "t1", 0))},
+			expect: []expectEntity{{"c", 0}},
+		},
+		{
+			name:   "tie keeps first",
+			msgs:   []*pb.FeedMessage{msg(100, tripUpdate("a", "t1", 0)), msg(100, tripUpdate("c", "t1", 0))},
+			expect: []expectEntity{{"a", 0}},
+		},
+		{
+			name:   "vehicles by vehicle id",
+			msgs:   []*pb.FeedMessage{msg(100, vehicle("a", "bus1", "t1", 100)), msg(100, vehicle("b", "bus1", "t2", 120), vehicle("c", "bus2", "t3", 50))},
+			expect: []expectEntity{{"b", 120}, {"c", 50}},
+		},
+		{
+			name:   "vehicles by trip when no vehicle id",
+			msgs:   []*pb.FeedMessage{msg(100, vehicle("a", "", "t1", 100)), msg(100, vehicle("b", "", "t1", 120))},
+			expect: []expectEntity{{"b", 120}},
+		},
+		{
+			name:   "vehicles by entity id within a message",
+			msgs:   []*pb.FeedMessage{msg(100, vehicle("a", "", "", 100), vehicle("a", "", "", 120)), msg(100, vehicle("a", "", "", 110))},
+			expect: []expectEntity{{"a", 120}, {"a-2", 110}},
+		},
+		{
+			name:   "alerts by entity id",
+			msgs:   []*pb.FeedMessage{msg(100, alert("a1"), alert("a2")), msg(120, alert("a1"))},
+			expect: []expectEntity{{"a1", 0}, {"a2", 0}, {"a1-2", 0}},
+		},
+		{
+			name:   "colliding entity ids renamed",
+			msgs:   []*pb.FeedMessage{msg(100, tripUpdate("1", "t1", 0)), msg(100, tripUpdate("1", "t2", 0), vehicle("1", "bus1", "", 0))},
+			expect: []expectEntity{{"1", 0}, {"1-2", 0}, {"1-3", 0}},
+		},
+		{
+			name: "empty",
+			msgs: []*pb.FeedMessage{nil, msg(0)},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			ret := MergeFeedMessages(tc.msgs...)
+			assert.Equal(t, pb.FeedHeader_FULL_DATASET, ret.GetHeader().GetIncrementality())
+			if tc.expectTimestamp > 0 {
+				assert.Equal(t, tc.expectTimestamp, ret.GetHeader().GetTimestamp())
+			}
+			var got []expectEntity
+			for _, ent := range ret.Entity {
+				got = append(got, expectEntity{ent.GetId(), entityTimestamp(ent)})
+			}
+			assert.Equal(t, tc.expect, got)
+		})
+	}
+}
+
+func TestMergeFeedMessages_Files(t *testing.T) {
+	vehicles, err := ReadFile(testpath.RelPath("testdata/rt/ct-vehicle-positions.pb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tripUpdates, err := ReadFile(testpath.RelPath("testdata/rt/bart-trip-updates.pb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret := MergeFeedMessages(vehicles, tripUpdates, vehicles)
+	assert.Equal(t, len(vehicles.Entity)+len(tripUpdates.Entity), len(ret.Entity))
+	assert.Equal(t, max(vehicles.GetHeader().GetTimestamp(), tripUpdates.GetHeader().GetTimestamp()), ret.GetHeader().GetTimestamp())
+	// Entity ids are kept
+	assert.Equal(t, vehicles.Entity[0].GetId(), ret.Entity[0].GetId())
+}
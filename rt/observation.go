@@ -0,0 +1,274 @@
+package rt
+
+import (
+	"time"
+
+	"github.com/interline-io/transitland-lib/rt/pb"
+	"github.com/interline-io/transitland-lib/tt"
+)
+
+// StopObservation is an observed arrival and departure at a stop, compared with the schedule.
+// The observed segment runs from the trip's previous scheduled stop to this stop.
+// Times are seconds since midnight of the trip's service date, in the feed timezone.
+type StopObservation struct {
+	Source                 string // TripUpdate or VehiclePosition
+	TripID                 string
+	RouteID                string
+	AgencyID               string
+	DirectionID            int
+	TripStartDate          tt.Date
+	TripStartTime          tt.Seconds
+	ScheduleRelationship   string
+	VehicleID              string
+	StopSequence           tt.Int // of the from stop
+	FromStopID             string
+	ToStopID               string
+	ScheduledArrivalTime   tt.Seconds
+	ScheduledDepartureTime tt.Seconds
+	ObservedArrivalTime    tt.Seconds
+	ObservedDepartureTime  tt.Seconds
+}
+
+// scheduledStopTime is a stop time kept for StopObservations.
+type scheduledStopTime struct {
+	StopID        string
+	StopSequence  int
+	ArrivalTime   int
+	DepartureTime int
+}
+
+// StopObservations returns the observations in a message for trips in the static feed.
+// The Validator must have been created with KeepStopTimes.
+//
+// A stop time update is an observation once its time is no later than the
+// trip update or header timestamp; later times are predictions.
+// Skipped and unmatched stops and added trips are not observed.
+// A canceled trip is observed at each of its stops, without observed times.
+// A vehicle position is an observation when the vehicle is STOPPED_AT a stop,
+// with the vehicle timestamp as both arrival and departure; consumers combine
+// successive positions into the dwell at the stop.
+func (fi *Validator) StopObservations(msg *pb.FeedMessage) []StopObservation {
+	loc, err := time.LoadLocation(fi.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	headerTimestamp := int64(msg.GetHeader().GetTimestamp())
+	var ret []StopObservation
+	for _, ent := range msg.GetEntity() {
+		if tu := ent.GetTripUpdate(); tu != nil {
+			ret = append(ret, fi.tripUpdateObservations(tu, headerTimestamp, loc)...)
+		}
+		if vp := ent.GetVehicle(); vp != nil {
+			if obs, ok := fi.vehiclePositionObservation(vp, headerTimestamp, loc); ok {
+				ret = append(ret, obs)
+			}
+		}
+	}
+	return ret
+}
+
+func (fi *Validator) tripUpdateObservations(tu *pb.TripUpdate, headerTimestamp int64, loc *time.Location) []StopObservation {
+	td := tu.GetTrip()
+	sts, offset, ok := fi.tripStopTimes(td)
+	if !ok {
+		return nil
+	}
+	observedAt := headerTimestamp
+	if v := int64(tu.GetTimestamp()); v > 0 {
+		observedAt = v
+	}
+	if td.GetScheduleRelationship() == pb.TripDescriptor_CANCELED {
+		return fi.canceledTripObservations(td, sts, offset, observedAt, loc)
+	}
+	var ret []StopObservation
+	idx := 0
+	for _, stu := range tu.GetStopTimeUpdate() {
+		i, found := matchStopTime(sts, idx, stu.StopSequence, stu.GetStopId())
+		if !found {
+			continue
+		}
+		idx = i + 1
+		switch stu.GetScheduleRelationship() {
+		case pb.TripUpdate_StopTimeUpdate_SKIPPED, pb.TripUpdate_StopTimeUpdate_NO_DATA:
+			continue
+		}
+		st := sts[i]
+		arrival := stu.GetArrival().GetTime()
+		departure := stu.GetDeparture().GetTime()
+		ref := arrival
+		if ref == 0 {
+			ref = departure
+		}
+		if ref == 0 {
+			// Only a delay; place the trip on a service date from the observation time
+			ref = observedAt
+		}
+		serviceDate, ok := fi.serviceDate(td, st.ArrivalTime+offset, ref, loc)
+		if !ok {
+			continue
+		}
+		midnight := serviceMidnight(serviceDate, loc).Unix()
+		if arrival == 0 && stu.GetArrival().Delay != nil {
+			arrival = midnight + int64(st.ArrivalTime+offset) + int64(stu.GetArrival().GetDelay())
+		}
+		if departure == 0 && stu.GetDeparture().Delay != nil {
+			departure = midnight + int64(st.DepartureTime+offset) + int64(stu.GetDeparture().GetDelay())
+		}
+		obs := fi.newObservation("TripUpdate", td, sts, i, offset, serviceDate)
+		obs.VehicleID = tu.GetVehicle().GetId()
+		if arrival > 0 && arrival <= observedAt {
+			obs.ObservedArrivalTime = tt.NewSeconds(int(arrival - midnight))
+		}
+		if departure > 0 && departure <= observedAt {
+			obs.ObservedDepartureTime = tt.NewSeconds(int(departure - midnight))
+		}
+		if obs.ObservedArrivalTime.Valid || obs.ObservedDepartureTime.Valid {
+			ret = append(ret, obs)
+		}
+	}
+	return ret
+}
+
+// canceledTripObservations returns an observation without observed times for each stop of a canceled trip.
+func (fi *Validator) canceledTripObservations(td *pb.TripDescriptor, sts []scheduledStopTime, offset int, observedAt int64, loc *time.Location) []StopObservation {
+	if td.GetStartDate() == "" && observedAt <= 0 {
+		return nil
+	}
+	serviceDate, ok := fi.serviceDate(td, sts[0].DepartureTime+offset, observedAt, loc)
+	if !ok {
+		return nil
+	}
+	var ret []StopObservation
+	for i := range sts {
+		ret = append(ret, fi.newObservation("TripUpdate", td, sts, i, offset, serviceDate))
+	}
+	return ret
+}
+
+func (fi *Validator) vehiclePositionObservation(vp *pb.VehiclePosition, headerTimestamp int64, loc *time.Location) (StopObservation, bool) {
+	if vp.CurrentStatus == nil || vp.GetCurrentStatus() != pb.VehiclePosition_STOPPED_AT {
+		return StopObservation{}, false
+	}
+	td := vp.GetTrip()
+	if td.GetScheduleRelationship() == pb.TripDescriptor_CANCELED {
+		return StopObservation{}, false
+	}
+	sts, offset, ok := fi.tripStopTimes(td)
+	if !ok {
+		return StopObservation{}, false
+	}
+	i, found := matchStopTime(sts, 0, vp.CurrentStopSequence, vp.GetStopId())
+	if !found {
+		return StopObservation{}, false
+	}
+	observedAt := headerTimestamp
+	if v := int64(vp.GetTimestamp()); v > 0 {
+		observedAt = v
+	}
+	if observedAt <= 0 {
+		return StopObservation{}, false
+	}
+	serviceDate, ok := fi.serviceDate(td, sts[i].ArrivalTime+offset, observedAt, loc)
+	if !ok {
+		return StopObservation{}, false
+	}
+	obs := fi.newObservation("VehiclePosition", td, sts, i, offset, serviceDate)
+	obs.VehicleID = vp.GetVehicle().GetId()
+	observed := tt.NewSeconds(int(observedAt - serviceMidnight(serviceDate, loc).Unix()))
+	obs.ObservedArrivalTime = observed
+	obs.ObservedDepartureTime = observed
+	return obs, true
+}
+
+func (fi *Validator) newObservation(source string, td *pb.TripDescriptor, sts []scheduledStopTime, i int, offset int, serviceDate time.Time) StopObservation {
+	rtKey := fi.getRtTripKey(td)
+	st := sts[i]
+	obs := StopObservation{
+		Source:                 source,
+		TripID:                 rtKey.TripID,
+		RouteID:                rtKey.RouteID,
+		AgencyID:               rtKey.AgencyID,
+		DirectionID:            fi.tripInfo[rtKey.TripID].DirectionID,
+		TripStartDate:          tt.NewDate(serviceDate),
+		TripStartTime:          tt.NewSeconds(sts[0].DepartureTime + offset),
+		ScheduleRelationship:   td.GetScheduleRelationship().String(),
+		ToStopID:               st.StopID,
+		ScheduledArrivalTime:   tt.NewSeconds(st.ArrivalTime + offset),
+		ScheduledDepartureTime: tt.NewSeconds(st.DepartureTime + offset),
+	}
+	if i > 0 {
+		obs.FromStopID = sts[i-1].StopID
+		obs.StopSequence = tt.NewInt(sts[i-1].StopSequence)
+	}
+	return obs
+}
+
+// tripStopTimes returns the scheduled stop times of a trip and the offset to apply
+// to them, which is non-zero for frequency-based trips with a start_time.
+func (fi *Validator) tripStopTimes(td *pb.TripDescriptor) ([]scheduledStopTime, int, bool) {
+	if td.GetScheduleRelationship() == pb.TripDescriptor_ADDED {
+		return nil, 0, false
+	}
+	sts := fi.stopTimes[td.GetTripId()]
+	if len(sts) == 0 {
+		return nil, 0, false
+	}
+	offset := 0
+	if fi.tripInfo[td.GetTripId()].UsesFrequency && td.GetStartTime() != "" {
+		startTime, err := tt.NewSecondsFromString(td.GetStartTime())
+		if err != nil {
+			return nil, 0, false
+		}
+		offset = startTime.Int() - sts[0].DepartureTime
+	}
+	return sts, offset, true
+}
+
+// serviceDate returns the trip start_date, or, when it is not set, the service date
+// on which the scheduled time is closest to the reference time.
+func (fi *Validator) serviceDate(td *pb.TripDescriptor, scheduled int, ref int64, loc *time.Location) (time.Time, bool) {
+	if v := td.GetStartDate(); v != "" {
+		d, err := time.ParseInLocation("20060102", v, loc)
+		return d, err == nil
+	}
+	refTime := time.Unix(ref, 0).In(loc)
+	var ret time.Time
+	best := int64(-1)
+	for _, day := range []int{0, -1} {
+		d := time.Date(refTime.Year(), refTime.Month(), refTime.Day()+day, 0, 0, 0, 0, loc)
+		diff := serviceMidnight(d, loc).Unix() + int64(scheduled) - ref
+		if diff < 0 {
+			diff = -diff
+		}
+		if best < 0 || diff < best {
+			ret, best = d, diff
+		}
+	}
+	return ret, true
+}
+
+// matchStopTime finds the stop time for a stop_sequence, or for a stop_id at or after index start.
+func matchStopTime(sts []scheduledStopTime, start int, stopSequence *uint32, stopID string) (int, bool) {
+	if stopSequence != nil {
+		for i, st := range sts {
+			if st.StopSequence == int(*stopSequence) {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+	if stopID == "" {
+		return 0, false
+	}
+	for i := start; i < len(sts); i++ {
+		if sts[i].StopID == stopID {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// serviceMidnight returns "noon minus 12h" of a service date, the reference for GTFS times.
+func serviceMidnight(d time.Time, loc *time.Location) time.Time {
+	return time.Date(d.Year(), d.Month(), d.Day(), 12, 0, 0, 0, loc).Add(-12 * time.Hour)
+}
@@ -0,0 +1,158 @@
+package rt
+
+import (
+	"context"
+	"testing"
+
+	"github.com/interline-io/transitland-lib/adapters/empty"
+	"github.com/interline-io/transitland-lib/copier"
+	"github.com/interline-io/transitland-lib/internal/testpath"
+	"github.com/interline-io/transitland-lib/rt/pb"
+	"github.com/interline-io/transitland-lib/tlcsv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestValidator_StopObservations(t *testing.T) {
+	r, err := tlcsv.NewReader(testpath.RelPath("testdata/rt/bart-rt.zip"))
+	require.NoError(t, err)
+	fi := NewValidator()
+	fi.KeepStopTimes = true
+	cpOpts := copier.Options{}
+	cpOpts.AddExtension(fi)
+	_, err = copier.CopyWithOptions(context.Background(), r, &empty.Writer{}, cpOpts)
+	require.NoError(t, err)
+
+	// 2019-08-07 00:00 America/Los_Angeles
+	const midnight = 1565161200
+	tripUpdates, err := ReadFile(testpath.RelPath("testdata/rt/bart-trip-updates.pb"))
+	require.NoError(t, err)
+	trip := func(msg *pb.FeedMessage, tripID string) *pb.FeedEntity {
+		for _, ent := range msg.Entity {
+			if ent.GetTripUpdate().GetTrip().GetTripId() == tripID {
+				return ent
+			}
+		}
+		t.Fatalf("trip %s not found", tripID)
+		return nil
+	}
+	msg := func(ts uint64, ents ...*pb.FeedEntity) *pb.FeedMessage {
+		return &pb.FeedMessage{
+			Header: &pb.FeedHeader{GtfsRealtimeVersion: proto.String("2.0"), Timestamp: proto.Uint64(ts)},
+			Entity: ents,
+		}
+	}
+
+	t.Run("past stop times only", func(t *testing.T) {
+		obs := fi.StopObservations(tripUpdates)
+		assert.Len(t, obs, 8)
+		for _, o := range obs {
+			// 10:45:21
+			assert.LessOrEqual(t, o.ObservedArrivalTime.Val, int64(1565199921-midnight))
+			assert.LessOrEqual(t, o.ObservedDepartureTime.Val, int64(1565199921-midnight))
+		}
+	})
+
+	t.Run("trip update", func(t *testing.T) {
+		ent := trip(tripUpdates, "1011112WKDY")
+		obs := fi.StopObservations(msg(1565201900, ent))
+		require.Len(t, obs, 2)
+		daly, balb := obs[0], obs[1]
+		assert.Equal(t, "TripUpdate", daly.Source)
+		assert.Equal(t, "1011112WKDY", daly.TripID)
+		assert.Equal(t, "5", daly.RouteID)
+		assert.Equal(t, "BART", daly.AgencyID)
+		assert.Equal(t, "2019-08-07", daly.TripStartDate.Format("2006-01-02"))
+		assert.Equal(t, "SCHEDULED", daly.ScheduleRelationship)
+		// First stop has no segment origin
+		assert.Equal(t, "", daly.FromStopID)
+		assert.False(t, daly.StopSequence.Valid)
+		assert.Equal(t, "DALY", daly.ToStopID)
+		assert.EqualValues(t, 1565201526-midnight, daly.ObservedArrivalTime.Val)
+		assert.EqualValues(t, 1565201626-midnight, daly.ObservedDepartureTime.Val)
+		assert.EqualValues(t, daly.ScheduledDepartureTime.Val, daly.TripStartTime.Val)
+		assert.Equal(t, "DALY", balb.FromStopID)
+		assert.Equal(t, "BALB", balb.ToStopID)
+		assert.EqualValues(t, 1, balb.StopSequence.Val)
+		assert.EqualValues(t, 1565201802-midnight, balb.ObservedArrivalTime.Val)
+		assert.Equal(t, "11:16:00", balb.ScheduledArrivalTime.String())
+	})
+
+	t.Run("arrival observed before departure", func(t *testing.T) {
+		ent := trip(tripUpdates, "1011112WKDY")
+		obs := fi.StopObservations(msg(1565201530, ent))
+		require.Len(t, obs, 1)
+		assert.True(t, obs[0].ObservedArrivalTime.Valid)
+		assert.False(t, obs[0].ObservedDepartureTime.Valid)
+	})
+
+	t.Run("delay only", func(t *testing.T) {
+		ent := proto.Clone(trip(tripUpdates, "1011112WKDY")).(*pb.FeedEntity)
+		for _, stu := range ent.TripUpdate.StopTimeUpdate {
+			stu.Arrival.Time = nil
+			stu.Departure.Time = nil
+		}
+		obs := fi.StopObservations(msg(1565201900, ent))
+		require.Len(t, obs, 3)
+		assert.Equal(t, "11:12:00", obs[0].ScheduledArrivalTime.String())
+		assert.Equal(t, "11:12:29", obs[0].ObservedArrivalTime.String())
+		assert.Equal(t, "2019-08-07", obs[0].TripStartDate.Format("2006-01-02"))
+	})
+
+	t.Run("skipped and canceled", func(t *testing.T) {
+		ent := proto.Clone(trip(tripUpdates, "1011112WKDY")).(*pb.FeedEntity)
+		ent.TripUpdate.StopTimeUpdate[0].ScheduleRelationship = pb.TripUpdate_StopTimeUpdate_SKIPPED.Enum()
+		obs := fi.StopObservations(msg(1565201900, ent))
+		require.Len(t, obs, 1)
+		assert.Equal(t, "BALB", obs[0].ToStopID)
+		// Canceled trips are observed at every stop, without observed times
+		ent.TripUpdate.Trip.ScheduleRelationship = pb.TripDescriptor_CANCELED.Enum()
+		obs = fi.StopObservations(msg(1565201900, ent))
+		require.NotEmpty(t, obs)
+		for _, o := range obs {
+			assert.Equal(t, "CANCELED", o.ScheduleRelationship)
+			assert.Equal(t, "2019-08-07", o.TripStartDate.Format("2006-01-02"))
+			assert.False(t, o.ObservedArrivalTime.Valid)
+			assert.False(t, o.ObservedDepartureTime.Valid)
+		}
+		assert.Equal(t, "DALY", obs[0].ToStopID)
+	})
+
+	t.Run("unknown trip", func(t *testing.T) {
+		ent := proto.Clone(trip(tripUpdates, "1011112WKDY")).(*pb.FeedEntity)
+		ent.TripUpdate.Trip.TripId = proto.String("unknown")
+		assert.Empty(t, fi.StopObservations(msg(1565201900, ent)))
+	})
+
+	t.Run("vehicle position", func(t *testing.T) {
+		vp := &pb.FeedEntity{
+			Id: proto.String("v1"),
+			Vehicle: &pb.VehiclePosition{
+				Trip:          &pb.TripDescriptor{TripId: proto.String("1011112WKDY")},
+				Vehicle:       &pb.VehicleDescriptor{Id: proto.String("bus1")},
+				StopId:        proto.String("BALB"),
+				CurrentStatus: pb.VehiclePosition_STOPPED_AT.Enum(),
+				Timestamp:     proto.Uint64(1565201810),
+			},
+		}
+		obs := fi.StopObservations(msg(1565201900, vp))
+		require.Len(t, obs, 1)
+		assert.Equal(t, "VehiclePosition", obs[0].Source)
+		assert.Equal(t, "bus1", obs[0].VehicleID)
+		assert.Equal(t, "DALY", obs[0].FromStopID)
+		assert.Equal(t, "BALB", obs[0].ToStopID)
+		assert.Equal(t, "2019-08-07", obs[0].TripStartDate.Format("2006-01-02"))
+		assert.EqualValues(t, 1565201810-midnight, obs[0].ObservedArrivalTime.Val)
+		assert.EqualValues(t, 1565201810-midnight, obs[0].ObservedDepartureTime.Val)
+
+		// In transit is not an observation
+		vp.Vehicle.CurrentStatus = pb.VehiclePosition_IN_TRANSIT_TO.Enum()
+		assert.Empty(t, fi.StopObservations(msg(1565201900, vp)))
+	})
+
+	t.Run("stop times are not kept by default", func(t *testing.T) {
+		v := NewValidator()
+		assert.Empty(t, v.StopObservations(msg(1565201900, trip(tripUpdates, "1011112WKDY"))))
+	})
+}
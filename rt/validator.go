@@ -41,11 +41,15 @@ type rtTripKey struct {
 type Validator struct {
 	Timezone            string
 	MaxDistanceFromTrip float64
-	tripInfo            map[string]tripInfo
-	routeInfo           map[string]routeInfo
-	stopInfo            map[string]stopInfo
-	geomCache           tlxy.GeomCache // shared with copier
-	sched               *sched.ScheduleChecker
+	// KeepStopTimes keeps the scheduled stop times of each trip for StopObservations.
+	// It is off by default, since it holds the entire schedule in memory.
+	KeepStopTimes bool
+	tripInfo      map[string]tripInfo
+	routeInfo     map[string]routeInfo
+	stopInfo      map[string]stopInfo
+	stopTimes     map[string][]scheduledStopTime
+	geomCache     tlxy.GeomCache // shared with copier
+	sched         *sched.ScheduleChecker
 }
 
 // NewValidator returns an initialized validator.
@@ -55,6 +59,7 @@ func NewValidator() *Validator {
 		tripInfo:            map[string]tripInfo{},
 		routeInfo:           map[string]routeInfo{},
 		stopInfo:            map[string]stopInfo{},
+		stopTimes:           map[string][]scheduledStopTime{},
 		sched:               sched.NewScheduleChecker(),
 		geomCache:           geomcache.NewGeomCache(),
 	}
@@ -83,6 +88,18 @@ func (fi *Validator) Validate(ent tt.Entity) []error {
 			ShapeID:     v.ShapeID.String(),
 			RouteID:     v.RouteID.Val,
 		}
+		if fi.KeepStopTimes && !gtfs.CheckFlexStopTimes(v.StopTimes).IsFlexTrip() {
+			sts := make([]scheduledStopTime, 0, len(v.StopTimes))
+			for _, st := range v.StopTimes {
+				sts = append(sts, scheduledStopTime{
+					StopID:        st.StopID.Val,
+					StopSequence:  st.StopSequence.Int(),
+					ArrivalTime:   st.ArrivalTime.Int(),
+					DepartureTime: st.DepartureTime.Int(),
+				})
+			}
+			fi.stopTimes[v.TripID.Val] = sts
+		}
 	case *gtfs.Frequency:
 		a := fi.tripInfo[v.TripID.Val]
 		a.UsesFrequency = true
@@ -218,6 +218,8 @@ CREATE TABLE IF NOT EXISTS "feed_states" (
   "fetch_wait" integer,
   "rt_retention_period" integer not null default 0,
   "onestop_id_retention_period" integer not null default 0,
+  "next_fetch_at" datetime,
+  "fetch_failures" integer not null default 0,
   "created_at" datetime DEFAULT CURRENT_TIMESTAMP,
   "updated_at" datetime DEFAULT CURRENT_TIMESTAMP,
   foreign key(feed_version_id) REFERENCES feed_versions(id),
@@ -653,6 +655,8 @@ CREATE TABLE feed_fetches (
   "upload_duration_ms" int,
   "feed_version_id" int,
   "storage_key" text,
+  "response_etag" text,
+  "response_last_modified" text,
   "created_at" datetime DEFAULT CURRENT_TIMESTAMP,
   "updated_at" datetime DEFAULT CURRENT_TIMESTAMP,
   foreign key(feed_version_id) REFERENCES feed_versions(id)
@@ -1122,4 +1126,39 @@ CREATE TABLE tl_job_artifacts (
   "deleted_at" datetime
 );
 CREATE INDEX idx_tl_job_artifacts_job_id ON "tl_job_artifacts"(job_id);
-CREATE INDEX idx_tl_job_artifacts_user_id ON "tl_job_artifacts"(user_id);
\ No newline at end of file
+CREATE INDEX idx_tl_job_artifacts_user_id ON "tl_job_artifacts"(user_id);
+CREATE TABLE ext_performance_stop_observations (
+  "id" integer primary key autoincrement,
+  "created_at" datetime DEFAULT CURRENT_TIMESTAMP,
+  "updated_at" datetime DEFAULT CURRENT_TIMESTAMP,
+  "feed_version_id" integer NOT NULL,
+  "trip_id" varchar(255),
+  "route_id" varchar(255),
+  "agency_id" varchar(255),
+  "direction_id" integer,
+  "trip_start_time" integer,
+  "trip_start_date" date,
+  "schedule_relationship" varchar(255),
+  "vehicle_id" varchar(255),
+  "source" varchar(255),
+  "stop_sequence" integer,
+  "observed_arrival_time" integer,
+  "observed_departure_time" integer,
+  "uncertainty" integer,
+  "dwell_time_secs" integer,
+  "scheduled_dwell_time_secs" integer,
+  "occupancy_status" integer,
+  "occupancy_percentage" integer,
+  "observed_arrival_delay" integer,
+  "scheduled_arrival_time" integer,
+  "scheduled_departure_time" integer,
+  "from_stop_id" varchar(255),
+  "to_stop_id" varchar(255),
+  "distance" real,
+  "duration" real,
+  "speed_mph" real,
+  "build_id" varchar(255),
+  "source_id" varchar(255)
+);
+CREATE INDEX idx_ext_performance_stop_observations_feed_version_id ON "ext_performance_stop_observations"(feed_version_id);
+CREATE INDEX idx_ext_performance_stop_observations_trip_id ON "ext_performance_stop_observations"(trip_id);
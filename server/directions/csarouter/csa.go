@@ -0,0 +1,303 @@
+package csarouter
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/interline-io/transitland-lib/tlxy"
+)
+
+// connection is a scheduled trip segment between two consecutive stops.
+type connection struct {
+	depStop int
+	arrStop int
+	dep     int64
+	arr     int64
+	trip    int // index into the query trip instances
+	idx     int // index of the departure stop time in the trip
+	pickup  bool
+	dropOff bool
+}
+
+// tripInstance is a trip on a specific service date, or a single departure of a frequency-based trip.
+type tripInstance struct {
+	trip   int
+	offset int64 // added to stop time seconds to get unix time
+}
+
+const (
+	reachedAccess = iota
+	reachedTransit
+	reachedFootpath
+)
+
+// reached records how a stop was reached.
+type reached struct {
+	kind     int
+	enter    int // boarding connection
+	exit     int // alighting connection
+	from     int // footpath origin stop
+	start    int64
+	distance float64
+}
+
+// journeyLeg is a walking or transit leg of a journey.
+// Stop indexes are -1 for the origin and destination.
+type journeyLeg struct {
+	transit  bool
+	fromStop int
+	toStop   int
+	start    int64
+	end      int64
+	distance float64
+	trip     tripInstance
+	enter    int // index of boarding stop time
+	exit     int // index of alighting stop time
+}
+
+// searchParams are the parameters for a single earliest arrival search.
+type searchParams struct {
+	from            tlxy.Point
+	to              tlxy.Point
+	departAt        time.Time
+	maxDuration     time.Duration
+	maxWalkDistance float64
+}
+
+// connectionSet is the trip instances and sorted connections departing within a time window.
+// It is built once for a request and shared by its searches.
+type connectionSet struct {
+	start int64
+	end   int64
+	trips []tripInstance
+	conns []connection
+}
+
+// covers checks if the connections departing in the search window are all in the set.
+func (cs *connectionSet) covers(p searchParams) bool {
+	return cs != nil && p.departAt.Unix() >= cs.start && p.departAt.Add(p.maxDuration).Unix() <= cs.end
+}
+
+// connections returns the trip instances and sorted connections departing within a time window.
+func (t *Timetable) connections(departAt time.Time, window time.Duration) *connectionSet {
+	start := departAt.Unix()
+	end := departAt.Add(window).Unix()
+	var trips []tripInstance
+	var conns []connection
+	for tidx, trip := range t.trips {
+		if len(trip.stopTimes) < 2 {
+			continue
+		}
+		svc, ok := t.feeds[trip.feed].services[trip.serviceID]
+		if !ok {
+			continue
+		}
+		loc := t.routes[trip.route].loc
+		local := departAt.In(loc)
+		// Include the previous day for trips past midnight and the next day for long windows
+		for _, day := range []int{-1, 0, 1} {
+			d := time.Date(local.Year(), local.Month(), local.Day()+day, 0, 0, 0, 0, time.UTC)
+			if !svc.IsActive(d) {
+				continue
+			}
+			// Service day begins at noon minus 12 hours, per GTFS
+			base := time.Date(d.Year(), d.Month(), d.Day(), 12, 0, 0, 0, loc).Add(-12 * time.Hour).Unix()
+			var offsets []int64
+			if len(trip.frequencies) == 0 {
+				offsets = append(offsets, base)
+			}
+			for _, freq := range trip.frequencies {
+				first := trip.stopTimes[0].departure
+				for s := freq.start; s < freq.end; s += freq.headway {
+					offsets = append(offsets, base+int64(s-first))
+				}
+			}
+			for _, offset := range offsets {
+				sts := trip.stopTimes
+				if offset+int64(sts[len(sts)-1].arrival) < start || offset+int64(sts[0].departure) > end {
+					continue
+				}
+				instance := len(trips)
+				trips = append(trips, tripInstance{trip: tidx, offset: offset})
+				for i := 0; i < len(sts)-1; i++ {
+					dep := offset + int64(sts[i].departure)
+					if dep < start || dep > end {
+						continue
+					}
+					conns = append(conns, connection{
+						depStop: sts[i].stop,
+						arrStop: sts[i+1].stop,
+						dep:     dep,
+						arr:     offset + int64(sts[i+1].arrival),
+						trip:    instance,
+						idx:     i,
+						pickup:  sts[i].pickup,
+						dropOff: sts[i+1].dropOff,
+					})
+				}
+			}
+		}
+	}
+	sort.SliceStable(conns, func(i, j int) bool {
+		if conns[i].dep != conns[j].dep {
+			return conns[i].dep < conns[j].dep
+		}
+		return conns[i].arr < conns[j].arr
+	})
+	return &connectionSet{start: start, end: end, trips: trips, conns: conns}
+}
+
+// search runs an earliest arrival Connection Scan over a set covering the search window
+// and returns the legs of the best journey. Returns nil if the destination cannot be reached.
+func (t *Timetable) search(p searchParams, cs *connectionSet) []journeyLeg {
+	departAt := p.departAt.Unix()
+	end := p.departAt.Add(p.maxDuration).Unix()
+	trips, conns := cs.trips, cs.conns
+	best := int64(math.MaxInt64)
+	bestStop := -1
+	found := false
+
+	// Direct walk
+	if dist := tlxy.DistanceHaversine(p.from, p.to); dist <= p.maxWalkDistance {
+		best = departAt + int64(t.walkTime(dist))
+		found = true
+	}
+
+	// Walking access and egress
+	arrival := make([]int64, len(t.stops))
+	for i := range arrival {
+		arrival[i] = math.MaxInt64
+	}
+	how := make([]reached, len(t.stops))
+	for _, s := range t.nearbyStops(p.from, p.maxWalkDistance) {
+		dist := tlxy.DistanceHaversine(p.from, t.stops[s].Point)
+		arrival[s] = departAt + int64(t.walkTime(dist))
+		how[s] = reached{kind: reachedAccess, start: departAt, distance: dist}
+	}
+	egress := map[int]float64{}
+	for _, s := range t.nearbyStops(p.to, p.maxWalkDistance) {
+		egress[s] = tlxy.DistanceHaversine(t.stops[s].Point, p.to)
+	}
+	checkEgress := func(s int) {
+		if dist, ok := egress[s]; ok {
+			if a := arrival[s] + int64(t.walkTime(dist)); a < best {
+				best = a
+				bestStop = s
+				found = true
+			}
+		}
+	}
+
+	// Scan connections
+	boarded := make([]int, len(trips))
+	for i := range boarded {
+		boarded[i] = -1
+	}
+	first := sort.Search(len(conns), func(i int) bool { return conns[i].dep >= departAt })
+	for ci := first; ci < len(conns); ci++ {
+		c := conns[ci]
+		if c.dep > best || c.dep > end {
+			break
+		}
+		if boarded[c.trip] < 0 && c.pickup && arrival[c.depStop] <= c.dep {
+			boarded[c.trip] = ci
+		}
+		if boarded[c.trip] < 0 || !c.dropOff || c.arr >= arrival[c.arrStop] {
+			continue
+		}
+		arrival[c.arrStop] = c.arr
+		how[c.arrStop] = reached{kind: reachedTransit, enter: boarded[c.trip], exit: ci}
+		checkEgress(c.arrStop)
+		for _, fp := range t.footpaths[c.arrStop] {
+			if a := c.arr + int64(fp.duration); a < arrival[fp.to] {
+				arrival[fp.to] = a
+				how[fp.to] = reached{kind: reachedFootpath, from: c.arrStop, start: c.arr, distance: fp.distance}
+				checkEgress(fp.to)
+			}
+		}
+	}
+	if !found {
+		return nil
+	}
+	if bestStop < 0 {
+		return []journeyLeg{{fromStop: -1, toStop: -1, start: departAt, end: best, distance: tlxy.DistanceHaversine(p.from, p.to)}}
+	}
+
+	// Reconstruct journey from the destination
+	legs := []journeyLeg{{fromStop: bestStop, toStop: -1, start: arrival[bestStop], end: best, distance: egress[bestStop]}}
+	for s := bestStop; ; {
+		r := how[s]
+		if r.kind == reachedAccess {
+			legs = append(legs, journeyLeg{fromStop: -1, toStop: s, start: r.start, end: arrival[s], distance: r.distance})
+			break
+		}
+		if r.kind == reachedFootpath {
+			legs = append(legs, journeyLeg{fromStop: r.from, toStop: s, start: r.start, end: arrival[s], distance: r.distance})
+			s = r.from
+			continue
+		}
+		enter := conns[r.enter]
+		exit := conns[r.exit]
+		legs = append(legs, journeyLeg{
+			transit:  true,
+			fromStop: enter.depStop,
+			toStop:   exit.arrStop,
+			start:    enter.dep,
+			end:      exit.arr,
+			trip:     trips[enter.trip],
+			enter:    enter.idx,
+			exit:     exit.idx + 1,
+		})
+		s = enter.depStop
+	}
+	for i, j := 0, len(legs)-1; i < j; i, j = i+1, j-1 {
+		legs[i], legs[j] = legs[j], legs[i]
+	}
+
+	// Leave the origin as late as possible
+	if len(legs) > 1 && legs[1].transit {
+		walk := legs[0].end - legs[0].start
+		legs[0].end = legs[1].start
+		legs[0].start = legs[1].start - walk
+	}
+	return legs
+}
+
+// reachable runs a one-to-many earliest arrival Connection Scan from the origin and
+// returns the earliest arrival at each stop reached within the maximum duration.
+// Stops that are not reached have an arrival of math.MaxInt64.
+func (t *Timetable) reachable(p searchParams) []int64 {
+	departAt := p.departAt.Unix()
+	end := p.departAt.Add(p.maxDuration).Unix()
+	cs := t.connections(p.departAt, p.maxDuration)
+	trips, conns := cs.trips, cs.conns
+	arrival := make([]int64, len(t.stops))
+	for i := range arrival {
+		arrival[i] = math.MaxInt64
+	}
+	for _, s := range t.nearbyStops(p.from, p.maxWalkDistance) {
+		if a := departAt + int64(t.walkTime(tlxy.DistanceHaversine(p.from, t.stops[s].Point))); a <= end {
+			arrival[s] = a
+		}
+	}
+	boarded := make([]bool, len(trips))
+	for _, c := range conns {
+		if c.dep > end {
+			break
+		}
+		if !boarded[c.trip] && c.pickup && arrival[c.depStop] <= c.dep {
+			boarded[c.trip] = true
+		}
+		if !boarded[c.trip] || !c.dropOff || c.arr > end || c.arr >= arrival[c.arrStop] {
+			continue
+		}
+		arrival[c.arrStop] = c.arr
+		for _, fp := range t.footpaths[c.arrStop] {
+			if a := c.arr + int64(fp.duration); a <= end && a < arrival[fp.to] {
+				arrival[fp.to] = a
+			}
+		}
+	}
+	return arrival
+}
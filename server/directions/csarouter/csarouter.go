@@ -0,0 +1,432 @@
+// Package csarouter is an in-process transit router using the Connection Scan Algorithm.
+package csarouter
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/interline-io/log"
+	"github.com/interline-io/transitland-lib/internal/clock"
+	"github.com/interline-io/transitland-lib/server/directions"
+	"github.com/interline-io/transitland-lib/server/model"
+	"github.com/interline-io/transitland-lib/tldb"
+	"github.com/interline-io/transitland-lib/tlxy"
+	"github.com/interline-io/transitland-lib/tt"
+)
+
+func init() {
+	// The timetable is loaded from the database on the first request
+	router := &Router{}
+	if err := directions.RegisterRouter("csa", func() directions.Handler {
+		return router
+	}); err != nil {
+		panic(err)
+	}
+}
+
+// reloadInterval is how often a Router loaded from the database checks for
+// changes to the active feed versions.
+const reloadInterval = 1 * time.Minute
+
+// Router answers transit directions requests from a Timetable.
+// If no Timetable is provided, the active feed versions are loaded from the
+// request's database on first use, and reloaded when they change.
+type Router struct {
+	Clock           clock.Clock
+	MaxWalkDistance float64       // meters; used for access, egress, and walk-only itineraries
+	MaxDuration     time.Duration // maximum itinerary duration
+	MaxItineraries  int
+	timetable       atomic.Pointer[Timetable]
+	static          bool         // timetable was provided, never reloaded
+	loadedKey       string       // active feed versions of the loaded timetable; guarded by reloadLock
+	checkedAt       atomic.Int64 // unix nanoseconds of the last check for changes
+	reloadLock      sync.Mutex   // held while checking for changes and loading a new Timetable
+}
+
+// NewRouter returns a Router for a loaded Timetable.
+func NewRouter(timetable *Timetable) *Router {
+	h := &Router{static: true}
+	h.timetable.Store(timetable)
+	return h
+}
+
+func (h *Router) Request(ctx context.Context, req model.DirectionRequest) (*model.Directions, error) {
+	// Prepare response
+	ret := model.Directions{
+		Origin:      wpiWaypoint(req.From),
+		Destination: wpiWaypoint(req.To),
+	}
+	if err := directions.ValidateDirectionRequest(req); err != nil {
+		ret.Exception = aws.String("invalid input")
+		return &ret, nil
+	}
+	if req.Mode != model.StepModeTransit {
+		ret.Exception = aws.String("unsupported travel mode")
+		return &ret, nil
+	}
+	timetable, err := h.getTimetable(ctx)
+	if err != nil {
+		log.For(ctx).Error().Err(err).Msg("csarouter: failed to load timetable")
+		ret.Exception = aws.String("no timetable available")
+		return &ret, nil
+	}
+
+	// Prepare departure time
+	departAt := time.Now().In(time.UTC)
+	if h.Clock != nil {
+		departAt = h.Clock.Now()
+	}
+	if req.DepartAt != nil {
+		departAt = *req.DepartAt
+	}
+	departAt = departAt.In(time.UTC)
+
+	p := searchParams{
+		from:            tlxy.Point{Lon: req.From.Lon, Lat: req.From.Lat},
+		to:              tlxy.Point{Lon: req.To.Lon, Lat: req.To.Lat},
+		departAt:        departAt,
+		maxDuration:     h.MaxDuration,
+		maxWalkDistance: h.MaxWalkDistance,
+	}
+	if p.maxDuration <= 0 {
+		p.maxDuration = 4 * time.Hour
+	}
+	if p.maxWalkDistance <= 0 {
+		p.maxWalkDistance = 1_000
+	}
+	maxItineraries := h.MaxItineraries
+	if maxItineraries <= 0 {
+		maxItineraries = 3
+	}
+	// Search for successive departures, sharing connections between searches.
+	// The set is rebuilt only if a later departure passes its window.
+	var conns *connectionSet
+	seen := map[string]bool{}
+	// One extra itinerary is searched to check that the last one is not dominated
+	for i := 0; i < maxItineraries*4 && len(ret.Itineraries) <= maxItineraries; i++ {
+		if !conns.covers(p) {
+			conns = timetable.connections(p.departAt, 2*p.maxDuration)
+		}
+		legs := timetable.search(p, conns)
+		if len(legs) == 0 {
+			break
+		}
+		itin := timetable.makeItinerary(p.from, p.to, legs)
+		if key := itineraryKey(legs, timetable); !seen[key] {
+			seen[key] = true
+			// A later departure arriving no later dominates the previous itinerary
+			if n := len(ret.Itineraries); n > 0 && !itin.EndTime.After(ret.Itineraries[n-1].EndTime) {
+				ret.Itineraries[n-1] = itin
+			} else {
+				ret.Itineraries = append(ret.Itineraries, itin)
+			}
+		}
+		if !hasTransit(legs) {
+			break
+		}
+		p.departAt = itin.StartTime.Add(time.Minute)
+	}
+	if len(ret.Itineraries) > maxItineraries {
+		ret.Itineraries = ret.Itineraries[:maxItineraries]
+	}
+	if len(ret.Itineraries) == 0 {
+		ret.Exception = aws.String("could not calculate route")
+		return &ret, nil
+	}
+	r0 := ret.Itineraries[0]
+	ret.Success = true
+	ret.DataSource = aws.String("Transitland")
+	ret.Duration = r0.Duration
+	ret.Distance = r0.Distance
+	ret.StartTime = &r0.StartTime
+	ret.EndTime = &r0.EndTime
+	return &ret, nil
+}
+
+// getTimetable returns the loaded Timetable. A Timetable loaded from the database
+// is reloaded when the set of active feed versions has changed, checked at most
+// once every reloadInterval; the previous Timetable is kept if the check fails.
+func (h *Router) getTimetable(ctx context.Context) (*Timetable, error) {
+	timetable := h.timetable.Load()
+	if timetable != nil && (h.static || !h.reloadDue()) {
+		return timetable, nil
+	}
+	// Requests are served from the current Timetable while it is reloaded;
+	// only the first load waits for the database.
+	if timetable == nil {
+		h.reloadLock.Lock()
+	} else if !h.reloadLock.TryLock() {
+		return timetable, nil
+	}
+	defer h.reloadLock.Unlock()
+	if timetable = h.timetable.Load(); timetable != nil && !h.reloadDue() {
+		return timetable, nil
+	}
+	adapter := model.ForContext(ctx).Adapter
+	if adapter == nil {
+		if timetable != nil {
+			return timetable, nil
+		}
+		return nil, errors.New("no database available")
+	}
+	fvs, err := activeFeedVersions(ctx, adapter)
+	if err == nil && (timetable == nil || feedVersionsKey(fvs) != h.loadedKey) {
+		var loaded *Timetable
+		if loaded, err = loadFeedVersions(ctx, adapter, fvs); err == nil {
+			timetable = loaded
+			h.timetable.Store(loaded)
+			h.loadedKey = feedVersionsKey(fvs)
+		}
+	}
+	if err != nil {
+		if timetable == nil {
+			return nil, err
+		}
+		log.For(ctx).Error().Err(err).Msg("csarouter: failed to reload timetable, using previous timetable")
+	}
+	h.checkedAt.Store(time.Now().UnixNano())
+	return timetable, nil
+}
+
+// reloadDue checks if reloadInterval has passed since the last check for changes.
+func (h *Router) reloadDue() bool {
+	return time.Since(time.Unix(0, h.checkedAt.Load())) >= reloadInterval
+}
+
+type activeFeedVersion struct {
+	ID            int    `db:"id"`
+	SHA1          string `db:"sha1"`
+	FeedOnestopID string `db:"feed_onestop_id"`
+}
+
+// activeFeedVersions returns the active feed version for each feed.
+func activeFeedVersions(ctx context.Context, adapter tldb.Adapter) ([]activeFeedVersion, error) {
+	q := adapter.Sqrl().
+		Select("feed_versions.id", "feed_versions.sha1", "current_feeds.onestop_id AS feed_onestop_id").
+		From("feed_states").
+		Join("feed_versions ON feed_versions.id = feed_states.active_feed_version_id").
+		Join("current_feeds ON current_feeds.id = feed_versions.feed_id").
+		Where("current_feeds.deleted_at IS NULL").
+		OrderBy("feed_versions.id")
+	qstr, args, err := q.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var fvs []activeFeedVersion
+	if err := adapter.Select(ctx, &fvs, qstr, args...); err != nil {
+		return nil, err
+	}
+	return fvs, nil
+}
+
+// feedVersionsKey identifies a set of feed versions, ordered by id.
+func feedVersionsKey(fvs []activeFeedVersion) string {
+	var ids []string
+	for _, fv := range fvs {
+		ids = append(ids, strconv.Itoa(fv.ID))
+	}
+	return strings.Join(ids, ",")
+}
+
+// LoadDatabase returns a Timetable with the active feed version for each feed.
+func LoadDatabase(ctx context.Context, adapter tldb.Adapter) (*Timetable, error) {
+	fvs, err := activeFeedVersions(ctx, adapter)
+	if err != nil {
+		return nil, err
+	}
+	return loadFeedVersions(ctx, adapter, fvs)
+}
+
+func loadFeedVersions(ctx context.Context, adapter tldb.Adapter, fvs []activeFeedVersion) (*Timetable, error) {
+	timetable := NewTimetable()
+	for _, fv := range fvs {
+		reader := &tldb.Reader{Adapter: adapter, PageSize: 1_000, FeedVersionIDs: []int{fv.ID}}
+		if err := timetable.LoadReader(reader, fv.FeedOnestopID, fv.SHA1); err != nil {
+			return nil, err
+		}
+		log.For(ctx).Trace().Str("feed_onestop_id", fv.FeedOnestopID).Str("feed_version_sha1", fv.SHA1).Msg("csarouter: loaded feed version")
+	}
+	return timetable, nil
+}
+
+func hasTransit(legs []journeyLeg) bool {
+	for _, leg := range legs {
+		if leg.transit {
+			return true
+		}
+	}
+	return false
+}
+
+// itineraryKey identifies the trips used by a journey.
+func itineraryKey(legs []journeyLeg, timetable *Timetable) string {
+	key := ""
+	for _, leg := range legs {
+		if leg.transit {
+			key += timetable.trips[leg.trip.trip].TripID + ":" + time.Unix(leg.start, 0).String() + ";"
+		}
+	}
+	return key
+}
+
+func (t *Timetable) makeItinerary(from tlxy.Point, to tlxy.Point, legs []journeyLeg) *model.Itinerary {
+	itin := model.Itinerary{
+		From:      &model.Waypoint{Lon: from.Lon, Lat: from.Lat},
+		To:        &model.Waypoint{Lon: to.Lon, Lat: to.Lat},
+		StartTime: unixTime(legs[0].start),
+		EndTime:   unixTime(legs[len(legs)-1].end),
+	}
+	distance := 0.0
+	for _, jleg := range legs {
+		var leg *model.Leg
+		if jleg.transit {
+			leg = t.makeTransitLeg(jleg)
+		} else {
+			leg = t.makeWalkLeg(from, to, jleg)
+		}
+		distance += leg.Distance.Distance
+		itin.Legs = append(itin.Legs, leg)
+	}
+	itin.Duration = makeDuration(float64(legs[len(legs)-1].end - legs[0].start))
+	itin.Distance = makeDistance(distance)
+	return &itin
+}
+
+func (t *Timetable) makeWalkLeg(from tlxy.Point, to tlxy.Point, jleg journeyLeg) *model.Leg {
+	leg := model.Leg{
+		StartTime: unixTime(jleg.start),
+		EndTime:   unixTime(jleg.end),
+		Duration:  makeDuration(float64(jleg.end - jleg.start)),
+		Distance:  makeDistance(jleg.distance / 1000.0),
+	}
+	mode := model.StepModeWalk
+	leg.Mode = &mode
+	leg.From = &model.Waypoint{Lon: from.Lon, Lat: from.Lat}
+	if jleg.fromStop >= 0 {
+		leg.From = t.stopWaypoint(jleg.fromStop, jleg.start)
+	}
+	leg.To = &model.Waypoint{Lon: to.Lon, Lat: to.Lat}
+	if jleg.toStop >= 0 {
+		leg.To = t.stopWaypoint(jleg.toStop, jleg.end)
+	}
+	leg.Geometry = tt.NewLineStringFromFlatCoords([]float64{
+		leg.From.Lon, leg.From.Lat, 0.0,
+		leg.To.Lon, leg.To.Lat, 0.0,
+	})
+	leg.Steps = append(leg.Steps, &model.Step{
+		Duration:  leg.Duration,
+		Distance:  leg.Distance,
+		StartTime: leg.StartTime,
+		EndTime:   leg.EndTime,
+		To:        leg.To,
+		Mode:      model.StepModeWalk,
+	})
+	return &leg
+}
+
+func (t *Timetable) makeTransitLeg(jleg journeyLeg) *model.Leg {
+	trip := t.trips[jleg.trip.trip]
+	route := t.routes[trip.route]
+	feed := t.feeds[trip.feed]
+	leg := model.Leg{
+		StartTime: unixTime(jleg.start),
+		EndTime:   unixTime(jleg.end),
+		Duration:  makeDuration(float64(jleg.end - jleg.start)),
+	}
+	mode := model.StepModeTransit
+	leg.Mode = &mode
+	leg.From = t.stopWaypoint(jleg.fromStop, jleg.start)
+	leg.To = t.stopWaypoint(jleg.toStop, jleg.end)
+	leg.Trip = &model.LegTrip{
+		TripID:          trip.TripID,
+		TripShortName:   trip.TripShortName,
+		Headsign:        trip.Headsign,
+		FeedID:          feed.FeedOnestopID,
+		FeedVersionSha1: feed.FeedVersionSHA1,
+		Route: &model.LegRoute{
+			RouteID:        route.RouteID,
+			RouteShortName: route.RouteShortName,
+			RouteLongName:  route.RouteLongName,
+			RouteType:      route.RouteType,
+			RouteColor:     aws.String(route.RouteColor),
+			RouteTextColor: aws.String(route.RouteTextColor),
+			Agency: &model.LegRouteAgency{
+				AgencyID:   route.AgencyID,
+				AgencyName: route.AgencyName,
+			},
+		},
+	}
+	var coords []float64
+	distance := 0.0
+	for i := jleg.enter; i <= jleg.exit; i++ {
+		st := trip.stopTimes[i]
+		stop := t.stops[st.stop]
+		departure := jleg.trip.offset + int64(st.departure)
+		if i == jleg.exit {
+			departure = jleg.trip.offset + int64(st.arrival)
+		}
+		leg.Stops = append(leg.Stops, &model.WaypointDeparture{
+			Lon:          stop.Point.Lon,
+			Lat:          stop.Point.Lat,
+			Departure:    unixTime(departure),
+			StopID:       stop.StopID,
+			StopName:     stop.StopName,
+			StopCode:     stop.StopCode,
+			StopIndex:    aws.Int(i - jleg.enter),
+			StopSequence: aws.Int(st.stopSequence),
+		})
+		if i > jleg.enter {
+			distance += tlxy.DistanceHaversine(t.stops[trip.stopTimes[i-1].stop].Point, stop.Point)
+		}
+		coords = append(coords, stop.Point.Lon, stop.Point.Lat, 0.0)
+	}
+	leg.Distance = makeDistance(distance / 1000.0)
+	leg.Geometry = tt.NewLineStringFromFlatCoords(coords)
+	return &leg
+}
+
+func (t *Timetable) stopWaypoint(idx int, departure int64) *model.Waypoint {
+	stop := t.stops[idx]
+	return &model.Waypoint{
+		Lon:  stop.Point.Lon,
+		Lat:  stop.Point.Lat,
+		Name: aws.String(stop.StopName),
+		Stop: &model.WaypointStop{
+			Lon:       stop.Point.Lon,
+			Lat:       stop.Point.Lat,
+			Departure: unixTime(departure),
+			StopID:    stop.StopID,
+			StopName:  stop.StopName,
+			StopCode:  stop.StopCode,
+		},
+	}
+}
+
+func unixTime(v int64) time.Time {
+	return time.Unix(v, 0).In(time.UTC)
+}
+
+func wpiWaypoint(w *model.WaypointInput) *model.Waypoint {
+	if w == nil {
+		return nil
+	}
+	return &model.Waypoint{
+		Lon:  w.Lon,
+		Lat:  w.Lat,
+		Name: w.Name,
+	}
+}
+
+func makeDuration(t float64) *model.Duration {
+	return &model.Duration{Duration: t, Units: model.DurationUnitSeconds}
+}
+
+func makeDistance(v float64) *model.Distance {
+	return &model.Distance{Distance: v, Units: model.DistanceUnitKilometers}
+}
@@ -0,0 +1,208 @@
+package csarouter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/interline-io/transitland-lib/internal/testpath"
+	"github.com/interline-io/transitland-lib/server/model"
+	"github.com/interline-io/transitland-lib/tlcsv"
+	"github.com/interline-io/transitland-lib/tldb"
+	_ "github.com/interline-io/transitland-lib/tldb/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRouter(t testing.TB) *Router {
+	reader, err := tlcsv.NewReader(testpath.RelPath("testdata/gtfs-external/bart.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	timetable := NewTimetable()
+	if err := timetable.LoadReader(reader, "BART", "test"); err != nil {
+		t.Fatal(err)
+	}
+	return NewRouter(timetable)
+}
+
+func TestRouter(t *testing.T) {
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	departAt := time.Date(2018, 6, 4, 8, 0, 0, 0, loc)
+	h := newTestRouter(t)
+	t.Run("transit", func(t *testing.T) {
+		req := model.DirectionRequest{
+			Mode:     model.StepModeTransit,
+			From:     &model.WaypointInput{Lat: 37.7894, Lon: -122.4011},
+			To:       &model.WaypointInput{Lat: 37.8701, Lon: -122.2681},
+			DepartAt: &departAt,
+		}
+		res, err := h.Request(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !assert.True(t, res.Success) || !assert.NotEmpty(t, res.Itineraries) {
+			return
+		}
+		assert.Equal(t, "Transitland", *res.DataSource)
+		for _, itin := range res.Itineraries {
+			assert.False(t, itin.StartTime.Before(departAt))
+			assert.True(t, itin.EndTime.After(itin.StartTime))
+			var transitLegs []*model.Leg
+			for i, leg := range itin.Legs {
+				if i > 0 {
+					assert.False(t, leg.StartTime.Before(itin.Legs[i-1].EndTime), "legs must be in order")
+				}
+				if *leg.Mode == model.StepModeTransit {
+					transitLegs = append(transitLegs, leg)
+				}
+			}
+			if assert.NotEmpty(t, transitLegs) {
+				first := transitLegs[0]
+				last := transitLegs[len(transitLegs)-1]
+				assert.Equal(t, "MONT", first.From.Stop.StopID)
+				assert.Equal(t, "DBRK", last.To.Stop.StopID)
+				assert.Equal(t, "test", first.Trip.FeedVersionSha1)
+				assert.NotEmpty(t, first.Trip.TripID)
+				assert.NotEmpty(t, first.Trip.Route.RouteID)
+				assert.Greater(t, len(first.Stops), 1)
+			}
+		}
+		// The direct train is preferred over riding back one stop to board it earlier
+		transitCount := 0
+		for _, leg := range res.Itineraries[0].Legs {
+			if *leg.Mode == model.StepModeTransit {
+				transitCount++
+			}
+		}
+		assert.Equal(t, 1, transitCount)
+		// Successive departures
+		if assert.Greater(t, len(res.Itineraries), 1) {
+			assert.True(t, res.Itineraries[1].StartTime.After(res.Itineraries[0].StartTime))
+		}
+	})
+	t.Run("walk only", func(t *testing.T) {
+		req := model.DirectionRequest{
+			Mode:     model.StepModeTransit,
+			From:     &model.WaypointInput{Lat: 37.7894, Lon: -122.4011},
+			To:       &model.WaypointInput{Lat: 37.7900, Lon: -122.4000},
+			DepartAt: &departAt,
+		}
+		res, err := h.Request(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if assert.True(t, res.Success) && assert.Equal(t, 1, len(res.Itineraries)) {
+			legs := res.Itineraries[0].Legs
+			if assert.Equal(t, 1, len(legs)) {
+				assert.Equal(t, model.StepModeWalk, *legs[0].Mode)
+			}
+		}
+	})
+	t.Run("no stops nearby", func(t *testing.T) {
+		req := model.DirectionRequest{
+			Mode:     model.StepModeTransit,
+			From:     &model.WaypointInput{Lat: 37.7894, Lon: -122.4011},
+			To:       &model.WaypointInput{Lat: 38.5, Lon: -121.5},
+			DepartAt: &departAt,
+		}
+		res, err := h.Request(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.False(t, res.Success)
+	})
+	t.Run("no service", func(t *testing.T) {
+		noService := time.Date(2025, 6, 2, 8, 0, 0, 0, loc)
+		req := model.DirectionRequest{
+			Mode:     model.StepModeTransit,
+			From:     &model.WaypointInput{Lat: 37.7894, Lon: -122.4011},
+			To:       &model.WaypointInput{Lat: 37.8701, Lon: -122.2681},
+			DepartAt: &noService,
+		}
+		res, err := h.Request(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.False(t, res.Success)
+	})
+	t.Run("unsupported mode", func(t *testing.T) {
+		req := model.DirectionRequest{
+			Mode: model.StepModeWalk,
+			From: &model.WaypointInput{Lat: 37.7894, Lon: -122.4011},
+			To:   &model.WaypointInput{Lat: 37.8701, Lon: -122.2681},
+		}
+		res, err := h.Request(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.False(t, res.Success)
+	})
+}
+
+// The timetable is reloaded when the active feed versions change.
+// The feed versions have only an agency; only the loaded feed versions are checked.
+func TestRouter_Reload(t *testing.T) {
+	ctx := context.Background()
+	writer, err := tldb.OpenWriter("sqlite3://:memory:", true)
+	require.NoError(t, err)
+	adapter := writer.Adapter
+	addFeedVersion := func(sha1 string) int {
+		var fvid int
+		q, args, err := adapter.Sqrl().Insert("feed_versions").Columns("feed_id", "sha1").Values(1, sha1).Suffix("RETURNING id").ToSql()
+		require.NoError(t, err)
+		require.NoError(t, adapter.DBX().QueryRowxContext(ctx, q, args...).Scan(&fvid))
+		_, err = adapter.Sqrl().
+			Insert("gtfs_agencies").
+			Columns("feed_version_id", "agency_id", "agency_name", "agency_url", "agency_timezone").
+			Values(fvid, "BART", "BART", "https://www.bart.gov", "America/Los_Angeles").
+			RunWith(adapter.DBX()).
+			ExecContext(ctx)
+		require.NoError(t, err)
+		return fvid
+	}
+	_, err = adapter.Sqrl().Insert("current_feeds").Columns("id", "onestop_id").Values(1, "BART").RunWith(adapter.DBX()).ExecContext(ctx)
+	require.NoError(t, err)
+	fv1 := addFeedVersion("fv1")
+	// The deprecated feed_version_id column is not used
+	_, err = adapter.Sqrl().
+		Insert("feed_states").
+		Columns("feed_id", "feed_version_id", "active_feed_version_id", "feed_realtime_enabled", "public").
+		Values(1, 0, fv1, false, true).
+		RunWith(adapter.DBX()).
+		ExecContext(ctx)
+	require.NoError(t, err)
+
+	h := &Router{}
+	rctx := model.WithConfig(ctx, model.Config{Adapter: adapter})
+	loaded := func() string {
+		timetable, err := h.getTimetable(rctx)
+		require.NoError(t, err)
+		require.Len(t, timetable.feeds, 1)
+		return timetable.feeds[0].FeedVersionSHA1
+	}
+	assert.Equal(t, "fv1", loaded())
+	first := h.timetable.Load()
+
+	// Unchanged feed versions are not reloaded
+	h.checkedAt.Store(0)
+	assert.Equal(t, "fv1", loaded())
+	assert.Same(t, first, h.timetable.Load())
+
+	// A new active feed version is loaded after the next check
+	fv2 := addFeedVersion("fv2")
+	_, err = adapter.Sqrl().Update("feed_states").Set("active_feed_version_id", fv2).RunWith(adapter.DBX()).ExecContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "fv1", loaded())
+	h.checkedAt.Store(0)
+	assert.Equal(t, "fv2", loaded())
+
+	// The current timetable is served while another request is reloading
+	h.checkedAt.Store(0)
+	h.reloadLock.Lock()
+	assert.Equal(t, "fv2", loaded())
+	h.reloadLock.Unlock()
+}
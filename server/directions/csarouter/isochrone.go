@@ -0,0 +1,266 @@
+package csarouter
+
+import (
+	"math"
+
+	"github.com/interline-io/transitland-lib/tlxy"
+	"github.com/twpayne/go-geom"
+)
+
+const (
+	isochroneMinCellSize = 25.0 // meters
+	isochroneMaxCells    = 500  // per side
+)
+
+// circle is a walking radius around a point, in meters.
+type circle struct {
+	center tlxy.Point
+	radius float64
+}
+
+// isochroneRaster is a grid of cells covered by a set of circles, in meters relative to an origin.
+type isochroneRaster struct {
+	approx   tlxy.Approx
+	origin   tlxy.Point
+	minX     float64
+	minY     float64
+	cellSize float64
+	nx       int
+	ny       int
+	cells    []bool
+}
+
+// gridVertex is a corner of a raster cell.
+type gridVertex struct {
+	x int
+	y int
+}
+
+// gridEdge is a cell side on the boundary of the covered area, directed so covered cells are on its left.
+type gridEdge struct {
+	from gridVertex
+	to   gridVertex
+}
+
+// isochronePolygon returns the union of circles as a MultiPolygon.
+// The union is approximated on a grid whose cells are at least isochroneMinCellSize meters.
+func isochronePolygon(origin tlxy.Point, circles []circle) *geom.MultiPolygon {
+	ret := geom.NewMultiPolygon(geom.XY)
+	r := newIsochroneRaster(origin, circles)
+	if r == nil {
+		return ret
+	}
+	for _, rings := range r.polygons() {
+		pg := geom.NewPolygon(geom.XY)
+		for _, ring := range rings {
+			var coords []float64
+			for _, v := range ring {
+				pt := r.point(v)
+				coords = append(coords, pt.Lon, pt.Lat)
+			}
+			if err := pg.Push(geom.NewLinearRingFlat(geom.XY, coords)); err != nil {
+				continue
+			}
+		}
+		if err := ret.Push(pg); err != nil {
+			continue
+		}
+	}
+	return ret
+}
+
+func newIsochroneRaster(origin tlxy.Point, circles []circle) *isochroneRaster {
+	r := &isochroneRaster{approx: tlxy.NewApprox(origin), origin: origin}
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, c := range circles {
+		if c.radius <= 0 {
+			continue
+		}
+		x, y := r.xy(c.center)
+		minX, minY = math.Min(minX, x-c.radius), math.Min(minY, y-c.radius)
+		maxX, maxY = math.Max(maxX, x+c.radius), math.Max(maxY, y+c.radius)
+	}
+	if math.IsInf(minX, 1) {
+		return nil
+	}
+	r.cellSize = math.Max(isochroneMinCellSize, math.Max(maxX-minX, maxY-minY)/isochroneMaxCells)
+	// Pad by one cell so the boundary is always closed
+	r.minX = minX - r.cellSize
+	r.minY = minY - r.cellSize
+	r.nx = int(math.Ceil((maxX-minX)/r.cellSize)) + 2
+	r.ny = int(math.Ceil((maxY-minY)/r.cellSize)) + 2
+	r.cells = make([]bool, r.nx*r.ny)
+	for _, c := range circles {
+		if c.radius <= 0 {
+			continue
+		}
+		cx, cy := r.xy(c.center)
+		x0 := max(0, int((cx-c.radius-r.minX)/r.cellSize))
+		x1 := min(r.nx-1, int((cx+c.radius-r.minX)/r.cellSize))
+		y0 := max(0, int((cy-c.radius-r.minY)/r.cellSize))
+		y1 := min(r.ny-1, int((cy+c.radius-r.minY)/r.cellSize))
+		for i := x0; i <= x1; i++ {
+			for j := y0; j <= y1; j++ {
+				// Cell centers within the radius are covered
+				dx := r.minX + (float64(i)+0.5)*r.cellSize - cx
+				dy := r.minY + (float64(j)+0.5)*r.cellSize - cy
+				if dx*dx+dy*dy <= c.radius*c.radius {
+					r.cells[i*r.ny+j] = true
+				}
+			}
+		}
+	}
+	return r
+}
+
+func (r *isochroneRaster) xy(pt tlxy.Point) (float64, float64) {
+	return (pt.Lon - r.origin.Lon) * r.approx.LonMeters(), (pt.Lat - r.origin.Lat) * r.approx.LatMeters()
+}
+
+func (r *isochroneRaster) point(v gridVertex) tlxy.Point {
+	return tlxy.Point{
+		Lon: r.origin.Lon + (r.minX+float64(v.x)*r.cellSize)/r.approx.LonMeters(),
+		Lat: r.origin.Lat + (r.minY+float64(v.y)*r.cellSize)/r.approx.LatMeters(),
+	}
+}
+
+func (r *isochroneRaster) covered(i, j int) bool {
+	if i < 0 || j < 0 || i >= r.nx || j >= r.ny {
+		return false
+	}
+	return r.cells[i*r.ny+j]
+}
+
+// polygons traces the boundary of the covered cells and returns polygons as
+// an exterior ring followed by its holes. Exterior rings are counter-clockwise.
+func (r *isochroneRaster) polygons() [][][]gridVertex {
+	// Collect boundary edges
+	edges := map[gridVertex][]gridEdge{}
+	addEdge := func(x0, y0, x1, y1 int) {
+		e := gridEdge{from: gridVertex{x0, y0}, to: gridVertex{x1, y1}}
+		edges[e.from] = append(edges[e.from], e)
+	}
+	for i := 0; i < r.nx; i++ {
+		for j := 0; j < r.ny; j++ {
+			if !r.covered(i, j) {
+				continue
+			}
+			if !r.covered(i, j-1) {
+				addEdge(i, j, i+1, j)
+			}
+			if !r.covered(i+1, j) {
+				addEdge(i+1, j, i+1, j+1)
+			}
+			if !r.covered(i, j+1) {
+				addEdge(i+1, j+1, i, j+1)
+			}
+			if !r.covered(i-1, j) {
+				addEdge(i, j+1, i, j)
+			}
+		}
+	}
+
+	// Link edges into rings. Where two cells touch only at a corner,
+	// turn left so each ring follows a single cell and rings do not cross.
+	var exteriors, holes [][]gridVertex
+	for i := 0; i < r.nx+1; i++ {
+		for j := 0; j < r.ny+1; j++ {
+			for len(edges[gridVertex{i, j}]) > 0 {
+				start := edges[gridVertex{i, j}][0]
+				ring := []gridVertex{start.from}
+				cur := start
+				removeEdge(edges, cur)
+				for cur.to != start.from {
+					next := edges[cur.to]
+					pick := 0
+					if len(next) > 1 {
+						dx, dy := cur.to.x-cur.from.x, cur.to.y-cur.from.y
+						for k, e := range next {
+							if e.to.x-e.from.x == -dy && e.to.y-e.from.y == dx {
+								pick = k
+							}
+						}
+					}
+					cur = next[pick]
+					removeEdge(edges, cur)
+					ring = append(ring, cur.from)
+				}
+				ring = simplifyRing(ring)
+				ring = append(ring, ring[0])
+				if ringArea(ring) > 0 {
+					exteriors = append(exteriors, ring)
+				} else {
+					holes = append(holes, ring)
+				}
+			}
+		}
+	}
+
+	// Assign each hole to the smallest exterior ring containing it
+	ret := make([][][]gridVertex, len(exteriors))
+	for i, ext := range exteriors {
+		ret[i] = [][]gridVertex{ext}
+	}
+	for _, hole := range holes {
+		// The right side of a hole's edge is inside the hole
+		a, b := hole[0], hole[1]
+		dx, dy := float64(b.x-a.x), float64(b.y-a.y)
+		px := float64(a.x) + dx/2 + dy/2
+		py := float64(a.y) + dy/2 - dx/2
+		best := -1
+		for i, ext := range exteriors {
+			if ringContains(ext, px, py) && (best < 0 || ringArea(ext) < ringArea(exteriors[best])) {
+				best = i
+			}
+		}
+		if best >= 0 {
+			ret[best] = append(ret[best], hole)
+		}
+	}
+	return ret
+}
+
+func removeEdge(edges map[gridVertex][]gridEdge, e gridEdge) {
+	v := edges[e.from]
+	for i := range v {
+		if v[i] == e {
+			edges[e.from] = append(v[:i], v[i+1:]...)
+			return
+		}
+	}
+}
+
+// simplifyRing removes vertices in the middle of straight runs from an open ring.
+func simplifyRing(ring []gridVertex) []gridVertex {
+	n := len(ring)
+	var ret []gridVertex
+	for i, v := range ring {
+		prev, next := ring[(i+n-1)%n], ring[(i+1)%n]
+		if (prev.x == v.x && v.x == next.x) || (prev.y == v.y && v.y == next.y) {
+			continue
+		}
+		ret = append(ret, v)
+	}
+	return ret
+}
+
+// ringArea returns the signed area of a closed ring; positive if counter-clockwise.
+func ringArea(ring []gridVertex) float64 {
+	a := 0
+	for i := 0; i < len(ring)-1; i++ {
+		a += ring[i].x*ring[i+1].y - ring[i+1].x*ring[i].y
+	}
+	return float64(a) / 2
+}
+
+func ringContains(ring []gridVertex, x, y float64) bool {
+	inside := false
+	for i := 0; i < len(ring)-1; i++ {
+		ax, ay := float64(ring[i].x), float64(ring[i].y)
+		bx, by := float64(ring[i+1].x), float64(ring[i+1].y)
+		if (ay > y) != (by > y) && x < ax+(y-ay)*(bx-ax)/(by-ay) {
+			inside = !inside
+		}
+	}
+	return inside
+}
@@ -0,0 +1,118 @@
+package csarouter
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/interline-io/log"
+	"github.com/interline-io/transitland-lib/server/model"
+	"github.com/interline-io/transitland-lib/tlxy"
+	"github.com/interline-io/transitland-lib/tt"
+)
+
+const (
+	defaultReachabilityCutoff = 1800  // seconds
+	maxReachabilityCutoff     = 10800 // seconds
+)
+
+// Reachability returns the stops reachable from the origin and an isochrone for each cutoff.
+func (h *Router) Reachability(ctx context.Context, req model.ReachabilityRequest) (*model.Reachability, error) {
+	ret := model.Reachability{
+		Origin:     wpiWaypoint(req.From),
+		Stops:      []*model.ReachableStop{},
+		Isochrones: []*model.Isochrone{},
+	}
+	if req.From == nil {
+		ret.Exception = aws.String("invalid input")
+		return &ret, nil
+	}
+	var cutoffs []int
+	for _, c := range req.Cutoffs {
+		if c <= 0 || c > maxReachabilityCutoff {
+			ret.Exception = aws.String("invalid cutoff")
+			return &ret, nil
+		}
+		cutoffs = append(cutoffs, c)
+	}
+	if len(cutoffs) == 0 {
+		cutoffs = []int{defaultReachabilityCutoff}
+	}
+	sort.Ints(cutoffs)
+	timetable, err := h.getTimetable(ctx)
+	if err != nil {
+		log.For(ctx).Error().Err(err).Msg("csarouter: failed to load timetable")
+		ret.Exception = aws.String("no timetable available")
+		return &ret, nil
+	}
+
+	// Prepare departure time
+	departAt := time.Now().In(time.UTC)
+	if h.Clock != nil {
+		departAt = h.Clock.Now()
+	}
+	if req.DepartAt != nil {
+		departAt = *req.DepartAt
+	}
+	departAt = departAt.In(time.UTC)
+	ret.DepartAt = &departAt
+
+	p := searchParams{
+		from:            tlxy.Point{Lon: req.From.Lon, Lat: req.From.Lat},
+		departAt:        departAt,
+		maxDuration:     time.Duration(cutoffs[len(cutoffs)-1]) * time.Second,
+		maxWalkDistance: h.MaxWalkDistance,
+	}
+	if req.MaxWalkDistance != nil && *req.MaxWalkDistance >= 0 {
+		p.maxWalkDistance = *req.MaxWalkDistance
+	}
+	if p.maxWalkDistance <= 0 {
+		p.maxWalkDistance = 1_000
+	}
+	arrival := timetable.reachable(p)
+
+	// Reached stops
+	start := departAt.Unix()
+	for i, a := range arrival {
+		if a == math.MaxInt64 {
+			continue
+		}
+		stop := timetable.stops[i]
+		feed := timetable.feeds[stop.feed]
+		ret.Stops = append(ret.Stops, &model.ReachableStop{
+			Lon:             stop.Point.Lon,
+			Lat:             stop.Point.Lat,
+			StopID:          stop.StopID,
+			StopName:        stop.StopName,
+			StopCode:        stop.StopCode,
+			FeedOnestopID:   feed.FeedOnestopID,
+			FeedVersionSha1: feed.FeedVersionSHA1,
+			ArrivalTime:     unixTime(a),
+			TravelTime:      int(a - start),
+		})
+	}
+	sort.SliceStable(ret.Stops, func(i, j int) bool {
+		return ret.Stops[i].TravelTime < ret.Stops[j].TravelTime
+	})
+
+	// Walk from the origin and from each stop for the remaining time
+	for _, cutoff := range cutoffs {
+		circles := []circle{{center: p.from, radius: math.Min(float64(cutoff)*timetable.WalkSpeed, p.maxWalkDistance)}}
+		for _, rs := range ret.Stops {
+			if remaining := cutoff - rs.TravelTime; remaining > 0 {
+				circles = append(circles, circle{
+					center: tlxy.Point{Lon: rs.Lon, Lat: rs.Lat},
+					radius: math.Min(float64(remaining)*timetable.WalkSpeed, p.maxWalkDistance),
+				})
+			}
+		}
+		ret.Isochrones = append(ret.Isochrones, &model.Isochrone{
+			Cutoff:   cutoff,
+			Geometry: tt.NewMultiPolygon(isochronePolygon(p.from, circles)),
+		})
+	}
+	ret.Success = true
+	return &ret, nil
+}
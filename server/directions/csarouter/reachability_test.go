@@ -0,0 +1,119 @@
+package csarouter
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/interline-io/transitland-lib/server/model"
+	"github.com/interline-io/transitland-lib/tlxy"
+	"github.com/interline-io/transitland-lib/tt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom/xy"
+)
+
+func TestRouter_Reachability(t *testing.T) {
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	require.NoError(t, err)
+	departAt := time.Date(2018, 6, 4, 8, 0, 0, 0, loc)
+	from := &model.WaypointInput{Lat: 37.7894, Lon: -122.4011}
+	h := newTestRouter(t)
+	t.Run("stops and isochrones", func(t *testing.T) {
+		res, err := h.Reachability(context.Background(), model.ReachabilityRequest{
+			From:     from,
+			DepartAt: &departAt,
+			Cutoffs:  []int{1800, 900},
+		})
+		require.NoError(t, err)
+		require.True(t, res.Success)
+		travelTimes := map[string]int{}
+		for i, s := range res.Stops {
+			if i > 0 {
+				assert.GreaterOrEqual(t, s.TravelTime, res.Stops[i-1].TravelTime)
+			}
+			assert.LessOrEqual(t, s.TravelTime, 1800)
+			assert.True(t, s.ArrivalTime.Equal(departAt.Add(time.Duration(s.TravelTime)*time.Second)))
+			travelTimes[s.StopID] = s.TravelTime
+		}
+		assert.Contains(t, travelTimes, "MONT")
+		assert.Contains(t, travelTimes, "DBRK")
+		assert.NotContains(t, travelTimes, "RICH")
+		if assert.Len(t, res.Isochrones, 2) {
+			short, long := res.Isochrones[0], res.Isochrones[1]
+			assert.Equal(t, 900, short.Cutoff)
+			assert.Equal(t, 1800, long.Cutoff)
+			assert.Greater(t, long.Geometry.Val.Area(), short.Geometry.Val.Area())
+			assert.True(t, multiPolygonContains(short.Geometry, tlxy.Point{Lon: from.Lon, Lat: from.Lat}))
+			assert.False(t, multiPolygonContains(short.Geometry, tlxy.Point{Lon: -122.2681, Lat: 37.8701}), "downtown berkeley")
+			assert.True(t, multiPolygonContains(long.Geometry, tlxy.Point{Lon: -122.2681, Lat: 37.8701}), "downtown berkeley")
+		}
+	})
+	t.Run("default cutoff", func(t *testing.T) {
+		res, err := h.Reachability(context.Background(), model.ReachabilityRequest{From: from, DepartAt: &departAt})
+		require.NoError(t, err)
+		if assert.Len(t, res.Isochrones, 1) {
+			assert.Equal(t, 1800, res.Isochrones[0].Cutoff)
+		}
+	})
+	t.Run("invalid cutoff", func(t *testing.T) {
+		res, err := h.Reachability(context.Background(), model.ReachabilityRequest{From: from, DepartAt: &departAt, Cutoffs: []int{100_000}})
+		require.NoError(t, err)
+		assert.False(t, res.Success)
+	})
+}
+
+func TestIsochronePolygon(t *testing.T) {
+	origin := tlxy.Point{Lon: -122.4, Lat: 37.8}
+	approx := tlxy.NewApprox(origin)
+	offset := func(dx, dy float64) tlxy.Point {
+		return tlxy.Point{Lon: origin.Lon + dx/approx.LonMeters(), Lat: origin.Lat + dy/approx.LatMeters()}
+	}
+	t.Run("overlapping", func(t *testing.T) {
+		mp := isochronePolygon(origin, []circle{{offset(0, 0), 300}, {offset(400, 0), 300}})
+		require.Equal(t, 1, mp.NumPolygons())
+		assert.Equal(t, 1, mp.Polygon(0).NumLinearRings())
+		// Two 300m circles overlapping by 200m
+		area := mp.Polygon(0).Area() * approx.LonMeters() * approx.LatMeters()
+		assert.InEpsilon(t, 2*math.Pi*300*300-2*(300*300*math.Acos(2.0/3.0)-200*math.Sqrt(300*300-200*200)), area, 0.05)
+	})
+	t.Run("disjoint", func(t *testing.T) {
+		mp := isochronePolygon(origin, []circle{{offset(0, 0), 300}, {offset(1000, 0), 300}})
+		assert.Equal(t, 2, mp.NumPolygons())
+	})
+	t.Run("hole", func(t *testing.T) {
+		var circles []circle
+		for i := 0; i < 16; i++ {
+			a := float64(i) * math.Pi / 8
+			circles = append(circles, circle{offset(600*math.Cos(a), 600*math.Sin(a)), 200})
+		}
+		mp := isochronePolygon(origin, circles)
+		require.Equal(t, 1, mp.NumPolygons())
+		assert.Equal(t, 2, mp.Polygon(0).NumLinearRings())
+		assert.False(t, multiPolygonContains(tt.NewMultiPolygon(mp), origin))
+		assert.True(t, multiPolygonContains(tt.NewMultiPolygon(mp), offset(600, 0)))
+	})
+	t.Run("empty", func(t *testing.T) {
+		assert.Equal(t, 0, isochronePolygon(origin, nil).NumPolygons())
+	})
+}
+
+func multiPolygonContains(mp tt.MultiPolygon, pt tlxy.Point) bool {
+	for i := 0; i < mp.Val.NumPolygons(); i++ {
+		pg := mp.Val.Polygon(i)
+		if !xy.IsPointInRing(pg.Layout(), []float64{pt.Lon, pt.Lat}, pg.LinearRing(0).FlatCoords()) {
+			continue
+		}
+		inHole := false
+		for j := 1; j < pg.NumLinearRings(); j++ {
+			if xy.IsPointInRing(pg.Layout(), []float64{pt.Lon, pt.Lat}, pg.LinearRing(j).FlatCoords()) {
+				inHole = true
+			}
+		}
+		if !inHole {
+			return true
+		}
+	}
+	return false
+}
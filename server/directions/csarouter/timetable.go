@@ -0,0 +1,340 @@
+package csarouter
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/interline-io/transitland-lib/adapters"
+	"github.com/interline-io/transitland-lib/gtfs"
+	"github.com/interline-io/transitland-lib/service"
+	"github.com/interline-io/transitland-lib/tlxy"
+)
+
+// Timetable holds the scheduled service used for routing.
+// Data from multiple feed versions can be loaded into the same Timetable.
+type Timetable struct {
+	MaxTransferDistance float64 // meters
+	WalkSpeed           float64 // meters per second
+	feeds               []ttFeed
+	stops               []ttStop
+	routes              []ttRoute
+	trips               []ttTrip
+	transfers           []ttTransfer
+	footpaths           [][]footpath
+	grid                map[gridCell][]int
+}
+
+type ttFeed struct {
+	FeedOnestopID   string
+	FeedVersionSHA1 string
+	services        map[string]*service.Service
+}
+
+type ttStop struct {
+	feed     int
+	StopID   string
+	StopName string
+	StopCode string
+	Point    tlxy.Point
+}
+
+type ttRoute struct {
+	RouteID        string
+	RouteShortName string
+	RouteLongName  string
+	RouteType      int
+	RouteColor     string
+	RouteTextColor string
+	AgencyID       string
+	AgencyName     string
+	loc            *time.Location
+}
+
+type ttTrip struct {
+	feed          int
+	route         int
+	serviceID     string
+	TripID        string
+	TripShortName string
+	Headsign      string
+	stopTimes     []ttStopTime
+	frequencies   []ttFrequency
+}
+
+type ttStopTime struct {
+	stop         int
+	arrival      int
+	departure    int
+	stopSequence int
+	pickup       bool
+	dropOff      bool
+}
+
+type ttFrequency struct {
+	start   int
+	end     int
+	headway int
+}
+
+type ttTransfer struct {
+	from            int
+	to              int
+	transferType    int
+	minTransferTime int
+}
+
+type footpath struct {
+	to       int
+	duration int
+	distance float64
+}
+
+// NewTimetable returns an empty Timetable with default walking parameters.
+func NewTimetable() *Timetable {
+	return &Timetable{
+		MaxTransferDistance: 400,
+		WalkSpeed:           1.2,
+		grid:                map[gridCell][]int{},
+	}
+}
+
+// LoadReader adds the stops, routes, trips, and services from a reader.
+// References between entities are resolved using EntityID, so both tlcsv and tldb readers are supported.
+func (t *Timetable) LoadReader(reader adapters.Reader, feedOnestopID string, fvsha1 string) error {
+	feedIdx := len(t.feeds)
+	feed := ttFeed{
+		FeedOnestopID:   feedOnestopID,
+		FeedVersionSHA1: fvsha1,
+		services:        map[string]*service.Service{},
+	}
+
+	// Agencies
+	type agencyInfo struct {
+		agencyID   string
+		agencyName string
+		loc        *time.Location
+	}
+	agencies := map[string]agencyInfo{}
+	var defaultAgency agencyInfo
+	for ent := range reader.Agencies() {
+		loc, err := time.LoadLocation(ent.AgencyTimezone.Val)
+		if err != nil {
+			return fmt.Errorf("invalid agency_timezone '%s': %w", ent.AgencyTimezone.Val, err)
+		}
+		a := agencyInfo{agencyID: ent.AgencyID.Val, agencyName: ent.AgencyName.Val, loc: loc}
+		if defaultAgency.loc == nil {
+			defaultAgency = a
+		}
+		agencies[ent.EntityID()] = a
+	}
+	if defaultAgency.loc == nil {
+		return fmt.Errorf("no agencies in feed '%s'", feedOnestopID)
+	}
+
+	// Routes
+	routeIdx := map[string]int{}
+	for ent := range reader.Routes() {
+		a, ok := agencies[ent.AgencyID.Val]
+		if !ok {
+			a = defaultAgency
+		}
+		routeIdx[ent.EntityID()] = len(t.routes)
+		t.routes = append(t.routes, ttRoute{
+			RouteID:        ent.RouteID.Val,
+			RouteShortName: ent.RouteShortName.Val,
+			RouteLongName:  ent.RouteLongName.Val,
+			RouteType:      ent.RouteType.Int(),
+			RouteColor:     ent.RouteColor.Val,
+			RouteTextColor: ent.RouteTextColor.Val,
+			AgencyID:       a.agencyID,
+			AgencyName:     a.agencyName,
+			loc:            a.loc,
+		})
+	}
+
+	// Stops
+	stopIdx := map[string]int{}
+	for ent := range reader.Stops() {
+		if ent.LocationType.Val != 0 {
+			continue
+		}
+		pt := ent.ToPoint()
+		if pt.Lon == 0 && pt.Lat == 0 {
+			pt = tlxy.Point{Lon: ent.StopLon.Val, Lat: ent.StopLat.Val}
+		}
+		stopIdx[ent.EntityID()] = len(t.stops)
+		t.stops = append(t.stops, ttStop{
+			feed:     feedIdx,
+			StopID:   ent.StopID.Val,
+			StopName: ent.StopName.Val,
+			StopCode: ent.StopCode.Val,
+			Point:    pt,
+		})
+	}
+
+	// Services
+	calendarDates := map[string][]gtfs.CalendarDate{}
+	for ent := range reader.CalendarDates() {
+		calendarDates[ent.ServiceID.Val] = append(calendarDates[ent.ServiceID.Val], ent)
+	}
+	for ent := range reader.Calendars() {
+		sid := ent.EntityID()
+		feed.services[sid] = service.NewService(ent, calendarDates[sid]...)
+		delete(calendarDates, sid)
+	}
+	for sid, cds := range calendarDates {
+		feed.services[sid] = service.NewService(gtfs.Calendar{}, cds...)
+	}
+
+	// Trips
+	tripIdx := map[string]int{}
+	for ent := range reader.Trips() {
+		ridx, ok := routeIdx[ent.RouteID.Val]
+		if !ok {
+			continue
+		}
+		tripIdx[ent.EntityID()] = len(t.trips)
+		t.trips = append(t.trips, ttTrip{
+			feed:          feedIdx,
+			route:         ridx,
+			serviceID:     ent.ServiceID.Val,
+			TripID:        ent.TripID.Val,
+			TripShortName: ent.TripShortName.Val,
+			Headsign:      ent.TripHeadsign.Val,
+		})
+	}
+	for sts := range reader.StopTimesByTripID() {
+		if len(sts) == 0 {
+			continue
+		}
+		tidx, ok := tripIdx[sts[0].TripID.Val]
+		if !ok {
+			continue
+		}
+		var trip []ttStopTime
+		for _, st := range sts {
+			sidx, ok := stopIdx[st.StopID.Val]
+			if !ok || !st.ArrivalTime.Valid || !st.DepartureTime.Valid {
+				// Skip flex stop times and untimed stops
+				continue
+			}
+			trip = append(trip, ttStopTime{
+				stop:         sidx,
+				arrival:      st.ArrivalTime.Int(),
+				departure:    st.DepartureTime.Int(),
+				stopSequence: st.StopSequence.Int(),
+				pickup:       st.PickupType.Val != 1,
+				dropOff:      st.DropOffType.Val != 1,
+			})
+		}
+		t.trips[tidx].stopTimes = trip
+	}
+	for ent := range reader.Frequencies() {
+		tidx, ok := tripIdx[ent.TripID.Val]
+		if !ok || ent.HeadwaySecs.Val <= 0 {
+			continue
+		}
+		t.trips[tidx].frequencies = append(t.trips[tidx].frequencies, ttFrequency{
+			start:   ent.StartTime.Int(),
+			end:     ent.EndTime.Int(),
+			headway: ent.HeadwaySecs.Int(),
+		})
+	}
+
+	// Transfers between stops
+	for ent := range reader.Transfers() {
+		from, ok1 := stopIdx[ent.FromStopID.Val]
+		to, ok2 := stopIdx[ent.ToStopID.Val]
+		if !ok1 || !ok2 || from == to {
+			continue
+		}
+		t.transfers = append(t.transfers, ttTransfer{
+			from:            from,
+			to:              to,
+			transferType:    ent.TransferType.Int(),
+			minTransferTime: ent.MinTransferTime.Int(),
+		})
+	}
+	t.feeds = append(t.feeds, feed)
+	t.buildFootpaths()
+	return nil
+}
+
+// walkTime returns the walking time in seconds for a distance in meters.
+func (t *Timetable) walkTime(distance float64) int {
+	return int(math.Ceil(distance / t.WalkSpeed))
+}
+
+// buildFootpaths creates straight-line walking transfers between nearby stops,
+// adjusted by transfers.txt.
+func (t *Timetable) buildFootpaths() {
+	t.grid = map[gridCell][]int{}
+	for i, stop := range t.stops {
+		cell := newGridCell(stop.Point)
+		t.grid[cell] = append(t.grid[cell], i)
+	}
+	type stopPair struct{ from, to int }
+	transfers := map[stopPair]ttTransfer{}
+	for _, tr := range t.transfers {
+		transfers[stopPair{tr.from, tr.to}] = tr
+	}
+	t.footpaths = make([][]footpath, len(t.stops))
+	for i, stop := range t.stops {
+		for _, j := range t.nearbyStops(stop.Point, t.MaxTransferDistance) {
+			if i == j {
+				continue
+			}
+			if _, ok := transfers[stopPair{i, j}]; ok {
+				continue
+			}
+			dist := tlxy.DistanceHaversine(stop.Point, t.stops[j].Point)
+			t.footpaths[i] = append(t.footpaths[i], footpath{to: j, duration: max(1, t.walkTime(dist)), distance: dist})
+		}
+	}
+	for pair, tr := range transfers {
+		if tr.transferType == 3 {
+			// Transfer not possible
+			continue
+		}
+		dist := tlxy.DistanceHaversine(t.stops[pair.from].Point, t.stops[pair.to].Point)
+		duration := max(1, t.walkTime(dist))
+		if tr.transferType == 2 {
+			duration = max(duration, tr.minTransferTime)
+		}
+		t.footpaths[pair.from] = append(t.footpaths[pair.from], footpath{to: pair.to, duration: duration, distance: dist})
+	}
+}
+
+// nearbyStops returns the stops within a radius of a point.
+func (t *Timetable) nearbyStops(pt tlxy.Point, radius float64) []int {
+	var ret []int
+	bbox := tlxy.BboxFromPointRadius(pt.Lon, pt.Lat, radius)
+	minCell := newGridCell(tlxy.Point{Lon: bbox.MinLon, Lat: bbox.MinLat})
+	maxCell := newGridCell(tlxy.Point{Lon: bbox.MaxLon, Lat: bbox.MaxLat})
+	for x := minCell.x; x <= maxCell.x; x++ {
+		for y := minCell.y; y <= maxCell.y; y++ {
+			for _, i := range t.grid[gridCell{x, y}] {
+				if tlxy.DistanceHaversine(pt, t.stops[i].Point) <= radius {
+					ret = append(ret, i)
+				}
+			}
+		}
+	}
+	return ret
+}
+
+// gridCell is a cell in a coarse spatial index of stops.
+type gridCell struct {
+	x int
+	y int
+}
+
+const gridCellSize = 0.01 // degrees
+
+func newGridCell(pt tlxy.Point) gridCell {
+	return gridCell{
+		x: int(math.Floor(pt.Lon / gridCellSize)),
+		y: int(math.Floor(pt.Lat / gridCellSize)),
+	}
+}
@@ -15,6 +15,11 @@ type Handler interface {
 	Request(context.Context, model.DirectionRequest) (*model.Directions, error)
 }
 
+// ReachabilityHandler is implemented by routers that support one-to-many reachability searches.
+type ReachabilityHandler interface {
+	Reachability(context.Context, model.ReachabilityRequest) (*model.Reachability, error)
+}
+
 type handlerFunc func() Handler
 
 var handlersLock sync.Mutex
@@ -38,6 +43,15 @@ func getHandler(name string) (handlerFunc, bool) {
 	return a, ok
 }
 
+// NewHandler returns the registered handler with the given name.
+func NewHandler(name string) (Handler, bool) {
+	hf, ok := getHandler(name)
+	if !ok {
+		return nil, false
+	}
+	return hf(), true
+}
+
 func HandleRequest(ctx context.Context, pref string, req model.DirectionRequest) (*model.Directions, error) {
 	// Default to walking
 	if !req.Mode.IsValid() {
@@ -76,6 +90,14 @@ func HandleRequest(ctx context.Context, pref string, req model.DirectionRequest)
 
 	// Call the handler
 	h, err := handler.Request(ctx, req)
+
+	// Calculate fares for transit itineraries
+	if finder := model.ForContext(ctx).Finder; err == nil && finder != nil && req.Mode == model.StepModeTransit {
+		if fareErr := applyFares(ctx, finder, h); fareErr != nil {
+			log.For(ctx).Error().Err(fareErr).Msg("failed to calculate itinerary fares")
+		}
+	}
+
 	a := log.For(ctx).Trace()
 	if err != nil {
 		a = log.For(ctx).Error().Err(err)
@@ -96,6 +118,33 @@ func HandleRequest(ctx context.Context, pref string, req model.DirectionRequest)
 	return h, err
 }
 
+// HandleReachabilityRequest answers a reachability request using the router named by
+// TL_ROUTER_REACHABILITY, falling back to the in-process "csa" router.
+func HandleReachabilityRequest(ctx context.Context, req model.ReachabilityRequest) (*model.Reachability, error) {
+	pref := os.Getenv("TL_ROUTER_REACHABILITY")
+	if pref == "" {
+		pref = "csa"
+	}
+	var handler ReachabilityHandler
+	if hf, ok := getHandler(pref); ok {
+		handler, _ = hf().(ReachabilityHandler)
+	}
+	if handler == nil {
+		a := "no reachability handler found"
+		return &model.Reachability{Success: false, Exception: &a, Stops: []*model.ReachableStop{}, Isochrones: []*model.Isochrone{}}, nil
+	}
+	h, err := handler.Reachability(ctx, req)
+	a := log.For(ctx).Trace()
+	if err != nil {
+		a = log.For(ctx).Error().Err(err)
+	}
+	if req.From != nil {
+		a = a.Float64("from_lat", req.From.Lat).Float64("from_lon", req.From.Lon)
+	}
+	a.Str("handler", pref).Ints("cutoffs", req.Cutoffs).Msg("reachability request")
+	return h, err
+}
+
 func ValidateDirectionRequest(req model.DirectionRequest) error {
 	if req.From == nil || req.To == nil {
 		return errors.New("from and to waypoints required")
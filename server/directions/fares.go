@@ -0,0 +1,287 @@
+package directions
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/interline-io/transitland-lib/fares"
+	"github.com/interline-io/transitland-lib/server/model"
+	"github.com/interline-io/transitland-lib/service"
+)
+
+// transitFareLegs returns the feed version and fare legs for the transit legs of an itinerary,
+// along with the index of each fare leg in the itinerary.
+// Itineraries that use more than one feed version are not priced.
+func transitFareLegs(itin *model.Itinerary) (string, []fares.Leg, []int) {
+	fvsha1 := ""
+	var legs []fares.Leg
+	var indexes []int
+	for i, leg := range itin.Legs {
+		if leg == nil || leg.Trip == nil || leg.Trip.Route == nil {
+			continue
+		}
+		if fvsha1 != "" && fvsha1 != leg.Trip.FeedVersionSha1 {
+			return "", nil, nil
+		}
+		fvsha1 = leg.Trip.FeedVersionSha1
+		fl := fares.Leg{
+			RouteID:   leg.Trip.Route.RouteID,
+			StartTime: leg.StartTime,
+			EndTime:   leg.EndTime,
+		}
+		if len(leg.Stops) > 0 {
+			fl.FromStopID = leg.Stops[0].StopID
+			fl.ToStopID = leg.Stops[len(leg.Stops)-1].StopID
+		} else {
+			if leg.From != nil && leg.From.Stop != nil {
+				fl.FromStopID = leg.From.Stop.StopID
+			}
+			if leg.To != nil && leg.To.Stop != nil {
+				fl.ToStopID = leg.To.Stop.StopID
+			}
+		}
+		legs = append(legs, fl)
+		indexes = append(indexes, i)
+	}
+	return fvsha1, legs, indexes
+}
+
+// applyFares sets calculated GTFS Fares v2 fares on each transit itinerary.
+func applyFares(ctx context.Context, finder model.Finder, d *model.Directions) error {
+	if d == nil {
+		return nil
+	}
+	// Collect the routes and stops used in each feed version
+	routeIDs := map[string][]string{}
+	stopIDs := map[string][]string{}
+	for _, itin := range d.Itineraries {
+		fvsha1, legs, _ := transitFareLegs(itin)
+		for _, leg := range legs {
+			routeIDs[fvsha1] = append(routeIDs[fvsha1], leg.RouteID)
+			stopIDs[fvsha1] = append(stopIDs[fvsha1], leg.FromStopID, leg.ToStopID)
+		}
+	}
+	fareData := map[string]*fares.Data{}
+	for fvsha1 := range routeIDs {
+		fd, err := loadFareData(ctx, finder, fvsha1, routeIDs[fvsha1], stopIDs[fvsha1])
+		if err != nil {
+			return err
+		}
+		fareData[fvsha1] = fd
+	}
+	for _, itin := range d.Itineraries {
+		fvsha1, legs, indexes := transitFareLegs(itin)
+		fd := fareData[fvsha1]
+		if fd == nil || len(legs) == 0 {
+			continue
+		}
+		for _, result := range fd.Calculate(legs) {
+			fare := &model.ItineraryFare{
+				RiderCategoryID: result.RiderCategoryID,
+				FareMediaID:     result.FareMediaID,
+				Amount:          result.Amount,
+				Currency:        result.Currency,
+			}
+			for _, lf := range result.Legs {
+				fareLeg := &model.ItineraryFareLeg{
+					FareProductID:   lf.FareProduct.FareProductID.Val,
+					FareProductName: lf.FareProduct.FareProductName.Ptr(),
+					Amount:          lf.Amount,
+				}
+				if lf.LegGroupID != "" {
+					fareLeg.LegGroupID = &lf.LegGroupID
+				}
+				if lf.TransferProduct != nil {
+					fareLeg.TransferFareProductID = &lf.TransferProduct.FareProductID.Val
+				}
+				for _, idx := range lf.LegIndexes {
+					fareLeg.LegIndexes = append(fareLeg.LegIndexes, indexes[idx])
+				}
+				fare.Legs = append(fare.Legs, fareLeg)
+			}
+			itin.Fares = append(itin.Fares, fare)
+		}
+	}
+	return nil
+}
+
+// loadFareData loads the fare data for a feed version, limited to the given routes and stops.
+// Returns nil if the feed version does not have fare products.
+func loadFareData(ctx context.Context, finder model.Finder, fvsha1 string, routeIDs []string, stopIDs []string) (*fares.Data, error) {
+	fvs, err := finder.FindFeedVersions(ctx, nil, nil, nil, &model.FeedVersionFilter{Sha1: &fvsha1})
+	if err != nil || len(fvs) == 0 {
+		return nil, err
+	}
+	fvid := fvs[0].ID
+	fvids := []int{fvid}
+	d := fares.NewData()
+
+	// Fare products reference fare media by internal ID
+	fareMedia, err := finder.FareMediaByFeedVersionIDs(ctx, nil, nil, fvids)
+	if err != nil {
+		return nil, err
+	}
+	fareMediaIDs := map[string]string{}
+	for _, ent := range fareMedia[0] {
+		fareMediaIDs[strconv.Itoa(ent.ID)] = ent.FareMediaID.Val
+	}
+	fareProducts, err := finder.FareProductsByFeedVersionIDs(ctx, nil, nil, fvids)
+	if err != nil {
+		return nil, err
+	}
+	if len(fareProducts[0]) == 0 {
+		return nil, nil
+	}
+	for _, ent := range fareProducts[0] {
+		fp := ent.FareProduct
+		if v, ok := fareMediaIDs[fp.FareMediaID.Val]; ok {
+			fp.FareMediaID.Set(v)
+		}
+		d.FareProducts = append(d.FareProducts, fp)
+	}
+	fareLegRules, err := finder.FareLegRulesByFeedVersionIDs(ctx, nil, nil, fvids)
+	if err != nil {
+		return nil, err
+	}
+	for _, ent := range fareLegRules[0] {
+		d.FareLegRules = append(d.FareLegRules, ent.FareLegRule)
+	}
+	fareLegJoinRules, err := finder.FareLegJoinRulesByFeedVersionIDs(ctx, nil, fvids)
+	if err != nil {
+		return nil, err
+	}
+	for _, ent := range fareLegJoinRules[0] {
+		d.FareLegJoinRules = append(d.FareLegJoinRules, ent.FareLegJoinRule)
+	}
+	fareTransferRules, err := finder.FareTransferRulesByFeedVersionIDs(ctx, nil, nil, fvids)
+	if err != nil {
+		return nil, err
+	}
+	for _, ent := range fareTransferRules[0] {
+		d.FareTransferRules = append(d.FareTransferRules, ent.FareTransferRule)
+	}
+
+	// Timeframes reference calendars by internal ID
+	timeframes, err := finder.TimeframesByFeedVersionIDs(ctx, nil, nil, fvids)
+	if err != nil {
+		return nil, err
+	}
+	var serviceIDs []int
+	for _, ent := range timeframes[0] {
+		d.Timeframes = append(d.Timeframes, ent.Timeframe)
+		serviceIDs = append(serviceIDs, ent.ServiceID.Int())
+	}
+	if len(serviceIDs) > 0 {
+		calendars, errs := finder.CalendarsByIDs(ctx, serviceIDs)
+		for _, err := range errs {
+			if err != nil {
+				return nil, err
+			}
+		}
+		calendarDates, err := finder.CalendarDatesByServiceIDs(ctx, nil, nil, serviceIDs)
+		if err != nil {
+			return nil, err
+		}
+		for i, ent := range calendars {
+			if ent == nil {
+				continue
+			}
+			svc := service.NewService(ent.Calendar)
+			for _, cd := range calendarDates[i] {
+				svc.AddCalendarDate(cd.CalendarDate)
+			}
+			d.Services[strconv.Itoa(serviceIDs[i])] = svc
+		}
+	}
+
+	// Timezone for timeframes
+	agencies, err := finder.AgenciesByFeedVersionIDs(ctx, nil, nil, fvids)
+	if err != nil {
+		return nil, err
+	}
+	if len(agencies[0]) > 0 {
+		if loc, err := time.LoadLocation(agencies[0][0].AgencyTimezone.Val); err == nil {
+			d.Location = loc
+		}
+	}
+
+	// Route networks, as referenced by fare leg rules
+	var routeKeys []model.FVEntityID
+	for _, routeID := range routeIDs {
+		routeKeys = append(routeKeys, model.FVEntityID{FeedVersionID: fvid, EntityID: routeID})
+	}
+	routes, errs := finder.RoutesByFeedVersionRouteIDs(ctx, routeKeys)
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	var routeIntIDs []int
+	routeGtfsIDs := map[int]string{}
+	for _, ent := range routes {
+		if ent == nil {
+			continue
+		}
+		if _, ok := routeGtfsIDs[ent.ID]; !ok {
+			routeIntIDs = append(routeIntIDs, ent.ID)
+			routeGtfsIDs[ent.ID] = ent.RouteID.Val
+		}
+	}
+	routeRules, err := finder.FareLegRulesByRouteIDs(ctx, nil, nil, routeIntIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i, ents := range routeRules {
+		routeID := routeGtfsIDs[routeIntIDs[i]]
+		for _, ent := range ents {
+			if !slices.Contains(d.RouteNetworks[routeID], ent.NetworkID.Val) {
+				d.RouteNetworks[routeID] = append(d.RouteNetworks[routeID], ent.NetworkID.Val)
+			}
+		}
+	}
+
+	// Stop areas, including the areas of parent stations
+	var stopKeys []model.FVEntityID
+	for _, stopID := range stopIDs {
+		stopKeys = append(stopKeys, model.FVEntityID{FeedVersionID: fvid, EntityID: stopID})
+	}
+	stops, errs := finder.StopsByFeedVersionStopIDs(ctx, stopKeys)
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	var stopIntIDs []int
+	stopGtfsIDs := map[int][]string{}
+	addStop := func(id int, stopID string) {
+		if _, ok := stopGtfsIDs[id]; !ok {
+			stopIntIDs = append(stopIntIDs, id)
+		}
+		stopGtfsIDs[id] = append(stopGtfsIDs[id], stopID)
+	}
+	seen := map[string]bool{}
+	for _, ent := range stops {
+		if ent == nil || seen[ent.StopID.Val] {
+			continue
+		}
+		seen[ent.StopID.Val] = true
+		addStop(ent.ID, ent.StopID.Val)
+		if ent.ParentStation.Valid {
+			addStop(ent.ParentStation.Int(), ent.StopID.Val)
+		}
+	}
+	stopAreas, err := finder.AreasByStopIDs(ctx, nil, stopIntIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i, ents := range stopAreas {
+		for _, stopID := range stopGtfsIDs[stopIntIDs[i]] {
+			for _, ent := range ents {
+				d.StopAreas[stopID] = append(d.StopAreas[stopID], ent.AreaID.Val)
+			}
+		}
+	}
+	return d, nil
+}
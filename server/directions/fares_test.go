@@ -0,0 +1,127 @@
+package directions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/interline-io/transitland-lib/fares"
+	"github.com/interline-io/transitland-lib/internal/testconfig"
+	"github.com/interline-io/transitland-lib/internal/testpath"
+	"github.com/interline-io/transitland-lib/server/model"
+	"github.com/interline-io/transitland-lib/server/testutil"
+	"github.com/interline-io/transitland-lib/tlcsv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransitFareLegs(t *testing.T) {
+	base := time.Date(2024, 6, 3, 8, 0, 0, 0, time.UTC)
+	walk := &model.Leg{StartTime: base, EndTime: base.Add(5 * time.Minute)}
+	transit := func(fvsha1 string, routeID string, from string, to string) *model.Leg {
+		return &model.Leg{
+			StartTime: base.Add(5 * time.Minute),
+			EndTime:   base.Add(20 * time.Minute),
+			Stops:     []*model.WaypointDeparture{{StopID: from}, {StopID: "mid"}, {StopID: to}},
+			Trip:      &model.LegTrip{FeedVersionSha1: fvsha1, Route: &model.LegRoute{RouteID: routeID}},
+		}
+	}
+	t.Run("transit legs", func(t *testing.T) {
+		itin := &model.Itinerary{Legs: []*model.Leg{walk, transit("abc", "r1", "a", "b"), walk, transit("abc", "r2", "c", "d"), walk}}
+		fvsha1, legs, indexes := transitFareLegs(itin)
+		assert.Equal(t, "abc", fvsha1)
+		assert.Equal(t, []int{1, 3}, indexes)
+		if assert.Equal(t, 2, len(legs)) {
+			assert.Equal(t, "r1", legs[0].RouteID)
+			assert.Equal(t, "a", legs[0].FromStopID)
+			assert.Equal(t, "b", legs[0].ToStopID)
+			assert.Equal(t, "r2", legs[1].RouteID)
+		}
+	})
+	t.Run("multiple feed versions", func(t *testing.T) {
+		itin := &model.Itinerary{Legs: []*model.Leg{transit("abc", "r1", "a", "b"), transit("def", "r2", "c", "d")}}
+		fvsha1, legs, _ := transitFareLegs(itin)
+		assert.Equal(t, "", fvsha1)
+		assert.Equal(t, 0, len(legs))
+	})
+	t.Run("walk only", func(t *testing.T) {
+		itin := &model.Itinerary{Legs: []*model.Leg{walk}}
+		_, legs, _ := transitFareLegs(itin)
+		assert.Equal(t, 0, len(legs))
+	})
+}
+
+func TestLoadFareData(t *testing.T) {
+	if msg, ok := testutil.CheckTestDB(); !ok {
+		t.Skip(msg)
+		return
+	}
+	ctx := context.Background()
+	cfg := testconfig.Config(t, testconfig.Options{})
+	feedOnestopID := "ctran-flex"
+	fvs, err := cfg.Finder.FindFeedVersions(ctx, nil, nil, nil, &model.FeedVersionFilter{FeedOnestopID: &feedOnestopID})
+	require.NoError(t, err)
+	require.NotEmpty(t, fvs)
+	fvsha1 := fvs[0].SHA1
+
+	// Fare data loaded from the source feed, for comparison
+	reader, err := tlcsv.NewReader(testpath.RelPath("testdata/gtfs-external/ctran-flex.zip"))
+	require.NoError(t, err)
+	expect, err := fares.NewDataFromReader(reader)
+	require.NoError(t, err)
+
+	routeID := "2bc6804f-9e24-4b91-8947-c73a2363e7b6"
+	stopID := "stop_id__09a24d02-a63c-4fa4-b625-c9ba13efd2c0"
+	t.Run("fares v2 feed", func(t *testing.T) {
+		d, err := loadFareData(ctx, cfg.Finder, fvsha1, []string{routeID, "unknown"}, []string{stopID})
+		require.NoError(t, err)
+		require.NotNil(t, d)
+		assert.Equal(t, len(expect.FareProducts), len(d.FareProducts))
+		assert.Equal(t, len(expect.FareLegRules), len(d.FareLegRules))
+		assert.Equal(t, len(expect.FareTransferRules), len(d.FareTransferRules))
+		assert.Equal(t, []string{"LOCAL"}, d.RouteNetworks[routeID])
+		assert.Equal(t, 0, len(d.RouteNetworks["unknown"]))
+		assert.Equal(t, 0, len(d.StopAreas[stopID]))
+		assert.Equal(t, expect.Location.String(), d.Location.String())
+		var fareMediaIDs []string
+		for _, fp := range d.FareProducts {
+			fareMediaIDs = append(fareMediaIDs, fp.FareMediaID.Val)
+		}
+		assert.Contains(t, fareMediaIDs, "0", "fare media should use gtfs ids")
+	})
+	t.Run("same fares as source feed", func(t *testing.T) {
+		d, err := loadFareData(ctx, cfg.Finder, fvsha1, []string{routeID}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, d)
+		start := time.Date(2024, 6, 3, 8, 0, 0, 0, d.Location)
+		legs := []fares.Leg{
+			{RouteID: routeID, StartTime: start, EndTime: start.Add(20 * time.Minute)},
+			{RouteID: routeID, StartTime: start.Add(time.Hour), EndTime: start.Add(80 * time.Minute)},
+		}
+		type resultKey struct {
+			riderCategoryID string
+			fareMediaID     string
+		}
+		amounts := func(results []fares.Result) map[resultKey]float64 {
+			ret := map[resultKey]float64{}
+			for _, r := range results {
+				ret[resultKey{r.RiderCategoryID, r.FareMediaID}] = r.Amount
+			}
+			return ret
+		}
+		expectAmounts := amounts(expect.Calculate(legs))
+		assert.NotEmpty(t, expectAmounts)
+		assert.Equal(t, expectAmounts, amounts(d.Calculate(legs)))
+	})
+	t.Run("feed version without fare products", func(t *testing.T) {
+		// Caltrain only has Fares v1 data
+		d, err := loadFareData(ctx, cfg.Finder, "d2813c293bcfd7a97dde599527ae6c62c98e66c6", nil, nil)
+		require.NoError(t, err)
+		assert.Nil(t, d)
+	})
+	t.Run("unknown feed version", func(t *testing.T) {
+		d, err := loadFareData(ctx, cfg.Finder, "unknown", nil, nil)
+		require.NoError(t, err)
+		assert.Nil(t, d)
+	})
+}
@@ -0,0 +1,587 @@
+// Package flexrouter is an in-process router for demand-responsive (GTFS-Flex) service.
+//
+// A flex trip can carry a rider between two of its stop times when the first
+// one's zone contains the origin and allows pickup, and the second one's zone
+// contains the destination and allows drop-off. A zone is a GTFS location
+// polygon, or the stops of a location group or a single stop within walking
+// distance. In-vehicle time is estimated from the straight-line driving time
+// and the trip's duration factors and offsets.
+package flexrouter
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/interline-io/log"
+	"github.com/interline-io/transitland-lib/internal/clock"
+	"github.com/interline-io/transitland-lib/server/directions"
+	"github.com/interline-io/transitland-lib/server/model"
+	"github.com/interline-io/transitland-lib/tldb"
+	"github.com/interline-io/transitland-lib/tlxy"
+	"github.com/interline-io/transitland-lib/tt"
+)
+
+func init() {
+	// The index is loaded from the database on the first request
+	router := &Router{}
+	if err := directions.RegisterRouter("flex", func() directions.Handler {
+		return router
+	}); err != nil {
+		panic(err)
+	}
+}
+
+// reloadInterval is how often a Router loaded from the database checks for
+// changes to the active feed versions.
+const reloadInterval = 1 * time.Minute
+
+// Router answers transit directions requests using flex trips from an Index.
+// If no Index is provided, the active feed versions with flex zones are loaded
+// from the request's database on first use, and reloaded when they change.
+//
+// Itineraries from a fixed-route Transit handler are merged with the flex
+// itineraries. If Transit is nil, the handler named by TL_ROUTER_FLEX_TRANSIT is used, if any.
+type Router struct {
+	Clock           clock.Clock
+	Transit         directions.Handler
+	MaxWalkDistance float64       // meters; used to reach stops of location groups
+	MaxDuration     time.Duration // maximum itinerary duration
+	MaxItineraries  int
+	DriveSpeed      float64 // meters per second; used to estimate in-vehicle time
+	WalkSpeed       float64 // meters per second
+	index           atomic.Pointer[Index]
+	static          bool         // index was provided, never reloaded
+	loadedKey       string       // active feed versions of the loaded index; guarded by reloadLock
+	checkedAt       atomic.Int64 // unix nanoseconds of the last check for changes
+	reloadLock      sync.Mutex   // held while checking for changes and loading a new Index
+}
+
+// NewRouter returns a Router for a loaded Index.
+func NewRouter(index *Index) *Router {
+	h := &Router{static: true}
+	h.index.Store(index)
+	return h
+}
+
+func (h *Router) Request(ctx context.Context, req model.DirectionRequest) (*model.Directions, error) {
+	// Prepare response
+	ret := model.Directions{
+		Origin:      wpiWaypoint(req.From),
+		Destination: wpiWaypoint(req.To),
+	}
+	if err := directions.ValidateDirectionRequest(req); err != nil {
+		ret.Exception = aws.String("invalid input")
+		return &ret, nil
+	}
+	if req.Mode != model.StepModeTransit {
+		ret.Exception = aws.String("unsupported travel mode")
+		return &ret, nil
+	}
+
+	// Prepare departure time
+	now := time.Now().In(time.UTC)
+	if h.Clock != nil {
+		now = h.Clock.Now()
+	}
+	departAt := now
+	if req.DepartAt != nil {
+		departAt = *req.DepartAt
+	}
+
+	p := searchParams{
+		from:            tlxy.Point{Lon: req.From.Lon, Lat: req.From.Lat},
+		to:              tlxy.Point{Lon: req.To.Lon, Lat: req.To.Lat},
+		now:             now.In(time.UTC),
+		departAt:        departAt.In(time.UTC),
+		maxDuration:     h.MaxDuration,
+		maxWalkDistance: h.MaxWalkDistance,
+		driveSpeed:      h.DriveSpeed,
+		walkSpeed:       h.WalkSpeed,
+	}
+	if p.maxDuration <= 0 {
+		p.maxDuration = 4 * time.Hour
+	}
+	if p.maxWalkDistance <= 0 {
+		p.maxWalkDistance = 1_000
+	}
+	if p.driveSpeed <= 0 {
+		p.driveSpeed = 11
+	}
+	if p.walkSpeed <= 0 {
+		p.walkSpeed = 1.2
+	}
+	maxItineraries := h.MaxItineraries
+	if maxItineraries <= 0 {
+		maxItineraries = 3
+	}
+
+	// Flex itineraries
+	if index, err := h.getIndex(ctx); err != nil {
+		log.For(ctx).Error().Err(err).Msg("flexrouter: failed to load index")
+	} else {
+		for _, opt := range index.search(p) {
+			ret.Itineraries = append(ret.Itineraries, index.makeItinerary(p, opt))
+		}
+	}
+
+	// Fixed-route itineraries
+	transit := h.Transit
+	if transit == nil {
+		if name := os.Getenv("TL_ROUTER_FLEX_TRANSIT"); name != "" && name != "flex" {
+			transit, _ = directions.NewHandler(name)
+		}
+	}
+	if transit != nil {
+		tres, err := transit.Request(ctx, req)
+		if err != nil {
+			log.For(ctx).Error().Err(err).Msg("flexrouter: transit handler failed")
+		} else if tres != nil && tres.Success {
+			ret.Itineraries = append(ret.Itineraries, tres.Itineraries...)
+		}
+	}
+
+	sort.SliceStable(ret.Itineraries, func(i, j int) bool {
+		return ret.Itineraries[i].EndTime.Before(ret.Itineraries[j].EndTime)
+	})
+	if len(ret.Itineraries) > maxItineraries {
+		ret.Itineraries = ret.Itineraries[:maxItineraries]
+	}
+	if len(ret.Itineraries) == 0 {
+		ret.Exception = aws.String("could not calculate route")
+		return &ret, nil
+	}
+	r0 := ret.Itineraries[0]
+	ret.Success = true
+	ret.DataSource = aws.String("Transitland")
+	ret.Duration = r0.Duration
+	ret.Distance = r0.Distance
+	ret.StartTime = &r0.StartTime
+	ret.EndTime = &r0.EndTime
+	return &ret, nil
+}
+
+// getIndex returns the loaded Index. An Index loaded from the database is
+// reloaded when the set of active feed versions has changed, checked at most
+// once every reloadInterval; the previous Index is kept if the check fails.
+func (h *Router) getIndex(ctx context.Context) (*Index, error) {
+	index := h.index.Load()
+	if index != nil && (h.static || !h.reloadDue()) {
+		return index, nil
+	}
+	// Requests are served from the current Index while it is reloaded;
+	// only the first load waits for the database.
+	if index == nil {
+		h.reloadLock.Lock()
+	} else if !h.reloadLock.TryLock() {
+		return index, nil
+	}
+	defer h.reloadLock.Unlock()
+	if index = h.index.Load(); index != nil && !h.reloadDue() {
+		return index, nil
+	}
+	adapter := model.ForContext(ctx).Adapter
+	if adapter == nil {
+		if index != nil {
+			return index, nil
+		}
+		return nil, errors.New("no database available")
+	}
+	fvs, err := activeFeedVersions(ctx, adapter)
+	if err == nil && (index == nil || feedVersionsKey(fvs) != h.loadedKey) {
+		var loaded *Index
+		if loaded, err = loadFeedVersions(ctx, adapter, fvs); err == nil {
+			index = loaded
+			h.index.Store(loaded)
+			h.loadedKey = feedVersionsKey(fvs)
+		}
+	}
+	if err != nil {
+		if index == nil {
+			return nil, err
+		}
+		log.For(ctx).Error().Err(err).Msg("flexrouter: failed to reload index, using previous index")
+	}
+	h.checkedAt.Store(time.Now().UnixNano())
+	return index, nil
+}
+
+// reloadDue checks if reloadInterval has passed since the last check for changes.
+func (h *Router) reloadDue() bool {
+	return time.Since(time.Unix(0, h.checkedAt.Load())) >= reloadInterval
+}
+
+type activeFeedVersion struct {
+	ID            int    `db:"id"`
+	SHA1          string `db:"sha1"`
+	FeedOnestopID string `db:"feed_onestop_id"`
+}
+
+// activeFeedVersions returns the active feed version for each feed that has
+// GTFS locations or location groups.
+func activeFeedVersions(ctx context.Context, adapter tldb.Adapter) ([]activeFeedVersion, error) {
+	q := adapter.Sqrl().
+		Select("feed_versions.id", "feed_versions.sha1", "current_feeds.onestop_id AS feed_onestop_id").
+		From("feed_states").
+		Join("feed_versions ON feed_versions.id = feed_states.active_feed_version_id").
+		Join("current_feeds ON current_feeds.id = feed_versions.feed_id").
+		Where("current_feeds.deleted_at IS NULL").
+		Where("(EXISTS (SELECT 1 FROM gtfs_locations WHERE gtfs_locations.feed_version_id = feed_versions.id) OR EXISTS (SELECT 1 FROM gtfs_location_groups WHERE gtfs_location_groups.feed_version_id = feed_versions.id))").
+		OrderBy("feed_versions.id")
+	qstr, args, err := q.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var fvs []activeFeedVersion
+	if err := adapter.Select(ctx, &fvs, qstr, args...); err != nil {
+		return nil, err
+	}
+	return fvs, nil
+}
+
+// feedVersionsKey identifies a set of feed versions, ordered by id.
+func feedVersionsKey(fvs []activeFeedVersion) string {
+	var ids []string
+	for _, fv := range fvs {
+		ids = append(ids, strconv.Itoa(fv.ID))
+	}
+	return strings.Join(ids, ",")
+}
+
+// LoadDatabase returns an Index with the active feed version for each feed
+// that has GTFS locations or location groups.
+func LoadDatabase(ctx context.Context, adapter tldb.Adapter) (*Index, error) {
+	fvs, err := activeFeedVersions(ctx, adapter)
+	if err != nil {
+		return nil, err
+	}
+	return loadFeedVersions(ctx, adapter, fvs)
+}
+
+func loadFeedVersions(ctx context.Context, adapter tldb.Adapter, fvs []activeFeedVersion) (*Index, error) {
+	index := NewIndex()
+	for _, fv := range fvs {
+		reader := &tldb.Reader{Adapter: adapter, PageSize: 1_000, FeedVersionIDs: []int{fv.ID}}
+		if err := index.LoadReader(reader, fv.FeedOnestopID, fv.SHA1); err != nil {
+			return nil, err
+		}
+		log.For(ctx).Trace().Str("feed_onestop_id", fv.FeedOnestopID).Str("feed_version_sha1", fv.SHA1).Msg("flexrouter: loaded feed version")
+	}
+	return index, nil
+}
+
+type searchParams struct {
+	from            tlxy.Point
+	to              tlxy.Point
+	now             time.Time
+	departAt        time.Time
+	maxDuration     time.Duration
+	maxWalkDistance float64
+	driveSpeed      float64
+	walkSpeed       float64
+}
+
+// flexOption is a ride on a flex trip between two of its stop times.
+// Times are unix seconds; durations are seconds.
+type flexOption struct {
+	trip         int
+	board        int
+	alight       int
+	fromStop     *flexStop
+	toStop       *flexStop
+	accessDist   float64
+	egressDist   float64
+	distance     float64
+	meanDuration int64
+	safeDuration int64
+	pickupStart  int64
+	pickupEnd    int64
+	dropOffStart int64
+	dropOffEnd   int64
+	arrival      int64
+}
+
+// search returns the earliest arriving ride on each flex trip serving the
+// origin and destination, earliest arrival first.
+func (idx *Index) search(p searchParams) []flexOption {
+	var ret []flexOption
+	departAt := p.departAt.Unix()
+	for tidx, trip := range idx.trips {
+		svc, ok := idx.feeds[trip.feed].services[trip.serviceID]
+		if !ok {
+			continue
+		}
+		var best *flexOption
+		local := p.departAt.In(trip.loc)
+		// Include the previous day for windows past midnight
+		for _, day := range []int{-1, 0} {
+			d := time.Date(local.Year(), local.Month(), local.Day()+day, 0, 0, 0, 0, time.UTC)
+			if !svc.IsActive(d) {
+				continue
+			}
+			// Service day begins at noon minus 12 hours, per GTFS
+			base := time.Date(d.Year(), d.Month(), d.Day(), 12, 0, 0, 0, trip.loc).Add(-12 * time.Hour).Unix()
+			for i, a := range trip.stopTimes {
+				if !a.pickup {
+					continue
+				}
+				fromStop, accessDist, ok := idx.zones[a.zone].access(p.from, p.maxWalkDistance)
+				if !ok {
+					continue
+				}
+				for j := i + 1; j < len(trip.stopTimes); j++ {
+					b := trip.stopTimes[j]
+					if !b.dropOff {
+						continue
+					}
+					toStop, egressDist, ok := idx.zones[b.zone].access(p.to, p.maxWalkDistance)
+					if !ok {
+						continue
+					}
+					opt := flexOption{
+						trip:       tidx,
+						board:      i,
+						alight:     j,
+						fromStop:   fromStop,
+						toStop:     toStop,
+						accessDist: accessDist,
+						egressDist: egressDist,
+					}
+					if !opt.schedule(p, trip, base, departAt) {
+						continue
+					}
+					if best == nil || opt.arrival < best.arrival {
+						best = &opt
+					}
+				}
+			}
+		}
+		if best != nil {
+			ret = append(ret, *best)
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].arrival < ret[j].arrival
+	})
+	return ret
+}
+
+// schedule computes the pickup and drop-off windows of a ride on the service
+// day beginning at base, and reports whether the ride is possible.
+//
+// The expected in-vehicle time is the driving time scaled by the boarding stop
+// time's mean_duration_factor and mean_duration_offset; the worst case uses
+// the trip's safe_duration_factor and safe_duration_offset, falling back to the
+// stop time's. Offsets are in minutes. Same-day booking rules with a minimum
+// prior notice delay the earliest pickup, and advance booking rules exclude
+// service days whose booking deadline has passed.
+func (opt *flexOption) schedule(p searchParams, trip flexTrip, base int64, departAt int64) bool {
+	a := trip.stopTimes[opt.board]
+	b := trip.stopTimes[opt.alight]
+	pickupPt, dropOffPt := p.from, p.to
+	if opt.fromStop != nil {
+		pickupPt = opt.fromStop.Point
+	}
+	if opt.toStop != nil {
+		dropOffPt = opt.toStop.Point
+	}
+	opt.distance = tlxy.DistanceHaversine(pickupPt, dropOffPt)
+	drive := opt.distance / p.driveSpeed
+	opt.meanDuration = flexDuration(drive, a.meanDurationFactor, a.meanDurationOffset)
+	safeFactor, safeOffset := trip.safeDurationFactor, trip.safeDurationOffset
+	if !safeFactor.Valid {
+		safeFactor, safeOffset = a.safeDurationFactor, a.safeDurationOffset
+	}
+	opt.safeDuration = max(opt.meanDuration, flexDuration(drive, safeFactor, safeOffset))
+
+	earliest := departAt + walkTime(opt.accessDist, p.walkSpeed)
+	if rule := a.pickupBookingRule; rule != nil && rule.BookingType == 1 && rule.PriorNoticeDurationMin != nil {
+		earliest = max(earliest, p.now.Unix()+int64(*rule.PriorNoticeDurationMin)*60)
+	}
+	if rule := a.pickupBookingRule; rule != nil && rule.BookingType == 2 && p.now.Unix() > bookingDeadline(rule, base, trip.loc) {
+		return false
+	}
+	opt.pickupStart = max(earliest, base+int64(a.start), base+int64(b.start)-opt.meanDuration)
+	opt.pickupEnd = min(base+int64(a.end), base+int64(b.end)-opt.meanDuration)
+	if opt.pickupStart > opt.pickupEnd {
+		return false
+	}
+	opt.dropOffStart = opt.pickupStart + opt.meanDuration
+	opt.dropOffEnd = max(opt.dropOffStart, min(opt.pickupEnd+opt.safeDuration, base+int64(b.end)))
+	opt.arrival = opt.dropOffStart + walkTime(opt.egressDist, p.walkSpeed)
+	return opt.arrival-departAt <= int64(p.maxDuration.Seconds())
+}
+
+// bookingDeadline returns the last time to book an advance booking rule for the
+// service day beginning at base: prior_notice_last_time on the day
+// prior_notice_last_day days before. Without a time, the whole day is allowed.
+func bookingDeadline(rule *model.LegBookingRule, base int64, loc *time.Location) int64 {
+	if rule.PriorNoticeLastDay == nil {
+		return math.MaxInt64
+	}
+	noon := time.Unix(base, 0).In(loc).Add(12 * time.Hour)
+	last := time.Date(noon.Year(), noon.Month(), noon.Day()-*rule.PriorNoticeLastDay, 12, 0, 0, 0, loc).Add(-12 * time.Hour).Unix()
+	if rule.PriorNoticeLastTime != nil && rule.PriorNoticeLastTime.Valid {
+		return last + int64(rule.PriorNoticeLastTime.Int())
+	}
+	return last + 24*3600
+}
+
+// flexDuration applies a duration factor and an offset in minutes to a driving time in seconds.
+func flexDuration(drive float64, factor tt.Float, offset tt.Float) int64 {
+	f := 1.0
+	if factor.Valid {
+		f = factor.Val
+	}
+	return int64(math.Ceil(drive*f + offset.Val*60))
+}
+
+func walkTime(distance float64, speed float64) int64 {
+	return int64(math.Ceil(distance / speed))
+}
+
+func (idx *Index) makeItinerary(p searchParams, opt flexOption) *model.Itinerary {
+	trip := idx.trips[opt.trip]
+	feed := idx.feeds[trip.feed]
+	a := trip.stopTimes[opt.board]
+	b := trip.stopTimes[opt.alight]
+	from := &model.Waypoint{Lon: p.from.Lon, Lat: p.from.Lat}
+	to := &model.Waypoint{Lon: p.to.Lon, Lat: p.to.Lat}
+	pickup := zoneWaypoint(idx.zones[a.zone], opt.fromStop, p.from, opt.pickupStart)
+	dropOff := zoneWaypoint(idx.zones[b.zone], opt.toStop, p.to, opt.dropOffStart)
+
+	var legs []*model.Leg
+	start := opt.pickupStart
+	distance := opt.distance
+	if opt.fromStop != nil {
+		start = opt.pickupStart - walkTime(opt.accessDist, p.walkSpeed)
+		legs = append(legs, makeWalkLeg(from, pickup, start, opt.pickupStart, opt.accessDist))
+		distance += opt.accessDist
+	}
+	mode := model.StepModeTransit
+	route := trip.route
+	leg := model.Leg{
+		StartTime: unixTime(opt.pickupStart),
+		EndTime:   unixTime(opt.dropOffStart),
+		Duration:  makeDuration(float64(opt.meanDuration)),
+		Distance:  makeDistance(opt.distance / 1000.0),
+		From:      pickup,
+		To:        dropOff,
+		Mode:      &mode,
+		Geometry: tt.NewLineStringFromFlatCoords([]float64{
+			pickup.Lon, pickup.Lat, 0.0,
+			dropOff.Lon, dropOff.Lat, 0.0,
+		}),
+		Trip: &model.LegTrip{
+			TripID:          trip.TripID,
+			TripShortName:   trip.TripShortName,
+			Headsign:        trip.Headsign,
+			FeedID:          feed.FeedOnestopID,
+			FeedVersionSha1: feed.FeedVersionSHA1,
+			Route:           &route,
+		},
+		Flex: &model.LegFlex{
+			PickupLocationID:   idx.zones[a.zone].ZoneID,
+			DropOffLocationID:  idx.zones[b.zone].ZoneID,
+			PickupWindowStart:  unixTime(opt.pickupStart),
+			PickupWindowEnd:    unixTime(opt.pickupEnd),
+			DropOffWindowStart: unixTime(opt.dropOffStart),
+			DropOffWindowEnd:   unixTime(opt.dropOffEnd),
+			MeanDuration:       makeDuration(float64(opt.meanDuration)),
+			SafeDuration:       makeDuration(float64(opt.safeDuration)),
+			PickupBookingRule:  a.pickupBookingRule,
+			DropOffBookingRule: b.dropOffBookingRule,
+		},
+	}
+	legs = append(legs, &leg)
+	if opt.toStop != nil {
+		distance += opt.egressDist
+		legs = append(legs, makeWalkLeg(dropOff, to, opt.dropOffStart, opt.arrival, opt.egressDist))
+	}
+	return &model.Itinerary{
+		From:      from,
+		To:        to,
+		StartTime: unixTime(start),
+		EndTime:   unixTime(opt.arrival),
+		Duration:  makeDuration(float64(opt.arrival - start)),
+		Distance:  makeDistance(distance / 1000.0),
+		Legs:      legs,
+	}
+}
+
+func makeWalkLeg(from *model.Waypoint, to *model.Waypoint, start int64, end int64, distance float64) *model.Leg {
+	mode := model.StepModeWalk
+	leg := model.Leg{
+		StartTime: unixTime(start),
+		EndTime:   unixTime(end),
+		Duration:  makeDuration(float64(end - start)),
+		Distance:  makeDistance(distance / 1000.0),
+		From:      from,
+		To:        to,
+		Mode:      &mode,
+		Geometry: tt.NewLineStringFromFlatCoords([]float64{
+			from.Lon, from.Lat, 0.0,
+			to.Lon, to.Lat, 0.0,
+		}),
+	}
+	leg.Steps = append(leg.Steps, &model.Step{
+		Duration:  leg.Duration,
+		Distance:  leg.Distance,
+		StartTime: leg.StartTime,
+		EndTime:   leg.EndTime,
+		To:        leg.To,
+		Mode:      model.StepModeWalk,
+	})
+	return &leg
+}
+
+// zoneWaypoint returns the stop used in a zone, or the point itself for a location polygon.
+func zoneWaypoint(zone flexZone, stop *flexStop, pt tlxy.Point, departure int64) *model.Waypoint {
+	if stop == nil {
+		return &model.Waypoint{Lon: pt.Lon, Lat: pt.Lat, Name: aws.String(zone.ZoneName)}
+	}
+	return &model.Waypoint{
+		Lon:  stop.Point.Lon,
+		Lat:  stop.Point.Lat,
+		Name: aws.String(stop.StopName),
+		Stop: &model.WaypointStop{
+			Lon:       stop.Point.Lon,
+			Lat:       stop.Point.Lat,
+			Departure: unixTime(departure),
+			StopID:    stop.StopID,
+			StopName:  stop.StopName,
+			StopCode:  stop.StopCode,
+		},
+	}
+}
+
+func unixTime(v int64) time.Time {
+	return time.Unix(v, 0).In(time.UTC)
+}
+
+func wpiWaypoint(w *model.WaypointInput) *model.Waypoint {
+	if w == nil {
+		return nil
+	}
+	return &model.Waypoint{
+		Lon:  w.Lon,
+		Lat:  w.Lat,
+		Name: w.Name,
+	}
+}
+
+func makeDuration(t float64) *model.Duration {
+	return &model.Duration{Duration: t, Units: model.DurationUnitSeconds}
+}
+
+func makeDistance(v float64) *model.Distance {
+	return &model.Distance{Distance: v, Units: model.DistanceUnitKilometers}
+}
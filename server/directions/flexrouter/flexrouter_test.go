@@ -0,0 +1,250 @@
+package flexrouter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/interline-io/transitland-lib/internal/clock"
+	"github.com/interline-io/transitland-lib/internal/testpath"
+	"github.com/interline-io/transitland-lib/server/model"
+	"github.com/interline-io/transitland-lib/tlcsv"
+	"github.com/interline-io/transitland-lib/tldb"
+	_ "github.com/interline-io/transitland-lib/tldb/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRouter(t testing.TB, now time.Time) *Router {
+	reader, err := tlcsv.NewReader(testpath.RelPath("testdata/flex/flex-routing"))
+	require.NoError(t, err)
+	index := NewIndex()
+	require.NoError(t, index.LoadReader(reader, "DAR", "test"))
+	h := NewRouter(index)
+	h.Clock = &clock.Mock{T: now}
+	return h
+}
+
+type testTransitHandler struct {
+	itin *model.Itinerary
+}
+
+func (h *testTransitHandler) Request(context.Context, model.DirectionRequest) (*model.Directions, error) {
+	return &model.Directions{Success: true, Itineraries: []*model.Itinerary{h.itin}}, nil
+}
+
+func TestRouter(t *testing.T) {
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	require.NoError(t, err)
+	at := func(s string) time.Time {
+		v, err := time.ParseInLocation("2006-01-02 15:04", s, loc)
+		require.NoError(t, err)
+		return v
+	}
+	west := &model.WaypointInput{Lat: 37.8200, Lon: -122.2800}
+	nearMainSt := &model.WaypointInput{Lat: 37.8060, Lon: -122.2980}
+	east := &model.WaypointInput{Lat: 37.8200, Lon: -122.2200}
+	request := func(from *model.WaypointInput, to *model.WaypointInput, departAt time.Time) model.DirectionRequest {
+		return model.DirectionRequest{Mode: model.StepModeTransit, From: from, To: to, DepartAt: &departAt}
+	}
+
+	t.Run("zone to zone", func(t *testing.T) {
+		h := newTestRouter(t, at("2024-06-03 08:00"))
+		res, err := h.Request(context.Background(), request(west, east, at("2024-06-03 09:00")))
+		require.NoError(t, err)
+		require.True(t, res.Success)
+		// The feeder's last pickup is 09:00, too early to walk to a stop
+		require.Len(t, res.Itineraries, 1)
+		itin := res.Itineraries[0]
+		require.Len(t, itin.Legs, 1)
+		leg := itin.Legs[0]
+		assert.Equal(t, model.StepModeTransit, *leg.Mode)
+		assert.Equal(t, "zone_am", leg.Trip.TripID)
+		assert.Equal(t, "zone", leg.Trip.Route.RouteID)
+		assert.Equal(t, "DAR", leg.Trip.FeedID)
+		flex := leg.Flex
+		require.NotNil(t, flex)
+		assert.Equal(t, "west", flex.PickupLocationID)
+		assert.Equal(t, "east", flex.DropOffLocationID)
+		assert.True(t, flex.PickupWindowStart.Equal(at("2024-06-03 09:00")))
+		// Mean in-vehicle time is the driving time; safe is 1.5x plus 5 minutes
+		assert.InDelta(t, 480, flex.MeanDuration.Duration, 10)
+		assert.InDelta(t, flex.MeanDuration.Duration*1.5+300, flex.SafeDuration.Duration, 2)
+		assert.True(t, flex.PickupWindowEnd.Equal(at("2024-06-03 12:00").Add(-time.Duration(flex.MeanDuration.Duration)*time.Second)))
+		assert.True(t, flex.DropOffWindowStart.Equal(leg.EndTime))
+		assert.True(t, flex.DropOffWindowEnd.Equal(at("2024-06-03 12:00")))
+		if assert.NotNil(t, flex.PickupBookingRule) {
+			assert.Equal(t, "realtime", flex.PickupBookingRule.BookingRuleID)
+			assert.Equal(t, "555-0100", *flex.PickupBookingRule.PhoneNumber)
+		}
+		if assert.NotNil(t, flex.DropOffBookingRule) {
+			assert.Equal(t, "realtime", flex.DropOffBookingRule.BookingRuleID)
+		}
+	})
+
+	t.Run("location group with prior notice", func(t *testing.T) {
+		h := newTestRouter(t, at("2024-06-03 07:30"))
+		res, err := h.Request(context.Background(), request(nearMainSt, east, at("2024-06-03 08:00")))
+		require.NoError(t, err)
+		require.True(t, res.Success)
+		require.Len(t, res.Itineraries, 2)
+		var feeder *model.Itinerary
+		for _, itin := range res.Itineraries {
+			for _, leg := range itin.Legs {
+				if leg.Trip != nil && leg.Trip.TripID == "feeder_am" {
+					feeder = itin
+				}
+			}
+		}
+		require.NotNil(t, feeder)
+		require.Len(t, feeder.Legs, 2)
+		walk, ride := feeder.Legs[0], feeder.Legs[1]
+		assert.Equal(t, model.StepModeWalk, *walk.Mode)
+		assert.Equal(t, "main_st", walk.To.Stop.StopID)
+		assert.Equal(t, "main_st", ride.From.Stop.StopID)
+		assert.Equal(t, "feeder_stops", ride.Flex.PickupLocationID)
+		// Booking requires an hour of notice
+		assert.True(t, ride.Flex.PickupWindowStart.Equal(at("2024-06-03 08:30")))
+		assert.True(t, ride.Flex.PickupWindowEnd.Equal(at("2024-06-03 09:00")))
+		if assert.NotNil(t, ride.Flex.PickupBookingRule) {
+			assert.Equal(t, "sameday", ride.Flex.PickupBookingRule.BookingRuleID)
+			assert.Equal(t, 60, *ride.Flex.PickupBookingRule.PriorNoticeDurationMin)
+		}
+		// No safe duration on the trip
+		assert.Equal(t, ride.Flex.MeanDuration.Duration, ride.Flex.SafeDuration.Duration)
+		// Earliest arrival first
+		assert.False(t, res.Itineraries[1].EndTime.Before(res.Itineraries[0].EndTime))
+	})
+
+	t.Run("advance booking", func(t *testing.T) {
+		// Bookable until 17:00 the day before
+		h := newTestRouter(t, at("2024-06-02 16:30"))
+		res, err := h.Request(context.Background(), request(west, east, at("2024-06-03 14:00")))
+		require.NoError(t, err)
+		require.True(t, res.Success)
+		leg := res.Itineraries[0].Legs[0]
+		assert.Equal(t, "zone_pm", leg.Trip.TripID)
+		if assert.NotNil(t, leg.Flex.PickupBookingRule) {
+			assert.Equal(t, "advance", leg.Flex.PickupBookingRule.BookingRuleID)
+			assert.Equal(t, 1, *leg.Flex.PickupBookingRule.PriorNoticeLastDay)
+		}
+	})
+
+	t.Run("advance booking after deadline", func(t *testing.T) {
+		h := newTestRouter(t, at("2024-06-02 17:30"))
+		res, err := h.Request(context.Background(), request(west, east, at("2024-06-03 14:00")))
+		require.NoError(t, err)
+		assert.False(t, res.Success)
+	})
+
+	t.Run("outside zones", func(t *testing.T) {
+		h := newTestRouter(t, at("2024-06-03 08:00"))
+		res, err := h.Request(context.Background(), request(west, &model.WaypointInput{Lat: 37.9, Lon: -122.1}, at("2024-06-03 09:00")))
+		require.NoError(t, err)
+		assert.False(t, res.Success)
+		assert.Equal(t, "could not calculate route", *res.Exception)
+	})
+
+	t.Run("no service", func(t *testing.T) {
+		h := newTestRouter(t, at("2024-06-01 08:00"))
+		res, err := h.Request(context.Background(), request(west, east, at("2024-06-01 09:00")))
+		require.NoError(t, err)
+		assert.False(t, res.Success)
+	})
+
+	t.Run("after window", func(t *testing.T) {
+		h := newTestRouter(t, at("2024-06-03 08:00"))
+		res, err := h.Request(context.Background(), request(west, east, at("2024-06-03 11:55")))
+		require.NoError(t, err)
+		assert.False(t, res.Success)
+	})
+
+	t.Run("merges transit itineraries", func(t *testing.T) {
+		h := newTestRouter(t, at("2024-06-03 08:00"))
+		fixed := &model.Itinerary{StartTime: at("2024-06-03 09:00"), EndTime: at("2024-06-03 09:05")}
+		h.Transit = &testTransitHandler{itin: fixed}
+		res, err := h.Request(context.Background(), request(west, east, at("2024-06-03 09:00")))
+		require.NoError(t, err)
+		require.True(t, res.Success)
+		require.Len(t, res.Itineraries, 2)
+		assert.Same(t, fixed, res.Itineraries[0])
+		assert.NotNil(t, res.Itineraries[1].Legs[0].Flex)
+	})
+
+	t.Run("unsupported mode", func(t *testing.T) {
+		h := newTestRouter(t, at("2024-06-03 08:00"))
+		req := request(west, east, at("2024-06-03 09:00"))
+		req.Mode = model.StepModeWalk
+		res, err := h.Request(context.Background(), req)
+		require.NoError(t, err)
+		assert.False(t, res.Success)
+	})
+}
+
+// The index is reloaded when the active feed versions change.
+// The feed versions have only an agency and a location group; only the loaded feed versions are checked.
+func TestRouter_Reload(t *testing.T) {
+	ctx := context.Background()
+	writer, err := tldb.OpenWriter("sqlite3://:memory:", true)
+	require.NoError(t, err)
+	adapter := writer.Adapter
+	exec := func(q interface {
+		ToSql() (string, []any, error)
+	}) {
+		qstr, args, err := q.ToSql()
+		require.NoError(t, err)
+		_, err = adapter.DBX().ExecContext(ctx, qstr, args...)
+		require.NoError(t, err)
+	}
+	addFeedVersion := func(sha1 string) int {
+		var fvid int
+		q, args, err := adapter.Sqrl().Insert("feed_versions").Columns("feed_id", "sha1").Values(1, sha1).Suffix("RETURNING id").ToSql()
+		require.NoError(t, err)
+		require.NoError(t, adapter.DBX().QueryRowxContext(ctx, q, args...).Scan(&fvid))
+		exec(adapter.Sqrl().
+			Insert("gtfs_agencies").
+			Columns("feed_version_id", "agency_id", "agency_name", "agency_url", "agency_timezone").
+			Values(fvid, "dar", "DAR", "https://example.com", "America/Los_Angeles"))
+		exec(adapter.Sqrl().
+			Insert("gtfs_location_groups").
+			Columns("feed_version_id", "location_group_id").
+			Values(fvid, "feeder_stops"))
+		return fvid
+	}
+	exec(adapter.Sqrl().Insert("current_feeds").Columns("id", "onestop_id").Values(1, "DAR"))
+	fv1 := addFeedVersion("fv1")
+	// The deprecated feed_version_id column is not used
+	exec(adapter.Sqrl().
+		Insert("feed_states").
+		Columns("feed_id", "feed_version_id", "active_feed_version_id", "feed_realtime_enabled", "public").
+		Values(1, 0, fv1, false, true))
+
+	h := &Router{}
+	rctx := model.WithConfig(ctx, model.Config{Adapter: adapter})
+	loaded := func() string {
+		index, err := h.getIndex(rctx)
+		require.NoError(t, err)
+		require.Len(t, index.feeds, 1)
+		return index.feeds[0].FeedVersionSHA1
+	}
+	assert.Equal(t, "fv1", loaded())
+	first := h.index.Load()
+
+	// Unchanged feed versions are not reloaded
+	h.checkedAt.Store(0)
+	assert.Equal(t, "fv1", loaded())
+	assert.Same(t, first, h.index.Load())
+
+	// A new active feed version is loaded after the next check
+	fv2 := addFeedVersion("fv2")
+	exec(adapter.Sqrl().Update("feed_states").Set("active_feed_version_id", fv2))
+	assert.Equal(t, "fv1", loaded())
+	h.checkedAt.Store(0)
+	assert.Equal(t, "fv2", loaded())
+
+	// The current index is served while another request is reloading
+	h.checkedAt.Store(0)
+	h.reloadLock.Lock()
+	assert.Equal(t, "fv2", loaded())
+	h.reloadLock.Unlock()
+}
@@ -0,0 +1,358 @@
+package flexrouter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/interline-io/transitland-lib/adapters"
+	"github.com/interline-io/transitland-lib/gtfs"
+	"github.com/interline-io/transitland-lib/server/model"
+	"github.com/interline-io/transitland-lib/service"
+	"github.com/interline-io/transitland-lib/tlxy"
+	"github.com/interline-io/transitland-lib/tt"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/xy"
+)
+
+// Index holds the demand-responsive service used for flex routing.
+// Data from multiple feed versions can be loaded into the same Index.
+type Index struct {
+	feeds []flexFeed
+	zones []flexZone
+	trips []flexTrip
+}
+
+type flexFeed struct {
+	FeedOnestopID   string
+	FeedVersionSHA1 string
+	services        map[string]*service.Service
+}
+
+// flexZone is where a flex stop time picks up or drops off: the polygons of a
+// GTFS location, or the stops of a location group or a single stop.
+type flexZone struct {
+	ZoneID   string
+	ZoneName string
+	polygons []*geom.Polygon
+	stops    []flexStop
+}
+
+type flexStop struct {
+	StopID   string
+	StopName string
+	StopCode string
+	Point    tlxy.Point
+}
+
+type flexTrip struct {
+	feed               int
+	serviceID          string
+	TripID             string
+	TripShortName      string
+	Headsign           string
+	route              model.LegRoute
+	loc                *time.Location
+	safeDurationFactor tt.Float
+	safeDurationOffset tt.Float
+	stopTimes          []flexStopTime
+}
+
+type flexStopTime struct {
+	zone               int
+	start              int
+	end                int
+	stopSequence       int
+	pickup             bool
+	dropOff            bool
+	pickupBookingRule  *model.LegBookingRule
+	dropOffBookingRule *model.LegBookingRule
+	meanDurationFactor tt.Float
+	meanDurationOffset tt.Float
+	safeDurationFactor tt.Float
+	safeDurationOffset tt.Float
+}
+
+// NewIndex returns an empty Index.
+func NewIndex() *Index {
+	return &Index{}
+}
+
+// LoadReader adds the flex trips, zones, and services from a reader.
+// Only stop times with a pickup/drop-off window are loaded; trips without any are skipped.
+// References between entities are resolved using EntityID, so both tlcsv and tldb readers are supported.
+func (idx *Index) LoadReader(reader adapters.Reader, feedOnestopID string, fvsha1 string) error {
+	feedIdx := len(idx.feeds)
+	feed := flexFeed{
+		FeedOnestopID:   feedOnestopID,
+		FeedVersionSHA1: fvsha1,
+		services:        map[string]*service.Service{},
+	}
+
+	// Agencies
+	type agencyInfo struct {
+		agencyID   string
+		agencyName string
+		loc        *time.Location
+	}
+	agencies := map[string]agencyInfo{}
+	var defaultAgency agencyInfo
+	for ent := range reader.Agencies() {
+		loc, err := time.LoadLocation(ent.AgencyTimezone.Val)
+		if err != nil {
+			return fmt.Errorf("invalid agency_timezone '%s': %w", ent.AgencyTimezone.Val, err)
+		}
+		a := agencyInfo{agencyID: ent.AgencyID.Val, agencyName: ent.AgencyName.Val, loc: loc}
+		if defaultAgency.loc == nil {
+			defaultAgency = a
+		}
+		agencies[ent.EntityID()] = a
+	}
+	if defaultAgency.loc == nil {
+		return fmt.Errorf("no agencies in feed '%s'", feedOnestopID)
+	}
+
+	// Routes
+	type routeInfo struct {
+		route model.LegRoute
+		loc   *time.Location
+	}
+	routes := map[string]routeInfo{}
+	for ent := range reader.Routes() {
+		a, ok := agencies[ent.AgencyID.Val]
+		if !ok {
+			a = defaultAgency
+		}
+		routes[ent.EntityID()] = routeInfo{
+			route: model.LegRoute{
+				RouteID:        ent.RouteID.Val,
+				RouteShortName: ent.RouteShortName.Val,
+				RouteLongName:  ent.RouteLongName.Val,
+				RouteType:      ent.RouteType.Int(),
+				RouteColor:     aws.String(ent.RouteColor.Val),
+				RouteTextColor: aws.String(ent.RouteTextColor.Val),
+				Agency: &model.LegRouteAgency{
+					AgencyID:   a.agencyID,
+					AgencyName: a.agencyName,
+				},
+			},
+			loc: a.loc,
+		}
+	}
+
+	// Zones are created on first use by a flex stop time
+	stops := map[string]flexStop{}
+	for ent := range reader.Stops() {
+		if ent.LocationType.Val != 0 {
+			continue
+		}
+		pt := ent.ToPoint()
+		if pt.Lon == 0 && pt.Lat == 0 {
+			pt = tlxy.Point{Lon: ent.StopLon.Val, Lat: ent.StopLat.Val}
+		}
+		stops[ent.EntityID()] = flexStop{
+			StopID:   ent.StopID.Val,
+			StopName: ent.StopName.Val,
+			StopCode: ent.StopCode.Val,
+			Point:    pt,
+		}
+	}
+	locations := map[string]flexZone{}
+	for ent := range reader.Locations() {
+		if !ent.Geometry.Valid {
+			continue
+		}
+		z := flexZone{ZoneID: ent.LocationID.Val, ZoneName: ent.StopName.Val}
+		switch g := ent.Geometry.Val.(type) {
+		case *geom.Polygon:
+			z.polygons = append(z.polygons, g)
+		case *geom.MultiPolygon:
+			for i := 0; i < g.NumPolygons(); i++ {
+				z.polygons = append(z.polygons, g.Polygon(i))
+			}
+		}
+		locations[ent.EntityID()] = z
+	}
+	groups := map[string]flexZone{}
+	for ent := range reader.LocationGroups() {
+		groups[ent.EntityID()] = flexZone{ZoneID: ent.LocationGroupID.Val, ZoneName: ent.LocationGroupName.Val}
+	}
+	for ent := range reader.LocationGroupStops() {
+		g, ok := groups[ent.LocationGroupID.Val]
+		stop, ok2 := stops[ent.StopID.Val]
+		if !ok || !ok2 {
+			continue
+		}
+		g.stops = append(g.stops, stop)
+		groups[ent.LocationGroupID.Val] = g
+	}
+	zoneIdx := map[string]int{}
+	getZone := func(st gtfs.StopTime) (int, bool) {
+		var key string
+		var z flexZone
+		var ok bool
+		switch {
+		case st.LocationID.Val != "":
+			key = "location:" + st.LocationID.Val
+			z, ok = locations[st.LocationID.Val]
+		case st.LocationGroupID.Val != "":
+			key = "group:" + st.LocationGroupID.Val
+			z, ok = groups[st.LocationGroupID.Val]
+		case st.StopID.Val != "":
+			key = "stop:" + st.StopID.Val
+			var stop flexStop
+			stop, ok = stops[st.StopID.Val]
+			z = flexZone{ZoneID: stop.StopID, ZoneName: stop.StopName, stops: []flexStop{stop}}
+		}
+		if !ok {
+			return 0, false
+		}
+		if i, ok := zoneIdx[key]; ok {
+			return i, true
+		}
+		zoneIdx[key] = len(idx.zones)
+		idx.zones = append(idx.zones, z)
+		return zoneIdx[key], true
+	}
+
+	// Booking rules
+	bookingRules := map[string]*model.LegBookingRule{}
+	for ent := range reader.BookingRules() {
+		bookingRules[ent.EntityID()] = &model.LegBookingRule{
+			BookingRuleID:          ent.BookingRuleID.Val,
+			BookingType:            ent.BookingType.Int(),
+			PriorNoticeDurationMin: intPtr(ent.PriorNoticeDurationMin),
+			PriorNoticeDurationMax: intPtr(ent.PriorNoticeDurationMax),
+			PriorNoticeLastDay:     intPtr(ent.PriorNoticeLastDay),
+			PriorNoticeLastTime:    secondsPtr(ent.PriorNoticeLastTime),
+			Message:                ent.Message.Ptr(),
+			PickupMessage:          ent.PickupMessage.Ptr(),
+			DropOffMessage:         ent.DropOffMessage.Ptr(),
+			PhoneNumber:            ent.PhoneNumber.Ptr(),
+			InfoURL:                ent.InfoURL.Ptr(),
+			BookingURL:             ent.BookingURL.Ptr(),
+		}
+	}
+
+	// Services
+	calendarDates := map[string][]gtfs.CalendarDate{}
+	for ent := range reader.CalendarDates() {
+		calendarDates[ent.ServiceID.Val] = append(calendarDates[ent.ServiceID.Val], ent)
+	}
+	for ent := range reader.Calendars() {
+		sid := ent.EntityID()
+		feed.services[sid] = service.NewService(ent, calendarDates[sid]...)
+		delete(calendarDates, sid)
+	}
+	for sid, cds := range calendarDates {
+		feed.services[sid] = service.NewService(gtfs.Calendar{}, cds...)
+	}
+
+	// Trips
+	trips := map[string]flexTrip{}
+	for ent := range reader.Trips() {
+		r, ok := routes[ent.RouteID.Val]
+		if !ok {
+			continue
+		}
+		trips[ent.EntityID()] = flexTrip{
+			feed:               feedIdx,
+			serviceID:          ent.ServiceID.Val,
+			TripID:             ent.TripID.Val,
+			TripShortName:      ent.TripShortName.Val,
+			Headsign:           ent.TripHeadsign.Val,
+			route:              r.route,
+			loc:                r.loc,
+			safeDurationFactor: ent.SafeDurationFactor,
+			safeDurationOffset: ent.SafeDurationOffset,
+		}
+	}
+	for sts := range reader.StopTimesByTripID() {
+		if len(sts) == 0 {
+			continue
+		}
+		trip, ok := trips[sts[0].TripID.Val]
+		if !ok {
+			continue
+		}
+		for _, st := range sts {
+			if !st.StartPickupDropOffWindow.Valid || !st.EndPickupDropOffWindow.Valid {
+				// Skip timed stops
+				continue
+			}
+			zidx, ok := getZone(st)
+			if !ok {
+				continue
+			}
+			trip.stopTimes = append(trip.stopTimes, flexStopTime{
+				zone:               zidx,
+				start:              st.StartPickupDropOffWindow.Int(),
+				end:                st.EndPickupDropOffWindow.Int(),
+				stopSequence:       st.StopSequence.Int(),
+				pickup:             st.PickupType.Val != 1,
+				dropOff:            st.DropOffType.Val != 1,
+				pickupBookingRule:  bookingRules[st.PickupBookingRuleID.Val],
+				dropOffBookingRule: bookingRules[st.DropOffBookingRuleID.Val],
+				meanDurationFactor: st.MeanDurationFactor,
+				meanDurationOffset: st.MeanDurationOffset,
+				safeDurationFactor: st.SafeDurationFactor,
+				safeDurationOffset: st.SafeDurationOffset,
+			})
+		}
+		if len(trip.stopTimes) > 0 {
+			idx.trips = append(idx.trips, trip)
+		}
+	}
+	idx.feeds = append(idx.feeds, feed)
+	return nil
+}
+
+// access returns where a rider at pt boards or alights in the zone, and the
+// walking distance there: pt itself for a location polygon containing it,
+// otherwise the nearest stop within maxWalkDistance.
+func (z *flexZone) access(pt tlxy.Point, maxWalkDistance float64) (*flexStop, float64, bool) {
+	gp := []float64{pt.Lon, pt.Lat}
+	for _, pg := range z.polygons {
+		if pointInPolygon(pg, gp) {
+			return nil, 0, true
+		}
+	}
+	var ret *flexStop
+	minDist := -1.0
+	for i := range z.stops {
+		d := tlxy.DistanceHaversine(pt, z.stops[i].Point)
+		if d <= maxWalkDistance && (d < minDist || minDist < 0) {
+			ret = &z.stops[i]
+			minDist = d
+		}
+	}
+	return ret, minDist, ret != nil
+}
+
+// pointInPolygon tests if a point is inside a polygon's outer ring but not in its holes.
+func pointInPolygon(pg *geom.Polygon, p []float64) bool {
+	if pg.NumLinearRings() == 0 || !xy.IsPointInRing(pg.Layout(), p, pg.LinearRing(0).FlatCoords()) {
+		return false
+	}
+	for i := 1; i < pg.NumLinearRings(); i++ {
+		if xy.IsPointInRing(pg.Layout(), p, pg.LinearRing(i).FlatCoords()) {
+			return false
+		}
+	}
+	return true
+}
+
+func secondsPtr(v tt.Seconds) *tt.Seconds {
+	if !v.Valid {
+		return nil
+	}
+	return &v
+}
+
+func intPtr(v tt.Int) *int {
+	if !v.Valid {
+		return nil
+	}
+	i := v.Int()
+	return &i
+}
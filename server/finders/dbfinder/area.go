@@ -0,0 +1,84 @@
+package dbfinder
+
+import (
+	"context"
+
+	"github.com/interline-io/transitland-lib/server/dbutil"
+	"github.com/interline-io/transitland-lib/server/model"
+	sq "github.com/irees/squirrel"
+)
+
+func (f *Finder) AreasByFeedVersionIDs(ctx context.Context, limit *int, where *model.AreaFilter, keys []int) ([][]*model.Area, error) {
+	var ents []*model.Area
+	q := areaSelect(limit, nil, nil, where).Where(In("gtfs_areas.feed_version_id", keys))
+	err := dbutil.Select(ctx, f.db, q, &ents)
+	return arrangeGroup(keys, ents, func(ent *model.Area) int { return ent.FeedVersionID }), err
+}
+
+func (f *Finder) AreasByFeedVersionAreaIDs(ctx context.Context, keys []model.FVEntityID) ([]*model.Area, []error) {
+	var ents []*model.Area
+	for fvid, areaIds := range groupFVEntityIDs(keys) {
+		q := areaSelect(nil, nil, nil, nil).
+			Where(sq.Eq{"gtfs_areas.feed_version_id": fvid}).
+			Where(In("gtfs_areas.area_id", areaIds))
+		var group []*model.Area
+		if err := dbutil.Select(ctx, f.db, q, &group); err != nil {
+			return nil, logExtendErr(ctx, len(keys), err)
+		}
+		ents = append(ents, group...)
+	}
+	return arrangeBy(keys, ents, func(ent *model.Area) model.FVEntityID {
+		return model.FVEntityID{FeedVersionID: ent.FeedVersionID, EntityID: ent.AreaID.Val}
+	}), nil
+}
+
+func (f *Finder) AreasByStopIDs(ctx context.Context, limit *int, keys []int) ([][]*model.Area, error) {
+	var ents []*model.Area
+	err := dbutil.Select(ctx,
+		f.db,
+		lateralWrap(
+			areaSelect(limit, nil, nil, nil).
+				Join("gtfs_stop_areas ON gtfs_stop_areas.area_id = gtfs_areas.id").
+				Column("gtfs_stop_areas.stop_id AS with_stop_id"),
+			"gtfs_stops",
+			"id",
+			"gtfs_stop_areas",
+			"stop_id",
+			keys,
+		),
+		&ents,
+	)
+	return arrangeGroup(keys, ents, func(ent *model.Area) int { return ent.WithStopID.Int() }), err
+}
+
+func areaSelect(limit *int, _ *model.Cursor, ids []int, where *model.AreaFilter) sq.SelectBuilder {
+	q := sq.StatementBuilder.Select(
+		"gtfs_areas.id",
+		"gtfs_areas.feed_version_id",
+		"gtfs_areas.created_at",
+		"gtfs_areas.updated_at",
+		"gtfs_areas.area_id",
+		"gtfs_areas.area_name",
+		"gtfs_areas.agency_ids",
+		"gtfs_areas.geometry",
+		"feed_versions.sha1 AS feed_version_sha1",
+		"current_feeds.onestop_id AS feed_onestop_id",
+	).From("gtfs_areas").
+		Join("feed_versions ON feed_versions.id = gtfs_areas.feed_version_id").
+		Join("current_feeds ON current_feeds.id = feed_versions.feed_id")
+
+	if len(ids) > 0 {
+		q = q.Where(In("gtfs_areas.id", ids))
+	}
+	if where != nil {
+		if len(where.Ids) > 0 {
+			q = q.Where(In("gtfs_areas.id", where.Ids))
+		}
+		if where.AreaID != nil && *where.AreaID != "" {
+			q = q.Where(sq.Eq{"gtfs_areas.area_id": *where.AreaID})
+		}
+	}
+	q = q.OrderBy("gtfs_areas.id ASC")
+	q = q.Limit(finderCheckLimit(limit))
+	return q
+}
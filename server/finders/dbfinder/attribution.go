@@ -0,0 +1,56 @@
+package dbfinder
+
+import (
+	"context"
+
+	"github.com/interline-io/transitland-lib/server/dbutil"
+	"github.com/interline-io/transitland-lib/server/model"
+	sq "github.com/irees/squirrel"
+)
+
+func (f *Finder) AttributionsByFeedVersionIDs(ctx context.Context, limit *int, keys []int) ([][]*model.Attribution, error) {
+	var ents []*model.Attribution
+	q := attributionSelect(limit, nil, nil, f.PermFilter(ctx)).Where(In("gtfs_attributions.feed_version_id", keys))
+	err := dbutil.Select(ctx, f.db, q, &ents)
+	return arrangeGroup(keys, ents, func(ent *model.Attribution) int { return ent.FeedVersionID }), err
+}
+
+func attributionSelect(limit *int, after *model.Cursor, ids []int, permFilter *model.PermFilter) sq.SelectBuilder {
+	q := sq.StatementBuilder.
+		Select(
+			"gtfs_attributions.id",
+			"gtfs_attributions.feed_version_id",
+			"gtfs_attributions.attribution_id",
+			"gtfs_attributions.organization_name",
+			"gtfs_attributions.agency_id",
+			"gtfs_attributions.route_id",
+			"gtfs_attributions.trip_id",
+			"gtfs_attributions.is_producer",
+			"gtfs_attributions.is_operator",
+			"gtfs_attributions.is_authority",
+			"gtfs_attributions.attribution_url",
+			"gtfs_attributions.attribution_email",
+			"gtfs_attributions.attribution_phone",
+			"gtfs_attributions.created_at",
+			"gtfs_attributions.updated_at",
+			"feed_versions.sha1 AS feed_version_sha1",
+			"current_feeds.onestop_id AS feed_onestop_id",
+		).
+		From("gtfs_attributions").
+		Join("feed_versions on feed_versions.id = gtfs_attributions.feed_version_id").
+		Join("current_feeds on current_feeds.id = feed_versions.feed_id").
+		Limit(finderCheckLimit(limit)).
+		OrderBy("gtfs_attributions.id")
+
+	if len(ids) > 0 {
+		q = q.Where(In("gtfs_attributions.id", ids))
+	}
+	if after != nil && after.Valid && after.ID > 0 {
+		q = q.Where(sq.Gt{"gtfs_attributions.id": after.ID})
+	}
+
+	// Handle permissions
+	q = joinImported(q)
+	q = pfJoinCheckFv(q, permFilter)
+	return q
+}
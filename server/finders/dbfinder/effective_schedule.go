@@ -0,0 +1,187 @@
+package dbfinder
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/interline-io/transitland-lib/server/dbutil"
+	"github.com/interline-io/transitland-lib/server/model"
+	"github.com/interline-io/transitland-lib/tt"
+	sq "github.com/irees/squirrel"
+)
+
+// FindEffectiveFeedVersion returns the feed version that governs a service date
+// for the feed of the given feed version: the most recently fetched imported
+// version of the feed whose service window contains the date. The window is
+// the feed_info start and end dates when present, otherwise the calculated
+// window. If no version's window contains the date, fvid is returned.
+func (f *Finder) FindEffectiveFeedVersion(ctx context.Context, fvid int, date time.Time) (int, error) {
+	q := sq.StatementBuilder.
+		Select("feed_versions.id").
+		From("feed_versions").
+		Join("current_feeds on current_feeds.id = feed_versions.feed_id").
+		Where("feed_versions.feed_id = (select feed_id from feed_versions where id = ?)", fvid).
+		Where(sq.Eq{"fvgi.schedule_removed": false}).
+		OrderBy("feed_versions.fetched_at desc, feed_versions.id desc")
+	q = joinImported(q)
+	q = pfJoinCheckFv(q, f.PermFilter(ctx))
+	var candidates []int
+	if err := dbutil.Select(ctx, f.db, q, &candidates); err != nil {
+		return 0, err
+	}
+	for _, c := range candidates {
+		// A version without a resolvable window, e.g. no default timezone, can't govern.
+		sw, _, err := f.fvslCache.Get(ctx, c)
+		if err != nil || sw == nil || sw.Location == nil {
+			continue
+		}
+		day := tzTruncate(date, sw.Location).Val
+		if !day.Before(sw.StartDate) && !day.After(sw.EndDate) {
+			return c, nil
+		}
+	}
+	return fvid, nil
+}
+
+// effectiveEntityIDs maps entities of one feed version to the entities with the
+// same GTFS id in another, e.g. gtfs_stops by stop_id. Entities with no
+// counterpart are left out.
+func (f *Finder) effectiveEntityIDs(ctx context.Context, table string, column string, toFvid int, ids []int) (map[int]int, error) {
+	type entityPair struct {
+		FromID int
+		ToID   int
+	}
+	q := sq.StatementBuilder.
+		Select("a.id AS from_id", "b.id AS to_id").
+		From(table+" a").
+		Join(fmt.Sprintf("%s b on b.%s = a.%s and b.feed_version_id = ?", table, column, column), toFvid).
+		Where(In("a.id", ids))
+	var pairs []entityPair
+	if err := dbutil.Select(ctx, f.db, q, &pairs); err != nil {
+		return nil, err
+	}
+	ret := map[int]int{}
+	for _, p := range pairs {
+		ret[p.FromID] = p.ToID
+	}
+	return ret, nil
+}
+
+// effectiveKeys finds the feed version governing date for a batch of entities in
+// fvid and maps them into it. It returns the feed version to query, the entity
+// ids to query there, and for each of those the key it answers for; GTFS ids are
+// unique within a feed version, so each answers for one key. When fvid itself
+// governs, the keys are returned unchanged.
+func (f *Finder) effectiveKeys(ctx context.Context, table string, column string, fvid int, entityIDs []int, date time.Time) (int, []int, map[model.FVPair]model.FVPair, error) {
+	gfvid, err := f.FindEffectiveFeedVersion(ctx, fvid, date)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	origin := map[model.FVPair]model.FVPair{}
+	if gfvid == fvid {
+		for _, eid := range entityIDs {
+			k := model.FVPair{FeedVersionID: fvid, EntityID: eid}
+			origin[k] = k
+		}
+		return fvid, entityIDs, origin, nil
+	}
+	mapped, err := f.effectiveEntityIDs(ctx, table, column, gfvid, entityIDs)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	var qids []int
+	for _, eid := range entityIDs {
+		gid, ok := mapped[eid]
+		if !ok {
+			continue
+		}
+		k := model.FVPair{FeedVersionID: gfvid, EntityID: gid}
+		if _, ok := origin[k]; !ok {
+			qids = append(qids, gid)
+		}
+		origin[k] = model.FVPair{FeedVersionID: fvid, EntityID: eid}
+	}
+	return gfvid, qids, origin, nil
+}
+
+// effectiveFeedVersionGroup is the feed versions governing one service date.
+type effectiveFeedVersionGroup struct {
+	date  tt.Date
+	fvids []int
+}
+
+// effectiveMaterializedFeedVersions returns, for each feed with a materialized
+// feed version, optionally only the filter's feed, the feed version governing
+// the filter's service date, grouped by date. The date is resolved against each
+// materialized feed version's service window; a feed whose relative date cannot
+// be resolved is left out.
+func (f *Finder) effectiveMaterializedFeedVersions(ctx context.Context, where *model.TripFilter) ([]effectiveFeedVersionGroup, error) {
+	q := sq.StatementBuilder.
+		Select("feed_states.materialized_feed_version_id").
+		From("feed_states").
+		Join("current_feeds on current_feeds.id = feed_states.feed_id").
+		Where("feed_states.materialized_feed_version_id IS NOT NULL").
+		Where(sq.Eq{"current_feeds.deleted_at": nil}).
+		OrderBy("feed_states.materialized_feed_version_id")
+	if where.FeedOnestopID != nil {
+		q = q.Where(sq.Eq{"current_feeds.onestop_id": *where.FeedOnestopID})
+	}
+	var fvids []int
+	if err := dbutil.Select(ctx, f.db, q, &fvids); err != nil {
+		return nil, err
+	}
+	var ret []effectiveFeedVersionGroup
+	for _, fvid := range fvids {
+		// Without a service window, only an explicit service date can be used
+		fvsw, _ := f.FindFeedVersionServiceWindow(ctx, fvid)
+		d, err := effectiveTripDate(where, fvsw)
+		if err != nil {
+			return nil, err
+		}
+		if d == nil {
+			continue
+		}
+		gfvid, err := f.FindEffectiveFeedVersion(ctx, fvid, d.Val)
+		if err != nil {
+			return nil, err
+		}
+		idx := slices.IndexFunc(ret, func(g effectiveFeedVersionGroup) bool { return g.date.Val.Equal(d.Val) })
+		if idx < 0 {
+			idx = len(ret)
+			ret = append(ret, effectiveFeedVersionGroup{date: *d})
+		}
+		ret[idx].fvids = append(ret[idx].fvids, gfvid)
+	}
+	return ret, nil
+}
+
+// effectiveTripDate returns the single service date a trip filter asks for when
+// effective_schedule is set, resolved against the feed version's service window
+// but not mapped into its fallback week.
+func effectiveTripDate(where *model.TripFilter, fvsw *model.ServiceWindow) (*tt.Date, error) {
+	if !hasEffectiveTripDate(where) {
+		return nil, nil
+	}
+	return resolveServiceDate(where.ServiceDate, where.RelativeDate, false, fvsw)
+}
+
+// hasEffectiveTripDate checks if a trip filter sets effective_schedule with a
+// single service date or relative date.
+func hasEffectiveTripDate(where *model.TripFilter) bool {
+	if where == nil || !nilOr(where.EffectiveSchedule, false) || len(where.Dates) > 0 || len(where.ServiceDates) > 0 {
+		return false
+	}
+	return where.ServiceDate != nil || where.RelativeDate != nil
+}
+
+// effectiveTripFilter returns a copy of a trip filter that asks for exactly the
+// governed service date in another feed version.
+func effectiveTripFilter(where *model.TripFilter, serviceDate tt.Date) *model.TripFilter {
+	w := *where
+	w.ServiceDate = &serviceDate
+	w.RelativeDate = nil
+	w.UseServiceWindow = nil
+	return &w
+}
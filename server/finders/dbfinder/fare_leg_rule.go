@@ -0,0 +1,136 @@
+package dbfinder
+
+import (
+	"context"
+
+	"github.com/interline-io/transitland-lib/server/dbutil"
+	"github.com/interline-io/transitland-lib/server/model"
+	sq "github.com/irees/squirrel"
+)
+
+func (f *Finder) FareLegRulesByFeedVersionIDs(ctx context.Context, limit *int, where *model.FareLegRuleFilter, keys []int) ([][]*model.FareLegRule, error) {
+	var ents []*model.FareLegRule
+	q := fareLegRuleSelect(limit, nil, nil, where).Where(In("gtfs_fare_leg_rules.feed_version_id", keys))
+	err := dbutil.Select(ctx, f.db, q, &ents)
+	return arrangeGroup(keys, ents, func(ent *model.FareLegRule) int { return ent.FeedVersionID }), err
+}
+
+func (f *Finder) FareLegRulesByFeedVersionLegGroupIDs(ctx context.Context, limit *int, keys []model.FVEntityID) ([][]*model.FareLegRule, error) {
+	var ents []*model.FareLegRule
+	for fvid, legGroupIds := range groupFVEntityIDs(keys) {
+		q := fareLegRuleSelect(limit, nil, nil, nil).
+			Where(sq.Eq{"gtfs_fare_leg_rules.feed_version_id": fvid}).
+			Where(In("gtfs_fare_leg_rules.leg_group_id", legGroupIds))
+		var group []*model.FareLegRule
+		if err := dbutil.Select(ctx, f.db, q, &group); err != nil {
+			return nil, err
+		}
+		ents = append(ents, group...)
+	}
+	return arrangeGroup(keys, ents, func(ent *model.FareLegRule) model.FVEntityID {
+		return model.FVEntityID{FeedVersionID: ent.FeedVersionID, EntityID: ent.LegGroupID.Val}
+	}), nil
+}
+
+// FareLegRulesByRouteIDs returns the fare leg rules that reference a network containing each route,
+// either through routes.network_id or through route_networks.txt.
+func (f *Finder) FareLegRulesByRouteIDs(ctx context.Context, limit *int, where *model.FareLegRuleFilter, keys []int) ([][]*model.FareLegRule, error) {
+	var ents []*model.FareLegRule
+	q := fareLegRuleSelect(limit, nil, nil, where).
+		Join("gtfs_routes ON gtfs_routes.feed_version_id = gtfs_fare_leg_rules.feed_version_id").
+		Column("gtfs_routes.id AS with_route_id").
+		Where(sq.Or{
+			sq.Expr("gtfs_fare_leg_rules.network_id = gtfs_routes.network_id"),
+			sq.Expr("gtfs_fare_leg_rules.network_id IN (SELECT gtfs_route_networks.network_id::text FROM gtfs_route_networks WHERE gtfs_route_networks.route_id = gtfs_routes.id)"),
+		})
+	err := dbutil.Select(ctx,
+		f.db,
+		lateralWrap(q, "gtfs_routes", "id", "gtfs_routes", "id", keys),
+		&ents,
+	)
+	return arrangeGroup(keys, ents, func(ent *model.FareLegRule) int { return ent.WithRouteID.Int() }), err
+}
+
+func (f *Finder) FareLegJoinRulesByFeedVersionIDs(ctx context.Context, limit *int, keys []int) ([][]*model.FareLegJoinRule, error) {
+	var ents []*model.FareLegJoinRule
+	q := fareLegJoinRuleSelect(limit, nil, nil).Where(In("gtfs_fare_leg_join_rules.feed_version_id", keys))
+	err := dbutil.Select(ctx, f.db, q, &ents)
+	return arrangeGroup(keys, ents, func(ent *model.FareLegJoinRule) int { return ent.FeedVersionID }), err
+}
+
+// Area, network, and stop references are stored as internal ids on import;
+// these are joined back to their GTFS ids. Networks defined only through
+// routes.network_id are stored as-is and pass through unchanged.
+func fareLegRuleSelect(limit *int, _ *model.Cursor, ids []int, where *model.FareLegRuleFilter) sq.SelectBuilder {
+	q := sq.StatementBuilder.Select(
+		"gtfs_fare_leg_rules.id",
+		"gtfs_fare_leg_rules.feed_version_id",
+		"gtfs_fare_leg_rules.created_at",
+		"gtfs_fare_leg_rules.updated_at",
+		"gtfs_fare_leg_rules.leg_group_id",
+		"COALESCE(gtfs_networks.network_id, gtfs_fare_leg_rules.network_id) AS network_id",
+		"COALESCE(from_areas.area_id, gtfs_fare_leg_rules.from_area_id) AS from_area_id",
+		"COALESCE(to_areas.area_id, gtfs_fare_leg_rules.to_area_id) AS to_area_id",
+		"gtfs_fare_leg_rules.from_timeframe_group_id",
+		"gtfs_fare_leg_rules.to_timeframe_group_id",
+		"gtfs_fare_leg_rules.fare_product_id",
+		"gtfs_fare_leg_rules.rule_priority",
+		"gtfs_fare_leg_rules.transfer_only",
+		"feed_versions.sha1 AS feed_version_sha1",
+		"current_feeds.onestop_id AS feed_onestop_id",
+	).From("gtfs_fare_leg_rules").
+		Join("feed_versions ON feed_versions.id = gtfs_fare_leg_rules.feed_version_id").
+		Join("current_feeds ON current_feeds.id = feed_versions.feed_id").
+		LeftJoin("gtfs_networks ON gtfs_networks.id::text = gtfs_fare_leg_rules.network_id AND gtfs_networks.feed_version_id = gtfs_fare_leg_rules.feed_version_id").
+		LeftJoin("gtfs_areas from_areas ON from_areas.id::text = gtfs_fare_leg_rules.from_area_id AND from_areas.feed_version_id = gtfs_fare_leg_rules.feed_version_id").
+		LeftJoin("gtfs_areas to_areas ON to_areas.id::text = gtfs_fare_leg_rules.to_area_id AND to_areas.feed_version_id = gtfs_fare_leg_rules.feed_version_id")
+
+	if len(ids) > 0 {
+		q = q.Where(In("gtfs_fare_leg_rules.id", ids))
+	}
+	if where != nil {
+		if len(where.Ids) > 0 {
+			q = q.Where(In("gtfs_fare_leg_rules.id", where.Ids))
+		}
+		if where.LegGroupID != nil && *where.LegGroupID != "" {
+			q = q.Where(sq.Eq{"gtfs_fare_leg_rules.leg_group_id": *where.LegGroupID})
+		}
+		if where.NetworkID != nil && *where.NetworkID != "" {
+			q = q.Where(sq.Expr("COALESCE(gtfs_networks.network_id, gtfs_fare_leg_rules.network_id) = ?", *where.NetworkID))
+		}
+		if where.FareProductID != nil && *where.FareProductID != "" {
+			q = q.Where(sq.Eq{"gtfs_fare_leg_rules.fare_product_id": *where.FareProductID})
+		}
+	}
+	q = q.OrderBy("gtfs_fare_leg_rules.id ASC")
+	q = q.Limit(finderCheckLimit(limit))
+	return q
+}
+
+func fareLegJoinRuleSelect(limit *int, _ *model.Cursor, ids []int) sq.SelectBuilder {
+	q := sq.StatementBuilder.Select(
+		"gtfs_fare_leg_join_rules.id",
+		"gtfs_fare_leg_join_rules.feed_version_id",
+		"gtfs_fare_leg_join_rules.created_at",
+		"gtfs_fare_leg_join_rules.updated_at",
+		"COALESCE(from_networks.network_id, gtfs_fare_leg_join_rules.from_network_id) AS from_network_id",
+		"COALESCE(to_networks.network_id, gtfs_fare_leg_join_rules.to_network_id) AS to_network_id",
+		"COALESCE(from_stops.stop_id, gtfs_fare_leg_join_rules.from_stop_id) AS from_stop_id",
+		"COALESCE(to_stops.stop_id, gtfs_fare_leg_join_rules.to_stop_id) AS to_stop_id",
+		"feed_versions.sha1 AS feed_version_sha1",
+		"current_feeds.onestop_id AS feed_onestop_id",
+	).From("gtfs_fare_leg_join_rules").
+		Join("feed_versions ON feed_versions.id = gtfs_fare_leg_join_rules.feed_version_id").
+		Join("current_feeds ON current_feeds.id = feed_versions.feed_id").
+		LeftJoin("gtfs_networks from_networks ON from_networks.id::text = gtfs_fare_leg_join_rules.from_network_id AND from_networks.feed_version_id = gtfs_fare_leg_join_rules.feed_version_id").
+		LeftJoin("gtfs_networks to_networks ON to_networks.id::text = gtfs_fare_leg_join_rules.to_network_id AND to_networks.feed_version_id = gtfs_fare_leg_join_rules.feed_version_id").
+		LeftJoin("gtfs_stops from_stops ON from_stops.id::text = gtfs_fare_leg_join_rules.from_stop_id AND from_stops.feed_version_id = gtfs_fare_leg_join_rules.feed_version_id").
+		LeftJoin("gtfs_stops to_stops ON to_stops.id::text = gtfs_fare_leg_join_rules.to_stop_id AND to_stops.feed_version_id = gtfs_fare_leg_join_rules.feed_version_id")
+
+	if len(ids) > 0 {
+		q = q.Where(In("gtfs_fare_leg_join_rules.id", ids))
+	}
+	q = q.OrderBy("gtfs_fare_leg_join_rules.id ASC")
+	q = q.Limit(finderCheckLimit(limit))
+	return q
+}
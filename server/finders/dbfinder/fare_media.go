@@ -0,0 +1,56 @@
+package dbfinder
+
+import (
+	"context"
+
+	"github.com/interline-io/transitland-lib/server/dbutil"
+	"github.com/interline-io/transitland-lib/server/model"
+	sq "github.com/irees/squirrel"
+)
+
+func (f *Finder) FareMediaByFeedVersionIDs(ctx context.Context, limit *int, where *model.FareMediaFilter, keys []int) ([][]*model.FareMedia, error) {
+	var ents []*model.FareMedia
+	q := fareMediaSelect(limit, nil, nil, where).Where(In("gtfs_fare_media.feed_version_id", keys))
+	err := dbutil.Select(ctx, f.db, q, &ents)
+	return arrangeGroup(keys, ents, func(ent *model.FareMedia) int { return ent.FeedVersionID }), err
+}
+
+func (f *Finder) FareMediaByIDs(ctx context.Context, ids []int) ([]*model.FareMedia, []error) {
+	var ents []*model.FareMedia
+	q := fareMediaSelect(nil, nil, ids, nil)
+	if err := dbutil.Select(ctx, f.db, q, &ents); err != nil {
+		return nil, logExtendErr(ctx, len(ids), err)
+	}
+	return arrangeBy(ids, ents, func(ent *model.FareMedia) int { return ent.ID }), nil
+}
+
+func fareMediaSelect(limit *int, _ *model.Cursor, ids []int, where *model.FareMediaFilter) sq.SelectBuilder {
+	q := sq.StatementBuilder.Select(
+		"gtfs_fare_media.id",
+		"gtfs_fare_media.feed_version_id",
+		"gtfs_fare_media.created_at",
+		"gtfs_fare_media.updated_at",
+		"gtfs_fare_media.fare_media_id",
+		"gtfs_fare_media.fare_media_name",
+		"gtfs_fare_media.fare_media_type",
+		"feed_versions.sha1 AS feed_version_sha1",
+		"current_feeds.onestop_id AS feed_onestop_id",
+	).From("gtfs_fare_media").
+		Join("feed_versions ON feed_versions.id = gtfs_fare_media.feed_version_id").
+		Join("current_feeds ON current_feeds.id = feed_versions.feed_id")
+
+	if len(ids) > 0 {
+		q = q.Where(In("gtfs_fare_media.id", ids))
+	}
+	if where != nil {
+		if len(where.Ids) > 0 {
+			q = q.Where(In("gtfs_fare_media.id", where.Ids))
+		}
+		if where.FareMediaID != nil && *where.FareMediaID != "" {
+			q = q.Where(sq.Eq{"gtfs_fare_media.fare_media_id": *where.FareMediaID})
+		}
+	}
+	q = q.OrderBy("gtfs_fare_media.id ASC")
+	q = q.Limit(finderCheckLimit(limit))
+	return q
+}
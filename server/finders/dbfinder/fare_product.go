@@ -0,0 +1,83 @@
+package dbfinder
+
+import (
+	"context"
+
+	"github.com/interline-io/transitland-lib/server/dbutil"
+	"github.com/interline-io/transitland-lib/server/model"
+	sq "github.com/irees/squirrel"
+)
+
+func (f *Finder) FareProductsByFeedVersionIDs(ctx context.Context, limit *int, where *model.FareProductFilter, keys []int) ([][]*model.FareProduct, error) {
+	var ents []*model.FareProduct
+	q := fareProductSelect(limit, nil, nil, where).Where(In("gtfs_fare_products.feed_version_id", keys))
+	err := dbutil.Select(ctx, f.db, q, &ents)
+	return arrangeGroup(keys, ents, func(ent *model.FareProduct) int { return ent.FeedVersionID }), err
+}
+
+// FareProductsByFeedVersionFareProductIDs returns every fare product row matching a fare_product_id;
+// a single fare_product_id may be repeated once for each rider category and fare media combination.
+func (f *Finder) FareProductsByFeedVersionFareProductIDs(ctx context.Context, limit *int, keys []model.FVEntityID) ([][]*model.FareProduct, error) {
+	var ents []*model.FareProduct
+	for fvid, productIds := range groupFVEntityIDs(keys) {
+		q := fareProductSelect(limit, nil, nil, nil).
+			Where(sq.Eq{"gtfs_fare_products.feed_version_id": fvid}).
+			Where(In("gtfs_fare_products.fare_product_id", productIds))
+		var group []*model.FareProduct
+		if err := dbutil.Select(ctx, f.db, q, &group); err != nil {
+			return nil, err
+		}
+		ents = append(ents, group...)
+	}
+	return arrangeGroup(keys, ents, func(ent *model.FareProduct) model.FVEntityID {
+		return model.FVEntityID{FeedVersionID: ent.FeedVersionID, EntityID: ent.FareProductID.Val}
+	}), nil
+}
+
+func fareProductSelect(limit *int, _ *model.Cursor, ids []int, where *model.FareProductFilter) sq.SelectBuilder {
+	q := sq.StatementBuilder.Select(
+		"gtfs_fare_products.id",
+		"gtfs_fare_products.feed_version_id",
+		"gtfs_fare_products.created_at",
+		"gtfs_fare_products.updated_at",
+		"gtfs_fare_products.fare_product_id",
+		"gtfs_fare_products.fare_product_name",
+		"gtfs_fare_products.amount",
+		"gtfs_fare_products.currency",
+		"gtfs_fare_products.duration_start",
+		"gtfs_fare_products.duration_amount",
+		"gtfs_fare_products.duration_unit",
+		"gtfs_fare_products.duration_type",
+		"gtfs_fare_products.rider_category_id",
+		"gtfs_fare_products.fare_media_id",
+		"feed_versions.sha1 AS feed_version_sha1",
+		"current_feeds.onestop_id AS feed_onestop_id",
+	).From("gtfs_fare_products").
+		Join("feed_versions ON feed_versions.id = gtfs_fare_products.feed_version_id").
+		Join("current_feeds ON current_feeds.id = feed_versions.feed_id")
+
+	if len(ids) > 0 {
+		q = q.Where(In("gtfs_fare_products.id", ids))
+	}
+	if where != nil {
+		if len(where.Ids) > 0 {
+			q = q.Where(In("gtfs_fare_products.id", where.Ids))
+		}
+		if where.FareProductID != nil && *where.FareProductID != "" {
+			q = q.Where(sq.Eq{"gtfs_fare_products.fare_product_id": *where.FareProductID})
+		}
+		if where.RiderCategoryID != nil && *where.RiderCategoryID != "" {
+			q = q.Where(sq.Eq{"gtfs_fare_products.rider_category_id": *where.RiderCategoryID})
+		}
+		if where.FareMediaID != nil && *where.FareMediaID != "" {
+			// fare_media_id is stored as a reference to gtfs_fare_media.id
+			q = q.Where(sq.Expr(
+				"gtfs_fare_products.fare_media_id IN (SELECT gtfs_fare_media.id::text FROM gtfs_fare_media WHERE gtfs_fare_media.feed_version_id = gtfs_fare_products.feed_version_id AND gtfs_fare_media.fare_media_id = ?)",
+				*where.FareMediaID,
+			))
+		}
+	}
+	q = q.OrderBy("gtfs_fare_products.id ASC")
+	q = q.Limit(finderCheckLimit(limit))
+	return q
+}
@@ -0,0 +1,58 @@
+package dbfinder
+
+import (
+	"context"
+
+	"github.com/interline-io/transitland-lib/server/dbutil"
+	"github.com/interline-io/transitland-lib/server/model"
+	sq "github.com/irees/squirrel"
+)
+
+func (f *Finder) FareTransferRulesByFeedVersionIDs(ctx context.Context, limit *int, where *model.FareTransferRuleFilter, keys []int) ([][]*model.FareTransferRule, error) {
+	var ents []*model.FareTransferRule
+	q := fareTransferRuleSelect(limit, nil, nil, where).Where(In("gtfs_fare_transfer_rules.feed_version_id", keys))
+	err := dbutil.Select(ctx, f.db, q, &ents)
+	return arrangeGroup(keys, ents, func(ent *model.FareTransferRule) int { return ent.FeedVersionID }), err
+}
+
+func fareTransferRuleSelect(limit *int, _ *model.Cursor, ids []int, where *model.FareTransferRuleFilter) sq.SelectBuilder {
+	q := sq.StatementBuilder.Select(
+		"gtfs_fare_transfer_rules.id",
+		"gtfs_fare_transfer_rules.feed_version_id",
+		"gtfs_fare_transfer_rules.created_at",
+		"gtfs_fare_transfer_rules.updated_at",
+		"gtfs_fare_transfer_rules.from_leg_group_id",
+		"gtfs_fare_transfer_rules.to_leg_group_id",
+		"gtfs_fare_transfer_rules.transfer_count",
+		"gtfs_fare_transfer_rules.duration_limit",
+		"gtfs_fare_transfer_rules.duration_limit_type",
+		"gtfs_fare_transfer_rules.fare_transfer_type",
+		"gtfs_fare_transfer_rules.fare_product_id",
+		"gtfs_fare_transfer_rules.filter_fare_product_id",
+		"feed_versions.sha1 AS feed_version_sha1",
+		"current_feeds.onestop_id AS feed_onestop_id",
+	).From("gtfs_fare_transfer_rules").
+		Join("feed_versions ON feed_versions.id = gtfs_fare_transfer_rules.feed_version_id").
+		Join("current_feeds ON current_feeds.id = feed_versions.feed_id")
+
+	if len(ids) > 0 {
+		q = q.Where(In("gtfs_fare_transfer_rules.id", ids))
+	}
+	if where != nil {
+		if len(where.Ids) > 0 {
+			q = q.Where(In("gtfs_fare_transfer_rules.id", where.Ids))
+		}
+		if where.FromLegGroupID != nil && *where.FromLegGroupID != "" {
+			q = q.Where(sq.Eq{"gtfs_fare_transfer_rules.from_leg_group_id": *where.FromLegGroupID})
+		}
+		if where.ToLegGroupID != nil && *where.ToLegGroupID != "" {
+			q = q.Where(sq.Eq{"gtfs_fare_transfer_rules.to_leg_group_id": *where.ToLegGroupID})
+		}
+		if where.FareProductID != nil && *where.FareProductID != "" {
+			q = q.Where(sq.Eq{"gtfs_fare_transfer_rules.fare_product_id": *where.FareProductID})
+		}
+	}
+	q = q.OrderBy("gtfs_fare_transfer_rules.id ASC")
+	q = q.Limit(finderCheckLimit(limit))
+	return q
+}
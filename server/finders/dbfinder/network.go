@@ -0,0 +1,93 @@
+package dbfinder
+
+import (
+	"context"
+
+	"github.com/interline-io/transitland-lib/server/dbutil"
+	"github.com/interline-io/transitland-lib/server/model"
+	sq "github.com/irees/squirrel"
+)
+
+func (f *Finder) NetworksByFeedVersionIDs(ctx context.Context, limit *int, where *model.NetworkFilter, keys []int) ([][]*model.Network, error) {
+	var ents []*model.Network
+	q := networkSelect(limit, nil, nil, f.PermFilter(ctx), where).Where(In("gtfs_networks.feed_version_id", keys))
+	err := dbutil.Select(ctx, f.db, q, &ents)
+	return arrangeGroup(keys, ents, func(ent *model.Network) int { return ent.FeedVersionID }), err
+}
+
+// NetworksByRouteIDs returns the networks of each route, either assigned
+// through route_networks.txt or through the route's network_id.
+func (f *Finder) NetworksByRouteIDs(ctx context.Context, limit *int, keys []int) ([][]*model.Network, error) {
+	var ents []*model.Network
+	qInner := networkSelect(limit, nil, nil, f.PermFilter(ctx), nil).
+		Column("out.id AS with_route_id").
+		Where(sq.Or{
+			sq.Expr("gtfs_networks.id IN (SELECT gtfs_route_networks.network_id FROM gtfs_route_networks WHERE gtfs_route_networks.route_id = out.id)"),
+			sq.Expr("gtfs_networks.feed_version_id = out.feed_version_id AND gtfs_networks.network_id = out.network_id"),
+		})
+	q := sq.StatementBuilder.
+		Select("t.*").
+		From("gtfs_routes out").
+		JoinClause(qInner.Prefix("JOIN LATERAL (").Suffix(") t on true")).
+		Where(In("out.id", keys))
+	err := dbutil.Select(ctx, f.db, q, &ents)
+	return arrangeGroup(keys, ents, func(ent *model.Network) int { return ent.WithRouteID.Int() }), err
+}
+
+// RoutesByNetworkIDs returns the routes in each network, either assigned
+// through route_networks.txt or through the route's network_id.
+func (f *Finder) RoutesByNetworkIDs(ctx context.Context, limit *int, keys []int) ([][]*model.Route, error) {
+	var ents []*model.Route
+	qInner := routeSelect(limit, nil, nil, nil, f.PermFilter(ctx), nil).
+		Column("out.id AS with_network_id").
+		Where(sq.Or{
+			sq.Expr("gtfs_routes.id IN (SELECT gtfs_route_networks.route_id FROM gtfs_route_networks WHERE gtfs_route_networks.network_id = out.id)"),
+			sq.Expr("gtfs_routes.feed_version_id = out.feed_version_id AND gtfs_routes.network_id = out.network_id"),
+		})
+	q := sq.StatementBuilder.
+		Select("t.*").
+		From("gtfs_networks out").
+		JoinClause(qInner.Prefix("JOIN LATERAL (").Suffix(") t on true")).
+		Where(In("out.id", keys))
+	err := dbutil.Select(ctx, f.db, q, &ents)
+	return arrangeGroup(keys, ents, func(ent *model.Route) int { return ent.WithNetworkID.Int() }), err
+}
+
+func networkSelect(limit *int, after *model.Cursor, ids []int, permFilter *model.PermFilter, where *model.NetworkFilter) sq.SelectBuilder {
+	q := sq.StatementBuilder.
+		Select(
+			"gtfs_networks.id",
+			"gtfs_networks.feed_version_id",
+			"gtfs_networks.network_id",
+			"gtfs_networks.network_name",
+			"gtfs_networks.created_at",
+			"gtfs_networks.updated_at",
+			"feed_versions.sha1 AS feed_version_sha1",
+			"current_feeds.onestop_id AS feed_onestop_id",
+		).
+		From("gtfs_networks").
+		Join("feed_versions on feed_versions.id = gtfs_networks.feed_version_id").
+		Join("current_feeds on current_feeds.id = feed_versions.feed_id").
+		Limit(finderCheckLimit(limit)).
+		OrderBy("gtfs_networks.id")
+
+	if where != nil {
+		if len(where.Ids) > 0 {
+			q = q.Where(In("gtfs_networks.id", where.Ids))
+		}
+		if where.NetworkID != nil && *where.NetworkID != "" {
+			q = q.Where(sq.Eq{"gtfs_networks.network_id": *where.NetworkID})
+		}
+	}
+	if len(ids) > 0 {
+		q = q.Where(In("gtfs_networks.id", ids))
+	}
+	if after != nil && after.Valid && after.ID > 0 {
+		q = q.Where(sq.Gt{"gtfs_networks.id": after.ID})
+	}
+
+	// Handle permissions
+	q = joinImported(q)
+	q = pfJoinCheckFv(q, permFilter)
+	return q
+}
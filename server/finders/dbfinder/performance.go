@@ -0,0 +1,602 @@
+package dbfinder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/interline-io/transitland-lib/gtfs"
+	"github.com/interline-io/transitland-lib/server/dbutil"
+	"github.com/interline-io/transitland-lib/server/model"
+	"github.com/interline-io/transitland-lib/service"
+	"github.com/interline-io/transitland-lib/tt"
+	sq "github.com/irees/squirrel"
+)
+
+const (
+	// maxPerformanceDays bounds the date range of a performance query.
+	maxPerformanceDays = 366
+	// maxAgencyPerformanceDays bounds the date range of an agency performance query,
+	// which aggregates the observations of every route in memory.
+	maxAgencyPerformanceDays = 31
+	// Default on-time window, in seconds early and late.
+	defaultEarlyThreshold = 60
+	defaultLateThreshold  = 300
+	// headwayAdherenceRatio is the deviation from the scheduled headway that is still adherent.
+	headwayAdherenceRatio = 0.5
+)
+
+// delayHistogramBounds are the bounds of the delay histogram bins, in seconds.
+var delayHistogramBounds = []int{-300, -60, 0, 60, 180, 300, 600, 900}
+
+// FindPerformance aggregates the stop observations recorded for a feed version
+// into on-time performance metrics. Scheduled trips are counted from the feed
+// version's calendars over the same dates.
+func (f *Finder) FindPerformance(ctx context.Context, scope model.PerformanceScope, where *model.PerformanceFilter) (*model.PerformanceReport, error) {
+	opts, err := checkPerformanceFilter(scope, where)
+	if err != nil {
+		return nil, err
+	}
+	q := sq.StatementBuilder.
+		Select(
+			"source",
+			"trip_id",
+			"route_id",
+			"direction_id",
+			"trip_start_date",
+			"trip_start_time",
+			"schedule_relationship",
+			"from_stop_id",
+			"to_stop_id",
+			"scheduled_arrival_time",
+			"scheduled_departure_time",
+			"observed_arrival_time",
+			"observed_departure_time",
+		).
+		From("ext_performance_stop_observations").
+		Where("feed_version_id = ?", scope.FeedVersionID).
+		Where("trip_start_date >= ?", opts.startDate).
+		Where("trip_start_date <= ?", opts.endDate)
+	if scope.AgencyID != "" {
+		q = q.Where("agency_id = ?", scope.AgencyID)
+	}
+	if scope.RouteID != "" {
+		q = q.Where("route_id = ?", scope.RouteID)
+	}
+	if scope.StopID != "" {
+		q = q.Where("to_stop_id = ?", scope.StopID)
+	}
+	if opts.source != "" {
+		q = q.Where("source = ?", opts.source)
+	}
+	var observations []*performanceObservation
+	if err := dbutil.Select(ctx, f.db, q, &observations); err != nil {
+		return nil, logErr(ctx, err)
+	}
+	frequencies, err := f.performanceFrequencies(ctx, scope.FeedVersionID)
+	if err != nil {
+		return nil, logErr(ctx, err)
+	}
+	report := aggregatePerformance(observations, frequencies, opts)
+	report.ScheduledTripCount, err = f.scheduledTripCount(ctx, scope, frequencies, opts)
+	if err != nil {
+		return nil, logErr(ctx, err)
+	}
+	return report, nil
+}
+
+// performanceObservation is the part of a stop observation used for performance metrics.
+type performanceObservation struct {
+	Source                 tt.String
+	TripID                 tt.String
+	RouteID                tt.String
+	DirectionID            tt.Int
+	TripStartDate          tt.Date
+	TripStartTime          tt.Seconds
+	ScheduleRelationship   tt.String
+	FromStopID             tt.String
+	ToStopID               tt.String
+	ScheduledArrivalTime   tt.Seconds
+	ScheduledDepartureTime tt.Seconds
+	ObservedArrivalTime    tt.Seconds
+	ObservedDepartureTime  tt.Seconds
+}
+
+func (obs *performanceObservation) tripKey() string {
+	return fmt.Sprintf("%s|%s|%d", obs.TripID.Val, obs.TripStartDate.Format("2006-01-02"), obs.TripStartTime.Int())
+}
+
+func (obs *performanceObservation) segmentKey() string {
+	return fmt.Sprintf("%s|%s|%s", obs.tripKey(), obs.FromStopID.Val, obs.ToStopID.Val)
+}
+
+func (obs *performanceObservation) canceled() bool {
+	return obs.ScheduleRelationship.Val == "CANCELED"
+}
+
+// scheduledTime is the scheduled arrival, or departure, at the observed stop.
+func (obs *performanceObservation) scheduledTime() tt.Seconds {
+	if obs.ScheduledArrivalTime.Valid {
+		return obs.ScheduledArrivalTime
+	}
+	return obs.ScheduledDepartureTime
+}
+
+// observedTime is the observed arrival, or departure, at the observed stop.
+func (obs *performanceObservation) observedTime() tt.Seconds {
+	if obs.ObservedArrivalTime.Valid {
+		return obs.ObservedArrivalTime
+	}
+	return obs.ObservedDepartureTime
+}
+
+// delay is the observed arrival delay, or departure delay when no arrival was observed.
+func (obs *performanceObservation) delay() (int, bool) {
+	if obs.ObservedArrivalTime.Valid && obs.ScheduledArrivalTime.Valid {
+		return obs.ObservedArrivalTime.Int() - obs.ScheduledArrivalTime.Int(), true
+	}
+	if obs.ObservedDepartureTime.Valid && obs.ScheduledDepartureTime.Valid {
+		return obs.ObservedDepartureTime.Int() - obs.ScheduledDepartureTime.Int(), true
+	}
+	return 0, false
+}
+
+// performanceFrequency is a frequencies.txt window of a trip.
+type performanceFrequency struct {
+	TripID      string
+	StartTime   tt.Seconds
+	EndTime     tt.Seconds
+	HeadwaySecs int
+}
+
+type performanceOptions struct {
+	startDate      time.Time
+	endDate        time.Time
+	startTime      tt.Seconds
+	endTime        tt.Seconds
+	source         string
+	earlyThreshold int
+	lateThreshold  int
+}
+
+func checkPerformanceFilter(scope model.PerformanceScope, where *model.PerformanceFilter) (performanceOptions, error) {
+	opts := performanceOptions{
+		earlyThreshold: defaultEarlyThreshold,
+		lateThreshold:  defaultLateThreshold,
+	}
+	if where == nil || !where.StartDate.Valid {
+		return opts, errors.New("start_date is required")
+	}
+	opts.startDate = where.StartDate.Val
+	opts.endDate = opts.startDate
+	if where.EndDate != nil && where.EndDate.Valid {
+		opts.endDate = where.EndDate.Val
+	}
+	if opts.endDate.Before(opts.startDate) {
+		return opts, errors.New("end_date must not be before start_date")
+	}
+	if opts.endDate.Sub(opts.startDate) >= maxPerformanceDays*24*time.Hour {
+		return opts, fmt.Errorf("date range may cover at most %d days", maxPerformanceDays)
+	}
+	if scope.RouteID == "" && scope.StopID == "" && opts.endDate.Sub(opts.startDate) >= maxAgencyPerformanceDays*24*time.Hour {
+		return opts, fmt.Errorf("date range may cover at most %d days for an agency", maxAgencyPerformanceDays)
+	}
+	if where.StartTime != nil {
+		opts.startTime = *where.StartTime
+	}
+	if where.EndTime != nil {
+		opts.endTime = *where.EndTime
+	}
+	if where.Source != nil {
+		opts.source = *where.Source
+		if opts.source != "TripUpdate" && opts.source != "VehiclePosition" {
+			return opts, errors.New("source must be TripUpdate or VehiclePosition")
+		}
+	}
+	if where.EarlyThreshold != nil {
+		opts.earlyThreshold = *where.EarlyThreshold
+	}
+	if where.LateThreshold != nil {
+		opts.lateThreshold = *where.LateThreshold
+	}
+	if opts.earlyThreshold < 0 || opts.lateThreshold < 0 {
+		return opts, errors.New("thresholds must not be negative")
+	}
+	return opts, nil
+}
+
+// inWindow checks if a scheduled time is in the time window.
+func (opts performanceOptions) inWindow(t tt.Seconds) bool {
+	if !opts.startTime.Valid && !opts.endTime.Valid {
+		return true
+	}
+	if !t.Valid {
+		return false
+	}
+	if opts.startTime.Valid && t.Val < opts.startTime.Val {
+		return false
+	}
+	if opts.endTime.Valid && t.Val >= opts.endTime.Val {
+		return false
+	}
+	return true
+}
+
+// selectObservations applies the time window and, without a source filter,
+// prefers trip update observations over vehicle position observations of the same segment.
+func selectObservations(observations []*performanceObservation, opts performanceOptions) []*performanceObservation {
+	hasTripUpdate := map[string]bool{}
+	for _, obs := range observations {
+		if obs.Source.Val == "TripUpdate" {
+			hasTripUpdate[obs.segmentKey()] = true
+		}
+	}
+	var ret []*performanceObservation
+	for _, obs := range observations {
+		if !opts.inWindow(obs.scheduledTime()) {
+			continue
+		}
+		if opts.source == "" && obs.Source.Val != "TripUpdate" && hasTripUpdate[obs.segmentKey()] {
+			continue
+		}
+		ret = append(ret, obs)
+	}
+	return ret
+}
+
+// aggregatePerformance computes the metrics of the observations, overall and by route, stop, hour and date.
+func aggregatePerformance(observations []*performanceObservation, frequencies map[string][]performanceFrequency, opts performanceOptions) *model.PerformanceReport {
+	observations = selectObservations(observations, opts)
+	summary := newPerformanceGroup(&model.PerformanceMetrics{})
+	byRoute := map[string]*performanceGroup{}
+	byStop := map[string]*performanceGroup{}
+	byHour := map[int]*performanceGroup{}
+	byDate := map[string]*performanceGroup{}
+	groupsFor := func(obs *performanceObservation) []*performanceGroup {
+		ret := []*performanceGroup{summary}
+		routeID := obs.RouteID.Val
+		if byRoute[routeID] == nil {
+			byRoute[routeID] = newPerformanceGroup(&model.PerformanceMetrics{RouteID: &routeID})
+		}
+		stopID := obs.ToStopID.Val
+		if byStop[stopID] == nil {
+			byStop[stopID] = newPerformanceGroup(&model.PerformanceMetrics{StopID: &stopID})
+		}
+		dateKey := obs.TripStartDate.Format("2006-01-02")
+		if byDate[dateKey] == nil {
+			date := obs.TripStartDate
+			byDate[dateKey] = newPerformanceGroup(&model.PerformanceMetrics{Date: &date})
+		}
+		ret = append(ret, byRoute[routeID], byStop[stopID], byDate[dateKey])
+		if st := obs.scheduledTime(); st.Valid {
+			hour := st.Int() / 3600
+			if byHour[hour] == nil {
+				byHour[hour] = newPerformanceGroup(&model.PerformanceMetrics{Hour: &hour})
+			}
+			ret = append(ret, byHour[hour])
+		}
+		return ret
+	}
+	for _, obs := range observations {
+		for _, g := range groupsFor(obs) {
+			g.add(obs, opts)
+		}
+	}
+	for _, h := range observedHeadways(observations, frequencies) {
+		for _, g := range groupsFor(h.obs) {
+			g.headways = append(g.headways, h)
+		}
+	}
+	report := &model.PerformanceReport{
+		Summary: summary.metrics(),
+		ByRoute: []*model.PerformanceMetrics{},
+		ByStop:  []*model.PerformanceMetrics{},
+		ByHour:  []*model.PerformanceMetrics{},
+		ByDate:  []*model.PerformanceMetrics{},
+	}
+	for _, k := range sortedKeys(byRoute) {
+		report.ByRoute = append(report.ByRoute, byRoute[k].metrics())
+	}
+	for _, k := range sortedKeys(byStop) {
+		report.ByStop = append(report.ByStop, byStop[k].metrics())
+	}
+	for _, k := range sortedKeys(byHour) {
+		report.ByHour = append(report.ByHour, byHour[k].metrics())
+	}
+	for _, k := range sortedKeys(byDate) {
+		report.ByDate = append(report.ByDate, byDate[k].metrics())
+	}
+	return report
+}
+
+func sortedKeys[K string | int, V any](m map[K]V) []K {
+	var ret []K
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+	return ret
+}
+
+// observedHeadway is the time between two consecutive frequency-based trips at a stop.
+type observedHeadway struct {
+	obs       *performanceObservation // the later trip
+	scheduled int
+	observed  int
+}
+
+// observedHeadways returns the headways between consecutive frequency-based trips
+// of the same route and direction at each stop, on each service date.
+func observedHeadways(observations []*performanceObservation, frequencies map[string][]performanceFrequency) []observedHeadway {
+	groups := map[string][]*performanceObservation{}
+	var groupKeys []string
+	for _, obs := range observations {
+		if len(frequencies[obs.TripID.Val]) == 0 || obs.canceled() || !obs.observedTime().Valid {
+			continue
+		}
+		k := fmt.Sprintf("%s|%d|%s|%s", obs.RouteID.Val, obs.DirectionID.Int(), obs.ToStopID.Val, obs.TripStartDate.Format("2006-01-02"))
+		if _, ok := groups[k]; !ok {
+			groupKeys = append(groupKeys, k)
+		}
+		groups[k] = append(groups[k], obs)
+	}
+	var ret []observedHeadway
+	for _, k := range groupKeys {
+		group := groups[k]
+		sort.SliceStable(group, func(i, j int) bool { return group[i].observedTime().Val < group[j].observedTime().Val })
+		for i := 1; i < len(group); i++ {
+			obs := group[i]
+			observed := obs.observedTime().Int() - group[i-1].observedTime().Int()
+			scheduled := 0
+			for _, freq := range frequencies[obs.TripID.Val] {
+				if obs.TripStartTime.Val >= freq.StartTime.Val && obs.TripStartTime.Val < freq.EndTime.Val {
+					scheduled = freq.HeadwaySecs
+					break
+				}
+			}
+			if observed <= 0 || scheduled <= 0 {
+				continue
+			}
+			ret = append(ret, observedHeadway{obs: obs, scheduled: scheduled, observed: observed})
+		}
+	}
+	return ret
+}
+
+type performanceGroup struct {
+	ret      *model.PerformanceMetrics
+	delays   []int
+	trips    map[string]bool
+	canceled map[string]bool
+	headways []observedHeadway
+}
+
+func newPerformanceGroup(ret *model.PerformanceMetrics) *performanceGroup {
+	return &performanceGroup{
+		ret:      ret,
+		trips:    map[string]bool{},
+		canceled: map[string]bool{},
+	}
+}
+
+func (g *performanceGroup) add(obs *performanceObservation, opts performanceOptions) {
+	tripKey := obs.tripKey()
+	g.trips[tripKey] = true
+	if obs.canceled() {
+		g.canceled[tripKey] = true
+		return
+	}
+	delay, ok := obs.delay()
+	if !ok {
+		return
+	}
+	g.delays = append(g.delays, delay)
+	if delay < -opts.earlyThreshold {
+		g.ret.EarlyCount++
+	} else if delay > opts.lateThreshold {
+		g.ret.LateCount++
+	} else {
+		g.ret.OnTimeCount++
+	}
+}
+
+func (g *performanceGroup) metrics() *model.PerformanceMetrics {
+	ret := g.ret
+	ret.ObservationCount = len(g.delays)
+	ret.TripCount = len(g.trips)
+	ret.CanceledTripCount = len(g.canceled)
+	if n := len(g.delays); n > 0 {
+		pct := 100 * float64(ret.OnTimeCount) / float64(n)
+		ret.OnTimePercent = &pct
+		ret.Delay = delayDistribution(g.delays)
+	}
+	if len(g.headways) > 0 {
+		ret.Headway = headwayAdherence(g.headways)
+	}
+	return ret
+}
+
+func delayDistribution(delays []int) *model.DelayDistribution {
+	sorted := append([]int{}, delays...)
+	sort.Ints(sorted)
+	sum := 0
+	for _, d := range sorted {
+		sum += d
+	}
+	percentile := func(q float64) int {
+		idx := int(math.Ceil(q*float64(len(sorted)))) - 1
+		return sorted[max(0, min(idx, len(sorted)-1))]
+	}
+	ret := &model.DelayDistribution{
+		Mean:   float64(sum) / float64(len(sorted)),
+		Median: percentile(0.5),
+		P90:    percentile(0.9),
+		Min:    sorted[0],
+		Max:    sorted[len(sorted)-1],
+	}
+	for i := 0; i <= len(delayHistogramBounds); i++ {
+		bin := &model.DelayHistogramBin{}
+		if i > 0 {
+			v := delayHistogramBounds[i-1]
+			bin.MinDelay = &v
+		}
+		if i < len(delayHistogramBounds) {
+			v := delayHistogramBounds[i]
+			bin.MaxDelay = &v
+		}
+		for _, d := range sorted {
+			if (bin.MinDelay == nil || d >= *bin.MinDelay) && (bin.MaxDelay == nil || d < *bin.MaxDelay) {
+				bin.Count++
+			}
+		}
+		ret.Histogram = append(ret.Histogram, bin)
+	}
+	return ret
+}
+
+func headwayAdherence(headways []observedHeadway) *model.HeadwayAdherence {
+	ret := &model.HeadwayAdherence{HeadwayCount: len(headways)}
+	var sumScheduled, sumObserved, sumScheduledSq, sumObservedSq float64
+	for _, h := range headways {
+		s, o := float64(h.scheduled), float64(h.observed)
+		sumScheduled += s
+		sumObserved += o
+		sumScheduledSq += s * s
+		sumObservedSq += o * o
+		if math.Abs(o-s) <= headwayAdherenceRatio*s {
+			ret.AdherentCount++
+		}
+	}
+	n := float64(len(headways))
+	ret.ScheduledHeadwayMean = sumScheduled / n
+	ret.ObservedHeadwayMean = sumObserved / n
+	ret.AdherentPercent = 100 * float64(ret.AdherentCount) / n
+	// The average wait of a rider arriving at random is sum(h^2) / (2 * sum(h))
+	ret.ExcessWaitTime = sumObservedSq/(2*sumObserved) - sumScheduledSq/(2*sumScheduled)
+	return ret
+}
+
+// performanceFrequencies returns the frequencies of a feed version by GTFS trip_id.
+func (f *Finder) performanceFrequencies(ctx context.Context, fvid int) (map[string][]performanceFrequency, error) {
+	q := sq.StatementBuilder.
+		Select("gtfs_trips.trip_id", "gtfs_frequencies.start_time", "gtfs_frequencies.end_time", "gtfs_frequencies.headway_secs").
+		From("gtfs_frequencies").
+		Join("gtfs_trips on gtfs_trips.id = gtfs_frequencies.trip_id").
+		Where("gtfs_frequencies.feed_version_id = ?", fvid)
+	var ents []performanceFrequency
+	if err := dbutil.Select(ctx, f.db, q, &ents); err != nil {
+		return nil, err
+	}
+	ret := map[string][]performanceFrequency{}
+	for _, ent := range ents {
+		ret[ent.TripID] = append(ret[ent.TripID], ent)
+	}
+	return ret, nil
+}
+
+// scheduledTrip is a trip with its departure at the first stop, or at the stop of a stop scope.
+type scheduledTrip struct {
+	TripID             string
+	ServiceID          string
+	DepartureTime      tt.Seconds
+	FirstDepartureTime tt.Seconds
+}
+
+// scheduledTripCount counts the trips in scope that are scheduled in the date range and time window.
+// Trips of frequency-based trip_ids are counted once for each scheduled departure.
+func (f *Finder) scheduledTripCount(ctx context.Context, scope model.PerformanceScope, frequencies map[string][]performanceFrequency, opts performanceOptions) (int, error) {
+	services, err := f.performanceServices(ctx, scope.FeedVersionID)
+	if err != nil {
+		return 0, err
+	}
+	q := sq.StatementBuilder.
+		Select("gtfs_trips.trip_id", "gtfs_calendars.service_id", "min(gtfs_stop_times.departure_time) as departure_time").
+		From("gtfs_trips").
+		Join("gtfs_calendars on gtfs_calendars.id = gtfs_trips.service_id").
+		Join("gtfs_stop_times on gtfs_stop_times.trip_id = gtfs_trips.id").
+		Where("gtfs_trips.feed_version_id = ?", scope.FeedVersionID).
+		GroupBy("gtfs_trips.id", "gtfs_trips.trip_id", "gtfs_calendars.service_id")
+	if scope.StopID != "" {
+		q = q.
+			Column("(select min(st.departure_time) from gtfs_stop_times st where st.trip_id = gtfs_trips.id) as first_departure_time").
+			Join("gtfs_stops on gtfs_stops.id = gtfs_stop_times.stop_id").
+			Where("gtfs_stops.stop_id = ?", scope.StopID)
+	} else {
+		q = q.Column("min(gtfs_stop_times.departure_time) as first_departure_time")
+	}
+	if scope.RouteID != "" || scope.AgencyID != "" {
+		q = q.Join("gtfs_routes on gtfs_routes.id = gtfs_trips.route_id")
+	}
+	if scope.RouteID != "" {
+		q = q.Where("gtfs_routes.route_id = ?", scope.RouteID)
+	}
+	if scope.AgencyID != "" {
+		q = q.Join("gtfs_agencies on gtfs_agencies.id = gtfs_routes.agency_id").Where("gtfs_agencies.agency_id = ?", scope.AgencyID)
+	}
+	var trips []scheduledTrip
+	if err := dbutil.Select(ctx, f.db, q, &trips); err != nil {
+		return 0, err
+	}
+	// Departures in the time window, by service
+	serviceDepartures := map[string]int{}
+	for _, trip := range trips {
+		freqs := frequencies[trip.TripID]
+		if len(freqs) == 0 {
+			if opts.inWindow(trip.DepartureTime) {
+				serviceDepartures[trip.ServiceID]++
+			}
+			continue
+		}
+		offset := trip.DepartureTime.Int() - trip.FirstDepartureTime.Int()
+		for _, freq := range freqs {
+			if freq.HeadwaySecs <= 0 {
+				continue
+			}
+			for t := freq.StartTime.Int(); t < freq.EndTime.Int(); t += freq.HeadwaySecs {
+				if opts.inWindow(tt.NewSeconds(t + offset)) {
+					serviceDepartures[trip.ServiceID]++
+				}
+			}
+		}
+	}
+	count := 0
+	for d := opts.startDate; !d.After(opts.endDate); d = d.AddDate(0, 0, 1) {
+		for sid, n := range serviceDepartures {
+			if svc, ok := services[sid]; ok && svc.IsActive(d) {
+				count += n
+			}
+		}
+	}
+	return count, nil
+}
+
+// performanceServices returns the services of a feed version by GTFS service_id.
+func (f *Finder) performanceServices(ctx context.Context, fvid int) (map[string]*service.Service, error) {
+	var calendars []gtfs.Calendar
+	if err := dbutil.Select(ctx, f.db, sq.StatementBuilder.
+		Select("service_id", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday", "start_date", "end_date").
+		From("gtfs_calendars").
+		Where("feed_version_id = ?", fvid), &calendars); err != nil {
+		return nil, err
+	}
+	var calendarDates []gtfs.CalendarDate
+	if err := dbutil.Select(ctx, f.db, sq.StatementBuilder.
+		Select("gtfs_calendars.service_id", "gtfs_calendar_dates.date", "gtfs_calendar_dates.exception_type").
+		From("gtfs_calendar_dates").
+		Join("gtfs_calendars on gtfs_calendars.id = gtfs_calendar_dates.service_id").
+		Where("gtfs_calendar_dates.feed_version_id = ?", fvid), &calendarDates); err != nil {
+		return nil, err
+	}
+	serviceDates := map[string][]gtfs.CalendarDate{}
+	for _, cd := range calendarDates {
+		serviceDates[cd.ServiceID.Val] = append(serviceDates[cd.ServiceID.Val], cd)
+	}
+	ret := map[string]*service.Service{}
+	for _, c := range calendars {
+		ret[c.ServiceID.Val] = service.NewService(c, serviceDates[c.ServiceID.Val]...)
+	}
+	return ret, nil
+}
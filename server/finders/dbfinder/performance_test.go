@@ -0,0 +1,178 @@
+package dbfinder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/interline-io/transitland-lib/server/model"
+	"github.com/interline-io/transitland-lib/tt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregatePerformance(t *testing.T) {
+	date := tt.NewDate(time.Date(2026, 5, 11, 0, 0, 0, 0, time.UTC))
+	date2 := tt.NewDate(time.Date(2026, 5, 12, 0, 0, 0, 0, time.UTC))
+	mkObs := func(source string, tripID string, routeID string, stopID string, d tt.Date, scheduled int, observed int) *performanceObservation {
+		return &performanceObservation{
+			Source:               tt.NewString(source),
+			TripID:               tt.NewString(tripID),
+			RouteID:              tt.NewString(routeID),
+			TripStartDate:        d,
+			ScheduleRelationship: tt.NewString("SCHEDULED"),
+			ToStopID:             tt.NewString(stopID),
+			ScheduledArrivalTime: tt.NewSeconds(scheduled),
+			ObservedArrivalTime:  tt.NewSeconds(observed),
+		}
+	}
+	opts := performanceOptions{earlyThreshold: 60, lateThreshold: 300}
+
+	t.Run("on time", func(t *testing.T) {
+		canceled := mkObs("TripUpdate", "t4", "r2", "s1", date, 9*3600, 0)
+		canceled.ScheduleRelationship = tt.NewString("CANCELED")
+		canceled.ObservedArrivalTime = tt.Seconds{}
+		observations := []*performanceObservation{
+			mkObs("TripUpdate", "t1", "r1", "s1", date, 8*3600, 8*3600+30),       // on time
+			mkObs("TripUpdate", "t1", "r1", "s2", date, 8*3600+600, 8*3600+1000), // late
+			mkObs("TripUpdate", "t2", "r1", "s1", date, 9*3600, 9*3600-120),      // early
+			mkObs("TripUpdate", "t3", "r2", "s1", date2, 9*3600+60, 9*3600+60),   // on time
+			canceled,
+		}
+		report := aggregatePerformance(observations, nil, opts)
+		s := report.Summary
+		assert.Equal(t, 4, s.ObservationCount)
+		assert.Equal(t, 2, s.OnTimeCount)
+		assert.Equal(t, 1, s.EarlyCount)
+		assert.Equal(t, 1, s.LateCount)
+		require.NotNil(t, s.OnTimePercent)
+		assert.Equal(t, 50.0, *s.OnTimePercent)
+		assert.Equal(t, 4, s.TripCount)
+		assert.Equal(t, 1, s.CanceledTripCount)
+		require.NotNil(t, s.Delay)
+		assert.Equal(t, -120, s.Delay.Min)
+		assert.Equal(t, 400, s.Delay.Max)
+		assert.Equal(t, 0, s.Delay.Median)
+		assert.Equal(t, 400, s.Delay.P90)
+		assert.InDelta(t, 77.5, s.Delay.Mean, 0.001)
+		binCount := 0
+		for _, bin := range s.Delay.Histogram {
+			binCount += bin.Count
+		}
+		assert.Equal(t, 4, binCount)
+		assert.Nil(t, s.Delay.Histogram[0].MinDelay)
+		assert.Nil(t, s.Delay.Histogram[len(s.Delay.Histogram)-1].MaxDelay)
+		assert.Nil(t, s.Headway)
+
+		require.Len(t, report.ByRoute, 2)
+		assert.Equal(t, "r1", *report.ByRoute[0].RouteID)
+		assert.Equal(t, 3, report.ByRoute[0].ObservationCount)
+		assert.Equal(t, 0, report.ByRoute[0].CanceledTripCount)
+		assert.Equal(t, "r2", *report.ByRoute[1].RouteID)
+		assert.Equal(t, 1, report.ByRoute[1].CanceledTripCount)
+		require.Len(t, report.ByStop, 2)
+		assert.Equal(t, "s1", *report.ByStop[0].StopID)
+		require.Len(t, report.ByHour, 2)
+		assert.Equal(t, 8, *report.ByHour[0].Hour)
+		assert.Equal(t, 2, report.ByHour[0].ObservationCount)
+		require.Len(t, report.ByDate, 2)
+		assert.Equal(t, "2026-05-11", report.ByDate[0].Date.Format("2006-01-02"))
+		assert.Equal(t, 3, report.ByDate[0].TripCount)
+
+		// Wider on-time window
+		report = aggregatePerformance(observations, nil, performanceOptions{earlyThreshold: 120, lateThreshold: 600})
+		assert.Equal(t, 4, report.Summary.OnTimeCount)
+	})
+
+	t.Run("time window", func(t *testing.T) {
+		observations := []*performanceObservation{
+			mkObs("TripUpdate", "t1", "r1", "s1", date, 8*3600, 8*3600),
+			mkObs("TripUpdate", "t2", "r1", "s1", date, 9*3600, 9*3600),
+			mkObs("TripUpdate", "t3", "r1", "s1", date, 10*3600, 10*3600),
+		}
+		report := aggregatePerformance(observations, nil, performanceOptions{startTime: tt.NewSeconds(9 * 3600), endTime: tt.NewSeconds(10 * 3600)})
+		assert.Equal(t, 1, report.Summary.ObservationCount)
+		require.Len(t, report.ByHour, 1)
+		assert.Equal(t, 9, *report.ByHour[0].Hour)
+	})
+
+	t.Run("trip updates are preferred", func(t *testing.T) {
+		observations := []*performanceObservation{
+			mkObs("VehiclePosition", "t1", "r1", "s1", date, 8*3600, 8*3600+900),
+			mkObs("TripUpdate", "t1", "r1", "s1", date, 8*3600, 8*3600),
+			mkObs("VehiclePosition", "t1", "r1", "s2", date, 8*3600+600, 8*3600+600),
+		}
+		report := aggregatePerformance(observations, nil, opts)
+		assert.Equal(t, 2, report.Summary.ObservationCount)
+		assert.Equal(t, 2, report.Summary.OnTimeCount)
+		report = aggregatePerformance(observations, nil, performanceOptions{source: "VehiclePosition", lateThreshold: 300})
+		assert.Equal(t, 3, report.Summary.ObservationCount)
+		assert.Equal(t, 1, report.Summary.LateCount)
+	})
+
+	t.Run("headways", func(t *testing.T) {
+		frequencies := map[string][]performanceFrequency{
+			"f1": {{TripID: "f1", StartTime: tt.NewSeconds(6 * 3600), EndTime: tt.NewSeconds(10 * 3600), HeadwaySecs: 600}},
+		}
+		var observations []*performanceObservation
+		// Trips every 10 minutes, observed 600, 200 and 1000 seconds apart
+		for i, observed := range []int{0, 600, 800, 1800} {
+			obs := mkObs("TripUpdate", "f1", "r1", "s1", date, 7*3600+i*600, 7*3600+observed)
+			obs.TripStartTime = tt.NewSeconds(7*3600 + i*600)
+			observations = append(observations, obs)
+		}
+		observations = append(observations, mkObs("TripUpdate", "t1", "r1", "s1", date, 7*3600, 7*3600))
+		report := aggregatePerformance(observations, frequencies, opts)
+		assert.Equal(t, 5, report.Summary.TripCount)
+		h := report.Summary.Headway
+		require.NotNil(t, h)
+		assert.Equal(t, 3, h.HeadwayCount)
+		assert.Equal(t, 600.0, h.ScheduledHeadwayMean)
+		assert.Equal(t, 600.0, h.ObservedHeadwayMean)
+		assert.Equal(t, 1, h.AdherentCount)
+		// (600^2 + 200^2 + 1000^2) / (2 * 1800) - 600 / 2
+		assert.InDelta(t, 88.889, h.ExcessWaitTime, 0.001)
+	})
+}
+
+func TestCheckPerformanceFilter(t *testing.T) {
+	d := func(v string) *tt.Date {
+		r, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ret := tt.NewDate(r)
+		return &ret
+	}
+	source := func(v string) *string { return &v }
+	threshold := func(v int) *int { return &v }
+	route := model.PerformanceScope{FeedVersionID: 1, RouteID: "01"}
+	agency := model.PerformanceScope{FeedVersionID: 1, AgencyID: "BART"}
+	tcs := []struct {
+		name      string
+		scope     model.PerformanceScope
+		where     *model.PerformanceFilter
+		expectErr bool
+	}{
+		{"nil", route, nil, true},
+		{"start date only", route, &model.PerformanceFilter{StartDate: *d("2026-05-11")}, false},
+		{"date range", route, &model.PerformanceFilter{StartDate: *d("2026-05-11"), EndDate: d("2026-06-11")}, false},
+		{"end before start", route, &model.PerformanceFilter{StartDate: *d("2026-05-11"), EndDate: d("2026-05-10")}, true},
+		{"range too long", route, &model.PerformanceFilter{StartDate: *d("2026-01-01"), EndDate: d("2027-01-02")}, true},
+		{"agency date range", agency, &model.PerformanceFilter{StartDate: *d("2026-05-01"), EndDate: d("2026-05-31")}, false},
+		{"agency range too long", agency, &model.PerformanceFilter{StartDate: *d("2026-05-11"), EndDate: d("2026-06-11")}, true},
+		{"source", route, &model.PerformanceFilter{StartDate: *d("2026-05-11"), Source: source("VehiclePosition")}, false},
+		{"invalid source", route, &model.PerformanceFilter{StartDate: *d("2026-05-11"), Source: source("Alert")}, true},
+		{"negative threshold", route, &model.PerformanceFilter{StartDate: *d("2026-05-11"), LateThreshold: threshold(-1)}, true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			opts, err := checkPerformanceFilter(tc.scope, tc.where)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.False(t, opts.endDate.Before(opts.startDate))
+		})
+	}
+}
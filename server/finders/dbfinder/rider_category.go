@@ -0,0 +1,67 @@
+package dbfinder
+
+import (
+	"context"
+
+	"github.com/interline-io/transitland-lib/server/dbutil"
+	"github.com/interline-io/transitland-lib/server/model"
+	sq "github.com/irees/squirrel"
+)
+
+func (f *Finder) RiderCategoriesByFeedVersionIDs(ctx context.Context, limit *int, where *model.RiderCategoryFilter, keys []int) ([][]*model.RiderCategory, error) {
+	var ents []*model.RiderCategory
+	q := riderCategorySelect(limit, nil, nil, where).Where(In("gtfs_rider_categories.feed_version_id", keys))
+	err := dbutil.Select(ctx, f.db, q, &ents)
+	return arrangeGroup(keys, ents, func(ent *model.RiderCategory) int { return ent.FeedVersionID }), err
+}
+
+func (f *Finder) RiderCategoriesByFeedVersionRiderCategoryIDs(ctx context.Context, keys []model.FVEntityID) ([]*model.RiderCategory, []error) {
+	var ents []*model.RiderCategory
+	for fvid, categoryIds := range groupFVEntityIDs(keys) {
+		q := riderCategorySelect(nil, nil, nil, nil).
+			Where(sq.Eq{"gtfs_rider_categories.feed_version_id": fvid}).
+			Where(In("gtfs_rider_categories.rider_category_id", categoryIds))
+		var group []*model.RiderCategory
+		if err := dbutil.Select(ctx, f.db, q, &group); err != nil {
+			return nil, logExtendErr(ctx, len(keys), err)
+		}
+		ents = append(ents, group...)
+	}
+	return arrangeBy(keys, ents, func(ent *model.RiderCategory) model.FVEntityID {
+		return model.FVEntityID{FeedVersionID: ent.FeedVersionID, EntityID: ent.RiderCategoryID.Val}
+	}), nil
+}
+
+func riderCategorySelect(limit *int, _ *model.Cursor, ids []int, where *model.RiderCategoryFilter) sq.SelectBuilder {
+	q := sq.StatementBuilder.Select(
+		"gtfs_rider_categories.id",
+		"gtfs_rider_categories.feed_version_id",
+		"gtfs_rider_categories.created_at",
+		"gtfs_rider_categories.updated_at",
+		"gtfs_rider_categories.rider_category_id",
+		"gtfs_rider_categories.rider_category_name",
+		"gtfs_rider_categories.min_age",
+		"gtfs_rider_categories.max_age",
+		"gtfs_rider_categories.is_default_fare_category",
+		"gtfs_rider_categories.eligibility_url",
+		"feed_versions.sha1 AS feed_version_sha1",
+		"current_feeds.onestop_id AS feed_onestop_id",
+	).From("gtfs_rider_categories").
+		Join("feed_versions ON feed_versions.id = gtfs_rider_categories.feed_version_id").
+		Join("current_feeds ON current_feeds.id = feed_versions.feed_id")
+
+	if len(ids) > 0 {
+		q = q.Where(In("gtfs_rider_categories.id", ids))
+	}
+	if where != nil {
+		if len(where.Ids) > 0 {
+			q = q.Where(In("gtfs_rider_categories.id", where.Ids))
+		}
+		if where.RiderCategoryID != nil && *where.RiderCategoryID != "" {
+			q = q.Where(sq.Eq{"gtfs_rider_categories.rider_category_id": *where.RiderCategoryID})
+		}
+	}
+	q = q.OrderBy("gtfs_rider_categories.id ASC")
+	q = q.Limit(finderCheckLimit(limit))
+	return q
+}
@@ -188,6 +188,25 @@ func (f *Finder) StopsByLevelIDs(ctx context.Context, limit *int, where *model.S
 	return arrangeGroup(keys, ents, func(ent *model.Stop) int { return ent.LevelID.Int() }), err
 }
 
+func (f *Finder) StopsByAreaIDs(ctx context.Context, limit *int, keys []int) ([][]*model.Stop, error) {
+	var ents []*model.Stop
+	err := dbutil.Select(ctx,
+		f.db,
+		lateralWrap(
+			stopSelect(limit, nil, nil, nil, f.PermFilter(ctx), nil).
+				Join("gtfs_stop_areas ON gtfs_stop_areas.stop_id = gtfs_stops.id").
+				Column("gtfs_stop_areas.area_id AS with_area_id"),
+			"gtfs_areas",
+			"id",
+			"gtfs_stop_areas",
+			"area_id",
+			keys,
+		),
+		&ents,
+	)
+	return arrangeGroup(keys, ents, func(ent *model.Stop) int { return ent.WithAreaID.Int() }), err
+}
+
 func (f *Finder) StopsByLocationGroupIDs(ctx context.Context, limit *int, keys []int) ([][]*model.Stop, error) {
 	var ents []*model.Stop
 	err := dbutil.Select(ctx,
@@ -23,13 +23,7 @@ func (f *Finder) StopTimesByTripIDs(ctx context.Context, limit *int, where *mode
 }
 
 func (f *Finder) StopTimesByStopIDs(ctx context.Context, limit *int, where *model.StopTimeFilter, keys []model.FVPair) ([][]*model.StopTime, error) {
-	ents, err := f.stopTimesByEntityIDs(ctx, stopTimeEntityStop, where, keys)
-	if err != nil {
-		return nil, err
-	}
-	return arrangeGroup(keys, ents, func(ent *model.StopTime) model.FVPair {
-		return model.FVPair{FeedVersionID: ent.FeedVersionID, EntityID: ent.StopID.Int()}
-	}), nil
+	return f.stopTimesByEntityIDs(ctx, stopTimeEntityStop, where, keys)
 }
 
 // FlexStopTimesByTripIDs returns flex stop times for the given trip IDs.
@@ -47,35 +41,27 @@ func (f *Finder) FlexStopTimesByStopIDs(ctx context.Context, limit *int, where *
 // FlexStopTimesByLocationIDs returns flex stop times for the given location IDs.
 // Used by the Location resolver to get stop_times for flex service areas.
 func (f *Finder) FlexStopTimesByLocationIDs(ctx context.Context, limit *int, where *model.StopTimeFilter, keys []model.FVPair) ([][]*model.FlexStopTime, error) {
-	ents, err := f.stopTimesByEntityIDs(ctx, stopTimeEntityLocation, where, keys)
-	if err != nil {
-		return nil, err
-	}
-	return arrangeGroup(keys, ents, func(ent *model.StopTime) model.FVPair {
-		return model.FVPair{FeedVersionID: ent.FeedVersionID, EntityID: ent.LocationID.Int()}
-	}), nil
+	return f.stopTimesByEntityIDs(ctx, stopTimeEntityLocation, where, keys)
 }
 
 // FlexStopTimesByLocationGroupIDs returns flex stop times for the given location group IDs.
 // Used by the LocationGroup resolver to get stop_times for flex service location groups.
 func (f *Finder) FlexStopTimesByLocationGroupIDs(ctx context.Context, limit *int, where *model.StopTimeFilter, keys []model.FVPair) ([][]*model.FlexStopTime, error) {
-	ents, err := f.stopTimesByEntityIDs(ctx, stopTimeEntityLocationGroup, where, keys)
-	if err != nil {
-		return nil, err
-	}
-	return arrangeGroup(keys, ents, func(ent *model.StopTime) model.FVPair {
-		return model.FVPair{FeedVersionID: ent.FeedVersionID, EntityID: ent.LocationGroupID.Int()}
-	}), nil
+	return f.stopTimesByEntityIDs(ctx, stopTimeEntityLocationGroup, where, keys)
 }
 
 // stopTimesByEntityIDs is the internal method that fetches stop_times for any entity type.
-// Public methods call this and arrange results by the appropriate grouping key.
-func (f *Finder) stopTimesByEntityIDs(ctx context.Context, entityType stopTimeEntityType, where *model.StopTimeFilter, keys []model.FVPair) ([]*model.StopTime, error) {
+// Results are arranged by key; with effective_schedule, a key's stop_times may
+// come from another feed version of the same feed.
+func (f *Finder) stopTimesByEntityIDs(ctx context.Context, entityType stopTimeEntityType, where *model.StopTimeFilter, keys []model.FVPair) ([][]*model.StopTime, error) {
 	pairGroups := map[int][]model.FVPair{}
 	for _, v := range keys {
 		pairGroups[v.FeedVersionID] = append(pairGroups[v.FeedVersionID], v)
 	}
+	effective := where != nil && nilOr(where.EffectiveSchedule, false)
 	var ents []*model.StopTime
+	// Keys of stop_times from a governing feed version, by the key they answer for
+	originKeys := map[*model.StopTime]model.FVPair{}
 	for fvid, entityPairs := range pairGroups {
 		fvsw, err := f.FindFeedVersionServiceWindow(ctx, fvid)
 		if err != nil {
@@ -87,6 +73,15 @@ func (f *Finder) stopTimesByEntityIDs(ctx context.Context, entityType stopTimeEn
 		if where != nil {
 			w := *where
 			fvWhere = &w
+			// The fallback week only applies to dates no version governs,
+			// which is known per service date.
+			if effective {
+				fvWhere.UseServiceWindow = nil
+			}
+		}
+		var entityKeys []int
+		for _, k := range entityPairs {
+			entityKeys = append(entityKeys, k.EntityID)
 		}
 		// Run separate queries for each possible service day
 		for _, w := range stopTimeFilterExpand(fvWhere, fvsw) {
@@ -94,15 +89,28 @@ func (f *Finder) stopTimesByEntityIDs(ctx context.Context, entityType stopTimeEn
 			if w != nil && w.ServiceDate != nil {
 				serviceDate = w.ServiceDate
 			}
+			qfvid := fvid
+			var origin map[model.FVPair]model.FVPair
 			var sts []*model.StopTime
 			var q sq.SelectBuilder
 			if serviceDate != nil {
 				// Get stop_times on a specified day
-				var entityKeys []int
-				for _, k := range entityPairs {
-					entityKeys = append(entityKeys, k.EntityID)
+				qkeys := entityKeys
+				if effective {
+					table, column := entityType.gtfsColumn()
+					qfvid, qkeys, origin, err = f.effectiveKeys(ctx, table, column, fvid, entityKeys, serviceDate.Val)
+					if err != nil {
+						return nil, err
+					}
+					if qfvid == fvid && nilOr(where.UseServiceWindow, false) {
+						serviceDate = mapIntoServiceWindow(serviceDate.Val, fvsw)
+						w.ServiceDate = serviceDate
+					}
+					if len(qkeys) == 0 {
+						continue
+					}
 				}
-				q = stopDeparturesSelect(fvid, entityKeys, entityType, w)
+				q = stopDeparturesSelect(qfvid, qkeys, entityType, w)
 			} else {
 				// Otherwise get all stop_times for entity
 				q = stopTimeSelect(entityPairs, entityType, nil)
@@ -122,10 +130,20 @@ func (f *Finder) stopTimesByEntityIDs(ctx context.Context, entityType stopTimeEn
 					}
 				}
 			}
+			for _, ent := range sts {
+				if origin != nil {
+					originKeys[ent] = origin[model.FVPair{FeedVersionID: ent.FeedVersionID, EntityID: entityType.entityID(ent)}]
+				}
+			}
 			ents = append(ents, sts...)
 		}
 	}
-	return ents, nil
+	return arrangeGroup(keys, ents, func(ent *model.StopTime) model.FVPair {
+		if k, ok := originKeys[ent]; ok {
+			return k
+		}
+		return model.FVPair{FeedVersionID: ent.FeedVersionID, EntityID: entityType.entityID(ent)}
+	}), nil
 }
 
 // stopTimeEntityType specifies which entity type to filter stop_times by
@@ -138,6 +156,32 @@ const (
 	stopTimeEntityLocationGroup
 )
 
+// entityID returns the id of the entity of this type a stop_time belongs to.
+func (e stopTimeEntityType) entityID(ent *model.StopTime) int {
+	switch e {
+	case stopTimeEntityTrip:
+		return ent.TripID.Int()
+	case stopTimeEntityLocation:
+		return ent.LocationID.Int()
+	case stopTimeEntityLocationGroup:
+		return ent.LocationGroupID.Int()
+	}
+	return ent.StopID.Int()
+}
+
+// gtfsColumn returns the table of this entity type and its GTFS id column.
+func (e stopTimeEntityType) gtfsColumn() (string, string) {
+	switch e {
+	case stopTimeEntityTrip:
+		return "gtfs_trips", "trip_id"
+	case stopTimeEntityLocation:
+		return "gtfs_locations", "location_id"
+	case stopTimeEntityLocationGroup:
+		return "gtfs_location_groups", "location_group_id"
+	}
+	return "gtfs_stops", "stop_id"
+}
+
 func stopTimeSelect(pairs []model.FVPair, entityType stopTimeEntityType, where *model.TripStopTimeFilter) sq.SelectBuilder {
 	q := sq.StatementBuilder.Select(
 		"gtfs_trips.journey_pattern_id",
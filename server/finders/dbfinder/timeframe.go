@@ -0,0 +1,65 @@
+package dbfinder
+
+import (
+	"context"
+
+	"github.com/interline-io/transitland-lib/server/dbutil"
+	"github.com/interline-io/transitland-lib/server/model"
+	sq "github.com/irees/squirrel"
+)
+
+func (f *Finder) TimeframesByFeedVersionIDs(ctx context.Context, limit *int, where *model.TimeframeFilter, keys []int) ([][]*model.Timeframe, error) {
+	var ents []*model.Timeframe
+	q := timeframeSelect(limit, nil, nil, where).Where(In("gtfs_timeframes.feed_version_id", keys))
+	err := dbutil.Select(ctx, f.db, q, &ents)
+	return arrangeGroup(keys, ents, func(ent *model.Timeframe) int { return ent.FeedVersionID }), err
+}
+
+func (f *Finder) TimeframesByFeedVersionTimeframeGroupIDs(ctx context.Context, limit *int, keys []model.FVEntityID) ([][]*model.Timeframe, error) {
+	var ents []*model.Timeframe
+	for fvid, groupIds := range groupFVEntityIDs(keys) {
+		q := timeframeSelect(limit, nil, nil, nil).
+			Where(sq.Eq{"gtfs_timeframes.feed_version_id": fvid}).
+			Where(In("gtfs_timeframes.timeframe_group_id", groupIds))
+		var group []*model.Timeframe
+		if err := dbutil.Select(ctx, f.db, q, &group); err != nil {
+			return nil, err
+		}
+		ents = append(ents, group...)
+	}
+	return arrangeGroup(keys, ents, func(ent *model.Timeframe) model.FVEntityID {
+		return model.FVEntityID{FeedVersionID: ent.FeedVersionID, EntityID: ent.TimeframeGroupID.Val}
+	}), nil
+}
+
+func timeframeSelect(limit *int, _ *model.Cursor, ids []int, where *model.TimeframeFilter) sq.SelectBuilder {
+	q := sq.StatementBuilder.Select(
+		"gtfs_timeframes.id",
+		"gtfs_timeframes.feed_version_id",
+		"gtfs_timeframes.created_at",
+		"gtfs_timeframes.updated_at",
+		"gtfs_timeframes.timeframe_group_id",
+		"gtfs_timeframes.start_time",
+		"gtfs_timeframes.end_time",
+		"gtfs_timeframes.service_id",
+		"feed_versions.sha1 AS feed_version_sha1",
+		"current_feeds.onestop_id AS feed_onestop_id",
+	).From("gtfs_timeframes").
+		Join("feed_versions ON feed_versions.id = gtfs_timeframes.feed_version_id").
+		Join("current_feeds ON current_feeds.id = feed_versions.feed_id")
+
+	if len(ids) > 0 {
+		q = q.Where(In("gtfs_timeframes.id", ids))
+	}
+	if where != nil {
+		if len(where.Ids) > 0 {
+			q = q.Where(In("gtfs_timeframes.id", where.Ids))
+		}
+		if where.TimeframeGroupID != nil && *where.TimeframeGroupID != "" {
+			q = q.Where(sq.Eq{"gtfs_timeframes.timeframe_group_id": *where.TimeframeGroupID})
+		}
+	}
+	q = q.OrderBy("gtfs_timeframes.id ASC")
+	q = q.Limit(finderCheckLimit(limit))
+	return q
+}
@@ -0,0 +1,92 @@
+package dbfinder
+
+import (
+	"context"
+
+	"github.com/interline-io/transitland-lib/server/dbutil"
+	"github.com/interline-io/transitland-lib/server/model"
+	sq "github.com/irees/squirrel"
+)
+
+func (f *Finder) TransfersByFeedVersionIDs(ctx context.Context, limit *int, where *model.TransferFilter, keys []int) ([][]*model.Transfer, error) {
+	var ents []*model.Transfer
+	q := transferSelect(limit, nil, nil, f.PermFilter(ctx), where).Where(In("gtfs_transfers.feed_version_id", keys))
+	err := dbutil.Select(ctx, f.db, q, &ents)
+	return arrangeGroup(keys, ents, func(ent *model.Transfer) int { return ent.FeedVersionID }), err
+}
+
+func (f *Finder) TransfersByFromStopIDs(ctx context.Context, limit *int, where *model.TransferFilter, keys []int) ([][]*model.Transfer, error) {
+	var ents []*model.Transfer
+	err := dbutil.Select(ctx,
+		f.db,
+		lateralWrap(
+			transferSelect(limit, nil, nil, f.PermFilter(ctx), where),
+			"gtfs_stops",
+			"id",
+			"gtfs_transfers",
+			"from_stop_id",
+			keys,
+		),
+		&ents,
+	)
+	return arrangeGroup(keys, ents, func(ent *model.Transfer) int { return ent.FromStopID.Int() }), err
+}
+
+func (f *Finder) TransfersByToStopIDs(ctx context.Context, limit *int, where *model.TransferFilter, keys []int) ([][]*model.Transfer, error) {
+	var ents []*model.Transfer
+	err := dbutil.Select(ctx,
+		f.db,
+		lateralWrap(
+			transferSelect(limit, nil, nil, f.PermFilter(ctx), where),
+			"gtfs_stops",
+			"id",
+			"gtfs_transfers",
+			"to_stop_id",
+			keys,
+		),
+		&ents,
+	)
+	return arrangeGroup(keys, ents, func(ent *model.Transfer) int { return ent.ToStopID.Int() }), err
+}
+
+func transferSelect(limit *int, after *model.Cursor, ids []int, permFilter *model.PermFilter, where *model.TransferFilter) sq.SelectBuilder {
+	q := sq.StatementBuilder.
+		Select(
+			"gtfs_transfers.id",
+			"gtfs_transfers.feed_version_id",
+			"gtfs_transfers.from_stop_id",
+			"gtfs_transfers.to_stop_id",
+			"gtfs_transfers.from_route_id",
+			"gtfs_transfers.to_route_id",
+			"gtfs_transfers.from_trip_id",
+			"gtfs_transfers.to_trip_id",
+			"gtfs_transfers.transfer_type",
+			"gtfs_transfers.min_transfer_time",
+			"gtfs_transfers.created_at",
+			"gtfs_transfers.updated_at",
+			"feed_versions.sha1 AS feed_version_sha1",
+			"current_feeds.onestop_id AS feed_onestop_id",
+		).
+		From("gtfs_transfers").
+		Join("feed_versions on feed_versions.id = gtfs_transfers.feed_version_id").
+		Join("current_feeds on current_feeds.id = feed_versions.feed_id").
+		Limit(finderCheckLimit(limit)).
+		OrderBy("gtfs_transfers.id")
+
+	if where != nil {
+		if where.TransferType != nil {
+			q = q.Where(sq.Eq{"gtfs_transfers.transfer_type": *where.TransferType})
+		}
+	}
+	if len(ids) > 0 {
+		q = q.Where(In("gtfs_transfers.id", ids))
+	}
+	if after != nil && after.Valid && after.ID > 0 {
+		q = q.Where(sq.Gt{"gtfs_transfers.id": after.ID})
+	}
+
+	// Handle permissions
+	q = joinImported(q)
+	q = pfJoinCheckFv(q, permFilter)
+	return q
+}
@@ -0,0 +1,101 @@
+package dbfinder
+
+import (
+	"context"
+
+	"github.com/interline-io/transitland-lib/server/dbutil"
+	"github.com/interline-io/transitland-lib/server/model"
+	sq "github.com/irees/squirrel"
+)
+
+// TranslationsByFeedVersionRecordIDs returns the translations of a GTFS table that apply to each key:
+// translations referencing the record by record_id, and translations matched by the key's field_value.
+func (f *Finder) TranslationsByFeedVersionRecordIDs(ctx context.Context, limit *int, tableName string, keys []model.TranslationKey) ([][]*model.Translation, error) {
+	type fvValue struct {
+		fvid  int
+		value string
+	}
+	type fvLookup struct {
+		recordIDs []string
+		values    []string
+	}
+	lookups := map[int]*fvLookup{}
+	for _, key := range keys {
+		lookup, ok := lookups[key.FeedVersionID]
+		if !ok {
+			lookup = &fvLookup{}
+			lookups[key.FeedVersionID] = lookup
+		}
+		if key.RecordID != "" {
+			lookup.recordIDs = append(lookup.recordIDs, key.RecordID)
+		}
+		if key.FieldValue != "" {
+			lookup.values = append(lookup.values, key.FieldValue)
+		}
+	}
+	byRecord := map[model.FVEntityID][]*model.Translation{}
+	byValue := map[fvValue][]*model.Translation{}
+	for fvid, lookup := range lookups {
+		match := sq.Or{}
+		if len(lookup.recordIDs) > 0 {
+			match = append(match, In("gtfs_translations.record_id", lookup.recordIDs))
+		}
+		if len(lookup.values) > 0 {
+			match = append(match, sq.And{
+				sq.Or{sq.Eq{"gtfs_translations.record_id": nil}, sq.Eq{"gtfs_translations.record_id": ""}},
+				In("gtfs_translations.field_value", lookup.values),
+			})
+		}
+		if len(match) == 0 {
+			continue
+		}
+		q := translationSelect(limit).
+			Where(sq.Eq{"gtfs_translations.feed_version_id": fvid}).
+			Where(sq.Eq{"gtfs_translations.table_name": tableName}).
+			Where(match)
+		var group []*model.Translation
+		if err := dbutil.Select(ctx, f.db, q, &group); err != nil {
+			return nil, err
+		}
+		for _, ent := range group {
+			if ent.RecordID.Val == "" {
+				k := fvValue{fvid: fvid, value: ent.FieldValue.Val}
+				byValue[k] = append(byValue[k], ent)
+			} else {
+				k := model.FVEntityID{FeedVersionID: fvid, EntityID: ent.RecordID.Val}
+				byRecord[k] = append(byRecord[k], ent)
+			}
+		}
+	}
+	ret := make([][]*model.Translation, len(keys))
+	for idx, key := range keys {
+		var ents []*model.Translation
+		if key.RecordID != "" {
+			ents = append(ents, byRecord[model.FVEntityID{FeedVersionID: key.FeedVersionID, EntityID: key.RecordID}]...)
+		}
+		if key.FieldValue != "" {
+			ents = append(ents, byValue[fvValue{fvid: key.FeedVersionID, value: key.FieldValue}]...)
+		}
+		ret[idx] = ents
+	}
+	return ret, nil
+}
+
+func translationSelect(limit *int) sq.SelectBuilder {
+	q := sq.StatementBuilder.Select(
+		"gtfs_translations.id",
+		"gtfs_translations.feed_version_id",
+		"gtfs_translations.table_name",
+		"gtfs_translations.field_name",
+		"gtfs_translations.field_value",
+		"gtfs_translations.language",
+		"gtfs_translations.translation",
+		"gtfs_translations.record_id",
+		"gtfs_translations.record_sub_id",
+	).From("gtfs_translations")
+	q = q.OrderBy("gtfs_translations.id ASC")
+	if limit != nil {
+		q = q.Limit(finderCheckLimit(limit))
+	}
+	return q
+}
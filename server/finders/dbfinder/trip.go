@@ -19,6 +19,11 @@ func (f *Finder) FindTrips(ctx context.Context, limit *int, after *model.Cursor,
 	if len(ids) > 0 || (where != nil && where.FeedVersionSha1 != nil) || (where != nil && len(where.RouteIds) > 0) {
 		active = false
 	}
+	// With effective_schedule, each active feed is answered by the feed version
+	// governing the service date instead of its active feed version.
+	if active && hasEffectiveTripDate(where) {
+		return f.findEffectiveTrips(ctx, limit, after, ids, where)
+	}
 	q, tripDates, err := tripSelect(limit, after, ids, active, f.PermFilter(ctx), where, nil)
 	if err != nil {
 		return nil, err
@@ -30,6 +35,42 @@ func (f *Finder) FindTrips(ctx context.Context, limit *int, after *model.Cursor,
 	return ents, nil
 }
 
+// findEffectiveTrips answers FindTrips with effective_schedule. The service date
+// is resolved for each feed, so a relative date follows the feed's timezone, and
+// feeds resolving to different dates are queried separately and merged in order.
+func (f *Finder) findEffectiveTrips(ctx context.Context, limit *int, after *model.Cursor, ids []int, where *model.TripFilter) ([]*model.Trip, error) {
+	groups, err := f.effectiveMaterializedFeedVersions(ctx, where)
+	if err != nil {
+		return nil, err
+	}
+	var ents []*model.Trip
+	for _, g := range groups {
+		q, tripDates, err := tripSelect(limit, after, ids, false, f.PermFilter(ctx), effectiveTripFilter(where, g.date), nil)
+		if err != nil {
+			return nil, err
+		}
+		q = q.Where(In("gtfs_trips.feed_version_id", g.fvids))
+		var group []*model.Trip
+		if err := dbutil.Select(ctx, f.db, q, &group); err != nil {
+			return nil, logErr(ctx, err)
+		}
+		expandTripServiceDates(group, tripDates)
+		ents = append(ents, group...)
+	}
+	if len(groups) > 1 {
+		sort.SliceStable(ents, func(i, j int) bool {
+			if ents[i].FeedVersionID != ents[j].FeedVersionID {
+				return ents[i].FeedVersionID < ents[j].FeedVersionID
+			}
+			return ents[i].ID < ents[j].ID
+		})
+		if n := int(finderCheckLimit(limit)); len(ents) > n {
+			ents = ents[:n]
+		}
+	}
+	return ents, nil
+}
+
 func (f *Finder) TripsByIDs(ctx context.Context, ids []int) ([]*model.Trip, []error) {
 	ents, err := f.FindTrips(ctx, nil, nil, ids, nil)
 	if err != nil {
@@ -218,6 +259,8 @@ func expandTripServiceDates(ents []*model.Trip, dates []tripDate) {
 
 func (f *Finder) TripsByRouteIDs(ctx context.Context, limit *int, where *model.TripFilter, keys []model.FVPair) ([][]*model.Trip, error) {
 	var ents []*model.Trip
+	// Keys of trips from a governing feed version, by the key they answer for
+	originKeys := map[*model.Trip]model.FVPair{}
 	// Group by fvid
 	groups := map[int][]int{}
 	for _, key := range keys {
@@ -228,7 +271,28 @@ func (f *Finder) TripsByRouteIDs(ctx context.Context, limit *int, where *model.T
 		if err != nil {
 			return nil, err
 		}
-		inner, tripDates, err := tripSelect(limit, nil, nil, false, f.PermFilter(ctx), where, fvsw)
+		// With effective_schedule, the routes' trips come from the feed version
+		// governing the service date, matched by GTFS route_id.
+		qfvid, qwhere, qids := fvid, where, entityIds
+		var origin map[model.FVPair]model.FVPair
+		if d, err := effectiveTripDate(where, fvsw); err != nil {
+			return nil, err
+		} else if d != nil {
+			qfvid, qids, origin, err = f.effectiveKeys(ctx, "gtfs_routes", "route_id", fvid, entityIds, d.Val)
+			if err != nil {
+				return nil, err
+			}
+			if qfvid != fvid {
+				if fvsw, err = f.FindFeedVersionServiceWindow(ctx, qfvid); err != nil {
+					return nil, err
+				}
+				qwhere = effectiveTripFilter(where, *d)
+			}
+			if len(qids) == 0 {
+				continue
+			}
+		}
+		inner, tripDates, err := tripSelect(limit, nil, nil, false, f.PermFilter(ctx), qwhere, fvsw)
 		if err != nil {
 			return nil, err
 		}
@@ -241,7 +305,7 @@ func (f *Finder) TripsByRouteIDs(ctx context.Context, limit *int, where *model.T
 				"id",
 				"gtfs_trips",
 				"route_id",
-				entityIds,
+				qids,
 			),
 			&q,
 		); err != nil {
@@ -250,9 +314,17 @@ func (f *Finder) TripsByRouteIDs(ctx context.Context, limit *int, where *model.T
 		// Per feed version: each resolves the requested dates against its own
 		// service window.
 		expandTripServiceDates(q, tripDates)
+		for _, ent := range q {
+			if origin != nil {
+				originKeys[ent] = origin[model.FVPair{FeedVersionID: ent.FeedVersionID, EntityID: ent.RouteID.Int()}]
+			}
+		}
 		ents = append(ents, q...)
 	}
 	return arrangeGroup(keys, ents, func(ent *model.Trip) model.FVPair {
+		if k, ok := originKeys[ent]; ok {
+			return k
+		}
 		return model.FVPair{FeedVersionID: ent.FeedVersionID, EntityID: ent.RouteID.Int()}
 	}), nil
 }
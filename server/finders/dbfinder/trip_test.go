@@ -229,3 +229,35 @@ func TestExpandTripServiceDates(t *testing.T) {
 		assert.Nil(t, got(ent))
 	})
 }
+
+func TestEffectiveTripDate(t *testing.T) {
+	fvsw := testServiceWindow(t)
+	today := model.RelativeDateToday
+	tcs := []struct {
+		name   string
+		where  *model.TripFilter
+		expect string
+	}{
+		{"service date", &model.TripFilter{EffectiveSchedule: ptr(true), ServiceDate: testDates(t, "2030-01-02")[0]}, "2030-01-02"},
+		{"relative date", &model.TripFilter{EffectiveSchedule: ptr(true), RelativeDate: &today}, "2026-05-13"},
+		{"no date", &model.TripFilter{EffectiveSchedule: ptr(true)}, ""},
+		{"without effective_schedule", &model.TripFilter{RelativeDate: &today}, ""},
+		{"multiple dates", &model.TripFilter{EffectiveSchedule: ptr(true), RelativeDate: &today, Dates: testDates(t, "2026-05-13")}, ""},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect != "", hasEffectiveTripDate(tc.where))
+			d, err := effectiveTripDate(tc.where, fvsw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.expect == "" {
+				assert.Nil(t, d)
+				return
+			}
+			if assert.NotNil(t, d) {
+				assert.Equal(t, tc.expect, d.Val.Format("2006-01-02"))
+			}
+		})
+	}
+}
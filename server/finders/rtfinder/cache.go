@@ -16,10 +16,15 @@ const (
 	// remembered as absent. Roughly a feed's publish cadence: checking more
 	// often cannot find anything new.
 	missingTTL = 1 * time.Minute
+	// removedTTL is how long, in feed time, an entity dropped from a topic is
+	// remembered for differential output.
+	removedTTL = 1 * time.Hour
 	// reconnectDelay paces re-subscription attempts.
 	reconnectDelay = 1 * time.Second
 	// updatesChannel carries topic pointers to the notify-then-read listeners.
 	updatesChannel = "rtfetch:updates"
+	// watchBuffer is how many pending update notifications a watcher may hold.
+	watchBuffer = 64
 )
 
 // storeCache is the RT Cache backed by a kvcache.Store. It keeps decoded
@@ -43,16 +48,19 @@ type storeCache struct {
 	// missing records topics the store had nothing for, so a caller looping
 	// over dozens of associated feeds does not re-read each one every time.
 	missing map[string]time.Time
+	// watchers receive the topic of each Source installed by an update.
+	watchers map[chan string]struct{}
 }
 
 func newStoreCache(store kvcache.Store) *storeCache {
 	ctx, cancel := context.WithCancel(context.Background())
 	c := &storeCache{
-		store:   store,
-		ctx:     ctx,
-		cancel:  cancel,
-		sources: map[string]*Source{},
-		missing: map[string]time.Time{},
+		store:    store,
+		ctx:      ctx,
+		cancel:   cancel,
+		sources:  map[string]*Source{},
+		missing:  map[string]time.Time{},
+		watchers: map[chan string]struct{}{},
 	}
 	if ps, ok := store.(kvcache.PubSubStore); ok {
 		c.pubsub = ps
@@ -131,6 +139,7 @@ func (c *storeCache) GetSource(ctx context.Context, topic string) (*Source, bool
 		c.lock.Unlock()
 		return existing, true
 	}
+	s.trackRemoved(nil)
 	c.sources[topic] = s
 	c.lock.Unlock()
 	return s, true
@@ -225,11 +234,46 @@ func (c *storeCache) watching(topic string) bool {
 	return ok
 }
 
-// putSource installs s as the local snapshot for topic, replacing any prior one.
+// putSource installs s as the local snapshot for topic, replacing any prior one,
+// and notifies watchers. Entities in the prior snapshot but not in s are
+// recorded as removed.
 func (c *storeCache) putSource(topic string, s *Source) {
 	c.lock.Lock()
+	s.trackRemoved(c.sources[topic])
 	c.sources[topic] = s
+	for ch := range c.watchers {
+		// A watcher that has fallen behind misses this notification rather
+		// than stalling distribution; the topic will be announced again on
+		// its next fetch.
+		select {
+		case ch <- topic:
+		default:
+		}
+	}
+	c.lock.Unlock()
+}
+
+// Watch returns a channel that receives the topic of each Source updated in
+// this process, whether by a local AddData or a pub/sub notification. Only
+// topics this process has already read are refreshed from pub/sub, so a
+// watcher should read its topics once before waiting on updates. The channel
+// is closed when ctx is canceled.
+func (c *storeCache) Watch(ctx context.Context) <-chan string {
+	ch := make(chan string, watchBuffer)
+	c.lock.Lock()
+	c.watchers[ch] = struct{}{}
 	c.lock.Unlock()
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-c.ctx.Done():
+		}
+		c.lock.Lock()
+		delete(c.watchers, ch)
+		close(ch)
+		c.lock.Unlock()
+	}()
+	return ch
 }
 
 func (c *storeCache) decode(ctx context.Context, topic string, data []byte) (*Source, error) {
@@ -72,6 +72,86 @@ func TestStoreCache_PubSubUpdate(t *testing.T) {
 	}, 5*time.Second, 100*time.Millisecond, "notification should refresh cached source to v2")
 }
 
+// Watchers are told of each updated topic, and their channel is closed when
+// they stop watching.
+func TestStoreCache_Watch(t *testing.T) {
+	c := newStoreCache(kvcache.NewMemoryStore())
+	defer c.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := c.Watch(ctx)
+	topic := fmt.Sprintf("rtwatch-%d", time.Now().UnixNano())
+	if err := c.AddData(context.Background(), topic, mkRTData(1000)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-updates:
+		assert.Equal(t, topic, got)
+	case <-time.After(time.Second):
+		t.Fatal("expected update notification")
+	}
+	cancel()
+	assert.Eventually(t, func() bool {
+		select {
+		case _, ok := <-updates:
+			return !ok
+		default:
+			return false
+		}
+	}, time.Second, 10*time.Millisecond, "channel should be closed after cancel")
+}
+
+// Entities dropped between updates are remembered as removed, for differential output.
+func TestStoreCache_Removed(t *testing.T) {
+	ctx := context.Background()
+	c := newStoreCache(kvcache.NewMemoryStore())
+	defer c.Close()
+	mkData := func(ts uint64, ids ...string) []byte {
+		v := "2.0"
+		msg := &pb.FeedMessage{Header: &pb.FeedHeader{GtfsRealtimeVersion: &v, Timestamp: &ts}}
+		for _, id := range ids {
+			msg.Entity = append(msg.Entity, &pb.FeedEntity{Id: proto.String(id)})
+		}
+		data, _ := proto.Marshal(msg)
+		return data
+	}
+	getRemoved := func(t *testing.T) (map[string]uint64, uint64) {
+		s, ok := c.GetSource(ctx, "removed")
+		require.True(t, ok)
+		return s.GetRemoved()
+	}
+	const t0 = uint64(100000)
+	require.NoError(t, c.AddData(ctx, "removed", mkData(t0, "a", "b", "c")))
+	removed, since := getRemoved(t)
+	assert.Empty(t, removed)
+	assert.Equal(t, t0, since)
+
+	require.NoError(t, c.AddData(ctx, "removed", mkData(t0+10, "a")))
+	removed, since = getRemoved(t)
+	assert.Equal(t, map[string]uint64{"b": t0 + 10, "c": t0 + 10}, removed)
+	assert.Equal(t, t0, since)
+
+	// A returning entity is no longer removed; earlier removal times are kept
+	require.NoError(t, c.AddData(ctx, "removed", mkData(t0+20, "b")))
+	removed, _ = getRemoved(t)
+	assert.Equal(t, map[string]uint64{"a": t0 + 20, "c": t0 + 10}, removed)
+
+	// Removals older than removedTTL are forgotten
+	later := t0 + 20 + uint64(removedTTL.Seconds())
+	require.NoError(t, c.AddData(ctx, "removed", mkData(later, "b")))
+	removed, since = getRemoved(t)
+	assert.Empty(t, removed)
+	assert.Equal(t, later-uint64(removedTTL.Seconds()), since)
+}
+
+func TestParseTopicKey(t *testing.T) {
+	update, ok := parseTopicKey(getTopicKey("BA~rt", "realtime_trip_updates"))
+	assert.True(t, ok)
+	assert.Equal(t, "BA~rt", update.FeedOnestopID)
+	assert.Equal(t, "realtime_trip_updates", update.MessageType)
+	_, ok = parseTopicKey("BA~rt")
+	assert.False(t, ok)
+}
+
 func mkRTData(ts uint64) []byte {
 	v := "2.0"
 	data, _ := proto.Marshal(&pb.FeedMessage{Header: &pb.FeedHeader{GtfsRealtimeVersion: &v, Timestamp: &ts}})
@@ -3,7 +3,9 @@ package rtfinder
 import (
 	"context"
 	"errors"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/interline-io/log"
@@ -18,6 +20,7 @@ import (
 type Cache interface {
 	AddData(context.Context, string, []byte) error
 	GetSource(context.Context, string) (*Source, bool)
+	Watch(context.Context) <-chan string
 	Close() error
 }
 
@@ -47,6 +50,27 @@ func (f *Finder) AddData(ctx context.Context, topic string, data []byte) error {
 	return f.cache.AddData(ctx, topic, data)
 }
 
+// WatchUpdates returns a channel that receives each realtime message updated in this process.
+// The channel is closed when ctx is canceled.
+func (f *Finder) WatchUpdates(ctx context.Context) <-chan model.RTUpdate {
+	out := make(chan model.RTUpdate, watchBuffer)
+	topics := f.cache.Watch(ctx)
+	go func() {
+		defer close(out)
+		for topicKey := range topics {
+			update, ok := parseTopicKey(topicKey)
+			if !ok {
+				continue
+			}
+			select {
+			case out <- update:
+			default:
+			}
+		}
+	}()
+	return out
+}
+
 func (f *Finder) GetGtfsTripID(ctx context.Context, id int) (string, bool) {
 	return f.lc.GetGtfsTripID(id)
 }
@@ -148,6 +172,18 @@ func (f *Finder) GetMessage(ctx context.Context, topic string, topicKey string)
 	return nil, false
 }
 
+// GetRemovedEntities returns the ids of entities removed from a realtime message,
+// with the time of removal, and the time from which the returned removals are complete.
+func (f *Finder) GetRemovedEntities(ctx context.Context, topic string, topicKey string) (map[string]uint64, uint64, bool) {
+	tk := getTopicKey(topic, topicKey)
+	a, ok := f.cache.GetSource(ctx, tk)
+	if a != nil && ok {
+		removed, since := a.GetRemoved()
+		return removed, since, true
+	}
+	return nil, 0, false
+}
+
 func (f *Finder) FindAlertsForAgency(ctx context.Context, t *model.Agency, limit *int, active *bool) []*model.Alert {
 	foundAlerts := []*model.Alert{}
 	topics, _ := f.lc.GetFeedVersionRTFeeds(t.FeedVersionID)
@@ -184,38 +220,55 @@ func (f *Finder) FindAlertsForAgency(ctx context.Context, t *model.Agency, limit
 }
 
 func (f *Finder) FindAlertsForStop(ctx context.Context, t *model.Stop, limit *int, active *bool) []*model.Alert {
-	foundAlerts := []*model.Alert{}
-	topics, _ := f.lc.GetFeedVersionRTFeeds(t.FeedVersionID)
-	tnow := f.Clock.Now()
-	for _, topic := range topics {
-		a, ok := f.cache.GetSource(ctx, getTopicKey(topic, "realtime_alerts"))
-		if a == nil || !ok {
-			continue
+	return limitAlerts(f.FindAlertsForStops(ctx, []*model.Stop{t}, active)[0], limit)
+}
+
+// FindAlertsForStops returns the alerts for each stop, checking the alerts of each feed version's RT feeds once.
+func (f *Finder) FindAlertsForStops(ctx context.Context, stops []*model.Stop, active *bool) [][]*model.Alert {
+	ret := make([][]*model.Alert, len(stops))
+	fvStops := map[int]map[string][]int{}
+	for i, stop := range stops {
+		ret[i] = []*model.Alert{}
+		if fvStops[stop.FeedVersionID] == nil {
+			fvStops[stop.FeedVersionID] = map[string][]int{}
 		}
-		for _, alert := range a.alerts {
-			if !checkAlertActivePeriod(tnow, active, alert) {
-				continue
-			}
-			if alert == nil {
+		fvStops[stop.FeedVersionID][stop.StopID.Val] = append(fvStops[stop.FeedVersionID][stop.StopID.Val], i)
+	}
+	tnow := f.Clock.Now()
+	for fvid, stopIdx := range fvStops {
+		topics, _ := f.lc.GetFeedVersionRTFeeds(fvid)
+		for _, topic := range topics {
+			a, ok := f.cache.GetSource(ctx, getTopicKey(topic, "realtime_alerts"))
+			if a == nil || !ok {
 				continue
 			}
-			found := false
-			for _, s := range alert.GetInformedEntity() {
-				// agency, route can be anything
-				// trip must be empty
-				if s == nil || s.Trip != nil {
+			for _, alert := range a.alerts {
+				if alert == nil {
 					continue
 				}
-				if s.GetStopId() == t.StopID.Val {
-					found = true
+				if !checkAlertActivePeriod(tnow, active, alert) {
+					continue
+				}
+				var found []int
+				for _, s := range alert.GetInformedEntity() {
+					// agency, route can be anything
+					// trip must be empty
+					if s == nil || s.Trip != nil {
+						continue
+					}
+					for _, i := range stopIdx[s.GetStopId()] {
+						if !slices.Contains(found, i) {
+							found = append(found, i)
+						}
+					}
+				}
+				for _, i := range found {
+					ret[i] = append(ret[i], makeAlert(alert))
 				}
-			}
-			if found {
-				foundAlerts = append(foundAlerts, makeAlert(alert))
 			}
 		}
 	}
-	return limitAlerts(foundAlerts, limit)
+	return ret
 }
 
 func (f *Finder) FindStopTimeUpdate(ctx context.Context, t *model.Trip, st *model.StopTime) (*model.RTStopTimeUpdate, bool) {
@@ -434,6 +487,19 @@ func getTopicKey(topic string, t string) string {
 	return "rtdata:" + topic + ":" + t
 }
 
+// parseTopicKey is the inverse of getTopicKey.
+func parseTopicKey(topicKey string) (model.RTUpdate, bool) {
+	v, ok := strings.CutPrefix(topicKey, "rtdata:")
+	if !ok {
+		return model.RTUpdate{}, false
+	}
+	i := strings.LastIndex(v, ":")
+	if i < 0 {
+		return model.RTUpdate{}, false
+	}
+	return model.RTUpdate{FeedOnestopID: v[:i], MessageType: v[i+1:]}, true
+}
+
 func copyPtr[T any, PT *T](v PT) PT {
 	if v == nil {
 		return nil
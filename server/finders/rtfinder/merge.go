@@ -0,0 +1,219 @@
+package rtfinder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/interline-io/log"
+	"github.com/interline-io/transitland-lib/dmfr"
+	"github.com/interline-io/transitland-lib/rt"
+	"github.com/interline-io/transitland-lib/rt/pb"
+	"github.com/interline-io/transitland-lib/server/model"
+	"google.golang.org/protobuf/proto"
+)
+
+// MergeMessageTypes are the realtime messages merged for each target.
+var MergeMessageTypes = []string{"realtime_trip_updates", "realtime_vehicle_positions", "realtime_alerts"}
+
+// defaultMergeInterval is how often all targets are merged again, in addition to merging on updates.
+const defaultMergeInterval = 30 * time.Second
+
+// defaultMergeMaxAge is the age after which a source message is no longer merged.
+const defaultMergeMaxAge = 5 * time.Minute
+
+// MergeTarget publishes the merged realtime data of several source feeds under the target feed.
+// The target may be an operator's realtime feed or a region that has no feed of its own.
+type MergeTarget struct {
+	Target  string
+	Sources []string
+}
+
+// ParseMergeTarget parses a target as "target=source1,source2".
+func ParseMergeTarget(v string) (MergeTarget, error) {
+	target, sources, ok := strings.Cut(v, "=")
+	ret := MergeTarget{Target: strings.TrimSpace(target)}
+	if !ok || ret.Target == "" {
+		return ret, fmt.Errorf("invalid merge target '%s', expected target=source1,source2", v)
+	}
+	for _, s := range strings.Split(sources, ",") {
+		s = strings.TrimSpace(s)
+		if s == ret.Target {
+			return ret, fmt.Errorf("merge target '%s' cannot be its own source", ret.Target)
+		}
+		if s != "" {
+			ret.Sources = append(ret.Sources, s)
+		}
+	}
+	if len(ret.Sources) == 0 {
+		return ret, fmt.Errorf("merge target '%s' has no sources", ret.Target)
+	}
+	return ret, nil
+}
+
+// CheckMergeLicenses returns an error if a target's license is less restrictive than the license of one of its sources.
+// Merged data is served under the target feed, so its license must also cover the data of every source.
+// A target without a feed record, such as a region, has no license terms and may only merge sources without restrictions.
+func CheckMergeLicenses(targets []MergeTarget, licenses map[string]dmfr.FeedLicense) error {
+	for _, t := range targets {
+		targetLicense := licenses[t.Target]
+		for _, s := range t.Sources {
+			sourceLicense := licenses[s]
+			for _, field := range mergeLicenseFields {
+				targetValue, sourceValue := field.value(targetLicense), field.value(sourceLicense)
+				if licenseValueRank(targetValue) > licenseValueRank(sourceValue) {
+					return fmt.Errorf("merge target '%s' license %s '%s' is less restrictive than source '%s' value '%s'", t.Target, field.name, targetValue, s, sourceValue)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+var mergeLicenseFields = []struct {
+	name  string
+	value func(dmfr.FeedLicense) string
+}{
+	{"use_without_attribution", func(l dmfr.FeedLicense) string { return l.UseWithoutAttribution }},
+	{"create_derived_product", func(l dmfr.FeedLicense) string { return l.CreateDerivedProduct }},
+	{"redistribution_allowed", func(l dmfr.FeedLicense) string { return l.RedistributionAllowed }},
+	{"commercial_use_allowed", func(l dmfr.FeedLicense) string { return l.CommercialUseAllowed }},
+	{"share_alike_optional", func(l dmfr.FeedLicense) string { return l.ShareAlikeOptional }},
+}
+
+// licenseValueRank orders license values from most restrictive ("no") to least restrictive ("yes").
+func licenseValueRank(v string) int {
+	switch v {
+	case "no":
+		return 0
+	case "yes":
+		return 2
+	}
+	return 1
+}
+
+// Merger republishes merged realtime messages from several source feeds.
+// Merged messages are stored with AddData, so they are served by the RTFinder
+// and the feed download endpoints like any fetched realtime feed.
+type Merger struct {
+	Interval time.Duration
+	// MaxAge excludes source messages with an older header timestamp; zero disables the check
+	MaxAge  time.Duration
+	finder  model.RTFinder
+	targets []MergeTarget
+	// published tracks the source timestamps of the last merge of each topic
+	published map[string]string
+}
+
+// NewMerger returns a Merger for the given targets.
+func NewMerger(finder model.RTFinder, targets []MergeTarget) (*Merger, error) {
+	sources := map[string]bool{}
+	for _, t := range targets {
+		for _, s := range t.Sources {
+			sources[s] = true
+		}
+	}
+	for _, t := range targets {
+		if sources[t.Target] {
+			return nil, fmt.Errorf("merge target '%s' is also a source", t.Target)
+		}
+	}
+	return &Merger{
+		Interval:  defaultMergeInterval,
+		MaxAge:    defaultMergeMaxAge,
+		finder:    finder,
+		targets:   targets,
+		published: map[string]string{},
+	}, nil
+}
+
+// Run merges all targets, then merges again when a source is updated
+// or the interval passes, until ctx is canceled.
+func (m *Merger) Run(ctx context.Context) error {
+	if len(m.targets) == 0 {
+		return errors.New("no merge targets")
+	}
+	// Watch before the first merge: sources are read by the merge,
+	// and only topics already read are refreshed from other processes.
+	updates := m.finder.WatchUpdates(ctx)
+	m.MergeAll(ctx)
+	ticker := time.NewTicker(m.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			m.MergeAll(ctx)
+		case update, ok := <-updates:
+			if !ok {
+				return nil
+			}
+			for _, t := range m.targets {
+				for _, s := range t.Sources {
+					if s == update.FeedOnestopID {
+						m.logMerge(ctx, t, update.MessageType)
+					}
+				}
+			}
+		}
+	}
+}
+
+// MergeAll merges every message type of every target.
+func (m *Merger) MergeAll(ctx context.Context) {
+	for _, t := range m.targets {
+		for _, msgType := range MergeMessageTypes {
+			m.logMerge(ctx, t, msgType)
+		}
+	}
+}
+
+// Merge merges one message type of a target and publishes the result.
+// Sources with a header timestamp older than MaxAge, or without one, are left out.
+// Nothing is published when no source has data, or when no source changed since the last merge;
+// once every source is left out, an empty message replaces the previously merged data.
+func (m *Merger) Merge(ctx context.Context, target MergeTarget, msgType string) (bool, error) {
+	var msgs []*pb.FeedMessage
+	var sig []string
+	for _, s := range target.Sources {
+		msg, ok := m.finder.GetMessage(ctx, s, msgType)
+		if !ok || msg == nil {
+			continue
+		}
+		if m.MaxAge > 0 {
+			ts := int64(msg.GetHeader().GetTimestamp())
+			if ts <= 0 || time.Since(time.Unix(ts, 0)) > m.MaxAge {
+				continue
+			}
+		}
+		msgs = append(msgs, msg)
+		sig = append(sig, s+":"+strconv.FormatUint(msg.GetHeader().GetTimestamp(), 10))
+	}
+	topic := getTopicKey(target.Target, msgType)
+	sigKey := strings.Join(sig, ",")
+	if m.published[topic] == sigKey {
+		return false, nil
+	}
+	data, err := proto.Marshal(rt.MergeFeedMessages(msgs...))
+	if err != nil {
+		return false, err
+	}
+	if err := m.finder.AddData(ctx, topic, data); err != nil {
+		return false, err
+	}
+	m.published[topic] = sigKey
+	return true, nil
+}
+
+func (m *Merger) logMerge(ctx context.Context, target MergeTarget, msgType string) {
+	ok, err := m.Merge(ctx, target, msgType)
+	if err != nil {
+		log.For(ctx).Error().Err(err).Str("target", target.Target).Str("message_type", msgType).Msg("rtmerge: failed to publish merged data")
+	} else if ok {
+		log.For(ctx).Trace().Str("target", target.Target).Str("message_type", msgType).Msg("rtmerge: published merged data")
+	}
+}
@@ -0,0 +1,191 @@
+package rtfinder
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/interline-io/transitland-lib/dmfr"
+	"github.com/interline-io/transitland-lib/rt/pb"
+	"github.com/interline-io/transitland-lib/server/caches/kvcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestParseMergeTarget(t *testing.T) {
+	tcs := []struct {
+		value     string
+		expect    MergeTarget
+		expectErr bool
+	}{
+		{value: "f-region=f-vp,f-tu", expect: MergeTarget{Target: "f-region", Sources: []string{"f-vp", "f-tu"}}},
+		{value: " f-region = f-vp, ,f-tu ", expect: MergeTarget{Target: "f-region", Sources: []string{"f-vp", "f-tu"}}},
+		{value: "f-region", expectErr: true},
+		{value: "=f-vp", expectErr: true},
+		{value: "f-region=", expectErr: true},
+		{value: "f-region=f-region,f-vp", expectErr: true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.value, func(t *testing.T) {
+			ret, err := ParseMergeTarget(tc.value)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expect, ret)
+		})
+	}
+}
+
+func TestNewMerger_TargetIsSource(t *testing.T) {
+	f := NewFinder(kvcache.NewMemoryStore(), nil)
+	defer f.Close()
+	_, err := NewMerger(f, []MergeTarget{
+		{Target: "a", Sources: []string{"b"}},
+		{Target: "b", Sources: []string{"c"}},
+	})
+	assert.Error(t, err)
+}
+
+func TestMerger_Merge(t *testing.T) {
+	ctx := context.Background()
+	f := NewFinder(kvcache.NewMemoryStore(), nil)
+	defer f.Close()
+	target := MergeTarget{Target: "merged", Sources: []string{"vendor-a", "vendor-b"}}
+	m, err := NewMerger(f, []MergeTarget{target})
+	require.NoError(t, err)
+	m.MaxAge = 0
+
+	// No data yet
+	ok, err := m.Merge(ctx, target, "realtime_vehicle_positions")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// Both vendors report bus1; vendor-b is fresher
+	require.NoError(t, f.AddData(ctx, getTopicKey("vendor-a", "realtime_vehicle_positions"), mkVehicleData(100, "a1", "bus1", 90)))
+	require.NoError(t, f.AddData(ctx, getTopicKey("vendor-b", "realtime_vehicle_positions"), mkVehicleData(100, "b1", "bus1", 95)))
+	ok, err = m.Merge(ctx, target, "realtime_vehicle_positions")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	msg, found := f.GetMessage(ctx, "merged", "realtime_vehicle_positions")
+	require.True(t, found)
+	if assert.Len(t, msg.Entity, 1) {
+		assert.Equal(t, "b1", msg.Entity[0].GetId())
+	}
+
+	// Unchanged sources are not published again
+	ok, err = m.Merge(ctx, target, "realtime_vehicle_positions")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// A newer message from vendor-a replaces it
+	require.NoError(t, f.AddData(ctx, getTopicKey("vendor-a", "realtime_vehicle_positions"), mkVehicleData(110, "a1", "bus1", 105)))
+	ok, err = m.Merge(ctx, target, "realtime_vehicle_positions")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	msg, _ = f.GetMessage(ctx, "merged", "realtime_vehicle_positions")
+	if assert.Len(t, msg.Entity, 1) {
+		assert.Equal(t, "a1", msg.Entity[0].GetId())
+	}
+	assert.Equal(t, uint64(110), msg.GetHeader().GetTimestamp())
+}
+
+func TestMerger_MaxAge(t *testing.T) {
+	ctx := context.Background()
+	f := NewFinder(kvcache.NewMemoryStore(), nil)
+	defer f.Close()
+	target := MergeTarget{Target: "merged", Sources: []string{"vendor-a", "vendor-b"}}
+	m, err := NewMerger(f, []MergeTarget{target})
+	require.NoError(t, err)
+	m.MaxAge = time.Minute
+	now := uint64(time.Now().Unix())
+
+	// vendor-a stopped updating an hour ago
+	require.NoError(t, f.AddData(ctx, getTopicKey("vendor-a", "realtime_vehicle_positions"), mkVehicleData(now-3600, "a1", "bus1", now-3600)))
+	require.NoError(t, f.AddData(ctx, getTopicKey("vendor-b", "realtime_vehicle_positions"), mkVehicleData(now, "b1", "bus2", now)))
+	ok, err := m.Merge(ctx, target, "realtime_vehicle_positions")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	msg, _ := f.GetMessage(ctx, "merged", "realtime_vehicle_positions")
+	if assert.Len(t, msg.Entity, 1) {
+		assert.Equal(t, "b1", msg.Entity[0].GetId())
+	}
+
+	// Once every source is stale, the merged data is cleared
+	require.NoError(t, f.AddData(ctx, getTopicKey("vendor-b", "realtime_vehicle_positions"), mkVehicleData(now-3600, "b1", "bus2", now-3600)))
+	ok, err = m.Merge(ctx, target, "realtime_vehicle_positions")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	msg, _ = f.GetMessage(ctx, "merged", "realtime_vehicle_positions")
+	assert.Empty(t, msg.Entity)
+	ok, err = m.Merge(ctx, target, "realtime_vehicle_positions")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestCheckMergeLicenses(t *testing.T) {
+	open := dmfr.FeedLicense{RedistributionAllowed: "yes", CommercialUseAllowed: "yes"}
+	noRedist := dmfr.FeedLicense{RedistributionAllowed: "no", CommercialUseAllowed: "yes"}
+	tcs := []struct {
+		name      string
+		licenses  map[string]dmfr.FeedLicense
+		expectErr bool
+	}{
+		{"same license", map[string]dmfr.FeedLicense{"merged": open, "vendor-a": open, "vendor-b": open}, false},
+		{"more restrictive target", map[string]dmfr.FeedLicense{"merged": noRedist, "vendor-a": open, "vendor-b": open}, false},
+		{"less restrictive target", map[string]dmfr.FeedLicense{"merged": open, "vendor-a": open, "vendor-b": noRedist}, true},
+		{"unknown target value", map[string]dmfr.FeedLicense{"merged": {}, "vendor-a": open, "vendor-b": noRedist}, true},
+		{"target without feed", map[string]dmfr.FeedLicense{"vendor-a": {}, "vendor-b": {}}, false},
+		{"target without feed and restricted source", map[string]dmfr.FeedLicense{"vendor-a": noRedist}, true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckMergeLicenses([]MergeTarget{{Target: "merged", Sources: []string{"vendor-a", "vendor-b"}}}, tc.licenses)
+			if tc.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestMerger_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f := NewFinder(kvcache.NewMemoryStore(), nil)
+	defer f.Close()
+	m, err := NewMerger(f, []MergeTarget{{Target: "merged", Sources: []string{"vendor-a", "vendor-b"}}})
+	require.NoError(t, err)
+	m.Interval = time.Hour
+	m.MaxAge = 0
+	done := make(chan error)
+	go func() {
+		done <- m.Run(ctx)
+	}()
+
+	// Published on update
+	require.NoError(t, f.AddData(ctx, getTopicKey("vendor-b", "realtime_vehicle_positions"), mkVehicleData(100, "b1", "bus1", 95)))
+	assert.Eventually(t, func() bool {
+		msg, ok := f.GetMessage(ctx, "merged", "realtime_vehicle_positions")
+		return ok && len(msg.Entity) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func mkVehicleData(ts uint64, eid string, vehicleID string, vehicleTs uint64) []byte {
+	data, _ := proto.Marshal(&pb.FeedMessage{
+		Header: &pb.FeedHeader{GtfsRealtimeVersion: proto.String("2.0"), Timestamp: proto.Uint64(ts)},
+		Entity: []*pb.FeedEntity{{
+			Id: proto.String(eid),
+			Vehicle: &pb.VehiclePosition{
+				Vehicle:   &pb.VehicleDescriptor{Id: proto.String(vehicleID)},
+				Timestamp: proto.Uint64(vehicleTs),
+			},
+		}},
+	})
+	return data
+}
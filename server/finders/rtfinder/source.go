@@ -2,6 +2,7 @@ package rtfinder
 
 import (
 	"context"
+	"time"
 
 	"github.com/interline-io/log"
 	"github.com/interline-io/transitland-lib/rt/pb"
@@ -14,6 +15,10 @@ type Source struct {
 	entityByTrip     map[string]*pb.TripUpdate
 	alerts           []*pb.Alert
 	vehiclePositions []VehiclePositionEntity
+	// removed maps the ids of entities dropped from the feed to the feed time
+	// they were last seen missing; it is complete from removedSince onward.
+	removed      map[string]uint64
+	removedSince uint64
 }
 
 // VehiclePositionEntity pairs a vehicle position with the id of the FeedEntity
@@ -44,6 +49,51 @@ func (f *Source) GetTrip(tid string) (*pb.TripUpdate, bool) {
 	return nil, false
 }
 
+// GetRemoved returns the ids of entities removed from the feed, with the time
+// of removal, and the time from which the returned removals are complete.
+func (f *Source) GetRemoved() (map[string]uint64, uint64) {
+	return f.removed, f.removedSince
+}
+
+// trackRemoved carries forward the removals known to prev, the Source this one
+// replaces, and adds the entities prev had that this message does not.
+// Removals older than removedTTL of feed time are forgotten.
+func (f *Source) trackRemoved(prev *Source) {
+	ts := f.GetTimestamp()
+	if ts == 0 {
+		ts = uint64(time.Now().Unix())
+	}
+	if prev == nil || prev.msg == nil {
+		// Nothing is known about entities removed before this message
+		f.removed = map[string]uint64{}
+		f.removedSince = ts
+		return
+	}
+	current := map[string]bool{}
+	for _, ent := range f.msg.GetEntity() {
+		current[ent.GetId()] = true
+	}
+	cutoff := uint64(0)
+	if ttl := uint64(removedTTL.Seconds()); ts > ttl {
+		cutoff = ts - ttl
+	}
+	removed := map[string]uint64{}
+	for eid, rts := range prev.removed {
+		if rts > cutoff && !current[eid] {
+			removed[eid] = rts
+		}
+	}
+	for _, ent := range prev.msg.GetEntity() {
+		if eid := ent.GetId(); eid != "" && !current[eid] {
+			if _, ok := removed[eid]; !ok {
+				removed[eid] = ts
+			}
+		}
+	}
+	f.removed = removed
+	f.removedSince = max(prev.removedSince, cutoff)
+}
+
 func (f *Source) GetVehiclePositions() []VehiclePositionEntity {
 	return f.vehiclePositions
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/interline-io/transitland-lib/server/model"
+	"github.com/interline-io/transitland-lib/tt"
 )
 
 // AGENCY
@@ -38,3 +39,18 @@ func (r *agencyResolver) Alerts(ctx context.Context, obj *model.Agency, active *
 	rtAlerts := model.ForContext(ctx).RTFinder.FindAlertsForAgency(ctx, obj, resolverCheckLimit(limit), active)
 	return rtAlerts, nil
 }
+
+func (r *agencyResolver) Performance(ctx context.Context, obj *model.Agency, where model.PerformanceFilter) (*model.PerformanceReport, error) {
+	return model.ForContext(ctx).Finder.FindPerformance(ctx, model.PerformanceScope{FeedVersionID: obj.FeedVersionID, AgencyID: obj.AgencyID.Val}, &where)
+}
+
+func (r *agencyResolver) AgencyName(ctx context.Context, obj *model.Agency, lang *string) (string, error) {
+	v, err := translateField(ctx, lang, obj.FeedVersionID, "agency", obj.AgencyID.Val, "", "agency_name", obj.AgencyName)
+	return v.Val, err
+}
+
+func (r *agencyResolver) Translations(ctx context.Context, obj *model.Agency, lang *string) ([]*model.Translation, error) {
+	return recordTranslations(ctx, lang, obj.FeedVersionID, "agency", obj.AgencyID.Val, map[string]tt.String{
+		"agency_name": obj.AgencyName,
+	})
+}
@@ -0,0 +1,20 @@
+package gql
+
+import (
+	"context"
+
+	"github.com/interline-io/transitland-lib/server/model"
+)
+
+type areaResolver struct{ *Resolver }
+
+func (r *areaResolver) FeedVersion(ctx context.Context, obj *model.Area) (*model.FeedVersion, error) {
+	return LoaderFor(ctx).FeedVersionsByIDs.Load(ctx, obj.FeedVersionID)()
+}
+
+func (r *areaResolver) Stops(ctx context.Context, obj *model.Area, limit *int) ([]*model.Stop, error) {
+	return LoaderFor(ctx).StopsByAreaIDs.Load(ctx, stopsByAreaLoaderParam{
+		AreaID: obj.ID,
+		Limit:  resolverCheckLimit(limit),
+	})()
+}
@@ -2,9 +2,11 @@ package gql
 
 import (
 	"context"
+	"errors"
 
 	"github.com/interline-io/transitland-lib/server/directions"
 	"github.com/interline-io/transitland-lib/server/model"
+	"github.com/interline-io/transitland-lib/tt"
 )
 
 type directionsResolver struct{ *Resolver }
@@ -13,3 +15,43 @@ type directionsResolver struct{ *Resolver }
 func (r *directionsResolver) Directions(ctx context.Context, where model.DirectionRequest) (*model.Directions, error) {
 	return directions.HandleRequest(ctx, "", where)
 }
+
+func (r *directionsResolver) Reachability(ctx context.Context, where model.ReachabilityRequest) (*model.Reachability, error) {
+	return directions.HandleReachabilityRequest(ctx, where)
+}
+
+type isochroneResolver struct{ *Resolver }
+
+// CensusGeographies searches within each polygon of the isochrone.
+func (r *isochroneResolver) CensusGeographies(ctx context.Context, obj *model.Isochrone, limit *int, where *model.CensusGeographyFilter) ([]*model.CensusGeography, error) {
+	if where == nil || where.Dataset == nil {
+		return nil, errors.New("dataset is required")
+	}
+	datasets, err := model.ForContext(ctx).Finder.FindCensusDatasets(ctx, nil, nil, nil, &model.CensusDatasetFilter{Name: where.Dataset})
+	if err != nil || len(datasets) == 0 || obj.Geometry.Val == nil {
+		return nil, err
+	}
+	limit = resolverCheckLimitMax(limit, RESOLVER_CENSUS_MAXLIMIT)
+	var ret []*model.CensusGeography
+	for i := 0; i < obj.Geometry.Val.NumPolygons() && len(ret) < *limit; i++ {
+		within := tt.NewPolygon(obj.Geometry.Val.Polygon(i))
+		ents, err := LoaderFor(ctx).CensusGeographiesByDatasetIDs.Load(ctx, censusDatasetGeographyLoaderParam{
+			DatasetID: datasets[0].ID,
+			Limit:     limit,
+			Where: &model.CensusDatasetGeographyFilter{
+				Dataset:  where.Dataset,
+				Layer:    where.Layer,
+				Search:   where.Search,
+				Location: &model.CensusDatasetGeographyLocationFilter{Within: &within},
+			},
+		})()
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, ents...)
+	}
+	if len(ret) > *limit {
+		ret = ret[:*limit]
+	}
+	return ret, nil
+}
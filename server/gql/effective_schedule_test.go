@@ -0,0 +1,104 @@
+package gql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/interline-io/transitland-lib/internal/testconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+// BA has two imported feed versions: the active one (feed_info 2018-05-26 to
+// 2019-07-01) and bart-old.zip (2016-02-08 to 2017-01-01). With
+// effective_schedule, a date only the old version covers is answered from it.
+const (
+	effectiveOldSha1 = "dd7aca4a8e4c90908fd3603c097fabee75fea907"
+	effectiveCurSha1 = "e535eb2b3b9ac3ef15d82c56575e914575e732e0"
+)
+
+func TestEffectiveSchedule(t *testing.T) {
+	c, _ := newTestClient(t)
+	stopQuery := `query($where:StopTimeFilter){stops(where:{feed_onestop_id:"BA", stop_id:"MONT"}){departures(where:$where){ service_date stop { stop_id } trip { feed_version { sha1 } } }}}`
+	tcs := []testcase{
+		{
+			name:               "departures from the version governing the date",
+			query:              stopQuery,
+			vars:               hw{"where": hw{"service_date": "2016-05-02", "start": "10:00:00", "end": "11:00:00", "effective_schedule": true}},
+			selector:           "stops.0.departures.#.trip.feed_version.sha1",
+			selectExpectUnique: []string{effectiveOldSha1},
+		},
+		{
+			name:               "departures keep the requested service date and stop",
+			query:              stopQuery,
+			vars:               hw{"where": hw{"service_date": "2016-05-02", "start": "10:00:00", "end": "11:00:00", "effective_schedule": true}},
+			selector:           "stops.0.departures.#.stop.stop_id",
+			selectExpectUnique: []string{"MONT"},
+		},
+		{
+			name:              "departures outside the active version without effective_schedule",
+			query:             stopQuery,
+			vars:              hw{"where": hw{"service_date": "2016-05-02", "start": "10:00:00", "end": "11:00:00"}},
+			selector:          "stops.0.departures.#.trip.feed_version.sha1",
+			selectExpectCount: 0,
+		},
+		{
+			name:               "departures in the active version's window",
+			query:              stopQuery,
+			vars:               hw{"where": hw{"service_date": "2018-06-04", "start": "10:00:00", "end": "11:00:00", "effective_schedule": true}},
+			selector:           "stops.0.departures.#.trip.feed_version.sha1",
+			selectExpectUnique: []string{effectiveCurSha1},
+		},
+		{
+			name:               "route trips",
+			query:              `query{routes(where:{feed_onestop_id:"BA", route_id:"01"}){trips(where:{service_date:"2016-05-02", effective_schedule:true}){ feed_version { sha1 } }}}`,
+			selector:           "routes.0.trips.#.feed_version.sha1",
+			selectExpectUnique: []string{effectiveOldSha1},
+		},
+		{
+			name:               "trips",
+			query:              `query{trips(where:{feed_onestop_id:"BA", service_date:"2016-05-02", effective_schedule:true}){ feed_version { sha1 } }}`,
+			selector:           "trips.#.feed_version.sha1",
+			selectExpectUnique: []string{effectiveOldSha1},
+		},
+		{
+			name:               "trips without effective_schedule",
+			query:              `query{trips(where:{feed_onestop_id:"BA", service_date:"2018-06-04"}){ feed_version { sha1 } }}`,
+			selector:           "trips.#.feed_version.sha1",
+			selectExpectUnique: []string{effectiveCurSha1},
+		},
+		{
+			name:  "route headways",
+			query: `query{routes(where:{feed_onestop_id:"BA", route_id:"01"}){headways(effective_date:"2016-05-02"){ service_date }}}`,
+			f: func(t *testing.T, jj string) {
+				dates := gjson.Get(jj, "routes.0.headways.#.service_date").Array()
+				assert.NotEmpty(t, dates)
+				for _, d := range dates {
+					assert.True(t, strings.HasPrefix(d.String(), "2016-"), "headway service_date %s", d.String())
+				}
+			},
+		},
+	}
+	queryTestcases(t, c, tcs)
+}
+
+// A relative date is resolved before finding the governing feed version.
+func TestEffectiveSchedule_RelativeDate(t *testing.T) {
+	// Monday 2016-05-02, 11:00 in America/Los_Angeles
+	c, _ := newTestClientWithOpts(t, testconfig.Options{WhenUtc: "2016-05-02T18:00:00Z"})
+	tcs := []testcase{
+		{
+			name:               "trips",
+			query:              `query{trips(where:{feed_onestop_id:"BA", relative_date:TODAY, effective_schedule:true}){ feed_version { sha1 } }}`,
+			selector:           "trips.#.feed_version.sha1",
+			selectExpectUnique: []string{effectiveOldSha1},
+		},
+		{
+			name:               "route trips",
+			query:              `query{routes(where:{feed_onestop_id:"BA", route_id:"01"}){trips(where:{relative_date:TODAY, effective_schedule:true}){ feed_version { sha1 } }}}`,
+			selector:           "routes.0.trips.#.feed_version.sha1",
+			selectExpectUnique: []string{effectiveOldSha1},
+		},
+	}
+	queryTestcases(t, c, tcs)
+}
@@ -0,0 +1,155 @@
+package gql
+
+import (
+	"context"
+
+	"github.com/interline-io/transitland-lib/server/model"
+)
+
+// FARE PRODUCT
+
+type fareProductResolver struct{ *Resolver }
+
+func (r *fareProductResolver) FeedVersion(ctx context.Context, obj *model.FareProduct) (*model.FeedVersion, error) {
+	return LoaderFor(ctx).FeedVersionsByIDs.Load(ctx, obj.FeedVersionID)()
+}
+
+func (r *fareProductResolver) RiderCategory(ctx context.Context, obj *model.FareProduct) (*model.RiderCategory, error) {
+	if !obj.RiderCategoryID.Valid {
+		return nil, nil
+	}
+	return LoaderFor(ctx).RiderCategoriesByFeedVersionRiderCategoryIDs.Load(ctx, model.FVEntityID{FeedVersionID: obj.FeedVersionID, EntityID: obj.RiderCategoryID.Val})()
+}
+
+func (r *fareProductResolver) FareMedia(ctx context.Context, obj *model.FareProduct) (*model.FareMedia, error) {
+	// fare_media_id is stored as a reference to gtfs_fare_media.id
+	if !obj.FareMediaID.Valid {
+		return nil, nil
+	}
+	return LoaderFor(ctx).FareMediaByIDs.Load(ctx, obj.FareMediaID.Int())()
+}
+
+// FARE MEDIA
+
+type fareMediaResolver struct{ *Resolver }
+
+func (r *fareMediaResolver) FeedVersion(ctx context.Context, obj *model.FareMedia) (*model.FeedVersion, error) {
+	return LoaderFor(ctx).FeedVersionsByIDs.Load(ctx, obj.FeedVersionID)()
+}
+
+// RIDER CATEGORY
+
+type riderCategoryResolver struct{ *Resolver }
+
+func (r *riderCategoryResolver) FeedVersion(ctx context.Context, obj *model.RiderCategory) (*model.FeedVersion, error) {
+	return LoaderFor(ctx).FeedVersionsByIDs.Load(ctx, obj.FeedVersionID)()
+}
+
+// FARE LEG RULE
+
+type fareLegRuleResolver struct{ *Resolver }
+
+func (r *fareLegRuleResolver) FeedVersion(ctx context.Context, obj *model.FareLegRule) (*model.FeedVersion, error) {
+	return LoaderFor(ctx).FeedVersionsByIDs.Load(ctx, obj.FeedVersionID)()
+}
+
+func (r *fareLegRuleResolver) FromArea(ctx context.Context, obj *model.FareLegRule) (*model.Area, error) {
+	if !obj.FromAreaID.Valid {
+		return nil, nil
+	}
+	return LoaderFor(ctx).AreasByFeedVersionAreaIDs.Load(ctx, model.FVEntityID{FeedVersionID: obj.FeedVersionID, EntityID: obj.FromAreaID.Val})()
+}
+
+func (r *fareLegRuleResolver) ToArea(ctx context.Context, obj *model.FareLegRule) (*model.Area, error) {
+	if !obj.ToAreaID.Valid {
+		return nil, nil
+	}
+	return LoaderFor(ctx).AreasByFeedVersionAreaIDs.Load(ctx, model.FVEntityID{FeedVersionID: obj.FeedVersionID, EntityID: obj.ToAreaID.Val})()
+}
+
+func (r *fareLegRuleResolver) FromTimeframes(ctx context.Context, obj *model.FareLegRule) ([]*model.Timeframe, error) {
+	if !obj.FromTimeframeGroupID.Valid {
+		return nil, nil
+	}
+	return LoaderFor(ctx).TimeframesByFeedVersionTimeframeGroupIDs.Load(ctx, timeframesByTimeframeGroupIDLoaderParam{FeedVersionID: obj.FeedVersionID, TimeframeGroupID: obj.FromTimeframeGroupID.Val})()
+}
+
+func (r *fareLegRuleResolver) ToTimeframes(ctx context.Context, obj *model.FareLegRule) ([]*model.Timeframe, error) {
+	if !obj.ToTimeframeGroupID.Valid {
+		return nil, nil
+	}
+	return LoaderFor(ctx).TimeframesByFeedVersionTimeframeGroupIDs.Load(ctx, timeframesByTimeframeGroupIDLoaderParam{FeedVersionID: obj.FeedVersionID, TimeframeGroupID: obj.ToTimeframeGroupID.Val})()
+}
+
+func (r *fareLegRuleResolver) FareProducts(ctx context.Context, obj *model.FareLegRule) ([]*model.FareProduct, error) {
+	if !obj.FareProductID.Valid {
+		return nil, nil
+	}
+	return LoaderFor(ctx).FareProductsByFeedVersionFareProductIDs.Load(ctx, fareProductsByFareProductIDLoaderParam{FeedVersionID: obj.FeedVersionID, FareProductID: obj.FareProductID.Val})()
+}
+
+// FARE LEG JOIN RULE
+
+type fareLegJoinRuleResolver struct{ *Resolver }
+
+func (r *fareLegJoinRuleResolver) FeedVersion(ctx context.Context, obj *model.FareLegJoinRule) (*model.FeedVersion, error) {
+	return LoaderFor(ctx).FeedVersionsByIDs.Load(ctx, obj.FeedVersionID)()
+}
+
+func (r *fareLegJoinRuleResolver) FromStop(ctx context.Context, obj *model.FareLegJoinRule) (*model.Stop, error) {
+	if !obj.FromStopID.Valid {
+		return nil, nil
+	}
+	return LoaderFor(ctx).StopsByFeedVersionStopIDs.Load(ctx, model.FVEntityID{FeedVersionID: obj.FeedVersionID, EntityID: obj.FromStopID.Val})()
+}
+
+func (r *fareLegJoinRuleResolver) ToStop(ctx context.Context, obj *model.FareLegJoinRule) (*model.Stop, error) {
+	if !obj.ToStopID.Valid {
+		return nil, nil
+	}
+	return LoaderFor(ctx).StopsByFeedVersionStopIDs.Load(ctx, model.FVEntityID{FeedVersionID: obj.FeedVersionID, EntityID: obj.ToStopID.Val})()
+}
+
+// FARE TRANSFER RULE
+
+type fareTransferRuleResolver struct{ *Resolver }
+
+func (r *fareTransferRuleResolver) FeedVersion(ctx context.Context, obj *model.FareTransferRule) (*model.FeedVersion, error) {
+	return LoaderFor(ctx).FeedVersionsByIDs.Load(ctx, obj.FeedVersionID)()
+}
+
+func (r *fareTransferRuleResolver) FromLegRules(ctx context.Context, obj *model.FareTransferRule) ([]*model.FareLegRule, error) {
+	if !obj.FromLegGroupID.Valid {
+		return nil, nil
+	}
+	return LoaderFor(ctx).FareLegRulesByFeedVersionLegGroupIDs.Load(ctx, fareLegRulesByLegGroupIDLoaderParam{FeedVersionID: obj.FeedVersionID, LegGroupID: obj.FromLegGroupID.Val})()
+}
+
+func (r *fareTransferRuleResolver) ToLegRules(ctx context.Context, obj *model.FareTransferRule) ([]*model.FareLegRule, error) {
+	if !obj.ToLegGroupID.Valid {
+		return nil, nil
+	}
+	return LoaderFor(ctx).FareLegRulesByFeedVersionLegGroupIDs.Load(ctx, fareLegRulesByLegGroupIDLoaderParam{FeedVersionID: obj.FeedVersionID, LegGroupID: obj.ToLegGroupID.Val})()
+}
+
+func (r *fareTransferRuleResolver) FareProducts(ctx context.Context, obj *model.FareTransferRule) ([]*model.FareProduct, error) {
+	if !obj.FareProductID.Valid {
+		return nil, nil
+	}
+	return LoaderFor(ctx).FareProductsByFeedVersionFareProductIDs.Load(ctx, fareProductsByFareProductIDLoaderParam{FeedVersionID: obj.FeedVersionID, FareProductID: obj.FareProductID.Val})()
+}
+
+// TIMEFRAME
+
+type timeframeResolver struct{ *Resolver }
+
+func (r *timeframeResolver) FeedVersion(ctx context.Context, obj *model.Timeframe) (*model.FeedVersion, error) {
+	return LoaderFor(ctx).FeedVersionsByIDs.Load(ctx, obj.FeedVersionID)()
+}
+
+func (r *timeframeResolver) Service(ctx context.Context, obj *model.Timeframe) (*model.Calendar, error) {
+	if !obj.ServiceID.Valid {
+		return nil, nil
+	}
+	return LoaderFor(ctx).CalendarsByIDs.Load(ctx, obj.ServiceID.Int())()
+}
@@ -0,0 +1,441 @@
+package gql
+
+import (
+	"testing"
+)
+
+func TestFareProductResolver(t *testing.T) {
+	ctranFlexSha1 := "e8bc76c3c8602cad745f41a49ed5c5627ad6904c"
+	testcases := []testcase{
+		{
+			name: "fare products",
+			query: `query($sha1: String!) {
+				feed_versions(where: {sha1: $sha1}) {
+					fare_products {
+						fare_product_id
+					}
+				}
+			}`,
+			vars:               hw{"sha1": ctranFlexSha1},
+			selector:           "feed_versions.0.fare_products.#.fare_product_id",
+			selectExpectCount:  14,
+			selectExpectUnique: []string{"ADULT_LOCAL_SINGLE_RIDE", "HC_LOCAL_SINGLE_RIDE", "YOUTH_LOCAL_SINGLE_RIDE"},
+		},
+		{
+			name: "fare products filter by fare_product_id",
+			query: `query($sha1: String!) {
+				feed_versions(where: {sha1: $sha1}) {
+					fare_products(where: {fare_product_id: "YOUTH_LOCAL_SINGLE_RIDE"}) {
+						fare_product_id
+					}
+				}
+			}`,
+			vars:              hw{"sha1": ctranFlexSha1},
+			selector:          "feed_versions.0.fare_products.#.fare_product_id",
+			selectExpectCount: 4,
+		},
+		{
+			name: "fare products filter by rider_category_id",
+			query: `query($sha1: String!) {
+				feed_versions(where: {sha1: $sha1}) {
+					fare_products(where: {rider_category_id: "HONORED_CITIZEN"}) {
+						fare_product_id
+					}
+				}
+			}`,
+			vars:               hw{"sha1": ctranFlexSha1},
+			selector:           "feed_versions.0.fare_products.#.fare_product_id",
+			selectExpectCount:  5,
+			selectExpectUnique: []string{"HC_LOCAL_SINGLE_RIDE"},
+		},
+		{
+			name: "fare products filter by fare_media_id",
+			query: `query($sha1: String!) {
+				feed_versions(where: {sha1: $sha1}) {
+					fare_products(where: {fare_media_id: "1"}) {
+						fare_product_id
+					}
+				}
+			}`,
+			vars:         hw{"sha1": ctranFlexSha1},
+			selector:     "feed_versions.0.fare_products.#.fare_product_id",
+			selectExpect: []string{"ADULT_LOCAL_SINGLE_RIDE", "HC_LOCAL_SINGLE_RIDE"},
+		},
+		{
+			name: "fare products not found",
+			query: `query($sha1: String!) {
+				feed_versions(where: {sha1: $sha1}) {
+					fare_products(where: {fare_product_id: "nonexistent"}) {
+						fare_product_id
+					}
+				}
+			}`,
+			vars:         hw{"sha1": ctranFlexSha1},
+			selector:     "feed_versions.0.fare_products.#.fare_product_id",
+			selectExpect: []string{},
+		},
+		{
+			name: "fare product amount and currency",
+			query: `query($sha1: String!) {
+				feed_versions(where: {sha1: $sha1}) {
+					fare_products(where: {fare_product_id: "HC_LOCAL_SINGLE_RIDE", fare_media_id: "0"}) {
+						fare_product_id
+						fare_product_name
+						amount
+						currency
+					}
+				}
+			}`,
+			vars:   hw{"sha1": ctranFlexSha1},
+			expect: `{"feed_versions":[{"fare_products":[{"amount":0.6,"currency":"USD","fare_product_id":"HC_LOCAL_SINGLE_RIDE","fare_product_name":"Honored Citizen Local 2 1/2 Hours"}]}]}`,
+		},
+		{
+			name: "fare product rider category",
+			query: `query($sha1: String!) {
+				feed_versions(where: {sha1: $sha1}) {
+					fare_products(where: {fare_product_id: "HC_LOCAL_SINGLE_RIDE"}) {
+						rider_category {
+							rider_category_id
+						}
+					}
+				}
+			}`,
+			vars:               hw{"sha1": ctranFlexSha1},
+			selector:           "feed_versions.0.fare_products.#.rider_category.rider_category_id",
+			selectExpectUnique: []string{"HONORED_CITIZEN"},
+		},
+		{
+			name: "fare product fare media",
+			query: `query($sha1: String!) {
+				feed_versions(where: {sha1: $sha1}) {
+					fare_products(where: {fare_product_id: "YOUTH_LOCAL_SINGLE_RIDE"}) {
+						fare_media {
+							fare_media_id
+						}
+					}
+				}
+			}`,
+			vars:         hw{"sha1": ctranFlexSha1},
+			selector:     "feed_versions.0.fare_products.#.fare_media.fare_media_id",
+			selectExpect: []string{"0", "2", "3", "4"},
+		},
+		{
+			name: "fare product feed metadata",
+			query: `query($sha1: String!) {
+				feed_versions(where: {sha1: $sha1}) {
+					fare_products(limit: 1) {
+						feed_onestop_id
+						feed_version_sha1
+						feed_version {
+							sha1
+						}
+					}
+				}
+			}`,
+			vars:   hw{"sha1": ctranFlexSha1},
+			expect: `{"feed_versions":[{"fare_products":[{"feed_onestop_id":"ctran-flex","feed_version":{"sha1":"e8bc76c3c8602cad745f41a49ed5c5627ad6904c"},"feed_version_sha1":"e8bc76c3c8602cad745f41a49ed5c5627ad6904c"}]}]}`,
+		},
+	}
+	c, _ := newTestClient(t)
+	queryTestcases(t, c, testcases)
+}
+
+func TestFareMediaResolver(t *testing.T) {
+	ctranFlexSha1 := "e8bc76c3c8602cad745f41a49ed5c5627ad6904c"
+	testcases := []testcase{
+		{
+			name: "fare media",
+			query: `query($sha1: String!) {
+				feed_versions(where: {sha1: $sha1}) {
+					fare_media {
+						fare_media_id
+					}
+				}
+			}`,
+			vars:         hw{"sha1": ctranFlexSha1},
+			selector:     "feed_versions.0.fare_media.#.fare_media_id",
+			selectExpect: []string{"0", "1", "2", "3", "4"},
+		},
+		{
+			name: "fare media all fields",
+			query: `query($sha1: String!) {
+				feed_versions(where: {sha1: $sha1}) {
+					fare_media(where: {fare_media_id: "2"}) {
+						fare_media_id
+						fare_media_name
+						fare_media_type
+					}
+				}
+			}`,
+			vars:   hw{"sha1": ctranFlexSha1},
+			expect: `{"feed_versions":[{"fare_media":[{"fare_media_id":"2","fare_media_name":"HOP Fastpass","fare_media_type":2}]}]}`,
+		},
+	}
+	c, _ := newTestClient(t)
+	queryTestcases(t, c, testcases)
+}
+
+func TestRiderCategoryResolver(t *testing.T) {
+	ctranFlexSha1 := "e8bc76c3c8602cad745f41a49ed5c5627ad6904c"
+	testcases := []testcase{
+		{
+			name: "rider categories",
+			query: `query($sha1: String!) {
+				feed_versions(where: {sha1: $sha1}) {
+					rider_categories {
+						rider_category_id
+					}
+				}
+			}`,
+			vars:         hw{"sha1": ctranFlexSha1},
+			selector:     "feed_versions.0.rider_categories.#.rider_category_id",
+			selectExpect: []string{"ADULT", "HONORED_CITIZEN", "YOUTH"},
+		},
+		{
+			name: "rider category all fields",
+			query: `query($sha1: String!) {
+				feed_versions(where: {sha1: $sha1}) {
+					rider_categories(where: {rider_category_id: "YOUTH"}) {
+						rider_category_id
+						rider_category_name
+						is_default_fare_category
+						eligibility_url
+					}
+				}
+			}`,
+			vars:   hw{"sha1": ctranFlexSha1},
+			expect: `{"feed_versions":[{"rider_categories":[{"eligibility_url":"https://c-tran.com/fares/fares-and-id-cards","is_default_fare_category":0,"rider_category_id":"YOUTH","rider_category_name":"Youth"}]}]}`,
+		},
+	}
+	c, _ := newTestClient(t)
+	queryTestcases(t, c, testcases)
+}
+
+func TestFareLegRuleResolver(t *testing.T) {
+	ctranFlexSha1 := "e8bc76c3c8602cad745f41a49ed5c5627ad6904c"
+	testcases := []testcase{
+		{
+			name: "fare leg rules",
+			query: `query($sha1: String!) {
+				feed_versions(where: {sha1: $sha1}) {
+					fare_leg_rules {
+						fare_product_id
+					}
+				}
+			}`,
+			vars:         hw{"sha1": ctranFlexSha1},
+			selector:     "feed_versions.0.fare_leg_rules.#.fare_product_id",
+			selectExpect: []string{"ADULT_LOCAL_SINGLE_RIDE", "HC_LOCAL_SINGLE_RIDE", "YOUTH_LOCAL_SINGLE_RIDE"},
+		},
+		{
+			name: "fare leg rules filter by network_id",
+			query: `query($sha1: String!) {
+				feed_versions(where: {sha1: $sha1}) {
+					fare_leg_rules(where: {network_id: "LOCAL"}) {
+						leg_group_id
+						network_id
+					}
+				}
+			}`,
+			vars:               hw{"sha1": ctranFlexSha1},
+			selector:           "feed_versions.0.fare_leg_rules.#.network_id",
+			selectExpectCount:  3,
+			selectExpectUnique: []string{"LOCAL"},
+		},
+		{
+			name: "fare leg rules filter by fare_product_id",
+			query: `query($sha1: String!) {
+				feed_versions(where: {sha1: $sha1}) {
+					fare_leg_rules(where: {fare_product_id: "YOUTH_LOCAL_SINGLE_RIDE"}) {
+						fare_product_id
+					}
+				}
+			}`,
+			vars:         hw{"sha1": ctranFlexSha1},
+			selector:     "feed_versions.0.fare_leg_rules.#.fare_product_id",
+			selectExpect: []string{"YOUTH_LOCAL_SINGLE_RIDE"},
+		},
+		{
+			name: "fare leg rule fare products",
+			query: `query($sha1: String!) {
+				feed_versions(where: {sha1: $sha1}) {
+					fare_leg_rules(where: {fare_product_id: "ADULT_LOCAL_SINGLE_RIDE"}) {
+						fare_products {
+							fare_product_id
+						}
+					}
+				}
+			}`,
+			vars:               hw{"sha1": ctranFlexSha1},
+			selector:           "feed_versions.0.fare_leg_rules.0.fare_products.#.fare_product_id",
+			selectExpectCount:  5,
+			selectExpectUnique: []string{"ADULT_LOCAL_SINGLE_RIDE"},
+		},
+		{
+			name: "fare leg rule without areas or timeframes",
+			query: `query($sha1: String!) {
+				feed_versions(where: {sha1: $sha1}) {
+					fare_leg_rules(where: {fare_product_id: "ADULT_LOCAL_SINGLE_RIDE"}) {
+						from_area { area_id }
+						to_area { area_id }
+						from_timeframes { timeframe_group_id }
+						to_timeframes { timeframe_group_id }
+					}
+				}
+			}`,
+			vars:   hw{"sha1": ctranFlexSha1},
+			expect: `{"feed_versions":[{"fare_leg_rules":[{"from_area":null,"to_area":null,"from_timeframes":[],"to_timeframes":[]}]}]}`,
+		},
+		{
+			name: "route fare leg rules",
+			query: `query($sha1: String!) {
+				feed_versions(where: {sha1: $sha1}) {
+					routes(where: {route_id: "2bc6804f-9e24-4b91-8947-c73a2363e7b6"}) {
+						fare_leg_rules {
+							fare_product_id
+						}
+					}
+				}
+			}`,
+			vars:         hw{"sha1": ctranFlexSha1},
+			selector:     "feed_versions.0.routes.0.fare_leg_rules.#.fare_product_id",
+			selectExpect: []string{"ADULT_LOCAL_SINGLE_RIDE", "HC_LOCAL_SINGLE_RIDE", "YOUTH_LOCAL_SINGLE_RIDE"},
+		},
+		{
+			name: "route fare leg rules with filter",
+			query: `query($sha1: String!) {
+				feed_versions(where: {sha1: $sha1}) {
+					routes(where: {route_id: "2bc6804f-9e24-4b91-8947-c73a2363e7b6"}) {
+						fare_leg_rules(where: {fare_product_id: "HC_LOCAL_SINGLE_RIDE"}) {
+							fare_product_id
+						}
+					}
+				}
+			}`,
+			vars:         hw{"sha1": ctranFlexSha1},
+			selector:     "feed_versions.0.routes.0.fare_leg_rules.#.fare_product_id",
+			selectExpect: []string{"HC_LOCAL_SINGLE_RIDE"},
+		},
+		{
+			name: "route without network has no fare leg rules",
+			query: `query {
+				routes(where: {feed_onestop_id: "BA", route_id: "01"}) {
+					fare_leg_rules {
+						fare_product_id
+					}
+				}
+			}`,
+			selector:     "routes.0.fare_leg_rules.#.fare_product_id",
+			selectExpect: []string{},
+		},
+	}
+	c, _ := newTestClient(t)
+	queryTestcases(t, c, testcases)
+}
+
+func TestFareTransferRuleResolver(t *testing.T) {
+	ctranFlexSha1 := "e8bc76c3c8602cad745f41a49ed5c5627ad6904c"
+	testcases := []testcase{
+		{
+			name: "fare transfer rules",
+			query: `query($sha1: String!) {
+				feed_versions(where: {sha1: $sha1}) {
+					fare_transfer_rules {
+						from_leg_group_id
+						to_leg_group_id
+						transfer_count
+						duration_limit
+						duration_limit_type
+						fare_transfer_type
+						fare_product_id
+					}
+				}
+			}`,
+			vars:   hw{"sha1": ctranFlexSha1},
+			expect: `{"feed_versions":[{"fare_transfer_rules":[{"duration_limit":9000,"duration_limit_type":1,"fare_product_id":null,"fare_transfer_type":0,"from_leg_group_id":"LOCAL","to_leg_group_id":"LOCAL","transfer_count":-1}]}]}`,
+		},
+		{
+			name: "fare transfer rule leg rules",
+			query: `query($sha1: String!) {
+				feed_versions(where: {sha1: $sha1}) {
+					fare_transfer_rules(where: {from_leg_group_id: "LOCAL"}) {
+						from_leg_rules {
+							fare_product_id
+						}
+						fare_products {
+							fare_product_id
+						}
+					}
+				}
+			}`,
+			vars: hw{"sha1": ctranFlexSha1},
+			sel: []testcaseSelector{
+				{
+					selector: "feed_versions.0.fare_transfer_rules.0.from_leg_rules.#.fare_product_id",
+					expect:   []string{"ADULT_LOCAL_SINGLE_RIDE", "HC_LOCAL_SINGLE_RIDE", "YOUTH_LOCAL_SINGLE_RIDE"},
+				},
+				{
+					selector: "feed_versions.0.fare_transfer_rules.0.fare_products.#.fare_product_id",
+					expect:   []string{},
+				},
+			},
+		},
+		{
+			name: "fare transfer rules filter not found",
+			query: `query($sha1: String!) {
+				feed_versions(where: {sha1: $sha1}) {
+					fare_transfer_rules(where: {to_leg_group_id: "nonexistent"}) {
+						id
+					}
+				}
+			}`,
+			vars:         hw{"sha1": ctranFlexSha1},
+			selector:     "feed_versions.0.fare_transfer_rules.#.id",
+			selectExpect: []string{},
+		},
+	}
+	c, _ := newTestClient(t)
+	queryTestcases(t, c, testcases)
+}
+
+func TestAreaResolver(t *testing.T) {
+	ctranFlexSha1 := "e8bc76c3c8602cad745f41a49ed5c5627ad6904c"
+	testcases := []testcase{
+		{
+			name: "feed version without areas",
+			query: `query($sha1: String!) {
+				feed_versions(where: {sha1: $sha1}) {
+					areas {
+						area_id
+					}
+					timeframes {
+						timeframe_group_id
+					}
+					fare_leg_join_rules {
+						from_network_id
+					}
+				}
+			}`,
+			vars: hw{"sha1": ctranFlexSha1},
+			sel: []testcaseSelector{
+				{selector: "feed_versions.0.areas.#.area_id", expect: []string{}},
+				{selector: "feed_versions.0.timeframes.#.timeframe_group_id", expect: []string{}},
+				{selector: "feed_versions.0.fare_leg_join_rules.#.from_network_id", expect: []string{}},
+			},
+		},
+		{
+			name: "stop without areas",
+			query: `query {
+				stops(where: {feed_onestop_id: "BA", stop_id: "FTVL"}) {
+					areas {
+						area_id
+					}
+				}
+			}`,
+			selector:     "stops.0.areas.#.area_id",
+			selectExpect: []string{},
+		},
+	}
+	c, _ := newTestClient(t)
+	queryTestcases(t, c, testcases)
+}
@@ -73,6 +73,52 @@ func (r *feedVersionResolver) LocationGroups(ctx context.Context, obj *model.Fee
 	return LoaderFor(ctx).LocationGroupsByFeedVersionIDs.Load(ctx, locationGroupLoaderParam{FeedVersionID: obj.ID, Limit: resolverCheckLimit(limit), Where: where})()
 }
 
+// FARES V2
+
+func (r *feedVersionResolver) FareProducts(ctx context.Context, obj *model.FeedVersion, limit *int, where *model.FareProductFilter) ([]*model.FareProduct, error) {
+	return LoaderFor(ctx).FareProductsByFeedVersionIDs.Load(ctx, fareProductLoaderParam{FeedVersionID: obj.ID, Limit: resolverCheckLimit(limit), Where: where})()
+}
+
+func (r *feedVersionResolver) FareMedia(ctx context.Context, obj *model.FeedVersion, limit *int, where *model.FareMediaFilter) ([]*model.FareMedia, error) {
+	return LoaderFor(ctx).FareMediaByFeedVersionIDs.Load(ctx, fareMediaLoaderParam{FeedVersionID: obj.ID, Limit: resolverCheckLimit(limit), Where: where})()
+}
+
+func (r *feedVersionResolver) FareLegRules(ctx context.Context, obj *model.FeedVersion, limit *int, where *model.FareLegRuleFilter) ([]*model.FareLegRule, error) {
+	return LoaderFor(ctx).FareLegRulesByFeedVersionIDs.Load(ctx, fareLegRuleLoaderParam{FeedVersionID: obj.ID, Limit: resolverCheckLimit(limit), Where: where})()
+}
+
+func (r *feedVersionResolver) FareLegJoinRules(ctx context.Context, obj *model.FeedVersion, limit *int) ([]*model.FareLegJoinRule, error) {
+	return LoaderFor(ctx).FareLegJoinRulesByFeedVersionIDs.Load(ctx, fareLegJoinRuleLoaderParam{FeedVersionID: obj.ID, Limit: resolverCheckLimit(limit)})()
+}
+
+func (r *feedVersionResolver) FareTransferRules(ctx context.Context, obj *model.FeedVersion, limit *int, where *model.FareTransferRuleFilter) ([]*model.FareTransferRule, error) {
+	return LoaderFor(ctx).FareTransferRulesByFeedVersionIDs.Load(ctx, fareTransferRuleLoaderParam{FeedVersionID: obj.ID, Limit: resolverCheckLimit(limit), Where: where})()
+}
+
+func (r *feedVersionResolver) RiderCategories(ctx context.Context, obj *model.FeedVersion, limit *int, where *model.RiderCategoryFilter) ([]*model.RiderCategory, error) {
+	return LoaderFor(ctx).RiderCategoriesByFeedVersionIDs.Load(ctx, riderCategoryLoaderParam{FeedVersionID: obj.ID, Limit: resolverCheckLimit(limit), Where: where})()
+}
+
+func (r *feedVersionResolver) Timeframes(ctx context.Context, obj *model.FeedVersion, limit *int, where *model.TimeframeFilter) ([]*model.Timeframe, error) {
+	return LoaderFor(ctx).TimeframesByFeedVersionIDs.Load(ctx, timeframeLoaderParam{FeedVersionID: obj.ID, Limit: resolverCheckLimit(limit), Where: where})()
+}
+
+func (r *feedVersionResolver) Areas(ctx context.Context, obj *model.FeedVersion, limit *int, where *model.AreaFilter) ([]*model.Area, error) {
+	return LoaderFor(ctx).AreasByFeedVersionIDs.Load(ctx, areaLoaderParam{FeedVersionID: obj.ID, Limit: resolverCheckLimit(limit), Where: where})()
+}
+
+func (r *feedVersionResolver) Transfers(ctx context.Context, obj *model.FeedVersion, limit *int, where *model.TransferFilter) ([]*model.Transfer, error) {
+	return LoaderFor(ctx).TransfersByFeedVersionIDs.Load(ctx, transferLoaderParam{FeedVersionID: obj.ID, Limit: resolverCheckLimit(limit), Where: where})()
+}
+
+func (r *feedVersionResolver) Networks(ctx context.Context, obj *model.FeedVersion, limit *int, where *model.NetworkFilter) ([]*model.Network, error) {
+	return LoaderFor(ctx).NetworksByFeedVersionIDs.Load(ctx, networkLoaderParam{FeedVersionID: obj.ID, Limit: resolverCheckLimit(limit), Where: where})()
+}
+
+func (r *feedVersionResolver) Attributions(ctx context.Context, obj *model.FeedVersion, limit *int) ([]*model.Attribution, error) {
+	return LoaderFor(ctx).AttributionsByFeedVersionIDs.Load(ctx, attributionLoaderParam{FeedVersionID: obj.ID, Limit: resolverCheckLimit(limit)})()
+}
+
 // FEED VALIDATION REPORT
 
 func (r *feedVersionResolver) ValidationReports(ctx context.Context, obj *model.FeedVersion, limit *int, where *model.ValidationReportFilter) ([]*model.ValidationReport, error) {
@@ -7,25 +7,41 @@ import (
 
 	"github.com/99designs/gqlgen/graphql"
 
+	"github.com/interline-io/transitland-lib/server/auth/authn"
 	"github.com/interline-io/transitland-lib/server/auth/authz"
 	"github.com/interline-io/transitland-lib/server/jobs"
 	"github.com/interline-io/transitland-lib/server/model"
 )
 
-// Job kinds the import/unimport mutations enqueue. These match the Kind() of
+// Job kinds the feed version mutations enqueue. These match the Kind() of
 // the workers that process them (server/workers here, private workers in the
 // deployment binary); the string is the queue contract, so the resolver stays
 // free of a worker-package dependency.
 const (
 	feedVersionImportJobKind   = "feed-version-import"
 	feedVersionUnimportJobKind = "feed-version-unimport"
+	validateUploadJobKind      = "validate-upload"
+	staticFetchJobKind         = "static-fetch"
 )
 
 // mutation root
 
 type mutationResolver struct{ *Resolver }
 
-func (r *mutationResolver) ValidateGtfs(ctx context.Context, file *graphql.Upload, url *string, rturls []string) (*model.ValidationReport, error) {
+func (r *mutationResolver) ValidateGtfs(ctx context.Context, file *graphql.Upload, url *string, rturls []string, async *bool) (*model.ValidationReport, error) {
+	if async != nil && *async {
+		if file != nil || url == nil || *url == "" {
+			return nil, errors.New("async validation requires a url")
+		}
+		if authn.ForContext(ctx) == nil {
+			return nil, authz.ErrUnauthorized
+		}
+		jobId, err := submitJob(ctx, validateUploadJobKind, jobs.Args{"url": *url, "realtime_urls": rturls})
+		if err != nil {
+			return nil, err
+		}
+		return &model.ValidationReport{JobID: &jobId}, nil
+	}
 	var src io.Reader
 	if file != nil {
 		src = file.File
@@ -33,15 +49,28 @@ func (r *mutationResolver) ValidateGtfs(ctx context.Context, file *graphql.Uploa
 	return model.ForContext(ctx).Actions.ValidateUpload(ctx, src, url, rturls)
 }
 
-func (r *mutationResolver) FeedVersionFetch(ctx context.Context, file *graphql.Upload, url *string, feedId string) (*model.FeedVersionFetchResult, error) {
-	var feedSrc io.Reader
-	if file != nil {
-		feedSrc = file.File
-	}
+func (r *mutationResolver) FeedVersionFetch(ctx context.Context, file *graphql.Upload, url *string, feedId string, async *bool) (*model.FeedVersionFetchResult, error) {
 	feedUrl := ""
 	if url != nil {
 		feedUrl = *url
 	}
+	if async != nil && *async {
+		if file != nil {
+			return nil, errors.New("async fetch does not support file uploads")
+		}
+		if err := checkFeedCreateFeedVersion(ctx, feedId); err != nil {
+			return nil, err
+		}
+		jobId, err := submitJob(ctx, staticFetchJobKind, jobs.Args{"feed_onestop_id": feedId, "url": feedUrl})
+		if err != nil {
+			return nil, err
+		}
+		return &model.FeedVersionFetchResult{JobID: &jobId}, nil
+	}
+	var feedSrc io.Reader
+	if file != nil {
+		feedSrc = file.File
+	}
 	return model.ForContext(ctx).Actions.StaticFetch(ctx, feedId, feedSrc, feedUrl)
 }
 
@@ -97,6 +126,53 @@ func checkFeedVersionEdit(ctx context.Context, fvid int) error {
 	return nil
 }
 
+// checkFeedCreateFeedVersion gates the async fetch mutation on the request
+// context, as checkFeedVersionEdit does for import. Not-found and not-authorized
+// both return ErrUnauthorized so callers can't probe feed existence.
+func checkFeedCreateFeedVersion(ctx context.Context, feedId string) error {
+	cfg := model.ForContext(ctx)
+	if cfg.Checker == nil {
+		return authz.ErrUnauthorized
+	}
+	feeds, err := cfg.Finder.FindFeeds(ctx, nil, nil, nil, &model.FeedFilter{OnestopID: &feedId})
+	if err != nil {
+		return err
+	}
+	if len(feeds) == 0 {
+		return authz.ErrUnauthorized
+	}
+	ok, err := cfg.Checker.Check(ctx, authz.ObjectRef{Type: authz.FeedType, ID: int64(feeds[0].ID)}, authz.CanCreateFeedVersion)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return authz.ErrUnauthorized
+	}
+	return nil
+}
+
+// submitJob queues a job on the queue named for its kind, owned by the calling
+// user so they can follow it and read its artifacts through the job server.
+func submitJob(ctx context.Context, kind string, args jobs.Args) (string, error) {
+	cfg := model.ForContext(ctx)
+	if cfg.Jobs == nil {
+		return "", errors.New("background jobs not available")
+	}
+	q, err := cfg.Jobs.Queue(kind)
+	if err != nil {
+		return "", err
+	}
+	job := jobs.Job{Kind: kind, Args: args}
+	if user := authn.ForContext(ctx); user != nil {
+		job.Opts.UserID = user.ID()
+	}
+	st, err := q.Submit(ctx, job)
+	if err != nil {
+		return "", err
+	}
+	return st.Job.ID, nil
+}
+
 // enqueueAndWaitFeedVersion submits a feed-version job and blocks until it
 // reaches a terminal state, so the mutation keeps its synchronous result while
 // the work runs on a job context that survives request cancellation. A queue
@@ -10,6 +10,7 @@ import (
 
 	"github.com/99designs/gqlgen/client"
 	"github.com/interline-io/transitland-lib/internal/testconfig"
+	"github.com/interline-io/transitland-lib/server/auth/authn"
 	"github.com/interline-io/transitland-lib/server/auth/mw/usercheck"
 	"github.com/interline-io/transitland-lib/server/jobs"
 	"github.com/interline-io/transitland-lib/server/model"
@@ -172,6 +173,63 @@ func TestValidateGtfsResolver(t *testing.T) {
 	// })
 }
 
+func TestValidateGtfsResolver_Async(t *testing.T) {
+	t.Run("queues job", func(t *testing.T) {
+		testconfig.ConfigTxRollback(t, testconfig.Options{}, func(cfg model.Config) {
+			srv, _ := NewServer()
+			srv = model.AddConfigAndPerms(cfg, srv)
+			srv = usercheck.UserDefaultMiddleware("test")(srv)
+			c := client.New(srv)
+			resp := make(map[string]interface{})
+			err := c.Post(`mutation($url:String!) {validate_gtfs(url:$url, async:true){success job_id}}`, &resp, client.Var("url", "http://example.com/gtfs.zip"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			jobId := gjson.Get(toJson(resp), "validate_gtfs.job_id").String()
+			assert.NotEmpty(t, jobId)
+			q, err := cfg.Jobs.Queue("validate-upload")
+			if err != nil {
+				t.Fatal(err)
+			}
+			st, err := q.(jobs.StatusQueue).Status(authn.WithUser(context.Background(), authn.NewCtxUser("test", "", "")), jobId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, jobs.JobStateQueued, st.State)
+			assert.Equal(t, "http://example.com/gtfs.zip", st.Job.Args["url"])
+		})
+	})
+	t.Run("requires url", func(t *testing.T) {
+		testconfig.ConfigTxRollback(t, testconfig.Options{}, func(cfg model.Config) {
+			srv, _ := NewServer()
+			srv = model.AddConfigAndPerms(cfg, srv)
+			srv = usercheck.UserDefaultMiddleware("test")(srv)
+			c := client.New(srv)
+			resp := make(map[string]interface{})
+			err := c.Post(`mutation {validate_gtfs(async:true){job_id}}`, &resp)
+			assert.ErrorContains(t, err, "requires a url")
+		})
+	})
+}
+
+func TestFeedVersionFetchResolver_Async(t *testing.T) {
+	testconfig.ConfigTxRollback(t, testconfig.Options{AllowAll: true}, func(cfg model.Config) {
+		srv, _ := NewServer()
+		srv = model.AddConfigAndPerms(cfg, srv)
+		srv = usercheck.AdminDefaultMiddleware("test")(srv)
+		c := client.New(srv)
+		resp := make(map[string]interface{})
+		err := c.Post(`mutation {feed_version_fetch(feed_onestop_id:"BA", async:true){job_id feed_version{id}}}`, &resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.NotEmpty(t, gjson.Get(toJson(resp), "feed_version_fetch.job_id").String())
+		assert.Nil(t, gjson.Get(toJson(resp), "feed_version_fetch.feed_version").Value())
+		err = c.Post(`mutation {feed_version_fetch(feed_onestop_id:"not-a-feed", async:true){job_id}}`, &resp)
+		assert.Error(t, err)
+	})
+}
+
 // stubJobWorker stands in for the deployment's real import/unimport worker so
 // the resolver's submit-and-watch path can be tested without running an import.
 type stubJobWorker struct {
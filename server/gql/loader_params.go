@@ -267,3 +267,115 @@ type locationLoaderParam struct {
 	Limit         *int
 	Where         *model.LocationFilter
 }
+
+type fareProductLoaderParam struct {
+	FeedVersionID int
+	Limit         *int
+	Where         *model.FareProductFilter
+}
+
+type fareProductsByFareProductIDLoaderParam struct {
+	FeedVersionID int
+	FareProductID string
+	Limit         *int
+}
+
+type fareMediaLoaderParam struct {
+	FeedVersionID int
+	Limit         *int
+	Where         *model.FareMediaFilter
+}
+
+type fareLegRuleLoaderParam struct {
+	FeedVersionID int
+	RouteID       int
+	Limit         *int
+	Where         *model.FareLegRuleFilter
+}
+
+type fareLegRulesByLegGroupIDLoaderParam struct {
+	FeedVersionID int
+	LegGroupID    string
+	Limit         *int
+}
+
+type fareLegJoinRuleLoaderParam struct {
+	FeedVersionID int
+	Limit         *int
+}
+
+type fareTransferRuleLoaderParam struct {
+	FeedVersionID int
+	Limit         *int
+	Where         *model.FareTransferRuleFilter
+}
+
+type riderCategoryLoaderParam struct {
+	FeedVersionID int
+	Limit         *int
+	Where         *model.RiderCategoryFilter
+}
+
+type timeframeLoaderParam struct {
+	FeedVersionID int
+	Limit         *int
+	Where         *model.TimeframeFilter
+}
+
+type timeframesByTimeframeGroupIDLoaderParam struct {
+	FeedVersionID    int
+	TimeframeGroupID string
+	Limit            *int
+}
+
+type translationLoaderParam struct {
+	FeedVersionID int
+	TableName     string
+	RecordID      string
+	FieldValue    string
+}
+
+type areaLoaderParam struct {
+	FeedVersionID int
+	Limit         *int
+	Where         *model.AreaFilter
+}
+
+type areasByStopLoaderParam struct {
+	StopID int
+	Limit  *int
+}
+
+type stopsByAreaLoaderParam struct {
+	AreaID int
+	Limit  *int
+}
+
+type transferLoaderParam struct {
+	FeedVersionID int
+	FromStopID    int
+	ToStopID      int
+	Limit         *int
+	Where         *model.TransferFilter
+}
+
+type networkLoaderParam struct {
+	FeedVersionID int
+	Limit         *int
+	Where         *model.NetworkFilter
+}
+
+type networksByRouteLoaderParam struct {
+	RouteID int
+	Limit   *int
+}
+
+type routesByNetworkLoaderParam struct {
+	NetworkID int
+	Limit     *int
+}
+
+type attributionLoaderParam struct {
+	FeedVersionID int
+	Limit         *int
+}
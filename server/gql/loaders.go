@@ -8,10 +8,12 @@ import (
 	"strings"
 	"time"
 
+	"github.com/99designs/gqlgen/graphql"
 	dataloader "github.com/graph-gophers/dataloader/v7"
 	"github.com/interline-io/log"
 	"github.com/interline-io/transitland-lib/server/model"
 	"github.com/interline-io/transitland-lib/tt"
+	"github.com/vektah/gqlparser/v2/ast"
 )
 
 type ctxKey string
@@ -29,6 +31,10 @@ type Loaders struct {
 	AgenciesByIDs                                                 *dataloader.Loader[int, *model.Agency]
 	AgenciesByOnestopIDs                                          *dataloader.Loader[agencyLoaderParam, []*model.Agency]
 	AgencyPlacesByAgencyIDs                                       *dataloader.Loader[agencyPlaceLoaderParam, []*model.AgencyPlace]
+	AreasByFeedVersionAreaIDs                                     *dataloader.Loader[model.FVEntityID, *model.Area]
+	AreasByFeedVersionIDs                                         *dataloader.Loader[areaLoaderParam, []*model.Area]
+	AreasByStopIDs                                                *dataloader.Loader[areasByStopLoaderParam, []*model.Area]
+	AttributionsByFeedVersionIDs                                  *dataloader.Loader[attributionLoaderParam, []*model.Attribution]
 	BookingRulesByFeedVersionIDs                                  *dataloader.Loader[bookingRuleLoaderParam, []*model.BookingRule]
 	BookingRulesByIDs                                             *dataloader.Loader[int, *model.BookingRule]
 	CalendarDatesByServiceIDs                                     *dataloader.Loader[calendarDateLoaderParam, []*model.CalendarDate]
@@ -47,6 +53,15 @@ type Loaders struct {
 	CensusLayersByIDs                                             *dataloader.Loader[int, *model.CensusLayer]
 	CensusTableByIDs                                              *dataloader.Loader[int, *model.CensusTable]
 	CensusValuesByGeographyIDs                                    *dataloader.Loader[censusValueLoaderParam, []*model.CensusValue]
+	FareLegJoinRulesByFeedVersionIDs                              *dataloader.Loader[fareLegJoinRuleLoaderParam, []*model.FareLegJoinRule]
+	FareLegRulesByFeedVersionIDs                                  *dataloader.Loader[fareLegRuleLoaderParam, []*model.FareLegRule]
+	FareLegRulesByFeedVersionLegGroupIDs                          *dataloader.Loader[fareLegRulesByLegGroupIDLoaderParam, []*model.FareLegRule]
+	FareLegRulesByRouteIDs                                        *dataloader.Loader[fareLegRuleLoaderParam, []*model.FareLegRule]
+	FareMediaByFeedVersionIDs                                     *dataloader.Loader[fareMediaLoaderParam, []*model.FareMedia]
+	FareMediaByIDs                                                *dataloader.Loader[int, *model.FareMedia]
+	FareProductsByFeedVersionFareProductIDs                       *dataloader.Loader[fareProductsByFareProductIDLoaderParam, []*model.FareProduct]
+	FareProductsByFeedVersionIDs                                  *dataloader.Loader[fareProductLoaderParam, []*model.FareProduct]
+	FareTransferRulesByFeedVersionIDs                             *dataloader.Loader[fareTransferRuleLoaderParam, []*model.FareTransferRule]
 	FeedFetchesByFeedIDs                                          *dataloader.Loader[feedFetchLoaderParam, []*model.FeedFetch]
 	FeedInfosByFeedVersionIDs                                     *dataloader.Loader[feedInfoLoaderParam, []*model.FeedInfo]
 	FeedsByIDs                                                    *dataloader.Loader[int, *model.Feed]
@@ -72,12 +87,16 @@ type Loaders struct {
 	StopsByLocationGroupIDs                                       *dataloader.Loader[stopsByLocationGroupLoaderParam, []*model.Stop]
 	LocationsByFeedVersionIDs                                     *dataloader.Loader[locationLoaderParam, []*model.Location]
 	LocationsByIDs                                                *dataloader.Loader[int, *model.Location]
+	NetworksByFeedVersionIDs                                      *dataloader.Loader[networkLoaderParam, []*model.Network]
+	NetworksByRouteIDs                                            *dataloader.Loader[networksByRouteLoaderParam, []*model.Network]
 	OperatorsByAgencyIDs                                          *dataloader.Loader[int, *model.Operator]
 	OperatorsByCOIFs                                              *dataloader.Loader[int, *model.Operator]
 	OperatorsByFeedIDs                                            *dataloader.Loader[operatorLoaderParam, []*model.Operator]
 	PathwaysByFromStopIDs                                         *dataloader.Loader[pathwayLoaderParam, []*model.Pathway]
 	PathwaysByIDs                                                 *dataloader.Loader[int, *model.Pathway]
 	PathwaysByToStopID                                            *dataloader.Loader[pathwayLoaderParam, []*model.Pathway]
+	RiderCategoriesByFeedVersionIDs                               *dataloader.Loader[riderCategoryLoaderParam, []*model.RiderCategory]
+	RiderCategoriesByFeedVersionRiderCategoryIDs                  *dataloader.Loader[model.FVEntityID, *model.RiderCategory]
 	RouteAttributesByRouteIDs                                     *dataloader.Loader[int, *model.RouteAttribute]
 	RouteGeometriesByRouteIDs                                     *dataloader.Loader[routeGeometryLoaderParam, []*model.RouteGeometry]
 	RouteHeadwaysByRouteIDs                                       *dataloader.Loader[routeHeadwayLoaderParam, []*model.RouteHeadway]
@@ -85,6 +104,7 @@ type Loaders struct {
 	RoutesByFeedVersionIDs                                        *dataloader.Loader[routeLoaderParam, []*model.Route]
 	RoutesByFeedVersionRouteIDs                                   *dataloader.Loader[model.FVEntityID, *model.Route]
 	RoutesByIDs                                                   *dataloader.Loader[int, *model.Route]
+	RoutesByNetworkIDs                                            *dataloader.Loader[routesByNetworkLoaderParam, []*model.Route]
 	RouteStopPatternsByRouteIDs                                   *dataloader.Loader[routeStopPatternLoaderParam, []*model.RouteStopPattern]
 	RouteStopsByRouteIDs                                          *dataloader.Loader[routeStopLoaderParam, []*model.RouteStop]
 	RouteStopsByStopIDs                                           *dataloader.Loader[routeStopLoaderParam, []*model.RouteStop]
@@ -97,6 +117,7 @@ type Loaders struct {
 	StopExternalReferencesByStopIDs                               *dataloader.Loader[int, *model.StopExternalReference]
 	StopObservationsByStopIDs                                     *dataloader.Loader[stopObservationLoaderParam, []*model.StopObservation]
 	StopPlacesByStopID                                            *dataloader.Loader[model.StopPlaceParam, *model.StopPlace]
+	StopsByAreaIDs                                                *dataloader.Loader[stopsByAreaLoaderParam, []*model.Stop]
 	StopsByFeedVersionIDs                                         *dataloader.Loader[stopLoaderParam, []*model.Stop]
 	StopsByFeedVersionStopIDs                                     *dataloader.Loader[model.FVEntityID, *model.Stop]
 	StopsByIDs                                                    *dataloader.Loader[int, *model.Stop]
@@ -106,6 +127,12 @@ type Loaders struct {
 	StopTimesByStopIDs                                            *dataloader.Loader[stopTimeLoaderParam, []*model.StopTime]
 	StopTimesByTripIDs                                            *dataloader.Loader[tripStopTimeLoaderParam, []*model.StopTime]
 	TargetStopsByStopIDs                                          *dataloader.Loader[int, *model.Stop]
+	TimeframesByFeedVersionIDs                                    *dataloader.Loader[timeframeLoaderParam, []*model.Timeframe]
+	TimeframesByFeedVersionTimeframeGroupIDs                      *dataloader.Loader[timeframesByTimeframeGroupIDLoaderParam, []*model.Timeframe]
+	TransfersByFeedVersionIDs                                     *dataloader.Loader[transferLoaderParam, []*model.Transfer]
+	TransfersByFromStopIDs                                        *dataloader.Loader[transferLoaderParam, []*model.Transfer]
+	TransfersByToStopIDs                                          *dataloader.Loader[transferLoaderParam, []*model.Transfer]
+	TranslationsByFeedVersionRecordIDs                            *dataloader.Loader[translationLoaderParam, []*model.Translation]
 	TripsByFeedVersionIDs                                         *dataloader.Loader[tripLoaderParam, []*model.Trip]
 	TripsByFeedVersionTripIDs                                     *dataloader.Loader[model.FVEntityID, *model.Trip]
 	TripsByIDs                                                    *dataloader.Loader[int, *model.Trip]
@@ -149,6 +176,24 @@ func NewLoaders(dbf model.Finder, batchSize int, stopTimeBatchSize int) *Loaders
 				return p.AgencyID, p.Where, p.Limit
 			},
 		),
+		AreasByFeedVersionAreaIDs: withWaitAndCapacity(waitTime, batchSize, dbf.AreasByFeedVersionAreaIDs),
+		AreasByFeedVersionIDs: withWaitAndCapacityGroup(waitTime, batchSize, dbf.AreasByFeedVersionIDs,
+			func(p areaLoaderParam) (int, *model.AreaFilter, *int) {
+				return p.FeedVersionID, p.Where, p.Limit
+			},
+		),
+		AreasByStopIDs: withWaitAndCapacityGroup(waitTime, batchSize,
+			paramGroupAdapter(dbf.AreasByStopIDs),
+			func(p areasByStopLoaderParam) (int, bool, *int) {
+				return p.StopID, false, p.Limit
+			},
+		),
+		AttributionsByFeedVersionIDs: withWaitAndCapacityGroup(waitTime, batchSize,
+			paramGroupAdapter(dbf.AttributionsByFeedVersionIDs),
+			func(p attributionLoaderParam) (int, bool, *int) {
+				return p.FeedVersionID, false, p.Limit
+			},
+		),
 		BookingRulesByFeedVersionIDs: withWaitAndCapacityGroup(waitTime, batchSize,
 			dbf.BookingRulesByFeedVersionIDs,
 			func(p bookingRuleLoaderParam) (int, *model.BookingRuleFilter, *int) {
@@ -232,6 +277,50 @@ func NewLoaders(dbf model.Finder, batchSize int, stopTimeBatchSize int) *Loaders
 				return p.Geoid, &censusValueLoaderParam{TableNames: p.TableNames, Dataset: p.Dataset}, p.Limit
 			},
 		),
+		FareLegJoinRulesByFeedVersionIDs: withWaitAndCapacityGroup(waitTime, batchSize,
+			paramGroupAdapter(dbf.FareLegJoinRulesByFeedVersionIDs),
+			func(p fareLegJoinRuleLoaderParam) (int, bool, *int) {
+				return p.FeedVersionID, false, p.Limit
+			},
+		),
+		FareLegRulesByFeedVersionIDs: withWaitAndCapacityGroup(waitTime, batchSize, dbf.FareLegRulesByFeedVersionIDs,
+			func(p fareLegRuleLoaderParam) (int, *model.FareLegRuleFilter, *int) {
+				return p.FeedVersionID, p.Where, p.Limit
+			},
+		),
+		FareLegRulesByFeedVersionLegGroupIDs: withWaitAndCapacityGroup(waitTime, batchSize,
+			paramGroupAdapter(dbf.FareLegRulesByFeedVersionLegGroupIDs),
+			func(p fareLegRulesByLegGroupIDLoaderParam) (model.FVEntityID, bool, *int) {
+				return model.FVEntityID{FeedVersionID: p.FeedVersionID, EntityID: p.LegGroupID}, false, p.Limit
+			},
+		),
+		FareLegRulesByRouteIDs: withWaitAndCapacityGroup(waitTime, batchSize, dbf.FareLegRulesByRouteIDs,
+			func(p fareLegRuleLoaderParam) (int, *model.FareLegRuleFilter, *int) {
+				return p.RouteID, p.Where, p.Limit
+			},
+		),
+		FareMediaByFeedVersionIDs: withWaitAndCapacityGroup(waitTime, batchSize, dbf.FareMediaByFeedVersionIDs,
+			func(p fareMediaLoaderParam) (int, *model.FareMediaFilter, *int) {
+				return p.FeedVersionID, p.Where, p.Limit
+			},
+		),
+		FareMediaByIDs: withWaitAndCapacity(waitTime, batchSize, dbf.FareMediaByIDs),
+		FareProductsByFeedVersionFareProductIDs: withWaitAndCapacityGroup(waitTime, batchSize,
+			paramGroupAdapter(dbf.FareProductsByFeedVersionFareProductIDs),
+			func(p fareProductsByFareProductIDLoaderParam) (model.FVEntityID, bool, *int) {
+				return model.FVEntityID{FeedVersionID: p.FeedVersionID, EntityID: p.FareProductID}, false, p.Limit
+			},
+		),
+		FareProductsByFeedVersionIDs: withWaitAndCapacityGroup(waitTime, batchSize, dbf.FareProductsByFeedVersionIDs,
+			func(p fareProductLoaderParam) (int, *model.FareProductFilter, *int) {
+				return p.FeedVersionID, p.Where, p.Limit
+			},
+		),
+		FareTransferRulesByFeedVersionIDs: withWaitAndCapacityGroup(waitTime, batchSize, dbf.FareTransferRulesByFeedVersionIDs,
+			func(p fareTransferRuleLoaderParam) (int, *model.FareTransferRuleFilter, *int) {
+				return p.FeedVersionID, p.Where, p.Limit
+			},
+		),
 		FeedFetchesByFeedIDs: withWaitAndCapacityGroup(waitTime, batchSize, dbf.FeedFetchesByFeedIDs,
 			func(p feedFetchLoaderParam) (int, *model.FeedFetchFilter, *int) {
 				return p.FeedID, p.Where, p.Limit
@@ -331,6 +420,17 @@ func NewLoaders(dbf model.Finder, batchSize int, stopTimeBatchSize int) *Loaders
 		),
 		LocationsByIDs: withWaitAndCapacity(waitTime, batchSize, dbf.LocationsByIDs),
 
+		NetworksByFeedVersionIDs: withWaitAndCapacityGroup(waitTime, batchSize, dbf.NetworksByFeedVersionIDs,
+			func(p networkLoaderParam) (int, *model.NetworkFilter, *int) {
+				return p.FeedVersionID, p.Where, p.Limit
+			},
+		),
+		NetworksByRouteIDs: withWaitAndCapacityGroup(waitTime, batchSize,
+			paramGroupAdapter(dbf.NetworksByRouteIDs),
+			func(p networksByRouteLoaderParam) (int, bool, *int) {
+				return p.RouteID, false, p.Limit
+			},
+		),
 		OperatorsByAgencyIDs: withWaitAndCapacity(waitTime, batchSize, dbf.OperatorsByAgencyIDs),
 		OperatorsByCOIFs:     withWaitAndCapacity(waitTime, batchSize, dbf.OperatorsByCOIFs),
 		OperatorsByFeedIDs: withWaitAndCapacityGroup(waitTime, batchSize, dbf.OperatorsByFeedIDs,
@@ -349,7 +449,13 @@ func NewLoaders(dbf model.Finder, batchSize int, stopTimeBatchSize int) *Loaders
 				return p.ToStopID, p.Where, p.Limit
 			},
 		),
-		RouteAttributesByRouteIDs: withWaitAndCapacity(waitTime, batchSize, dbf.RouteAttributesByRouteIDs),
+		RiderCategoriesByFeedVersionIDs: withWaitAndCapacityGroup(waitTime, batchSize, dbf.RiderCategoriesByFeedVersionIDs,
+			func(p riderCategoryLoaderParam) (int, *model.RiderCategoryFilter, *int) {
+				return p.FeedVersionID, p.Where, p.Limit
+			},
+		),
+		RiderCategoriesByFeedVersionRiderCategoryIDs: withWaitAndCapacity(waitTime, batchSize, dbf.RiderCategoriesByFeedVersionRiderCategoryIDs),
+		RouteAttributesByRouteIDs:                    withWaitAndCapacity(waitTime, batchSize, dbf.RouteAttributesByRouteIDs),
 		RouteGeometriesByRouteIDs: withWaitAndCapacityGroup(waitTime, batchSize,
 			paramGroupAdapter(dbf.RouteGeometriesByRouteIDs),
 			func(p routeGeometryLoaderParam) (int, bool, *int) {
@@ -374,6 +480,12 @@ func NewLoaders(dbf model.Finder, batchSize int, stopTimeBatchSize int) *Loaders
 		),
 		RoutesByFeedVersionRouteIDs: withWaitAndCapacity(waitTime, batchSize, dbf.RoutesByFeedVersionRouteIDs),
 		RoutesByIDs:                 withWaitAndCapacity(waitTime, batchSize, dbf.RoutesByIDs),
+		RoutesByNetworkIDs: withWaitAndCapacityGroup(waitTime, batchSize,
+			paramGroupAdapter(dbf.RoutesByNetworkIDs),
+			func(p routesByNetworkLoaderParam) (int, bool, *int) {
+				return p.NetworkID, false, p.Limit
+			},
+		),
 		RouteStopPatternsByRouteIDs: withWaitAndCapacityGroup(waitTime, batchSize, dbf.RouteStopPatternsByRouteIDs,
 			func(p routeStopPatternLoaderParam) (model.FVPair, *model.RouteStopPatternFilter, *int) {
 				return model.FVPair{FeedVersionID: p.FeedVersionID, EntityID: p.RouteID}, p.Where, &routeStopPatternLimit
@@ -420,6 +532,12 @@ func NewLoaders(dbf model.Finder, batchSize int, stopTimeBatchSize int) *Loaders
 			},
 		),
 		StopPlacesByStopID: withWaitAndCapacity(waitTime, batchSize, dbf.StopPlacesByStopID),
+		StopsByAreaIDs: withWaitAndCapacityGroup(waitTime, batchSize,
+			paramGroupAdapter(dbf.StopsByAreaIDs),
+			func(p stopsByAreaLoaderParam) (int, bool, *int) {
+				return p.AreaID, false, p.Limit
+			},
+		),
 		StopsByFeedVersionIDs: withWaitAndCapacityGroup(waitTime, batchSize, dbf.StopsByFeedVersionIDs,
 			func(p stopLoaderParam) (int, *model.StopFilter, *int) {
 				return p.FeedVersionID, p.Where, p.Limit
@@ -453,6 +571,37 @@ func NewLoaders(dbf model.Finder, batchSize int, stopTimeBatchSize int) *Loaders
 			},
 		),
 		TargetStopsByStopIDs: withWaitAndCapacity(waitTime, batchSize, dbf.TargetStopsByStopIDs),
+		TimeframesByFeedVersionIDs: withWaitAndCapacityGroup(waitTime, batchSize, dbf.TimeframesByFeedVersionIDs,
+			func(p timeframeLoaderParam) (int, *model.TimeframeFilter, *int) {
+				return p.FeedVersionID, p.Where, p.Limit
+			},
+		),
+		TimeframesByFeedVersionTimeframeGroupIDs: withWaitAndCapacityGroup(waitTime, batchSize,
+			paramGroupAdapter(dbf.TimeframesByFeedVersionTimeframeGroupIDs),
+			func(p timeframesByTimeframeGroupIDLoaderParam) (model.FVEntityID, bool, *int) {
+				return model.FVEntityID{FeedVersionID: p.FeedVersionID, EntityID: p.TimeframeGroupID}, false, p.Limit
+			},
+		),
+		TransfersByFeedVersionIDs: withWaitAndCapacityGroup(waitTime, batchSize, dbf.TransfersByFeedVersionIDs,
+			func(p transferLoaderParam) (int, *model.TransferFilter, *int) {
+				return p.FeedVersionID, p.Where, p.Limit
+			},
+		),
+		TransfersByFromStopIDs: withWaitAndCapacityGroup(waitTime, batchSize, dbf.TransfersByFromStopIDs,
+			func(p transferLoaderParam) (int, *model.TransferFilter, *int) {
+				return p.FromStopID, p.Where, p.Limit
+			},
+		),
+		TransfersByToStopIDs: withWaitAndCapacityGroup(waitTime, batchSize, dbf.TransfersByToStopIDs,
+			func(p transferLoaderParam) (int, *model.TransferFilter, *int) {
+				return p.ToStopID, p.Where, p.Limit
+			},
+		),
+		TranslationsByFeedVersionRecordIDs: withWaitAndCapacityGroup(waitTime, batchSize, dbf.TranslationsByFeedVersionRecordIDs,
+			func(p translationLoaderParam) (model.TranslationKey, string, *int) {
+				return model.TranslationKey{FeedVersionID: p.FeedVersionID, RecordID: p.RecordID, FieldValue: p.FieldValue}, p.TableName, nil
+			},
+		),
 		TripsByFeedVersionIDs: withWaitAndCapacityGroup(waitTime, batchSize, dbf.TripsByFeedVersionIDs,
 			func(p tripLoaderParam) (int, *model.TripFilter, *int) {
 				return p.FeedVersionID, p.Where, p.Limit
@@ -506,6 +655,34 @@ func loaderMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// subscriptionLoaders gives each response of a subscription its own loaders,
+// so a long-lived subscription does not accumulate a cache or serve stale entities.
+type subscriptionLoaders struct{}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+} = subscriptionLoaders{}
+
+func (subscriptionLoaders) ExtensionName() string {
+	return "SubscriptionLoaders"
+}
+
+func (subscriptionLoaders) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (subscriptionLoaders) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	if graphql.HasOperationContext(ctx) {
+		if oc := graphql.GetOperationContext(ctx); oc.Operation != nil && oc.Operation.Operation == ast.Subscription {
+			if cfg := model.ForContext(ctx); cfg.Finder != nil {
+				ctx = context.WithValue(ctx, loadersKey, NewLoaders(cfg.Finder, cfg.LoaderBatchSize, cfg.LoaderStopTimeBatchSize))
+			}
+		}
+	}
+	return next(ctx)
+}
+
 // LoaderFor returns the dataloader for a given context
 func LoaderFor(ctx context.Context) *Loaders {
 	return ctx.Value(loadersKey).(*Loaders)
@@ -0,0 +1,22 @@
+package gql
+
+import (
+	"context"
+
+	"github.com/interline-io/transitland-lib/server/model"
+)
+
+// NETWORKS
+
+type networkResolver struct{ *Resolver }
+
+func (r *networkResolver) Routes(ctx context.Context, obj *model.Network, limit *int) ([]*model.Route, error) {
+	return LoaderFor(ctx).RoutesByNetworkIDs.Load(ctx, routesByNetworkLoaderParam{
+		NetworkID: obj.ID,
+		Limit:     resolverCheckLimit(limit),
+	})()
+}
+
+func (r *networkResolver) FeedVersion(ctx context.Context, obj *model.Network) (*model.FeedVersion, error) {
+	return LoaderFor(ctx).FeedVersionsByIDs.Load(ctx, obj.FeedVersionID)()
+}
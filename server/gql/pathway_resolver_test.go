@@ -173,3 +173,71 @@ func TestPathwayResolver(t *testing.T) {
 	}
 	queryTestcases(t, c, testcases)
 }
+
+func TestStopResolver_PathwayRoutes(t *testing.T) {
+	c, _ := newTestClient(t)
+	wmataSha1 := "148d00724546e1526d5d84d1f9d095df24f6517c"
+	query := `query($sha1:String!,$stop_id:String!,$where:PathwayRouteFilter) {
+		feed_versions(where:{sha1:$sha1}) {
+			stops(where:{stop_id:$stop_id}) {
+				pathway_routes(where:$where) {
+					from_stop { stop_id }
+					to_stop { stop_id }
+					fastest { traversal_time step_free steps { pathway { pathway_id } reversed } }
+					step_free { traversal_time step_free steps { pathway { pathway_id } } }
+				}
+			}
+		}
+	}`
+	routes := "feed_versions.0.stops.0.pathway_routes"
+	testcases := []testcase{
+		{
+			// Every entrance to every platform of Pentagon City
+			name:  "entrances to platforms",
+			query: query,
+			vars:  hw{"sha1": wmataSha1, "stop_id": "STN_C08"},
+			sel: []testcaseSelector{
+				{selector: routes + ".#.from_stop.stop_id", expect: []string{"ENT_C08_E", "ENT_C08_E", "ENT_C08_E_EL", "ENT_C08_E_EL", "ENT_C08_W", "ENT_C08_W"}},
+				{selector: routes + ".#.to_stop.stop_id", expect: []string{"PF_C08_1", "PF_C08_2", "PF_C08_1", "PF_C08_2", "PF_C08_1", "PF_C08_2"}},
+			},
+		},
+		{
+			// Child stops use their parent station
+			name:  "fastest route uses escalators",
+			query: query,
+			vars:  hw{"sha1": wmataSha1, "stop_id": "PF_C08_2", "where": hw{"from_stop_id": "ENT_C08_W", "to_stop_id": "PF_C08_2"}},
+			sel: []testcaseSelector{
+				{selector: routes + ".0.fastest.traversal_time", expect: []string{"175"}},
+				{selector: routes + ".0.fastest.step_free", expect: []string{"false"}},
+				{selector: routes + ".0.fastest.steps.#.pathway.pathway_id", expect: []string{"C08_137110", "C08_137112", "C08_137119", "C08_137120", "C08_137128", "C08_137129", "C08_137130", "C08_137146"}},
+				{selector: routes + ".#.step_free.traversal_time", expect: []string{}},
+			},
+		},
+		{
+			name:  "step-free route uses elevators",
+			query: query,
+			vars:  hw{"sha1": wmataSha1, "stop_id": "STN_C08", "where": hw{"from_stop_id": "ENT_C08_E_EL", "to_stop_id": "PF_C08_2"}},
+			sel: []testcaseSelector{
+				{selector: routes + ".0.step_free.traversal_time", expect: []string{"478"}},
+				{selector: routes + ".0.step_free.step_free", expect: []string{"true"}},
+			},
+		},
+		{
+			name:  "unavailable elevator",
+			query: query,
+			vars:  hw{"sha1": wmataSha1, "stop_id": "STN_C08", "where": hw{"from_stop_id": "ENT_C08_E_EL", "to_stop_id": "PF_C08_2", "unavailable_pathway_ids": []string{"C08_137123"}}},
+			sel: []testcaseSelector{
+				{selector: routes + ".#.fastest.step_free", expect: []string{"false"}},
+				{selector: routes + ".#.step_free.traversal_time", expect: []string{}},
+			},
+		},
+		{
+			name:         "unknown from_stop_id",
+			query:        query,
+			vars:         hw{"sha1": wmataSha1, "stop_id": "ENT_C08_W", "where": hw{"from_stop_id": "missing"}},
+			selector:     routes + ".#.from_stop.stop_id",
+			selectExpect: []string{},
+		},
+	}
+	queryTestcases(t, c, testcases)
+}
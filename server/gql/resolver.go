@@ -175,6 +175,9 @@ func (r *Resolver) Query() gqlout.QueryResolver { return &queryResolver{r} }
 // Mutation .
 func (r *Resolver) Mutation() gqlout.MutationResolver { return &mutationResolver{r} }
 
+// Subscription .
+func (r *Resolver) Subscription() gqlout.SubscriptionResolver { return &subscriptionResolver{r} }
+
 // Agency .
 func (r *Resolver) Agency() gqlout.AgencyResolver { return &agencyResolver{r} }
 
@@ -240,6 +243,46 @@ func (r *Resolver) LocationGroupStop() gqlout.LocationGroupStopResolver {
 	return &locationGroupStopResolver{r}
 }
 
+// FareProduct .
+func (r *Resolver) FareProduct() gqlout.FareProductResolver { return &fareProductResolver{r} }
+
+// FareMedia .
+func (r *Resolver) FareMedia() gqlout.FareMediaResolver { return &fareMediaResolver{r} }
+
+// RiderCategory .
+func (r *Resolver) RiderCategory() gqlout.RiderCategoryResolver { return &riderCategoryResolver{r} }
+
+// FareLegRule .
+func (r *Resolver) FareLegRule() gqlout.FareLegRuleResolver { return &fareLegRuleResolver{r} }
+
+// FareLegJoinRule .
+func (r *Resolver) FareLegJoinRule() gqlout.FareLegJoinRuleResolver {
+	return &fareLegJoinRuleResolver{r}
+}
+
+// FareTransferRule .
+func (r *Resolver) FareTransferRule() gqlout.FareTransferRuleResolver {
+	return &fareTransferRuleResolver{r}
+}
+
+// Timeframe .
+func (r *Resolver) Timeframe() gqlout.TimeframeResolver { return &timeframeResolver{r} }
+
+// Area .
+func (r *Resolver) Area() gqlout.AreaResolver { return &areaResolver{r} }
+
+// Translation .
+func (r *Resolver) Translation() gqlout.TranslationResolver { return &translationResolver{r} }
+
+// Transfer .
+func (r *Resolver) Transfer() gqlout.TransferResolver { return &transferResolver{r} }
+
+// Network .
+func (r *Resolver) Network() gqlout.NetworkResolver { return &networkResolver{r} }
+
+// Attribution .
+func (r *Resolver) Attribution() gqlout.AttributionResolver { return &attributionResolver{r} }
+
 // Operator .
 func (r *Resolver) Operator() gqlout.OperatorResolver { return &operatorResolver{r} }
 
@@ -286,6 +329,17 @@ func (r *Resolver) Directions(ctx context.Context, where model.DirectionRequest)
 	return dr.Directions(ctx, where)
 }
 
+// Reachability .
+func (r *Resolver) Reachability(ctx context.Context, where model.ReachabilityRequest) (*model.Reachability, error) {
+	dr := directionsResolver{r}
+	return dr.Reachability(ctx, where)
+}
+
+// Isochrone .
+func (r *Resolver) Isochrone() gqlout.IsochroneResolver {
+	return &isochroneResolver{r}
+}
+
 func (r *Resolver) Place() gqlout.PlaceResolver {
 	return &placeResolver{r}
 }
@@ -55,8 +55,29 @@ func (r *routeResolver) RouteStops(ctx context.Context, obj *model.Route, limit
 	return LoaderFor(ctx).RouteStopsByRouteIDs.Load(ctx, routeStopLoaderParam{RouteID: obj.ID, Limit: resolverCheckLimit(limit)})()
 }
 
-func (r *routeResolver) Headways(ctx context.Context, obj *model.Route, limit *int) ([]*model.RouteHeadway, error) {
-	return LoaderFor(ctx).RouteHeadwaysByRouteIDs.Load(ctx, routeHeadwayLoaderParam{RouteID: obj.ID, Limit: resolverCheckLimit(limit)})()
+func (r *routeResolver) Headways(ctx context.Context, obj *model.Route, limit *int, effectiveDate *tt.Date) ([]*model.RouteHeadway, error) {
+	routeID := obj.ID
+	if effectiveDate != nil {
+		fvid, err := model.ForContext(ctx).Finder.FindEffectiveFeedVersion(ctx, obj.FeedVersionID, effectiveDate.Val)
+		if err != nil {
+			return nil, err
+		}
+		if fvid != obj.FeedVersionID {
+			route, err := LoaderFor(ctx).RoutesByFeedVersionRouteIDs.Load(ctx, model.FVEntityID{FeedVersionID: fvid, EntityID: obj.RouteID.Val})()
+			if err != nil {
+				return nil, err
+			}
+			if route == nil {
+				return nil, nil
+			}
+			routeID = route.ID
+		}
+	}
+	return LoaderFor(ctx).RouteHeadwaysByRouteIDs.Load(ctx, routeHeadwayLoaderParam{RouteID: routeID, Limit: resolverCheckLimit(limit)})()
+}
+
+func (r *routeResolver) Performance(ctx context.Context, obj *model.Route, where model.PerformanceFilter) (*model.PerformanceReport, error) {
+	return model.ForContext(ctx).Finder.FindPerformance(ctx, model.PerformanceScope{FeedVersionID: obj.FeedVersionID, RouteID: obj.RouteID.Val}, &where)
 }
 
 func (r *routeResolver) RouteStopBuffer(ctx context.Context, obj *model.Route, radius *float64) (*model.RouteStopBuffer, error) {
@@ -95,6 +116,14 @@ func (r *routeResolver) Segments(ctx context.Context, obj *model.Route, limit *i
 	return LoaderFor(ctx).SegmentsByRouteIDs.Load(ctx, segmentLoaderParam{RouteID: obj.ID, Where: where, Limit: resolverCheckLimitMax(limit, RESOLVER_SEGMENT_MAXLIMIT)})()
 }
 
+func (r *routeResolver) FareLegRules(ctx context.Context, obj *model.Route, limit *int, where *model.FareLegRuleFilter) ([]*model.FareLegRule, error) {
+	return LoaderFor(ctx).FareLegRulesByRouteIDs.Load(ctx, fareLegRuleLoaderParam{RouteID: obj.ID, Where: where, Limit: resolverCheckLimit(limit)})()
+}
+
+func (r *routeResolver) Networks(ctx context.Context, obj *model.Route, limit *int) ([]*model.Network, error) {
+	return LoaderFor(ctx).NetworksByRouteIDs.Load(ctx, networksByRouteLoaderParam{RouteID: obj.ID, Limit: resolverCheckLimit(limit)})()
+}
+
 // ROUTE HEADWAYS
 
 type routeHeadwayResolver struct{ *Resolver }
@@ -150,3 +179,23 @@ func (r *routePatternResolver) Trips(ctx context.Context, obj *model.RouteStopPa
 // func (r *routePatternResolver) Stops(ctx context.Context, obj *model.RouteStopPattern) ([]*model.Stop, error) {
 // 	return nil, nil
 // }
+
+func (r *routeResolver) RouteShortName(ctx context.Context, obj *model.Route, lang *string) (*string, error) {
+	return translatedString(translateField(ctx, lang, obj.FeedVersionID, "routes", obj.RouteID.Val, "", "route_short_name", obj.RouteShortName))
+}
+
+func (r *routeResolver) RouteLongName(ctx context.Context, obj *model.Route, lang *string) (*string, error) {
+	return translatedString(translateField(ctx, lang, obj.FeedVersionID, "routes", obj.RouteID.Val, "", "route_long_name", obj.RouteLongName))
+}
+
+func (r *routeResolver) RouteDesc(ctx context.Context, obj *model.Route, lang *string) (*string, error) {
+	return translatedString(translateField(ctx, lang, obj.FeedVersionID, "routes", obj.RouteID.Val, "", "route_desc", obj.RouteDesc))
+}
+
+func (r *routeResolver) Translations(ctx context.Context, obj *model.Route, lang *string) ([]*model.Translation, error) {
+	return recordTranslations(ctx, lang, obj.FeedVersionID, "routes", obj.RouteID.Val, map[string]tt.String{
+		"route_short_name": obj.RouteShortName,
+		"route_long_name":  obj.RouteLongName,
+		"route_desc":       obj.RouteDesc,
+	})
+}
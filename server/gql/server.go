@@ -90,6 +90,7 @@ func NewServer(opts ...ServerOption) (http.Handler, error) {
 	})
 	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
 	srv.Use(extension.Introspection{})
+	srv.Use(subscriptionLoaders{})
 	srv.Use(extension.AutomaticPersistedQuery{
 		Cache: lru.New[string](100),
 	})
@@ -101,6 +102,6 @@ func NewServer(opts ...ServerOption) (http.Handler, error) {
 		}
 	}
 
-	graphqlServer := loaderMiddleware(srv)
+	graphqlServer := LanguageMiddleware(loaderMiddleware(srv))
 	return graphqlServer, nil
 }
@@ -6,9 +6,11 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/interline-io/transitland-lib/internal/graph"
 	"github.com/interline-io/transitland-lib/server/directions"
 	"github.com/interline-io/transitland-lib/server/model"
 	"github.com/interline-io/transitland-lib/tlxy"
+	"github.com/interline-io/transitland-lib/tt"
 )
 
 // STOP
@@ -30,6 +32,10 @@ func (r *stopResolver) LocationGroups(ctx context.Context, obj *model.Stop, limi
 	return LoaderFor(ctx).LocationGroupsByStopIDs.Load(ctx, locationGroupsByStopLoaderParam{StopID: obj.ID, Limit: limit})()
 }
 
+func (r *stopResolver) Areas(ctx context.Context, obj *model.Stop, limit *int) ([]*model.Area, error) {
+	return LoaderFor(ctx).AreasByStopIDs.Load(ctx, areasByStopLoaderParam{StopID: obj.ID, Limit: resolverCheckLimit(limit)})()
+}
+
 func (r *stopResolver) Level(ctx context.Context, obj *model.Stop) (*model.Level, error) {
 	if !obj.LevelID.Valid {
 		return nil, nil
@@ -69,6 +75,168 @@ func (r *stopResolver) PathwaysToStop(ctx context.Context, obj *model.Stop, limi
 	return LoaderFor(ctx).PathwaysByToStopID.Load(ctx, pathwayLoaderParam{ToStopID: obj.ID, Limit: resolverCheckLimit(limit)})()
 }
 
+func (r *stopResolver) TransfersFrom(ctx context.Context, obj *model.Stop, limit *int, where *model.TransferFilter) ([]*model.Transfer, error) {
+	return LoaderFor(ctx).TransfersByFromStopIDs.Load(ctx, transferLoaderParam{FromStopID: obj.ID, Limit: resolverCheckLimit(limit), Where: where})()
+}
+
+func (r *stopResolver) TransfersTo(ctx context.Context, obj *model.Stop, limit *int, where *model.TransferFilter) ([]*model.Transfer, error) {
+	return LoaderFor(ctx).TransfersByToStopIDs.Load(ctx, transferLoaderParam{ToStopID: obj.ID, Limit: resolverCheckLimit(limit), Where: where})()
+}
+
+// PathwayRoutes finds routes through the pathways of the stop's station.
+// Child stops use their parent station; boarding areas are included through their platforms.
+func (r *stopResolver) PathwayRoutes(ctx context.Context, obj *model.Stop, where *model.PathwayRouteFilter) ([]*model.PathwayRoute, error) {
+	if where == nil {
+		where = &model.PathwayRouteFilter{}
+	}
+	// Find the station
+	station := obj
+	for station.LocationType.Val != 1 && station.ParentStation.Valid {
+		parent, err := LoaderFor(ctx).StopsByIDs.Load(ctx, station.ParentStation.Int())()
+		if err != nil {
+			return nil, err
+		}
+		if parent == nil {
+			break
+		}
+		station = parent
+	}
+	if station.LocationType.Val != 1 {
+		return nil, nil
+	}
+
+	// Load the station's stops and pathways, one level of the hierarchy at a time
+	stops := map[string]*model.Stop{station.EntityID(): station}
+	g := graph.NewPathwayGraph()
+	g.AddStop(&station.Stop)
+	stopIDs := []int{station.ID}
+	for pending := []int{station.ID}; len(pending) > 0; {
+		params := make([]stopLoaderParam, len(pending))
+		for i, parentID := range pending {
+			params[i] = stopLoaderParam{ParentStopID: parentID, Limit: resolverCheckLimitDefault(nil, RESOLVER_MAXLIMIT, RESOLVER_MAXLIMIT)}
+		}
+		groups, errs := LoaderFor(ctx).StopsByParentStopIDs.LoadMany(ctx, params)()
+		for _, err := range errs {
+			if err != nil {
+				return nil, err
+			}
+		}
+		pending = nil
+		for _, children := range groups {
+			for _, child := range children {
+				stops[child.EntityID()] = child
+				stopIDs = append(stopIDs, child.ID)
+				g.AddStop(&child.Stop)
+				if child.LocationType.Val == 0 {
+					pending = append(pending, child.ID)
+				}
+			}
+		}
+	}
+	pathwayParams := make([]pathwayLoaderParam, len(stopIDs))
+	for i, stopID := range stopIDs {
+		pathwayParams[i] = pathwayLoaderParam{FromStopID: stopID, Limit: resolverCheckLimitDefault(nil, RESOLVER_MAXLIMIT, RESOLVER_MAXLIMIT)}
+	}
+	pathwayGroups, errs := LoaderFor(ctx).PathwaysByFromStopIDs.LoadMany(ctx, pathwayParams)()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	pathways := map[string]*model.Pathway{}
+	for _, ents := range pathwayGroups {
+		for _, ent := range ents {
+			pathways[ent.EntityID()] = ent
+			g.AddPathway(&ent.Pathway)
+		}
+	}
+
+	// Unavailable pathways
+	opts := graph.PathwayRouteOptions{Unavailable: map[string]bool{}}
+	unavailableIDs := map[string]bool{}
+	for _, v := range where.UnavailablePathwayIds {
+		unavailableIDs[v] = true
+	}
+	alertStops := map[string]bool{}
+	if where.UseAlerts != nil && *where.UseAlerts {
+		active := true
+		var stationStops []*model.Stop
+		for _, stop := range stops {
+			stationStops = append(stationStops, stop)
+		}
+		for i, alerts := range model.ForContext(ctx).RTFinder.FindAlertsForStops(ctx, stationStops, &active) {
+			for _, alert := range alerts {
+				if alert.Effect != nil && (*alert.Effect == "ACCESSIBILITY_ISSUE" || *alert.Effect == "NO_SERVICE") {
+					alertStops[stationStops[i].EntityID()] = true
+				}
+			}
+		}
+	}
+	for eid, ent := range pathways {
+		if unavailableIDs[ent.PathwayID.Val] {
+			opts.Unavailable[eid] = true
+		} else if ent.PathwayMode.Val == 5 && (alertStops[ent.FromStopID.Val] || alertStops[ent.ToStopID.Val]) {
+			opts.Unavailable[eid] = true
+		}
+	}
+
+	// Select endpoints by GTFS stop_id
+	selectStops := func(eids []string, stopID *string) []string {
+		if stopID == nil {
+			return eids
+		}
+		for eid, stop := range stops {
+			if stop.StopID.Val == *stopID {
+				return []string{eid}
+			}
+		}
+		return nil
+	}
+	fromStops := selectStops(g.Entrances(station.EntityID()), where.FromStopID)
+	toStops := selectStops(g.Platforms(station.EntityID()), where.ToStopID)
+	routeOption := func(route graph.PathwayRoute) *model.PathwayRouteOption {
+		ret := &model.PathwayRouteOption{TraversalTime: route.TraversalTime, StepFree: route.StepFree(), Steps: []*model.PathwayRouteStep{}}
+		for _, e := range route.Edges {
+			if e.Pathway == nil {
+				continue
+			}
+			ret.Steps = append(ret.Steps, &model.PathwayRouteStep{
+				Pathway:       pathways[e.Pathway.EntityID()],
+				Reversed:      e.Reversed,
+				TraversalTime: e.TraversalTime(),
+			})
+		}
+		return ret
+	}
+	var ret []*model.PathwayRoute
+	stepFreeOpts := opts
+	stepFreeOpts.StepFree = true
+	for _, from := range fromStops {
+		fastest := map[string]graph.PathwayRoute{}
+		for _, route := range g.Routes(from, toStops, opts) {
+			fastest[route.To] = route
+		}
+		stepFree := map[string]graph.PathwayRoute{}
+		for _, route := range g.Routes(from, toStops, stepFreeOpts) {
+			stepFree[route.To] = route
+		}
+		for _, to := range toStops {
+			if from == to {
+				continue
+			}
+			pr := &model.PathwayRoute{FromStop: stops[from], ToStop: stops[to]}
+			if route, ok := fastest[to]; ok {
+				pr.Fastest = routeOption(route)
+			}
+			if route, ok := stepFree[to]; ok {
+				pr.StepFree = routeOption(route)
+			}
+			ret = append(ret, pr)
+		}
+	}
+	return ret, nil
+}
+
 func (r *stopResolver) ExternalReference(ctx context.Context, obj *model.Stop) (*model.StopExternalReference, error) {
 	return LoaderFor(ctx).StopExternalReferencesByStopIDs.Load(ctx, obj.ID)()
 }
@@ -77,6 +245,10 @@ func (r *stopResolver) Observations(ctx context.Context, obj *model.Stop, limit
 	return LoaderFor(ctx).StopObservationsByStopIDs.Load(ctx, stopObservationLoaderParam{StopID: obj.ID, Where: where, Limit: resolverCheckLimitMax(limit, RESOLVER_STOP_OBSERVATION_MAXLIMIT)})()
 }
 
+func (r *stopResolver) Performance(ctx context.Context, obj *model.Stop, where model.PerformanceFilter) (*model.PerformanceReport, error) {
+	return model.ForContext(ctx).Finder.FindPerformance(ctx, model.PerformanceScope{FeedVersionID: obj.FeedVersionID, StopID: obj.StopID.Val}, &where)
+}
+
 func (r *stopResolver) Departures(ctx context.Context, obj *model.Stop, limit *int, where *model.StopTimeFilter) ([]*model.StopTime, error) {
 	if where == nil {
 		where = &model.StopTimeFilter{}
@@ -198,3 +370,28 @@ type stopExternalReferenceResolver struct {
 func (r *stopExternalReferenceResolver) TargetActiveStop(ctx context.Context, obj *model.StopExternalReference) (*model.Stop, error) {
 	return LoaderFor(ctx).TargetStopsByStopIDs.Load(ctx, obj.StopID.Int())()
 }
+
+func (r *stopResolver) StopName(ctx context.Context, obj *model.Stop, lang *string) (*string, error) {
+	return translatedString(translateField(ctx, lang, obj.FeedVersionID, "stops", obj.StopID.Val, "", "stop_name", obj.StopName))
+}
+
+func (r *stopResolver) TtsStopName(ctx context.Context, obj *model.Stop, lang *string) (*string, error) {
+	return translatedString(translateField(ctx, lang, obj.FeedVersionID, "stops", obj.StopID.Val, "", "tts_stop_name", obj.TtsStopName))
+}
+
+func (r *stopResolver) StopDesc(ctx context.Context, obj *model.Stop, lang *string) (*string, error) {
+	return translatedString(translateField(ctx, lang, obj.FeedVersionID, "stops", obj.StopID.Val, "", "stop_desc", obj.StopDesc))
+}
+
+func (r *stopResolver) PlatformCode(ctx context.Context, obj *model.Stop, lang *string) (*string, error) {
+	return translatedString(translateField(ctx, lang, obj.FeedVersionID, "stops", obj.StopID.Val, "", "platform_code", obj.PlatformCode))
+}
+
+func (r *stopResolver) Translations(ctx context.Context, obj *model.Stop, lang *string) ([]*model.Translation, error) {
+	return recordTranslations(ctx, lang, obj.FeedVersionID, "stops", obj.StopID.Val, map[string]tt.String{
+		"stop_name":     obj.StopName,
+		"tts_stop_name": obj.TtsStopName,
+		"stop_desc":     obj.StopDesc,
+		"platform_code": obj.PlatformCode,
+	})
+}
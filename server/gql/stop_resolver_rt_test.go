@@ -1,9 +1,11 @@
 package gql
 
 import (
+	"context"
 	"testing"
 
 	"github.com/interline-io/transitland-lib/internal/testconfig"
+	"github.com/interline-io/transitland-lib/server/model"
 	"github.com/interline-io/transitland-lib/tt"
 	"github.com/stretchr/testify/assert"
 	"github.com/tidwall/gjson"
@@ -553,3 +555,28 @@ func TestStopRT_ScheduleRelationshipWithoutArrivalDeparture(t *testing.T) {
 		},
 	})
 }
+
+func TestStopRT_AlertsForStops(t *testing.T) {
+	_, cfg := newTestClient(t)
+	ctx := model.WithConfig(context.Background(), cfg)
+	limit := 1000
+	stops, err := cfg.Finder.FindStops(ctx, &limit, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, active := range []bool{false, true} {
+		byStop := cfg.RTFinder.FindAlertsForStops(ctx, stops, &active)
+		if len(byStop) != len(stops) {
+			t.Fatalf("got %d results, expected %d", len(byStop), len(stops))
+		}
+		found := 0
+		for i, stop := range stops {
+			expect := cfg.RTFinder.FindAlertsForStop(ctx, stop, nil, &active)
+			assert.Equal(t, len(expect), len(byStop[i]), "stop %s", stop.StopID.Val)
+			found += len(byStop[i])
+		}
+		if found == 0 {
+			t.Errorf("expected alerts for at least one stop")
+		}
+	}
+}
@@ -3,6 +3,7 @@ package gql
 import (
 	"context"
 	"errors"
+	"strconv"
 	"time"
 
 	"github.com/interline-io/transitland-lib/rt/pb"
@@ -184,3 +185,21 @@ func fromSte(ste *pb.TripUpdate_StopTimeEvent, lastDelay *int32, sched tt.Second
 	}
 	return &a
 }
+
+// StopHeadsign translations reference the stop time by GTFS trip_id and stop_sequence.
+func (r *stopTimeResolver) StopHeadsign(ctx context.Context, obj *model.StopTime, lang *string) (*string, error) {
+	if len(requestLanguages(ctx, lang)) == 0 {
+		return translatedString(obj.StopHeadsign, nil)
+	}
+	tripID := ""
+	if obj.TripID.Val != "0" && obj.TripID.Val != "" {
+		trip, err := LoaderFor(ctx).TripsByIDs.Load(ctx, obj.TripID.Int())()
+		if err != nil {
+			return nil, err
+		}
+		if trip != nil {
+			tripID = trip.TripID.Val
+		}
+	}
+	return translatedString(translateField(ctx, lang, obj.FeedVersionID, "stop_times", tripID, strconv.Itoa(obj.StopSequence.Int()), "stop_headsign", obj.StopHeadsign))
+}
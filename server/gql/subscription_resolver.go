@@ -0,0 +1,170 @@
+package gql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+
+	"github.com/interline-io/log"
+	"github.com/interline-io/transitland-lib/server/model"
+	"google.golang.org/protobuf/proto"
+)
+
+type subscriptionResolver struct{ *Resolver }
+
+func (r *subscriptionResolver) VehiclePositions(ctx context.Context, limit *int, where model.VehiclePositionFilter) (<-chan []*model.VehiclePosition, error) {
+	ctx = addMetric(ctx, "subscriptionVehiclePositions")
+	if err := checkVehiclePositionGeo(ctx, &where); err != nil {
+		return nil, err
+	}
+	// The search area is fixed for the subscription, so its agencies are resolved once
+	agencies, err := vehiclePositionAgencies(ctx, where)
+	if err != nil {
+		return nil, err
+	}
+	return subscribeRT(ctx, []string{"realtime_vehicle_positions"}, func(ctx context.Context) ([]*model.VehiclePosition, []byte, error) {
+		ret := findVehiclePositions(ctx, agencies, limit, where)
+		fp, err := json.Marshal(ret)
+		return ret, fp, err
+	})
+}
+
+func (r *subscriptionResolver) TripUpdates(ctx context.Context, where model.TripUpdateSubscriptionFilter) (<-chan *model.Trip, error) {
+	ctx = addMetric(ctx, "subscriptionTripUpdates")
+	trips, err := model.ForContext(ctx).Finder.FindTrips(ctx, ptr(1), nil, nil, &model.TripFilter{
+		FeedOnestopID: &where.FeedOnestopID,
+		TripID:        &where.TripID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(trips) == 0 {
+		return nil, errors.New("trip not found")
+	}
+	trip := trips[0]
+	return subscribeRT(ctx, []string{"realtime_trip_updates"}, func(ctx context.Context) (*model.Trip, []byte, error) {
+		// The trip's realtime fields are resolved from the current trip update
+		var fp []byte
+		if rtTrip := model.ForContext(ctx).RTFinder.FindTrip(ctx, trip); rtTrip != nil {
+			var err error
+			if fp, err = (proto.MarshalOptions{Deterministic: true}).Marshal(rtTrip); err != nil {
+				return nil, nil, err
+			}
+		}
+		return trip, fp, nil
+	})
+}
+
+func (r *subscriptionResolver) Alerts(ctx context.Context, active *bool, limit *int, where model.AlertSubscriptionFilter) (<-chan []*model.Alert, error) {
+	ctx = addMetric(ctx, "subscriptionAlerts")
+	finder := model.ForContext(ctx).Finder
+	rtFinder := model.ForContext(ctx).RTFinder
+	var find func(context.Context) []*model.Alert
+	switch {
+	case where.AgencyID != nil && where.RouteID == nil && where.StopID == nil:
+		ents, err := finder.FindAgencies(ctx, ptr(1), nil, nil, &model.AgencyFilter{FeedOnestopID: &where.FeedOnestopID, AgencyID: where.AgencyID})
+		if err != nil {
+			return nil, err
+		}
+		if len(ents) == 0 {
+			return nil, errors.New("agency not found")
+		}
+		find = func(ctx context.Context) []*model.Alert {
+			return rtFinder.FindAlertsForAgency(ctx, ents[0], resolverCheckLimit(limit), active)
+		}
+	case where.RouteID != nil && where.AgencyID == nil && where.StopID == nil:
+		ents, err := finder.FindRoutes(ctx, ptr(1), nil, nil, &model.RouteFilter{FeedOnestopID: &where.FeedOnestopID, RouteID: where.RouteID})
+		if err != nil {
+			return nil, err
+		}
+		if len(ents) == 0 {
+			return nil, errors.New("route not found")
+		}
+		find = func(ctx context.Context) []*model.Alert {
+			return rtFinder.FindAlertsForRoute(ctx, ents[0], resolverCheckLimit(limit), active)
+		}
+	case where.StopID != nil && where.AgencyID == nil && where.RouteID == nil:
+		ents, err := finder.FindStops(ctx, ptr(1), nil, nil, &model.StopFilter{FeedOnestopID: &where.FeedOnestopID, StopID: where.StopID})
+		if err != nil {
+			return nil, err
+		}
+		if len(ents) == 0 {
+			return nil, errors.New("stop not found")
+		}
+		find = func(ctx context.Context) []*model.Alert {
+			return rtFinder.FindAlertsForStop(ctx, ents[0], resolverCheckLimit(limit), active)
+		}
+	default:
+		return nil, errors.New("exactly one of agency_id, route_id, or stop_id is required")
+	}
+	return subscribeRT(ctx, []string{"realtime_alerts"}, func(ctx context.Context) ([]*model.Alert, []byte, error) {
+		ret := find(ctx)
+		fp, err := json.Marshal(ret)
+		return ret, fp, err
+	})
+}
+
+// subscribeRT sends the result of fetch when the subscription starts, and again
+// after each update to a realtime message of one of the given types.
+// Results with the same fingerprint as the last result sent are skipped.
+func subscribeRT[T any](ctx context.Context, messageTypes []string, fetch func(context.Context) (T, []byte, error)) (<-chan T, error) {
+	rtFinder := model.ForContext(ctx).RTFinder
+	if rtFinder == nil {
+		return nil, errors.New("realtime data not available")
+	}
+	// Watch before the first fetch so an update in between is not missed.
+	// The first fetch also reads the topics, which subscribes this process
+	// to their pub/sub updates.
+	updates := rtFinder.WatchUpdates(ctx)
+	first, last, err := fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan T, 1)
+	out <- first
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case update, ok := <-updates:
+				if !ok {
+					return
+				}
+				if !slices.Contains(messageTypes, update.MessageType) {
+					continue
+				}
+			}
+			// Coalesce notifications that arrived while the last result was sent
+		drain:
+			for {
+				select {
+				case _, ok := <-updates:
+					if !ok {
+						return
+					}
+				default:
+					break drain
+				}
+			}
+			ret, fp, err := fetch(ctx)
+			if err != nil {
+				log.For(ctx).Error().Err(err).Msg("subscription: failed to fetch result")
+				continue
+			}
+			if bytes.Equal(fp, last) {
+				continue
+			}
+			last = fp
+			select {
+			case <-ctx.Done():
+				return
+			case out <- ret:
+			}
+		}
+	}()
+	return out, nil
+}
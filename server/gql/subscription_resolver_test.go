@@ -0,0 +1,104 @@
+package gql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/interline-io/transitland-lib/internal/testconfig"
+	"github.com/interline-io/transitland-lib/rt"
+	"github.com/interline-io/transitland-lib/testdata"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSubscriptionResolver_VehiclePositions(t *testing.T) {
+	c, cfg := newTestClientWithOpts(t, testconfig.Options{
+		RTJsons: baVehiclePositions(),
+	})
+	sub := c.Websocket(`subscription($where: VehiclePositionFilter!) { vehicle_positions(where: $where) { id } }`,
+		client.Var("where", hw{"bbox": oaklandBbox}),
+	)
+	defer sub.Close()
+	type vpResponse struct {
+		VehiclePositions []struct {
+			ID string `json:"id"`
+		} `json:"vehicle_positions"`
+	}
+	nextIDs := func() []string {
+		var resp vpResponse
+		done := make(chan error, 1)
+		go func() { done <- sub.Next(&resp) }()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for subscription result")
+		}
+		var ids []string
+		for _, vp := range resp.VehiclePositions {
+			ids = append(ids, vp.ID)
+		}
+		return ids
+	}
+
+	// Initial result
+	assert.ElementsMatch(t, []string{"1001", "1002"}, nextIDs())
+
+	// Publish an update without vehicle 1002
+	msg, err := rt.ReadFile(testdata.Path("server", "rt", "BA-vehicle-positions.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg = rt.FilterFeedMessage(msg, rt.FeedMessageFilter{TripIDs: []string{"3210613WKDY"}})
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.RTFinder.AddData(context.Background(), "rtdata:BA:realtime_vehicle_positions", data); err != nil {
+		t.Fatal(err)
+	}
+	assert.ElementsMatch(t, []string{"1001"}, nextIDs())
+}
+
+func TestSubscriptionResolver_Alerts(t *testing.T) {
+	c, _ := newTestClient(t)
+	t.Run("requires one entity", func(t *testing.T) {
+		sub := c.Websocket(`subscription { alerts(where: {feed_onestop_id: "BA", route_id: "01", stop_id: "12TH"}) { id } }`)
+		defer sub.Close()
+		var resp map[string]any
+		assert.Error(t, sub.Next(&resp))
+	})
+	t.Run("route not found", func(t *testing.T) {
+		sub := c.Websocket(`subscription { alerts(where: {feed_onestop_id: "BA", route_id: "missing"}) { id } }`)
+		defer sub.Close()
+		var resp map[string]any
+		assert.Error(t, sub.Next(&resp))
+	})
+	t.Run("agency", func(t *testing.T) {
+		sub := c.Websocket(`subscription { alerts(where: {feed_onestop_id: "BA", agency_id: "BART"}) { header_text { text } } }`)
+		defer sub.Close()
+		var resp map[string]any
+		assert.NoError(t, sub.Next(&resp))
+		assert.Contains(t, resp, "alerts")
+	})
+}
+
+func TestSubscriptionResolver_TripUpdates(t *testing.T) {
+	c, _ := newTestClient(t)
+	sub := c.Websocket(`subscription { trip_updates(where: {feed_onestop_id: "BA", trip_id: "1011630WKDY"}) { trip_id schedule_relationship } }`)
+	defer sub.Close()
+	var resp struct {
+		TripUpdates struct {
+			TripID               string `json:"trip_id"`
+			ScheduleRelationship string `json:"schedule_relationship"`
+		} `json:"trip_updates"`
+	}
+	if err := sub.Next(&resp); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "1011630WKDY", resp.TripUpdates.TripID)
+}
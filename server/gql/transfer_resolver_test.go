@@ -0,0 +1,85 @@
+package gql
+
+import (
+	"testing"
+)
+
+// BART provides stop-level transfers.txt records for a handful of stations;
+// the test feeds do not include networks.txt or attributions.txt.
+
+func TestTransferResolver(t *testing.T) {
+	c, _ := newTestClient(t)
+	bartSha1 := "e535eb2b3b9ac3ef15d82c56575e914575e732e0"
+	testcases := []testcase{
+		{
+			name:         "feed version transfers",
+			query:        `query($sha1:String!) { feed_versions(where:{sha1:$sha1}) { transfers { from_stop { stop_id } } } }`,
+			vars:         hw{"sha1": bartSha1},
+			selector:     "feed_versions.0.transfers.#.from_stop.stop_id",
+			selectExpect: []string{"19TH_N", "MCAR_S", "BALB", "BAYF", "SBRN", "COLS", "FRMT", "WCRK"},
+		},
+		{
+			name:         "feed version transfers by transfer_type",
+			query:        `query($sha1:String!) { feed_versions(where:{sha1:$sha1}) { transfers(where:{transfer_type:1}) { from_stop { stop_id } } } }`,
+			vars:         hw{"sha1": bartSha1},
+			selector:     "feed_versions.0.transfers.#.from_stop.stop_id",
+			selectExpect: []string{"19TH_N", "MCAR_S", "SBRN", "FRMT", "WCRK"},
+		},
+		{
+			name:         "feed version transfers limit",
+			query:        `query($sha1:String!) { feed_versions(where:{sha1:$sha1}) { transfers(limit:2) { from_stop { stop_id } } } }`,
+			vars:         hw{"sha1": bartSha1},
+			selector:     "feed_versions.0.transfers.#.from_stop.stop_id",
+			selectExpect: []string{"19TH_N", "MCAR_S"},
+		},
+		{
+			name:  "stop transfers_from",
+			query: `query($sha1:String!) { feed_versions(where:{sha1:$sha1}) { stops(where:{stop_id:"COLS"}) { transfers_from { transfer_type min_transfer_time to_stop { stop_id } from_route { route_id } from_trip { trip_id } feed_version_sha1 } } } }`,
+			vars:  hw{"sha1": bartSha1},
+			sel: []testcaseSelector{
+				{selector: "feed_versions.0.stops.0.transfers_from.#.to_stop.stop_id", expect: []string{"COLS"}},
+				{selector: "feed_versions.0.stops.0.transfers_from.#.transfer_type", expect: []string{"2"}},
+				{selector: "feed_versions.0.stops.0.transfers_from.#.min_transfer_time", expect: []string{"240"}},
+				{selector: "feed_versions.0.stops.0.transfers_from.#.from_route.route_id", expect: []string{}},
+				{selector: "feed_versions.0.stops.0.transfers_from.#.from_trip.trip_id", expect: []string{}},
+				{selector: "feed_versions.0.stops.0.transfers_from.#.feed_version_sha1", expect: []string{bartSha1}},
+			},
+		},
+		{
+			name:         "stop transfers_to",
+			query:        `query($sha1:String!) { feed_versions(where:{sha1:$sha1}) { stops(where:{stop_id:"BALB"}) { transfers_to { from_stop { stop_id } } } } }`,
+			vars:         hw{"sha1": bartSha1},
+			selector:     "feed_versions.0.stops.0.transfers_to.#.from_stop.stop_id",
+			selectExpect: []string{"BALB"},
+		},
+		{
+			name:         "stop without transfers",
+			query:        `query($sha1:String!) { feed_versions(where:{sha1:$sha1}) { stops(where:{stop_id:"MONT"}) { transfers_from { id } } } }`,
+			vars:         hw{"sha1": bartSha1},
+			selector:     "feed_versions.0.stops.0.transfers_from.#.id",
+			selectExpect: []string{},
+		},
+		{
+			name:         "feed version networks",
+			query:        `query($sha1:String!) { feed_versions(where:{sha1:$sha1}) { networks { network_id routes { route_id } } } }`,
+			vars:         hw{"sha1": bartSha1},
+			selector:     "feed_versions.0.networks.#.network_id",
+			selectExpect: []string{},
+		},
+		{
+			name:         "route networks",
+			query:        `query($sha1:String!) { feed_versions(where:{sha1:$sha1}) { routes(where:{route_id:"01"}) { networks { network_id } } } }`,
+			vars:         hw{"sha1": bartSha1},
+			selector:     "feed_versions.0.routes.0.networks.#.network_id",
+			selectExpect: []string{},
+		},
+		{
+			name:         "feed version attributions",
+			query:        `query($sha1:String!) { feed_versions(where:{sha1:$sha1}) { attributions { organization_name agency { agency_id } } } }`,
+			vars:         hw{"sha1": bartSha1},
+			selector:     "feed_versions.0.attributions.#.organization_name",
+			selectExpect: []string{},
+		},
+	}
+	queryTestcases(t, c, testcases)
+}
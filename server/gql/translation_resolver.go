@@ -0,0 +1,215 @@
+package gql
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/interline-io/transitland-lib/server/model"
+	"github.com/interline-io/transitland-lib/tt"
+)
+
+// TRANSLATIONS
+
+type translationResolver struct{ *Resolver }
+
+func (r *translationResolver) Translation(ctx context.Context, obj *model.Translation) (string, error) {
+	return obj.Translation.Translation.Val, nil
+}
+
+const languagesKey = ctxKey("languages")
+
+// LanguageMiddleware stores the languages of the Accept-Language header, in order of preference,
+// for translating fields. Requests without the header keep any languages already in the context.
+func LanguageMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if langs := parseAcceptLanguage(r.Header.Get("Accept-Language")); len(langs) > 0 {
+			r = r.WithContext(context.WithValue(r.Context(), languagesKey, langs))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// parseAcceptLanguage returns the language tags of an Accept-Language header, ordered by quality.
+// Wildcards and tags with a quality of 0 are ignored.
+func parseAcceptLanguage(header string) []string {
+	type langQ struct {
+		lang string
+		q    float64
+	}
+	var items []langQ
+	for _, part := range strings.Split(header, ",") {
+		lang, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		lang = strings.TrimSpace(lang)
+		if lang == "" || lang == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if k, v, ok := strings.Cut(strings.TrimSpace(param), "="); ok && strings.TrimSpace(k) == "q" {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		items = append(items, langQ{lang: lang, q: q})
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].q > items[j].q })
+	var ret []string
+	for _, item := range items {
+		ret = append(ret, item.lang)
+	}
+	return ret
+}
+
+// requestLanguages returns the requested languages: the lang argument if provided,
+// otherwise the Accept-Language header. An empty lang argument disables translation.
+func requestLanguages(ctx context.Context, lang *string) []string {
+	if lang != nil {
+		var ret []string
+		for _, v := range strings.Split(*lang, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				ret = append(ret, v)
+			}
+		}
+		return ret
+	}
+	langs, _ := ctx.Value(languagesKey).([]string)
+	return langs
+}
+
+// languageMatch returns 2 if the tags are equal, 1 if they share a primary language subtag, otherwise 0.
+func languageMatch(requested string, lang string) int {
+	if strings.EqualFold(requested, lang) {
+		return 2
+	}
+	a, _, _ := strings.Cut(requested, "-")
+	b, _, _ := strings.Cut(lang, "-")
+	if strings.EqualFold(a, b) {
+		return 1
+	}
+	return 0
+}
+
+// selectTranslation finds the best translation of a field value. Earlier languages are preferred,
+// then exact language matches, then translations by record_id over translations by field_value.
+func selectTranslation(translations []*model.Translation, langs []string, fieldName string, recordSubID string, value string) (string, bool) {
+	bestScore := -1
+	best := ""
+	for _, ent := range translations {
+		if ent.FieldName.Val != fieldName {
+			continue
+		}
+		byRecord := ent.RecordID.Val != ""
+		if byRecord && ent.RecordSubID.Val != recordSubID {
+			continue
+		}
+		if !byRecord && (value == "" || ent.FieldValue.Val != value) {
+			continue
+		}
+		for i, lang := range langs {
+			m := languageMatch(lang, ent.Language.Val)
+			if m == 0 {
+				continue
+			}
+			score := i * 4
+			if m == 1 {
+				score += 2
+			}
+			if !byRecord {
+				score += 1
+			}
+			if bestScore < 0 || score < bestScore {
+				bestScore = score
+				best = ent.Translation.Translation.Val
+			}
+			break
+		}
+	}
+	return best, bestScore >= 0
+}
+
+// translateField returns the translation of a field in the requested languages, or the original value.
+func translateField(ctx context.Context, lang *string, fvid int, tableName string, recordID string, recordSubID string, fieldName string, value tt.String) (tt.String, error) {
+	langs := requestLanguages(ctx, lang)
+	if len(langs) == 0 {
+		return value, nil
+	}
+	translations, err := LoaderFor(ctx).TranslationsByFeedVersionRecordIDs.Load(ctx, translationLoaderParam{FeedVersionID: fvid, TableName: tableName, RecordID: recordID, FieldValue: value.Val})()
+	if err != nil {
+		return value, err
+	}
+	if v, ok := selectTranslation(translations, langs, fieldName, recordSubID, value.Val); ok {
+		return tt.NewString(v), nil
+	}
+	return value, nil
+}
+
+// recordTranslations returns the translations of a record, by record_id or matching one of its field values,
+// optionally only in the languages of lang.
+func recordTranslations(ctx context.Context, lang *string, fvid int, tableName string, recordID string, values map[string]tt.String) ([]*model.Translation, error) {
+	// Load translations by record_id, and by each distinct field value
+	loader := LoaderFor(ctx).TranslationsByFeedVersionRecordIDs
+	params := []translationLoaderParam{{FeedVersionID: fvid, TableName: tableName, RecordID: recordID}}
+	seen := map[string]bool{}
+	var fieldValues []string
+	for _, v := range values {
+		if v.Val != "" && !seen[v.Val] {
+			seen[v.Val] = true
+			fieldValues = append(fieldValues, v.Val)
+		}
+	}
+	sort.Strings(fieldValues)
+	for _, v := range fieldValues {
+		params = append(params, translationLoaderParam{FeedVersionID: fvid, TableName: tableName, FieldValue: v})
+	}
+	groups, errs := loader.LoadMany(ctx, params)()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	var translations []*model.Translation
+	for _, group := range groups {
+		translations = append(translations, group...)
+	}
+	var langs []string
+	if lang != nil {
+		langs = requestLanguages(ctx, lang)
+	}
+	ret := []*model.Translation{}
+	for _, ent := range translations {
+		if ent.RecordID.Val == "" {
+			if v, ok := values[ent.FieldName.Val]; !ok || v.Val == "" || v.Val != ent.FieldValue.Val {
+				continue
+			}
+		}
+		if len(langs) > 0 {
+			found := false
+			for _, l := range langs {
+				if languageMatch(l, ent.Language.Val) > 0 {
+					found = true
+					break
+				}
+			}
+			if !found {
+				continue
+			}
+		}
+		ret = append(ret, ent)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].ID < ret[j].ID })
+	return ret, nil
+}
+
+func translatedString(v tt.String, err error) (*string, error) {
+	if !v.Valid {
+		return nil, err
+	}
+	return &v.Val, err
+}
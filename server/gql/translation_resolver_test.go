@@ -0,0 +1,237 @@
+package gql
+
+import (
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/interline-io/transitland-lib/gtfs"
+	"github.com/interline-io/transitland-lib/internal/testconfig"
+	"github.com/interline-io/transitland-lib/server/model"
+	"github.com/interline-io/transitland-lib/testdata"
+	"github.com/interline-io/transitland-lib/tt"
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+func TestTranslationResolver(t *testing.T) {
+	c, _ := newTestClient(t)
+	testcases := []testcase{
+		{
+			name:         "untranslated stop name",
+			query:        `query { stops(where:{feed_onestop_id:"BART", stop_id:"MONT"}) { stop_name(lang:"fr") translations { language } } }`,
+			selector:     "stops.0.stop_name",
+			selectExpect: []string{"Montgomery St."},
+		},
+		{
+			name:         "no translations",
+			query:        `query { stops(where:{feed_onestop_id:"BART", stop_id:"MONT"}) { translations { language } } }`,
+			selector:     "stops.0.translations.#.language",
+			selectExpect: []string{},
+		},
+		{
+			name:         "untranslated trip headsign",
+			query:        `query { trips(where:{feed_onestop_id:"BART", trip_id:"3850526WKDY"}) { trip_headsign(lang:"es") stop_times(limit:1) { stop_headsign(lang:"es") } } }`,
+			selector:     "trips.0.trip_headsign",
+			selectExpect: []string{"Antioch"},
+		},
+	}
+	queryTestcases(t, c, testcases)
+
+	t.Run("accept-language header", func(t *testing.T) {
+		var resp map[string]any
+		c.MustPost(`query { routes(where:{feed_onestop_id:"BART", route_id:"01"}) { route_long_name agency { agency_name } } }`, &resp, client.AddHeader("Accept-Language", "fr-CA, fr;q=0.9"))
+		assert.Equal(t, "Antioch - SFIA/Millbrae", gjson.Get(toJson(resp), "routes.0.route_long_name").String())
+		assert.Equal(t, "Bay Area Rapid Transit", gjson.Get(toJson(resp), "routes.0.agency.agency_name").String())
+	})
+}
+
+func TestTranslationResolver_Feed(t *testing.T) {
+	testconfig.ConfigTxRollback(t, testconfig.Options{AllowAll: true}, func(cfg model.Config) {
+		fv := testconfig.ImportFeed(t, cfg, "test", testdata.Path("server/gtfs/translations"))
+		c := newPermTestClientFromConfig(cfg, "test")
+		vars := hw{"sha1": fv.SHA1}
+		testcases := []testcase{
+			{
+				name:         "stop name by record_id",
+				query:        `query($sha1:String!) { feed_versions(where:{sha1:$sha1}) { stops(where:{stop_id:"central"}) { stop_name(lang:"fr") } } }`,
+				vars:         vars,
+				selector:     "feed_versions.0.stops.0.stop_name",
+				selectExpect: []string{"Gare Centrale"},
+			},
+			{
+				name:         "stop name by region",
+				query:        `query($sha1:String!) { feed_versions(where:{sha1:$sha1}) { stops(where:{stop_id:"central"}) { stop_name(lang:"fr-CA") } } }`,
+				vars:         vars,
+				selector:     "feed_versions.0.stops.0.stop_name",
+				selectExpect: []string{"Gare Centrale QC"},
+			},
+			{
+				name:         "stop name primary language fallback",
+				query:        `query($sha1:String!) { feed_versions(where:{sha1:$sha1}) { stops(where:{stop_id:"central"}) { stop_name(lang:"fr-BE") } } }`,
+				vars:         vars,
+				selector:     "feed_versions.0.stops.0.stop_name",
+				selectExpect: []string{"Gare Centrale"},
+			},
+			{
+				name:         "stop name by field_value",
+				query:        `query($sha1:String!) { feed_versions(where:{sha1:$sha1}) { stops(where:{stop_id:"harbor"}) { stop_name(lang:"fr") } } }`,
+				vars:         vars,
+				selector:     "feed_versions.0.stops.0.stop_name",
+				selectExpect: []string{"Port"},
+			},
+			{
+				name:         "stop name language preference",
+				query:        `query($sha1:String!) { feed_versions(where:{sha1:$sha1}) { stops(where:{stop_id:"central"}) { stop_name(lang:"de,fr") } } }`,
+				vars:         vars,
+				selector:     "feed_versions.0.stops.0.stop_name",
+				selectExpect: []string{"Gare Centrale"},
+			},
+			{
+				name:         "stop name untranslated language",
+				query:        `query($sha1:String!) { feed_versions(where:{sha1:$sha1}) { stops(where:{stop_id:"central"}) { stop_name(lang:"de") } } }`,
+				vars:         vars,
+				selector:     "feed_versions.0.stops.0.stop_name",
+				selectExpect: []string{"Central Station"},
+			},
+			{
+				name:         "stop translations",
+				query:        `query($sha1:String!) { feed_versions(where:{sha1:$sha1}) { stops(where:{stop_id:"central"}) { translations { language translation } } } }`,
+				vars:         vars,
+				selector:     "feed_versions.0.stops.0.translations.#.translation",
+				selectExpect: []string{"Gare Centrale", "Gare Centrale QC"},
+			},
+			{
+				name:         "stop translations by field_value",
+				query:        `query($sha1:String!) { feed_versions(where:{sha1:$sha1}) { stops(where:{stop_id:"harbor"}) { translations { language translation } } } }`,
+				vars:         vars,
+				selector:     "feed_versions.0.stops.0.translations.#.translation",
+				selectExpect: []string{"Port"},
+			},
+			{
+				name:         "stop translations filtered by lang",
+				query:        `query($sha1:String!) { feed_versions(where:{sha1:$sha1}) { stops(where:{stop_id:"central"}) { translations(lang:"fr-CA") { language } } } }`,
+				vars:         vars,
+				selector:     "feed_versions.0.stops.0.translations.#.language",
+				selectExpect: []string{"fr", "fr-CA"},
+			},
+			{
+				name:         "field_value translations do not apply to other tables",
+				query:        `query($sha1:String!) { feed_versions(where:{sha1:$sha1}) { trips(where:{trip_id:"t1"}) { trip_headsign(lang:"fr") } } }`,
+				vars:         vars,
+				selector:     "feed_versions.0.trips.0.trip_headsign",
+				selectExpect: []string{"Harbor"},
+			},
+			{
+				name:         "trip headsign",
+				query:        `query($sha1:String!) { feed_versions(where:{sha1:$sha1}) { trips(where:{trip_id:"t1"}) { trip_headsign(lang:"es") } } }`,
+				vars:         vars,
+				selector:     "feed_versions.0.trips.0.trip_headsign",
+				selectExpect: []string{"Puerto"},
+			},
+			{
+				name:         "stop headsign by record_sub_id",
+				query:        `query($sha1:String!) { feed_versions(where:{sha1:$sha1}) { trips(where:{trip_id:"t1"}) { stop_times { stop_headsign(lang:"es") } } } }`,
+				vars:         vars,
+				selector:     "feed_versions.0.trips.0.stop_times.#.stop_headsign",
+				selectExpect: []string{"Hacia el Puerto"},
+			},
+			{
+				name:  "route and agency names",
+				query: `query($sha1:String!) { feed_versions(where:{sha1:$sha1}) { routes(where:{route_id:"1"}) { route_long_name(lang:"fr") agency { agency_name(lang:"fr") } } } }`,
+				vars:  vars,
+				sel: []testcaseSelector{
+					{selector: "feed_versions.0.routes.0.route_long_name", expect: []string{"Ligne du Port"}},
+					{selector: "feed_versions.0.routes.0.agency.agency_name", expect: []string{"Transports du Port"}},
+				},
+			},
+		}
+		queryTestcases(t, c, testcases)
+
+		t.Run("accept-language header", func(t *testing.T) {
+			var resp map[string]any
+			c.MustPost(
+				`query($sha1:String!) { feed_versions(where:{sha1:$sha1}) { routes(where:{route_id:"1"}) { route_long_name agency { agency_name } } stops(where:{stop_id:"central"}) { stop_name } } }`,
+				&resp,
+				client.Var("sha1", fv.SHA1),
+				client.AddHeader("Accept-Language", "fr-CA, fr;q=0.9"),
+			)
+			jj := toJson(resp)
+			assert.Equal(t, "Ligne du Port", gjson.Get(jj, "feed_versions.0.routes.0.route_long_name").String())
+			assert.Equal(t, "Transports du Port", gjson.Get(jj, "feed_versions.0.routes.0.agency.agency_name").String())
+			assert.Equal(t, "Gare Centrale QC", gjson.Get(jj, "feed_versions.0.stops.0.stop_name").String())
+		})
+		t.Run("lang argument overrides accept-language header", func(t *testing.T) {
+			var resp map[string]any
+			c.MustPost(
+				`query($sha1:String!) { feed_versions(where:{sha1:$sha1}) { stops(where:{stop_id:"central"}) { translated: stop_name(lang:"fr") original: stop_name(lang:"") } } }`,
+				&resp,
+				client.Var("sha1", fv.SHA1),
+				client.AddHeader("Accept-Language", "fr-CA"),
+			)
+			jj := toJson(resp)
+			assert.Equal(t, "Gare Centrale", gjson.Get(jj, "feed_versions.0.stops.0.translated").String())
+			assert.Equal(t, "Central Station", gjson.Get(jj, "feed_versions.0.stops.0.original").String())
+		})
+	})
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tcs := []struct {
+		header string
+		expect []string
+	}{
+		{"", nil},
+		{"fr", []string{"fr"}},
+		{"fr-CA,fr;q=0.9,en;q=0.8", []string{"fr-CA", "fr", "en"}},
+		{"en;q=0.5, es", []string{"es", "en"}},
+		{"*, de;q=0, nl", []string{"nl"}},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.header, func(t *testing.T) {
+			assert.Equal(t, tc.expect, parseAcceptLanguage(tc.header))
+		})
+	}
+}
+
+func TestSelectTranslation(t *testing.T) {
+	mk := func(field string, lang string, translation string, recordID string, recordSubID string, fieldValue string) *model.Translation {
+		return &model.Translation{Translation: gtfs.Translation{
+			TableNameValue: tt.NewString("stops"),
+			FieldName:      tt.NewString(field),
+			Language:       tt.NewLanguage(lang),
+			Translation:    tt.NewString(translation),
+			RecordID:       tt.NewString(recordID),
+			RecordSubID:    tt.NewString(recordSubID),
+			FieldValue:     tt.NewString(fieldValue),
+		}}
+	}
+	translations := []*model.Translation{
+		mk("stop_name", "fr", "Gare Centrale (valeur)", "", "", "Central Station"),
+		mk("stop_name", "fr", "Gare Centrale", "central", "", ""),
+		mk("stop_name", "fr-CA", "Gare Centrale QC", "central", "", ""),
+		mk("stop_name", "es", "Estación Central", "", "", "Central Station"),
+		mk("stop_desc", "fr", "Description", "central", "", ""),
+	}
+	tcs := []struct {
+		name   string
+		langs  []string
+		value  string
+		expect string
+		ok     bool
+	}{
+		{"by record_id over field_value", []string{"fr"}, "Central Station", "Gare Centrale", true},
+		{"exact region", []string{"fr-CA"}, "Central Station", "Gare Centrale QC", true},
+		{"primary language fallback", []string{"fr-BE"}, "Central Station", "Gare Centrale", true},
+		{"by field_value", []string{"es"}, "Central Station", "Estación Central", true},
+		{"field_value must match", []string{"es"}, "Other Station", "", false},
+		{"language preference", []string{"de", "es", "fr"}, "Central Station", "Estación Central", true},
+		{"no match", []string{"de"}, "Central Station", "", false},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			v, ok := selectTranslation(translations, tc.langs, "stop_name", "", tc.value)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.expect, v)
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/interline-io/transitland-lib/server/model"
+	"github.com/interline-io/transitland-lib/tt"
 )
 
 // TRIP
@@ -90,3 +91,18 @@ func (r *tripResolver) Alerts(ctx context.Context, obj *model.Trip, active *bool
 	rtAlerts := model.ForContext(ctx).RTFinder.FindAlertsForTrip(ctx, obj, resolverCheckLimit(limit), active)
 	return rtAlerts, nil
 }
+
+func (r *tripResolver) TripHeadsign(ctx context.Context, obj *model.Trip, lang *string) (*string, error) {
+	return translatedString(translateField(ctx, lang, obj.FeedVersionID, "trips", obj.TripID.Val, "", "trip_headsign", obj.TripHeadsign))
+}
+
+func (r *tripResolver) TripShortName(ctx context.Context, obj *model.Trip, lang *string) (*string, error) {
+	return translatedString(translateField(ctx, lang, obj.FeedVersionID, "trips", obj.TripID.Val, "", "trip_short_name", obj.TripShortName))
+}
+
+func (r *tripResolver) Translations(ctx context.Context, obj *model.Trip, lang *string) ([]*model.Translation, error) {
+	return recordTranslations(ctx, lang, obj.FeedVersionID, "trips", obj.TripID.Val, map[string]tt.String{
+		"trip_headsign":   obj.TripHeadsign,
+		"trip_short_name": obj.TripShortName,
+	})
+}
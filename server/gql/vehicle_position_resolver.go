@@ -96,7 +96,11 @@ func (r *queryResolver) VehiclePositions(ctx context.Context, limit *int, where
 	if err != nil {
 		return nil, err
 	}
+	return findVehiclePositions(ctx, agencies, limit, where), nil
+}
 
+// findVehiclePositions returns the vehicles of the agencies within the search area.
+func findVehiclePositions(ctx context.Context, agencies []*model.Agency, limit *int, where model.VehiclePositionFilter) []*model.VehiclePosition {
 	// Each agency is asked without a limit: which vehicles survive one is
 	// decided across the whole viewport rather than per agency.
 	rtFinder := model.ForContext(ctx).RTFinder
@@ -104,7 +108,7 @@ func (r *queryResolver) VehiclePositions(ctx context.Context, limit *int, where
 	for _, agency := range agencies {
 		found = append(found, rtFinder.FindVehiclePositionsForAgency(ctx, agency, nil, &where)...)
 	}
-	return model.OrderVehiclePositions(found, vehiclePositionLimit(limit)), nil
+	return model.OrderVehiclePositions(found, vehiclePositionLimit(limit))
 }
 
 // vehiclePositionAgencies resolves the search area to the agencies whose
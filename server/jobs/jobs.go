@@ -226,8 +226,8 @@ type PeriodicQueue interface {
 //	    backend.Stop(context.Background()) // hard fallback on timeout
 //	}
 //
-// Implementations: LocalBackend (in-process), RiverBackend (Postgres),
-// ArgoBackend (k8s workflows), RedisBackend (fire-and-forget — Shutdown is
+// Implementations: LocalBackend (in-process), PostgresBackend (durable,
+// tl_jobs table), RiverBackend (Postgres), ArgoBackend (k8s workflows), RedisBackend (fire-and-forget — Shutdown is
 // a no-op since there's nothing to drain on the producer side). A Router is
 // itself a Backend that aggregates other Backends and dispatches Queue(name)
 // to whichever Backend hosts the named queue.
@@ -0,0 +1,793 @@
+package jobs
+
+import (
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/interline-io/log"
+	"github.com/interline-io/transitland-lib/server/jobs"
+	"github.com/interline-io/transitland-lib/tldb"
+	"github.com/jmoiron/sqlx"
+	"github.com/robfig/cron/v3"
+)
+
+type QueueOpts struct {
+	// Workers is the number of goroutines draining the queue. Defaults to 1.
+	Workers int
+	// MaxAttempts is the number of times a failing job is run before it is
+	// marked failed. Defaults to 1 (no retries).
+	MaxAttempts int
+}
+
+const (
+	defaultListLimit    = 100
+	defaultPollInterval = 1 * time.Second
+	defaultStaleAfter   = 5 * time.Minute
+	defaultMinBackoff   = 10 * time.Second
+	defaultMaxBackoff   = 1 * time.Hour
+	watchBufferSize     = 256
+)
+
+var (
+	_ jobs.Backend        = (*PostgresBackend)(nil)
+	_ jobs.Queue          = (*pgQueue)(nil)
+	_ jobs.StatusQueue    = (*pgQueue)(nil)
+	_ jobs.DeletableQueue = (*pgQueue)(nil)
+	_ jobs.PeriodicQueue  = (*pgQueue)(nil)
+)
+
+// PostgresBackend is a durable backend that stores jobs in the tl_jobs table.
+// Workers claim jobs with SELECT ... FOR UPDATE SKIP LOCKED, so any number of
+// processes may share the same queues; job state survives restarts.
+//
+// Jobs left running by a process that exited without draining are rescued
+// once their heartbeat is older than StaleAfter.
+type PostgresBackend struct {
+	// PollInterval is how often idle workers, watchers, heartbeats and
+	// periodic schedules check the database. Defaults to 1s.
+	PollInterval time.Duration
+	// StaleAfter is how long a running job may go without a heartbeat
+	// before it is requeued. Defaults to 5m.
+	StaleAfter time.Duration
+	// MinBackoff and MaxBackoff bound the exponential delay between retries.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	db      tldb.Ext
+	runner  *jobs.Runner
+	queues  map[string]*pgQueue
+	policy  jobs.AccessPolicy
+	startMu sync.Mutex
+	started bool
+	cancel  context.CancelFunc
+	runDone chan struct{}
+	drain   chan struct{}
+	drained bool
+}
+
+// NewPostgresBackend constructs a backend using an open Postgres connection.
+// nil policy → CreatorOrAdmin.
+func NewPostgresBackend(db tldb.Ext, runner *jobs.Runner, queues map[string]QueueOpts, policy jobs.AccessPolicy) *PostgresBackend {
+	if policy == nil {
+		policy = jobs.CreatorOrAdmin{}
+	}
+	b := &PostgresBackend{
+		PollInterval: defaultPollInterval,
+		StaleAfter:   defaultStaleAfter,
+		MinBackoff:   defaultMinBackoff,
+		MaxBackoff:   defaultMaxBackoff,
+		db:           db,
+		runner:       runner,
+		queues:       map[string]*pgQueue{},
+		policy:       policy,
+	}
+	for name, opts := range queues {
+		b.queues[name] = newPgQueue(b, name, opts)
+	}
+	return b
+}
+
+func (b *PostgresBackend) Queue(name string) (jobs.Queue, error) {
+	q, ok := b.queues[name]
+	if !ok {
+		return nil, jobs.ErrUnknownQueue
+	}
+	return q, nil
+}
+
+func (b *PostgresBackend) Run(ctx context.Context) error {
+	b.startMu.Lock()
+	if b.started {
+		b.startMu.Unlock()
+		return errors.New("already running")
+	}
+	b.started = true
+	ctx, b.cancel = context.WithCancel(ctx)
+	b.runDone = make(chan struct{})
+	b.drain = make(chan struct{})
+	b.drained = false
+	drain := b.drain
+	b.startMu.Unlock()
+
+	defer close(b.runDone)
+	var wg sync.WaitGroup
+	for _, q := range b.queues {
+		q := q
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.run(ctx, drain)
+		}()
+	}
+	select {
+	case <-ctx.Done():
+	case <-drain:
+	}
+	wg.Wait()
+	for _, q := range b.queues {
+		q.shutdown()
+	}
+	return nil
+}
+
+// Shutdown stops workers from claiming new jobs and blocks until in-flight
+// jobs finish or ctx fires. Idempotent. Returns nil if Run was never called.
+func (b *PostgresBackend) Shutdown(ctx context.Context) error {
+	b.startMu.Lock()
+	done := b.runDone
+	if done == nil {
+		b.startMu.Unlock()
+		return nil
+	}
+	if !b.drained && b.drain != nil {
+		close(b.drain)
+		b.drained = true
+	}
+	b.startMu.Unlock()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stop cancels in-flight workers. Interrupted jobs are returned to the queue.
+func (b *PostgresBackend) Stop(ctx context.Context) error {
+	b.startMu.Lock()
+	if !b.started {
+		b.startMu.Unlock()
+		return errors.New("not running")
+	}
+	cancel := b.cancel
+	b.started = false
+	b.startMu.Unlock()
+	cancel()
+	return nil
+}
+
+// Wait blocks until Run returns or ctx fires. Does not trigger shutdown.
+// Returns nil if Run was never called.
+func (b *PostgresBackend) Wait(ctx context.Context) error {
+	b.startMu.Lock()
+	done := b.runDone
+	b.startMu.Unlock()
+	if done == nil {
+		return nil
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// backoff returns the delay before the next attempt: MinBackoff doubled for
+// each previous attempt, capped at MaxBackoff.
+func (b *PostgresBackend) backoff(attempt int) time.Duration {
+	d := b.MinBackoff
+	for i := 1; i < attempt && d < b.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, b.MaxBackoff)
+}
+
+type pgQueue struct {
+	b           *PostgresBackend
+	name        string
+	workers     int
+	maxAttempts int
+	// wake is signalled on local Submit so idle workers don't wait a full poll.
+	wake     chan struct{}
+	done     chan struct{}
+	doneOnce sync.Once
+
+	periodicMu sync.Mutex
+	periodics  map[string]context.CancelFunc
+}
+
+func newPgQueue(b *PostgresBackend, name string, opts QueueOpts) *pgQueue {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+	return &pgQueue{
+		b:           b,
+		name:        name,
+		workers:     workers,
+		maxAttempts: maxAttempts,
+		wake:        make(chan struct{}, 1),
+		done:        make(chan struct{}),
+		periodics:   map[string]context.CancelFunc{},
+	}
+}
+
+func (q *pgQueue) run(ctx context.Context, drain chan struct{}) {
+	var wg sync.WaitGroup
+	for i := 0; i < q.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-drain:
+					return
+				case <-ctx.Done():
+					return
+				default:
+				}
+				row, err := q.claim(ctx)
+				if err != nil && ctx.Err() == nil {
+					log.For(ctx).Error().Err(err).Str("queue", q.name).Msg("failed to claim job")
+				}
+				if row != nil {
+					q.execute(ctx, row)
+					continue
+				}
+				select {
+				case <-drain:
+					return
+				case <-ctx.Done():
+					return
+				case <-q.wake:
+				case <-time.After(q.b.PollInterval):
+					q.rescue(ctx)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (q *pgQueue) shutdown() {
+	q.doneOnce.Do(func() { close(q.done) })
+}
+
+func (q *pgQueue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// jobRow is a row in tl_jobs.
+type jobRow struct {
+	ID              string     `db:"id"`
+	Queue           string     `db:"queue"`
+	Kind            string     `db:"kind"`
+	Args            []byte     `db:"args"`
+	UserID          string     `db:"user_id"`
+	Deadline        *time.Time `db:"deadline"`
+	IsUnique        bool       `db:"is_unique"`
+	State           string     `db:"state"`
+	Attempt         int        `db:"attempt"`
+	MaxAttempts     int        `db:"max_attempts"`
+	Error           string     `db:"error"`
+	CancelRequested bool       `db:"cancel_requested"`
+	SubmittedAt     time.Time  `db:"submitted_at"`
+	StartedAt       *time.Time `db:"started_at"`
+	FinishedAt      *time.Time `db:"finished_at"`
+}
+
+const jobColumns = `id, queue, kind, args, user_id, deadline, is_unique, state, attempt, max_attempts, error, cancel_requested, submitted_at, started_at, finished_at`
+
+func (r *jobRow) job() jobs.Job {
+	job := jobs.Job{
+		ID:   r.ID,
+		Kind: r.Kind,
+		Opts: jobs.JobOpts{UserID: r.UserID, Unique: r.IsUnique},
+	}
+	if len(r.Args) > 0 {
+		var args jobs.Args
+		if err := json.Unmarshal(r.Args, &args); err == nil && len(args) > 0 {
+			job.Args = args
+		}
+	}
+	if r.Deadline != nil {
+		job.Opts.Deadline = r.Deadline.UTC()
+	}
+	return job
+}
+
+func (r *jobRow) status() jobs.JobStatus {
+	st := jobs.JobStatus{
+		State:       jobs.JobState(r.State),
+		Job:         r.job(),
+		SubmittedAt: r.SubmittedAt.UTC(),
+		StartedAt:   utcPtr(r.StartedAt),
+		FinishedAt:  utcPtr(r.FinishedAt),
+		Error:       r.Error,
+		Attempt:     r.Attempt,
+	}
+	return st
+}
+
+func utcPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	v := t.UTC()
+	return &v
+}
+
+func (q *pgQueue) Submit(ctx context.Context, job jobs.Job) (jobs.JobStatus, error) {
+	if err := q.b.policy.CanSubmit(ctx, job); err != nil {
+		return jobs.JobStatus{}, err
+	}
+	job.ID = uuid.NewString()
+	args, err := json.Marshal(job.Args)
+	if err != nil {
+		return jobs.JobStatus{}, err
+	}
+	var deadline *time.Time
+	if !job.Opts.Deadline.IsZero() {
+		deadline = &job.Opts.Deadline
+	}
+	var uniqueKey *string
+	if job.Opts.Unique {
+		key, err := dedupKey(job.Kind, job.Args)
+		if err != nil {
+			return jobs.JobStatus{}, err
+		}
+		uniqueKey = &key
+	}
+	row := jobRow{}
+	err = sqlx.GetContext(ctx, q.b.db, &row, `
+		INSERT INTO tl_jobs (id, queue, kind, args, user_id, deadline, is_unique, unique_key, state, max_attempts)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (queue, unique_key) WHERE unique_key IS NOT NULL AND state IN ('queued', 'running') DO NOTHING
+		RETURNING `+jobColumns,
+		job.ID, q.name, job.Kind, args, job.Opts.UserID, deadline, job.Opts.Unique, uniqueKey, string(jobs.JobStateQueued), q.maxAttempts,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		// A matching unique job is already queued or running; record this
+		// submission as a cancelled duplicate.
+		err = sqlx.GetContext(ctx, q.b.db, &row, `
+			INSERT INTO tl_jobs (id, queue, kind, args, user_id, deadline, is_unique, state, max_attempts, error, finished_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, 'duplicate', now())
+			RETURNING `+jobColumns,
+			job.ID, q.name, job.Kind, args, job.Opts.UserID, deadline, job.Opts.Unique, string(jobs.JobStateCancelled), q.maxAttempts,
+		)
+	}
+	if err != nil {
+		return jobs.JobStatus{}, err
+	}
+	q.notify()
+	return row.status(), nil
+}
+
+func dedupKey(kind string, args jobs.Args) (string, error) {
+	bytes, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum(bytes)
+	return kind + ":" + hex.EncodeToString(sum[:]), nil
+}
+
+func (q *pgQueue) SubmitMany(ctx context.Context, in []jobs.Job) ([]jobs.JobStatus, error) {
+	out := make([]jobs.JobStatus, 0, len(in))
+	for _, job := range in {
+		st, err := q.Submit(ctx, job)
+		if err != nil {
+			return out, err
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+func (q *pgQueue) getRow(ctx context.Context, jobId string) (*jobRow, error) {
+	row := jobRow{}
+	err := sqlx.GetContext(ctx, q.b.db, &row, `SELECT `+jobColumns+` FROM tl_jobs WHERE id = $1 AND queue = $2`, jobId, q.name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, jobs.ErrJobNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+func (q *pgQueue) Status(ctx context.Context, jobId string) (jobs.JobStatus, error) {
+	row, err := q.getRow(ctx, jobId)
+	if err != nil {
+		return jobs.JobStatus{}, err
+	}
+	st := row.status()
+	if err := q.b.policy.CanRead(ctx, st); err != nil {
+		return jobs.JobStatus{}, err
+	}
+	return st, nil
+}
+
+// Watch polls the job every PollInterval and emits an event for each state
+// or attempt change, closing after the terminal event or when ctx is done.
+func (q *pgQueue) Watch(ctx context.Context, jobId string) (<-chan jobs.JobEvent, error) {
+	st, err := q.Status(ctx, jobId)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan jobs.JobEvent, watchBufferSize)
+	event := func(st jobs.JobStatus) jobs.JobEvent {
+		return jobs.JobEvent{JobID: jobId, State: st.State, Attempt: st.Attempt, Message: st.Error, Time: time.Now().UTC()}
+	}
+	if st.State.Terminal() {
+		ch <- event(st)
+		close(ch)
+		return ch, nil
+	}
+	go func() {
+		defer close(ch)
+		last := st
+		ticker := time.NewTicker(q.b.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			row, err := q.getRow(ctx, jobId)
+			if errors.Is(err, jobs.ErrJobNotFound) {
+				return
+			} else if err != nil {
+				continue
+			}
+			cur := row.status()
+			if cur.State == last.State && cur.Attempt == last.Attempt {
+				continue
+			}
+			last = cur
+			select {
+			case ch <- event(cur):
+			default:
+			}
+			if cur.State.Terminal() {
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func (q *pgQueue) List(ctx context.Context, opts jobs.ListOptions) (jobs.ListResult, error) {
+	scoped, err := q.b.policy.ScopeList(ctx, opts)
+	if err != nil {
+		return jobs.ListResult{}, err
+	}
+	opts = scoped
+	where := []string{"queue = $1"}
+	args := []any{q.name}
+	if opts.UserID != "" {
+		args = append(args, opts.UserID)
+		where = append(where, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+	if opts.Kind != "" {
+		args = append(args, opts.Kind)
+		where = append(where, fmt.Sprintf("kind = $%d", len(args)))
+	}
+	if len(opts.States) > 0 {
+		var states []string
+		for _, s := range opts.States {
+			states = append(states, string(s))
+		}
+		args = append(args, states)
+		where = append(where, fmt.Sprintf("state = ANY($%d)", len(args)))
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	offset := max(opts.Offset, 0)
+	args = append(args, limit, offset)
+	var rows []jobRow
+	if err := sqlx.SelectContext(ctx, q.b.db, &rows, fmt.Sprintf(
+		`SELECT %s FROM tl_jobs WHERE %s ORDER BY submitted_at DESC, id ASC LIMIT $%d OFFSET $%d`,
+		jobColumns, strings.Join(where, " AND "), len(args)-1, len(args),
+	), args...); err != nil {
+		return jobs.ListResult{}, err
+	}
+	ret := jobs.ListResult{}
+	for _, row := range rows {
+		ret.Jobs = append(ret.Jobs, row.status())
+	}
+	return ret, nil
+}
+
+// Cancel cancels a queued job immediately. A running job is flagged, and its
+// worker context is cancelled at the next heartbeat by whichever process runs it.
+func (q *pgQueue) Cancel(ctx context.Context, jobId string) error {
+	st, err := q.Status(ctx, jobId)
+	if err != nil {
+		return err
+	}
+	if st.State.Terminal() {
+		return nil
+	}
+	res, err := q.b.db.ExecContext(ctx, `
+		UPDATE tl_jobs SET state = $2, error = 'cancelled', finished_at = now()
+		WHERE id = $1 AND state = $3`,
+		jobId, string(jobs.JobStateCancelled), string(jobs.JobStateQueued),
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+	_, err = q.b.db.ExecContext(ctx, `UPDATE tl_jobs SET cancel_requested = true WHERE id = $1 AND state = $2`, jobId, string(jobs.JobStateRunning))
+	return err
+}
+
+// Delete removes a terminal job's record. Returns ErrJobNotFound if unknown,
+// ErrJobNotTerminal if the job is still queued or running (cancel it first),
+// or a policy error if the caller may not read the job. Artifacts are stored
+// separately and are left untouched.
+func (q *pgQueue) Delete(ctx context.Context, jobId string) error {
+	st, err := q.Status(ctx, jobId)
+	if err != nil {
+		return err
+	}
+	if !st.State.Terminal() {
+		return jobs.ErrJobNotTerminal
+	}
+	_, err = q.b.db.ExecContext(ctx, `DELETE FROM tl_jobs WHERE id = $1 AND queue = $2`, jobId, q.name)
+	return err
+}
+
+// claim marks the next due job as running and returns it, or nil if none is due.
+func (q *pgQueue) claim(ctx context.Context) (*jobRow, error) {
+	row := jobRow{}
+	err := sqlx.GetContext(ctx, q.b.db, &row, `
+		UPDATE tl_jobs SET state = $2, attempt = attempt + 1, started_at = coalesce(started_at, now()), heartbeat_at = now()
+		WHERE id = (
+			SELECT id FROM tl_jobs
+			WHERE queue = $1 AND state = $3 AND scheduled_at <= now()
+			ORDER BY scheduled_at, submitted_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+jobColumns,
+		q.name, string(jobs.JobStateRunning), string(jobs.JobStateQueued),
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// rescue requeues running jobs whose heartbeat is older than StaleAfter,
+// e.g. after a process was killed mid-run.
+func (q *pgQueue) rescue(ctx context.Context) {
+	_, err := q.b.db.ExecContext(ctx, `
+		UPDATE tl_jobs
+		SET state = CASE WHEN attempt < max_attempts THEN $3 ELSE $4 END,
+			error = 'worker lost',
+			finished_at = CASE WHEN attempt < max_attempts THEN NULL ELSE now() END
+		WHERE queue = $1 AND state = $2 AND heartbeat_at < now() - make_interval(secs => $5)`,
+		q.name, string(jobs.JobStateRunning), string(jobs.JobStateQueued), string(jobs.JobStateFailed), q.b.StaleAfter.Seconds(),
+	)
+	if err != nil && ctx.Err() == nil {
+		log.For(ctx).Error().Err(err).Str("queue", q.name).Msg("failed to rescue stale jobs")
+	}
+}
+
+// finish moves a running job to its next state.
+func (q *pgQueue) finish(row *jobRow, state jobs.JobState, errMsg string) {
+	// The backend context may already be cancelled on Stop; always record the outcome.
+	ctx := context.Background()
+	var err error
+	if state.Terminal() {
+		_, err = q.b.db.ExecContext(ctx, `
+			UPDATE tl_jobs SET state = $2, error = $3, finished_at = now()
+			WHERE id = $1`,
+			row.ID, string(state), errMsg,
+		)
+	} else {
+		_, err = q.b.db.ExecContext(ctx, `
+			UPDATE tl_jobs SET state = $2, error = $3, scheduled_at = now() + make_interval(secs => $4)
+			WHERE id = $1`,
+			row.ID, string(state), errMsg, q.b.backoff(row.Attempt).Seconds(),
+		)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("job_id", row.ID).Str("state", string(state)).Msg("failed to update job state")
+	}
+}
+
+func (q *pgQueue) execute(ctx context.Context, row *jobRow) {
+	job := row.job()
+	if row.CancelRequested {
+		q.finish(row, jobs.JobStateCancelled, "cancelled")
+		return
+	}
+	if !job.Opts.Deadline.IsZero() && time.Now().UTC().After(job.Opts.Deadline) {
+		log.Trace().Time("job_deadline", job.Opts.Deadline).Msg("job skipped - deadline in past")
+		q.finish(row, jobs.JobStateCancelled, "deadline in past")
+		return
+	}
+
+	// Heartbeat keeps the job from being rescued and picks up cancel requests
+	// made through any process.
+	runCtx, runCancel := context.WithCancel(ctx)
+	defer runCancel()
+	var cancelled bool
+	var cancelMu sync.Mutex
+	hbDone := make(chan struct{})
+	go func() {
+		defer close(hbDone)
+		ticker := time.NewTicker(q.b.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-runCtx.Done():
+				return
+			case <-ticker.C:
+			}
+			var cancelRequested bool
+			if err := q.b.db.QueryRowContext(runCtx, `UPDATE tl_jobs SET heartbeat_at = now() WHERE id = $1 RETURNING cancel_requested`, row.ID).Scan(&cancelRequested); err != nil {
+				continue
+			}
+			if cancelRequested {
+				cancelMu.Lock()
+				cancelled = true
+				cancelMu.Unlock()
+				runCancel()
+				return
+			}
+		}
+	}()
+	runErr := q.b.runner.Run(runCtx, job)
+	runCancel()
+	<-hbDone
+
+	cancelMu.Lock()
+	wasCancelled := cancelled
+	cancelMu.Unlock()
+	if !wasCancelled {
+		// Check once more; the cancel may have arrived since the last heartbeat.
+		_ = q.b.db.QueryRowContext(context.Background(), `SELECT cancel_requested FROM tl_jobs WHERE id = $1`, row.ID).Scan(&wasCancelled)
+	}
+	switch {
+	case wasCancelled:
+		q.finish(row, jobs.JobStateCancelled, "cancelled")
+	case ctx.Err() != nil:
+		// Backend stopped mid-run; return the job to the queue without
+		// counting the interrupted attempt.
+		if _, err := q.b.db.ExecContext(context.Background(), `UPDATE tl_jobs SET state = $2, attempt = attempt - 1 WHERE id = $1`, row.ID, string(jobs.JobStateQueued)); err != nil {
+			log.Error().Err(err).Str("job_id", row.ID).Msg("failed to requeue interrupted job")
+		}
+	case runErr != nil && row.Attempt < row.MaxAttempts:
+		q.finish(row, jobs.JobStateQueued, runErr.Error())
+	case runErr != nil:
+		q.finish(row, jobs.JobStateFailed, runErr.Error())
+	default:
+		q.finish(row, jobs.JobStateSucceeded, "")
+	}
+}
+
+// AddPeriodic registers a recurring job. Every process that registers the same
+// job (Kind, Args and schedule) shares one schedule slot in tl_job_periodics,
+// so each run is submitted once regardless of the number of replicas.
+func (q *pgQueue) AddPeriodic(ctx context.Context, jobFunc func() jobs.Job, period time.Duration, cronTab string) (string, error) {
+	if cronTab == "" && period <= 0 {
+		return "", errors.New("AddPeriodic: either cronTab or positive period required")
+	}
+	var schedule cron.Schedule
+	if cronTab != "" {
+		s, err := cron.ParseStandard(cronTab)
+		if err != nil {
+			return "", err
+		}
+		schedule = s
+	}
+	next := func(t time.Time) time.Time {
+		if schedule != nil {
+			return schedule.Next(t)
+		}
+		return t.Add(period)
+	}
+	sample := jobFunc()
+	key, err := dedupKey(sample.Kind, sample.Args)
+	if err != nil {
+		return "", err
+	}
+	key = fmt.Sprintf("%s:%s:%s:%s", q.name, key, cronTab, period)
+	if _, err := q.b.db.ExecContext(ctx, `
+		INSERT INTO tl_job_periodics (periodic_key, queue, next_run_at) VALUES ($1, $2, $3)
+		ON CONFLICT (periodic_key) DO NOTHING`,
+		key, q.name, next(time.Now().UTC()),
+	); err != nil {
+		return "", err
+	}
+	id := uuid.NewString()
+	pctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-q.done:
+			cancel()
+		case <-pctx.Done():
+		}
+		q.periodicMu.Lock()
+		delete(q.periodics, id)
+		q.periodicMu.Unlock()
+	}()
+	q.periodicMu.Lock()
+	q.periodics[id] = cancel
+	q.periodicMu.Unlock()
+	go q.runPeriodic(pctx, key, next, jobFunc)
+	return id, nil
+}
+
+func (q *pgQueue) runPeriodic(ctx context.Context, key string, next func(time.Time) time.Time, jobFunc func() jobs.Job) {
+	ticker := time.NewTicker(q.b.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		res, err := q.b.db.ExecContext(ctx, `
+			UPDATE tl_job_periodics SET next_run_at = $2, updated_at = now()
+			WHERE periodic_key = $1 AND next_run_at <= now()`,
+			key, next(time.Now().UTC()),
+		)
+		if err != nil {
+			continue
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			if _, err := q.Submit(ctx, jobFunc()); err != nil && ctx.Err() == nil {
+				log.For(ctx).Error().Err(err).Str("queue", q.name).Msg("failed to submit periodic job")
+			}
+		}
+	}
+}
+
+func (q *pgQueue) RemovePeriodic(ctx context.Context, id string) error {
+	q.periodicMu.Lock()
+	defer q.periodicMu.Unlock()
+	cancel, ok := q.periodics[id]
+	if !ok {
+		return jobs.ErrJobNotFound
+	}
+	cancel()
+	delete(q.periodics, id)
+	return nil
+}
@@ -0,0 +1,134 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/interline-io/transitland-lib/server/auth/authn"
+	"github.com/interline-io/transitland-lib/server/jobs"
+	"github.com/interline-io/transitland-lib/server/jobs/jobtest"
+	"github.com/interline-io/transitland-lib/server/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBackend(t *testing.T, queueName string, opts QueueOpts) (*jobs.Runner, *PostgresBackend) {
+	db := testutil.MustOpenTestDB(t)
+	runner := jobs.NewRunner()
+	backend := NewPostgresBackend(db, runner, map[string]QueueOpts{queueName: opts}, nil)
+	backend.PollInterval = 50 * time.Millisecond
+	return runner, backend
+}
+
+func TestPostgresBackend(t *testing.T) {
+	if msg, ok := testutil.CheckTestDB(); !ok {
+		t.Skip(msg)
+	}
+	newSetup := func(queueName string) jobtest.TestSetup {
+		runner, backend := newTestBackend(t, queueName, QueueOpts{Workers: 4})
+		return jobtest.TestSetup{Runner: runner, Backend: backend, QueueName: queueName}
+	}
+	jobtest.TestBackend(t, newSetup)
+}
+
+func TestPostgresBackend_Backoff(t *testing.T) {
+	b := NewPostgresBackend(nil, nil, nil, nil)
+	b.MinBackoff = 10 * time.Second
+	b.MaxBackoff = 1 * time.Minute
+	assert.Equal(t, 10*time.Second, b.backoff(1))
+	assert.Equal(t, 20*time.Second, b.backoff(2))
+	assert.Equal(t, 40*time.Second, b.backoff(3))
+	assert.Equal(t, 1*time.Minute, b.backoff(4))
+	assert.Equal(t, 1*time.Minute, b.backoff(20))
+}
+
+func TestPostgresBackend_Claim(t *testing.T) {
+	if msg, ok := testutil.CheckTestDB(); !ok {
+		t.Skip(msg)
+	}
+	ctx := authn.WithUser(context.Background(), authn.NewCtxUser("test-admin", "", "").WithRoles("admin"))
+	queueName := fmt.Sprintf("test-claim-%d", time.Now().UnixNano())
+	_, backend := newTestBackend(t, queueName, QueueOpts{Workers: 1})
+	q, err := backend.Queue(queueName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pq := q.(*pgQueue)
+	// Nothing to claim yet
+	row, err := pq.claim(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, row)
+	st, err := q.Submit(ctx, jobs.Job{Kind: "testClaim"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	row, err = pq.claim(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.NotNil(t, row) {
+		assert.Equal(t, st.Job.ID, row.ID)
+		assert.Equal(t, string(jobs.JobStateRunning), row.State)
+		assert.Equal(t, 1, row.Attempt)
+	}
+	// A claimed job is not claimed again
+	row, err = pq.claim(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, row)
+}
+
+type flakyWorker struct {
+	count *int64
+}
+
+func (w *flakyWorker) Kind() string { return "testFlaky" }
+func (w *flakyWorker) Run(ctx context.Context) error {
+	if atomic.AddInt64(w.count, 1) < 3 {
+		return errors.New("try again")
+	}
+	return nil
+}
+
+func TestPostgresBackend_Retry(t *testing.T) {
+	if msg, ok := testutil.CheckTestDB(); !ok {
+		t.Skip(msg)
+	}
+	ctx := authn.WithUser(context.Background(), authn.NewCtxUser("test-admin", "", "").WithRoles("admin"))
+	queueName := fmt.Sprintf("test-retry-%d", time.Now().UnixNano())
+	runner, backend := newTestBackend(t, queueName, QueueOpts{Workers: 1, MaxAttempts: 3})
+	backend.MinBackoff = 10 * time.Millisecond
+	backend.MaxBackoff = 20 * time.Millisecond
+	count := int64(0)
+	if err := runner.Register(func() jobs.Worker { return &flakyWorker{count: &count} }); err != nil {
+		t.Fatal(err)
+	}
+	q, err := backend.Queue(queueName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := q.Submit(ctx, jobs.Job{Kind: "testFlaky"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(2 * time.Second)
+		backend.Stop(ctx)
+	}()
+	if err := backend.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	final, err := q.(jobs.StatusQueue).Status(ctx, st.Job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, jobs.JobStateSucceeded, final.State)
+	assert.Equal(t, 3, final.Attempt)
+	assert.Equal(t, int64(3), atomic.LoadInt64(&count))
+}
@@ -23,6 +23,23 @@ type FVEntityID struct {
 	EntityID      string
 }
 
+// TranslationKey selects the translations of a GTFS record by record_id, and the translations
+// matched by field_value to FieldValue. Empty values are not used for lookups.
+type TranslationKey struct {
+	FeedVersionID int
+	RecordID      string
+	FieldValue    string
+}
+
+// PerformanceScope selects the stop observations of a feed version for on-time performance
+// metrics, by the GTFS id of an agency, route or stop. Empty ids are not used as filters.
+type PerformanceScope struct {
+	FeedVersionID int
+	AgencyID      string
+	RouteID       string
+	StopID        string
+}
+
 // Finder provides all necessary database methods
 type Finder interface {
 	PermFinder
@@ -50,6 +67,8 @@ type EntityFinder interface {
 	FindCensusValuesByDatasetID(context.Context, *int, CensusCursor, int, *CensusDatasetValueFilter) ([]*CensusValue, error)
 	RouteStopBuffer(context.Context, *int, *float64, int) ([]*RouteStopBuffer, error)
 	FindFeedVersionServiceWindow(context.Context, int) (*ServiceWindow, error)
+	FindEffectiveFeedVersion(context.Context, int, time.Time) (int, error)
+	FindPerformance(context.Context, PerformanceScope, *PerformanceFilter) (*PerformanceReport, error)
 }
 
 type EntityLoader interface {
@@ -57,6 +76,10 @@ type EntityLoader interface {
 	AgenciesByIDs(context.Context, []int) ([]*Agency, []error)
 	AgenciesByOnestopIDs(context.Context, *int, *AgencyFilter, []string) ([][]*Agency, error)
 	AgencyPlacesByAgencyIDs(context.Context, *int, *AgencyPlaceFilter, []int) ([][]*AgencyPlace, error)
+	AreasByFeedVersionAreaIDs(context.Context, []FVEntityID) ([]*Area, []error)
+	AreasByFeedVersionIDs(context.Context, *int, *AreaFilter, []int) ([][]*Area, error)
+	AreasByStopIDs(context.Context, *int, []int) ([][]*Area, error)
+	AttributionsByFeedVersionIDs(context.Context, *int, []int) ([][]*Attribution, error)
 	BookingRulesByFeedVersionIDs(context.Context, *int, *BookingRuleFilter, []int) ([][]*BookingRule, error)
 	BookingRulesByIDs(context.Context, []int) ([]*BookingRule, []error)
 	CalendarDatesByServiceIDs(context.Context, *int, *CalendarDateFilter, []int) ([][]*CalendarDate, error)
@@ -75,6 +98,15 @@ type EntityLoader interface {
 	CensusTablesBySourceIDs(context.Context, *int, *CensusTableFilter, []int) ([][]*CensusTable, error)
 	CensusTableByIDs(context.Context, []int) ([]*CensusTable, []error)
 	CensusValuesByGeographyIDs(context.Context, *int, string, []string, []string) ([][]*CensusValue, error)
+	FareLegJoinRulesByFeedVersionIDs(context.Context, *int, []int) ([][]*FareLegJoinRule, error)
+	FareLegRulesByFeedVersionIDs(context.Context, *int, *FareLegRuleFilter, []int) ([][]*FareLegRule, error)
+	FareLegRulesByFeedVersionLegGroupIDs(context.Context, *int, []FVEntityID) ([][]*FareLegRule, error)
+	FareLegRulesByRouteIDs(context.Context, *int, *FareLegRuleFilter, []int) ([][]*FareLegRule, error)
+	FareMediaByFeedVersionIDs(context.Context, *int, *FareMediaFilter, []int) ([][]*FareMedia, error)
+	FareMediaByIDs(context.Context, []int) ([]*FareMedia, []error)
+	FareProductsByFeedVersionFareProductIDs(context.Context, *int, []FVEntityID) ([][]*FareProduct, error)
+	FareProductsByFeedVersionIDs(context.Context, *int, *FareProductFilter, []int) ([][]*FareProduct, error)
+	FareTransferRulesByFeedVersionIDs(context.Context, *int, *FareTransferRuleFilter, []int) ([][]*FareTransferRule, error)
 	FeedFetchesByFeedIDs(context.Context, *int, *FeedFetchFilter, []int) ([][]*FeedFetch, error)
 	FeedInfosByFeedVersionIDs(context.Context, *int, []int) ([][]*FeedInfo, error)
 	FeedsByIDs(context.Context, []int) ([]*Feed, []error)
@@ -99,12 +131,16 @@ type EntityLoader interface {
 	LocationGroupsByStopIDs(context.Context, *int, []int) ([][]*LocationGroup, error)
 	LocationsByFeedVersionIDs(context.Context, *int, *LocationFilter, []int) ([][]*Location, error)
 	LocationsByIDs(context.Context, []int) ([]*Location, []error)
+	NetworksByFeedVersionIDs(context.Context, *int, *NetworkFilter, []int) ([][]*Network, error)
+	NetworksByRouteIDs(context.Context, *int, []int) ([][]*Network, error)
 	OperatorsByAgencyIDs(context.Context, []int) ([]*Operator, []error)
 	OperatorsByCOIFs(context.Context, []int) ([]*Operator, []error)
 	OperatorsByFeedIDs(context.Context, *int, *OperatorFilter, []int) ([][]*Operator, error)
 	PathwaysByFromStopIDs(context.Context, *int, *PathwayFilter, []int) ([][]*Pathway, error)
 	PathwaysByIDs(context.Context, []int) ([]*Pathway, []error)
 	PathwaysByToStopIDs(context.Context, *int, *PathwayFilter, []int) ([][]*Pathway, error)
+	RiderCategoriesByFeedVersionIDs(context.Context, *int, *RiderCategoryFilter, []int) ([][]*RiderCategory, error)
+	RiderCategoriesByFeedVersionRiderCategoryIDs(context.Context, []FVEntityID) ([]*RiderCategory, []error)
 	RouteAttributesByRouteIDs(context.Context, []int) ([]*RouteAttribute, []error)
 	RouteGeometriesByRouteIDs(context.Context, *int, []int) ([][]*RouteGeometry, error)
 	RouteHeadwaysByRouteIDs(context.Context, *int, []int) ([][]*RouteHeadway, error)
@@ -112,6 +148,7 @@ type EntityLoader interface {
 	RoutesByFeedVersionIDs(context.Context, *int, *RouteFilter, []int) ([][]*Route, error)
 	RoutesByFeedVersionRouteIDs(context.Context, []FVEntityID) ([]*Route, []error)
 	RoutesByIDs(context.Context, []int) ([]*Route, []error)
+	RoutesByNetworkIDs(context.Context, *int, []int) ([][]*Route, error)
 	RouteStopPatternsByRouteIDs(context.Context, *int, *RouteStopPatternFilter, []FVPair) ([][]*RouteStopPattern, error)
 	RouteStopsByRouteIDs(context.Context, *int, []int) ([][]*RouteStop, error)
 	RouteStopsByStopIDs(context.Context, *int, []int) ([][]*RouteStop, error)
@@ -124,6 +161,7 @@ type EntityLoader interface {
 	StopExternalReferencesByStopIDs(context.Context, []int) ([]*StopExternalReference, []error)
 	StopObservationsByStopIDs(context.Context, *int, *StopObservationFilter, []int) ([][]*StopObservation, error)
 	StopPlacesByStopID(context.Context, []StopPlaceParam) ([]*StopPlace, []error)
+	StopsByAreaIDs(context.Context, *int, []int) ([][]*Stop, error)
 	StopsByFeedVersionIDs(context.Context, *int, *StopFilter, []int) ([][]*Stop, error)
 	StopsByFeedVersionStopIDs(context.Context, []FVEntityID) ([]*Stop, []error)
 	StopsByIDs(context.Context, []int) ([]*Stop, []error)
@@ -134,6 +172,12 @@ type EntityLoader interface {
 	StopTimesByStopIDs(context.Context, *int, *StopTimeFilter, []FVPair) ([][]*StopTime, error)
 	StopTimesByTripIDs(context.Context, *int, *TripStopTimeFilter, []FVPair) ([][]*StopTime, error)
 	TargetStopsByStopIDs(context.Context, []int) ([]*Stop, []error)
+	TimeframesByFeedVersionIDs(context.Context, *int, *TimeframeFilter, []int) ([][]*Timeframe, error)
+	TimeframesByFeedVersionTimeframeGroupIDs(context.Context, *int, []FVEntityID) ([][]*Timeframe, error)
+	TransfersByFeedVersionIDs(context.Context, *int, *TransferFilter, []int) ([][]*Transfer, error)
+	TransfersByFromStopIDs(context.Context, *int, *TransferFilter, []int) ([][]*Transfer, error)
+	TransfersByToStopIDs(context.Context, *int, *TransferFilter, []int) ([][]*Transfer, error)
+	TranslationsByFeedVersionRecordIDs(context.Context, *int, string, []TranslationKey) ([][]*Translation, error)
 	TripsByFeedVersionIDs(context.Context, *int, *TripFilter, []int) ([][]*Trip, error)
 	TripsByFeedVersionTripIDs(context.Context, []FVEntityID) ([]*Trip, []error)
 	TripsByIDs(context.Context, []int) ([]*Trip, []error)
@@ -163,6 +207,7 @@ type RTFinder interface {
 	MakeTrip(context.Context, *Trip) (*Trip, error)
 	FindAlertsForTrip(context.Context, *Trip, *int, *bool) []*Alert
 	FindAlertsForStop(context.Context, *Stop, *int, *bool) []*Alert
+	FindAlertsForStops(context.Context, []*Stop, *bool) [][]*Alert
 	FindAlertsForRoute(context.Context, *Route, *int, *bool) []*Alert
 	FindAlertsForAgency(context.Context, *Agency, *int, *bool) []*Alert
 	FindVehiclePositionsForAgency(context.Context, *Agency, *int, *VehiclePositionFilter) []*VehiclePosition
@@ -175,6 +220,15 @@ type RTFinder interface {
 	FeedVersionTimezone(context.Context, int) (*time.Location, bool)
 	GetGtfsTripID(context.Context, int) (string, bool)
 	GetMessage(context.Context, string, string) (*pb.FeedMessage, bool)
+	GetRemovedEntities(context.Context, string, string) (map[string]uint64, uint64, bool)
+	// WatchUpdates notifies of realtime messages updated in this process
+	WatchUpdates(context.Context) <-chan RTUpdate
+}
+
+// RTUpdate identifies an updated realtime message
+type RTUpdate struct {
+	FeedOnestopID string
+	MessageType   string // e.g. realtime_trip_updates
 }
 
 // GbfsFinder manages and looks up GBFS data
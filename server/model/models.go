@@ -99,6 +99,7 @@ type Route struct {
 	OnestopID                    *string
 	HeadwaySecondsWeekdayMorning *int
 	SearchRank                   *string
+	WithNetworkID                tt.Int
 	gtfs.Route
 }
 
@@ -133,6 +134,7 @@ type Stop struct {
 	SearchRank      *string
 	WithinFeatures  tt.Strings
 	WithRouteID     tt.Int
+	WithAreaID      tt.Int
 	gtfs.Stop
 }
 
@@ -202,6 +204,79 @@ type LocationGroupStop struct {
 	gtfs.LocationGroupStop
 }
 
+type FareProduct struct {
+	FeedOnestopID   string
+	FeedVersionSHA1 string
+	gtfs.FareProduct
+}
+
+type FareMedia struct {
+	FeedOnestopID   string
+	FeedVersionSHA1 string
+	gtfs.FareMedia
+}
+
+type RiderCategory struct {
+	FeedOnestopID   string
+	FeedVersionSHA1 string
+	gtfs.RiderCategory
+}
+
+type FareLegRule struct {
+	FeedOnestopID   string
+	FeedVersionSHA1 string
+	WithRouteID     tt.Int
+	gtfs.FareLegRule
+}
+
+type FareLegJoinRule struct {
+	FeedOnestopID   string
+	FeedVersionSHA1 string
+	gtfs.FareLegJoinRule
+}
+
+type FareTransferRule struct {
+	FeedOnestopID   string
+	FeedVersionSHA1 string
+	gtfs.FareTransferRule
+}
+
+type Timeframe struct {
+	FeedOnestopID   string
+	FeedVersionSHA1 string
+	gtfs.Timeframe
+}
+
+type Area struct {
+	FeedOnestopID   string
+	FeedVersionSHA1 string
+	WithStopID      tt.Int
+	gtfs.Area
+}
+
+type Translation struct {
+	gtfs.Translation
+}
+
+type Transfer struct {
+	FeedOnestopID   string
+	FeedVersionSHA1 string
+	gtfs.Transfer
+}
+
+type Network struct {
+	FeedOnestopID   string
+	FeedVersionSHA1 string
+	WithRouteID     tt.Int
+	gtfs.Network
+}
+
+type Attribution struct {
+	FeedOnestopID   string
+	FeedVersionSHA1 string
+	gtfs.Attribution
+}
+
 // FlexStopTime is an alias for StopTime.
 // Both types represent stop_times records and share the same underlying model (gtfs.StopTime).
 // The separation exists only for GraphQL schema purposes where they have different resolvers.
@@ -105,6 +105,26 @@ type Alert struct {
 	SeverityLevel *string `json:"severity_level,omitempty"`
 }
 
+// Selects the alerts for an `alerts` subscription. Exactly one of `agency_id`, `route_id`, or `stop_id` is required
+type AlertSubscriptionFilter struct {
+	// Feed Onestop ID of the static feed
+	FeedOnestopID string `json:"feed_onestop_id"`
+	// GTFS agency_id
+	AgencyID *string `json:"agency_id,omitempty"`
+	// GTFS route_id
+	RouteID *string `json:"route_id,omitempty"`
+	// GTFS stop_id
+	StopID *string `json:"stop_id,omitempty"`
+}
+
+// Search options for areas
+type AreaFilter struct {
+	// Restrict to specific ids
+	Ids []int `json:"ids,omitempty"`
+	// Search for areas with this area_id
+	AreaID *string `json:"area_id,omitempty"`
+}
+
 // Search options for GTFS Flex booking rules
 type BookingRuleFilter struct {
 	// Restrict to specific ids
@@ -410,6 +430,32 @@ type CensusValue struct {
 	TableID     int    `json:"-"`
 }
 
+// Distribution of observed delays, in seconds
+type DelayDistribution struct {
+	// Mean delay
+	Mean float64 `json:"mean"`
+	// Median delay
+	Median int `json:"median"`
+	// 90th percentile delay
+	P90 int `json:"p90"`
+	// Earliest observation
+	Min int `json:"min"`
+	// Latest observation
+	Max int `json:"max"`
+	// Number of observations by delay range
+	Histogram []*DelayHistogramBin `json:"histogram"`
+}
+
+// Number of observations with a delay in a range
+type DelayHistogramBin struct {
+	// Lower bound of the range in seconds, inclusive; null for the first range
+	MinDelay *int `json:"min_delay,omitempty"`
+	// Upper bound of the range in seconds, exclusive; null for the last range
+	MaxDelay *int `json:"max_delay,omitempty"`
+	// Number of observations in the range
+	Count int `json:"count"`
+}
+
 // Input parameters for a directions (routing) request.
 //
 // Specifies an origin, destination, travel mode, and optional departure time.
@@ -475,6 +521,50 @@ type EntityDeleteResult struct {
 	ID int `json:"id"`
 }
 
+// Search options for fare leg rules
+type FareLegRuleFilter struct {
+	// Restrict to specific ids
+	Ids []int `json:"ids,omitempty"`
+	// Search for fare leg rules with this leg_group_id
+	LegGroupID *string `json:"leg_group_id,omitempty"`
+	// Search for fare leg rules with this network_id
+	NetworkID *string `json:"network_id,omitempty"`
+	// Search for fare leg rules with this fare_product_id
+	FareProductID *string `json:"fare_product_id,omitempty"`
+}
+
+// Search options for fare media
+type FareMediaFilter struct {
+	// Restrict to specific ids
+	Ids []int `json:"ids,omitempty"`
+	// Search for fare media with this fare_media_id
+	FareMediaID *string `json:"fare_media_id,omitempty"`
+}
+
+// Search options for fare products
+type FareProductFilter struct {
+	// Restrict to specific ids
+	Ids []int `json:"ids,omitempty"`
+	// Search for fare products with this fare_product_id
+	FareProductID *string `json:"fare_product_id,omitempty"`
+	// Search for fare products with this rider_category_id
+	RiderCategoryID *string `json:"rider_category_id,omitempty"`
+	// Search for fare products with this fare_media_id
+	FareMediaID *string `json:"fare_media_id,omitempty"`
+}
+
+// Search options for fare transfer rules
+type FareTransferRuleFilter struct {
+	// Restrict to specific ids
+	Ids []int `json:"ids,omitempty"`
+	// Search for fare transfer rules with this from_leg_group_id
+	FromLegGroupID *string `json:"from_leg_group_id,omitempty"`
+	// Search for fare transfer rules with this to_leg_group_id
+	ToLegGroupID *string `json:"to_leg_group_id,omitempty"`
+	// Search for fare transfer rules with this fare_product_id
+	FareProductID *string `json:"fare_product_id,omitempty"`
+}
+
 // A GeoJSON-style feature used as a search-area input. Stops contained in the feature are returned with the feature's `id` echoed back via `Stop.within_features`
 type Feature struct {
 	// Caller-supplied identifier echoed back on matched stops
@@ -545,6 +635,8 @@ type FeedVersionFetchResult struct {
 	FoundSha1 bool `json:"found_sha1"`
 	// True if a zip with identical unpacked contents is already in the database (matched by directory SHA1)
 	FoundDirSha1 bool `json:"found_dir_sha1"`
+	// ID of the queued job in the `static-fetch` queue, if the fetch was requested with `async: true`
+	JobID *string `json:"job_id,omitempty"`
 }
 
 // Search options for feed versions
@@ -662,6 +754,33 @@ type Group struct {
 	Permissions *Permissions `json:"permissions,omitempty"`
 }
 
+// Headway adherence of frequency-based trips (GTFS `frequencies.txt`).
+// Observed headways are measured between consecutive trips of the same route and direction at each stop, and compared with the `headway_secs` in effect for the later trip.
+type HeadwayAdherence struct {
+	// Number of observed headways
+	HeadwayCount int `json:"headway_count"`
+	// Mean scheduled headway, in seconds
+	ScheduledHeadwayMean float64 `json:"scheduled_headway_mean"`
+	// Mean observed headway, in seconds
+	ObservedHeadwayMean float64 `json:"observed_headway_mean"`
+	// Number of observed headways within 50% of the scheduled headway
+	AdherentCount int `json:"adherent_count"`
+	// Percentage (0-100) of observed headways within 50% of the scheduled headway
+	AdherentPercent float64 `json:"adherent_percent"`
+	// Excess wait time in seconds: the average wait of a rider arriving at random, beyond the wait expected from the scheduled headways
+	ExcessWaitTime float64 `json:"excess_wait_time"`
+}
+
+// Area reachable within a travel time cutoff: walking distance from the origin and from each stop reached in time, using the remaining time.
+type Isochrone struct {
+	// Travel time cutoff in seconds
+	Cutoff int `json:"cutoff"`
+	// Reachable area
+	Geometry tt.MultiPolygon `json:"geometry"`
+	// Census geographies intersecting the reachable area; `dataset` is required. Use `intersection_area` and `geometry_area` with `values` to estimate population reached
+	CensusGeographies []*CensusGeography `json:"census_geographies,omitempty"`
+}
+
 // A single trip option from origin to destination, composed of one or more `Leg`s.
 type Itinerary struct {
 	// Total duration of this itinerary
@@ -678,6 +797,38 @@ type Itinerary struct {
 	To *Waypoint `json:"to"`
 	// Ordered list of legs making up this itinerary
 	Legs []*Leg `json:"legs,omitempty"`
+	// Fares calculated from GTFS Fares v2 data, one for each rider category and fare media combination that can price every transit leg (transit mode only)
+	Fares []*ItineraryFare `json:"fares,omitempty"`
+}
+
+// The calculated fare for an itinerary, for a single rider category and fare media combination.
+type ItineraryFare struct {
+	// GTFS rider_categories.rider_category_id; empty if the fare products do not specify a rider category
+	RiderCategoryID string `json:"rider_category_id"`
+	// GTFS fare_media.fare_media_id; empty if the fare products do not specify a fare media
+	FareMediaID string `json:"fare_media_id"`
+	// Total price of the itinerary
+	Amount float64 `json:"amount"`
+	// Currency of the total price
+	Currency string `json:"currency"`
+	// Fare products applied to the transit legs of the itinerary
+	Legs []*ItineraryFareLeg `json:"legs"`
+}
+
+// A fare product applied to one or more transit legs of an itinerary.
+type ItineraryFareLeg struct {
+	// Indexes into Itinerary.legs covered by this fare; more than one when legs are joined by fare_leg_join_rules
+	LegIndexes []int `json:"leg_indexes"`
+	// GTFS fare_leg_rules.leg_group_id
+	LegGroupID *string `json:"leg_group_id,omitempty"`
+	// GTFS fare_products.fare_product_id
+	FareProductID string `json:"fare_product_id"`
+	// GTFS fare_products.fare_product_name
+	FareProductName *string `json:"fare_product_name,omitempty"`
+	// GTFS fare_products.fare_product_id for the transfer fare, if a fare transfer rule was applied
+	TransferFareProductID *string `json:"transfer_fare_product_id,omitempty"`
+	// Amount charged for these legs, after any transfer discount
+	Amount float64 `json:"amount"`
 }
 
 // A single segment of an itinerary, traveled by a single mode (e.g. walk, transit vehicle).
@@ -706,6 +857,60 @@ type Leg struct {
 	Geometry tt.LineString `json:"geometry"`
 	// Transit trip details for this leg (transit mode only)
 	Trip *LegTrip `json:"trip,omitempty"`
+	// Demand-responsive service details for this leg (GTFS-Flex trips only)
+	Flex *LegFlex `json:"flex,omitempty"`
+}
+
+// Summary of a GTFS booking rule used within a leg.
+type LegBookingRule struct {
+	// GTFS booking_rule_id
+	BookingRuleID string `json:"booking_rule_id"`
+	// GTFS booking_type [0=real-time booking, 1=same-day booking with prior notice, 2=advance booking with prior notice]
+	BookingType int `json:"booking_type"`
+	// Minimum minutes before travel for a booking request
+	PriorNoticeDurationMin *int `json:"prior_notice_duration_min,omitempty"`
+	// Maximum minutes before travel for a booking request
+	PriorNoticeDurationMax *int `json:"prior_notice_duration_max,omitempty"`
+	// Last day before travel when a booking may be made
+	PriorNoticeLastDay *int `json:"prior_notice_last_day,omitempty"`
+	// Latest time on prior_notice_last_day when a booking may be made
+	PriorNoticeLastTime *tt.Seconds `json:"prior_notice_last_time,omitempty"`
+	// General message shown to riders when booking this service
+	Message *string `json:"message,omitempty"`
+	// Message shown to riders when booking a pickup
+	PickupMessage *string `json:"pickup_message,omitempty"`
+	// Message shown to riders when booking a drop-off
+	DropOffMessage *string `json:"drop_off_message,omitempty"`
+	// Phone number to call to make a booking
+	PhoneNumber *string `json:"phone_number,omitempty"`
+	// URL with more information about this booking rule
+	InfoURL *string `json:"info_url,omitempty"`
+	// URL to the booking system or interface for this service
+	BookingURL *string `json:"booking_url,omitempty"`
+}
+
+// Demand-responsive (GTFS-Flex) service details for a leg: when the vehicle may pick up and drop off, the expected and worst-case in-vehicle times, and how to book.
+type LegFlex struct {
+	// GTFS location_id, location_group_id, or stop_id where the rider is picked up
+	PickupLocationID string `json:"pickup_location_id"`
+	// GTFS location_id, location_group_id, or stop_id where the rider is dropped off
+	DropOffLocationID string `json:"drop_off_location_id"`
+	// Earliest pickup time, from start_pickup_drop_off_window and the requested departure time
+	PickupWindowStart time.Time `json:"pickup_window_start"`
+	// Latest pickup time that still reaches the destination within its drop-off window
+	PickupWindowEnd time.Time `json:"pickup_window_end"`
+	// Earliest expected drop-off time
+	DropOffWindowStart time.Time `json:"drop_off_window_start"`
+	// Latest drop-off time, from the safe in-vehicle time and end_pickup_drop_off_window
+	DropOffWindowEnd time.Time `json:"drop_off_window_end"`
+	// Expected in-vehicle time, from mean_duration_factor and mean_duration_offset
+	MeanDuration *Duration `json:"mean_duration"`
+	// Worst-case in-vehicle time, from safe_duration_factor and safe_duration_offset
+	SafeDuration *Duration `json:"safe_duration"`
+	// Booking rule for the pickup, from stop_times.pickup_booking_rule_id
+	PickupBookingRule *LegBookingRule `json:"pickup_booking_rule,omitempty"`
+	// Booking rule for the drop-off, from stop_times.drop_off_booking_rule_id
+	DropOffBookingRule *LegBookingRule `json:"drop_off_booking_rule,omitempty"`
 }
 
 // Summary of the route used within a leg.
@@ -822,6 +1027,14 @@ type Me struct {
 type Mutation struct {
 }
 
+// Search options for networks
+type NetworkFilter struct {
+	// Restrict to specific ids
+	Ids []int `json:"ids,omitempty"`
+	// Search for networks with this network_id
+	NetworkID *string `json:"network_id,omitempty"`
+}
+
 // Search options for operators
 type OperatorFilter struct {
 	// If true, collapse multiple agency-operator associations into a single result per operator
@@ -862,6 +1075,50 @@ type PathwayFilter struct {
 	PathwayMode *int `json:"pathway_mode,omitempty"`
 }
 
+// A route through a station's pathways between two of its stops, such as an entrance and a platform.
+type PathwayRoute struct {
+	// Route begins at this stop
+	FromStop *Stop `json:"from_stop"`
+	// Route ends at this stop
+	ToStop *Stop `json:"to_stop"`
+	// Fastest route; null if to_stop cannot be reached
+	Fastest *PathwayRouteOption `json:"fastest,omitempty"`
+	// Fastest route without stairs or escalators; null if there is none
+	StepFree *PathwayRouteOption `json:"step_free,omitempty"`
+}
+
+// Options for station pathway routes
+type PathwayRouteFilter struct {
+	// Only routes from this GTFS stop_id; defaults to the station's entrances
+	FromStopID *string `json:"from_stop_id,omitempty"`
+	// Only routes to this GTFS stop_id; defaults to the station's platforms
+	ToStopID *string `json:"to_stop_id,omitempty"`
+	// Treat pathways with these GTFS pathway_ids as unavailable, e.g. elevators that are out of service
+	UnavailablePathwayIds []string `json:"unavailable_pathway_ids,omitempty"`
+	// Treat elevators as unavailable when an active GTFS-RT alert with effect ACCESSIBILITY_ISSUE or NO_SERVICE applies to a stop they connect
+	UseAlerts *bool `json:"use_alerts,omitempty"`
+}
+
+// A sequence of pathways from one stop of a station to another.
+type PathwayRouteOption struct {
+	// Total traversal time in seconds; pathways without traversal_time are estimated from their length
+	TraversalTime int `json:"traversal_time"`
+	// True if the route avoids stairs and escalators
+	StepFree bool `json:"step_free"`
+	// Pathways in the order they are traversed
+	Steps []*PathwayRouteStep `json:"steps"`
+}
+
+// A pathway traversed as part of a pathway route.
+type PathwayRouteStep struct {
+	// Pathway traversed
+	Pathway *Pathway `json:"pathway"`
+	// True if the pathway is traversed from its to_stop to its from_stop
+	Reversed bool `json:"reversed"`
+	// Traversal time of this step in seconds
+	TraversalTime int `json:"traversal_time"`
+}
+
 // Create or update a pathway entity. For updates, supply `id`. For creation, supply `feed_version`
 type PathwaySetInput struct {
 	// Integer ID of the pathway to update; omit when creating a new pathway
@@ -894,6 +1151,72 @@ type PathwaySetInput struct {
 	ToStop *StopSetInput `json:"to_stop,omitempty"`
 }
 
+// Options for on-time performance metrics
+type PerformanceFilter struct {
+	// First service date, inclusive
+	StartDate tt.Date `json:"start_date"`
+	// Last service date, inclusive; defaults to `start_date`. The range may cover at most 366 days, or 31 days for an agency
+	EndDate *tt.Date `json:"end_date,omitempty"`
+	// Only observations scheduled at or after this time of day
+	StartTime *tt.Seconds `json:"start_time,omitempty"`
+	// Only observations scheduled before this time of day
+	EndTime *tt.Seconds `json:"end_time,omitempty"`
+	// Only observations from this GTFS-RT message type: `TripUpdate` or `VehiclePosition`. By default, trip update observations are used, and vehicle position observations for stops without one
+	Source *string `json:"source,omitempty"`
+	// Observations more than this many seconds early are early; defaults to 60
+	EarlyThreshold *int `json:"early_threshold,omitempty"`
+	// Observations more than this many seconds late are late; defaults to 300
+	LateThreshold *int `json:"late_threshold,omitempty"`
+}
+
+// On-time performance metrics for a set of stop observations.
+// Delays are measured at the observed arrival, or at the observed departure when no arrival was observed; negative delays are early.
+type PerformanceMetrics struct {
+	// GTFS route_id of this group; set in `by_route`
+	RouteID *string `json:"route_id,omitempty"`
+	// GTFS stop_id of this group; set in `by_stop`
+	StopID *string `json:"stop_id,omitempty"`
+	// Hour of the scheduled time, in the feed timezone; may be 24 or more for trips after midnight. Set in `by_hour`
+	Hour *int `json:"hour,omitempty"`
+	// Service date of this group; set in `by_date`
+	Date *tt.Date `json:"date,omitempty"`
+	// Number of observations with a measured delay
+	ObservationCount int `json:"observation_count"`
+	// Number of observations within the on-time window
+	OnTimeCount int `json:"on_time_count"`
+	// Number of observations earlier than the on-time window
+	EarlyCount int `json:"early_count"`
+	// Number of observations later than the on-time window
+	LateCount int `json:"late_count"`
+	// Percentage (0-100) of observations within the on-time window; null without observations
+	OnTimePercent *float64 `json:"on_time_percent,omitempty"`
+	// Number of observed trips, counted once per service date, including canceled trips
+	TripCount int `json:"trip_count"`
+	// Number of trips reported with a GTFS-RT schedule_relationship of `CANCELED`, counted once per service date
+	CanceledTripCount int `json:"canceled_trip_count"`
+	// Distribution of delays; null without observations
+	Delay *DelayDistribution `json:"delay,omitempty"`
+	// Headway adherence of frequency-based trips; null when no consecutive frequency-based trips were observed
+	Headway *HeadwayAdherence `json:"headway,omitempty"`
+}
+
+// On-time performance aggregated from archived stop observations over a date range and time window.
+// `summary` covers all observations; the `by_*` lists break the same observations down by route, stop, scheduled hour and service date.
+type PerformanceReport struct {
+	// Metrics over all observations
+	Summary *PerformanceMetrics `json:"summary"`
+	// Number of trips scheduled in the date range and time window, from the feed version's calendars; trips are counted at their first departure, or at the stop for `Stop.performance`
+	ScheduledTripCount int `json:"scheduled_trip_count"`
+	// Metrics by GTFS route_id
+	ByRoute []*PerformanceMetrics `json:"by_route"`
+	// Metrics by GTFS stop_id of the observed stop
+	ByStop []*PerformanceMetrics `json:"by_stop"`
+	// Metrics by hour of the scheduled time
+	ByHour []*PerformanceMetrics `json:"by_hour"`
+	// Metrics by service date
+	ByDate []*PerformanceMetrics `json:"by_date"`
+}
+
 // Reference to a related entity in the authorization hierarchy
 type PermissionRef struct {
 	// Entity type (e.g. tenant, group, feed, feed_version)
@@ -1019,6 +1342,66 @@ type RTVehicleDescriptor struct {
 	LicensePlate *string `json:"license_plate,omitempty"`
 }
 
+// Result of a reachability request.
+type Reachability struct {
+	// Whether the request succeeded
+	Success bool `json:"success"`
+	// Error message if the request did not succeed
+	Exception *string `json:"exception,omitempty"`
+	// Resolved origin waypoint
+	Origin *Waypoint `json:"origin,omitempty"`
+	// Departure time used for the search
+	DepartAt *time.Time `json:"depart_at,omitempty"`
+	// Stops reachable within the largest cutoff, ordered by arrival time
+	Stops []*ReachableStop `json:"stops"`
+	// Isochrones, ordered by cutoff
+	Isochrones []*Isochrone `json:"isochrones"`
+}
+
+// Input parameters for a reachability (isochrone) request.
+//
+// Searches the scheduled service from an origin and departure time, walking to and from stops.
+type ReachabilityRequest struct {
+	// Origin waypoint
+	From *WaypointInput `json:"from"`
+	// Departure time. Defaults to now
+	DepartAt *time.Time `json:"depart_at,omitempty"`
+	// Travel time cutoffs in seconds; one isochrone is returned for each. Defaults to 1800, maximum 10800
+	Cutoffs []int `json:"cutoffs,omitempty"`
+	// Maximum walking distance in meters to and from stops. Defaults to 1000
+	MaxWalkDistance *float64 `json:"max_walk_distance,omitempty"`
+}
+
+// A stop reached in a reachability search, with the earliest arrival time.
+type ReachableStop struct {
+	// Longitude of the stop
+	Lon float64 `json:"lon"`
+	// Latitude of the stop
+	Lat float64 `json:"lat"`
+	// GTFS stop_id
+	StopID string `json:"stop_id"`
+	// Stop name
+	StopName string `json:"stop_name"`
+	// Stop code
+	StopCode string `json:"stop_code"`
+	// Feed Onestop ID of the stop
+	FeedOnestopID string `json:"feed_onestop_id"`
+	// Feed version SHA1 of the stop
+	FeedVersionSha1 string `json:"feed_version_sha1"`
+	// Earliest arrival time at this stop
+	ArrivalTime time.Time `json:"arrival_time"`
+	// Travel time from the origin in seconds
+	TravelTime int `json:"travel_time"`
+}
+
+// Search options for rider categories
+type RiderCategoryFilter struct {
+	// Restrict to specific ids
+	Ids []int `json:"ids,omitempty"`
+	// Search for rider categories with this rider_category_id
+	RiderCategoryID *string `json:"rider_category_id,omitempty"`
+}
+
 // Extended route metadata from the [MTC GTFS+ extension](https://github.com/MobilityData/gtfs-plus-spec) `route_attributes.txt` file. Values are integer codes defined by the extension; consult that spec for the meaning of each code.
 type RouteAttribute struct {
 	// Route category code (e.g. service tier such as local, express, rapid)
@@ -1498,6 +1881,8 @@ type StopTimeFilter struct {
 	ServiceDate *tt.Date `json:"service_date,omitempty"`
 	// If true and the requested date falls outside the feed version's normal service window, use the feed version's `fallback_week` instead
 	UseServiceWindow *bool `json:"use_service_window,omitempty"`
+	// If true, answer each service date from the feed version that governs it rather than this stop's feed version: the most recently fetched imported version of the same feed whose service window (`feed_info` start/end dates, or the calculated window) contains the date. Stop times then belong to that version's trips and stops, matched by GTFS `stop_id`. Dates no version governs fall back to this stop's feed version, and to `use_service_window` if set.
+	EffectiveSchedule *bool `json:"effective_schedule,omitempty"`
 	// Lower bound for departure time, in seconds since midnight
 	StartTime *int `json:"start_time,omitempty"`
 	// Upper bound for arrival time, in seconds since midnight
@@ -1518,6 +1903,12 @@ type StopTimeFilter struct {
 	ExcludeLast *bool `json:"exclude_last,omitempty"`
 }
 
+// Root Subscription type. Subscriptions are delivered over a websocket connection.
+//
+// Each subscription sends its current result when it starts, and again whenever the realtime data it depends on is updated.
+type Subscription struct {
+}
+
 // A tenant organization that owns groups and feeds
 type Tenant struct {
 	// Internal integer ID
@@ -1530,6 +1921,20 @@ type Tenant struct {
 	Permissions *Permissions `json:"permissions,omitempty"`
 }
 
+// Search options for timeframes
+type TimeframeFilter struct {
+	// Restrict to specific ids
+	Ids []int `json:"ids,omitempty"`
+	// Search for timeframes with this timeframe_group_id
+	TimeframeGroupID *string `json:"timeframe_group_id,omitempty"`
+}
+
+// Search options for transfers
+type TransferFilter struct {
+	// Search for transfers with this GTFS transfer_type
+	TransferType *int `json:"transfer_type,omitempty"`
+}
+
 // Search options for trips
 type TripFilter struct {
 	// GTFS service date on which trips run. Lowest precedence: ignored if `dates`, `service_dates` or `relative_date` is set
@@ -1542,6 +1947,8 @@ type TripFilter struct {
 	RelativeDate *RelativeDate `json:"relative_date,omitempty"`
 	// If true and the requested date falls outside the feed version's normal service window, use the feed version's `fallback_week` instead
 	UseServiceWindow *bool `json:"use_service_window,omitempty"`
+	// If true, return trips from the feed version that governs `service_date` or `relative_date` rather than the active (or the route's) feed version: the most recently fetched imported version of the same feed whose service window contains the date. Route trips are matched by GTFS `route_id`. Dates no version governs fall back to the usual feed version. Ignored for `dates` and `service_dates`.
+	EffectiveSchedule *bool `json:"effective_schedule,omitempty"`
 	// Search for trips with this GTFS trip_id
 	TripID *string `json:"trip_id,omitempty"`
 	// Search for trips with this stop pattern ID (scoped to feed version)
@@ -1568,6 +1975,14 @@ type TripStopTimeFilter struct {
 	StopIds []int `json:"stop_ids,omitempty"`
 }
 
+// Selects the trip for a `trip_updates` subscription
+type TripUpdateSubscriptionFilter struct {
+	// Feed Onestop ID of the static feed
+	FeedOnestopID string `json:"feed_onestop_id"`
+	// GTFS trip_id
+	TripID string `json:"trip_id"`
+}
+
 // A user in the authorization system
 type User struct {
 	// User identifier
@@ -1621,8 +2036,10 @@ type ValidationReport struct {
 	// Validation warnings, grouped by filename, if present
 	Warnings []*ValidationReportErrorGroup `json:"warnings"`
 	// Details about the validated feed
-	Details       *ValidationReportDetails `json:"details,omitempty"`
-	FeedVersionID int                      `json:"-"`
+	Details *ValidationReportDetails `json:"details,omitempty"`
+	// ID of the queued job in the `validate-upload` queue, if validation was requested with `async: true`
+	JobID         *string `json:"job_id,omitempty"`
+	FeedVersionID int     `json:"-"`
 }
 
 // Details about the validated feed, including selected entities, metadata of contained files, calendar extent, etc.
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/interline-io/transitland-lib/tt"
 )
@@ -103,6 +104,12 @@ func (UnimplementedFinder) RouteStopBuffer(context.Context, *int, *float64, int)
 func (UnimplementedFinder) FindFeedVersionServiceWindow(context.Context, int) (*ServiceWindow, error) {
 	return nil, notImplErr()
 }
+func (UnimplementedFinder) FindEffectiveFeedVersion(context.Context, int, time.Time) (int, error) {
+	return 0, notImplErr()
+}
+func (UnimplementedFinder) FindPerformance(context.Context, PerformanceScope, *PerformanceFilter) (*PerformanceReport, error) {
+	return nil, notImplErr()
+}
 
 // EntityLoader
 
@@ -118,6 +125,18 @@ func (UnimplementedFinder) AgenciesByOnestopIDs(context.Context, *int, *AgencyFi
 func (UnimplementedFinder) AgencyPlacesByAgencyIDs(context.Context, *int, *AgencyPlaceFilter, []int) ([][]*AgencyPlace, error) {
 	return nil, notImplErr()
 }
+func (UnimplementedFinder) AreasByFeedVersionAreaIDs(_ context.Context, keys []FVEntityID) ([]*Area, []error) {
+	return notImplBatch[*Area](keys)
+}
+func (UnimplementedFinder) AreasByFeedVersionIDs(context.Context, *int, *AreaFilter, []int) ([][]*Area, error) {
+	return nil, notImplErr()
+}
+func (UnimplementedFinder) AreasByStopIDs(context.Context, *int, []int) ([][]*Area, error) {
+	return nil, notImplErr()
+}
+func (UnimplementedFinder) AttributionsByFeedVersionIDs(context.Context, *int, []int) ([][]*Attribution, error) {
+	return nil, notImplErr()
+}
 func (UnimplementedFinder) BookingRulesByFeedVersionIDs(context.Context, *int, *BookingRuleFilter, []int) ([][]*BookingRule, error) {
 	return nil, notImplErr()
 }
@@ -173,6 +192,33 @@ func (UnimplementedFinder) CensusTableByIDs(_ context.Context, ids []int) ([]*Ce
 func (UnimplementedFinder) CensusValuesByGeographyIDs(context.Context, *int, string, []string, []string) ([][]*CensusValue, error) {
 	return nil, notImplErr()
 }
+func (UnimplementedFinder) FareLegJoinRulesByFeedVersionIDs(context.Context, *int, []int) ([][]*FareLegJoinRule, error) {
+	return nil, notImplErr()
+}
+func (UnimplementedFinder) FareLegRulesByFeedVersionIDs(context.Context, *int, *FareLegRuleFilter, []int) ([][]*FareLegRule, error) {
+	return nil, notImplErr()
+}
+func (UnimplementedFinder) FareLegRulesByFeedVersionLegGroupIDs(context.Context, *int, []FVEntityID) ([][]*FareLegRule, error) {
+	return nil, notImplErr()
+}
+func (UnimplementedFinder) FareLegRulesByRouteIDs(context.Context, *int, *FareLegRuleFilter, []int) ([][]*FareLegRule, error) {
+	return nil, notImplErr()
+}
+func (UnimplementedFinder) FareMediaByFeedVersionIDs(context.Context, *int, *FareMediaFilter, []int) ([][]*FareMedia, error) {
+	return nil, notImplErr()
+}
+func (UnimplementedFinder) FareMediaByIDs(_ context.Context, ids []int) ([]*FareMedia, []error) {
+	return notImplBatch[*FareMedia](ids)
+}
+func (UnimplementedFinder) FareProductsByFeedVersionFareProductIDs(context.Context, *int, []FVEntityID) ([][]*FareProduct, error) {
+	return nil, notImplErr()
+}
+func (UnimplementedFinder) FareProductsByFeedVersionIDs(context.Context, *int, *FareProductFilter, []int) ([][]*FareProduct, error) {
+	return nil, notImplErr()
+}
+func (UnimplementedFinder) FareTransferRulesByFeedVersionIDs(context.Context, *int, *FareTransferRuleFilter, []int) ([][]*FareTransferRule, error) {
+	return nil, notImplErr()
+}
 func (UnimplementedFinder) FeedFetchesByFeedIDs(context.Context, *int, *FeedFetchFilter, []int) ([][]*FeedFetch, error) {
 	return nil, notImplErr()
 }
@@ -245,6 +291,12 @@ func (UnimplementedFinder) LocationsByFeedVersionIDs(context.Context, *int, *Loc
 func (UnimplementedFinder) LocationsByIDs(_ context.Context, ids []int) ([]*Location, []error) {
 	return notImplBatch[*Location](ids)
 }
+func (UnimplementedFinder) NetworksByFeedVersionIDs(context.Context, *int, *NetworkFilter, []int) ([][]*Network, error) {
+	return nil, notImplErr()
+}
+func (UnimplementedFinder) NetworksByRouteIDs(context.Context, *int, []int) ([][]*Network, error) {
+	return nil, notImplErr()
+}
 func (UnimplementedFinder) OperatorsByAgencyIDs(_ context.Context, ids []int) ([]*Operator, []error) {
 	return notImplBatch[*Operator](ids)
 }
@@ -263,6 +315,12 @@ func (UnimplementedFinder) PathwaysByIDs(_ context.Context, ids []int) ([]*Pathw
 func (UnimplementedFinder) PathwaysByToStopIDs(context.Context, *int, *PathwayFilter, []int) ([][]*Pathway, error) {
 	return nil, notImplErr()
 }
+func (UnimplementedFinder) RiderCategoriesByFeedVersionIDs(context.Context, *int, *RiderCategoryFilter, []int) ([][]*RiderCategory, error) {
+	return nil, notImplErr()
+}
+func (UnimplementedFinder) RiderCategoriesByFeedVersionRiderCategoryIDs(_ context.Context, keys []FVEntityID) ([]*RiderCategory, []error) {
+	return notImplBatch[*RiderCategory](keys)
+}
 func (UnimplementedFinder) RouteAttributesByRouteIDs(_ context.Context, ids []int) ([]*RouteAttribute, []error) {
 	return notImplBatch[*RouteAttribute](ids)
 }
@@ -284,6 +342,9 @@ func (UnimplementedFinder) RoutesByFeedVersionRouteIDs(_ context.Context, keys [
 func (UnimplementedFinder) RoutesByIDs(_ context.Context, ids []int) ([]*Route, []error) {
 	return notImplBatch[*Route](ids)
 }
+func (UnimplementedFinder) RoutesByNetworkIDs(context.Context, *int, []int) ([][]*Route, error) {
+	return nil, notImplErr()
+}
 func (UnimplementedFinder) RouteStopPatternsByRouteIDs(context.Context, *int, *RouteStopPatternFilter, []FVPair) ([][]*RouteStopPattern, error) {
 	return nil, notImplErr()
 }
@@ -320,6 +381,9 @@ func (UnimplementedFinder) StopObservationsByStopIDs(context.Context, *int, *Sto
 func (UnimplementedFinder) StopPlacesByStopID(_ context.Context, keys []StopPlaceParam) ([]*StopPlace, []error) {
 	return notImplBatch[*StopPlace](keys)
 }
+func (UnimplementedFinder) StopsByAreaIDs(context.Context, *int, []int) ([][]*Stop, error) {
+	return nil, notImplErr()
+}
 func (UnimplementedFinder) StopsByFeedVersionIDs(context.Context, *int, *StopFilter, []int) ([][]*Stop, error) {
 	return nil, notImplErr()
 }
@@ -350,6 +414,24 @@ func (UnimplementedFinder) StopTimesByTripIDs(context.Context, *int, *TripStopTi
 func (UnimplementedFinder) TargetStopsByStopIDs(_ context.Context, ids []int) ([]*Stop, []error) {
 	return notImplBatch[*Stop](ids)
 }
+func (UnimplementedFinder) TimeframesByFeedVersionIDs(context.Context, *int, *TimeframeFilter, []int) ([][]*Timeframe, error) {
+	return nil, notImplErr()
+}
+func (UnimplementedFinder) TimeframesByFeedVersionTimeframeGroupIDs(context.Context, *int, []FVEntityID) ([][]*Timeframe, error) {
+	return nil, notImplErr()
+}
+func (UnimplementedFinder) TransfersByFeedVersionIDs(context.Context, *int, *TransferFilter, []int) ([][]*Transfer, error) {
+	return nil, notImplErr()
+}
+func (UnimplementedFinder) TransfersByFromStopIDs(context.Context, *int, *TransferFilter, []int) ([][]*Transfer, error) {
+	return nil, notImplErr()
+}
+func (UnimplementedFinder) TransfersByToStopIDs(context.Context, *int, *TransferFilter, []int) ([][]*Transfer, error) {
+	return nil, notImplErr()
+}
+func (UnimplementedFinder) TranslationsByFeedVersionRecordIDs(context.Context, *int, string, []TranslationKey) ([][]*Translation, error) {
+	return nil, notImplErr()
+}
 func (UnimplementedFinder) TripsByFeedVersionIDs(context.Context, *int, *TripFilter, []int) ([][]*Trip, error) {
 	return nil, notImplErr()
 }
@@ -0,0 +1,89 @@
+package rest
+
+import (
+	"context"
+	_ "embed"
+
+	oa "github.com/getkin/kin-openapi/openapi3"
+)
+
+//go:embed area_request.gql
+var areaQuery string
+
+// AreaRequest holds options for a /feed_versions/{feed_version_key}/areas request
+type AreaRequest struct {
+	FeedVersionKey string `json:"feed_version_key"`
+	StopKey        string `json:"stop_key"`
+	AreaID         string `json:"area_id"`
+	WithCursor
+}
+
+func (r AreaRequest) RequestInfo() RequestInfo {
+	return RequestInfo{
+		Path: "/feed_versions/{feed_version_key}/areas",
+		Get: &RequestOperation{
+			Query: areaQuery,
+			Operation: &oa.Operation{
+				Summary:     `Areas`,
+				Description: `Areas defined in areas.txt for a feed version, or the areas containing a stop through stop_areas.txt.`,
+				Extensions: map[string]any{
+					"x-alternates": []RequestAltPath{
+						{"GET", "/stops/{stop_key}/areas", "Request areas containing a stop"},
+					},
+				},
+				Parameters: oa.Parameters{
+					&pref{Value: &param{
+						Name:        "feed_version_key",
+						In:          "path",
+						Required:    true,
+						Description: `Feed version lookup key; can be an integer ID or a SHA1 value`,
+						Schema:      newSRVal("string", "", nil),
+					}},
+					&pref{Value: &param{
+						Name:        "area_id",
+						In:          "query",
+						Description: `Search for areas with this GTFS area_id`,
+						Schema:      newSRVal("string", "", nil),
+					}},
+					newPRef("limitParam"),
+				},
+			},
+		},
+	}
+}
+
+// ResponseKey returns the GraphQL response entity key.
+func (r AreaRequest) ResponseKey() string {
+	if r.StopKey != "" {
+		return "stops"
+	}
+	return "feed_versions"
+}
+
+// IncludeNext
+func (r AreaRequest) IncludeNext() bool { return false }
+
+// Query returns a GraphQL query string and variables.
+func (r AreaRequest) Query(ctx context.Context) (string, map[string]interface{}) {
+	responseKey := r.ResponseKey()
+	var id int
+	entityWhere := hw{}
+	if responseKey == "stops" {
+		id = entityKeyWhere(r.StopKey, "stop_id", entityWhere)
+	} else {
+		id = feedVersionKeyWhere(r.FeedVersionKey, entityWhere)
+	}
+	where := hw{}
+	if r.AreaID != "" {
+		where["area_id"] = r.AreaID
+	}
+	return areaQuery, hw{
+		"include_feed_versions": responseKey == "feed_versions",
+		"include_stops":         responseKey == "stops",
+		"ids":                   checkIds(id),
+		"limit":                 r.CheckLimit(),
+		"feed_version_where":    entityWhere,
+		"stop_where":            entityWhere,
+		"where":                 where,
+	}
+}
@@ -0,0 +1,34 @@
+package rest
+
+import (
+	"testing"
+)
+
+func TestAreaRequest(t *testing.T) {
+	ctranFlexSha1 := "e8bc76c3c8602cad745f41a49ed5c5627ad6904c"
+	testcases := []testCase{
+		{
+			name:         "feed version by sha1",
+			h:            AreaRequest{FeedVersionKey: ctranFlexSha1},
+			selector:     "feed_versions.#.sha1",
+			expectSelect: []string{ctranFlexSha1},
+		},
+		{
+			name:         "feed version areas",
+			h:            AreaRequest{FeedVersionKey: ctranFlexSha1},
+			selector:     "feed_versions.0.areas.#.area_id",
+			expectSelect: []string{},
+		},
+		{
+			name:         "stop by feed key",
+			h:            AreaRequest{StopKey: "BART:MONT"},
+			selector:     "stops.0.areas.#.area_id",
+			expectSelect: []string{},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			checkTestCase(t, tc)
+		})
+	}
+}
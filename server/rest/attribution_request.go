@@ -0,0 +1,59 @@
+package rest
+
+import (
+	"context"
+	_ "embed"
+
+	oa "github.com/getkin/kin-openapi/openapi3"
+)
+
+//go:embed attribution_request.gql
+var attributionQuery string
+
+// AttributionRequest holds options for a /feed_versions/{feed_version_key}/attributions request
+type AttributionRequest struct {
+	FeedVersionKey string `json:"feed_version_key"`
+	WithCursor
+}
+
+func (r AttributionRequest) RequestInfo() RequestInfo {
+	return RequestInfo{
+		Path: "/feed_versions/{feed_version_key}/attributions",
+		Get: &RequestOperation{
+			Query: attributionQuery,
+			Operation: &oa.Operation{
+				Summary:     `Attributions`,
+				Description: `Organizations credited in attributions.txt for a feed version, and the agency, route or trip each attribution applies to.`,
+				Parameters: oa.Parameters{
+					&pref{Value: &param{
+						Name:        "feed_version_key",
+						In:          "path",
+						Required:    true,
+						Description: `Feed version lookup key; can be an integer ID or a SHA1 value`,
+						Schema:      newSRVal("string", "", nil),
+					}},
+					newPRef("limitParam"),
+				},
+			},
+		},
+	}
+}
+
+// ResponseKey returns the GraphQL response entity key.
+func (r AttributionRequest) ResponseKey() string {
+	return "feed_versions"
+}
+
+// IncludeNext
+func (r AttributionRequest) IncludeNext() bool { return false }
+
+// Query returns a GraphQL query string and variables.
+func (r AttributionRequest) Query(ctx context.Context) (string, map[string]interface{}) {
+	where := hw{}
+	id := feedVersionKeyWhere(r.FeedVersionKey, where)
+	return attributionQuery, hw{
+		"ids":                checkIds(id),
+		"limit":              r.CheckLimit(),
+		"feed_version_where": where,
+	}
+}
@@ -0,0 +1,78 @@
+package rest
+
+import (
+	"testing"
+
+	"github.com/interline-io/transitland-lib/internal/testconfig"
+	"github.com/interline-io/transitland-lib/server/model"
+	"github.com/interline-io/transitland-lib/testdata"
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+func TestAttributionRequest(t *testing.T) {
+	bartSha1 := "e535eb2b3b9ac3ef15d82c56575e914575e732e0"
+	testcases := []testCase{
+		{
+			name:         "feed version by sha1",
+			h:            AttributionRequest{FeedVersionKey: bartSha1},
+			selector:     "feed_versions.#.sha1",
+			expectSelect: []string{bartSha1},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			checkTestCase(t, tc)
+		})
+	}
+}
+
+func TestAttributionRequest_Feed(t *testing.T) {
+	testconfig.ConfigTxRollback(t, testconfig.Options{AllowAll: true}, func(cfg model.Config) {
+		fv := testconfig.ImportFeed(t, cfg, "test", testdata.Path("server/gtfs/networks"))
+		graphqlHandler, _, _ := testHandlersWithConfig(t, cfg)
+		testcases := []testCase{
+			{
+				name:         "attributions",
+				h:            AttributionRequest{FeedVersionKey: fv.SHA1},
+				selector:     "feed_versions.0.attributions.#.attribution_id",
+				expectSelect: []string{"producer", "operator", "authority", "trip"},
+			},
+			{
+				name:         "organization names",
+				h:            AttributionRequest{FeedVersionKey: fv.SHA1},
+				selector:     "feed_versions.0.attributions.#.organization_name",
+				expectSelect: []string{"Harbor Data Cooperative", "Harbor Transit Operations", "Harbor Transit Authority", "Harbor Ferry Services"},
+			},
+			{
+				name: "roles and contacts",
+				h:    AttributionRequest{FeedVersionKey: fv.SHA1},
+				f: func(t *testing.T, jj string) {
+					a := gjson.Get(jj, `feed_versions.0.attributions.#(attribution_id=="producer")`)
+					assert.True(t, a.Get("is_producer").Bool())
+					assert.False(t, a.Get("is_operator").Bool())
+					assert.Equal(t, "http://data.example.com", a.Get("attribution_url").String())
+					assert.Equal(t, "data@example.com", a.Get("attribution_email").String())
+					b := gjson.Get(jj, `feed_versions.0.attributions.#(attribution_id=="authority")`)
+					assert.True(t, b.Get("is_authority").Bool())
+					assert.Equal(t, "555-555-5555", b.Get("attribution_phone").String())
+				},
+			},
+			{
+				name: "attributed entities",
+				h:    AttributionRequest{FeedVersionKey: fv.SHA1},
+				f: func(t *testing.T, jj string) {
+					assert.Equal(t, "TM", gjson.Get(jj, `feed_versions.0.attributions.#(attribution_id=="operator").agency.agency_id`).String())
+					assert.Equal(t, "r1", gjson.Get(jj, `feed_versions.0.attributions.#(attribution_id=="authority").route.route_id`).String())
+					assert.Equal(t, "t3", gjson.Get(jj, `feed_versions.0.attributions.#(attribution_id=="trip").trip.trip_id`).String())
+					assert.False(t, gjson.Get(jj, `feed_versions.0.attributions.#(attribution_id=="producer").agency.agency_id`).Exists())
+				},
+			},
+		}
+		for _, tc := range testcases {
+			t.Run(tc.name, func(t *testing.T) {
+				checkTestCaseWithHandler(t, graphqlHandler, tc)
+			})
+		}
+	})
+}
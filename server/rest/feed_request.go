@@ -276,7 +276,7 @@ type FeedDownloadRtRequest struct {
 func (r FeedDownloadRtRequest) RequestInfo() RequestInfo {
 	return RequestInfo{
 		Path:        "/feeds/{feed_key}/download_latest_rt/{rt_type}.{format}",
-		Description: `Download the latest snapshot of the specified GTFS Realtime feed, if redistribution is allowed by the source feed's license. Returns 404 if feed or message not found, 401 if redistribution not allowed.`,
+		Description: `Download the latest snapshot of the specified GTFS Realtime feed, if redistribution is allowed by the source feed's license. Entities can be filtered by agency, route, trip, stop, or bounding box; agency, route, and bounding box filters use the static feeds of the operators associated with the realtime feed. Realtime data merged from several feeds is available under the merge target's key. Returns 404 if feed or message not found, 401 if redistribution not allowed.`,
 		Get: &RequestOperation{
 			Operation: &oa.Operation{
 				Summary: "Download latest GTFS Realtime feed data",
@@ -302,6 +302,45 @@ func (r FeedDownloadRtRequest) RequestInfo() RequestInfo {
 						Description: `Output format (JSON, Protocol Buffers, or GeoJSON for vehicle positions)`,
 						Schema:      newSRVal("string", "", []any{"json", "pb", "geojson", "geojsonl"}),
 					}},
+					&pref{Value: &param{
+						Name:        "agency_id",
+						In:          "query",
+						Description: `Only include entities for these GTFS agency_ids. Accepts comma separated values.`,
+						Schema:      newSRVal("string", "", nil),
+						Extensions:  newExt("", "agency_id=BART", ""),
+					}},
+					&pref{Value: &param{
+						Name:        "route_id",
+						In:          "query",
+						Description: `Only include entities for these GTFS route_ids. Accepts comma separated values.`,
+						Schema:      newSRVal("string", "", nil),
+						Extensions:  newExt("", "route_id=01,03", ""),
+					}},
+					&pref{Value: &param{
+						Name:        "trip_id",
+						In:          "query",
+						Description: `Only include entities for these GTFS trip_ids. Accepts comma separated values.`,
+						Schema:      newSRVal("string", "", nil),
+					}},
+					&pref{Value: &param{
+						Name:        "stop_id",
+						In:          "query",
+						Description: `Only include entities for these GTFS stop_ids. Accepts comma separated values.`,
+						Schema:      newSRVal("string", "", nil),
+					}},
+					newPRefExt("bboxParam", "", "bbox=-122.269,37.807,-122.267,37.808", ""),
+					&pref{Value: &param{
+						Name:        "incrementality",
+						In:          "query",
+						Description: `Feed incrementality. DIFFERENTIAL returns only entities updated after 'since', with entities removed or no longer matching the filter marked is_deleted. If removals since 'since' are not known, the full dataset is returned.`,
+						Schema:      newSRVal("string", "", []any{"full_dataset", "differential"}),
+					}},
+					&pref{Value: &param{
+						Name:        "since",
+						In:          "query",
+						Description: `POSIX timestamp (seconds) for DIFFERENTIAL incrementality`,
+						Schema:      newSRVal("integer", "", nil),
+					}},
 				},
 				Responses: oa.NewResponses(
 					oa.WithStatus(200, &oa.ResponseRef{
@@ -1,11 +1,14 @@
 package rest
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/interline-io/log"
@@ -14,7 +17,9 @@ import (
 	"github.com/interline-io/transitland-lib/rt"
 	"github.com/interline-io/transitland-lib/server/meters"
 	"github.com/interline-io/transitland-lib/server/model"
+	"github.com/interline-io/transitland-lib/tlxy"
 	"github.com/tidwall/gjson"
+	"github.com/tidwall/tinylru"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 )
@@ -83,6 +88,31 @@ func feedDownloadRtHelper(graphqlHandler http.Handler, w http.ResponseWriter, r
 		return
 	}
 
+	// Apply filters
+	if filter, ok, err := parseRtFilter(r); err != nil {
+		util.WriteJsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if ok {
+		if err := loadRtFilterStaticData(ctx, graphqlHandler, gvars, &filter); errors.Is(err, errRtFilterStaticLimit) {
+			util.WriteJsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			log.For(ctx).Error().Err(err).Msg("failed to load static data for realtime filter")
+			util.WriteJsonError(w, "server error", http.StatusInternalServerError)
+			return
+		}
+		matchKey := rtFilterMatchKey(key, rtType, r)
+		if filter.Differential {
+			filter.Removed, filter.RemovedSince, _ = rtf.GetRemovedEntities(ctx, key, rtType)
+			if v, ok := rtFilterMatches.Get(fmt.Sprintf("%s@%d", matchKey, filter.Since)); ok {
+				filter.Matched = v.(map[string]bool)
+			}
+		}
+		// Record the entities matched at this message, for later differentials of the same filter
+		rtFilterMatches.Set(fmt.Sprintf("%s@%d", matchKey, rtMsg.GetHeader().GetTimestamp()), rt.MatchedEntityIDs(rtMsg, filter))
+		rtMsg = rt.FilterFeedMessage(rtMsg, filter)
+	}
+
 	var data []byte
 	var marshalErr error
 	switch format {
@@ -302,3 +332,210 @@ func serveFromStorage(w http.ResponseWriter, r *http.Request, storage string, fv
 	}
 	return nil
 }
+
+// parseRtFilter returns the realtime filter from the request query parameters,
+// and false if no filter parameters were provided.
+func parseRtFilter(r *http.Request) (rt.FeedMessageFilter, bool, error) {
+	q := r.URL.Query()
+	filter := rt.FeedMessageFilter{
+		AgencyIDs: commaSplit(q.Get("agency_id")),
+		RouteIDs:  commaSplit(q.Get("route_id")),
+		TripIDs:   commaSplit(q.Get("trip_id")),
+		StopIDs:   commaSplit(q.Get("stop_id")),
+	}
+	if v := q.Get("bbox"); v != "" {
+		bbox := restBbox{}
+		if err := bbox.UnmarshalText([]byte(v)); err != nil {
+			return filter, false, errors.New("invalid bbox")
+		}
+		filter.Bbox = &tlxy.BoundingBox{MinLon: bbox.MinLon, MinLat: bbox.MinLat, MaxLon: bbox.MaxLon, MaxLat: bbox.MaxLat}
+	}
+	switch strings.ToLower(q.Get("incrementality")) {
+	case "", "full_dataset":
+	case "differential":
+		filter.Differential = true
+	default:
+		return filter, false, errors.New("invalid incrementality")
+	}
+	if v := q.Get("since"); v != "" {
+		since, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return filter, false, errors.New("invalid since")
+		}
+		filter.Since = since
+	}
+	ok := len(filter.AgencyIDs) > 0 ||
+		len(filter.RouteIDs) > 0 ||
+		len(filter.TripIDs) > 0 ||
+		len(filter.StopIDs) > 0 ||
+		filter.Bbox != nil ||
+		filter.Differential
+	return filter, ok, nil
+}
+
+// rtFilterMatches holds the entity ids matched by a realtime filter, by filter and message timestamp.
+// A differential only reports an entity that no longer matches as deleted if it matched at since.
+var rtFilterMatches = &tinylru.LRU{}
+
+// rtFilterMatchKey identifies a realtime filter, excluding the differential parameters.
+func rtFilterMatchKey(feedKey string, rtType string, r *http.Request) string {
+	q := r.URL.Query()
+	q.Del("incrementality")
+	q.Del("since")
+	return fmt.Sprintf("%s/%s?%s", feedKey, rtType, q.Encode())
+}
+
+// Limits on the static data loaded for a realtime filter. These are the
+// resolver maximums; nested results cannot be paged, so a filter that reaches
+// one is refused rather than applied to a partial set.
+var (
+	rtFilterRouteLimit = 1_000
+	rtFilterTripLimit  = 100_000
+	rtFilterStopLimit  = 1_000
+)
+
+var errRtFilterStaticLimit = errors.New("too many routes, trips, or stops to apply this realtime filter")
+
+// rtFilterStaticCache holds the rtFilterRoutes of recently filtered feed versions, by sha1.
+var rtFilterStaticCache = &tinylru.LRU{}
+
+// rtFilterRoute is the static data for one route used to apply a realtime filter.
+type rtFilterRoute struct {
+	AgencyID string
+	TripIDs  []string
+	Stops    map[string]tlxy.Point
+}
+
+// rtFilterRoutes maps route_id to the route's static data.
+type rtFilterRoutes map[string]rtFilterRoute
+
+const rtFilterAgenciesQuery = `
+query($feed_onestop_id: String, $ids: [Int!]) {
+	feeds(ids: $ids, where: { onestop_id: $feed_onestop_id }) {
+	  associated_operators {
+		agencies {
+		  agency_id
+		  feed_version_sha1
+		}
+	  }
+	}
+  }
+`
+
+const rtFilterStaticQuery = `
+query($sha1: String!, $route_limit: Int!, $trip_limit: Int!, $stop_limit: Int!) {
+	feed_versions(where: { sha1: $sha1 }) {
+	  routes(limit: $route_limit) {
+		route_id
+		agency {
+		  agency_id
+		}
+		trips(limit: $trip_limit) {
+		  trip_id
+		}
+		stops(limit: $stop_limit) {
+		  stop_id
+		  geometry
+		}
+	  }
+	}
+  }
+`
+
+// loadRtFilterStaticData resolves routes, agencies, and stop locations from the
+// static feeds of the operators associated with the realtime feed.
+// It returns errRtFilterStaticLimit if any part of the result may be truncated.
+func loadRtFilterStaticData(ctx context.Context, graphqlHandler http.Handler, feedVars hw, filter *rt.FeedMessageFilter) error {
+	withTrips := len(filter.AgencyIDs) > 0 || len(filter.RouteIDs) > 0
+	withStops := filter.Bbox != nil
+	if !withTrips && !withStops {
+		return nil
+	}
+	response, err := makeGraphQLRequest(ctx, graphqlHandler, rtFilterAgenciesQuery, feedVars)
+	if err != nil {
+		return err
+	}
+	jj, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	// Associated agencies, by feed version
+	var fvsha1s []string
+	fvAgencies := map[string]map[string]bool{}
+	for _, agency := range gjson.GetBytes(jj, "feeds.0.associated_operators.#.agencies|@flatten").Array() {
+		fvsha1 := agency.Get("feed_version_sha1").String()
+		if fvAgencies[fvsha1] == nil {
+			fvsha1s = append(fvsha1s, fvsha1)
+			fvAgencies[fvsha1] = map[string]bool{}
+		}
+		fvAgencies[fvsha1][agency.Get("agency_id").String()] = true
+	}
+	filter.TripRoutes = map[string]string{}
+	filter.RouteAgencies = map[string]string{}
+	filter.StopLocations = map[string]tlxy.Point{}
+	for _, fvsha1 := range fvsha1s {
+		routes, err := rtFilterFeedVersionRoutes(ctx, graphqlHandler, fvsha1)
+		if err != nil {
+			return err
+		}
+		for routeID, route := range routes {
+			if !fvAgencies[fvsha1][route.AgencyID] {
+				continue
+			}
+			filter.RouteAgencies[routeID] = route.AgencyID
+			for _, tripID := range route.TripIDs {
+				filter.TripRoutes[tripID] = routeID
+			}
+			for stopID, pt := range route.Stops {
+				filter.StopLocations[stopID] = pt
+			}
+		}
+	}
+	return nil
+}
+
+// rtFilterFeedVersionRoutes returns the routes of a feed version, from rtFilterStaticCache if available.
+func rtFilterFeedVersionRoutes(ctx context.Context, graphqlHandler http.Handler, fvsha1 string) (rtFilterRoutes, error) {
+	if v, ok := rtFilterStaticCache.Get(fvsha1); ok {
+		return v.(rtFilterRoutes), nil
+	}
+	gvars := hw{
+		"sha1":        fvsha1,
+		"route_limit": rtFilterRouteLimit,
+		"trip_limit":  rtFilterTripLimit,
+		"stop_limit":  rtFilterStopLimit,
+	}
+	response, err := makeGraphQLRequest(ctx, graphqlHandler, rtFilterStaticQuery, gvars)
+	if err != nil {
+		return nil, err
+	}
+	jj, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+	ret := rtFilterRoutes{}
+	routes := gjson.GetBytes(jj, "feed_versions.0.routes").Array()
+	if len(routes) >= rtFilterRouteLimit {
+		return nil, errRtFilterStaticLimit
+	}
+	for _, route := range routes {
+		trips := route.Get("trips").Array()
+		stops := route.Get("stops").Array()
+		if len(trips) >= rtFilterTripLimit || len(stops) >= rtFilterStopLimit {
+			return nil, errRtFilterStaticLimit
+		}
+		ent := rtFilterRoute{AgencyID: route.Get("agency.agency_id").String(), Stops: map[string]tlxy.Point{}}
+		for _, trip := range trips {
+			ent.TripIDs = append(ent.TripIDs, trip.Get("trip_id").String())
+		}
+		for _, stop := range stops {
+			coords := stop.Get("geometry.coordinates").Array()
+			if len(coords) == 2 {
+				ent.Stops[stop.Get("stop_id").String()] = tlxy.Point{Lon: coords[0].Float(), Lat: coords[1].Float()}
+			}
+		}
+		ret[route.Get("route_id").String()] = ent
+	}
+	rtFilterStaticCache.Set(fvsha1, ret)
+	return ret, nil
+}
@@ -2,6 +2,7 @@ package rest
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -14,6 +15,7 @@ import (
 	"github.com/interline-io/transitland-lib/testdata"
 	"github.com/stretchr/testify/assert"
 	"github.com/tidwall/gjson"
+	"github.com/tidwall/tinylru"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -427,3 +429,100 @@ func TestFeedDownloadRtVehiclePositions(t *testing.T) {
 	})
 
 }
+
+func TestFeedDownloadRtFiltered(t *testing.T) {
+	_, restSrv, _ := testHandlersWithOptions(t, testconfig.Options{
+		Storage: testdata.Path("server", "tmp"),
+		RTJsons: []testconfig.RTJsonFile{
+			{Feed: "BA~rt", Ftype: "realtime_trip_updates", Fname: "BA.json"},
+			{Feed: "CT~rt", Ftype: "realtime_vehicle_positions", Fname: "ct-vehicle-positions.pb.json"},
+		},
+	})
+	getMsg := func(t *testing.T, url string, expectCode int) *pb.FeedMessage {
+		req, _ := http.NewRequest("GET", url, nil)
+		rr := httptest.NewRecorder()
+		asAdmin := usercheck.AdminDefaultMiddleware("test")(restSrv)
+		asAdmin.ServeHTTP(rr, req)
+		assert.Equal(t, expectCode, rr.Result().StatusCode, "status code")
+		if expectCode != 200 {
+			return nil
+		}
+		var msg pb.FeedMessage
+		if err := proto.Unmarshal(rr.Body.Bytes(), &msg); err != nil {
+			t.Fatal(err)
+		}
+		return &msg
+	}
+	entityIDs := func(msg *pb.FeedMessage) []string {
+		var ret []string
+		for _, ent := range msg.Entity {
+			ret = append(ret, ent.GetId())
+		}
+		return ret
+	}
+	t.Run("trip_id", func(t *testing.T) {
+		msg := getMsg(t, "/feeds/BA~rt/download_latest_rt/trip_updates.pb?trip_id=1011630WKDY", 200)
+		assert.Equal(t, []string{"1011630WKDY"}, entityIDs(msg))
+		assert.Equal(t, pb.FeedHeader_FULL_DATASET, msg.Header.GetIncrementality())
+	})
+	t.Run("stop_id", func(t *testing.T) {
+		msg := getMsg(t, "/feeds/BA~rt/download_latest_rt/trip_updates.pb?stop_id=WARM", 200)
+		assert.Contains(t, entityIDs(msg), "1011630WKDY")
+		assert.Less(t, len(msg.Entity), 48)
+	})
+	t.Run("route_id", func(t *testing.T) {
+		msg := getMsg(t, "/feeds/CT~rt/download_latest_rt/vehicle_positions.pb?route_id=L3,B7", 200)
+		assert.ElementsMatch(t, []string{"308", "310", "311", "312", "709", "710"}, entityIDs(msg))
+	})
+	t.Run("bbox", func(t *testing.T) {
+		msg := getMsg(t, "/feeds/CT~rt/download_latest_rt/vehicle_positions.pb?bbox=-122.40,37.77,-122.39,37.78", 200)
+		assert.ElementsMatch(t, []string{"312", "412", "414", "710"}, entityIDs(msg))
+	})
+	t.Run("differential before first message", func(t *testing.T) {
+		// Removals before the first message seen are unknown
+		full := getMsg(t, "/feeds/CT~rt/download_latest_rt/vehicle_positions.pb", 200)
+		msg := getMsg(t, "/feeds/CT~rt/download_latest_rt/vehicle_positions.pb?incrementality=differential&since=1699405548", 200)
+		assert.Equal(t, pb.FeedHeader_FULL_DATASET, msg.Header.GetIncrementality())
+		assert.Equal(t, len(full.Entity), len(msg.Entity))
+	})
+	t.Run("differential no changes", func(t *testing.T) {
+		msg := getMsg(t, "/feeds/CT~rt/download_latest_rt/vehicle_positions.pb?incrementality=differential&since=1699405559", 200)
+		assert.Equal(t, pb.FeedHeader_DIFFERENTIAL, msg.Header.GetIncrementality())
+		assert.Equal(t, 0, len(msg.Entity))
+	})
+	t.Run("differential with filter", func(t *testing.T) {
+		rtFilterMatches = &tinylru.LRU{}
+		url := "/feeds/CT~rt/download_latest_rt/vehicle_positions.pb?route_id=L3,B7"
+		// No entities are known to have matched before the first request
+		msg := getMsg(t, url+"&incrementality=differential&since=1699405559", 200)
+		assert.Equal(t, pb.FeedHeader_FULL_DATASET, msg.Header.GetIncrementality())
+		assert.ElementsMatch(t, []string{"308", "310", "311", "312", "709", "710"}, entityIDs(msg))
+		// Entities that never matched are not reported as deleted
+		msg = getMsg(t, fmt.Sprintf("%s&incrementality=differential&since=%d", url, msg.Header.GetTimestamp()), 200)
+		assert.Equal(t, pb.FeedHeader_DIFFERENTIAL, msg.Header.GetIncrementality())
+		assert.Equal(t, 0, len(msg.Entity))
+	})
+	t.Run("static data is cached per feed version", func(t *testing.T) {
+		rtFilterStaticCache = &tinylru.LRU{}
+		getMsg(t, "/feeds/BA~rt/download_latest_rt/trip_updates.pb?route_id=01", 200)
+		assert.Equal(t, 1, rtFilterStaticCache.Len())
+		prev := rtFilterTripLimit
+		rtFilterTripLimit = 10
+		defer func() { rtFilterTripLimit = prev }()
+		getMsg(t, "/feeds/BA~rt/download_latest_rt/trip_updates.pb?route_id=01", 200)
+	})
+	t.Run("static data limit", func(t *testing.T) {
+		rtFilterStaticCache = &tinylru.LRU{}
+		prev := rtFilterTripLimit
+		rtFilterTripLimit = 10
+		defer func() { rtFilterTripLimit = prev }()
+		getMsg(t, "/feeds/BA~rt/download_latest_rt/trip_updates.pb?route_id=01", 400)
+		assert.Equal(t, 0, rtFilterStaticCache.Len())
+	})
+	t.Run("invalid incrementality", func(t *testing.T) {
+		getMsg(t, "/feeds/CT~rt/download_latest_rt/vehicle_positions.pb?incrementality=asd", 400)
+	})
+	t.Run("invalid since", func(t *testing.T) {
+		getMsg(t, "/feeds/CT~rt/download_latest_rt/vehicle_positions.pb?incrementality=differential&since=asd", 400)
+	})
+}
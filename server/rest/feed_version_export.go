@@ -3,11 +3,13 @@ package rest
 // Feed Version Export Handler
 //
 // This handler provides synchronous export of feed versions with optional transformations.
-// The export is streamed directly to the client as a ZIP file.
+// The export is streamed directly to the client as a ZIP file, containing either GTFS CSV files
+// or one Parquet table per GTFS file.
 
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -18,8 +20,9 @@ import (
 	"github.com/interline-io/log"
 	"github.com/interline-io/transitland-lib/adapters"
 	"github.com/interline-io/transitland-lib/internal/util"
+	"github.com/interline-io/transitland-lib/server/auth/authn"
+	"github.com/interline-io/transitland-lib/server/jobs"
 	"github.com/interline-io/transitland-lib/server/model"
-	"github.com/interline-io/transitland-lib/tlcsv"
 	"github.com/tidwall/gjson"
 )
 
@@ -28,10 +31,13 @@ type FeedVersionExportRequest struct {
 	// Feed version IDs or SHA1 hashes to export
 	FeedVersionKeys []string `json:"feed_version_keys"`
 	FeedVersionIDs  []int    `json:"-"`
-	// Output format (default: gtfs_zip)
+	// Output format: gtfs_zip or parquet (default: gtfs_zip)
 	Format string `json:"format,omitempty"`
 	// Transformation options
 	Transforms *ExportTransforms `json:"transforms,omitempty"`
+	// Run as a background job; the response is the queued job status and the
+	// zip is published as a job artifact
+	Async bool `json:"async,omitempty"`
 }
 
 // ExportTransforms defines available transformations
@@ -60,7 +66,7 @@ type FeedVersionExportOpenAPIRequest struct{}
 func (r FeedVersionExportOpenAPIRequest) RequestInfo() RequestInfo {
 	return RequestInfo{
 		Path:        "/feed_versions/export",
-		Description: `Export one or more feed versions as a GTFS zip file with optional transformations (ID prefixing, timezone normalization, shape simplification, etc.). Feed versions must be successfully imported before they can be exported. Available only using Transitland professional or enterprise plan API keys.`,
+		Description: `Export one or more feed versions as a GTFS zip file, or a zip of Parquet tables, with optional transformations (ID prefixing, timezone normalization, shape simplification, etc.). Feed versions must be successfully imported before they can be exported. Available only using Transitland professional or enterprise plan API keys.`,
 		Post: &RequestOperation{
 			Operation: &oa.Operation{
 				Summary: "Export feed versions with transformations",
@@ -91,9 +97,15 @@ func (r FeedVersionExportOpenAPIRequest) RequestInfo() RequestInfo {
 											"format": &oa.SchemaRef{
 												Value: &oa.Schema{
 													Type:        &oa.Types{"string"},
-													Description: "Output format (default: gtfs_zip)",
+													Description: "Output format (default: gtfs_zip). 'parquet' returns a zip with one Parquet table per GTFS file, with GeoParquet geometry columns for stops, shapes, and locations.",
 													Default:     "gtfs_zip",
-													Enum:        []any{"gtfs_zip"},
+													Enum:        []any{"gtfs_zip", "parquet"},
+												},
+											},
+											"async": &oa.SchemaRef{
+												Value: &oa.Schema{
+													Type:        &oa.Types{"boolean"},
+													Description: "Run the export as a background job (default: false). Responds 202 with the queued job status; the zip is published as an artifact of the job in the `feed-version-export` queue.",
 												},
 											},
 											"transforms": &oa.SchemaRef{
@@ -199,7 +211,7 @@ func (r FeedVersionExportOpenAPIRequest) RequestInfo() RequestInfo {
 				Responses: oa.NewResponses(
 					oa.WithStatus(200, &oa.ResponseRef{
 						Value: &oa.Response{
-							Description: toPtr("Successful export - returns GTFS zip file"),
+							Description: toPtr("Successful export - returns GTFS zip file, or zip of Parquet files"),
 							Content: oa.Content{
 								"application/zip": &oa.MediaType{
 									Schema: &oa.SchemaRef{
@@ -212,6 +224,20 @@ func (r FeedVersionExportOpenAPIRequest) RequestInfo() RequestInfo {
 							},
 						},
 					}),
+					oa.WithStatus(202, &oa.ResponseRef{
+						Value: &oa.Response{
+							Description: toPtr("Accepted - export queued as a background job (async requests); returns the job status"),
+							Content: oa.Content{
+								"application/json": &oa.MediaType{
+									Schema: &oa.SchemaRef{
+										Value: &oa.Schema{
+											Type: &oa.Types{"object"},
+										},
+									},
+								},
+							},
+						},
+					}),
 					oa.WithStatus(400, &oa.ResponseRef{
 						Value: &oa.Response{
 							Description: toPtr("Bad request - invalid parameters or feed version not imported"),
@@ -252,7 +278,13 @@ func feedVersionExportHandler(graphqlHandler http.Handler, w http.ResponseWriter
 		return
 	}
 
-	// Create CSV writer that writes to ZIP temporary file
+	// Queue as a background job instead of streaming the zip
+	if req.Async {
+		submitFeedVersionExportJob(w, r, req)
+		return
+	}
+
+	// Create writer that writes to ZIP temporary file
 	tmpFilename := ""
 	if tmpfile, err := os.CreateTemp("", "*-export.zip"); err != nil {
 		log.For(ctx).Error().Err(err).Msg("failed to create temp file for zip")
@@ -264,18 +296,18 @@ func feedVersionExportHandler(graphqlHandler http.Handler, w http.ResponseWriter
 		defer os.Remove(tmpFilename)
 	}
 
-	// Create CSV writer for ZIP output
-	csvWriter, err := tlcsv.NewWriter(tmpFilename)
+	// Create writer for ZIP output
+	writer, err := NewExportWriter(req.Format, tmpFilename)
 	if err != nil {
-		log.For(ctx).Error().Err(err).Msg("failed to create CSV writer")
-		util.WriteJsonError(w, "failed to create CSV writer", http.StatusInternalServerError)
+		log.For(ctx).Error().Err(err).Str("format", req.Format).Msg("failed to create writer")
+		util.WriteJsonError(w, "failed to create writer", http.StatusInternalServerError)
 		return
 	}
 
 	// DO THE THING
 	cfg := model.ForContext(ctx)
 	exporter := NewFeedVersionExporter(&cfg)
-	cpResult, err := exporter.Export(ctx, req.FeedVersionIDs, req.Transforms, csvWriter)
+	cpResult, err := exporter.Export(ctx, req.FeedVersionIDs, req.Transforms, writer)
 	if err != nil {
 		util.WriteJsonError(w, "export operation failed", http.StatusInternalServerError)
 		return
@@ -283,16 +315,11 @@ func feedVersionExportHandler(graphqlHandler http.Handler, w http.ResponseWriter
 	_ = cpResult
 
 	// Apply sorting if requested
-	if req.Transforms != nil && req.Transforms.StandardizedSort != "" {
-		csvWriter.SetStandardizedSortOptions(adapters.StandardizedSortOptions{
-			ApplySort:   req.Transforms.StandardizedSort,
-			SortColumns: req.Transforms.StandardizedSortColumns,
-		})
-	}
+	setExportSort(writer, req.Transforms)
 
-	if err := csvWriter.Close(); err != nil {
-		log.For(ctx).Error().Err(err).Msg("failed to close CSV writer")
-		util.WriteJsonError(w, "failed to close CSV writer", http.StatusInternalServerError)
+	if err := writer.Close(); err != nil {
+		log.For(ctx).Error().Err(err).Msg("failed to close writer")
+		util.WriteJsonError(w, "failed to close writer", http.StatusInternalServerError)
 		return
 	}
 
@@ -361,9 +388,54 @@ func CheckFeedVersionExportRequest(ctx context.Context, req *FeedVersionExportRe
 		FeedVersionIDs: fvids,
 		Transforms:     req.Transforms,
 		Format:         req.Format,
+		Async:          req.Async,
 	}, nil
 }
 
+// feedVersionExportJobKind matches the Kind() of the worker that runs
+// background exports (server/workers.FeedVersionExportWorker).
+const feedVersionExportJobKind = "feed-version-export"
+
+// submitFeedVersionExportJob queues a checked export request and responds with
+// 202 and the job status. The job ID is used to watch the job and download the
+// zip from its artifacts through the job server.
+func submitFeedVersionExportJob(w http.ResponseWriter, r *http.Request, req *FeedVersionExportRequest) {
+	ctx := r.Context()
+	cfg := model.ForContext(ctx)
+	if cfg.Jobs == nil {
+		util.WriteJsonError(w, "background jobs not available", http.StatusNotImplemented)
+		return
+	}
+	q, err := cfg.Jobs.Queue(feedVersionExportJobKind)
+	if err != nil {
+		util.WriteJsonError(w, "background jobs not available", http.StatusNotImplemented)
+		return
+	}
+	job := jobs.Job{
+		Kind: feedVersionExportJobKind,
+		Args: jobs.Args{
+			"feed_version_ids": req.FeedVersionIDs,
+			"format":           req.Format,
+			"transforms":       req.Transforms,
+		},
+	}
+	if user := authn.ForContext(ctx); user != nil {
+		job.Opts.UserID = user.ID()
+	}
+	st, err := q.Submit(ctx, job)
+	if errors.Is(err, jobs.ErrJobAccessDenied) {
+		util.WriteJsonError(w, "forbidden", http.StatusForbidden)
+		return
+	} else if err != nil {
+		log.For(ctx).Error().Err(err).Msg("failed to submit export job")
+		util.WriteJsonError(w, "failed to submit export job", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(st)
+}
+
 // validateExportRequest performs basic request validation
 func validateExportRequest(req *FeedVersionExportRequest) error {
 	if len(req.FeedVersionKeys) == 0 {
@@ -375,15 +447,17 @@ func validateExportRequest(req *FeedVersionExportRequest) error {
 		req.Format = "gtfs_zip"
 	}
 
-	// Only support gtfs_zip for now
-	if req.Format != "gtfs_zip" {
-		return util.NewBadRequestError("only 'gtfs_zip' format is currently supported", nil)
+	if req.Format != "gtfs_zip" && req.Format != "parquet" {
+		return util.NewBadRequestError("format must be 'gtfs_zip' or 'parquet'", nil)
 	}
 
 	if req.Transforms != nil {
 		if err := adapters.ValidateSortDirection(req.Transforms.StandardizedSort); err != nil {
 			return util.NewBadRequestError(fmt.Sprintf("standardized_sort: %s", err), nil)
 		}
+		if req.Transforms.StandardizedSort != "" && req.Format != "gtfs_zip" {
+			return util.NewBadRequestError("standardized_sort is only supported for 'gtfs_zip' format", nil)
+		}
 	}
 
 	return nil
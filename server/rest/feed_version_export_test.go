@@ -19,6 +19,7 @@ import (
 	"github.com/interline-io/transitland-lib/server/auth/authn"
 	"github.com/interline-io/transitland-lib/server/auth/mw/usercheck"
 	"github.com/interline-io/transitland-lib/server/dbutil"
+	"github.com/interline-io/transitland-lib/server/jobs"
 	"github.com/interline-io/transitland-lib/testdata"
 	"github.com/interline-io/transitland-lib/tlcsv"
 	"github.com/stretchr/testify/assert"
@@ -429,6 +430,124 @@ func TestFeedVersionExportRequest(t *testing.T) {
 		assert.Contains(t, rr.Body.String(), "invalid sort direction", "error message")
 	})
 
+	t.Run("bad request - unsupported format", func(t *testing.T) {
+		reqBody := FeedVersionExportRequest{
+			FeedVersionKeys: []string{caltrainFv},
+			Format:          "shapefile",
+		}
+		rr := makeExportRequest(t, reqBody, asAdmin)
+		assert.Equal(t, 400, rr.Result().StatusCode, "should be bad request")
+		assert.Contains(t, rr.Body.String(), "format", "error message")
+	})
+
+	t.Run("export as parquet", func(t *testing.T) {
+		reqBody := FeedVersionExportRequest{
+			FeedVersionKeys: []string{caltrainFv},
+			Format:          "parquet",
+		}
+		rr := makeExportRequest(t, reqBody, asAdmin)
+		assert.Equal(t, 200, rr.Result().StatusCode, "status code")
+		assert.Equal(t, "application/zip", rr.Header().Get("Content-Type"), "content-type")
+		zipReader, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files := map[string]bool{}
+		for _, f := range zipReader.File {
+			files[f.Name] = true
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.True(t, bytes.HasPrefix(data, []byte("PAR1")), "parquet magic in %s", f.Name)
+		}
+		for _, fn := range []string{"agency.parquet", "routes.parquet", "stops.parquet", "shapes.parquet", "trips.parquet", "stop_times.parquet"} {
+			assert.True(t, files[fn], "should have file %s", fn)
+		}
+	})
+
+	t.Run("bad request - standardized_sort with parquet", func(t *testing.T) {
+		reqBody := FeedVersionExportRequest{
+			FeedVersionKeys: []string{caltrainFv},
+			Format:          "parquet",
+			Transforms: &ExportTransforms{
+				StandardizedSort: "asc",
+			},
+		}
+		rr := makeExportRequest(t, reqBody, asAdmin)
+		assert.Equal(t, 400, rr.Result().StatusCode, "should be bad request")
+		assert.Contains(t, rr.Body.String(), "standardized_sort", "error message")
+	})
+
+	t.Run("async export queues job", func(t *testing.T) {
+		reqBody := FeedVersionExportRequest{
+			FeedVersionKeys: []string{caltrainFv},
+			Format:          "parquet",
+			Async:           true,
+		}
+		rr := makeExportRequest(t, reqBody, asAdmin)
+		assert.Equal(t, 202, rr.Result().StatusCode, "status code")
+		var st jobs.JobStatus
+		if err := json.Unmarshal(rr.Body.Bytes(), &st); err != nil {
+			t.Fatal(err)
+		}
+		assert.NotEmpty(t, st.Job.ID)
+		assert.Equal(t, "feed-version-export", st.Job.Kind)
+		assert.Equal(t, jobs.JobStateQueued, st.State)
+		assert.Equal(t, "test", st.Job.Opts.UserID)
+		assert.Equal(t, "parquet", st.Job.Args["format"])
+		assert.Len(t, st.Job.Args["feed_version_ids"], 1)
+	})
+
+	t.Run("async export still checks request", func(t *testing.T) {
+		reqBody := FeedVersionExportRequest{
+			FeedVersionKeys: []string{bartFv},
+			Async:           true,
+		}
+		rr := makeExportRequest(t, reqBody, asAdmin)
+		assert.Equal(t, 403, rr.Result().StatusCode, "should be rejected before queueing")
+	})
+
+	t.Run("async export without background jobs", func(t *testing.T) {
+		noJobsCfg := cfg
+		noJobsCfg.Jobs = nil
+		_, noJobsSrv, _ := testHandlersWithConfig(t, noJobsCfg)
+		reqBody := FeedVersionExportRequest{
+			FeedVersionKeys: []string{caltrainFv},
+			Async:           true,
+		}
+		rr := makeExportRequest(t, reqBody, usercheck.AdminDefaultMiddleware("test")(noJobsSrv))
+		assert.Equal(t, 501, rr.Result().StatusCode, "status code")
+	})
+
+	t.Run("async export status can be watched", func(t *testing.T) {
+		reqBody := FeedVersionExportRequest{
+			FeedVersionKeys: []string{caltrainFv, hartFv},
+			Async:           true,
+		}
+		rr := makeExportRequest(t, reqBody, asAdmin)
+		assert.Equal(t, 202, rr.Result().StatusCode, "status code")
+		var st jobs.JobStatus
+		if err := json.Unmarshal(rr.Body.Bytes(), &st); err != nil {
+			t.Fatal(err)
+		}
+		q, err := cfg.Jobs.Queue("feed-version-export")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := q.(jobs.StatusQueue).Status(authn.WithUser(context.Background(), authn.NewCtxUser("test", "", "")), st.Job.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, jobs.JobStateQueued, got.State)
+		assert.ElementsMatch(t, []any{float64(fvidBySha1[caltrainFv]), float64(fvidBySha1[hartFv])}, got.Job.Args["feed_version_ids"])
+	})
+
 	t.Run("bad request - feed version not imported", func(t *testing.T) {
 		// This test would need a feed version that exists but hasn't been imported
 		// The test database may not have such a case, so this is a placeholder
@@ -12,8 +12,10 @@ import (
 	"github.com/interline-io/transitland-lib/ext/filters"
 	"github.com/interline-io/transitland-lib/extract"
 	"github.com/interline-io/transitland-lib/server/model"
+	"github.com/interline-io/transitland-lib/tlcsv"
 	"github.com/interline-io/transitland-lib/tldb"
 	"github.com/interline-io/transitland-lib/tldb/postgres"
+	"github.com/interline-io/transitland-lib/tlparquet"
 )
 
 // FeedVersionExporter handles exporting feed versions with transformations
@@ -26,6 +28,46 @@ func NewFeedVersionExporter(cfg *model.Config) *FeedVersionExporter {
 	return &FeedVersionExporter{cfg: cfg}
 }
 
+// NewExportWriter returns the writer for an export format ("gtfs_zip" or
+// "parquet"), writing a zip to outfn.
+func NewExportWriter(format string, outfn string) (adapters.Writer, error) {
+	switch format {
+	case "parquet":
+		return tlparquet.NewWriter(outfn)
+	default:
+		return tlcsv.NewWriter(outfn)
+	}
+}
+
+// ExportFile exports the feed versions to a zip at outfn, applying transforms
+// and any requested sort. Used by the background export worker; the request
+// must already have been checked with CheckFeedVersionExportRequest.
+func (e *FeedVersionExporter) ExportFile(ctx context.Context, fvids []int, format string, transforms *ExportTransforms, outfn string) error {
+	writer, err := NewExportWriter(format, outfn)
+	if err != nil {
+		return err
+	}
+	if _, err := e.Export(ctx, fvids, transforms, writer); err != nil {
+		writer.Close()
+		return err
+	}
+	setExportSort(writer, transforms)
+	return writer.Close()
+}
+
+// setExportSort configures the standardized sort on writers that support it.
+func setExportSort(writer adapters.Writer, transforms *ExportTransforms) {
+	if transforms == nil || transforms.StandardizedSort == "" {
+		return
+	}
+	if sw, ok := writer.(adapters.SortableWriter); ok {
+		sw.SetStandardizedSortOptions(adapters.StandardizedSortOptions{
+			ApplySort:   transforms.StandardizedSort,
+			SortColumns: transforms.StandardizedSortColumns,
+		})
+	}
+}
+
 // Export performs the feed version export with optional transformations
 func (e *FeedVersionExporter) Export(ctx context.Context, fvids []int, transforms *ExportTransforms, writer adapters.Writer) (*copier.Result, error) {
 	// Create database readers for each feed version. Each reader gets its own
@@ -0,0 +1,89 @@
+package rest
+
+import (
+	"context"
+	_ "embed"
+
+	oa "github.com/getkin/kin-openapi/openapi3"
+)
+
+//go:embed network_request.gql
+var networkQuery string
+
+// NetworkRequest holds options for a /feed_versions/{feed_version_key}/networks request
+type NetworkRequest struct {
+	FeedVersionKey string `json:"feed_version_key"`
+	RouteKey       string `json:"route_key"`
+	NetworkID      string `json:"network_id"`
+	WithCursor
+}
+
+func (r NetworkRequest) RequestInfo() RequestInfo {
+	return RequestInfo{
+		Path: "/feed_versions/{feed_version_key}/networks",
+		Get: &RequestOperation{
+			Query: networkQuery,
+			Operation: &oa.Operation{
+				Summary:     `Networks`,
+				Description: `Networks defined in networks.txt for a feed version, or the networks of a route, with the routes assigned to each network through routes.network_id or route_networks.txt.`,
+				Extensions: map[string]any{
+					"x-alternates": []RequestAltPath{
+						{"GET", "/routes/{route_key}/networks", "Request networks for a route"},
+					},
+				},
+				Parameters: oa.Parameters{
+					&pref{Value: &param{
+						Name:        "feed_version_key",
+						In:          "path",
+						Required:    true,
+						Description: `Feed version lookup key; can be an integer ID or a SHA1 value`,
+						Schema:      newSRVal("string", "", nil),
+					}},
+					&pref{Value: &param{
+						Name:        "network_id",
+						In:          "query",
+						Description: `Search for networks with this GTFS network_id`,
+						Schema:      newSRVal("string", "", nil),
+					}},
+					newPRef("limitParam"),
+				},
+			},
+		},
+	}
+}
+
+// ResponseKey returns the GraphQL response entity key.
+func (r NetworkRequest) ResponseKey() string {
+	if r.RouteKey != "" {
+		return "routes"
+	}
+	return "feed_versions"
+}
+
+// IncludeNext
+func (r NetworkRequest) IncludeNext() bool { return false }
+
+// Query returns a GraphQL query string and variables.
+func (r NetworkRequest) Query(ctx context.Context) (string, map[string]interface{}) {
+	responseKey := r.ResponseKey()
+	var id int
+	entityWhere := hw{}
+	if responseKey == "routes" {
+		id = entityKeyWhere(r.RouteKey, "route_id", entityWhere)
+	} else {
+		id = feedVersionKeyWhere(r.FeedVersionKey, entityWhere)
+	}
+	where := hw{}
+	if r.NetworkID != "" {
+		where["network_id"] = r.NetworkID
+	}
+	return networkQuery, hw{
+		"include_feed_versions": responseKey == "feed_versions",
+		"include_routes":        responseKey == "routes",
+		"ids":                   checkIds(id),
+		"limit":                 r.CheckLimit(),
+		"feed_version_where":    entityWhere,
+		"route_where":           entityWhere,
+		"where":                 where,
+	}
+}
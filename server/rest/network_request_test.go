@@ -0,0 +1,97 @@
+package rest
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/interline-io/transitland-lib/internal/testconfig"
+	"github.com/interline-io/transitland-lib/server/model"
+	"github.com/interline-io/transitland-lib/testdata"
+)
+
+func TestNetworkRequest(t *testing.T) {
+	bartSha1 := "e535eb2b3b9ac3ef15d82c56575e914575e732e0"
+	testcases := []testCase{
+		{
+			name:         "feed version by sha1",
+			h:            NetworkRequest{FeedVersionKey: bartSha1},
+			selector:     "feed_versions.#.sha1",
+			expectSelect: []string{bartSha1},
+		},
+		{
+			name:         "route by feed key",
+			h:            NetworkRequest{RouteKey: "BART:01"},
+			selector:     "routes.#.route_id",
+			expectSelect: []string{"01"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			checkTestCase(t, tc)
+		})
+	}
+}
+
+func TestNetworkRequest_Feed(t *testing.T) {
+	testconfig.ConfigTxRollback(t, testconfig.Options{AllowAll: true}, func(cfg model.Config) {
+		fv := testconfig.ImportFeed(t, cfg, "test", testdata.Path("server/gtfs/networks"))
+		graphqlHandler, _, _ := testHandlersWithConfig(t, cfg)
+		routeKey := func(routeId string) string {
+			routes, err := cfg.Finder.FindRoutes(context.Background(), nil, nil, nil, &model.RouteFilter{FeedVersionSha1: &fv.SHA1, RouteID: &routeId})
+			if err != nil || len(routes) != 1 {
+				t.Fatalf("expected route %s: %v", routeId, err)
+			}
+			return strconv.Itoa(routes[0].ID)
+		}
+		testcases := []testCase{
+			{
+				name:         "feed version networks",
+				h:            NetworkRequest{FeedVersionKey: fv.SHA1},
+				selector:     "feed_versions.0.networks.#.network_id",
+				expectSelect: []string{"rail", "bus", "night"},
+			},
+			{
+				name:         "feed version network names",
+				h:            NetworkRequest{FeedVersionKey: fv.SHA1},
+				selector:     "feed_versions.0.networks.#.network_name",
+				expectSelect: []string{"Rail Network", "Bus Network", "Night Network"},
+			},
+			{
+				name:         "network_id",
+				h:            NetworkRequest{FeedVersionKey: fv.SHA1, NetworkID: "bus"},
+				selector:     "feed_versions.0.networks.#.network_id",
+				expectSelect: []string{"bus"},
+			},
+			{
+				name:         "network routes",
+				h:            NetworkRequest{FeedVersionKey: fv.SHA1, NetworkID: "rail"},
+				selector:     "feed_versions.0.networks.0.routes.#.route_id",
+				expectSelect: []string{"r1"},
+			},
+			{
+				name:         "network without routes",
+				h:            NetworkRequest{FeedVersionKey: fv.SHA1, NetworkID: "night"},
+				selector:     "feed_versions.0.networks.0.routes.#.route_id",
+				expectSelect: []string{},
+			},
+			{
+				name:         "route networks",
+				h:            NetworkRequest{RouteKey: routeKey("r2")},
+				selector:     "routes.0.networks.#.network_id",
+				expectSelect: []string{"bus"},
+			},
+			{
+				name:         "route without networks",
+				h:            NetworkRequest{RouteKey: routeKey("r3")},
+				selector:     "routes.0.networks.#.network_id",
+				expectSelect: []string{},
+			},
+		}
+		for _, tc := range testcases {
+			t.Run(tc.name, func(t *testing.T) {
+				checkTestCaseWithHandler(t, graphqlHandler, tc)
+			})
+		}
+	})
+}
@@ -0,0 +1,342 @@
+package rest
+
+import (
+	"context"
+	_ "embed"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	oa "github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-chi/chi/v5"
+	"github.com/interline-io/log"
+	"github.com/interline-io/transitland-lib/internal/util"
+)
+
+//go:embed performance_request.gql
+var performanceQuery string
+
+// PerformanceRequest holds options for a /routes/{route_key}/performance request
+type PerformanceRequest struct {
+	AgencyKey      string `json:"agency_key"`
+	RouteKey       string `json:"route_key"`
+	StopKey        string `json:"stop_key"`
+	StartDate      string `json:"start_date"`
+	EndDate        string `json:"end_date"`
+	StartTime      string `json:"start_time"`
+	EndTime        string `json:"end_time"`
+	Source         string `json:"source"`
+	EarlyThreshold *int   `json:"early_threshold,string"`
+	LateThreshold  *int   `json:"late_threshold,string"`
+	Format         string `json:"format"`
+}
+
+func (r PerformanceRequest) RequestInfo() RequestInfo {
+	return RequestInfo{
+		Path: "/routes/{route_key}/performance",
+		Get: &RequestOperation{
+			Query: performanceQuery,
+			Operation: &oa.Operation{
+				Summary:     `On-time performance report`,
+				Description: `Summarizes archived realtime stop observations for a route, stop or agency over a date range and time window: on-time, early and late counts, the delay distribution, canceled trips, and headway adherence for frequency-based trips. Metrics are also broken down by route, stop, scheduled hour and service date. Use the csv format for one row per summary or breakdown.`,
+				Extensions: map[string]any{
+					"x-alternates": []RequestAltPath{
+						{"GET", "/routes/{route_key}/performance.{format}", "Request performance report for a route in specified format"},
+						{"GET", "/stops/{stop_key}/performance", "Request performance report for a stop"},
+						{"GET", "/stops/{stop_key}/performance.{format}", "Request performance report for a stop in specified format"},
+						{"GET", "/agencies/{agency_key}/performance", "Request performance report for an agency"},
+						{"GET", "/agencies/{agency_key}/performance.{format}", "Request performance report for an agency in specified format"},
+					},
+				},
+				Parameters: oa.Parameters{
+					&pref{Value: &param{
+						Name:        "route_key",
+						In:          "path",
+						Required:    true,
+						Description: `Route lookup key; can be an integer ID, a '<feed onestop_id>:<route_id>' key, or a Onestop ID`,
+						Schema:      newSRVal("string", "", nil),
+					}},
+					&pref{Value: &param{
+						Name:        "start_date",
+						In:          "query",
+						Description: `First service date, inclusive`,
+						Required:    true,
+						Schema:      newSRVal("string", "date", nil),
+						Extensions:  newExt("", "start_date=2018-06-04", "/routes/BART:01/performance?start_date=2018-06-04"),
+					}},
+					&pref{Value: &param{
+						Name:        "end_date",
+						In:          "query",
+						Description: `Last service date, inclusive; defaults to start_date. The range may cover at most 366 days, or 31 days for an agency`,
+						Schema:      newSRVal("string", "date", nil),
+						Extensions:  newExt("", "start_date=2018-06-04&end_date=2018-06-08", "/routes/BART:01/performance?start_date=2018-06-04&end_date=2018-06-08"),
+					}},
+					&pref{Value: &param{
+						Name:        "start_time",
+						In:          "query",
+						Description: `Only observations scheduled at or after this time of day, in HH:MM:SS format`,
+						Schema:      newSRVal("string", "", nil),
+						Extensions:  newExt("", "start_time=07:00:00&end_time=10:00:00", "/routes/BART:01/performance?start_date=2018-06-04&start_time=07:00:00&end_time=10:00:00"),
+					}},
+					&pref{Value: &param{
+						Name:        "end_time",
+						In:          "query",
+						Description: `Only observations scheduled before this time of day, in HH:MM:SS format`,
+						Schema:      newSRVal("string", "", nil),
+					}},
+					&pref{Value: &param{
+						Name:        "source",
+						In:          "query",
+						Description: `Only observations from this GTFS-RT message type. By default, trip update observations are used, and vehicle position observations for stops without one`,
+						Schema:      newSRVal("string", "", []any{"TripUpdate", "VehiclePosition"}),
+					}},
+					&pref{Value: &param{
+						Name:        "early_threshold",
+						In:          "query",
+						Description: `Observations more than this many seconds early are early; defaults to 60`,
+						Schema:      newSRVal("integer", "", nil),
+					}},
+					&pref{Value: &param{
+						Name:        "late_threshold",
+						In:          "query",
+						Description: `Observations more than this many seconds late are late; defaults to 300`,
+						Schema:      newSRVal("integer", "", nil),
+						Extensions:  newExt("", "early_threshold=120&late_threshold=600", "/routes/BART:01/performance?start_date=2018-06-04&early_threshold=120&late_threshold=600"),
+					}},
+					&pref{Value: &param{
+						Name:        "format",
+						In:          "query",
+						Description: `Response format`,
+						Schema:      newSRVal("string", "", []any{"json", "csv"}),
+						Extensions:  newExt("", "format=csv", "/routes/BART:01/performance.csv?start_date=2018-06-04"),
+					}},
+				},
+			},
+		},
+	}
+}
+
+// ResponseKey returns the GraphQL response entity key.
+func (r PerformanceRequest) ResponseKey() string {
+	if r.StopKey != "" {
+		return "stops"
+	} else if r.AgencyKey != "" {
+		return "agencies"
+	}
+	return "routes"
+}
+
+// IncludeNext
+func (r PerformanceRequest) IncludeNext() bool { return false }
+
+// Query returns a GraphQL query string and variables.
+func (r PerformanceRequest) Query(ctx context.Context) (string, map[string]interface{}) {
+	responseKey := r.ResponseKey()
+	var id int
+	where := hw{}
+	switch responseKey {
+	case "stops":
+		id = entityKeyWhere(r.StopKey, "stop_id", where)
+	case "agencies":
+		id = entityKeyWhere(r.AgencyKey, "agency_id", where)
+	default:
+		id = entityKeyWhere(r.RouteKey, "route_id", where)
+	}
+	perfWhere := hw{"start_date": r.StartDate}
+	if r.EndDate != "" {
+		perfWhere["end_date"] = r.EndDate
+	}
+	if r.StartTime != "" {
+		perfWhere["start_time"] = r.StartTime
+	}
+	if r.EndTime != "" {
+		perfWhere["end_time"] = r.EndTime
+	}
+	if r.Source != "" {
+		perfWhere["source"] = r.Source
+	}
+	if r.EarlyThreshold != nil {
+		perfWhere["early_threshold"] = *r.EarlyThreshold
+	}
+	if r.LateThreshold != nil {
+		perfWhere["late_threshold"] = *r.LateThreshold
+	}
+	return performanceQuery, hw{
+		"include_agencies": responseKey == "agencies",
+		"include_routes":   responseKey == "routes",
+		"include_stops":    responseKey == "stops",
+		"ids":              checkIds(id),
+		"agency_where":     where,
+		"route_where":      where,
+		"stop_where":       where,
+		"where":            perfWhere,
+	}
+}
+
+// makePerformanceHandler returns the JSON report as other entity requests,
+// or one CSV row per summary and breakdown.
+func makePerformanceHandler(graphqlHandler http.Handler) http.HandlerFunc {
+	jsonHandler := makeEntityHandler(graphqlHandler, "performance", func() apiHandler { return &PerformanceRequest{} })
+	csvHandler := makeHandlerFunc(graphqlHandler, "performance", performanceCSVHandler)
+	return func(w http.ResponseWriter, r *http.Request) {
+		format := chi.URLParam(r, "format")
+		if format == "" {
+			format = r.URL.Query().Get("format")
+		}
+		if format == "csv" {
+			csvHandler(w, r)
+			return
+		}
+		jsonHandler(w, r)
+	}
+}
+
+func performanceCSVHandler(graphqlHandler http.Handler, w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	opts := queryToMap(r.URL.Query())
+	if rctx := chi.RouteContext(ctx); rctx != nil {
+		for _, k := range rctx.URLParams.Keys {
+			if k == "*" {
+				continue
+			}
+			opts[k] = rctx.URLParam(k)
+		}
+	}
+	req := PerformanceRequest{}
+	s, err := json.Marshal(opts)
+	if err == nil {
+		err = json.Unmarshal(s, &req)
+	}
+	if err != nil {
+		log.For(ctx).Error().Err(err).Msg("failed to parse request params")
+		util.WriteJsonError(w, "parameter error", http.StatusInternalServerError)
+		return
+	}
+	query, vars := req.Query(ctx)
+	response, err := makeGraphQLRequest(ctx, graphqlHandler, query, vars)
+	if err != nil {
+		util.WriteJsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	responseKey := req.ResponseKey()
+	ents, _ := response[responseKey].([]any)
+	if len(ents) == 0 {
+		util.WriteJsonError(w, "not found", http.StatusNotFound)
+		return
+	}
+	ent, _ := ents[0].(map[string]any)
+	w.Header().Add("Content-Type", "text/csv")
+	w.WriteHeader(http.StatusOK)
+	cw := csv.NewWriter(w)
+	cw.Write(performanceCSVHeader)
+	cw.WriteAll(performanceCSVRows(performanceEntityTypes[responseKey], ent))
+}
+
+var performanceEntityTypes = map[string]string{
+	"agencies": "agency",
+	"routes":   "route",
+	"stops":    "stop",
+}
+
+var performanceCSVHeader = []string{
+	"feed_onestop_id",
+	"feed_version_sha1",
+	"entity_type",
+	"entity_id",
+	"group",
+	"route_id",
+	"stop_id",
+	"hour",
+	"date",
+	"scheduled_trip_count",
+	"observation_count",
+	"on_time_count",
+	"early_count",
+	"late_count",
+	"on_time_percent",
+	"trip_count",
+	"canceled_trip_count",
+	"delay_mean",
+	"delay_median",
+	"delay_p90",
+	"delay_min",
+	"delay_max",
+	"headway_count",
+	"scheduled_headway_mean",
+	"observed_headway_mean",
+	"headway_adherent_count",
+	"headway_adherent_percent",
+	"excess_wait_time",
+}
+
+// performanceCSVRows flattens a performance report into rows matching performanceCSVHeader.
+func performanceCSVRows(entityType string, ent map[string]any) [][]string {
+	report, _ := ent["performance"].(map[string]any)
+	if report == nil {
+		return nil
+	}
+	entityID := csvValue(ent[entityType+"_id"])
+	var rows [][]string
+	addRow := func(group string, m map[string]any, scheduledTripCount any) {
+		delay, _ := m["delay"].(map[string]any)
+		headway, _ := m["headway"].(map[string]any)
+		rows = append(rows, []string{
+			csvValue(ent["feed_onestop_id"]),
+			csvValue(ent["feed_version_sha1"]),
+			entityType,
+			entityID,
+			group,
+			csvValue(m["route_id"]),
+			csvValue(m["stop_id"]),
+			csvValue(m["hour"]),
+			csvValue(m["date"]),
+			csvValue(scheduledTripCount),
+			csvValue(m["observation_count"]),
+			csvValue(m["on_time_count"]),
+			csvValue(m["early_count"]),
+			csvValue(m["late_count"]),
+			csvValue(m["on_time_percent"]),
+			csvValue(m["trip_count"]),
+			csvValue(m["canceled_trip_count"]),
+			csvValue(delay["mean"]),
+			csvValue(delay["median"]),
+			csvValue(delay["p90"]),
+			csvValue(delay["min"]),
+			csvValue(delay["max"]),
+			csvValue(headway["headway_count"]),
+			csvValue(headway["scheduled_headway_mean"]),
+			csvValue(headway["observed_headway_mean"]),
+			csvValue(headway["adherent_count"]),
+			csvValue(headway["adherent_percent"]),
+			csvValue(headway["excess_wait_time"]),
+		})
+	}
+	if summary, ok := report["summary"].(map[string]any); ok {
+		addRow("summary", summary, report["scheduled_trip_count"])
+	}
+	for _, group := range []string{"route", "stop", "hour", "date"} {
+		items, _ := report["by_"+group].([]any)
+		for _, item := range items {
+			if m, ok := item.(map[string]any); ok {
+				addRow(group, m, nil)
+			}
+		}
+	}
+	return rows
+}
+
+func csvValue(v any) string {
+	switch a := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return a
+	case float64:
+		return strconv.FormatFloat(a, 'f', -1, 64)
+	case json.Number:
+		return a.String()
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -0,0 +1,106 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/interline-io/transitland-lib/internal/testconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPerformanceRequest(t *testing.T) {
+	testcases := []testCase{
+		{
+			name:         "route by feed key",
+			h:            PerformanceRequest{RouteKey: "BART:01", StartDate: "2018-06-04", EndDate: "2018-06-08"},
+			selector:     "routes.#.route_id",
+			expectSelect: []string{"01"},
+		},
+		{
+			name:         "route with no observations",
+			h:            PerformanceRequest{RouteKey: "BART:01", StartDate: "2018-06-04"},
+			selector:     "routes.0.performance.summary.observation_count",
+			expectSelect: []string{"0"},
+		},
+		{
+			name:         "stop by feed key",
+			h:            PerformanceRequest{StopKey: "BART:MONT", StartDate: "2018-06-04", StartTime: "07:00:00", EndTime: "10:00:00"},
+			selector:     "stops.#.stop_id",
+			expectSelect: []string{"MONT"},
+		},
+		{
+			name:         "agency by feed key",
+			h:            PerformanceRequest{AgencyKey: "BART:BART", StartDate: "2018-06-04"},
+			selector:     "agencies.#.agency_id",
+			expectSelect: []string{"BART"},
+		},
+		{
+			name:        "invalid source",
+			h:           PerformanceRequest{RouteKey: "BART:01", StartDate: "2018-06-04", Source: "Alert"},
+			expectError: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			checkTestCase(t, tc)
+		})
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		_, restSrv, _ := testHandlersWithOptions(t, testconfig.Options{})
+		req := httptest.NewRequest("GET", "/routes/BART:01/performance.csv?start_date=2018-06-04", nil)
+		rr := httptest.NewRecorder()
+		restSrv.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "text/csv", rr.Header().Get("Content-Type"))
+		lines := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+		require.GreaterOrEqual(t, len(lines), 2)
+		assert.Equal(t, strings.Join(performanceCSVHeader, ","), lines[0])
+		assert.True(t, strings.HasPrefix(lines[1], "BART,"))
+		assert.Contains(t, lines[1], ",route,01,summary,")
+	})
+}
+
+func TestPerformanceCSVRows(t *testing.T) {
+	ent := map[string]any{
+		"route_id":          "01",
+		"feed_onestop_id":   "BART",
+		"feed_version_sha1": "abc",
+		"performance": map[string]any{
+			"scheduled_trip_count": 10.0,
+			"summary": map[string]any{
+				"observation_count": 4.0,
+				"on_time_count":     2.0,
+				"on_time_percent":   50.0,
+				"delay":             map[string]any{"mean": 77.5, "median": 0.0},
+			},
+			"by_hour": []any{
+				map[string]any{"hour": 8.0, "observation_count": 2.0},
+			},
+		},
+	}
+	rows := performanceCSVRows("route", ent)
+	require.Len(t, rows, 2)
+	for _, row := range rows {
+		assert.Len(t, row, len(performanceCSVHeader))
+	}
+	col := func(row []string, name string) string {
+		for i, v := range performanceCSVHeader {
+			if v == name {
+				return row[i]
+			}
+		}
+		return ""
+	}
+	assert.Equal(t, "summary", col(rows[0], "group"))
+	assert.Equal(t, "01", col(rows[0], "entity_id"))
+	assert.Equal(t, "10", col(rows[0], "scheduled_trip_count"))
+	assert.Equal(t, "50", col(rows[0], "on_time_percent"))
+	assert.Equal(t, "77.5", col(rows[0], "delay_mean"))
+	assert.Equal(t, "hour", col(rows[1], "group"))
+	assert.Equal(t, "8", col(rows[1], "hour"))
+	assert.Equal(t, "", col(rows[1], "scheduled_trip_count"))
+}
@@ -0,0 +1,187 @@
+package rest
+
+import (
+	"context"
+	_ "embed"
+	"strconv"
+	"time"
+
+	oa "github.com/getkin/kin-openapi/openapi3"
+)
+
+//go:embed reachability_request.gql
+var reachabilityQuery string
+
+// ReachabilityRequest holds options for a /reachability request
+type ReachabilityRequest struct {
+	Lon                float64 `json:"lon,string"`
+	Lat                float64 `json:"lat,string"`
+	DepartAt           string  `json:"depart_at"`
+	Cutoffs            string  `json:"cutoffs"`
+	MaxWalkDistance    float64 `json:"max_walk_distance,string"`
+	IncludeStops       bool    `json:"include_stops,string"`
+	CensusDataset      string  `json:"census_dataset"`
+	CensusLayer        string  `json:"census_layer"`
+	CensusTables       string  `json:"census_tables"`
+	CensusValueDataset string  `json:"census_value_dataset"`
+	Format             string  `json:"format"`
+}
+
+func (r ReachabilityRequest) RequestInfo() RequestInfo {
+	return RequestInfo{
+		Path: "/reachability",
+		Get: &RequestOperation{
+			Query: reachabilityQuery,
+			Operation: &oa.Operation{
+				Summary:     `Stops and areas reachable by transit from a point`,
+				Description: `Searches the scheduled service of the active feed versions from an origin and departure time, walking to and from stops, and returns an isochrone polygon for each travel time cutoff. Census geographies intersecting each isochrone can be included to estimate population reached.`,
+				Extensions: map[string]any{
+					"x-alternates": []RequestAltPath{
+						{"GET", "/reachability.{format}", "Request isochrones in specified format"},
+					},
+				},
+				Parameters: oa.Parameters{
+					&pref{Value: &param{
+						Name:        "lat",
+						In:          "query",
+						Description: `Origin latitude`,
+						Required:    true,
+						Schema:      newSRVal("number", "", nil),
+					}},
+					&pref{Value: &param{
+						Name:        "lon",
+						In:          "query",
+						Description: `Origin longitude`,
+						Required:    true,
+						Schema:      newSRVal("number", "", nil),
+						Extensions:  newExt("", "lon=-122.4011&lat=37.7894", "/reachability?lon=-122.4011&lat=37.7894"),
+					}},
+					&pref{Value: &param{
+						Name:        "depart_at",
+						In:          "query",
+						Description: `Departure time, in RFC3339 format; defaults to now`,
+						Schema:      newSRVal("string", "date-time", nil),
+						Extensions:  newExt("", "depart_at=2018-06-04T08:00:00-07:00", "/reachability?lon=-122.4011&lat=37.7894&depart_at=2018-06-04T08:00:00-07:00"),
+					}},
+					&pref{Value: &param{
+						Name:        "cutoffs",
+						In:          "query",
+						Description: `Travel time cutoffs in seconds; accepts comma separated values. Defaults to 1800, maximum 10800`,
+						Schema:      newSRVal("string", "", nil),
+						Extensions:  newExt("", "cutoffs=900,1800", "/reachability?lon=-122.4011&lat=37.7894&cutoffs=900,1800"),
+					}},
+					&pref{Value: &param{
+						Name:        "max_walk_distance",
+						In:          "query",
+						Description: `Maximum walking distance in meters to and from stops; defaults to 1000`,
+						Schema:      newSRVal("number", "", nil),
+					}},
+					&pref{Value: &param{
+						Name:        "census_dataset",
+						In:          "query",
+						Description: `Include census geographies from this dataset that intersect each isochrone`,
+						Schema:      newSRVal("string", "", nil),
+						Extensions:  newExt("", "census_dataset=tiger2021&census_layer=tract&census_tables=b01001&census_value_dataset=acsdt5y2022", "/reachability?lon=-122.4011&lat=37.7894&census_dataset=tiger2021&census_layer=tract&census_tables=b01001&census_value_dataset=acsdt5y2022"),
+					}},
+					&pref{Value: &param{
+						Name:        "census_layer",
+						In:          "query",
+						Description: `Census geography layer, e.g. tract`,
+						Schema:      newSRVal("string", "", nil),
+					}},
+					&pref{Value: &param{
+						Name:        "census_tables",
+						In:          "query",
+						Description: `Census tables to include values from for each geography; accepts comma separated values`,
+						Schema:      newSRVal("string", "", nil),
+					}},
+					&pref{Value: &param{
+						Name:        "census_value_dataset",
+						In:          "query",
+						Description: `Census dataset for values, if different from census_dataset`,
+						Schema:      newSRVal("string", "", nil),
+					}},
+					newPRefExt("includeStopsParam", "", "include_stops=true", "/reachability?lon=-122.4011&lat=37.7894&include_stops=true"),
+					newPRef("formatParam"),
+				},
+			},
+		},
+	}
+}
+
+// ResponseKey returns the GraphQL response entity key.
+func (r ReachabilityRequest) ResponseKey() string { return "reachability" }
+
+// IncludeNext
+func (r ReachabilityRequest) IncludeNext() bool { return false }
+
+// Query returns a GraphQL query string and variables.
+func (r ReachabilityRequest) Query(ctx context.Context) (string, map[string]interface{}) {
+	where := hw{
+		"from": hw{"lon": r.Lon, "lat": r.Lat},
+	}
+	if r.DepartAt != "" {
+		if t, err := time.Parse(time.RFC3339, r.DepartAt); err == nil {
+			where["depart_at"] = t
+		}
+	}
+	var cutoffs []int
+	for _, v := range commaSplit(r.Cutoffs) {
+		if c, err := strconv.Atoi(v); err == nil {
+			cutoffs = append(cutoffs, c)
+		}
+	}
+	if len(cutoffs) > 0 {
+		where["cutoffs"] = cutoffs
+	}
+	if r.MaxWalkDistance > 0 {
+		where["max_walk_distance"] = r.MaxWalkDistance
+	}
+	censusWhere := hw{}
+	if r.CensusDataset != "" {
+		censusWhere["dataset"] = r.CensusDataset
+	}
+	if r.CensusLayer != "" {
+		censusWhere["layer"] = r.CensusLayer
+	}
+	var censusValueDataset *string
+	if r.CensusValueDataset != "" {
+		censusValueDataset = &r.CensusValueDataset
+	}
+	censusTables := commaSplit(r.CensusTables)
+	if censusTables == nil {
+		censusTables = []string{}
+	}
+	return reachabilityQuery, hw{
+		"where":                where,
+		"include_stops":        r.IncludeStops,
+		"include_census":       r.CensusDataset != "",
+		"census_where":         censusWhere,
+		"census_tables":        censusTables,
+		"census_value_dataset": censusValueDataset,
+	}
+}
+
+// ProcessGeoJSON returns the isochrones as features.
+func (r ReachabilityRequest) ProcessGeoJSON(ctx context.Context, response map[string]interface{}) error {
+	features := []hw{}
+	reachability, _ := response[r.ResponseKey()].(map[string]interface{})
+	isochrones, _ := reachability["isochrones"].([]interface{})
+	for _, iso := range isochrones {
+		f, ok := iso.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		geometry := f["geometry"]
+		delete(f, "geometry")
+		features = append(features, hw{
+			"type":       "Feature",
+			"properties": f,
+			"geometry":   geometry,
+		})
+	}
+	delete(response, r.ResponseKey())
+	response["type"] = "FeatureCollection"
+	response["features"] = features
+	return nil
+}
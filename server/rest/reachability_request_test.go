@@ -0,0 +1,76 @@
+package rest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+
+	_ "github.com/interline-io/transitland-lib/server/directions/csarouter"
+)
+
+func TestReachabilityRequest(t *testing.T) {
+	montgomery := ReachabilityRequest{Lon: -122.4011, Lat: 37.7894, DepartAt: "2018-06-04T08:00:00-07:00"}
+	withCutoffs := montgomery
+	withCutoffs.Cutoffs = "1800,900"
+	withStops := montgomery
+	withStops.IncludeStops = true
+	testcases := []testCase{
+		{
+			name:         "default cutoff",
+			h:            montgomery,
+			selector:     "reachability.isochrones.#.cutoff",
+			expectSelect: []string{"1800"},
+		},
+		{
+			name:         "cutoffs",
+			h:            withCutoffs,
+			selector:     "reachability.isochrones.#.cutoff",
+			expectSelect: []string{"900", "1800"},
+		},
+		{
+			name:         "isochrone geometry",
+			h:            montgomery,
+			selector:     "reachability.isochrones.0.geometry.type",
+			expectSelect: []string{"MultiPolygon"},
+		},
+		{
+			name:         "stops not included by default",
+			h:            montgomery,
+			selector:     "reachability.stops.#.stop_id",
+			expectSelect: nil,
+			expectLength: 0,
+		},
+		{
+			name: "include_stops",
+			h:    withStops,
+			f: func(t *testing.T, jj string) {
+				travelTimes := map[string]int64{}
+				for _, s := range gjson.Get(jj, "reachability.stops").Array() {
+					travelTimes[s.Get("stop_id").String()] = s.Get("travel_time").Int()
+				}
+				assert.Contains(t, travelTimes, "MONT")
+				assert.Contains(t, travelTimes, "DBRK")
+				assert.LessOrEqual(t, travelTimes["DBRK"], int64(1800))
+			},
+		},
+		{
+			name:         "geojson",
+			h:            withCutoffs,
+			format:       "geojson",
+			selector:     "features.#.properties.cutoff",
+			expectSelect: []string{"900", "1800"},
+		},
+		{
+			name:         "invalid cutoff",
+			h:            ReachabilityRequest{Lon: -122.4011, Lat: 37.7894, Cutoffs: "100000"},
+			selector:     "reachability.success",
+			expectSelect: []string{"false"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			checkTestCase(t, tc)
+		})
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/interline-io/log"
 	"github.com/interline-io/transitland-lib/internal/util"
 	"github.com/interline-io/transitland-lib/server/auth/mw/usercheck"
+	"github.com/interline-io/transitland-lib/server/gql"
 	"github.com/interline-io/transitland-lib/server/meters"
 	"github.com/interline-io/transitland-lib/server/model"
 	"github.com/rs/zerolog"
@@ -66,6 +67,7 @@ func isProtocolRelative(s string) bool {
 // NewServer .
 func NewServer(graphqlHandler http.Handler) (http.Handler, error) {
 	r := chi.NewRouter()
+	r.Use(gql.LanguageMiddleware)
 
 	feedIndexHandler := makeIndexHandler(graphqlHandler, "feeds", func() apiHandler { return &FeedRequest{} })
 	feedEntityHandler := makeEntityHandler(graphqlHandler, "feeds", func() apiHandler { return &FeedRequest{} })
@@ -82,6 +84,12 @@ func NewServer(graphqlHandler http.Handler) (http.Handler, error) {
 	stopDepartureHandler := makeIndexHandler(graphqlHandler, "stopDepartures", func() apiHandler { return &StopDepartureRequest{} })
 	operatorIndexHandler := makeIndexHandler(graphqlHandler, "operators", func() apiHandler { return &OperatorRequest{} })
 	operatorEntityHandler := makeEntityHandler(graphqlHandler, "operators", func() apiHandler { return &OperatorRequest{} })
+	reachabilityHandler := makeIndexHandler(graphqlHandler, "reachability", func() apiHandler { return &ReachabilityRequest{} })
+	performanceHandler := makePerformanceHandler(graphqlHandler)
+	transferHandler := makeEntityHandler(graphqlHandler, "transfers", func() apiHandler { return &TransferRequest{} })
+	networkHandler := makeEntityHandler(graphqlHandler, "networks", func() apiHandler { return &NetworkRequest{} })
+	attributionHandler := makeEntityHandler(graphqlHandler, "attributions", func() apiHandler { return &AttributionRequest{} })
+	areaHandler := makeEntityHandler(graphqlHandler, "areas", func() apiHandler { return &AreaRequest{} })
 
 	// Redirect root to OpenAPI documentation
 	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -106,17 +114,26 @@ func NewServer(graphqlHandler http.Handler) (http.Handler, error) {
 	r.HandleFunc("/feed_versions/{feed_version_key}", feedVersionEntityHandler)
 	r.HandleFunc("/feeds/{feed_key}/feed_versions", feedVersionIndexHandler)
 	r.Handle("/feed_versions/{feed_version_key}/download", usercheck.RoleRequired("tl_download_fv_historic")(makeHandlerFunc(graphqlHandler, "feedVersionDownload", feedVersionDownloadHandler)))
+	r.HandleFunc("/feed_versions/{feed_version_key}/transfers", transferHandler)
+	r.HandleFunc("/feed_versions/{feed_version_key}/networks", networkHandler)
+	r.HandleFunc("/feed_versions/{feed_version_key}/attributions", attributionHandler)
+	r.HandleFunc("/feed_versions/{feed_version_key}/areas", areaHandler)
 	r.Method("POST", "/feed_versions/export", usercheck.RoleRequired("tl_export_feed_versions")(makeHandlerFunc(graphqlHandler, "feedVersionExport", feedVersionExportHandler)))
 
 	r.HandleFunc("/agencies.{format}", agencyIndexHandler)
 	r.HandleFunc("/agencies", agencyIndexHandler)
 	r.HandleFunc("/agencies/{agency_key}.{format}", agencyEntityHandler)
 	r.HandleFunc("/agencies/{agency_key}", agencyEntityHandler)
+	r.HandleFunc("/agencies/{agency_key}/performance.{format}", performanceHandler)
+	r.HandleFunc("/agencies/{agency_key}/performance", performanceHandler)
 
 	r.HandleFunc("/routes.{format}", routeIndexHandler)
 	r.HandleFunc("/routes", routeIndexHandler)
 	r.HandleFunc("/routes/{route_key}.{format}", routeEntityHandler)
 	r.HandleFunc("/routes/{route_key}", routeEntityHandler)
+	r.HandleFunc("/routes/{route_key}/performance.{format}", performanceHandler)
+	r.HandleFunc("/routes/{route_key}/performance", performanceHandler)
+	r.HandleFunc("/routes/{route_key}/networks", networkHandler)
 	r.HandleFunc("/agencies/{agency_key}/routes.{format}", routeIndexHandler)
 	r.HandleFunc("/agencies/{agency_key}/routes", routeIndexHandler)
 
@@ -131,12 +148,19 @@ func NewServer(graphqlHandler http.Handler) (http.Handler, error) {
 	r.HandleFunc("/stops/{stop_key}", stopEntityHandler)
 
 	r.HandleFunc("/stops/{stop_key}/departures", stopDepartureHandler)
+	r.HandleFunc("/stops/{stop_key}/performance.{format}", performanceHandler)
+	r.HandleFunc("/stops/{stop_key}/performance", performanceHandler)
+	r.HandleFunc("/stops/{stop_key}/transfers", transferHandler)
+	r.HandleFunc("/stops/{stop_key}/areas", areaHandler)
 
 	r.HandleFunc("/operators.{format}", operatorIndexHandler)
 	r.HandleFunc("/operators", operatorIndexHandler)
 	r.HandleFunc("/operators/{operator_key}.{format}", operatorEntityHandler)
 	r.HandleFunc("/operators/{operator_key}", operatorEntityHandler)
 
+	r.HandleFunc("/reachability.{format}", reachabilityHandler)
+	r.HandleFunc("/reachability", reachabilityHandler)
+
 	// OnestopID generic handler
 	r.Handle("/onestop_id/{onestop_id}", &OnestopIdEntityRedirectRequest{})
 
@@ -242,6 +266,29 @@ func checkIds(id int) []int {
 	return nil
 }
 
+// entityKeyWhere parses an entity key: an integer ID, a
+// '<feed onestop_id>:<entity_id>' key, or a Onestop ID.
+func entityKeyWhere(key string, entityField string, where hw) int {
+	if fsid, eid, ok := strings.Cut(key, ":"); ok {
+		where["feed_onestop_id"] = fsid
+		where[entityField] = eid
+	} else if v, err := strconv.Atoi(key); err == nil {
+		return v
+	} else {
+		where["onestop_id"] = key
+	}
+	return 0
+}
+
+// feedVersionKeyWhere parses a feed version key: an integer ID or a SHA1.
+func feedVersionKeyWhere(key string, where hw) int {
+	if v, err := strconv.Atoi(key); err == nil {
+		return v
+	}
+	where["sha1"] = key
+	return 0
+}
+
 // queryToMap converts url.Values to map[string]string
 func queryToMap(vars url.Values) map[string]string {
 	m := map[string]string{}
@@ -44,7 +44,10 @@ type testCase struct {
 }
 
 func testHandlersWithOptions(t testing.TB, opts testconfig.Options) (http.Handler, http.Handler, model.Config) {
-	cfg := testconfig.Config(t, opts)
+	return testHandlersWithConfig(t, testconfig.Config(t, opts))
+}
+
+func testHandlersWithConfig(t testing.TB, cfg model.Config) (http.Handler, http.Handler, model.Config) {
 	graphqlHandler, err := gql.NewServer()
 	if err != nil {
 		t.Fatal(err)
@@ -64,6 +67,10 @@ func checkTestCase(t *testing.T, tc testCase) {
 		RTJsons: testconfig.DefaultRTJson(),
 		Storage: testdata.Path("server", "tmp"),
 	})
+	checkTestCaseWithHandler(t, graphqlHandler, tc)
+}
+
+func checkTestCaseWithHandler(t *testing.T, graphqlHandler http.Handler, tc testCase) {
 	tested := false
 
 	// Inject user
@@ -26,6 +26,12 @@ var RestHandlersList = []RestHandlers{
 	&TripRequest{},          // /routes/{route_key}/trips
 	&StopRequest{},          // /stops
 	&StopDepartureRequest{}, // /stops/{stop_key}/departures
+	&ReachabilityRequest{},  // /reachability
+	&PerformanceRequest{},   // /routes/{route_key}/performance
+	&TransferRequest{},      // /feed_versions/{feed_version_key}/transfers
+	&NetworkRequest{},       // /feed_versions/{feed_version_key}/networks
+	&AttributionRequest{},   // /feed_versions/{feed_version_key}/attributions
+	&AreaRequest{},          // /feed_versions/{feed_version_key}/areas
 
 	// Individual resource endpoints (for direct lookups)
 	&FeedKeyRequest{},        // /feeds/{feed_key}
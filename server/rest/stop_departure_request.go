@@ -14,20 +14,21 @@ var stopDepartureQuery string
 
 // StopDepartureRequest holds options for a /stops/_/departures request
 type StopDepartureRequest struct {
-	StopKey          string `json:"stop_key"`
-	ID               int    `json:"id,string"`
-	StopID           string `json:"stop_id"`
-	FeedOnestopID    string `json:"feed_onestop_id"`
-	OnestopID        string `json:"onestop_id"`
-	Next             int    `json:"next,string"`
-	ServiceDate      string `json:"service_date"`
-	Date             string `json:"date"`
-	RelativeDate     string `json:"relative_date"`
-	StartTime        string `json:"start_time"`
-	EndTime          string `json:"end_time"`
-	IncludeGeometry  bool   `json:"include_geometry,string"`
-	IncludeAlerts    bool   `json:"include_alerts,string"`
-	UseServiceWindow *bool  `json:"use_service_window,string"`
+	StopKey           string `json:"stop_key"`
+	ID                int    `json:"id,string"`
+	StopID            string `json:"stop_id"`
+	FeedOnestopID     string `json:"feed_onestop_id"`
+	OnestopID         string `json:"onestop_id"`
+	Next              int    `json:"next,string"`
+	ServiceDate       string `json:"service_date"`
+	Date              string `json:"date"`
+	RelativeDate      string `json:"relative_date"`
+	StartTime         string `json:"start_time"`
+	EndTime           string `json:"end_time"`
+	IncludeGeometry   bool   `json:"include_geometry,string"`
+	IncludeAlerts     bool   `json:"include_alerts,string"`
+	UseServiceWindow  *bool  `json:"use_service_window,string"`
+	EffectiveSchedule bool   `json:"effective_schedule,string"`
 	WithCursor
 }
 
@@ -100,6 +101,13 @@ func (r StopDepartureRequest) RequestInfo() RequestInfo {
 						Schema:      newSRVal("string", "", []any{"true", "false"}),
 						Extensions:  newExt("", "use_service_window=false", "/stops/f-sf~bay~area~rg:LAKE/departures?use_service_window=false"),
 					}},
+					&pref{Value: &param{
+						Name:        "effective_schedule",
+						In:          "query",
+						Description: `Answer each service date from the feed version that governs it, the most recently fetched imported version of the stop's feed whose service period contains the date, instead of the stop's own feed version. Dates that no version governs use the stop's feed version, and use_service_window.`,
+						Schema:      newSRVal("string", "", []any{"true", "false"}),
+						Extensions:  newExt("", "effective_schedule=true", "/stops/f-sf~bay~area~rg:LAKE/departures?effective_schedule=true&service_date=2022-09-28"),
+					}},
 					newPRef("idParam"),
 					newPRefExt("relativeDateParam", "", "relative_date=NEXT_MONDAY", "/stops/f-sf~bay~area~rg:LAKE/departures?relative_date=NEXT_MONDAY"),
 					newPRef("includeAlertsParam"),
@@ -144,6 +152,9 @@ func (r StopDepartureRequest) Query(ctx context.Context) (string, map[string]int
 	if r.UseServiceWindow == nil || *r.UseServiceWindow {
 		stwhere["use_service_window"] = true
 	}
+	if r.EffectiveSchedule {
+		stwhere["effective_schedule"] = true
+	}
 	// Restore previous default behavior
 	// If no date is specified, use next hour
 	if r.Date != "" {
@@ -0,0 +1,90 @@
+package rest
+
+import (
+	"context"
+	_ "embed"
+
+	oa "github.com/getkin/kin-openapi/openapi3"
+)
+
+//go:embed transfer_request.gql
+var transferQuery string
+
+// TransferRequest holds options for a /feed_versions/{feed_version_key}/transfers request
+type TransferRequest struct {
+	FeedVersionKey string `json:"feed_version_key"`
+	StopKey        string `json:"stop_key"`
+	TransferType   *int   `json:"transfer_type,string"`
+	WithCursor
+}
+
+func (r TransferRequest) RequestInfo() RequestInfo {
+	return RequestInfo{
+		Path: "/feed_versions/{feed_version_key}/transfers",
+		Get: &RequestOperation{
+			Query: transferQuery,
+			Operation: &oa.Operation{
+				Summary:     `Transfers`,
+				Description: `Transfers defined in transfers.txt for a feed version, or from and to a stop, including the minimum transfer time and any route or trip-specific rules.`,
+				Extensions: map[string]any{
+					"x-alternates": []RequestAltPath{
+						{"GET", "/stops/{stop_key}/transfers", "Request transfers from and to a stop"},
+					},
+				},
+				Parameters: oa.Parameters{
+					&pref{Value: &param{
+						Name:        "feed_version_key",
+						In:          "path",
+						Required:    true,
+						Description: `Feed version lookup key; can be an integer ID or a SHA1 value`,
+						Schema:      newSRVal("string", "", nil),
+					}},
+					&pref{Value: &param{
+						Name:        "transfer_type",
+						In:          "query",
+						Description: `Search for transfers with this GTFS transfer_type`,
+						Schema:      newSRVal("integer", "", []any{0, 1, 2, 3, 4, 5}),
+						Extensions:  newExt("", "transfer_type=2", "/feed_versions/e535eb2b3b9ac3ef15d82c56575e914575e732e0/transfers?transfer_type=2"),
+					}},
+					newPRefExt("limitParam", "", "limit=1", "/feed_versions/e535eb2b3b9ac3ef15d82c56575e914575e732e0/transfers?limit=1"),
+				},
+			},
+		},
+	}
+}
+
+// ResponseKey returns the GraphQL response entity key.
+func (r TransferRequest) ResponseKey() string {
+	if r.StopKey != "" {
+		return "stops"
+	}
+	return "feed_versions"
+}
+
+// IncludeNext
+func (r TransferRequest) IncludeNext() bool { return false }
+
+// Query returns a GraphQL query string and variables.
+func (r TransferRequest) Query(ctx context.Context) (string, map[string]interface{}) {
+	responseKey := r.ResponseKey()
+	var id int
+	entityWhere := hw{}
+	if responseKey == "stops" {
+		id = entityKeyWhere(r.StopKey, "stop_id", entityWhere)
+	} else {
+		id = feedVersionKeyWhere(r.FeedVersionKey, entityWhere)
+	}
+	where := hw{}
+	if r.TransferType != nil {
+		where["transfer_type"] = *r.TransferType
+	}
+	return transferQuery, hw{
+		"include_feed_versions": responseKey == "feed_versions",
+		"include_stops":         responseKey == "stops",
+		"ids":                   checkIds(id),
+		"limit":                 r.CheckLimit(),
+		"feed_version_where":    entityWhere,
+		"stop_where":            entityWhere,
+		"where":                 where,
+	}
+}
@@ -0,0 +1,58 @@
+package rest
+
+import (
+	"testing"
+)
+
+func TestTransferRequest(t *testing.T) {
+	bartSha1 := "e535eb2b3b9ac3ef15d82c56575e914575e732e0"
+	testcases := []testCase{
+		{
+			name:         "feed version by sha1",
+			h:            TransferRequest{FeedVersionKey: bartSha1},
+			selector:     "feed_versions.0.transfers.#.from_stop.stop_id",
+			expectSelect: []string{"19TH_N", "MCAR_S", "BALB", "BAYF", "SBRN", "COLS", "FRMT", "WCRK"},
+		},
+		{
+			name:         "transfer_type",
+			h:            TransferRequest{FeedVersionKey: bartSha1, TransferType: ptr(2)},
+			selector:     "feed_versions.0.transfers.#.min_transfer_time",
+			expectSelect: []string{"240"},
+		},
+		{
+			name:         "limit",
+			h:            TransferRequest{FeedVersionKey: bartSha1, WithCursor: WithCursor{Limit: 2}},
+			selector:     "feed_versions.0.transfers.#.from_stop.stop_id",
+			expectSelect: []string{"19TH_N", "MCAR_S"},
+		},
+		{
+			name:         "stop transfers_from",
+			h:            TransferRequest{StopKey: "BART:COLS"},
+			selector:     "stops.0.transfers_from.#.to_stop.stop_id",
+			expectSelect: []string{"COLS"},
+		},
+		{
+			name:         "stop transfers_to",
+			h:            TransferRequest{StopKey: "BART:COLS"},
+			selector:     "stops.0.transfers_to.#.transfer_type",
+			expectSelect: []string{"2"},
+		},
+		{
+			name:         "stop without transfers",
+			h:            TransferRequest{StopKey: "BART:MONT"},
+			selector:     "stops.0.transfers_from.#.id",
+			expectSelect: []string{},
+		},
+		{
+			name:         "feed version not found",
+			h:            TransferRequest{FeedVersionKey: "missing"},
+			selector:     "feed_versions.#.id",
+			expectSelect: []string{},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			checkTestCase(t, tc)
+		})
+	}
+}
@@ -14,19 +14,20 @@ var tripQuery string
 
 // TripRequest holds options for a /trips request
 type TripRequest struct {
-	ID               int    `json:"id,string"`
-	TripID           string `json:"trip_id"`
-	RouteKey         string `json:"route_key"`
-	RouteID          int    `json:"route_id,string"`
-	RouteOnestopID   string `json:"route_onestop_id"`
-	FeedOnestopID    string `json:"feed_onestop_id"`
-	FeedVersionSHA1  string `json:"feed_version_sha1"`
-	ServiceDate      string `json:"service_date"`
-	RelativeDate     string `json:"relative_date"`
-	IncludeGeometry  bool   `json:"include_geometry,string"`
-	IncludeStopTimes bool   `json:"include_stop_times,string"`
-	IncludeAlerts    bool   `json:"include_alerts,string"`
-	Format           string
+	ID                int    `json:"id,string"`
+	TripID            string `json:"trip_id"`
+	RouteKey          string `json:"route_key"`
+	RouteID           int    `json:"route_id,string"`
+	RouteOnestopID    string `json:"route_onestop_id"`
+	FeedOnestopID     string `json:"feed_onestop_id"`
+	FeedVersionSHA1   string `json:"feed_version_sha1"`
+	ServiceDate       string `json:"service_date"`
+	RelativeDate      string `json:"relative_date"`
+	IncludeGeometry   bool   `json:"include_geometry,string"`
+	IncludeStopTimes  bool   `json:"include_stop_times,string"`
+	IncludeAlerts     bool   `json:"include_alerts,string"`
+	EffectiveSchedule bool   `json:"effective_schedule,string"`
+	Format            string
 	LicenseFilter
 	WithCursor
 }
@@ -81,6 +82,13 @@ func (r TripRequest) RequestInfo() RequestInfo {
 						Schema:      newSRVal("string", "", []any{"true", "false"}),
 						Extensions:  newExt("", "use_service_window=false", "route_onestop_id=r-9q9j-l1&use_service_window=false"),
 					}},
+					&pref{Value: &param{
+						Name:        "effective_schedule",
+						In:          "query",
+						Description: `Return trips from the feed version that governs service_date, the most recently fetched imported version of each feed whose service period contains the date, instead of the active feed version.`,
+						Schema:      newSRVal("string", "", []any{"true", "false"}),
+						Extensions:  newExt("", "effective_schedule=true", "route_onestop_id=r-9q9j-l1&service_date=2021-07-14&effective_schedule=true"),
+					}},
 					newPRefExt("relativeDateParam", "", "relative_date=NEXT_MONDAY", "route_onestop_id=r-9q9j-l1&relative_date=NEXT_MONDAY"),
 					newPRef("includeAlertsParam"),
 					newPRef("idParam"),
@@ -138,6 +146,9 @@ func (r TripRequest) Query(ctx context.Context) (string, map[string]interface{})
 	} else if r.ServiceDate != "" {
 		where["service_date"] = r.ServiceDate
 	}
+	if r.EffectiveSchedule {
+		where["effective_schedule"] = true
+	}
 	where["license"] = checkLicenseFilter(r.LicenseFilter)
 	// Include geometry when in geojson format
 	if r.ID > 0 || r.Format == "geojson" || r.Format == "geojsonl" {
@@ -0,0 +1,71 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/interline-io/transitland-lib/internal/feedstate"
+	"github.com/interline-io/transitland-lib/server/model"
+	"github.com/interline-io/transitland-lib/tldb"
+	"github.com/interline-io/transitland-lib/tt"
+)
+
+// FeedActivationWorker evaluates a feed version activation policy and applies
+// the resulting active feed version changes; the background equivalent of the
+// feed-activate command. The decisions are published as activation_report.json.
+type FeedActivationWorker struct {
+	// FeedOnestopIDs limits the policy to these feeds; empty means all gtfs feeds.
+	FeedOnestopIDs []string `json:"feed_onestop_ids"`
+	// Date the rules are evaluated for, as YYYY-MM-DD; empty means today.
+	Date            string   `json:"date"`
+	RequireStarted  bool     `json:"require_started"`
+	RequireCoverage bool     `json:"require_coverage"`
+	MaxServiceDrop  tt.Float `json:"max_service_drop"`
+	MaxErrorRate    tt.Float `json:"max_error_rate"`
+	// DryRun only reports the changes.
+	DryRun bool `json:"dry_run"`
+}
+
+func (w *FeedActivationWorker) Kind() string {
+	return "feed-activation"
+}
+
+func (w *FeedActivationWorker) Run(ctx context.Context) error {
+	cfg := model.ForContext(ctx)
+	if cfg.Adapter == nil {
+		return errors.New("feed activation requires a database")
+	}
+	policy := feedstate.ActivationPolicy{
+		RequireStarted:  w.RequireStarted,
+		RequireCoverage: w.RequireCoverage,
+		MaxServiceDrop:  w.MaxServiceDrop,
+		MaxErrorRate:    w.MaxErrorRate,
+	}
+	if w.Date != "" {
+		d, err := time.Parse("2006-01-02", w.Date)
+		if err != nil {
+			return err
+		}
+		policy.Date = d
+	}
+	var decisions []feedstate.ActivationDecision
+	if err := cfg.Adapter.Tx(func(atx tldb.Adapter) error {
+		m := feedstate.NewManager(atx)
+		feedIDs, err := m.GetFeedIDsForOnestopIDs(ctx, w.FeedOnestopIDs)
+		if err != nil {
+			return err
+		}
+		decisions, err = m.PlanActivation(ctx, policy, feedIDs)
+		if err != nil {
+			return err
+		}
+		if w.DryRun {
+			return nil
+		}
+		return m.ApplyActivation(ctx, decisions)
+	}); err != nil {
+		return err
+	}
+	return publishJSON(ctx, "activation_report.json", decisions, false)
+}
@@ -0,0 +1,35 @@
+package workers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/interline-io/log"
+	"github.com/interline-io/transitland-lib/server/model"
+)
+
+// errNoArtifactStore is returned by workers whose only output is an artifact,
+// when the deployment has no artifact storage configured.
+var errNoArtifactStore = errors.New("job artifacts not configured")
+
+// publishJSON writes v as a JSON artifact of the executing job. If required is
+// false and no artifact store is configured, the output is only logged as
+// skipped; the worker's other side effects (e.g. a fetched feed version) stand.
+func publishJSON(ctx context.Context, filename string, v any, required bool) error {
+	store := model.JobArtifacts(ctx)
+	if store == nil {
+		if required {
+			return errNoArtifactStore
+		}
+		log.For(ctx).Debug().Str("filename", filename).Msg("no artifact store; skipping job output")
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = store.CreateReader(ctx, model.ArtifactOpts{Filename: filename, ContentType: "application/json"}, bytes.NewReader(data))
+	return err
+}
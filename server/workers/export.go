@@ -0,0 +1,41 @@
+package workers
+
+import (
+	"context"
+	"os"
+
+	"github.com/interline-io/transitland-lib/server/model"
+	"github.com/interline-io/transitland-lib/server/rest"
+)
+
+// FeedVersionExportWorker exports one or more feed versions and publishes the
+// zip as export.zip. The submitter is expected to have checked the request
+// (rest.CheckFeedVersionExportRequest) before queueing it.
+type FeedVersionExportWorker struct {
+	FeedVersionIDs []int                  `json:"feed_version_ids"`
+	Format         string                 `json:"format"`
+	Transforms     *rest.ExportTransforms `json:"transforms"`
+}
+
+func (w *FeedVersionExportWorker) Kind() string {
+	return "feed-version-export"
+}
+
+func (w *FeedVersionExportWorker) Run(ctx context.Context) error {
+	store := model.JobArtifacts(ctx)
+	if store == nil {
+		return errNoArtifactStore
+	}
+	tmpfile, err := os.CreateTemp("", "*-export.zip")
+	if err != nil {
+		return err
+	}
+	tmpfile.Close()
+	defer os.Remove(tmpfile.Name())
+	cfg := model.ForContext(ctx)
+	if err := rest.NewFeedVersionExporter(&cfg).ExportFile(ctx, w.FeedVersionIDs, w.Format, w.Transforms, tmpfile.Name()); err != nil {
+		return err
+	}
+	_, err = store.CreateFile(ctx, model.ArtifactOpts{Filename: "export.zip", ContentType: "application/zip"}, tmpfile.Name())
+	return err
+}
@@ -0,0 +1,60 @@
+package workers
+
+import (
+	"context"
+
+	"github.com/interline-io/transitland-lib/server/model"
+)
+
+// StaticFetchWorker fetches a new static GTFS feed version for a feed. The
+// fetch result is published as fetch_result.json when artifacts are available.
+type StaticFetchWorker struct {
+	FeedOnestopID string `json:"feed_onestop_id"`
+	URL           string `json:"url"`
+}
+
+func (w *StaticFetchWorker) Kind() string {
+	return "static-fetch"
+}
+
+func (w *StaticFetchWorker) Run(ctx context.Context) error {
+	result, err := model.ForContext(ctx).Actions.StaticFetch(ctx, w.FeedOnestopID, nil, w.URL)
+	if err != nil {
+		return err
+	}
+	return publishJSON(ctx, "fetch_result.json", result, false)
+}
+
+// RTFetchWorker fetches a GTFS-RT message for a feed and stores it in the RT cache.
+type RTFetchWorker struct {
+	Target        string `json:"target"`
+	FeedOnestopID string `json:"feed_onestop_id"`
+	URL           string `json:"url"`
+	URLType       string `json:"url_type"`
+}
+
+func (w *RTFetchWorker) Kind() string {
+	return "rt-fetch"
+}
+
+func (w *RTFetchWorker) Run(ctx context.Context) error {
+	target := w.Target
+	if target == "" {
+		target = w.FeedOnestopID
+	}
+	return model.ForContext(ctx).Actions.RTFetch(ctx, target, w.FeedOnestopID, w.URL, w.URLType)
+}
+
+// GbfsFetchWorker fetches a GBFS feed and stores it in the GBFS cache.
+type GbfsFetchWorker struct {
+	FeedOnestopID string `json:"feed_onestop_id"`
+	URL           string `json:"url"`
+}
+
+func (w *GbfsFetchWorker) Kind() string {
+	return "gbfs-fetch"
+}
+
+func (w *GbfsFetchWorker) Run(ctx context.Context) error {
+	return model.ForContext(ctx).Actions.GbfsFetch(ctx, w.FeedOnestopID, w.URL)
+}
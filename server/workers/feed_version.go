@@ -1,7 +1,3 @@
-// Package workers provides reference job workers for the standalone server.
-// Production deployments generally register their own workers; these exist so
-// the demo server and tests can process the feed-version import/unimport jobs
-// that the GraphQL mutations enqueue.
 package workers
 
 import (
@@ -0,0 +1,86 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/interline-io/log"
+	"github.com/interline-io/transitland-lib/internal/rtrecorder"
+	"github.com/interline-io/transitland-lib/server/model"
+	"github.com/interline-io/transitland-lib/tldb"
+)
+
+// RTRecordWorker records the cached trip updates and vehicle positions of
+// realtime feeds as stop observations against the active feed version of a
+// static feed; the background equivalent of the rt-record command. It is
+// intended to run periodically, e.g. after each rt-fetch.
+type RTRecordWorker struct {
+	// FeedOnestopID is the static feed the realtime data is matched to.
+	FeedOnestopID string `json:"feed_onestop_id"`
+	// RTFeedOnestopIDs are the realtime feeds to record; empty means FeedOnestopID.
+	RTFeedOnestopIDs []string `json:"rt_feed_onestop_ids"`
+}
+
+func (w *RTRecordWorker) Kind() string {
+	return "rt-record"
+}
+
+var rtRecordMessageTypes = []string{"realtime_trip_updates", "realtime_vehicle_positions"}
+
+func (w *RTRecordWorker) Run(ctx context.Context) error {
+	if w.FeedOnestopID == "" {
+		return errors.New("feed_onestop_id is required")
+	}
+	cfg := model.ForContext(ctx)
+	if cfg.Adapter == nil || cfg.RTFinder == nil {
+		return errors.New("rt record requires a database and an rt finder")
+	}
+	fvid, err := rtrecorder.FeedVersionForFeed(ctx, cfg.Adapter.DBX(), w.FeedOnestopID)
+	if err != nil {
+		return err
+	}
+	rtFeeds := w.RTFeedOnestopIDs
+	if len(rtFeeds) == 0 {
+		rtFeeds = []string{w.FeedOnestopID}
+	}
+	r := recorderFor(cfg.Adapter)
+	for _, rtFeed := range rtFeeds {
+		for _, msgType := range rtRecordMessageTypes {
+			msg, ok := cfg.RTFinder.GetMessage(ctx, rtFeed, msgType)
+			if !ok || msg == nil {
+				continue
+			}
+			result, err := r.Record(ctx, fvid, rtFeed, msg)
+			if err != nil {
+				return fmt.Errorf("failed to record %s for '%s': %w", msgType, rtFeed, err)
+			}
+			log.For(ctx).Info().
+				Str("feed_onestop_id", rtFeed).
+				Str("message_type", msgType).
+				Int("feed_version_id", result.FeedVersionID).
+				Int("inserted", result.Inserted).
+				Int("updated", result.Updated).
+				Msg("rt-record: recorded observations")
+		}
+	}
+	return nil
+}
+
+// Recorders are shared between jobs so each schedule is loaded once per process.
+var (
+	recordersLock sync.Mutex
+	recorders     = map[tldb.Adapter]*rtrecorder.Recorder{}
+)
+
+func recorderFor(adapter tldb.Adapter) *rtrecorder.Recorder {
+	recordersLock.Lock()
+	defer recordersLock.Unlock()
+	r, ok := recorders[adapter]
+	if !ok {
+		r = rtrecorder.NewRecorder(adapter)
+		recorders[adapter] = r
+	}
+	return r
+}
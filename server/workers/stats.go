@@ -0,0 +1,54 @@
+package workers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/interline-io/transitland-lib/dmfr"
+	"github.com/interline-io/transitland-lib/server/model"
+	"github.com/interline-io/transitland-lib/stats"
+	"github.com/interline-io/transitland-lib/tlcsv"
+	"github.com/interline-io/transitland-lib/tldb"
+)
+
+// StatsRebuildWorker rebuilds the statistics of an imported feed version from
+// its stored source file; the background equivalent of the rebuild-stats command.
+type StatsRebuildWorker struct {
+	FeedVersionID int `json:"feed_version_id"`
+	// Stats limits the rebuild to a subset of stats.AllStats; empty means all.
+	Stats []string `json:"stats"`
+}
+
+func (w *StatsRebuildWorker) Kind() string {
+	return "stats-rebuild"
+}
+
+func (w *StatsRebuildWorker) Run(ctx context.Context) error {
+	cfg := model.ForContext(ctx)
+	if cfg.Adapter == nil {
+		return errors.New("stats rebuild requires a database")
+	}
+	if err := stats.ValidateStatNames(w.Stats); err != nil {
+		return err
+	}
+	fv := dmfr.FeedVersion{}
+	fv.ID = w.FeedVersionID
+	if err := cfg.Adapter.Find(ctx, &fv); err != nil {
+		return err
+	}
+	tladapter, err := tlcsv.NewStoreAdapter(ctx, cfg.Storage, fv.File, fv.Fragment.Val)
+	if err != nil {
+		return err
+	}
+	reader, err := tlcsv.NewReaderFromAdapter(tladapter)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	if err := reader.Open(); err != nil {
+		return err
+	}
+	return cfg.Adapter.Tx(func(atx tldb.Adapter) error {
+		return stats.CreateFeedStats(ctx, atx, reader, fv.ID, stats.WriteOptions{Stats: w.Stats})
+	})
+}
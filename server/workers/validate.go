@@ -0,0 +1,36 @@
+package workers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/interline-io/transitland-lib/server/model"
+)
+
+// ValidateUploadWorker validates a static GTFS feed by URL, optionally with
+// GTFS-RT URLs, and publishes the report as validation_report.json. A feed
+// that fails validation still succeeds as a job; see the report's success and
+// failure_reason fields.
+type ValidateUploadWorker struct {
+	URL          string   `json:"url"`
+	RealtimeURLs []string `json:"realtime_urls"`
+}
+
+func (w *ValidateUploadWorker) Kind() string {
+	return "validate-upload"
+}
+
+func (w *ValidateUploadWorker) Run(ctx context.Context) error {
+	if w.URL == "" {
+		return errors.New("url is required")
+	}
+	if model.JobArtifacts(ctx) == nil {
+		return errNoArtifactStore
+	}
+	url := w.URL
+	report, err := model.ForContext(ctx).Actions.ValidateUpload(ctx, nil, &url, w.RealtimeURLs)
+	if err != nil {
+		return err
+	}
+	return publishJSON(ctx, "validation_report.json", report, true)
+}
@@ -0,0 +1,28 @@
+// Package workers provides reference job workers for the standalone server.
+// Production deployments generally register their own workers; these exist so
+// the demo server and tests can process the jobs that the GraphQL mutations and
+// REST handlers enqueue: feed-version import/unimport, fetches, validation,
+// exports, stats rebuilds, feed version activation and realtime recording.
+//
+// Each worker's Kind is also the name of the queue it is submitted to. Workers
+// that produce files publish them as job artifacts (see model.JobArtifacts),
+// which are listed and downloaded through the job server.
+package workers
+
+import "github.com/interline-io/transitland-lib/server/jobs"
+
+// All returns the constructors for every worker in this package.
+func All() []jobs.WorkerFn {
+	return []jobs.WorkerFn{
+		func() jobs.Worker { return &FeedVersionImportWorker{} },
+		func() jobs.Worker { return &FeedVersionUnimportWorker{} },
+		func() jobs.Worker { return &StaticFetchWorker{} },
+		func() jobs.Worker { return &RTFetchWorker{} },
+		func() jobs.Worker { return &GbfsFetchWorker{} },
+		func() jobs.Worker { return &ValidateUploadWorker{} },
+		func() jobs.Worker { return &FeedVersionExportWorker{} },
+		func() jobs.Worker { return &StatsRebuildWorker{} },
+		func() jobs.Worker { return &FeedActivationWorker{} },
+		func() jobs.Worker { return &RTRecordWorker{} },
+	}
+}
@@ -0,0 +1,360 @@
+package workers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/interline-io/transitland-lib/internal/gbfs"
+	"github.com/interline-io/transitland-lib/internal/testconfig"
+	"github.com/interline-io/transitland-lib/rt"
+	"github.com/interline-io/transitland-lib/server/auth/mw/usercheck"
+	"github.com/interline-io/transitland-lib/server/dbutil"
+	"github.com/interline-io/transitland-lib/server/finders/actions"
+	"github.com/interline-io/transitland-lib/server/gql"
+	"github.com/interline-io/transitland-lib/server/jobs"
+	"github.com/interline-io/transitland-lib/server/model"
+	"github.com/interline-io/transitland-lib/server/rest"
+	"github.com/interline-io/transitland-lib/server/testutil"
+	"github.com/interline-io/transitland-lib/testdata"
+	sq "github.com/irees/squirrel"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+// memArtifacts is an in-memory model.ArtifactStoreFactory that keeps the bytes
+// of every published artifact.
+type memArtifacts struct {
+	arts []*model.JobArtifact
+	data map[string][]byte
+}
+
+func (f *memArtifacts) For(jobID, userID, kind string) model.ArtifactStore {
+	return &memScoped{f: f, jobID: jobID, userID: userID, kind: kind}
+}
+
+func (f *memArtifacts) ListByJob(ctx context.Context, jobID string) ([]*model.JobArtifact, error) {
+	var ret []*model.JobArtifact
+	for _, a := range f.arts {
+		if a.JobID == jobID {
+			ret = append(ret, a)
+		}
+	}
+	return ret, nil
+}
+
+func (f *memArtifacts) GetByID(ctx context.Context, id int) (*model.JobArtifact, error) {
+	return nil, model.ErrArtifactNotFound
+}
+
+type memScoped struct {
+	f                   *memArtifacts
+	jobID, userID, kind string
+}
+
+func (s *memScoped) CreateFile(ctx context.Context, opts model.ArtifactOpts, localPath string) (*model.JobArtifact, error) {
+	r, err := os.Open(localPath)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return s.CreateReader(ctx, opts, r)
+}
+
+func (s *memScoped) CreateReader(ctx context.Context, opts model.ArtifactOpts, r io.Reader) (*model.JobArtifact, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	a := &model.JobArtifact{JobID: s.jobID, JobKind: s.kind, UserID: s.userID, Filename: opts.Filename, ContentType: opts.ContentType, SizeBytes: int64(len(data))}
+	s.f.arts = append(s.f.arts, a)
+	s.f.data[opts.Filename] = data
+	return a, nil
+}
+
+func newTestRunner(cfg model.Config) *jobs.Runner {
+	runner := jobs.NewRunner()
+	for _, fn := range All() {
+		runner.Register(fn)
+	}
+	runner.Use(model.NewConfigMiddleware(cfg))
+	return runner
+}
+
+func TestAll(t *testing.T) {
+	seen := map[string]bool{}
+	for _, fn := range All() {
+		kind := fn().Kind()
+		assert.False(t, seen[kind], "duplicate kind %s", kind)
+		seen[kind] = true
+	}
+	for _, kind := range []string{"feed-version-import", "feed-version-unimport", "static-fetch", "rt-fetch", "gbfs-fetch", "validate-upload", "feed-version-export", "stats-rebuild", "feed-activation", "rt-record"} {
+		assert.True(t, seen[kind], "missing kind %s", kind)
+	}
+}
+
+func TestValidateUploadWorker(t *testing.T) {
+	ts := testutil.NewTestServer(testdata.Path())
+	defer ts.Close()
+	job := jobs.Job{
+		ID:   "test-job",
+		Kind: "validate-upload",
+		Args: jobs.Args{"url": ts.URL + "/server/gtfs/caltrain.zip"},
+		Opts: jobs.JobOpts{UserID: "alice"},
+	}
+	t.Run("publishes report", func(t *testing.T) {
+		store := &memArtifacts{data: map[string][]byte{}}
+		runner := newTestRunner(model.Config{
+			Actions:                  &actions.Actions{},
+			AllowHTTPFetchUnfiltered: true,
+			ArtifactStorage:          "mem",
+			ArtifactStoreFactory:     store,
+		})
+		if err := runner.Run(context.Background(), job); err != nil {
+			t.Fatal(err)
+		}
+		arts, _ := store.ListByJob(context.Background(), "test-job")
+		if assert.Len(t, arts, 1) {
+			assert.Equal(t, "validation_report.json", arts[0].Filename)
+			assert.Equal(t, "alice", arts[0].UserID)
+		}
+		report := model.ValidationReport{}
+		if err := json.Unmarshal(store.data["validation_report.json"], &report); err != nil {
+			t.Fatal(err)
+		}
+		assert.True(t, report.Success)
+	})
+	t.Run("requires artifact store", func(t *testing.T) {
+		runner := newTestRunner(model.Config{Actions: &actions.Actions{}})
+		assert.ErrorIs(t, runner.Run(context.Background(), job), errNoArtifactStore)
+	})
+}
+
+const caltrainSha1 = "d2813c293bcfd7a97dde599527ae6c62c98e66c6"
+
+func checkTestDB(t *testing.T) {
+	if msg, ok := testutil.CheckTestDB(); !ok {
+		t.Skip(msg)
+	}
+}
+
+// withArtifacts returns cfg with an in-memory artifact store.
+func withArtifacts(cfg model.Config) (model.Config, *memArtifacts) {
+	store := &memArtifacts{data: map[string][]byte{}}
+	cfg.ArtifactStorage = "mem"
+	cfg.ArtifactStoreFactory = store
+	return cfg, store
+}
+
+func testFeedVersionID(t *testing.T, cfg model.Config, sha1 string) int {
+	fvs, err := cfg.Finder.FindFeedVersions(context.Background(), nil, nil, nil, &model.FeedVersionFilter{Sha1: &sha1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fvs) != 1 {
+		t.Fatalf("expected feed version %s", sha1)
+	}
+	return fvs[0].ID
+}
+
+// zipFile returns the contents of a file in a zip archive.
+func zipFile(t *testing.T, data []byte, filename string) string {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, zf := range zr.File {
+		if zf.Name != filename {
+			continue
+		}
+		r, err := zf.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		buf, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(buf)
+	}
+	t.Fatalf("no %s in zip", filename)
+	return ""
+}
+
+func TestFeedVersionExportWorker(t *testing.T) {
+	checkTestDB(t)
+	cfg := testconfig.Config(t, testconfig.Options{Storage: testdata.Path("server", "tmp")})
+	fvid := testFeedVersionID(t, cfg, caltrainSha1)
+	job := jobs.Job{
+		ID:   "test-export",
+		Kind: "feed-version-export",
+		Args: jobs.Args{"feed_version_ids": []int{fvid}, "format": "gtfs_zip"},
+		Opts: jobs.JobOpts{UserID: "alice"},
+	}
+	t.Run("publishes export", func(t *testing.T) {
+		cfg, store := withArtifacts(cfg)
+		if err := newTestRunner(cfg).Run(context.Background(), job); err != nil {
+			t.Fatal(err)
+		}
+		arts, _ := store.ListByJob(context.Background(), "test-export")
+		if assert.Len(t, arts, 1) {
+			assert.Equal(t, "export.zip", arts[0].Filename)
+			assert.Equal(t, "application/zip", arts[0].ContentType)
+			assert.Equal(t, "alice", arts[0].UserID)
+		}
+		assert.Contains(t, zipFile(t, store.data["export.zip"], "agency.txt"), "Caltrain")
+	})
+	t.Run("requires artifact store", func(t *testing.T) {
+		assert.ErrorIs(t, newTestRunner(cfg).Run(context.Background(), job), errNoArtifactStore)
+	})
+}
+
+// TestFeedVersionExportWorker_Async runs the job queued by an async export
+// request, as the job server would after the 202 response.
+func TestFeedVersionExportWorker_Async(t *testing.T) {
+	checkTestDB(t)
+	cfg := testconfig.Config(t, testconfig.Options{Storage: testdata.Path("server", "tmp")})
+	graphqlHandler, err := gql.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restHandler, err := rest.NewServer(model.AddConfigAndPerms(cfg, graphqlHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := usercheck.AdminDefaultMiddleware("test")(model.AddConfigAndPerms(cfg, restHandler))
+	req := httptest.NewRequest("POST", "/feed_versions/export", strings.NewReader(`{"feed_version_keys":["`+caltrainSha1+`"],"async":true}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	srv.ServeHTTP(rr, req)
+	if !assert.Equal(t, http.StatusAccepted, rr.Result().StatusCode) {
+		t.Fatal(rr.Body.String())
+	}
+	var st jobs.JobStatus
+	if err := json.Unmarshal(rr.Body.Bytes(), &st); err != nil {
+		t.Fatal(err)
+	}
+	cfg, store := withArtifacts(cfg)
+	if err := newTestRunner(cfg).Run(context.Background(), st.Job); err != nil {
+		t.Fatal(err)
+	}
+	arts, _ := store.ListByJob(context.Background(), st.Job.ID)
+	if assert.Len(t, arts, 1) {
+		assert.Equal(t, "export.zip", arts[0].Filename)
+		assert.Equal(t, "test", arts[0].UserID)
+	}
+	assert.Contains(t, zipFile(t, store.data["export.zip"], "agency.txt"), "Caltrain")
+}
+
+func TestStatsRebuildWorker(t *testing.T) {
+	t.Run("requires database", func(t *testing.T) {
+		job := jobs.Job{Kind: "stats-rebuild", Args: jobs.Args{"feed_version_id": 1}}
+		assert.ErrorContains(t, newTestRunner(model.Config{}).Run(context.Background(), job), "requires a database")
+	})
+	t.Run("rebuilds stats", func(t *testing.T) {
+		checkTestDB(t)
+		testconfig.ConfigTxRollback(t, testconfig.Options{Storage: testdata.Path("server", "tmp")}, func(cfg model.Config) {
+			ctx := context.Background()
+			fvid := testFeedVersionID(t, cfg, caltrainSha1)
+			countServiceLevels := func() int {
+				count := 0
+				q := sq.StatementBuilder.Select("count(*)").From("feed_version_service_levels").Where(sq.Eq{"feed_version_id": fvid})
+				if err := dbutil.Get(ctx, cfg.Adapter.DBX(), q, &count); err != nil {
+					t.Fatal(err)
+				}
+				return count
+			}
+			if _, err := cfg.Adapter.DBX().ExecContext(ctx, "DELETE FROM feed_version_service_levels WHERE feed_version_id = $1", fvid); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, 0, countServiceLevels())
+			job := jobs.Job{Kind: "stats-rebuild", Args: jobs.Args{"feed_version_id": fvid, "stats": []string{"service_levels"}}}
+			if err := newTestRunner(cfg).Run(ctx, job); err != nil {
+				t.Fatal(err)
+			}
+			assert.Greater(t, countServiceLevels(), 0)
+		})
+	})
+	t.Run("unknown stat", func(t *testing.T) {
+		checkTestDB(t)
+		cfg := testconfig.Config(t, testconfig.Options{})
+		job := jobs.Job{Kind: "stats-rebuild", Args: jobs.Args{"feed_version_id": 1, "stats": []string{"unknown"}}}
+		assert.ErrorContains(t, newTestRunner(cfg).Run(context.Background(), job), "unknown stat name")
+	})
+}
+
+func TestRTFetchWorker(t *testing.T) {
+	checkTestDB(t)
+	msg, err := rt.ReadFile(testdata.Path("server", "rt", "BA.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rtdata, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(rtdata)
+	}))
+	defer ts.Close()
+	tcs := []struct {
+		name        string
+		target      string
+		expectTopic string
+	}{
+		{"feed topic", "", "BA~rt"},
+		{"target topic", "BA", "BA"},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			testconfig.ConfigTxRollback(t, testconfig.Options{AllowAll: true}, func(cfg model.Config) {
+				ctx := context.Background()
+				job := jobs.Job{Kind: "rt-fetch", Args: jobs.Args{
+					"target":          tc.target,
+					"feed_onestop_id": "BA~rt",
+					"url":             ts.URL,
+					"url_type":        "realtime_trip_updates",
+				}}
+				if err := newTestRunner(cfg).Run(ctx, job); err != nil {
+					t.Fatal(err)
+				}
+				got, ok := cfg.RTFinder.GetMessage(ctx, tc.expectTopic, "realtime_trip_updates")
+				if assert.True(t, ok, "expected message for topic %s", tc.expectTopic) {
+					assert.Equal(t, len(msg.Entity), len(got.Entity))
+				}
+			})
+		})
+	}
+}
+
+func TestGbfsFetchWorker(t *testing.T) {
+	checkTestDB(t)
+	ts := httptest.NewServer(gbfs.NewTestGbfsServer("en", testdata.Path("server/gbfs")))
+	defer ts.Close()
+	testconfig.ConfigTxRollback(t, testconfig.Options{AllowAll: true}, func(cfg model.Config) {
+		ctx := model.WithConfig(context.Background(), cfg)
+		job := jobs.Job{Kind: "gbfs-fetch", Args: jobs.Args{"feed_onestop_id": "test-gbfs", "url": ts.URL + "/gbfs.json"}}
+		if err := newTestRunner(cfg).Run(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+		bikes, err := cfg.GbfsFinder.FindBikes(ctx, nil, &model.GbfsBikeRequest{
+			Near: &model.PointRadius{Lon: -122.396445, Lat: 37.793250, Radius: 100},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var bikeids []string
+		for _, ent := range bikes {
+			bikeids = append(bikeids, ent.BikeID.Val)
+		}
+		assert.ElementsMatch(t, []string{"2e09a0ed99c8ad32cca516661618645e"}, bikeids)
+	})
+}
@@ -0,0 +1,452 @@
+// Package tlparquet provides a Writer that stores each GTFS file as a Parquet table.
+//
+// Column types follow the tt types used by the gtfs entities: tt.Int as INT64, tt.Float as DOUBLE,
+// tt.Bool as BOOLEAN, tt.Date as DATE, tt.Time as TIMESTAMP (UTC, microseconds), and tt.Seconds as INT32
+// seconds since the start of the service day (GTFS times may exceed 24:00:00, so TIME is not used).
+// Other values are written as UTF8 strings. Geometry columns (stops, shapes, locations) are
+// written as WKB and described in GeoParquet "geo" file metadata.
+//
+// The encoder is intentionally minimal: flat schemas, optional columns, PLAIN encoding,
+// one data page per column chunk, and GZIP compression.
+//
+// This package does not use a Parquet library. transitland-lib is imported by other
+// modules, and the Go Parquet implementations bring in their own compression codecs,
+// Thrift runtimes and assembly-backed encoders for features these flat exports do not use.
+// The encoder only depends on the standard library; in exchange, its output is checked
+// against pyarrow in TestWriter_PyArrow, which is required in CI. Changes to the encoder
+// must keep that test passing.
+package tlparquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+const magic = "PAR1"
+
+// Parquet physical types
+const (
+	typeBoolean   int32 = 0
+	typeInt32     int32 = 1
+	typeInt64     int32 = 2
+	typeDouble    int32 = 5
+	typeByteArray int32 = 6
+)
+
+// Parquet converted types
+const (
+	convertedNone            int32 = -1
+	convertedUTF8            int32 = 0
+	convertedDate            int32 = 6
+	convertedTimestampMicros int32 = 10
+)
+
+// Parquet logical types; values are the LogicalType union field ids
+const (
+	logicalNone      int16 = 0
+	logicalString    int16 = 1
+	logicalDate      int16 = 6
+	logicalTimestamp int16 = 8
+)
+
+const (
+	repetitionOptional int32 = 1
+	encodingPlain      int32 = 0
+	encodingRLE        int32 = 3
+	codecGzip          int32 = 2
+	pageTypeData       int32 = 0
+)
+
+// column buffers the values for one column in the current row group.
+type column struct {
+	name      string
+	physical  int32
+	converted int32
+	logical   int16
+	defs      []bool
+	bools     []bool
+	values    bytes.Buffer
+}
+
+func newColumn(name string, physical int32, converted int32, logical int16) *column {
+	return &column{name: name, physical: physical, converted: converted, logical: logical}
+}
+
+func (c *column) appendNull() {
+	c.defs = append(c.defs, false)
+}
+
+func (c *column) appendBool(v bool) {
+	c.defs = append(c.defs, true)
+	c.bools = append(c.bools, v)
+}
+
+func (c *column) appendInt32(v int32) {
+	c.defs = append(c.defs, true)
+	c.values.Write(binary.LittleEndian.AppendUint32(nil, uint32(v)))
+}
+
+func (c *column) appendInt64(v int64) {
+	c.defs = append(c.defs, true)
+	c.values.Write(binary.LittleEndian.AppendUint64(nil, uint64(v)))
+}
+
+func (c *column) appendDouble(v float64) {
+	c.defs = append(c.defs, true)
+	c.values.Write(binary.LittleEndian.AppendUint64(nil, math.Float64bits(v)))
+}
+
+func (c *column) appendBytes(v []byte) {
+	c.defs = append(c.defs, true)
+	c.values.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(v))))
+	c.values.Write(v)
+}
+
+// page returns the uncompressed data page body: definition levels followed by PLAIN values.
+func (c *column) page() []byte {
+	levels := encodeLevels(c.defs)
+	body := binary.LittleEndian.AppendUint32(nil, uint32(len(levels)))
+	body = append(body, levels...)
+	if c.physical == typeBoolean {
+		return append(body, packBools(c.bools)...)
+	}
+	return append(body, c.values.Bytes()...)
+}
+
+func (c *column) reset() {
+	c.defs = c.defs[:0]
+	c.bools = c.bools[:0]
+	c.values.Reset()
+}
+
+// encodeLevels encodes definition levels (bit width 1) using the RLE/bit-packed hybrid encoding.
+// Runs of 8 or more equal values are RLE encoded; everything else is bit-packed in groups of 8.
+func encodeLevels(defs []bool) []byte {
+	var out []byte
+	for i := 0; i < len(defs); {
+		j := i
+		for j < len(defs) && defs[j] == defs[i] {
+			j++
+		}
+		if j-i >= 8 {
+			out = binary.AppendUvarint(out, uint64(j-i)<<1)
+			out = append(out, boolByte(defs[i]))
+			i = j
+			continue
+		}
+		end := min(i+8, len(defs))
+		out = binary.AppendUvarint(out, 1<<1|1)
+		out = append(out, packBools(defs[i:end])...)
+		i = end
+	}
+	return out
+}
+
+func packBools(vals []bool) []byte {
+	out := make([]byte, (len(vals)+7)/8)
+	for i, v := range vals {
+		if v {
+			out[i/8] |= 1 << (i % 8)
+		}
+	}
+	return out
+}
+
+func boolByte(v bool) byte {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+type keyValue struct {
+	Key   string
+	Value string
+}
+
+type chunkMeta struct {
+	offset       int64
+	numValues    int64
+	uncompressed int64
+	compressed   int64
+}
+
+type rowGroup struct {
+	numRows int64
+	chunks  []chunkMeta
+}
+
+// fileWriter writes a Parquet file with a flat schema of optional columns.
+type fileWriter struct {
+	w         io.Writer
+	offset    int64
+	columns   []*column
+	rows      int64
+	numRows   int64
+	rowGroups []rowGroup
+	createdBy string
+	metadata  []keyValue
+}
+
+func newFileWriter(w io.Writer, columns []*column) (*fileWriter, error) {
+	fw := &fileWriter{w: w, columns: columns, createdBy: "transitland-lib"}
+	if err := fw.write([]byte(magic)); err != nil {
+		return nil, err
+	}
+	return fw, nil
+}
+
+func (fw *fileWriter) write(b []byte) error {
+	n, err := fw.w.Write(b)
+	fw.offset += int64(n)
+	return err
+}
+
+// endRow marks the end of a row; every column must have received exactly one value.
+func (fw *fileWriter) endRow() {
+	fw.rows++
+}
+
+// flush writes buffered rows as a row group.
+func (fw *fileWriter) flush() error {
+	if fw.rows == 0 {
+		return nil
+	}
+	rg := rowGroup{numRows: fw.rows}
+	for _, c := range fw.columns {
+		raw := c.page()
+		var zbuf bytes.Buffer
+		zw := gzip.NewWriter(&zbuf)
+		if _, err := zw.Write(raw); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		hw := &compactWriter{}
+		hw.i32(1, pageTypeData)
+		hw.i32(2, int32(len(raw)))
+		hw.i32(3, int32(zbuf.Len()))
+		hw.structBegin(5)
+		hw.i32(1, int32(len(c.defs)))
+		hw.i32(2, encodingPlain)
+		hw.i32(3, encodingRLE)
+		hw.i32(4, encodingRLE)
+		hw.structEnd()
+		hw.stop()
+		chunk := chunkMeta{
+			offset:       fw.offset,
+			numValues:    int64(len(c.defs)),
+			uncompressed: int64(hw.buf.Len() + len(raw)),
+			compressed:   int64(hw.buf.Len() + zbuf.Len()),
+		}
+		if err := fw.write(hw.buf.Bytes()); err != nil {
+			return err
+		}
+		if err := fw.write(zbuf.Bytes()); err != nil {
+			return err
+		}
+		rg.chunks = append(rg.chunks, chunk)
+		c.reset()
+	}
+	fw.rowGroups = append(fw.rowGroups, rg)
+	fw.numRows += fw.rows
+	fw.rows = 0
+	return nil
+}
+
+// close flushes any buffered rows and writes the file footer.
+func (fw *fileWriter) close() error {
+	if err := fw.flush(); err != nil {
+		return err
+	}
+	footer := fw.footer()
+	if err := fw.write(footer); err != nil {
+		return err
+	}
+	if err := fw.write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer)))); err != nil {
+		return err
+	}
+	return fw.write([]byte(magic))
+}
+
+// footer encodes the FileMetaData struct.
+func (fw *fileWriter) footer() []byte {
+	w := &compactWriter{}
+	w.i32(1, 1)
+	// Schema: root element followed by one element per column
+	w.listBegin(2, ctStruct, len(fw.columns)+1)
+	w.elemBegin()
+	w.str(4, "schema")
+	w.i32(5, int32(len(fw.columns)))
+	w.elemEnd()
+	for _, c := range fw.columns {
+		w.elemBegin()
+		w.i32(1, c.physical)
+		w.i32(3, repetitionOptional)
+		w.str(4, c.name)
+		if c.converted != convertedNone {
+			w.i32(6, c.converted)
+		}
+		if c.logical != logicalNone {
+			w.structBegin(10)
+			w.structBegin(c.logical)
+			if c.logical == logicalTimestamp {
+				w.boolean(1, true)
+				w.structBegin(2)
+				w.structBegin(2) // MICROS
+				w.structEnd()
+				w.structEnd()
+			}
+			w.structEnd()
+			w.structEnd()
+		}
+		w.elemEnd()
+	}
+	w.i64(3, fw.numRows)
+	// Row groups
+	w.listBegin(4, ctStruct, len(fw.rowGroups))
+	for _, rg := range fw.rowGroups {
+		w.elemBegin()
+		w.listBegin(1, ctStruct, len(rg.chunks))
+		totalSize := int64(0)
+		for i, chunk := range rg.chunks {
+			c := fw.columns[i]
+			totalSize += chunk.uncompressed
+			w.elemBegin()
+			w.i64(2, chunk.offset)
+			w.structBegin(3)
+			w.i32(1, c.physical)
+			w.listBegin(2, ctI32, 2)
+			w.elemI32(encodingPlain)
+			w.elemI32(encodingRLE)
+			w.listBegin(3, ctBinary, 1)
+			w.elemStr(c.name)
+			w.i32(4, codecGzip)
+			w.i64(5, chunk.numValues)
+			w.i64(6, chunk.uncompressed)
+			w.i64(7, chunk.compressed)
+			w.i64(9, chunk.offset)
+			w.structEnd()
+			w.elemEnd()
+		}
+		w.i64(2, totalSize)
+		w.i64(3, rg.numRows)
+		w.elemEnd()
+	}
+	if len(fw.metadata) > 0 {
+		w.listBegin(5, ctStruct, len(fw.metadata))
+		for _, kv := range fw.metadata {
+			w.elemBegin()
+			w.str(1, kv.Key)
+			w.str(2, kv.Value)
+			w.elemEnd()
+		}
+	}
+	w.str(6, fw.createdBy)
+	w.stop()
+	return w.buf.Bytes()
+}
+
+// Thrift compact protocol type ids
+const (
+	ctTrue   byte = 1
+	ctFalse  byte = 2
+	ctI32    byte = 5
+	ctI64    byte = 6
+	ctBinary byte = 8
+	ctList   byte = 9
+	ctStruct byte = 12
+)
+
+// compactWriter encodes the subset of the Thrift compact protocol needed for Parquet metadata.
+type compactWriter struct {
+	buf   bytes.Buffer
+	prev  int16
+	stack []int16
+}
+
+func (w *compactWriter) fieldHeader(id int16, typ byte) {
+	if delta := id - w.prev; delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.varint(int64(id))
+	}
+	w.prev = id
+}
+
+func (w *compactWriter) varint(v int64) {
+	w.buf.Write(binary.AppendUvarint(nil, uint64((v<<1)^(v>>63))))
+}
+
+func (w *compactWriter) i32(id int16, v int32) {
+	w.fieldHeader(id, ctI32)
+	w.varint(int64(v))
+}
+
+func (w *compactWriter) i64(id int16, v int64) {
+	w.fieldHeader(id, ctI64)
+	w.varint(v)
+}
+
+func (w *compactWriter) boolean(id int16, v bool) {
+	if v {
+		w.fieldHeader(id, ctTrue)
+	} else {
+		w.fieldHeader(id, ctFalse)
+	}
+}
+
+func (w *compactWriter) str(id int16, v string) {
+	w.fieldHeader(id, ctBinary)
+	w.elemStr(v)
+}
+
+func (w *compactWriter) structBegin(id int16) {
+	w.fieldHeader(id, ctStruct)
+	w.elemBegin()
+}
+
+func (w *compactWriter) structEnd() {
+	w.elemEnd()
+}
+
+func (w *compactWriter) listBegin(id int16, elemType byte, size int) {
+	w.fieldHeader(id, ctList)
+	if size < 15 {
+		w.buf.WriteByte(byte(size)<<4 | elemType)
+	} else {
+		w.buf.WriteByte(0xf0 | elemType)
+		w.buf.Write(binary.AppendUvarint(nil, uint64(size)))
+	}
+}
+
+// elemBegin starts a struct nested in a list or another struct.
+func (w *compactWriter) elemBegin() {
+	w.stack = append(w.stack, w.prev)
+	w.prev = 0
+}
+
+// elemEnd writes the stop field and restores the enclosing field id context.
+func (w *compactWriter) elemEnd() {
+	w.stop()
+	w.prev = w.stack[len(w.stack)-1]
+	w.stack = w.stack[:len(w.stack)-1]
+}
+
+func (w *compactWriter) elemI32(v int32) {
+	w.varint(int64(v))
+}
+
+func (w *compactWriter) elemStr(v string) {
+	w.buf.Write(binary.AppendUvarint(nil, uint64(len(v))))
+	w.buf.WriteString(v)
+}
+
+func (w *compactWriter) stop() {
+	w.buf.WriteByte(0)
+}
@@ -0,0 +1,264 @@
+package tlparquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// parquetFile is a decoded Parquet file, used to check writer output.
+type parquetFile struct {
+	NumRows   int64
+	RowGroups int
+	Columns   []string
+	Types     map[string]int32
+	Values    map[string][]any
+	Metadata  map[string]string
+}
+
+// readParquet decodes the subset of Parquet produced by fileWriter.
+func readParquet(t *testing.T, data []byte) *parquetFile {
+	t.Helper()
+	if len(data) < 12 || string(data[:4]) != magic || string(data[len(data)-4:]) != magic {
+		t.Fatal("missing parquet magic")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	fr := &compactReader{b: data[len(data)-8-footerLen : len(data)-8]}
+	md := fr.readStruct()
+	ret := &parquetFile{
+		NumRows:  md[3].(int64),
+		Types:    map[string]int32{},
+		Values:   map[string][]any{},
+		Metadata: map[string]string{},
+	}
+	for _, el := range md[2].([]any)[1:] {
+		se := el.(map[int16]any)
+		name := string(se[4].([]byte))
+		ret.Columns = append(ret.Columns, name)
+		ret.Types[name] = int32(se[1].(int64))
+	}
+	if kvs, ok := md[5].([]any); ok {
+		for _, kv := range kvs {
+			m := kv.(map[int16]any)
+			ret.Metadata[string(m[1].([]byte))] = string(m[2].([]byte))
+		}
+	}
+	rgs, _ := md[4].([]any)
+	ret.RowGroups = len(rgs)
+	for _, rg := range rgs {
+		for i, cc := range rg.(map[int16]any)[1].([]any) {
+			cmd := cc.(map[int16]any)[3].(map[int16]any)
+			name := ret.Columns[i]
+			offset := int(cmd[9].(int64))
+			pr := &compactReader{b: data[offset:]}
+			ph := pr.readStruct()
+			compressed := int(ph[3].(int64))
+			numValues := int(ph[5].(map[int16]any)[1].(int64))
+			zr, err := gzip.NewReader(bytes.NewReader(data[offset+pr.pos : offset+pr.pos+compressed]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			page, err := io.ReadAll(zr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ret.Values[name] = append(ret.Values[name], decodePage(page, numValues, ret.Types[name])...)
+		}
+	}
+	return ret
+}
+
+func decodePage(page []byte, numValues int, physical int32) []any {
+	levelsLen := int(binary.LittleEndian.Uint32(page))
+	defs := decodeLevels(page[4:4+levelsLen], numValues)
+	values := page[4+levelsLen:]
+	var ret []any
+	pos := 0
+	bit := 0
+	for _, def := range defs {
+		if !def {
+			ret = append(ret, nil)
+			continue
+		}
+		switch physical {
+		case typeBoolean:
+			ret = append(ret, values[bit/8]&(1<<(bit%8)) != 0)
+			bit++
+		case typeInt32:
+			ret = append(ret, int32(binary.LittleEndian.Uint32(values[pos:])))
+			pos += 4
+		case typeInt64:
+			ret = append(ret, int64(binary.LittleEndian.Uint64(values[pos:])))
+			pos += 8
+		case typeDouble:
+			ret = append(ret, math.Float64frombits(binary.LittleEndian.Uint64(values[pos:])))
+			pos += 8
+		case typeByteArray:
+			n := int(binary.LittleEndian.Uint32(values[pos:]))
+			ret = append(ret, string(values[pos+4:pos+4+n]))
+			pos += 4 + n
+		}
+	}
+	return ret
+}
+
+func decodeLevels(data []byte, n int) []bool {
+	ret := make([]bool, 0, n)
+	for pos := 0; len(ret) < n; {
+		h, sz := binary.Uvarint(data[pos:])
+		pos += sz
+		if h&1 == 0 {
+			for i := 0; i < int(h>>1); i++ {
+				ret = append(ret, data[pos] == 1)
+			}
+			pos++
+			continue
+		}
+		groups := int(h >> 1)
+		for i := 0; i < groups*8; i++ {
+			ret = append(ret, data[pos+i/8]&(1<<(i%8)) != 0)
+		}
+		pos += groups
+	}
+	return ret[:n]
+}
+
+// compactReader decodes Thrift compact protocol structs into maps keyed by field id.
+type compactReader struct {
+	b   []byte
+	pos int
+}
+
+func (r *compactReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.b[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *compactReader) varint() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *compactReader) readStruct() map[int16]any {
+	ret := map[int16]any{}
+	prev := int16(0)
+	for {
+		h := r.b[r.pos]
+		r.pos++
+		if h == 0 {
+			return ret
+		}
+		id := prev + int16(h>>4)
+		if h>>4 == 0 {
+			id = int16(r.varint())
+		}
+		prev = id
+		ret[id] = r.readValue(h & 0x0f)
+	}
+}
+
+func (r *compactReader) readValue(typ byte) any {
+	switch typ {
+	case ctTrue:
+		return true
+	case ctFalse:
+		return false
+	case 4, ctI32, ctI64:
+		return r.varint()
+	case ctBinary:
+		n := int(r.uvarint())
+		r.pos += n
+		return r.b[r.pos-n : r.pos]
+	case ctList:
+		h := r.b[r.pos]
+		r.pos++
+		size := int(h >> 4)
+		if size == 15 {
+			size = int(r.uvarint())
+		}
+		var ret []any
+		for i := 0; i < size; i++ {
+			ret = append(ret, r.readValue(h&0x0f))
+		}
+		return ret
+	case ctStruct:
+		return r.readStruct()
+	}
+	panic("unsupported thrift type")
+}
+
+func TestEncodeLevels(t *testing.T) {
+	tcs := map[string][]bool{
+		"empty":     {},
+		"short":     {true, false, true},
+		"run":       {true, true, true, true, true, true, true, true, true, true},
+		"mixed":     {true, false, true, true, true, true, true, true, true, true, true, false, false},
+		"alternate": {true, false, true, false, true, false, true, false, true, false, true},
+	}
+	for name, defs := range tcs {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, defs, decodeLevels(encodeLevels(defs), len(defs)))
+		})
+	}
+}
+
+func TestFileWriter(t *testing.T) {
+	columns := []*column{
+		newColumn("b", typeBoolean, convertedNone, logicalNone),
+		newColumn("i32", typeInt32, convertedNone, logicalNone),
+		newColumn("i64", typeInt64, convertedNone, logicalNone),
+		newColumn("f", typeDouble, convertedNone, logicalNone),
+		newColumn("s", typeByteArray, convertedUTF8, logicalString),
+		newColumn("ts", typeInt64, convertedTimestampMicros, logicalTimestamp),
+	}
+	buf := bytes.Buffer{}
+	fw, err := newFileWriter(&buf, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		if i%3 == 0 {
+			for _, c := range columns {
+				c.appendNull()
+			}
+		} else {
+			columns[0].appendBool(i%2 == 0)
+			columns[1].appendInt32(int32(-i))
+			columns[2].appendInt64(int64(i) << 40)
+			columns[3].appendDouble(float64(i) / 2)
+			columns[4].appendBytes([]byte{byte('a' + i)})
+			columns[5].appendInt64(int64(i))
+		}
+		fw.endRow()
+		if i == 9 {
+			if err := fw.flush(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	fw.metadata = append(fw.metadata, keyValue{Key: "test", Value: "ok"})
+	if err := fw.close(); err != nil {
+		t.Fatal(err)
+	}
+	pf := readParquet(t, buf.Bytes())
+	assert.Equal(t, int64(20), pf.NumRows)
+	assert.Equal(t, 2, pf.RowGroups)
+	assert.Equal(t, []string{"b", "i32", "i64", "f", "s", "ts"}, pf.Columns)
+	assert.Equal(t, "ok", pf.Metadata["test"])
+	for _, c := range pf.Columns {
+		assert.Len(t, pf.Values[c], 20)
+	}
+	assert.Nil(t, pf.Values["s"][0])
+	assert.Equal(t, "b", pf.Values["s"][1])
+	assert.Equal(t, true, pf.Values["b"][2])
+	assert.Equal(t, false, pf.Values["b"][19])
+	assert.Equal(t, int32(-19), pf.Values["i32"][19])
+	assert.Equal(t, int64(11)<<40, pf.Values["i64"][11])
+	assert.Equal(t, 5.5, pf.Values["f"][11])
+}
@@ -0,0 +1,487 @@
+package tlparquet
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/interline-io/transitland-lib/adapters"
+	"github.com/interline-io/transitland-lib/ext"
+	"github.com/interline-io/transitland-lib/gtfs"
+	"github.com/interline-io/transitland-lib/tlcsv"
+	"github.com/interline-io/transitland-lib/tt"
+	geom "github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/wkb"
+)
+
+func init() {
+	// Register writers
+	w := func(url string) (adapters.Writer, error) { return NewWriter(url) }
+	ext.RegisterWriter("parquet", w)
+}
+
+// DefaultRowGroupSize is the number of rows buffered for each table before a row group is written.
+var DefaultRowGroupSize = 100_000
+
+// Writer writes each GTFS file as a Parquet table, e.g. stops.txt as stops.parquet.
+// Paths ending in .zip are written as a zip archive of the Parquet files; otherwise the path is a directory.
+type Writer struct {
+	RowGroupSize int
+	path         string
+	outdir       string
+	tables       map[string]*table
+	tableOrder   []string
+}
+
+// NewWriter returns a new Writer for the given path, with or without the parquet:// prefix.
+func NewWriter(path string) (*Writer, error) {
+	path = strings.TrimPrefix(path, "parquet://")
+	if path == "" {
+		return nil, errors.New("no output path")
+	}
+	return &Writer{
+		RowGroupSize: DefaultRowGroupSize,
+		path:         path,
+		tables:       map[string]*table{},
+	}, nil
+}
+
+func (writer *Writer) String() string {
+	return "parquet://" + writer.path
+}
+
+// Open the Writer.
+func (writer *Writer) Open() error {
+	if strings.HasSuffix(writer.path, ".zip") {
+		tmpdir, err := os.MkdirTemp("", "tlparquet")
+		if err != nil {
+			return err
+		}
+		writer.outdir = tmpdir
+		return nil
+	}
+	writer.outdir = writer.path
+	return os.MkdirAll(writer.outdir, 0755)
+}
+
+// Create the Writer.
+func (writer *Writer) Create() error {
+	return nil
+}
+
+// Delete the Writer.
+func (writer *Writer) Delete() error {
+	return nil
+}
+
+// NewReader is not supported; Parquet output is intended for analysis tools.
+func (writer *Writer) NewReader() (adapters.Reader, error) {
+	return nil, errors.New("parquet writer does not support reading")
+}
+
+// Close writes the remaining row groups and file footers.
+func (writer *Writer) Close() error {
+	var errs []error
+	for _, name := range writer.tableOrder {
+		if err := writer.tables[name].close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	writer.tables = map[string]*table{}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	if !strings.HasSuffix(writer.path, ".zip") || writer.outdir == "" {
+		return nil
+	}
+	defer os.RemoveAll(writer.outdir)
+	return writer.writeZip()
+}
+
+func (writer *Writer) writeZip() error {
+	f, err := os.Create(writer.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for _, name := range writer.tableOrder {
+		fn := name + ".parquet"
+		zf, err := zw.CreateHeader(&zip.FileHeader{Name: fn, Method: zip.Store})
+		if err != nil {
+			return err
+		}
+		src, err := os.Open(filepath.Join(writer.outdir, fn))
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(zf, src)
+		src.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// AddEntity writes an entity to the output.
+func (writer *Writer) AddEntity(ent tt.Entity) (string, error) {
+	eids, err := writer.AddEntities([]tt.Entity{ent})
+	if err != nil {
+		return "", err
+	}
+	if len(eids) == 0 {
+		return "", errors.New("did not write expected number of entities")
+	}
+	return eids[0], nil
+}
+
+// AddEntities writes entities to the output.
+func (writer *Writer) AddEntities(ents []tt.Entity) ([]string, error) {
+	if writer.outdir == "" {
+		if err := writer.Open(); err != nil {
+			return nil, err
+		}
+	}
+	eids := make([]string, 0, len(ents))
+	for _, ent := range ents {
+		name := strings.TrimSuffix(ent.Filename(), filepath.Ext(ent.Filename()))
+		t, ok := writer.tables[name]
+		if !ok {
+			var err error
+			t, err = newTable(filepath.Join(writer.outdir, name+".parquet"), ent)
+			if err != nil {
+				return nil, err
+			}
+			writer.tables[name] = t
+			writer.tableOrder = append(writer.tableOrder, name)
+		}
+		// Stops store coordinates in Geometry
+		if v, ok := ent.(*gtfs.Stop); ok {
+			c := v.Coordinates()
+			v.StopLon.Set(c[0])
+			v.StopLat.Set(c[1])
+		}
+		if err := t.add(ent); err != nil {
+			return nil, err
+		}
+		if t.fw.rows >= int64(writer.RowGroupSize) {
+			if err := t.fw.flush(); err != nil {
+				return nil, err
+			}
+		}
+		if v, ok := ent.(interface{ EntityKey() string }); ok {
+			eids = append(eids, v.EntityKey())
+		} else {
+			eids = append(eids, ent.EntityID())
+		}
+	}
+	return eids, nil
+}
+
+// table writes entities of a single type to a Parquet file.
+type table struct {
+	entType  reflect.Type
+	header   []string
+	geomName string
+	geomIdx  []int
+	file     *os.File
+	fw       *fileWriter
+	geo      *geoStats
+}
+
+func newTable(fn string, ent tt.Entity) (*table, error) {
+	t := &table{entType: reflect.TypeOf(ent)}
+	header, err := tlcsv.MapperCache.GetHeader(ent)
+	if err != nil {
+		return nil, err
+	}
+	vals, err := tlcsv.MapperCache.GetInsert(ent, header)
+	if err != nil {
+		return nil, err
+	}
+	var columns []*column
+	for i, k := range header {
+		if isGeometry(vals[i]) {
+			t.geomName = k
+		}
+		columns = append(columns, newValueColumn(k, vals[i]))
+	}
+	t.header = header
+	// Geometry fields excluded from CSV output, e.g. stops and shapes
+	if t.geomName == "" {
+		if sf, ok := t.entType.Elem().FieldByName("Geometry"); ok {
+			if isGeometry(reflect.Zero(sf.Type).Interface()) {
+				t.geomName = "geometry"
+				t.geomIdx = sf.Index
+				columns = append(columns, newColumn(t.geomName, typeByteArray, convertedNone, logicalNone))
+			}
+		}
+	}
+	if t.geomName != "" {
+		t.geo = &geoStats{types: map[string]bool{}, bbox: [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}}
+	}
+	f, err := os.Create(fn)
+	if err != nil {
+		return nil, err
+	}
+	fw, err := newFileWriter(f, columns)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	t.file = f
+	t.fw = fw
+	return t, nil
+}
+
+func (t *table) add(ent tt.Entity) error {
+	if reflect.TypeOf(ent) != t.entType {
+		return fmt.Errorf("all entities in %s must be same type", ent.Filename())
+	}
+	vals, err := tlcsv.MapperCache.GetInsert(ent, t.header)
+	if err != nil {
+		return err
+	}
+	if t.geomIdx != nil {
+		vals = append(vals, reflect.ValueOf(ent).Elem().FieldByIndex(t.geomIdx).Interface())
+	}
+	for i, v := range vals {
+		if err := t.appendValue(t.fw.columns[i], v); err != nil {
+			return err
+		}
+	}
+	t.fw.endRow()
+	return nil
+}
+
+func (t *table) appendValue(c *column, val any) error {
+	switch v := val.(type) {
+	case tt.Int:
+		if !v.Valid {
+			c.appendNull()
+		} else {
+			c.appendInt64(v.Val)
+		}
+	case tt.Float:
+		if !v.Valid {
+			c.appendNull()
+		} else {
+			c.appendDouble(v.Val)
+		}
+	case tt.Bool:
+		if !v.Valid {
+			c.appendNull()
+		} else {
+			c.appendBool(v.Val)
+		}
+	case tt.Seconds:
+		if !v.Valid {
+			c.appendNull()
+		} else {
+			c.appendInt32(int32(v.Val))
+		}
+	case tt.Date:
+		if !v.Valid {
+			c.appendNull()
+		} else {
+			d := time.Date(v.Val.Year(), v.Val.Month(), v.Val.Day(), 0, 0, 0, 0, time.UTC)
+			c.appendInt32(int32(d.Unix() / 86400))
+		}
+	case tt.Time:
+		if !v.Valid {
+			c.appendNull()
+		} else {
+			c.appendInt64(v.Val.UnixMicro())
+		}
+	case int:
+		c.appendInt64(int64(v))
+	case int64:
+		c.appendInt64(v)
+	case float64:
+		c.appendDouble(v)
+	case bool:
+		c.appendBool(v)
+	case string:
+		c.appendBytes([]byte(v))
+	case tt.Point:
+		return t.appendGeometry(c, v.Valid, v.Val)
+	case tt.LineString:
+		return t.appendGeometry(c, v.Valid, v.Val)
+	case tt.Polygon:
+		return t.appendGeometry(c, v.Valid, v.Val)
+	case tt.MultiPolygon:
+		return t.appendGeometry(c, v.Valid, v.Val)
+	case tt.Geometry:
+		return t.appendGeometry(c, v.Valid, v.Val)
+	default:
+		if p, ok := val.(interface{ IsPresent() bool }); ok && !p.IsPresent() {
+			c.appendNull()
+			return nil
+		}
+		s, err := tt.ToCsv(val)
+		if err != nil {
+			return err
+		}
+		c.appendBytes([]byte(s))
+	}
+	return nil
+}
+
+func (t *table) appendGeometry(c *column, valid bool, g geom.T) error {
+	if !valid || g == nil || reflect.ValueOf(g).IsNil() {
+		c.appendNull()
+		return nil
+	}
+	g = force2D(g)
+	data, err := wkb.Marshal(g, wkb.NDR)
+	if err != nil {
+		return err
+	}
+	c.appendBytes(data)
+	t.geo.add(g)
+	return nil
+}
+
+func (t *table) close() error {
+	if t.geo != nil {
+		md, err := json.Marshal(t.geo.metadata(t.geomName))
+		if err != nil {
+			return err
+		}
+		t.fw.metadata = append(t.fw.metadata, keyValue{Key: "geo", Value: string(md)})
+	}
+	if err := t.fw.close(); err != nil {
+		t.file.Close()
+		return err
+	}
+	return t.file.Close()
+}
+
+// newValueColumn returns a column with the Parquet type for a field value.
+func newValueColumn(name string, val any) *column {
+	switch val.(type) {
+	case tt.Int, int, int64:
+		return newColumn(name, typeInt64, convertedNone, logicalNone)
+	case tt.Float, float64:
+		return newColumn(name, typeDouble, convertedNone, logicalNone)
+	case tt.Bool, bool:
+		return newColumn(name, typeBoolean, convertedNone, logicalNone)
+	case tt.Seconds:
+		return newColumn(name, typeInt32, convertedNone, logicalNone)
+	case tt.Date:
+		return newColumn(name, typeInt32, convertedDate, logicalDate)
+	case tt.Time:
+		return newColumn(name, typeInt64, convertedTimestampMicros, logicalTimestamp)
+	}
+	if isGeometry(val) {
+		return newColumn(name, typeByteArray, convertedNone, logicalNone)
+	}
+	return newColumn(name, typeByteArray, convertedUTF8, logicalString)
+}
+
+func isGeometry(val any) bool {
+	switch val.(type) {
+	case tt.Point, tt.LineString, tt.Polygon, tt.MultiPolygon, tt.Geometry:
+		return true
+	}
+	return false
+}
+
+// force2D drops Z and M values; GeoParquet geometry types do not include measures.
+func force2D(g geom.T) geom.T {
+	if g.Layout() == geom.XY {
+		return g
+	}
+	stride := g.Stride()
+	src := g.FlatCoords()
+	coords := make([]float64, 0, len(src)/stride*2)
+	for i := 0; i+1 < len(src); i += stride {
+		coords = append(coords, src[i], src[i+1])
+	}
+	ends := func(ends []int) []int {
+		ret := make([]int, len(ends))
+		for i, e := range ends {
+			ret[i] = e / stride * 2
+		}
+		return ret
+	}
+	switch v := g.(type) {
+	case *geom.Point:
+		return geom.NewPointFlat(geom.XY, coords)
+	case *geom.LineString:
+		return geom.NewLineStringFlat(geom.XY, coords)
+	case *geom.Polygon:
+		return geom.NewPolygonFlat(geom.XY, coords, ends(v.Ends()))
+	case *geom.MultiPolygon:
+		var endss [][]int
+		for _, e := range v.Endss() {
+			endss = append(endss, ends(e))
+		}
+		return geom.NewMultiPolygonFlat(geom.XY, coords, endss)
+	}
+	return g
+}
+
+// geoStats collects the geometry types and bounding box for GeoParquet metadata.
+type geoStats struct {
+	types map[string]bool
+	bbox  [4]float64
+}
+
+func (s *geoStats) add(g geom.T) {
+	switch g.(type) {
+	case *geom.Point:
+		s.types["Point"] = true
+	case *geom.LineString:
+		s.types["LineString"] = true
+	case *geom.Polygon:
+		s.types["Polygon"] = true
+	case *geom.MultiPolygon:
+		s.types["MultiPolygon"] = true
+	}
+	coords := g.FlatCoords()
+	for i := 0; i+1 < len(coords); i += g.Stride() {
+		s.bbox[0] = min(s.bbox[0], coords[i])
+		s.bbox[1] = min(s.bbox[1], coords[i+1])
+		s.bbox[2] = max(s.bbox[2], coords[i])
+		s.bbox[3] = max(s.bbox[3], coords[i+1])
+	}
+}
+
+type geoMetadata struct {
+	Version       string                       `json:"version"`
+	PrimaryColumn string                       `json:"primary_column"`
+	Columns       map[string]geoColumnMetadata `json:"columns"`
+}
+
+type geoColumnMetadata struct {
+	Encoding      string    `json:"encoding"`
+	GeometryTypes []string  `json:"geometry_types"`
+	Bbox          []float64 `json:"bbox,omitempty"`
+}
+
+func (s *geoStats) metadata(name string) geoMetadata {
+	cm := geoColumnMetadata{Encoding: "WKB", GeometryTypes: []string{}}
+	for k := range s.types {
+		cm.GeometryTypes = append(cm.GeometryTypes, k)
+	}
+	slices.Sort(cm.GeometryTypes)
+	if s.bbox[0] <= s.bbox[2] {
+		cm.Bbox = s.bbox[:]
+	}
+	return geoMetadata{
+		Version:       "1.1.0",
+		PrimaryColumn: name,
+		Columns:       map[string]geoColumnMetadata{name: cm},
+	}
+}
@@ -0,0 +1,244 @@
+package tlparquet
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/interline-io/transitland-lib/copier"
+	"github.com/interline-io/transitland-lib/ext"
+	"github.com/interline-io/transitland-lib/internal/testpath"
+	"github.com/interline-io/transitland-lib/internal/testreader"
+	"github.com/interline-io/transitland-lib/tlcsv"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeParquet(t *testing.T, src string, outfn string) {
+	t.Helper()
+	reader, err := tlcsv.NewReader(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reader.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	writer, err := ext.OpenWriter("parquet://"+outfn, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := copier.QuietCopy(context.Background(), reader, writer); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readParquetFile(t *testing.T, fn string) *parquetFile {
+	t.Helper()
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return readParquet(t, data)
+}
+
+func readGeoMetadata(t *testing.T, pf *parquetFile) geoMetadata {
+	t.Helper()
+	md := geoMetadata{}
+	if err := json.Unmarshal([]byte(pf.Metadata["geo"]), &md); err != nil {
+		t.Fatal(err)
+	}
+	return md
+}
+
+func TestWriter(t *testing.T) {
+	fe := testreader.ExampleFeedBART
+	outdir := filepath.Join(t.TempDir(), "bart")
+	DefaultRowGroupSize = 10_000
+	defer func() { DefaultRowGroupSize = 100_000 }()
+	writeParquet(t, fe.URL, outdir)
+	t.Run("counts", func(t *testing.T) {
+		for _, fn := range []string{"agency", "calendar", "calendar_dates", "routes", "stops", "trips", "stop_times"} {
+			pf := readParquetFile(t, filepath.Join(outdir, fn+".parquet"))
+			assert.Equal(t, int64(fe.Counts[fn+".txt"]), pf.NumRows, fn)
+		}
+	})
+	t.Run("stops", func(t *testing.T) {
+		pf := readParquetFile(t, filepath.Join(outdir, "stops.parquet"))
+		assert.Equal(t, typeDouble, pf.Types["stop_lat"])
+		assert.Equal(t, typeInt64, pf.Types["location_type"])
+		assert.Equal(t, typeByteArray, pf.Types["geometry"])
+		md := readGeoMetadata(t, pf)
+		assert.Equal(t, "geometry", md.PrimaryColumn)
+		assert.Equal(t, "WKB", md.Columns["geometry"].Encoding)
+		assert.Equal(t, []string{"Point"}, md.Columns["geometry"].GeometryTypes)
+		if bbox := md.Columns["geometry"].Bbox; assert.Len(t, bbox, 4) {
+			assert.InDelta(t, -122.5, bbox[0], 0.5)
+			assert.InDelta(t, 37.5, bbox[1], 0.5)
+		}
+		for _, v := range pf.Values["geometry"] {
+			// Little endian WKB point
+			assert.Len(t, v, 21)
+		}
+	})
+	t.Run("shapes", func(t *testing.T) {
+		pf := readParquetFile(t, filepath.Join(outdir, "shapes.parquet"))
+		assert.Equal(t, []string{"shape_id", "geometry"}, pf.Columns)
+		assert.Equal(t, int64(12), pf.NumRows)
+		md := readGeoMetadata(t, pf)
+		assert.Equal(t, []string{"LineString"}, md.Columns["geometry"].GeometryTypes)
+	})
+	t.Run("stop_times", func(t *testing.T) {
+		pf := readParquetFile(t, filepath.Join(outdir, "stop_times.parquet"))
+		assert.Greater(t, pf.RowGroups, 1)
+		assert.Equal(t, typeInt32, pf.Types["arrival_time"])
+		assert.Equal(t, typeInt64, pf.Types["stop_sequence"])
+		assert.Empty(t, pf.Metadata["geo"])
+		for i, v := range pf.Values["arrival_time"] {
+			if assert.NotNil(t, v) {
+				assert.Greater(t, v.(int32), int32(0))
+			}
+			if i > 100 {
+				break
+			}
+		}
+	})
+	t.Run("calendar", func(t *testing.T) {
+		pf := readParquetFile(t, filepath.Join(outdir, "calendar.parquet"))
+		assert.Equal(t, typeInt32, pf.Types["start_date"])
+		for _, v := range pf.Values["start_date"] {
+			// Days since epoch; after 2010-01-01
+			assert.Greater(t, v.(int32), int32(14610))
+		}
+	})
+}
+
+func TestWriter_Locations(t *testing.T) {
+	outdir := t.TempDir()
+	writeParquet(t, testpath.RelPath("testdata/flex/stops-absent-flex"), outdir)
+	pf := readParquetFile(t, filepath.Join(outdir, "locations.parquet"))
+	assert.Equal(t, int64(1), pf.NumRows)
+	assert.Equal(t, "area1", pf.Values["location_id"][0])
+	md := readGeoMetadata(t, pf)
+	assert.Equal(t, []string{"Polygon"}, md.Columns["geometry"].GeometryTypes)
+	assert.Equal(t, []float64{-122.0, 47.0, -121.9, 47.1}, md.Columns["geometry"].Bbox)
+}
+
+func TestWriter_Zip(t *testing.T) {
+	outfn := filepath.Join(t.TempDir(), "example.zip")
+	writeParquet(t, testpath.RelPath("testdata/gtfs-examples/example"), outfn)
+	zr, err := zip.OpenReader(outfn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	files := map[string]*parquetFile{}
+	for _, zf := range zr.File {
+		f, err := zf.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[zf.Name] = readParquet(t, data)
+	}
+	assert.Contains(t, files, "stops.parquet")
+	assert.Contains(t, files, "trips.parquet")
+	if pf, ok := files["trips.parquet"]; ok {
+		assert.Equal(t, int64(11), pf.NumRows)
+	}
+}
+
+// pyarrowScript prints the schema, row count, geo metadata and first row of each Parquet file as JSON.
+const pyarrowScript = `
+import json, sys
+import pyarrow.parquet as pq
+ret = {}
+for fn in sys.argv[1:]:
+    t = pq.read_table(fn)
+    md = t.schema.metadata or {}
+    first = t.slice(0, 1).to_pylist()
+    ret[fn] = {
+        "num_rows": t.num_rows,
+        "types": {f.name: str(f.type) for f in t.schema},
+        "geo": json.loads(md.get(b"geo", b"null")),
+        "first": {k: (v.hex() if isinstance(v, bytes) else str(v)) for k, v in (first[0] if first else {}).items()},
+    }
+print(json.dumps(ret))
+`
+
+// TestWriter_PyArrow reads the output with an independent Parquet implementation.
+// This is the interoperability check for the encoder and is required in CI.
+func TestWriter_PyArrow(t *testing.T) {
+	python := os.Getenv("TL_TEST_PYARROW_PYTHON")
+	if python == "" && os.Getenv("CI") != "" {
+		t.Fatal("TL_TEST_PYARROW_PYTHON must be set in CI")
+	} else if python == "" {
+		t.Skip("Set TL_TEST_PYARROW_PYTHON to a python interpreter with pyarrow installed for this test")
+		return
+	}
+	fe := testreader.ExampleFeedBART
+	outdir := filepath.Join(t.TempDir(), "bart")
+	DefaultRowGroupSize = 10_000
+	defer func() { DefaultRowGroupSize = 100_000 }()
+	writeParquet(t, fe.URL, outdir)
+	tables := []string{"agency", "calendar", "calendar_dates", "routes", "stops", "trips", "stop_times", "shapes"}
+	args := []string{"-c", pyarrowScript}
+	for _, fn := range tables {
+		args = append(args, filepath.Join(outdir, fn+".parquet"))
+	}
+	out, err := exec.Command(python, args...).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	type pyarrowTable struct {
+		NumRows int               `json:"num_rows"`
+		Types   map[string]string `json:"types"`
+		Geo     *geoMetadata      `json:"geo"`
+		First   map[string]string `json:"first"`
+	}
+	result := map[string]pyarrowTable{}
+	if err := json.Unmarshal(out, &result); err != nil {
+		t.Fatal(err)
+	}
+	get := func(fn string) pyarrowTable {
+		return result[filepath.Join(outdir, fn+".parquet")]
+	}
+	for _, fn := range tables {
+		if fn == "shapes" {
+			continue
+		}
+		assert.Equal(t, fe.Counts[fn+".txt"], get(fn).NumRows, fn)
+	}
+	stops := get("stops")
+	assert.Equal(t, "double", stops.Types["stop_lat"])
+	assert.Equal(t, "int64", stops.Types["location_type"])
+	assert.Equal(t, "binary", stops.Types["geometry"])
+	if assert.NotNil(t, stops.Geo) {
+		assert.Equal(t, "geometry", stops.Geo.PrimaryColumn)
+		assert.Equal(t, "WKB", stops.Geo.Columns["geometry"].Encoding)
+	}
+	// Little endian WKB point
+	assert.Len(t, stops.First["geometry"], 42)
+	assert.Equal(t, "0101000000", stops.First["geometry"][:10])
+	shapes := get("shapes")
+	assert.Equal(t, 12, shapes.NumRows)
+	if assert.NotNil(t, shapes.Geo) {
+		assert.Equal(t, []string{"LineString"}, shapes.Geo.Columns["geometry"].GeometryTypes)
+	}
+	calendar := get("calendar")
+	assert.Equal(t, "date32[day]", calendar.Types["start_date"])
+	assert.Equal(t, "2018-05-26", calendar.First["start_date"])
+	assert.Equal(t, "int32", get("stop_times").Types["arrival_time"])
+	assert.Equal(t, "string", get("trips").Types["trip_id"])
+}
@@ -452,6 +452,8 @@ func (v *Validator) copierOptions() copier.Options {
 		}, 1)
 		// GTFS-Flex best practice: location groups should have stops
 		cpOpts.AddExtensionWithLevel(&bestpractices.FlexLocationGroupEmptyCheck{}, 1)
+		// Station pathways: platforms should be reachable without stairs or escalators
+		cpOpts.AddExtensionWithLevel(&bestpractices.PlatformStepFreeCheck{}, 1)
 	}
 	return cpOpts
 }